@@ -16,6 +16,7 @@ from . import (
     ui,
     diagnostics,
     providers,
+    serve,
 )
 
 
@@ -30,3 +31,4 @@ def register_all(subparsers):
     ui.register(subparsers)
     diagnostics.register(subparsers)
     providers.register(subparsers)
+    serve.register(subparsers)
@@ -2,7 +2,8 @@
 Diagnostics command producing environment checks for UI/automation.
 
 Outputs JSON (or human-readable) with checks for:
-- Python version
+- Python version, interpreter and virtualenv
+- chi_llm package version and location
 - Node/npm presence
 - Cache dir existence and writability
 - Current model vs available RAM
@@ -15,6 +16,7 @@ from pathlib import Path
 import json
 import os
 import platform
+import sys
 import time
 
 try:
@@ -26,13 +28,25 @@ except Exception:  # pragma: no cover
 
 
 def _check_python() -> dict:
+    base_prefix = getattr(sys, "base_prefix", sys.prefix)
     return {
         "version": platform.python_version(),
         "implementation": platform.python_implementation(),
+        "executable": sys.executable,
+        "prefix": sys.prefix,
+        "venv": sys.prefix if sys.prefix != base_prefix else None,
         "ok": True,
     }
 
 
+def _check_package() -> dict:
+    try:
+        from .. import __version__ as version
+    except Exception:  # pragma: no cover
+        version = None
+    return {"version": version, "path": str(Path(__file__).resolve().parents[1])}
+
+
 def _check_node() -> dict:
     node = which("node")
     npm = which("npm")
@@ -94,6 +108,7 @@ def _check_network(timeout: float = 2.0) -> dict:
 def _gather() -> dict:
     data = {
         "python": _check_python(),
+        "chi_llm": _check_package(),
         "node": _check_node(),
         "cache": _check_cache(),
         "model": _check_model(),
@@ -141,7 +156,11 @@ def cmd_diagnostics(args):
         return
     # Human-readable summary
     print("Environment diagnostics:\n")
-    print(f"Python: {data['python']['version']} ({data['python']['implementation']})")
+    py = data["python"]
+    print(f"Python: {py['version']} ({py['implementation']}) at {py['executable']}")
+    print(f"Virtualenv: {py['venv'] or 'none'}")
+    pkg = data["chi_llm"]
+    print(f"chi_llm: {pkg['version']} ({pkg['path']})")
     print(f"Node: {'ok' if data['node']['ok'] else 'missing'}")
     cache = data["cache"]
     print(
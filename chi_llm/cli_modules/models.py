@@ -2,6 +2,8 @@
 Model management and setup commands.
 """
 
+import sys
+import time
 from argparse import _SubParsersAction
 from importlib import resources
 from typing import Any, Dict, List, Tuple
@@ -16,6 +18,50 @@ except Exception:  # pragma: no cover - optional
     HAS_MODELS = False
 
 
+def _download_via_tui(info, model) -> bool:
+    """Hand the download to a running chi-tui and follow its progress.
+
+    Returns False when the TUI refused the request, so the caller can fall
+    back to downloading here.
+    """
+    from .. import tui_bridge
+
+    try:
+        reply = tui_bridge.request(
+            info,
+            "download.start",
+            id=model.id,
+            repo=model.repo,
+            filename=model.filename,
+        )
+    except tui_bridge.BridgeError as e:
+        print(f"⚠️  chi-tui could not start the download: {e}")
+        return False
+    print(f"📥 Downloading {model.name} in chi-tui (pid {info.get('pid')})...")
+    job = reply["job"]
+    try:
+        while job["state"] == "running":
+            pct = job.get("percent")
+            eta = job.get("eta_s")
+            line = f"{pct:.0f}%" if pct is not None else f"{job['done'] // 2**20} MB"
+            if eta is not None:
+                line += f" ETA {eta // 60}m{eta % 60:02d}s"
+            print(f"\r   {line}    ", end="", flush=True)
+            time.sleep(0.5)
+            job = tui_bridge.request(info, "download.status", id=model.id)["job"]
+    except KeyboardInterrupt:
+        print(f"\nDownload continues in chi-tui (pid {info.get('pid')}).")
+        sys.exit(130)
+    except tui_bridge.BridgeError as e:
+        print(f"\n❌ Lost contact with chi-tui: {e}")
+        sys.exit(1)
+    print()
+    if job["state"] != "done":
+        print(f"❌ Download {job['state']}: {job.get('error') or model.id}")
+        sys.exit(1)
+    return True
+
+
 def cmd_setup(args):
     if not HAS_MODELS:
         print("❌ Model management not available")
@@ -170,19 +216,22 @@ def cmd_models(args):
             current = manager.get_current_model().id
             out = []
             for m in MODELS.values():
-                out.append(
-                    {
-                        "id": m.id,
-                        "name": m.name,
-                        "size": m.size,
-                        "file_size_mb": m.file_size_mb,
-                        "context_window": m.context_window,
-                        "recommended_ram_gb": m.recommended_ram_gb,
-                        "tags": m.tags,
-                        "downloaded": manager.is_downloaded(m.id),
-                        "current": m.id == current,
-                    }
-                )
+                item = {
+                    "id": m.id,
+                    "name": m.name,
+                    "size": m.size,
+                    "file_size_mb": m.file_size_mb,
+                    "context_window": m.context_window,
+                    "recommended_ram_gb": m.recommended_ram_gb,
+                    "tags": m.tags,
+                    "repo": m.repo,
+                    "filename": m.filename,
+                    "downloaded": manager.is_downloaded(m.id),
+                    "current": m.id == current,
+                }
+                if m.mirrors:
+                    item["mirrors"] = m.mirrors
+                out.append(item)
             _print_json(out)
         else:
             print("📦 Available Models:\n")
@@ -298,13 +347,34 @@ def cmd_models(args):
             return
         if not manager.is_downloaded(args.model_id):
             print(f"⚠️  Model {args.model_id} is not downloaded.")
-            print("Run 'chi-llm setup' to download it first.")
+            print(f"Run 'chi-llm models download {args.model_id}' first.")
             return
         save_target = "local" if hasattr(args, "local") and args.local else "global"
         manager.set_default_model(args.model_id, save_target=save_target)
         model = MODELS[args.model_id]
         location = "locally" if save_target == "local" else "globally"
         print(f"✅ {model.name} is now the default model {location}!")
+    elif args.models_command == "download":
+        if args.model_id not in MODELS:
+            print(f"❌ Unknown model: {args.model_id}")
+            print("Available models:", ", ".join(MODELS.keys()))
+            return
+        model = MODELS[args.model_id]
+        if manager.is_downloaded(args.model_id):
+            print(f"✅ {model.name} is already downloaded.")
+            return
+        # A running chi-tui owns the download so both show the same progress
+        info = None
+        if not getattr(args, "no_tui", False):
+            from .. import tui_bridge
+
+            info = tui_bridge.find_instance()
+        if info and _download_via_tui(info, model):
+            manager.mark_downloaded(args.model_id)
+            print(f"✅ {model.name} downloaded.")
+            return
+        if not SetupWizard().download_model(args.model_id):
+            sys.exit(1)
     elif args.models_command == "info":
         if args.model_id not in MODELS:
             print(f"❌ Unknown model: {args.model_id}")
@@ -405,6 +475,15 @@ def register(subparsers: _SubParsersAction):
     models_set.add_argument(
         "--local", action="store_true", help="Save to local project config"
     )
+    models_dl = models_sub.add_parser(
+        "download", help="Download a model (via a running chi-tui when available)"
+    )
+    models_dl.add_argument("model_id", help="Model ID")
+    models_dl.add_argument(
+        "--no-tui",
+        action="store_true",
+        help="Download in this process even if chi-tui is running",
+    )
     models_info = models_sub.add_parser("info", help="Show model details")
     models_info.add_argument("model_id", help="Model ID")
     models_info.add_argument("--json", action="store_true", help="Output JSON")
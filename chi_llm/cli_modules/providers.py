@@ -19,222 +19,12 @@ except Exception:  # pragma: no cover - optional
     MODEL_DIR = Path.home() / ".cache" / "chi_llm"  # type: ignore
     MODELS = {}  # type: ignore
 
-SUPPORTED = [
-    {"type": "local", "implemented": True, "notes": "Default llama.cpp (legacy alias)"},
-    {
-        "type": "local-zeroconfig",
-        "implemented": True,
-        "notes": "Curated models (one-pick)",
-    },
-    {
-        "type": "local-custom",
-        "implemented": True,
-        "notes": "Custom GGUF path and tuning",
-    },
-    {"type": "lmstudio", "implemented": True, "notes": "Local UI/server"},
-    {"type": "ollama", "implemented": True, "notes": "Local server"},
-    {"type": "openai", "implemented": True, "notes": "API key required"},
-    {"type": "claude-cli", "implemented": True, "notes": "Anthropic CLI bridge"},
-    {"type": "openai-cli", "implemented": True, "notes": "OpenAI CLI bridge"},
-    {"type": "anthropic", "implemented": True, "notes": "API key required"},
-    {"type": "groq", "implemented": False},
-    {"type": "gemini", "implemented": False},
-]
-
-# Provider field schema for UI/automation. This reflects what `providers set`
-# accepts today. Extend here if CLI gains new fields.
-PROVIDER_SCHEMAS = {
-    "local": {
-        "fields": [
-            {
-                "name": "model",
-                "type": "string",
-                "required": False,
-                "help": "Model ID (GGUF)",
-            },
-            {
-                "name": "model_path",
-                "type": "string",
-                "required": False,
-                "help": "Absolute path to GGUF model file",
-            },
-            {
-                "name": "context_window",
-                "type": "int",
-                "required": False,
-                "help": "Override context window (n_ctx)",
-            },
-            {
-                "name": "n_gpu_layers",
-                "type": "int",
-                "required": False,
-                "help": "Layers offloaded to GPU",
-            },
-            {
-                "name": "output_tokens",
-                "type": "int",
-                "required": False,
-                "help": "Default max output tokens",
-            },
-        ]
-    },
-    "local-zeroconfig": {
-        "fields": [
-            {
-                "name": "model",
-                "type": "string",
-                "required": False,
-                "help": "Recommended model ID from curated list",
-            }
-        ]
-    },
-    "local-custom": {
-        "fields": [
-            {
-                "name": "model_path",
-                "type": "string",
-                "required": False,
-                "help": "Absolute path to GGUF model file",
-            },
-            {
-                "name": "model",
-                "type": "string",
-                "required": False,
-                "help": "Model ID (optional; overrides default)",
-            },
-            {
-                "name": "context_window",
-                "type": "int",
-                "required": False,
-                "help": "Override context window (n_ctx)",
-            },
-            {
-                "name": "n_gpu_layers",
-                "type": "int",
-                "required": False,
-                "help": "Layers offloaded to GPU",
-            },
-            {
-                "name": "output_tokens",
-                "type": "int",
-                "required": False,
-                "help": "Default max output tokens",
-            },
-        ]
-    },
-    "lmstudio": {
-        "fields": [
-            {
-                "name": "host",
-                "type": "string",
-                "required": False,
-                "default": "localhost",
-                "help": "Server host",
-            },
-            {
-                "name": "port",
-                "type": "int",
-                "required": False,
-                "default": 1234,
-                "help": "Server port",
-            },
-            {
-                "name": "model",
-                "type": "string",
-                "required": False,
-                "help": "Default model ID",
-            },
-        ]
-    },
-    "ollama": {
-        "fields": [
-            {
-                "name": "host",
-                "type": "string",
-                "required": False,
-                "default": "localhost",
-                "help": "Server host",
-            },
-            {
-                "name": "port",
-                "type": "int",
-                "required": False,
-                "default": 11434,
-                "help": "Server port",
-            },
-            {
-                "name": "model",
-                "type": "string",
-                "required": False,
-                "help": "Default model ID",
-            },
-        ]
-    },
-    "openai": {
-        "fields": [
-            {
-                "name": "api_key",
-                "type": "secret",
-                "required": True,
-                "help": "OpenAI API key",
-            },
-            {
-                "name": "base_url",
-                "type": "string",
-                "required": False,
-                "help": "API base URL",
-            },
-            {
-                "name": "org_id",
-                "type": "string",
-                "required": False,
-                "help": "Organization ID",
-            },
-            {
-                "name": "model",
-                "type": "string",
-                "required": False,
-                "help": "Default model ID",
-            },
-        ]
-    },
-    "anthropic": {
-        "fields": [
-            {
-                "name": "api_key",
-                "type": "secret",
-                "required": True,
-                "help": "Anthropic API key",
-            },
-            {
-                "name": "model",
-                "type": "string",
-                "required": True,
-                "help": "Default model ID (e.g., claude-3-haiku-20240307)",
-            },
-        ]
-    },
-    "claude-cli": {
-        "fields": [
-            {
-                "name": "model",
-                "type": "string",
-                "required": False,
-                "help": "Default model ID (if applicable)",
-            },
-        ]
-    },
-    "openai-cli": {
-        "fields": [
-            {
-                "name": "model",
-                "type": "string",
-                "required": False,
-                "help": "Default model ID (if applicable)",
-            },
-        ]
-    },
-}
+from .providers_schema import (  # noqa: F401 - re-exported
+    PROVIDER_SCHEMAS,
+    SUPPORTED,
+    _all_supported,
+    _manifests,
+)
 
 
 def _print_json(obj: Any) -> None:
@@ -258,14 +48,17 @@ def _resolve_save_target(local: bool) -> Path:
 def cmd_providers(args):
     sub = args.providers_command
     if sub == "list":
+        supported = _all_supported()
         if getattr(args, "json", False):
-            _print_json(SUPPORTED)
+            _print_json(supported)
         else:
             print("Supported providers:\n")
-            for p in SUPPORTED:
+            for p in supported:
                 mark = "✓" if p.get("implemented") else "-"
                 note = f" ({p.get('notes')})" if p.get("notes") else ""
                 print(f"• {p['type']}: {mark}{note}")
+            for err in _manifests()[1]:
+                print(f"⚠️  Skipped manifest {err}")
         return
 
     if sub == "schema":
@@ -297,9 +90,28 @@ def cmd_providers(args):
                 {
                     "type": ptype,
                     "implemented": bool(p.get("implemented")),
+                    "privacy": p.get("privacy"),
                     "fields": fields,
                 }
             )
+        from ..providers.manifests import manifest_fields
+
+        manifests, errors = _manifests()
+        for m in manifests:
+            out["providers"].append(
+                {
+                    "type": m["type"],
+                    "implemented": True,
+                    "privacy": m.get("privacy"),
+                    "fields": manifest_fields(m),
+                    "source": m["source"],
+                }
+            )
+        if errors:
+            out["manifest_errors"] = errors
+        from .providers_deprecations import entries as _deprecated
+
+        out["deprecated_models"] = _deprecated()
         if getattr(args, "json", False):
             _print_json(out)
         else:
@@ -361,9 +173,10 @@ def cmd_providers(args):
 
     if sub == "set":
         ptype = args.type
-        if ptype not in [p["type"] for p in SUPPORTED]:
+        supported = _all_supported()
+        if ptype not in [p["type"] for p in supported]:
             print(f"❌ Unknown provider type: {ptype}")
-            print("Supported:", ", ".join([p["type"] for p in SUPPORTED]))
+            print("Supported:", ", ".join([p["type"] for p in supported]))
             return
         provider_cfg: Dict[str, Any] = {"type": ptype}
         if getattr(args, "host", None):
@@ -390,6 +203,11 @@ def cmd_providers(args):
                 provider_cfg["n_gpu_layers"] = int(args.n_gpu_layers)
             except Exception:
                 provider_cfg["n_gpu_layers"] = args.n_gpu_layers
+        if getattr(args, "timeout", None) is not None:
+            try:
+                provider_cfg["timeout"] = float(args.timeout)
+            except Exception:
+                provider_cfg["timeout"] = args.timeout
         if getattr(args, "output_tokens", None) is not None:
             try:
                 provider_cfg["output_tokens"] = int(args.output_tokens)
@@ -496,6 +314,11 @@ def register(subparsers: _SubParsersAction):
         help="Default max output tokens for local provider",
         default=None,
     )
+    setp.add_argument(
+        "--timeout",
+        help="Request timeout in seconds (server/API providers)",
+        default=None,
+    )
     setp.add_argument("--api-key", dest="api_key", help="API key (if required)")
     setp.add_argument("--local", action="store_true", help="Write to project config")
     setp.add_argument("--json", action="store_true", help="Echo saved config as JSON")
@@ -504,6 +327,16 @@ def register(subparsers: _SubParsersAction):
     tags.add_argument("--json", action="store_true", help="Output JSON")
     tags.set_defaults(func=cmd_list_tags)
 
+    dep = providers_sub.add_parser(
+        "deprecations", help="List deprecated/retired hosted models"
+    )
+    dep.add_argument(
+        "--refresh", action="store_true", help="Download the current list first"
+    )
+    dep.add_argument("--url", default=None, help="Where --refresh downloads from")
+    dep.add_argument("--json", action="store_true", help="Output JSON")
+    dep.set_defaults(func=cmd_deprecations)
+
     # Discover models for certain providers (e.g., lmstudio, ollama)
     disc = providers_sub.add_parser(
         "discover-models", help="Discover available models for a provider"
@@ -513,14 +346,44 @@ def register(subparsers: _SubParsersAction):
     disc.add_argument("--port", default=None, help="Provider port (int)")
     disc.add_argument("--json", action="store_true", help="Output JSON")
     disc.add_argument(
-        "--base-url", dest="base_url", default=None, help="API base URL (OpenAI)"
+        "--base-url",
+        dest="base_url",
+        default=None,
+        help="API base URL (OpenAI, Anthropic)",
     )
     disc.add_argument(
-        "--api-key", dest="api_key", default=None, help="API key (OpenAI)"
+        "--api-key", dest="api_key", default=None, help="API key (OpenAI, Anthropic)"
     )
     disc.add_argument(
         "--org-id", dest="org_id", default=None, help="Organization ID (OpenAI)"
     )
+    disc.add_argument(
+        "--user-agent", dest="user_agent", default=None, help="User-Agent header"
+    )
+    disc.add_argument(
+        "--scheme", default="http", help="http or https (LM Studio, Ollama)"
+    )
+    disc.add_argument(
+        "--ca-cert", dest="ca_cert", default=None, help="CA bundle for https"
+    )
+    disc.add_argument(
+        "--insecure", action="store_true", help="Skip TLS certificate verification"
+    )
+    disc.add_argument(
+        "--proxy", default=None, help="Proxy URL (default: HTTP(S)_PROXY)"
+    )
+    disc.add_argument(
+        "--debug-requests",
+        dest="debug_requests",
+        action="store_true",
+        help="Log request and response headers (secrets masked)",
+    )
+    disc.add_argument(
+        "--auth-header",
+        dest="auth_header",
+        default=None,
+        help="Header for --api-key instead of a bearer token (LM Studio, Ollama)",
+    )
     disc.set_defaults(func=cmd_discover_models)
 
 
@@ -529,3 +392,9 @@ def cmd_discover_models(args):
     from .providers_discovery import cmd_discover_models as _impl
 
     return _impl(args)
+
+
+def cmd_deprecations(args):
+    from .providers_deprecations import cmd_deprecations as _impl
+
+    return _impl(args)
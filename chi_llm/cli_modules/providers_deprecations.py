@@ -0,0 +1,116 @@
+"""Deprecated and retired hosted models (split to keep file sizes small).
+
+The list ships with the package (``chi_llm/deprecated_models.json``);
+``providers deprecations --refresh`` downloads the current one into the
+cache, which is used from then on unless the bundled list is newer.
+"""
+
+from datetime import date
+from typing import Any, Dict, List, Optional, Tuple
+import json as _json
+import logging
+from pathlib import Path
+from urllib import request as _request
+from urllib.error import URLError, HTTPError
+
+BUNDLED = Path(__file__).resolve().parent.parent / "deprecated_models.json"
+DEFAULT_URL = (
+    "https://raw.githubusercontent.com/jacekjursza/chi_llm/main/"
+    "chi_llm/deprecated_models.json"
+)
+
+logger = logging.getLogger(__name__)
+
+
+def cache_path() -> Path:
+    return Path.home() / ".cache" / "chi_llm" / "deprecated_models.json"
+
+
+def _validate(data: Any) -> Dict[str, Any]:
+    """Raise ValueError unless ``data`` looks like a deprecations list."""
+    if not isinstance(data, dict) or not isinstance(data.get("models"), list):
+        raise ValueError("expected an object with a 'models' list")
+    for i, m in enumerate(data["models"]):
+        if not isinstance(m, dict):
+            raise ValueError(f"models[{i}] is not an object")
+        for key in ("type", "id"):
+            if not isinstance(m.get(key), str) or not m[key]:
+                raise ValueError(f"models[{i}] has no '{key}'")
+    return data
+
+
+def _read(path: Path) -> Optional[Dict[str, Any]]:
+    try:
+        return _validate(_json.loads(path.read_text(encoding="utf-8")))
+    except (OSError, ValueError) as e:
+        if path.exists():
+            logger.warning("ignoring %s: %s", path, e)
+        return None
+
+
+def load() -> Tuple[Dict[str, Any], str]:
+    """The list in effect and where it came from (``bundled`` or a path)."""
+    bundled = _read(BUNDLED) or {"models": []}
+    cached = _read(cache_path())
+    if cached and str(cached.get("updated", "")) >= str(bundled.get("updated", "")):
+        return cached, str(cache_path())
+    return bundled, "bundled"
+
+
+def entries(today: Optional[date] = None) -> List[Dict[str, Any]]:
+    """Each model with ``status``: ``retired`` once its date has passed,
+    ``deprecated`` before that or when no date is known."""
+    today_s = (today or date.today()).isoformat()
+    out = []
+    for m in load()[0]["models"]:
+        retires = m.get("retires")
+        retired = isinstance(retires, str) and retires <= today_s
+        out.append(
+            {
+                "type": m["type"],
+                "id": m["id"],
+                "retires": retires,
+                "replacement": m.get("replacement"),
+                "note": m.get("note"),
+                "status": "retired" if retired else "deprecated",
+            }
+        )
+    return out
+
+
+def refresh(url: str = DEFAULT_URL, timeout: float = 10.0) -> Dict[str, Any]:
+    """Download the list from ``url`` into the cache."""
+    try:
+        with _request.urlopen(url, timeout=timeout) as resp:
+            data = _validate(_json.loads(resp.read().decode("utf-8")))
+    except (URLError, HTTPError, ValueError) as e:
+        raise RuntimeError(f"could not refresh deprecated models from {url}: {e}")
+    path = cache_path()
+    path.parent.mkdir(parents=True, exist_ok=True)
+    path.write_text(_json.dumps(data, indent=2), encoding="utf-8")
+    logger.info("deprecated models refreshed: %d from %s", len(data["models"]), url)
+    return data
+
+
+def cmd_deprecations(args):
+    if getattr(args, "refresh", False):
+        refresh(getattr(args, "url", None) or DEFAULT_URL)
+    data, source = load()
+    models = entries()
+    if getattr(args, "json", False):
+        print(
+            _json.dumps(
+                {"updated": data.get("updated"), "source": source, "models": models},
+                indent=2,
+            )
+        )
+        return
+    updated = data.get("updated", "?")
+    print(f"Deprecated hosted models ({source}, updated {updated}):\n")
+    for m in models:
+        when = ""
+        if m.get("retires"):
+            verb = "on" if m["status"] == "retired" else "— retires"
+            when = f" {verb} {m['retires']}"
+        use = f" → {m['replacement']}" if m.get("replacement") else ""
+        print(f"• {m['type']}/{m['id']}: {m['status']}{when}{use}")
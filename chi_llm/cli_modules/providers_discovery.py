@@ -2,17 +2,111 @@
 
 from typing import Any
 import json as _json
+import logging
 import os
+import ssl
+import time
 from urllib import request as _request
 from urllib.error import URLError, HTTPError
 
+# Anthropic requires an explicit API version header on every request
+ANTHROPIC_VERSION = "2023-06-01"
+
+logger = logging.getLogger(__name__)
+
 
 def _print_json(obj: Any) -> None:
     print(_json.dumps(obj, indent=2))
 
 
+def _find_manifest(ptype: str):
+    from ..providers.manifests import load_manifests
+
+    for m in load_manifests()[0]:
+        if m["type"].lower() == ptype:
+            return m
+    return None
+
+
+def user_agent(args=None) -> str:
+    """``--user-agent``, then ``CHI_LLM_USER_AGENT``, then ``chi-llm/<version>``."""
+    from .. import __version__
+
+    agent = getattr(args, "user_agent", None) or os.environ.get("CHI_LLM_USER_AGENT")
+    return (agent or "").strip() or f"chi-llm/{__version__}"
+
+
+def tls_options(args=None) -> dict:
+    """``urlopen`` keyword arguments for ``--scheme https``: the ``--ca-cert``
+    bundle and ``--insecure``. Empty for plain http."""
+    if (getattr(args, "scheme", None) or "http").lower() != "https":
+        return {}
+    ctx = ssl.create_default_context(cafile=getattr(args, "ca_cert", None) or None)
+    if getattr(args, "insecure", False):
+        ctx.check_hostname = False
+        ctx.verify_mode = ssl.CERT_NONE
+    return {"context": ctx}
+
+
+def proxy_auth(args=None) -> dict:
+    """Headers for a token in front of LM Studio/Ollama: ``--api-key`` as a
+    bearer token, or as the value of ``--auth-header``."""
+    token = getattr(args, "api_key", None)
+    if not token:
+        return {}
+    header = (getattr(args, "auth_header", None) or "").strip()
+    return {header: token} if header else {"Authorization": f"Bearer {token}"}
+
+
+def _redact(name: str, value: str, auth_header: str = "") -> str:
+    n = name.lower()
+    secret = n in ("authorization", "cookie", "set-cookie", auth_header.lower())
+    if not (secret or "api-key" in n or "token" in n):
+        return value
+    scheme, _, rest = value.partition(" ")
+    return f"{scheme} ••••••" if rest else "••••••"
+
+
+def _send(args, req, **kwargs):
+    """``urlopen``, through ``--proxy`` when given. Otherwise urllib applies
+    the HTTP(S)_PROXY/NO_PROXY environment; NO_PROXY also exempts hosts
+    from ``--proxy``."""
+    proxy = getattr(args, "proxy", None)
+    if not proxy:
+        return _request.urlopen(req, **kwargs)
+    handlers = [_request.ProxyHandler({"http": proxy, "https": proxy})]
+    if "context" in kwargs:
+        handlers.append(_request.HTTPSHandler(context=kwargs.pop("context")))
+    return _request.build_opener(*handlers).open(req, **kwargs)
+
+
+def _open(args, req, **kwargs):
+    """Send ``req``; with ``--debug-requests`` the request and response
+    headers go to the log (secrets masked)."""
+    if not getattr(args, "debug_requests", False):
+        return _send(args, req, **kwargs)
+    auth_header = getattr(args, "auth_header", None) or ""
+    log = logging.getLogger("chi_llm.requests")
+    log.info("> %s %s", req.get_method(), req.full_url)
+    for k, v in req.header_items():
+        log.info("> %s: %s", k, _redact(k, v, auth_header))
+    start = time.monotonic()
+    try:
+        resp = _send(args, req, **kwargs)
+    except Exception as e:
+        log.info("< failed after %d ms: %s", (time.monotonic() - start) * 1000, e)
+        raise
+    log.info("< HTTP %s in %d ms", resp.status, (time.monotonic() - start) * 1000)
+    for k, v in dict(resp.headers or {}).items():
+        log.info("< %s: %s", k, _redact(k, v, auth_header))
+    return resp
+
+
 def cmd_discover_models(args):
     ptype = (getattr(args, "ptype", "") or "").strip().lower()
+    agent = user_agent(args)
+    https = (getattr(args, "scheme", None) or "").lower() == "https"
+    scheme = "https" if https else "http"
     host = getattr(args, "host", "localhost") or "localhost"
     port = getattr(args, "port", None)
     if port is not None:
@@ -22,6 +116,12 @@ def cmd_discover_models(args):
             pass
 
     def _out(obj):
+        if obj.get("error"):
+            logger.warning("discover-models %s: %s", ptype, obj["error"])
+        else:
+            logger.info(
+                "discover-models %s: %d models", ptype, len(obj.get("models") or [])
+            )
         if getattr(args, "json", False):
             _print_json(obj)
         else:
@@ -34,8 +134,10 @@ def cmd_discover_models(args):
     try:
         if ptype == "lmstudio":
             port = 1234 if port is None else port
-            url = f"http://{host}:{port}/v1/models"
-            with _request.urlopen(url, timeout=3) as resp:
+            url = f"{scheme}://{host}:{port}/v1/models"
+            headers = {"User-Agent": agent, **proxy_auth(args)}
+            req = _request.Request(url, headers=headers)
+            with _open(args, req, timeout=3, **tls_options(args)) as resp:
                 if resp.status != 200:
                     raise HTTPError(
                         url, resp.status, "HTTP error", hdrs=resp.headers, fp=None
@@ -50,8 +152,10 @@ def cmd_discover_models(args):
 
         if ptype == "ollama":
             port = 11434 if port is None else port
-            url = f"http://{host}:{port}/api/tags"
-            with _request.urlopen(url, timeout=3) as resp:
+            url = f"{scheme}://{host}:{port}/api/tags"
+            headers = {"User-Agent": agent, **proxy_auth(args)}
+            req = _request.Request(url, headers=headers)
+            with _open(args, req, timeout=3, **tls_options(args)) as resp:
                 if resp.status != 200:
                     raise HTTPError(
                         url, resp.status, "HTTP error", hdrs=resp.headers, fp=None
@@ -77,11 +181,11 @@ def cmd_discover_models(args):
                     {"provider": ptype, "error": "missing api_key", "models": []}
                 )
             url = f"{base_url.rstrip('/')}/v1/models"
-            req = _request.Request(url)
+            req = _request.Request(url, headers={"User-Agent": agent})
             req.add_header("Authorization", f"Bearer {api_key}")
             if org_id:
                 req.add_header("OpenAI-Organization", org_id)
-            with _request.urlopen(req, timeout=5) as resp:
+            with _open(args, req, timeout=5) as resp:
                 if resp.status != 200:
                     raise HTTPError(
                         url, resp.status, "HTTP error", hdrs=resp.headers, fp=None
@@ -94,8 +198,91 @@ def cmd_discover_models(args):
                     items.append({"id": mid})
             return _out({"provider": ptype, "models": items})
 
+        if ptype == "openai-compatible":
+            from ..providers.openai import v1_base_url
+
+            base_url = getattr(args, "base_url", None)
+            if not base_url:
+                return _out(
+                    {"provider": ptype, "error": "missing base_url", "models": []}
+                )
+            url = f"{v1_base_url(base_url)}/models"
+            req = _request.Request(
+                url, headers={"Accept": "application/json", "User-Agent": agent}
+            )
+            api_key = getattr(args, "api_key", None)
+            if api_key:
+                req.add_header("Authorization", f"Bearer {api_key}")
+            with _open(args, req, timeout=5) as resp:
+                if resp.status != 200:
+                    raise HTTPError(
+                        url, resp.status, "HTTP error", hdrs=resp.headers, fp=None
+                    )
+                try:
+                    data = _json.loads(resp.read().decode("utf-8"))
+                except ValueError:
+                    data = None
+            # Validate the shape so a random web server does not pass as an API
+            if not isinstance(data, dict) or not isinstance(data.get("data"), list):
+                return _out(
+                    {
+                        "provider": ptype,
+                        "error": f"{url} did not return an OpenAI model list",
+                        "models": [],
+                    }
+                )
+            items = []
+            for it in data["data"]:
+                mid = (it or {}).get("id") or ""
+                if mid:
+                    items.append({"id": mid, "name": it.get("name") or mid})
+            return _out({"provider": ptype, "models": items})
+
+        if ptype == "anthropic":
+            base_url = getattr(args, "base_url", None) or "https://api.anthropic.com"
+            api_key = getattr(args, "api_key", None) or os.environ.get(
+                "ANTHROPIC_API_KEY"
+            )
+            if not api_key:
+                return _out(
+                    {"provider": ptype, "error": "missing api_key", "models": []}
+                )
+            url = f"{base_url.rstrip('/')}/v1/models?limit=1000"
+            req = _request.Request(url, headers={"User-Agent": agent})
+            req.add_header("x-api-key", api_key)
+            req.add_header("anthropic-version", ANTHROPIC_VERSION)
+            with _open(args, req, timeout=5) as resp:
+                if resp.status != 200:
+                    raise HTTPError(
+                        url, resp.status, "HTTP error", hdrs=resp.headers, fp=None
+                    )
+                data = _json.loads(resp.read().decode("utf-8"))
+            items = []
+            for it in data.get("data") or []:
+                mid = it.get("id") or ""
+                if mid:
+                    items.append({"id": mid, "name": it.get("display_name") or mid})
+            return _out({"provider": ptype, "models": items})
+
+        manifest = _find_manifest(ptype)
+        if manifest and manifest.get("discovery"):
+            from ..providers.manifests import discovery_request, extract_models
+
+            url, headers = discovery_request(
+                manifest, host, port, getattr(args, "api_key", None)
+            )
+            req = _request.Request(url, headers={**headers, "User-Agent": agent})
+            with _open(args, req, timeout=5) as resp:
+                if resp.status != 200:
+                    raise HTTPError(
+                        url, resp.status, "HTTP error", hdrs=resp.headers, fp=None
+                    )
+                data = _json.loads(resp.read().decode("utf-8"))
+            return _out({"provider": ptype, "models": extract_models(manifest, data)})
+
         return _out({"provider": ptype, "models": []})
     except (URLError, HTTPError) as e:  # pragma: no cover - network dependent
+        logger.error("discover-models %s failed: %s", ptype, e)
         if getattr(args, "json", False):
             _print_json({"provider": ptype, "error": str(e), "models": []})
         else:
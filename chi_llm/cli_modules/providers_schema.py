@@ -0,0 +1,488 @@
+"""Provider types, field schemas and manifest helpers for the providers CLI
+(split to keep file sizes small)."""
+
+# `privacy` classifies where prompts go: local (never leaves the machine),
+# lan (a server on the network), cloud-paid or cloud-free (free tier). UIs
+# show it as a label and may sort private options first.
+SUPPORTED = [
+    {
+        "type": "local",
+        "implemented": True,
+        "privacy": "local",
+        "notes": "Default llama.cpp (legacy alias)",
+    },
+    {
+        "type": "local-zeroconfig",
+        "implemented": True,
+        "privacy": "local",
+        "notes": "Curated models (one-pick)",
+    },
+    {
+        "type": "local-custom",
+        "implemented": True,
+        "privacy": "local",
+        "notes": "Custom GGUF path and tuning",
+    },
+    {
+        "type": "lmstudio",
+        "implemented": True,
+        "privacy": "local",
+        "notes": "Local UI/server",
+    },
+    {
+        "type": "ollama",
+        "implemented": True,
+        "privacy": "local",
+        "notes": "Local server",
+    },
+    {
+        "type": "openai",
+        "implemented": True,
+        "privacy": "cloud-paid",
+        "notes": "API key required",
+    },
+    {
+        "type": "claude-cli",
+        "implemented": True,
+        "privacy": "cloud-paid",
+        "notes": "Anthropic CLI bridge",
+    },
+    {
+        "type": "openai-cli",
+        "implemented": True,
+        "privacy": "cloud-paid",
+        "notes": "OpenAI CLI bridge",
+    },
+    {
+        "type": "openai-compatible",
+        "implemented": True,
+        "privacy": "cloud-paid",
+        "notes": "Any OpenAI-style API: OpenRouter, Together, Groq, vLLM, llama.cpp",
+    },
+    {
+        "type": "anthropic",
+        "implemented": True,
+        "privacy": "cloud-paid",
+        "notes": "API key required",
+    },
+    {"type": "groq", "implemented": False, "privacy": "cloud-free"},
+    {"type": "gemini", "implemented": False, "privacy": "cloud-free"},
+]
+
+# Provider field schema for UI/automation. This reflects what `providers set`
+# accepts today. Extend here if CLI gains new fields. Fields marked
+# `advanced` are tuning knobs UIs may hide behind an expander; numeric fields
+# may carry `min`/`max`/`step` bounds for stepper widgets.
+PROVIDER_SCHEMAS = {
+    "local": {
+        "fields": [
+            {
+                "name": "model",
+                "type": "string",
+                "required": False,
+                "help": "Model ID (GGUF)",
+            },
+            {
+                "name": "model_path",
+                "type": "string",
+                "required": False,
+                "help": "Absolute path to GGUF model file",
+            },
+            {
+                "name": "context_window",
+                "type": "int",
+                "required": False,
+                "advanced": True,
+                "min": 512,
+                "max": 131072,
+                "step": 512,
+                "help": "Override context window (n_ctx)",
+            },
+            {
+                "name": "n_gpu_layers",
+                "type": "int",
+                "required": False,
+                "advanced": True,
+                "min": -1,
+                "max": 200,
+                "step": 1,
+                "help": "Layers offloaded to GPU",
+            },
+            {
+                "name": "output_tokens",
+                "type": "int",
+                "required": False,
+                "advanced": True,
+                "min": 1,
+                "max": 32768,
+                "step": 64,
+                "help": "Default max output tokens",
+            },
+        ]
+    },
+    "local-zeroconfig": {
+        "fields": [
+            {
+                "name": "model",
+                "type": "string",
+                "required": False,
+                "help": "Recommended model ID from curated list",
+            }
+        ]
+    },
+    "local-custom": {
+        "fields": [
+            {
+                "name": "model_path",
+                "type": "string",
+                "required": False,
+                "help": "Absolute path to GGUF model file",
+            },
+            {
+                "name": "model",
+                "type": "string",
+                "required": False,
+                "help": "Model ID (optional; overrides default)",
+            },
+            {
+                "name": "context_window",
+                "type": "int",
+                "required": False,
+                "advanced": True,
+                "min": 512,
+                "max": 131072,
+                "step": 512,
+                "help": "Override context window (n_ctx)",
+            },
+            {
+                "name": "n_gpu_layers",
+                "type": "int",
+                "required": False,
+                "advanced": True,
+                "min": -1,
+                "max": 200,
+                "step": 1,
+                "help": "Layers offloaded to GPU",
+            },
+            {
+                "name": "output_tokens",
+                "type": "int",
+                "required": False,
+                "advanced": True,
+                "min": 1,
+                "max": 32768,
+                "step": 64,
+                "help": "Default max output tokens",
+            },
+        ]
+    },
+    "lmstudio": {
+        "fields": [
+            {
+                "name": "host",
+                "type": "string",
+                "required": False,
+                "default": "localhost",
+                "help": "Server host",
+            },
+            {
+                "name": "port",
+                "type": "int",
+                "required": False,
+                "default": 1234,
+                "min": 1,
+                "max": 65535,
+                "step": 1,
+                "help": "Server port",
+            },
+            {
+                "name": "model",
+                "type": "string",
+                "required": False,
+                "help": "Default model ID",
+            },
+            {
+                "name": "timeout",
+                "type": "float",
+                "required": False,
+                "advanced": True,
+                "default": 30,
+                "min": 1,
+                "max": 600,
+                "step": 5,
+                "help": "Request timeout in seconds",
+            },
+        ]
+    },
+    "ollama": {
+        "fields": [
+            {
+                "name": "host",
+                "type": "string",
+                "required": False,
+                "default": "localhost",
+                "help": "Server host",
+            },
+            {
+                "name": "port",
+                "type": "int",
+                "required": False,
+                "default": 11434,
+                "min": 1,
+                "max": 65535,
+                "step": 1,
+                "help": "Server port",
+            },
+            {
+                "name": "model",
+                "type": "string",
+                "required": False,
+                "help": "Default model ID",
+            },
+            {
+                "name": "timeout",
+                "type": "float",
+                "required": False,
+                "advanced": True,
+                "default": 30,
+                "min": 1,
+                "max": 600,
+                "step": 5,
+                "help": "Request timeout in seconds",
+            },
+        ]
+    },
+    "openai": {
+        "fields": [
+            {
+                "name": "api_key",
+                "type": "secret",
+                "required": True,
+                "help": "OpenAI API key",
+            },
+            {
+                "name": "base_url",
+                "type": "string",
+                "required": False,
+                "help": "API base URL",
+            },
+            {
+                "name": "org_id",
+                "type": "string",
+                "required": False,
+                "advanced": True,
+                "help": "Organization ID",
+            },
+            {
+                "name": "model",
+                "type": "string",
+                "required": False,
+                "help": "Default model ID",
+            },
+            {
+                "name": "timeout",
+                "type": "float",
+                "required": False,
+                "advanced": True,
+                "default": 30,
+                "min": 1,
+                "max": 600,
+                "step": 5,
+                "help": "Request timeout in seconds",
+            },
+        ]
+    },
+    "openai-compatible": {
+        "fields": [
+            {
+                "name": "base_url",
+                "type": "string",
+                "required": True,
+                "help": "API base URL, e.g. https://openrouter.ai/api/v1 "
+                "or http://127.0.0.1:8000/v1",
+            },
+            {
+                "name": "api_key",
+                "type": "secret",
+                "required": False,
+                "help": "API key (leave empty for local servers)",
+            },
+            {
+                "name": "model",
+                "type": "string",
+                "required": True,
+                "help": "Model ID as listed by {base_url}/models",
+            },
+            {
+                "name": "timeout",
+                "type": "float",
+                "required": False,
+                "advanced": True,
+                "default": 30,
+                "min": 1,
+                "max": 600,
+                "step": 5,
+                "help": "Request timeout in seconds",
+            },
+        ]
+    },
+    "anthropic": {
+        "fields": [
+            {
+                "name": "api_key",
+                "type": "secret",
+                "required": True,
+                "help": "Anthropic API key",
+            },
+            {
+                "name": "model",
+                "type": "string",
+                "required": True,
+                "help": "Default model ID (e.g., claude-3-haiku-20240307)",
+            },
+            {
+                "name": "base_url",
+                "type": "string",
+                "required": False,
+                "advanced": True,
+                "help": "API base URL (default https://api.anthropic.com)",
+            },
+            {
+                "name": "timeout",
+                "type": "float",
+                "required": False,
+                "advanced": True,
+                "default": 30,
+                "min": 1,
+                "max": 600,
+                "step": 5,
+                "help": "Request timeout in seconds",
+            },
+        ]
+    },
+    "claude-cli": {
+        "fields": [
+            {
+                "name": "model",
+                "type": "string",
+                "required": False,
+                "help": "Default model ID (if applicable)",
+            },
+        ]
+    },
+    "openai-cli": {
+        "fields": [
+            {
+                "name": "model",
+                "type": "string",
+                "required": False,
+                "help": "Default model ID (if applicable)",
+            },
+        ]
+    },
+}
+
+# LM Studio and Ollama behind a reverse proxy (TLS, token auth)
+for _ptype in ("lmstudio", "ollama"):
+    PROVIDER_SCHEMAS[_ptype]["fields"] += [
+        {
+            "name": "scheme",
+            "type": "string",
+            "required": False,
+            "advanced": True,
+            "default": "http",
+            "options": ["http", "https"],
+            "help": "https when the server sits behind a TLS reverse proxy",
+        },
+        {
+            "name": "ca_cert",
+            "type": "string",
+            "required": False,
+            "advanced": True,
+            "help": "CA bundle (PEM) that signed the server's certificate",
+        },
+        {
+            "name": "insecure_tls",
+            "type": "string",
+            "required": False,
+            "advanced": True,
+            "default": "false",
+            "options": ["false", "true"],
+            "help": "Skip TLS certificate verification (testing only)",
+        },
+        {
+            "name": "api_key",
+            "type": "secret",
+            "required": False,
+            "advanced": True,
+            "help": "Proxy token, sent as 'Authorization: Bearer <token>'",
+        },
+        {
+            "name": "auth_header",
+            "type": "string",
+            "required": False,
+            "advanced": True,
+            "help": "Send the token in this header instead (e.g. X-API-Key); "
+            "for basic auth use Authorization with 'Basic <base64>'",
+        },
+    ]
+
+# Hosted APIs: a proxy for just this provider (corporate networks)
+for _ptype in ("openai", "openai-compatible", "anthropic"):
+    PROVIDER_SCHEMAS[_ptype]["fields"].append(
+        {
+            "name": "proxy",
+            "type": "string",
+            "required": False,
+            "advanced": True,
+            "help": "Proxy URL for this provider (default: HTTPS_PROXY)",
+        }
+    )
+
+# HTTP providers: User-Agent for gateways that allow-list clients, and
+# per-provider request logging for debugging one backend
+for _ptype in ("lmstudio", "ollama", "openai", "openai-compatible", "anthropic"):
+    PROVIDER_SCHEMAS[_ptype]["fields"] += [
+        {
+            "name": "user_agent",
+            "type": "string",
+            "required": False,
+            "advanced": True,
+            "help": "User-Agent header (default: chi-tui/<version>)",
+        },
+        {
+            "name": "debug_requests",
+            "type": "string",
+            "required": False,
+            "advanced": True,
+            "default": "false",
+            "options": ["false", "true"],
+            "help": "Log each request and response (secrets masked)",
+        },
+    ]
+
+
+def _manifests():
+    """Provider manifests from providers.d whose type is not built in."""
+    try:
+        from ..providers.manifests import load_manifests
+    except Exception:  # pragma: no cover - defensive
+        return [], []
+    builtin = {p["type"] for p in SUPPORTED}
+    manifests, errors = load_manifests()
+    return [m for m in manifests if m["type"] not in builtin], errors
+
+
+def _all_supported():
+    out = list(SUPPORTED)
+    for m in _manifests()[0]:
+        out.append(
+            {
+                "type": m["type"],
+                "implemented": True,
+                "notes": m.get("notes") or "External manifest",
+                "privacy": m.get("privacy"),
+                "source": m["source"],
+            }
+        )
+    return out
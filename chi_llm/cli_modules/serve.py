@@ -0,0 +1,70 @@
+"""
+`chi-llm serve`: run the OpenAI-compatible API server.
+"""
+
+from argparse import _SubParsersAction
+import os
+import threading
+
+
+def _start_debug(port: int):
+    from ..server import create_debug_server
+
+    debug = create_debug_server(port)
+    threading.Thread(target=debug.serve_forever, daemon=True).start()
+    host, port = debug.server_address[:2]
+    print(f"🔍 debug endpoints on http://{host}:{port}/debug", flush=True)
+    return debug
+
+
+def cmd_serve(args):
+    if args.stdio:
+        from .serve_stdio import serve
+
+        return serve()
+
+    from ..core import MicroLLM
+    from ..server import create_server
+
+    debug = _start_debug(args.debug_port) if args.debug_port is not None else None
+
+    # Prefer the env var so the token does not show up in process listings
+    token = args.token or os.environ.get("CHI_LLM_SERVER_TOKEN") or None
+    llm = MicroLLM(temperature=args.temperature, max_tokens=args.max_tokens)
+    httpd = create_server(llm, host=args.host, port=args.port, token=token)
+    host, port = httpd.server_address[:2]
+    auth = "bearer token required" if token else "no auth"
+    print(f"🚀 chi_llm API server on http://{host}:{port}/v1 ({auth})", flush=True)
+    try:
+        httpd.serve_forever()
+    finally:
+        httpd.server_close()
+        if debug is not None:
+            debug.shutdown()
+            debug.server_close()
+
+
+def register(subparsers: _SubParsersAction):
+    sub = subparsers.add_parser(
+        "serve", help="Run an OpenAI-compatible API server (/v1/chat/completions)"
+    )
+    sub.add_argument("--host", default="127.0.0.1", help="Bind address")
+    sub.add_argument("--port", type=int, default=8765, help="Port (0 = any free)")
+    sub.add_argument(
+        "--token",
+        help="Require 'Authorization: Bearer <token>' (or set CHI_LLM_SERVER_TOKEN)",
+    )
+    sub.add_argument("--temperature", type=float, default=0.7)
+    sub.add_argument("--max-tokens", type=int, default=4096)
+    sub.add_argument(
+        "--debug-port",
+        type=int,
+        metavar="PORT",
+        help="Also serve thread/memory debug endpoints on 127.0.0.1:PORT (0 = any free)",
+    )
+    sub.add_argument(
+        "--stdio",
+        action="store_true",
+        help="Answer JSON-RPC CLI calls on stdin/stdout instead (used by chi-tui)",
+    )
+    sub.set_defaults(func=cmd_serve)
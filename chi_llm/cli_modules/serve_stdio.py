@@ -0,0 +1,90 @@
+"""`chi-llm serve --stdio`: run CLI commands for chi-tui in one process.
+
+chi-tui calls the CLI many times (schema, discovery, tags); each new
+process pays Python's startup. With ``--stdio`` it keeps one process and
+sends JSON-RPC 2.0 requests, one per line on stdin, answered one per line
+on stdout:
+
+- ``chi.hello`` returns ``{"version": ...}`` (chi-tui's support check)
+- ``cli.run`` with ``{"args": [...], "cwd": "..."}`` runs ``chi-llm <args>``
+  in ``cwd`` and returns ``{"code": int, "stdout": str, "stderr": str}``,
+  what a separate process would have exited with and printed.
+
+The loop ends when stdin closes.
+"""
+
+import contextlib
+import io
+import json as _json
+import logging
+import os
+import sys
+from typing import Any, Dict, Optional, TextIO
+
+VERSION = "chi_llm 2.1.0"
+
+logger = logging.getLogger(__name__)
+
+
+def run_cli(args, cwd: Optional[str] = None) -> Dict[str, Any]:
+    """``chi-llm <args>`` in this process, with its output captured."""
+    from ..cli_main import main
+
+    out, err = io.StringIO(), io.StringIO()
+    code = 0
+    old_cwd = os.getcwd()
+    try:
+        os.chdir(cwd or old_cwd)
+    except OSError as e:
+        return {"code": 1, "stdout": "", "stderr": f"{e}\n"}
+    # A prompt must not read the next request: commands see an empty stdin
+    old_stdin, sys.stdin = sys.stdin, io.StringIO()
+    try:
+        with contextlib.redirect_stdout(out), contextlib.redirect_stderr(err):
+            main([str(a) for a in args])
+    except SystemExit as e:
+        if isinstance(e.code, str):
+            err.write(e.code)
+        code = e.code if isinstance(e.code, int) else (0 if e.code is None else 1)
+    finally:
+        sys.stdin = old_stdin
+        os.chdir(old_cwd)
+    return {"code": code, "stdout": out.getvalue(), "stderr": err.getvalue()}
+
+
+def _error(rid: Any, code: int, message: str) -> Dict[str, Any]:
+    return {"jsonrpc": "2.0", "id": rid, "error": {"code": code, "message": message}}
+
+
+def handle(line: str) -> Dict[str, Any]:
+    """The response to one request line."""
+    try:
+        req = _json.loads(line)
+    except ValueError as e:
+        return _error(None, -32700, f"parse error: {e}")
+    if not isinstance(req, dict):
+        return _error(None, -32600, "expected a request object")
+    rid, method = req.get("id"), req.get("method")
+    params = req.get("params") or {}
+    if method == "chi.hello":
+        result: Dict[str, Any] = {"version": VERSION}
+    elif method == "cli.run":
+        args = params.get("args")
+        if not isinstance(args, list):
+            return _error(rid, -32602, "params.args must be a list")
+        result = run_cli(args, params.get("cwd"))
+    else:
+        return _error(rid, -32601, f"unknown method: {method}")
+    return {"jsonrpc": "2.0", "id": rid, "result": result}
+
+
+def serve(stdin: Optional[TextIO] = None, stdout: Optional[TextIO] = None) -> None:
+    """Answer requests until stdin closes."""
+    stdin, stdout = stdin or sys.stdin, stdout or sys.stdout
+    logger.info("stdio bridge started (pid %d)", os.getpid())
+    for line in stdin:
+        if not line.strip():
+            continue
+        stdout.write(_json.dumps(handle(line)) + "\n")
+        stdout.flush()
+    logger.info("stdio bridge stopped")
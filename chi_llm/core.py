@@ -141,6 +141,27 @@ class MicroLLM:
                         self._provider = None
                         self._provider_type = "openai"
                         self._provider_error = str(e)
+                elif provider.get("type") == "openai-compatible":
+                    # Any OpenAI-style endpoint; key optional, base_url required
+                    try:
+                        from .providers.openai import OpenAIProvider, v1_base_url
+
+                        if not provider.get("base_url"):
+                            raise RuntimeError(
+                                "openai-compatible provider requires 'base_url'."
+                            )
+                        self._provider = OpenAIProvider(
+                            api_key=str(provider.get("api_key") or ""),
+                            model=provider.get("model"),
+                            base_url=v1_base_url(str(provider.get("base_url"))),
+                            timeout=float(provider.get("timeout", 30.0)),
+                            require_api_key=False,
+                        )
+                        self._provider_type = "openai-compatible"
+                    except Exception as e:
+                        self._provider = None
+                        self._provider_type = "openai-compatible"
+                        self._provider_error = str(e)
                 elif provider.get("type") == "anthropic":
                     # Initialize Anthropic provider; defer failures
                     try:
@@ -150,6 +171,7 @@ class MicroLLM:
                             api_key=str(provider.get("api_key", "")),
                             model=provider.get("model"),
                             timeout=float(provider.get("timeout", 30.0)),
+                            base_url=provider.get("base_url"),
                         )
                         self._provider_type = "anthropic"
                     except Exception as e:
@@ -7,7 +7,7 @@ Changes:
 - Zero-config default model can be controlled from YAML (key: zero_config_default).
 """
 
-from dataclasses import dataclass
+from dataclasses import dataclass, field
 from typing import Dict, List, Optional, Tuple
 from pathlib import Path
 import json
@@ -38,6 +38,8 @@ class ModelInfo:
     # Optional tuning defaults
     n_gpu_layers: int = 0
     output_tokens: int = 4096
+    # Fallback sources: {"url": ...} or {"repo": ..., "filename": ...}
+    mirrors: List[Dict[str, str]] = field(default_factory=list)
 
 
 def _load_yaml_registry() -> Tuple[Dict[str, ModelInfo], Optional[str]]:
@@ -80,6 +82,11 @@ def _load_yaml_registry() -> Tuple[Dict[str, ModelInfo], Optional[str]]:
                     tags=list(item.get("tags", []) or []),
                     n_gpu_layers=int(item.get("n_gpu_layers", 0) or 0),
                     output_tokens=int(item.get("output_tokens", 4096) or 4096),
+                    mirrors=[
+                        {str(k): str(v) for k, v in m.items()}
+                        for m in (item.get("mirrors") or [])
+                        if isinstance(m, dict)
+                    ],
                 )
                 models[mi.id] = mi
             except Exception:
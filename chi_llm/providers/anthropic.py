@@ -7,6 +7,7 @@ Implements generate/chat/complete using Messages API.
 Configuration:
 - api_key: str (required)
 - model: str (required), e.g., "claude-3-haiku-20240307"
+- base_url: str (optional), API root for proxies/gateways
 - timeout: float (optional)
 """
 
@@ -21,10 +22,12 @@ class AnthropicProvider:
         api_key: Optional[str] = None,
         model: Optional[str] = None,
         timeout: float = 30.0,
+        base_url: Optional[str] = None,
     ) -> None:
         self.api_key = (api_key or "").strip()
         self.model = (model or "").strip()
         self.timeout = timeout
+        self.base_url = (base_url or "").strip() or None
         if not self.api_key:
             raise RuntimeError(
                 "Anthropic provider requires an API key. Set CHI_LLM_PROVIDER_API_KEY "
@@ -43,7 +46,10 @@ class AnthropicProvider:
             import anthropic  # type: ignore
 
             # SDK v0.30+: instantiate client with api_key
-            self._client = anthropic.Anthropic(api_key=self.api_key)
+            kwargs = {"api_key": self.api_key}
+            if self.base_url:
+                kwargs["base_url"] = self.base_url
+            self._client = anthropic.Anthropic(**kwargs)
             return self._client
         except Exception as e:  # pragma: no cover - depends on env
             raise RuntimeError(
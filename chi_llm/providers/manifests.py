@@ -0,0 +1,192 @@
+"""
+External provider manifests.
+
+Drop JSON or YAML files into ``~/.config/chi_llm/providers.d/`` (or the
+directory named by ``CHI_LLM_PROVIDERS_DIR``) to describe additional provider
+types without changing chi_llm itself. Example (YAML)::
+
+    type: vllm
+    notes: vLLM OpenAI-compatible server
+    privacy: lan         # local | lan | cloud-paid | cloud-free
+    default_port: 8000
+    fields:
+      - {name: model, type: string, help: Served model name}
+    discovery:
+      url: "http://{host}:{port}/v1/models"
+      items: data        # dotted path to the list in the response
+      id_key: id
+    auth:
+      style: bearer      # bearer | header | none
+      field: api_key     # config field holding the secret
+      header: X-API-Key  # only for style: header
+
+Manifests describe configuration and model discovery only; built-in types
+always take precedence over a manifest declaring the same ``type``.
+"""
+
+from __future__ import annotations
+
+from pathlib import Path
+from typing import Any, Dict, List, Optional, Tuple
+import json
+import os
+
+MANIFEST_SUFFIXES = (".json", ".yaml", ".yml")
+AUTH_STYLES = ("none", "bearer", "header")
+PRIVACY_CLASSES = ("local", "lan", "cloud-paid", "cloud-free")
+
+
+def manifest_dir() -> Path:
+    env = os.environ.get("CHI_LLM_PROVIDERS_DIR")
+    if env:
+        return Path(env).expanduser()
+    return Path.home() / ".config" / "chi_llm" / "providers.d"
+
+
+def _read(path: Path) -> Any:
+    text = path.read_text(encoding="utf-8")
+    if path.suffix == ".json":
+        return json.loads(text)
+    try:
+        import yaml  # type: ignore
+    except ModuleNotFoundError as e:  # pragma: no cover - pyyaml is a dependency
+        raise ValueError("PyYAML is required for YAML manifests") from e
+    return yaml.safe_load(text)
+
+
+def _validate(raw: Any) -> Dict[str, Any]:
+    if not isinstance(raw, dict):
+        raise ValueError("manifest must be a mapping")
+    ptype = raw.get("type")
+    if not isinstance(ptype, str) or not ptype.strip():
+        raise ValueError("missing 'type'")
+    fields = raw.get("fields") or []
+    if not isinstance(fields, list):
+        raise ValueError("'fields' must be a list")
+    for f in fields:
+        if not isinstance(f, dict) or not f.get("name"):
+            raise ValueError("each field needs a 'name'")
+    port = raw.get("default_port")
+    if port is not None and not isinstance(port, int):
+        raise ValueError("'default_port' must be an integer")
+    disc = raw.get("discovery")
+    if disc is not None and (not isinstance(disc, dict) or not disc.get("url")):
+        raise ValueError("'discovery' needs a 'url' template")
+    auth = raw.get("auth") or {"style": "none"}
+    if not isinstance(auth, dict) or auth.get("style", "none") not in AUTH_STYLES:
+        raise ValueError(f"'auth.style' must be one of {', '.join(AUTH_STYLES)}")
+    if auth.get("style") == "header" and not auth.get("header"):
+        raise ValueError("'auth.header' is required for style 'header'")
+    privacy = raw.get("privacy")
+    if privacy is not None and privacy not in PRIVACY_CLASSES:
+        raise ValueError(f"'privacy' must be one of {', '.join(PRIVACY_CLASSES)}")
+    return {
+        "type": ptype.strip(),
+        "notes": raw.get("notes"),
+        "privacy": privacy,
+        "default_port": port,
+        "fields": fields,
+        "discovery": disc,
+        "auth": auth,
+    }
+
+
+def load_manifests(
+    directory: Optional[Path] = None,
+) -> Tuple[List[Dict[str, Any]], List[str]]:
+    """Load all manifests. Returns (manifests, errors); invalid files are
+    reported in errors and skipped. Later files lose on duplicate types."""
+    d = directory or manifest_dir()
+    manifests: List[Dict[str, Any]] = []
+    errors: List[str] = []
+    if not d.is_dir():
+        return manifests, errors
+    seen = set()
+    for path in sorted(d.iterdir()):
+        if path.suffix not in MANIFEST_SUFFIXES:
+            continue
+        try:
+            m = _validate(_read(path))
+        except Exception as e:
+            errors.append(f"{path.name}: {e}")
+            continue
+        if m["type"] in seen:
+            errors.append(f"{path.name}: duplicate type '{m['type']}'")
+            continue
+        seen.add(m["type"])
+        m["source"] = str(path)
+        manifests.append(m)
+    return manifests, errors
+
+
+def manifest_fields(m: Dict[str, Any]) -> List[Dict[str, Any]]:
+    """UI field schema: declared fields plus implied host/port and secret."""
+    fields = [dict(f) for f in m.get("fields") or []]
+    for f in fields:
+        f.setdefault("type", "string")
+        f.setdefault("required", False)
+    names = {f["name"] for f in fields}
+    implied: List[Dict[str, Any]] = []
+    if m.get("default_port") is not None:
+        if "host" not in names:
+            implied.append(
+                {
+                    "name": "host",
+                    "type": "string",
+                    "required": False,
+                    "default": "localhost",
+                    "help": "Server host",
+                }
+            )
+        if "port" not in names:
+            implied.append(
+                {
+                    "name": "port",
+                    "type": "int",
+                    "required": False,
+                    "default": m["default_port"],
+                    "help": "Server port",
+                }
+            )
+    auth = m.get("auth") or {}
+    secret = auth.get("field", "api_key")
+    if auth.get("style", "none") != "none" and secret not in names:
+        implied.append(
+            {"name": secret, "type": "secret", "required": True, "help": "API key"}
+        )
+    return implied + fields
+
+
+def discovery_request(
+    m: Dict[str, Any],
+    host: str,
+    port: Optional[int],
+    api_key: Optional[str] = None,
+) -> Tuple[str, Dict[str, str]]:
+    """Expand the discovery URL template and build auth headers."""
+    disc = m.get("discovery") or {}
+    port = port if port is not None else m.get("default_port")
+    url = str(disc["url"]).format(host=host, port=port)
+    headers = {"Accept": "application/json"}
+    auth = m.get("auth") or {}
+    if api_key:
+        if auth.get("style") == "bearer":
+            headers["Authorization"] = f"Bearer {api_key}"
+        elif auth.get("style") == "header":
+            headers[str(auth["header"])] = api_key
+    return url, headers
+
+
+def extract_models(m: Dict[str, Any], data: Any) -> List[Dict[str, str]]:
+    """Pick model ids out of a discovery response using ``items``/``id_key``."""
+    disc = m.get("discovery") or {}
+    items: Any = data
+    for part in [p for p in str(disc.get("items", "data")).split(".") if p]:
+        items = items.get(part) if isinstance(items, dict) else None
+    id_key = disc.get("id_key", "id")
+    out: List[Dict[str, str]] = []
+    for it in items or []:
+        mid = it.get(id_key) if isinstance(it, dict) else it
+        if isinstance(mid, str) and mid:
+            out.append({"id": mid})
+    return out
@@ -10,6 +10,10 @@ Configuration:
 - base_url: str (optional; for custom endpoints). If not provided,
   tries to derive from `host` if it looks like a URL.
 - timeout: float (optional, default 30s)
+
+The same class backs the `openai-compatible` type (OpenRouter, Together,
+Groq, vLLM, llama.cpp server, ...), where base_url is required and the API
+key is optional.
 """
 
 from __future__ import annotations
@@ -17,6 +21,13 @@ from __future__ import annotations
 from typing import Dict, List, Optional
 
 
+def v1_base_url(url: str) -> str:
+    """Base URL ending in /v1, as the SDK expects ("http://h:8000" and
+    "https://openrouter.ai/api/v1" both work)."""
+    url = url.strip().rstrip("/")
+    return url if url.endswith("/v1") else f"{url}/v1"
+
+
 class OpenAIProvider:
     def __init__(
         self,
@@ -26,6 +37,7 @@ class OpenAIProvider:
         host: Optional[str] = None,
         port: Optional[int | str] = None,
         timeout: float = 30.0,
+        require_api_key: bool = True,
     ) -> None:
         self.api_key = (api_key or "").strip()
         self.model = (model or "").strip()
@@ -39,7 +51,7 @@ class OpenAIProvider:
         else:
             self.base_url = None
 
-        if not self.api_key:
+        if not self.api_key and require_api_key:
             raise RuntimeError(
                 "OpenAI provider requires an API key. Set CHI_LLM_PROVIDER_API_KEY "
                 "or use 'chi-llm providers set openai --api-key ...'."
@@ -58,7 +70,10 @@ class OpenAIProvider:
             # New-style SDK (>=1.0)
             from openai import OpenAI  # type: ignore
 
-            self._client = OpenAI(api_key=self.api_key, base_url=self.base_url)
+            # Keyless local servers still need a non-empty key for the SDK
+            self._client = OpenAI(
+                api_key=self.api_key or "not-needed", base_url=self.base_url
+            )
             return self._client
         except Exception:
             # Old SDK fallback
@@ -134,6 +134,18 @@ def default_registry() -> Dict[str, Callable[[Dict[str, Any]], Any]]:
         )
     except Exception:
         pass
+    try:
+        from .openai import OpenAIProvider, v1_base_url  # type: ignore
+
+        reg["openai-compatible"] = lambda prof: OpenAIProvider(
+            api_key=str(prof.get("api_key") or ""),
+            model=prof.get("model"),
+            base_url=v1_base_url(str(prof.get("base_url") or "")),
+            timeout=float(prof.get("timeout", 30.0)),
+            require_api_key=False,
+        )
+    except Exception:
+        pass
     try:
         from .anthropic import AnthropicProvider  # type: ignore
 
@@ -141,6 +153,7 @@ def default_registry() -> Dict[str, Callable[[Dict[str, Any]], Any]]:
             api_key=str(prof.get("api_key", "")),
             model=prof.get("model"),
             timeout=float(prof.get("timeout", 30.0)),
+            base_url=prof.get("base_url"),
         )
     except Exception:
         pass
@@ -0,0 +1,238 @@
+"""
+Minimal OpenAI-compatible HTTP server backed by chi_llm.
+
+Exposes ``GET /health``, ``GET /v1/models`` and ``POST /v1/chat/completions``
+using only the standard library. The backing provider is whatever
+``MicroLLM`` resolves from configuration (including ``CHI_LLM_CONFIG``), so
+the same server can front a local GGUF model or a remote provider.
+
+When a token is set, every ``/v1`` request must send
+``Authorization: Bearer <token>``.
+
+``create_debug_server`` is an optional, localhost-only companion for
+long-running ``serve`` processes: thread stacks, allocation sites and object
+counts as plain text, for chasing leaks and stuck threads.
+"""
+
+from __future__ import annotations
+
+from http.server import BaseHTTPRequestHandler, ThreadingHTTPServer
+from collections import Counter
+from typing import Any, Dict, List, Optional, Tuple
+import gc
+import hmac
+import json
+import sys
+import threading
+import time
+import traceback
+import tracemalloc
+import uuid
+
+MODEL_NAME = "chi_llm"
+
+
+def _split_messages(
+    messages: List[Dict[str, Any]],
+) -> Tuple[str, List[Dict[str, str]]]:
+    """Map OpenAI messages to (last user message, MicroLLM history pairs).
+    System messages are prepended to the final user message."""
+    system = [str(m.get("content", "")) for m in messages if m.get("role") == "system"]
+    turns = [m for m in messages if m.get("role") in ("user", "assistant")]
+    if not turns or turns[-1].get("role") != "user":
+        raise ValueError("messages must end with a user message")
+    history: List[Dict[str, str]] = []
+    pending: Optional[str] = None
+    for m in turns[:-1]:
+        if m["role"] == "user":
+            pending = str(m.get("content", ""))
+        elif pending is not None:
+            history.append({"user": pending, "assistant": str(m.get("content", ""))})
+            pending = None
+    prompt = str(turns[-1].get("content", ""))
+    if system:
+        prompt = "\n\n".join(system + [prompt])
+    return prompt, history
+
+
+def make_handler(llm: Any, token: Optional[str] = None, log=None):
+    """Build a request handler class serving ``llm`` (anything with
+    ``chat(message, history=...)``)."""
+    log = log or (lambda line: print(line, flush=True))
+
+    class Handler(BaseHTTPRequestHandler):
+        server_version = "chi_llm"
+
+        def log_message(self, fmt, *args):  # route access log through `log`
+            log(f"{self.address_string()} {fmt % args}")
+
+        def _send(self, status: int, body: Dict[str, Any]) -> None:
+            data = json.dumps(body).encode("utf-8")
+            self.send_response(status)
+            self.send_header("Content-Type", "application/json")
+            self.send_header("Content-Length", str(len(data)))
+            self.end_headers()
+            self.wfile.write(data)
+
+        def _error(self, status: int, message: str) -> None:
+            self._send(status, {"error": {"message": message}})
+
+        def _authorized(self) -> bool:
+            if not token:
+                return True
+            got = self.headers.get("Authorization", "")
+            return hmac.compare_digest(got, f"Bearer {token}")
+
+        def do_GET(self):  # noqa: N802 - http.server API
+            if self.path == "/health":
+                return self._send(200, {"status": "ok"})
+            if not self._authorized():
+                return self._error(401, "invalid or missing bearer token")
+            if self.path == "/v1/models":
+                return self._send(
+                    200,
+                    {
+                        "object": "list",
+                        "data": [{"id": MODEL_NAME, "object": "model"}],
+                    },
+                )
+            self._error(404, f"unknown path {self.path}")
+
+        def do_POST(self):  # noqa: N802 - http.server API
+            if not self._authorized():
+                return self._error(401, "invalid or missing bearer token")
+            if self.path != "/v1/chat/completions":
+                return self._error(404, f"unknown path {self.path}")
+            try:
+                length = int(self.headers.get("Content-Length") or 0)
+                req = json.loads(self.rfile.read(length) or b"{}")
+                prompt, history = _split_messages(req.get("messages") or [])
+            except (ValueError, json.JSONDecodeError) as e:
+                return self._error(400, str(e))
+            try:
+                text = llm.chat(prompt, history=history)
+            except Exception as e:
+                return self._error(502, f"generation failed: {e}")
+            self._send(
+                200,
+                {
+                    "id": f"chatcmpl-{uuid.uuid4().hex[:12]}",
+                    "object": "chat.completion",
+                    "created": int(time.time()),
+                    "model": req.get("model") or MODEL_NAME,
+                    "choices": [
+                        {
+                            "index": 0,
+                            "message": {"role": "assistant", "content": text},
+                            "finish_reason": "stop",
+                        }
+                    ],
+                },
+            )
+
+    return Handler
+
+
+def create_server(
+    llm: Any,
+    host: str = "127.0.0.1",
+    port: int = 8765,
+    token: Optional[str] = None,
+) -> ThreadingHTTPServer:
+    """Bind the server (not yet serving); call ``serve_forever()`` on it."""
+    return ThreadingHTTPServer((host, port), make_handler(llm, token))
+
+
+DEBUG_PATHS = {
+    "/debug/threads": "stack of every thread",
+    "/debug/memory": "top allocation sites, and growth since the previous call",
+    "/debug/objects": "live objects by type and gc generation counts",
+}
+
+
+def _thread_dump() -> str:
+    names = {t.ident: t for t in threading.enumerate()}
+    out = []
+    for ident, frame in sys._current_frames().items():
+        t = names.get(ident)
+        label = f"{t.name}{' (daemon)' if t.daemon else ''}" if t else "unknown"
+        out.append(f"Thread {label} [{ident}]:")
+        out.append("".join(traceback.format_stack(frame)).rstrip())
+        out.append("")
+    return "\n".join(out)
+
+
+def _memory_report(state: Dict[str, Any], limit: int = 25) -> str:
+    if not tracemalloc.is_tracing():
+        return "tracemalloc is not running\n"
+    snap = tracemalloc.take_snapshot().filter_traces(
+        [tracemalloc.Filter(False, tracemalloc.__file__)]
+    )
+    current, peak = tracemalloc.get_traced_memory()
+    out = [f"traced: {current / 1e6:.1f} MB (peak {peak / 1e6:.1f} MB)", ""]
+    out.append(f"Top {limit} allocation sites:")
+    out += [str(s) for s in snap.statistics("lineno")[:limit]]
+    last = state.get("last")
+    if last is not None:
+        out += ["", "Growth since the previous call:"]
+        grown = [d for d in snap.compare_to(last, "lineno") if d.size_diff > 0]
+        out += [str(d) for d in grown[:limit]] or ["(none)"]
+    state["last"] = snap
+    return "\n".join(out) + "\n"
+
+
+def _objects_report(limit: int = 30) -> str:
+    counts = Counter(type(o).__name__ for o in gc.get_objects())
+    out = [
+        f"gc generation counts: {gc.get_count()}",
+        f"threads: {threading.active_count()}",
+        "",
+        f"Top {limit} object types:",
+    ]
+    out += [f"{n:>10}  {name}" for name, n in counts.most_common(limit)]
+    return "\n".join(out) + "\n"
+
+
+def make_debug_handler(log=None):
+    """Request handler for the ``/debug`` endpoints (see ``DEBUG_PATHS``)."""
+    log = log or (lambda line: print(line, flush=True))
+    state: Dict[str, Any] = {}
+
+    class DebugHandler(BaseHTTPRequestHandler):
+        server_version = "chi_llm-debug"
+
+        def log_message(self, fmt, *args):
+            log(f"debug {self.address_string()} {fmt % args}")
+
+        def _text(self, status: int, text: str) -> None:
+            data = text.encode("utf-8")
+            self.send_response(status)
+            self.send_header("Content-Type", "text/plain; charset=utf-8")
+            self.send_header("Content-Length", str(len(data)))
+            self.end_headers()
+            self.wfile.write(data)
+
+        def do_GET(self):  # noqa: N802 - http.server API
+            path = self.path.split("?", 1)[0].rstrip("/")
+            if path == "/debug/threads":
+                return self._text(200, _thread_dump())
+            if path == "/debug/memory":
+                return self._text(200, _memory_report(state))
+            if path == "/debug/objects":
+                return self._text(200, _objects_report())
+            if path in ("", "/debug"):
+                lines = [f"{p}  {what}" for p, what in DEBUG_PATHS.items()]
+                return self._text(200, "\n".join(lines) + "\n")
+            self._text(404, f"unknown path {self.path}\n")
+
+    return DebugHandler
+
+
+def create_debug_server(port: int = 6060, log=None) -> ThreadingHTTPServer:
+    """Bind the debug endpoints on 127.0.0.1 only (they expose stacks and
+    allocation sites, never the API) and start tracing allocations."""
+    if not tracemalloc.is_tracing():
+        tracemalloc.start()
+    httpd = ThreadingHTTPServer(("127.0.0.1", port), make_debug_handler(log))
+    httpd.daemon_threads = True
+    return httpd
@@ -0,0 +1,69 @@
+"""
+Client for a running chi-tui's control socket.
+
+Each chi-tui publishes ``~/.cache/chi_llm/instances/<hash>.json`` with its
+pid, a loopback port and a token. Requests are one JSON line each way, e.g.
+``{"token": "...", "cmd": "download.status", "id": "phi3-mini"}``.
+"""
+
+import json
+import socket
+from pathlib import Path
+from typing import Any, Dict, List, Optional
+
+INSTANCES_DIR = Path.home() / ".cache" / "chi_llm" / "instances"
+
+
+class BridgeError(Exception):
+    """The instance answered with an error or could not be reached."""
+
+
+def _load(path: Path) -> Optional[Dict[str, Any]]:
+    try:
+        info = json.loads(path.read_text(encoding="utf-8"))
+    except (OSError, ValueError):
+        return None
+    if not isinstance(info, dict) or "port" not in info or "token" not in info:
+        return None
+    return info
+
+
+def instances(directory: Optional[Path] = None) -> List[Dict[str, Any]]:
+    """Published instances, newest first. Files of crashed instances may
+    linger; `find_instance` pings before trusting one."""
+    directory = directory or INSTANCES_DIR
+    found = [info for p in directory.glob("*.json") if (info := _load(p))]
+    return sorted(found, key=lambda i: str(i.get("started", "")), reverse=True)
+
+
+def request(
+    info: Dict[str, Any], cmd: str, timeout: float = 5.0, **params: Any
+) -> Dict[str, Any]:
+    """Send one command and return the reply; errors raise BridgeError."""
+    payload = {"token": info["token"], "cmd": cmd, **params}
+    try:
+        with socket.create_connection(
+            ("127.0.0.1", int(info["port"])), timeout=timeout
+        ) as sock:
+            sock.sendall((json.dumps(payload) + "\n").encode("utf-8"))
+            line = sock.makefile("r", encoding="utf-8").readline()
+    except OSError as e:
+        raise BridgeError(f"chi-tui (pid {info.get('pid')}) unreachable: {e}") from e
+    try:
+        reply = json.loads(line)
+    except ValueError as e:
+        raise BridgeError(f"bad reply from chi-tui: {line!r}") from e
+    if not reply.get("ok"):
+        raise BridgeError(reply.get("error") or "request failed")
+    return reply
+
+
+def find_instance(directory: Optional[Path] = None) -> Optional[Dict[str, Any]]:
+    """The first published instance that answers a ping."""
+    for info in instances(directory):
+        try:
+            request(info, "ping", timeout=1.0)
+        except BridgeError:
+            continue
+        return info
+    return None
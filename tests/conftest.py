@@ -16,6 +16,8 @@ def _offline_and_fast_llama(monkeypatch, tmp_path_factory):
     """
     # Disable any telemetry
     monkeypatch.setenv("HF_HUB_DISABLE_TELEMETRY", "1")
+    # CLI runs must not append to the user's ~/.cache/chi_llm/chi-llm.log
+    monkeypatch.setenv("CHI_LLM_LOG_FILE", "off")
 
     # Temp model file path returned by mocked download
     tmp_dir = tmp_path_factory.mktemp("models")
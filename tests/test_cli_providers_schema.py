@@ -28,3 +28,39 @@ def test_providers_schema_json_shape():
 
     openai_fields = {f.get("name") for f in pmap["openai"].get("fields", [])}
     assert "api_key" in openai_fields
+
+
+def test_providers_schema_marks_advanced_fields():
+    data = run_cli_json(["providers", "schema", "--json"])  # type: ignore
+    pmap = {p.get("type"): p for p in data["providers"]}
+    openai = {f["name"]: f for f in pmap["openai"]["fields"]}
+    assert openai["org_id"].get("advanced") is True
+    assert not openai["api_key"].get("advanced")
+    local = {f["name"]: f for f in pmap["local"]["fields"]}
+    assert local["n_gpu_layers"].get("advanced") is True
+
+
+def test_providers_schema_numeric_bounds():
+    data = run_cli_json(["providers", "schema", "--json"])  # type: ignore
+    pmap = {p.get("type"): p for p in data["providers"]}
+    ollama = {f["name"]: f for f in pmap["ollama"]["fields"]}
+    assert ollama["port"]["min"] == 1 and ollama["port"]["max"] == 65535
+    timeout = ollama["timeout"]
+    assert timeout["type"] == "float"
+    assert timeout["min"] < timeout["default"] <= timeout["max"]
+
+
+def test_providers_schema_privacy_labels():
+    data = run_cli_json(["providers", "schema", "--json"])  # type: ignore
+    pmap = {p.get("type"): p for p in data["providers"]}
+    assert pmap["local"]["privacy"] == "local"
+    assert pmap["ollama"]["privacy"] == "local"
+    assert pmap["openai"]["privacy"] == "cloud-paid"
+
+
+def test_providers_schema_lists_deprecated_models():
+    data = run_cli_json(["providers", "schema", "--json"])  # type: ignore
+    retired = {(m["type"], m["id"]): m for m in data["deprecated_models"]}
+    gpt4_32k = retired[("openai", "gpt-4-32k")]
+    assert gpt4_32k["status"] == "retired"
+    assert gpt4_32k["replacement"]
@@ -40,3 +40,16 @@ def test_diagnostics_json_basic(capsys, tmp_path, monkeypatch):
     assert data["model"]["fits"] is False
     assert data["network"]["ok"] is True
 
+
+
+def test_diagnostics_report_interpreter_and_package(capsys):
+    import sys
+    import chi_llm
+
+    with patch.object(diag, "_check_network", return_value={"hf": True, "ok": True}):
+        diag.cmd_diagnostics(SimpleNamespace(json=True))
+
+    data = json.loads(capsys.readouterr().out)
+    assert data["python"]["executable"] == sys.executable
+    assert data["python"]["prefix"] == sys.prefix
+    assert data["chi_llm"]["version"] == chi_llm.__version__
@@ -2,6 +2,7 @@
 Tests for models CLI JSON outputs and setup recommend.
 """
 
+import dataclasses
 import json
 from types import SimpleNamespace
 from unittest.mock import patch
@@ -30,6 +31,42 @@ def test_models_info_json(capsys):
     assert data["downloaded"] is True
 
 
+def test_models_list_json_includes_download_source(capsys):
+    class FakeMgr:
+        def is_downloaded(self, mid):
+            return False
+
+        def get_current_model(self):
+            return MODELS["gemma-270m"]
+
+    with patch.object(models_cli, "ModelManager", return_value=FakeMgr()):
+        models_cli.cmd_models(SimpleNamespace(models_command="list", json=True))
+    data = json.loads(capsys.readouterr().out)
+    gemma = next(m for m in data if m["id"] == "gemma-270m")
+    assert gemma["repo"] == MODELS["gemma-270m"].repo
+    assert gemma["filename"].endswith(".gguf")
+    assert "mirrors" not in gemma
+
+
+def test_models_list_json_includes_mirrors(capsys):
+    mirror = {"repo": "bartowski/gemma-3-270m-it-GGUF"}
+    gemma = dataclasses.replace(MODELS["gemma-270m"], mirrors=[mirror])
+
+    class FakeMgr:
+        def is_downloaded(self, mid):
+            return False
+
+        def get_current_model(self):
+            return gemma
+
+    with patch.dict(MODELS, {"gemma-270m": gemma}), patch.object(
+        models_cli, "ModelManager", return_value=FakeMgr()
+    ):
+        models_cli.cmd_models(SimpleNamespace(models_command="list", json=True))
+    data = json.loads(capsys.readouterr().out)
+    assert next(m for m in data if m["id"] == "gemma-270m")["mirrors"] == [mirror]
+
+
 def test_setup_recommend_json(capsys):
     # Recommend a known model id
     reco = MODELS["gemma-270m"]
@@ -44,3 +81,53 @@ def test_setup_recommend_json(capsys):
     out = capsys.readouterr().out
     data = json.loads(out)
     assert data["id"] == "gemma-270m"
+
+
+def test_models_download_delegates_to_running_tui(tmp_path, capsys):
+    import socket
+    import threading
+
+    from chi_llm import tui_bridge
+
+    model_id = "gemma-270m"
+    seen = []
+    srv = socket.socket()
+    srv.bind(("127.0.0.1", 0))
+    srv.listen()
+
+    def serve():
+        # ping, download.start, download.status
+        for state in (None, "running", "done"):
+            conn, _ = srv.accept()
+            with conn:
+                req = json.loads(conn.makefile("r").readline())
+                seen.append(req)
+                job = {"id": model_id, "state": state, "done": 1, "percent": 50.0}
+                conn.sendall((json.dumps({"ok": True, "job": job}) + "\n").encode())
+
+    threading.Thread(target=serve, daemon=True).start()
+    info = {"pid": 1, "port": srv.getsockname()[1], "token": "t0k"}
+    (tmp_path / "abc.json").write_text(json.dumps(info))
+
+    class FakeMgr:
+        marked = []
+
+        def is_downloaded(self, mid):
+            return False
+
+        def mark_downloaded(self, mid):
+            self.marked.append(mid)
+
+    mgr = FakeMgr()
+    with patch.object(models_cli, "ModelManager", return_value=mgr), patch.object(
+        tui_bridge, "INSTANCES_DIR", tmp_path
+    ), patch.object(models_cli.time, "sleep"):
+        models_cli.cmd_models(
+            SimpleNamespace(models_command="download", model_id=model_id, no_tui=False)
+        )
+    srv.close()
+    assert [r["cmd"] for r in seen] == ["ping", "download.start", "download.status"]
+    assert all(r["token"] == "t0k" for r in seen)
+    assert seen[1]["filename"] == MODELS[model_id].filename
+    assert mgr.marked == [model_id]
+    assert "downloaded" in capsys.readouterr().out
@@ -0,0 +1,104 @@
+import json
+from types import SimpleNamespace
+
+import chi_llm.cli_modules.providers as providers_cli
+import chi_llm.cli_modules.providers_discovery as disc
+from chi_llm.providers.manifests import load_manifests, manifest_fields
+
+
+VLLM = {
+    "type": "vllm",
+    "notes": "vLLM server",
+    "default_port": 8000,
+    "fields": [{"name": "model", "type": "string"}],
+    "discovery": {"url": "http://{host}:{port}/v1/models", "items": "data"},
+    "auth": {"style": "bearer", "field": "api_key"},
+}
+
+
+def _write(tmp_path, name, obj):
+    (tmp_path / name).write_text(json.dumps(obj), encoding="utf-8")
+
+
+def test_load_manifests_skips_invalid(tmp_path):
+    _write(tmp_path, "vllm.json", VLLM)
+    _write(tmp_path, "broken.json", {"fields": []})
+    (tmp_path / "tgi.yaml").write_text(
+        "type: tgi\ndefault_port: 3000\n", encoding="utf-8"
+    )
+
+    manifests, errors = load_manifests(tmp_path)
+
+    assert [m["type"] for m in manifests] == ["tgi", "vllm"]
+    assert len(errors) == 1 and errors[0].startswith("broken.json")
+
+
+def test_manifest_privacy_is_validated(tmp_path):
+    _write(tmp_path, "vllm.json", dict(VLLM, privacy="lan"))
+    _write(tmp_path, "odd.json", {"type": "odd", "privacy": "secret"})
+
+    manifests, errors = load_manifests(tmp_path)
+
+    assert [m["privacy"] for m in manifests] == ["lan"]
+    assert errors and errors[0].startswith("odd.json")
+
+
+def test_manifest_fields_imply_host_port_and_secret():
+    names = [f["name"] for f in manifest_fields(VLLM)]
+    assert names == ["host", "port", "api_key", "model"]
+    port = next(f for f in manifest_fields(VLLM) if f["name"] == "port")
+    assert port["default"] == 8000
+
+
+def test_schema_and_list_include_manifest(tmp_path, monkeypatch, capsys):
+    _write(tmp_path, "vllm.json", VLLM)
+    _write(tmp_path, "shadow.json", {"type": "ollama"})  # built-in wins
+    monkeypatch.setenv("CHI_LLM_PROVIDERS_DIR", str(tmp_path))
+
+    providers_cli.cmd_providers(
+        SimpleNamespace(providers_command="schema", json=True)
+    )
+    data = json.loads(capsys.readouterr().out)
+    types = [p["type"] for p in data["providers"]]
+    assert "vllm" in types
+    assert types.count("ollama") == 1
+
+    providers_cli.cmd_providers(SimpleNamespace(providers_command="list", json=True))
+    listed = json.loads(capsys.readouterr().out)
+    vllm = next(p for p in listed if p["type"] == "vllm")
+    assert vllm["source"].endswith("vllm.json")
+
+
+def test_discover_models_uses_manifest(tmp_path, monkeypatch, capsys):
+    _write(tmp_path, "vllm.json", VLLM)
+    monkeypatch.setenv("CHI_LLM_PROVIDERS_DIR", str(tmp_path))
+    seen = {}
+
+    class _Resp:
+        status = 200
+        headers = {}
+
+        def read(self):
+            return json.dumps({"data": [{"id": "llama-3-8b"}]}).encode("utf-8")
+
+        def __enter__(self):
+            return self
+
+        def __exit__(self, *exc):
+            return False
+
+    def _stub_urlopen(req, timeout=5):
+        seen["url"] = req.full_url
+        seen["auth"] = req.get_header("Authorization")
+        return _Resp()
+
+    monkeypatch.setattr(disc._request, "urlopen", _stub_urlopen)
+    args = SimpleNamespace(
+        ptype="vllm", host="gpu-box", port=None, json=True, api_key="sk-test"
+    )
+    disc.cmd_discover_models(args)
+    data = json.loads(capsys.readouterr().out)
+
+    assert seen["url"] == "http://gpu-box:8000/v1/models"
+    assert seen["auth"] == "Bearer sk-test"
+    assert data["models"] == [{"id": "llama-3-8b"}]
@@ -84,3 +84,14 @@ def test_micro_llm_integration_with_openai(monkeypatch):
         os.environ.pop("CHI_LLM_PROVIDER_TYPE", None)
         os.environ.pop("CHI_LLM_PROVIDER_API_KEY", None)
         os.environ.pop("CHI_LLM_PROVIDER_MODEL", None)
+
+
+def test_openai_compatible_registry_keyless_and_v1_base(monkeypatch):
+    _install_fake_openai(monkeypatch)
+    from chi_llm.providers.router import default_registry
+
+    make = default_registry()["openai-compatible"]
+    p = make({"base_url": "http://127.0.0.1:8000/", "model": "qwen2.5-7b"})
+    assert p.generate("hi") == "hello from openai"
+    assert p._client.base_url == "http://127.0.0.1:8000/v1"
+    assert p._client.api_key == "not-needed"
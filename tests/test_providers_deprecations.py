@@ -0,0 +1,49 @@
+"""
+Tests for the deprecated/retired hosted models list.
+"""
+
+import json
+from datetime import date
+
+import pytest
+
+from chi_llm.cli_modules import providers_deprecations as dep
+
+
+@pytest.fixture
+def home(tmp_path, monkeypatch):
+    monkeypatch.setattr(dep.Path, "home", lambda: tmp_path)
+    return tmp_path
+
+
+def test_bundled_list_status_follows_the_retirement_date(home):
+    models = {m["id"]: m for m in dep.entries(today=date(2025, 7, 1))}
+    assert models["gpt-3.5-turbo-0613"]["status"] == "retired"
+    assert models["claude-2.1"]["status"] == "deprecated"
+    assert models["claude-2.1"]["replacement"]
+    assert dep.load()[1] == "bundled"
+
+
+def test_refresh_writes_the_cache_and_newer_cache_wins(home, tmp_path):
+    remote = tmp_path / "remote.json"
+    remote.write_text(
+        json.dumps(
+            {
+                "updated": "2999-01-01",
+                "models": [{"type": "openai", "id": "gpt-x", "replacement": "gpt-y"}],
+            }
+        )
+    )
+    dep.refresh(remote.as_uri())
+    data, source = dep.load()
+    assert source == str(dep.cache_path())
+    assert [m["id"] for m in dep.entries()] == ["gpt-x"]
+    assert dep.entries()[0]["status"] == "deprecated"
+
+
+def test_refresh_rejects_a_malformed_list(home, tmp_path):
+    remote = tmp_path / "remote.json"
+    remote.write_text(json.dumps({"models": [{"id": "no-type"}]}))
+    with pytest.raises(RuntimeError):
+        dep.refresh(remote.as_uri())
+    assert not dep.cache_path().exists()
@@ -24,8 +24,8 @@ def test_discover_models_lmstudio_json(monkeypatch, capsys):
     # Arrange stubbed urlopen returning LM Studio-like models list
     payload = {"data": [{"id": "qwen2.5:latest"}, {"id": "gemma-270m"}]}
 
-    def _stub_urlopen(url, timeout=3):
-        assert "/v1/models" in url
+    def _stub_urlopen(req, timeout=3):
+        assert "/v1/models" in req.full_url
         return _StubResp(payload, status=200)
 
     monkeypatch.setattr(disc._request, "urlopen", _stub_urlopen)
@@ -84,3 +84,204 @@ def test_discover_models_openai_headers_and_json(monkeypatch, capsys):
     assert seen["auth"] == "Bearer test-key"
     assert seen["org"] == "org-123"
     assert "/v1/models" in (seen["url"] or "")
+
+
+def test_discover_models_anthropic_headers_and_names(monkeypatch, capsys):
+    seen = {}
+
+    def _stub_urlopen(req, timeout=5):
+        seen["url"] = req.full_url
+        seen["headers"] = {k.lower(): v for k, v in req.headers.items()}
+        payload = {
+            "data": [
+                {"id": "claude-3-5-haiku-latest", "display_name": "Claude Haiku 3.5"},
+                {"id": "claude-sonnet-4-0"},
+            ],
+            "has_more": False,
+        }
+        return _StubResp(payload, status=200)
+
+    monkeypatch.setattr(disc._request, "urlopen", _stub_urlopen)
+    args = SimpleNamespace(
+        ptype="anthropic",
+        base_url="https://gateway.example/",
+        api_key="sk-ant-test",
+        json=True,
+    )
+
+    disc.cmd_discover_models(args)
+    data = json.loads(capsys.readouterr().out)
+
+    assert seen["url"].startswith("https://gateway.example/v1/models")
+    assert seen["headers"]["x-api-key"] == "sk-ant-test"
+    assert seen["headers"]["anthropic-version"] == disc.ANTHROPIC_VERSION
+    assert "authorization" not in seen["headers"]
+    assert data["models"][0] == {
+        "id": "claude-3-5-haiku-latest",
+        "name": "Claude Haiku 3.5",
+    }
+    assert data["models"][1]["name"] == "claude-sonnet-4-0"
+
+
+def test_discover_models_openai_compatible_optional_key(monkeypatch, capsys):
+    seen = []
+
+    def _stub_urlopen(req, timeout=5):
+        seen.append((req.full_url, req.get_header("Authorization")))
+        return _StubResp({"data": [{"id": "meta-llama/llama-3.1-8b"}]}, status=200)
+
+    monkeypatch.setattr(disc._request, "urlopen", _stub_urlopen)
+    base = SimpleNamespace(ptype="openai-compatible", json=True)
+
+    # Local server, no key; base without /v1
+    disc.cmd_discover_models(
+        SimpleNamespace(**vars(base), base_url="http://127.0.0.1:8000", api_key=None)
+    )
+    local = json.loads(capsys.readouterr().out)
+    # OpenRouter-style base already ending in /v1
+    disc.cmd_discover_models(
+        SimpleNamespace(
+            **vars(base), base_url="https://openrouter.ai/api/v1/", api_key="sk-or"
+        )
+    )
+    remote = json.loads(capsys.readouterr().out)
+
+    assert seen == [
+        ("http://127.0.0.1:8000/v1/models", None),
+        ("https://openrouter.ai/api/v1/models", "Bearer sk-or"),
+    ]
+    assert local["models"] == remote["models"] == [
+        {"id": "meta-llama/llama-3.1-8b", "name": "meta-llama/llama-3.1-8b"}
+    ]
+
+
+def test_discover_models_openai_compatible_rejects_non_model_list(
+    monkeypatch, capsys
+):
+    monkeypatch.setattr(
+        disc._request,
+        "urlopen",
+        lambda req, timeout=5: _StubResp({"status": "ok"}, status=200),
+    )
+    disc.cmd_discover_models(
+        SimpleNamespace(
+            ptype="openai-compatible",
+            base_url="http://example.test",
+            api_key=None,
+            json=True,
+        )
+    )
+    data = json.loads(capsys.readouterr().out)
+    assert "did not return an OpenAI model list" in data["error"]
+    assert data["models"] == []
+
+
+def test_discover_models_sends_user_agent(monkeypatch, capsys):
+    seen = []
+
+    def _stub_urlopen(req, timeout=3):
+        seen.append(req.get_header("User-agent"))
+        return _StubResp({"models": []}, status=200)
+
+    monkeypatch.setattr(disc._request, "urlopen", _stub_urlopen)
+    monkeypatch.delenv("CHI_LLM_USER_AGENT", raising=False)
+    base = dict(ptype="ollama", host="127.0.0.1", port=11434, json=True)
+    disc.cmd_discover_models(SimpleNamespace(**base))
+    disc.cmd_discover_models(SimpleNamespace(**base, user_agent="corp-gw/1"))
+    monkeypatch.setenv("CHI_LLM_USER_AGENT", "from-env/2")
+    disc.cmd_discover_models(SimpleNamespace(**base))
+    assert seen[0].startswith("chi-llm/")
+    assert seen[1:] == ["corp-gw/1", "from-env/2"]
+
+
+def test_discover_models_https_with_tls_options(monkeypatch, capsys):
+    import ssl
+
+    seen = {}
+
+    def _stub_urlopen(req, timeout=3, context=None):
+        seen["url"], seen["context"] = req.full_url, context
+        return _StubResp({"data": [{"id": "qwen3"}]}, status=200)
+
+    monkeypatch.setattr(disc._request, "urlopen", _stub_urlopen)
+    args = SimpleNamespace(
+        ptype="lmstudio",
+        host="llm.internal",
+        port=443,
+        scheme="https",
+        insecure=True,
+        json=True,
+    )
+    disc.cmd_discover_models(args)
+    assert json.loads(capsys.readouterr().out)["models"] == [{"id": "qwen3"}]
+    assert seen["url"] == "https://llm.internal:443/v1/models"
+    assert seen["context"].verify_mode == ssl.CERT_NONE
+    assert disc.tls_options(SimpleNamespace(scheme="http")) == {}
+
+
+def test_discover_models_sends_proxy_token(monkeypatch, capsys):
+    seen = []
+
+    def _stub_urlopen(req, timeout=3):
+        seen.append((req.get_header("Authorization"), req.get_header("X-api-key")))
+        return _StubResp({"models": [{"name": "llama3.2"}]}, status=200)
+
+    monkeypatch.setattr(disc._request, "urlopen", _stub_urlopen)
+    base = dict(ptype="ollama", host="llm.internal", port=80, json=True)
+    disc.cmd_discover_models(SimpleNamespace(**base, api_key="tok"))
+    disc.cmd_discover_models(
+        SimpleNamespace(**base, api_key="tok", auth_header="X-API-Key")
+    )
+    capsys.readouterr()
+    assert seen == [("Bearer tok", None), (None, "tok")]
+
+
+def test_discover_models_debug_requests_logs_masked_headers(
+    monkeypatch, capsys, caplog
+):
+    monkeypatch.setattr(
+        disc._request,
+        "urlopen",
+        lambda req, timeout=5: _StubResp({"data": [{"id": "gpt-4o"}]}, status=200),
+    )
+    args = SimpleNamespace(
+        ptype="openai",
+        base_url="https://gw.example",
+        api_key="sk-secret",
+        json=True,
+        debug_requests=True,
+    )
+    with caplog.at_level("INFO", logger="chi_llm.requests"):
+        disc.cmd_discover_models(args)
+    capsys.readouterr()
+    text = caplog.text
+    assert "> GET https://gw.example/v1/models" in text
+    assert "Bearer ••••••" in text
+    assert "< HTTP 200" in text
+    assert "sk-secret" not in text
+
+
+def test_discover_models_uses_the_provider_proxy(monkeypatch, capsys):
+    seen = {}
+
+    class _Opener:
+        def open(self, req, timeout=5):
+            seen["url"] = req.full_url
+            return _StubResp({"data": [{"id": "claude-sonnet-4"}]}, status=200)
+
+    def _build_opener(*handlers):
+        seen["proxies"] = [h.proxies for h in handlers if hasattr(h, "proxies")]
+        return _Opener()
+
+    monkeypatch.setattr(disc._request, "build_opener", _build_opener)
+    args = SimpleNamespace(
+        ptype="anthropic",
+        api_key="sk-ant",
+        proxy="http://proxy.corp:3128",
+        json=True,
+    )
+    disc.cmd_discover_models(args)
+    assert json.loads(capsys.readouterr().out)["models"][0]["id"] == "claude-sonnet-4"
+    assert seen["url"] == "https://api.anthropic.com/v1/models?limit=1000"
+    proxy = "http://proxy.corp:3128"
+    assert seen["proxies"] == [{"http": proxy, "https": proxy}]
@@ -0,0 +1,46 @@
+"""
+Tests for `chi-llm serve --stdio`, the JSON-RPC bridge chi-tui keeps open.
+"""
+
+import io
+import json
+
+from chi_llm.cli_modules import serve_stdio
+
+
+def _serve(*requests):
+    out = io.StringIO()
+    lines = "".join(json.dumps(r) + "\n" for r in requests)
+    serve_stdio.serve(io.StringIO(lines), out)
+    return [json.loads(line) for line in out.getvalue().splitlines()]
+
+
+def test_cli_calls_are_answered_with_their_output(tmp_path):
+    hello, schema = _serve(
+        {"jsonrpc": "2.0", "id": 0, "method": "chi.hello"},
+        {
+            "jsonrpc": "2.0",
+            "id": 1,
+            "method": "cli.run",
+            "params": {"args": ["providers", "schema", "--json"], "cwd": str(tmp_path)},
+        },
+    )
+    assert hello["result"]["version"]
+    assert schema["id"] == 1
+    assert schema["result"]["code"] == 0
+    types = [p["type"] for p in json.loads(schema["result"]["stdout"])["providers"]]
+    assert "ollama" in types
+
+
+def test_failures_keep_the_exit_code_and_stderr():
+    bad, unknown = _serve(
+        {"jsonrpc": "2.0", "id": 2, "method": "cli.run", "params": {"args": ["nope"]}},
+        {"jsonrpc": "2.0", "id": 3, "method": "models.pull"},
+    )
+    assert bad["result"]["code"] == 2
+    assert "invalid choice" in bad["result"]["stderr"]
+    assert unknown["error"]["code"] == -32601
+
+
+def test_a_broken_line_does_not_end_the_session():
+    assert serve_stdio.handle("{not json")["error"]["code"] == -32700
@@ -0,0 +1,91 @@
+import json
+import threading
+import urllib.error
+import urllib.request
+
+import pytest
+
+from chi_llm.server import _split_messages, create_debug_server, create_server
+
+
+class FakeLLM:
+    def __init__(self):
+        self.calls = []
+
+    def chat(self, message, history=None):
+        self.calls.append((message, history))
+        return f"echo: {message}"
+
+
+@pytest.fixture
+def server():
+    llm = FakeLLM()
+    httpd = create_server(llm, port=0, token="s3cret")
+    t = threading.Thread(target=httpd.serve_forever, daemon=True)
+    t.start()
+    yield f"http://127.0.0.1:{httpd.server_address[1]}", llm
+    httpd.shutdown()
+    httpd.server_close()
+
+
+def _post(url, body, token=None):
+    req = urllib.request.Request(
+        url, data=json.dumps(body).encode("utf-8"), method="POST"
+    )
+    req.add_header("Content-Type", "application/json")
+    if token:
+        req.add_header("Authorization", f"Bearer {token}")
+    with urllib.request.urlopen(req, timeout=5) as resp:
+        return json.loads(resp.read())
+
+
+def test_split_messages_builds_history():
+    prompt, history = _split_messages(
+        [
+            {"role": "system", "content": "Be brief."},
+            {"role": "user", "content": "hi"},
+            {"role": "assistant", "content": "hello"},
+            {"role": "user", "content": "how are you?"},
+        ]
+    )
+    assert prompt == "Be brief.\n\nhow are you?"
+    assert history == [{"user": "hi", "assistant": "hello"}]
+
+
+def test_chat_completion_requires_token(server):
+    base, llm = server
+    body = {"messages": [{"role": "user", "content": "ping"}]}
+
+    with pytest.raises(urllib.error.HTTPError) as exc:
+        _post(f"{base}/v1/chat/completions", body)
+    assert exc.value.code == 401
+
+    data = _post(f"{base}/v1/chat/completions", body, token="s3cret")
+    assert data["choices"][0]["message"]["content"] == "echo: ping"
+    assert llm.calls == [("ping", [])]
+
+
+def test_health_is_open(server):
+    base, _ = server
+    with urllib.request.urlopen(f"{base}/health", timeout=5) as resp:
+        assert json.loads(resp.read()) == {"status": "ok"}
+
+
+def test_debug_server_reports_threads_and_memory():
+    httpd = create_debug_server(port=0, log=lambda line: None)
+    threading.Thread(target=httpd.serve_forever, daemon=True).start()
+    base = f"http://127.0.0.1:{httpd.server_address[1]}"
+    try:
+
+        def get(path):
+            with urllib.request.urlopen(f"{base}{path}", timeout=5) as resp:
+                return resp.read().decode("utf-8")
+
+        assert "/debug/threads" in get("/debug")
+        assert "Thread MainThread" in get("/debug/threads")
+        assert "Top 25 allocation sites" in get("/debug/memory")
+        assert "Growth since the previous call" in get("/debug/memory")
+        assert "Top 30 object types" in get("/debug/objects")
+    finally:
+        httpd.shutdown()
+        httpd.server_close()
@@ -1,11 +1,45 @@
 use std::time::Instant;
 
+use crate::audit::AuditState;
+use crate::backup::BackupsState;
 use crate::build::BuildState;
+use crate::cache::CacheState;
+use crate::confirm::ConfirmDialog;
+use crate::benchmark::BenchmarkState;
+use crate::deeptest::DeepTest;
+use crate::density::Density;
 use crate::diagnostics::DiagState;
-use crate::models::ModelBrowser;
-use crate::providers::{DefaultProviderState, ProvidersState};
+use crate::downloads::DownloadManager;
+use crate::health_watch::DefaultWatch;
+use crate::hf::{self, TokenSource};
+use crate::hooks::HookRunner;
+use crate::inspector::InspectorState;
+use crate::instance::Instance;
+use crate::latency::LatencyState;
+use crate::lmstudio::Loader;
+use crate::menu::MenuCache;
+use crate::modelname::ModelIndex;
+use crate::mouse::HitMap;
+use crate::models::{ModelBrowser, ModelPrefetch};
+use crate::playground::PlaygroundState;
+use crate::portforward::PortForwards;
+use crate::profile::StartupProfile;
+use crate::model_defaults::DefaultModels;
+use crate::profiles::ProfileSwitcher;
+use crate::providers::{DefaultProviderState, Preflight, ProvidersState};
 use crate::readme::ReadmeState;
+use crate::logs::LogView;
+use crate::recommend::RecommendState;
+use crate::server::{ApiServer, ServerForm};
+use crate::shutdown::ShutdownDialog;
+use crate::status::StatusBoard;
+use crate::store::Format;
+use crate::prefs::Prefs;
 use crate::theme::Theme;
+use crate::theme_picker::ThemePicker;
+use crate::util::{COMPACT_MIN_HEIGHT, COMPACT_MIN_WIDTH, NARROW_MIN_WIDTH};
+use crate::variables::VariablesState;
+use crate::toast::Toast;
 
 #[derive(Copy, Clone, Debug, PartialEq, Eq)]
 pub enum Page {
@@ -17,17 +51,77 @@ pub enum Page {
     Diagnostics,
     Build,
     Settings,
+    Audit,
+    Backups,
+    Latency,
+    Playground,
+    Cache,
+    Server,
+    Variables,
+    Status,
+    Benchmark,
+    Recommend,
+    Logs,
+}
+
+impl Page {
+    pub const ALL: [Page; 19] = [
+        Page::Welcome, Page::Readme, Page::Configure, Page::SelectDefault, Page::ModelBrowser,
+        Page::Diagnostics, Page::Build, Page::Settings, Page::Audit, Page::Backups,
+        Page::Latency, Page::Playground, Page::Cache, Page::Server, Page::Variables,
+        Page::Status, Page::Benchmark, Page::Recommend, Page::Logs,
+    ];
+
+    /// Name used by `--page` and remote "open" commands.
+    pub fn key(self) -> &'static str {
+        match self {
+            Page::Welcome => "welcome",
+            Page::Readme => "readme",
+            Page::Configure => "configure",
+            Page::SelectDefault => "select-default",
+            Page::ModelBrowser => "models",
+            Page::Diagnostics => "diagnostics",
+            Page::Build => "build",
+            Page::Settings => "settings",
+            Page::Audit => "audit",
+            Page::Backups => "backups",
+            Page::Latency => "latency",
+            Page::Playground => "playground",
+            Page::Cache => "cache",
+            Page::Server => "server",
+            Page::Variables => "variables",
+            Page::Status => "status",
+            Page::Benchmark => "benchmark",
+            Page::Recommend => "recommend",
+            Page::Logs => "logs",
+        }
+    }
+
+    pub fn from_key(s: &str) -> Option<Page> {
+        let s = s.trim().to_lowercase();
+        Self::ALL.iter().copied().find(|p| p.key() == s)
+    }
 }
 
 pub struct App {
     pub page: Page,
     pub menu_idx: usize,
+    /// Welcome menu built from `menu::REGISTRY`
+    pub menu: MenuCache,
     pub show_help: bool,
     pub anim: bool,
     pub tick: u64,
     pub last_tick: Instant,
     pub theme: Theme,
     pub use_alt: bool,
+    /// Single-column layout with full-area overlays (forced or small terminal)
+    pub compact: bool,
+    pub force_compact: bool,
+    /// Narrow but not compact: page bar instead of the hero header,
+    /// stacked panes
+    pub narrow: bool,
+    /// Paddings, spacer lines and menu help (Settings `d`)
+    pub density: Density,
     pub should_quit: bool,
     pub diag: Option<DiagState>,
     pub last_error: Option<String>,
@@ -37,6 +131,64 @@ pub struct App {
     pub defaultp: Option<DefaultProviderState>,
     pub providers: Option<ProvidersState>,
     pub build: Option<BuildState>,
+    pub audit: Option<AuditState>,
+    pub backups: Option<BackupsState>,
+    pub toast: Option<Toast>,
+    pub portfw: PortForwards,
+    pub latency: Option<LatencyState>,
+    /// Status page table, kept between visits
+    pub status_board: Option<StatusBoard>,
+    pub inspector: InspectorState,
+    pub playground: Option<PlaygroundState>,
+    pub downloads: DownloadManager,
+    pub cache: Option<CacheState>,
+    /// Sort local/private providers first in provider lists
+    pub prefer_private: bool,
+    /// Preferred format when writing chi.tmp.* and .chi_llm.*
+    pub config_format: Format,
+    /// Regenerate .env/.envrc after every store save
+    pub env_sync: bool,
+    pub api_server: ApiServer,
+    pub server_form: Option<ServerForm>,
+    /// Health of the default provider and a suggested replacement
+    pub default_watch: DefaultWatch,
+    /// Streamed completion test (Configure, Shift+T) running in the background
+    pub deep_test: DeepTest,
+    /// Models each provider listed, for "also available on"
+    pub model_index: ModelIndex,
+    /// Reachability of Select Default rows, checked as the cursor moves
+    pub preflight: Preflight,
+    /// LM Studio model loads requested from the Model Browser
+    pub lms_loader: Loader,
+    /// Model Browser lists fetched in the background since startup
+    pub prefetch: ModelPrefetch,
+    /// Where the Hugging Face token comes from; None when there is none
+    pub hf_token: Option<TokenSource>,
+    /// Settings: Hugging Face token being typed (`h`)
+    pub hf_token_input: Option<String>,
+    /// Settings: default model per provider type (`m`)
+    pub default_models: Option<DefaultModels>,
+    pub theme_picker: Option<ThemePicker>,
+    /// User settings from tui.json, as of startup or the last change
+    pub prefs: Prefs,
+    pub variables: Option<VariablesState>,
+    pub benchmark: Option<BenchmarkState>,
+    pub recommend: Option<RecommendState>,
+    pub logs: Option<LogView>,
+    /// Welcome: profile switcher overlay (`p`)
+    pub profiles: Option<ProfileSwitcher>,
+    /// `--profile-startup` timings until the first frame
+    pub startup: Option<StartupProfile>,
+    /// Quit dialog while downloads/server are still running
+    pub shutdown: Option<ShutdownDialog>,
+    /// Yes/no question before a destructive action
+    pub confirm: Option<ConfirmDialog>,
+    /// Store lock and control socket shared with other chi-tui processes
+    pub instance: Instance,
+    /// Clickable regions of the last frame
+    pub hits: HitMap,
+    /// Post-save hooks still running
+    pub hooks: HookRunner,
 }
 
 impl App {
@@ -44,12 +196,17 @@ impl App {
         Self {
             page: Page::Welcome,
             menu_idx: 0,
+            menu: MenuCache::default(),
             show_help: false,
             anim: true,
             tick: 0,
             last_tick: Instant::now(),
             theme: Theme::synthwave_dark(),
             use_alt,
+            compact: false,
+            force_compact: false,
+            narrow: false,
+            density: Density::default(),
             should_quit: false,
             diag: None,
             last_error: None,
@@ -59,18 +216,55 @@ impl App {
             defaultp: None,
             providers: None,
             build: None,
+            audit: None,
+            backups: None,
+            toast: None,
+            portfw: PortForwards::default(),
+            latency: None,
+            status_board: None,
+            inspector: InspectorState::default(),
+            playground: None,
+            downloads: DownloadManager::default(),
+            cache: None,
+            prefer_private: false,
+            config_format: Format::default(),
+            env_sync: false,
+            api_server: ApiServer::default(),
+            server_form: None,
+            default_watch: DefaultWatch::default(),
+            deep_test: DeepTest::default(),
+            model_index: ModelIndex::default(),
+            preflight: Preflight::default(),
+            lms_loader: Loader::default(),
+            prefetch: ModelPrefetch::default(),
+            hf_token: hf::token().map(|(_, src)| src),
+            hf_token_input: None,
+            default_models: None,
+            theme_picker: None,
+            prefs: Prefs::default(),
+            variables: None,
+            benchmark: None,
+            recommend: None,
+            logs: None,
+            profiles: None,
+            startup: None,
+            shutdown: None,
+            confirm: None,
+            instance: Instance::default(),
+            hits: HitMap::default(),
+            hooks: HookRunner::default(),
         }
     }
-}
 
-pub const WELCOME_ITEMS: &[(&str, Page)] = &[
-    ("README", Page::Readme),
-    ("Configure Providers", Page::Configure),
-    ("Select Default", Page::SelectDefault),
-    ("Diagnostics", Page::Diagnostics),
-    ("Build Configuration", Page::Build),
-    ("Settings", Page::Settings),
-    ("Model Browser", Page::ModelBrowser),
-    ("EXIT", Page::Welcome),
-];
+    /// Pick the layout for a terminal of `width` x `height`.
+    pub fn fit(&mut self, width: u16, height: u16) {
+        self.compact = self.force_compact || width < COMPACT_MIN_WIDTH || height < COMPACT_MIN_HEIGHT;
+        self.narrow = width < NARROW_MIN_WIDTH;
+    }
+
+    /// Panes one above the other instead of side by side.
+    pub fn stacked(&self) -> bool {
+        self.compact || self.narrow
+    }
+}
 
@@ -0,0 +1,252 @@
+use std::collections::BTreeMap;
+use std::fs::{self, OpenOptions};
+use std::io::Write;
+
+use anyhow::Result;
+use ratatui::layout::{Constraint, Direction, Layout, Rect};
+use ratatui::prelude::Frame;
+use ratatui::style::{Modifier, Style};
+use ratatui::text::{Line, Span};
+use ratatui::widgets::{Block, Borders, List, ListItem, Paragraph, Wrap};
+use serde_json::Value;
+
+use crate::app::App;
+use crate::locale;
+
+/// Append-only log of config mutations, one JSON object per line.
+pub const AUDIT_PATH: &str = "chi.audit.jsonl";
+
+#[derive(Clone, Debug)]
+pub struct AuditChange {
+    pub path: String,
+    pub old: Option<String>,
+    pub new: Option<String>,
+}
+
+#[derive(Clone, Debug)]
+pub struct AuditEntry {
+    pub timestamp: String,
+    pub user: String,
+    pub action: String,
+    pub target: String,
+    pub changes: Vec<AuditChange>,
+}
+
+#[derive(Clone, Debug, Default)]
+pub struct AuditState {
+    pub entries: Vec<AuditEntry>, // newest first
+    pub selected: usize,
+    pub status: Option<String>,
+}
+
+impl AuditState {
+    pub fn move_up(&mut self) {
+        if self.selected > 0 { self.selected -= 1; }
+    }
+    pub fn move_down(&mut self) {
+        if self.selected + 1 < self.entries.len() { self.selected += 1; }
+    }
+}
+
+fn current_user() -> String {
+    std::env::var("USER")
+        .or_else(|_| std::env::var("USERNAME"))
+        .unwrap_or_else(|_| "unknown".to_string())
+}
+
+/// Credential fields by name: `api_key`, `token`, `password`, `secret`, or a
+/// name ending in one (`hf_token`, `client_secret`). Substrings do not
+/// count, so `max_tokens` and `auth_header` stay readable.
+fn is_secret_key(path: &str) -> bool {
+    const NAMES: [&str; 5] = ["key", "token", "secret", "password", "passwd"];
+    let last = path.rsplit('.').next().unwrap_or(path).to_lowercase();
+    NAMES.iter().any(|n| last == *n || last.strip_suffix(n).map_or(false, |head| head.ends_with('_') || head.ends_with('-')))
+}
+
+fn mask(path: &str, v: Option<&String>) -> Option<String> {
+    match v {
+        Some(s) if is_secret_key(path) && !s.is_empty() => Some("••••••".to_string()),
+        other => other.cloned(),
+    }
+}
+
+/// Flatten JSON into dotted paths. Arrays of objects with an `id` are keyed
+/// by id so reordering providers does not show up as a change.
+fn flatten(v: &Value, prefix: &str, out: &mut BTreeMap<String, String>) {
+    let join = |k: &str| if prefix.is_empty() { k.to_string() } else { format!("{}.{}", prefix, k) };
+    match v {
+        Value::Object(map) => {
+            for (k, child) in map { flatten(child, &join(k.as_str()), out); }
+        }
+        Value::Array(arr) => {
+            for (i, child) in arr.iter().enumerate() {
+                let key = child
+                    .get("id")
+                    .and_then(|x| x.as_str())
+                    .map(|id| format!("[{}]", id))
+                    .unwrap_or_else(|| format!("[{}]", i));
+                flatten(child, &join(key.as_str()), out);
+            }
+        }
+        Value::String(s) => { out.insert(prefix.to_string(), s.clone()); }
+        other => { out.insert(prefix.to_string(), other.to_string()); }
+    }
+}
+
+pub fn diff_values(old: &Value, new: &Value) -> Vec<AuditChange> {
+    let mut a = BTreeMap::new();
+    let mut b = BTreeMap::new();
+    flatten(old, "", &mut a);
+    flatten(new, "", &mut b);
+    let mut keys: Vec<&String> = a.keys().chain(b.keys()).collect();
+    keys.sort();
+    keys.dedup();
+    let mut changes = Vec::new();
+    for k in keys {
+        let (ov, nv) = (a.get(k), b.get(k));
+        if ov != nv {
+            changes.push(AuditChange { path: k.clone(), old: mask(k, ov), new: mask(k, nv) });
+        }
+    }
+    changes
+}
+
+/// Record a mutation of `target` from `old` to `new`. No-op when nothing
+/// changed. Failures are returned but callers treat auditing as best-effort.
+pub fn record(action: &str, target: &str, old: &Value, new: &Value) -> Result<()> {
+    let changes = diff_values(old, new);
+    if changes.is_empty() { return Ok(()); }
+    let line = serde_json::json!({
+        "timestamp": chrono::Utc::now().to_rfc3339(),
+        "user": current_user(),
+        "action": action,
+        "target": target,
+        "changes": changes.iter().map(|c| serde_json::json!({"path": c.path, "old": c.old, "new": c.new})).collect::<Vec<_>>(),
+    });
+    let mut f = OpenOptions::new().create(true).append(true).open(AUDIT_PATH)?;
+    writeln!(f, "{}", serde_json::to_string(&line)?)?;
+    Ok(())
+}
+
+fn parse_entry(v: &Value) -> Option<AuditEntry> {
+    let s = |k: &str| v.get(k).and_then(|x| x.as_str()).unwrap_or("").to_string();
+    let changes = v.get("changes").and_then(|x| x.as_array()).map(|arr| {
+        arr.iter().map(|c| AuditChange {
+            path: c.get("path").and_then(|x| x.as_str()).unwrap_or("").to_string(),
+            old: c.get("old").and_then(|x| x.as_str()).map(|x| x.to_string()),
+            new: c.get("new").and_then(|x| x.as_str()).map(|x| x.to_string()),
+        }).collect()
+    }).unwrap_or_default();
+    let e = AuditEntry { timestamp: s("timestamp"), user: s("user"), action: s("action"), target: s("target"), changes };
+    if e.timestamp.is_empty() { None } else { Some(e) }
+}
+
+pub fn load_audit() -> AuditState {
+    let text = fs::read_to_string(AUDIT_PATH).unwrap_or_default();
+    let mut entries: Vec<AuditEntry> = text
+        .lines()
+        .filter_map(|l| serde_json::from_str::<Value>(l).ok())
+        .filter_map(|v| parse_entry(&v))
+        .collect();
+    entries.reverse();
+    AuditState { entries, selected: 0, status: None }
+}
+
+pub fn export_audit(st: &AuditState) -> Result<String> {
+    let arr: Vec<Value> = st.entries.iter().rev().map(|e| serde_json::json!({
+        "timestamp": e.timestamp,
+        "user": e.user,
+        "action": e.action,
+        "target": e.target,
+        "changes": e.changes.iter().map(|c| serde_json::json!({"path": c.path, "old": c.old, "new": c.new})).collect::<Vec<_>>(),
+    })).collect();
+    let path = "chi_llm_audit_export.json".to_string();
+    fs::write(&path, serde_json::to_vec_pretty(&Value::Array(arr))?)?;
+    Ok(path)
+}
+
+pub fn draw_audit(f: &mut Frame, area: Rect, app: &App) {
+    let chunks = Layout::default()
+        .direction(Direction::Vertical)
+        .constraints([Constraint::Percentage(55), Constraint::Percentage(45)])
+        .split(area);
+    let mut items: Vec<ListItem> = Vec::new();
+    let mut detail: Vec<Line> = Vec::new();
+    if let Some(st) = &app.audit {
+        for (i, e) in st.entries.iter().enumerate() {
+            let label = format!("{} {}  {}  {} {} ({} changes)", if i == st.selected { '›' } else { ' ' }, locale::ago_rfc3339(&e.timestamp), e.user, e.action, e.target, e.changes.len());
+            let style = if i == st.selected { Style::default().fg(app.theme.selected).add_modifier(Modifier::BOLD) } else { Style::default().fg(app.theme.fg) };
+            items.push(ListItem::new(Line::from(Span::styled(label, style))));
+        }
+        if st.entries.is_empty() { items.push(ListItem::new(format!("No entries in {} yet.", AUDIT_PATH))); }
+        if let Some(e) = st.entries.get(st.selected) {
+            detail.push(Line::from(Span::styled(format!("{} by {}", e.timestamp, e.user), Style::default().fg(app.theme.secondary))));
+            for c in &e.changes {
+                detail.push(Line::from(vec![
+                    Span::styled(format!("{}: ", c.path), Style::default().fg(app.theme.accent)),
+                    Span::raw(format!("{} → {}", c.old.as_deref().unwrap_or("∅"), c.new.as_deref().unwrap_or("∅"))),
+                ]));
+            }
+        }
+        if let Some(msg) = &st.status {
+            detail.push(Line::from(Span::styled(msg.clone(), Style::default().fg(app.theme.secondary))));
+        }
+    } else {
+        items.push(ListItem::new("Loading audit log..."));
+    }
+    let list = List::new(items)
+        .block(Block::default().borders(Borders::ALL).border_style(Style::default().fg(app.theme.frame)).title("Audit Log"));
+    f.render_widget(list, chunks[0]);
+    let p = Paragraph::new(detail)
+        .style(Style::default().bg(app.theme.bg).fg(app.theme.fg))
+        .block(Block::default().borders(Borders::ALL).border_style(Style::default().fg(app.theme.frame)).title("Changes (old → new)"))
+        .wrap(Wrap { trim: true });
+    f.render_widget(p, chunks[1]);
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+
+    #[test]
+    fn only_credential_names_are_secret() {
+        for name in ["api_key", "providers.[openai].config.api_key", "token", "hf_token", "password", "secret", "client_secret", "x-api-key"] {
+            assert!(is_secret_key(name), "{}", name);
+        }
+        for name in ["max_tokens", "auth_header", "providers.[a].config.model", "monkey", "tokenizer", "secret_ref_count"] {
+            assert!(!is_secret_key(name), "{}", name);
+        }
+    }
+
+    #[test]
+    fn changes_are_keyed_by_provider_id_and_masked() {
+        let old = serde_json::json!({"providers": [
+            {"id": "a", "config": {"api_key": "sk-old", "max_tokens": 256}},
+            {"id": "b", "config": {"model": "m"}},
+        ]});
+        let new = serde_json::json!({"providers": [
+            {"id": "b", "config": {"model": "m"}},
+            {"id": "a", "config": {"api_key": "sk-new", "max_tokens": 512}},
+        ]});
+        let changes = diff_values(&old, &new);
+        let paths: Vec<&str> = changes.iter().map(|c| c.path.as_str()).collect();
+        assert_eq!(paths, ["providers.[a].config.api_key", "providers.[a].config.max_tokens"]);
+        assert_eq!((changes[0].old.as_deref(), changes[0].new.as_deref()), (Some("••••••"), Some("••••••")));
+        assert_eq!((changes[1].old.as_deref(), changes[1].new.as_deref()), (Some("256"), Some("512")));
+        assert!(diff_values(&old, &old).is_empty());
+    }
+
+    #[cfg(unix)]
+    #[test]
+    fn records_read_back_newest_first() {
+        let _fake = crate::testing::FakeCli::new();
+        let (a, b, c) = (serde_json::json!({"x": "1"}), serde_json::json!({"x": "2"}), serde_json::json!({"x": "3"}));
+        record("providers.save", "chi.tmp.json", &a, &b).expect("first");
+        record("providers.save", "chi.tmp.json", &b, &b).expect("no change");
+        record("default.set", "chi.tmp.json", &b, &c).expect("second");
+        let st = load_audit();
+        let actions: Vec<&str> = st.entries.iter().map(|e| e.action.as_str()).collect();
+        assert_eq!(actions, ["default.set", "providers.save"]);
+        assert_eq!(st.entries[0].changes[0].new.as_deref(), Some("3"));
+    }
+}
@@ -0,0 +1,354 @@
+use std::fs;
+use std::path::PathBuf;
+use std::time::{Duration, SystemTime};
+
+use anyhow::{anyhow, Result};
+use ratatui::layout::Rect;
+use ratatui::prelude::Frame;
+use ratatui::style::{Modifier, Style};
+use ratatui::text::{Line, Span};
+use ratatui::widgets::{Block, Borders, List, ListItem, Paragraph, Wrap};
+use serde_json::Value;
+
+use crate::app::App;
+use crate::audit;
+use crate::focus::pane_block;
+use crate::locale;
+use crate::store;
+use crate::util::{fnv1a, split_panes};
+
+/// Snapshots kept per project; older ones are pruned.
+pub const KEEP_SNAPSHOTS: usize = 7;
+const SNAPSHOT_INTERVAL: Duration = Duration::from_secs(24 * 60 * 60);
+
+#[derive(Clone, Debug)]
+pub struct Snapshot {
+    pub path: PathBuf,
+    pub label: String,
+    pub root: Value,
+}
+
+#[derive(Clone, Copy, Debug, PartialEq, Eq)]
+pub enum ProviderDiff {
+    Same,
+    Changed,
+    Removed, // present in snapshot, missing now
+    Added,   // present now, missing in snapshot
+}
+
+#[derive(Clone, Debug, Default)]
+pub struct BackupsState {
+    pub snapshots: Vec<Snapshot>, // newest first
+    pub selected: usize,
+    pub provider_sel: usize,
+    pub focus_providers: bool,
+    pub current: Value,
+    pub status: Option<String>,
+}
+
+/// Per-project snapshot directory under the chi_llm cache.
+fn backup_dir() -> Result<PathBuf> {
+    let home = dirs::home_dir().ok_or_else(|| anyhow!("home dir not found"))?;
+    let cwd = std::env::current_dir()?;
+    let key = format!("{:016x}", fnv1a(&cwd.to_string_lossy()));
+    Ok(home.join(".cache").join("chi_llm").join("backups").join(key))
+}
+
+fn read_store() -> Value {
+    store::read().unwrap_or_else(|_| serde_json::json!({}))
+}
+
+fn list_snapshot_paths() -> Vec<PathBuf> {
+    let mut paths: Vec<PathBuf> = match backup_dir().and_then(|d| Ok(fs::read_dir(d)?)) {
+        Ok(rd) => rd
+            .filter_map(|e| e.ok().map(|e| e.path()))
+            .filter(|p| p.extension().map_or(false, |x| x == "json"))
+            .collect(),
+        Err(_) => Vec::new(),
+    };
+    // Names embed a sortable timestamp
+    paths.sort();
+    paths.reverse();
+    paths
+}
+
+pub fn snapshot_now() -> Result<PathBuf> {
+    // Snapshots are JSON whatever the store format, so restores can convert
+    let text = serde_json::to_string_pretty(&store::read()?)?;
+    let dir = backup_dir()?;
+    fs::create_dir_all(&dir)?;
+    // One name per second: a second snapshot within it takes the next free one
+    let mut at = chrono::Utc::now();
+    let mut path = dir.join(format!("chi.tmp-{}.json", at.format("%Y%m%d-%H%M%S")));
+    while path.exists() {
+        at += chrono::Duration::seconds(1);
+        path = dir.join(format!("chi.tmp-{}.json", at.format("%Y%m%d-%H%M%S")));
+    }
+    fs::write(&path, text)?;
+    prune(KEEP_SNAPSHOTS);
+    Ok(path)
+}
+
+fn prune(keep: usize) {
+    for p in list_snapshot_paths().into_iter().skip(keep) {
+        let _ = fs::remove_file(p);
+    }
+}
+
+/// Take a daily snapshot when the newest one is older than a day and the
+/// store has changed since. Called at startup and after saves.
+pub fn maybe_snapshot() -> Result<Option<PathBuf>> {
+    if !store::exists() { return Ok(None); }
+    if let Some(latest) = list_snapshot_paths().into_iter().next() {
+        let age = fs::metadata(&latest)?
+            .modified()
+            .ok()
+            .and_then(|m| SystemTime::now().duration_since(m).ok())
+            .unwrap_or(SNAPSHOT_INTERVAL);
+        if age < SNAPSHOT_INTERVAL { return Ok(None); }
+        let latest_root = fs::read_to_string(&latest).ok().and_then(|t| serde_json::from_str::<Value>(&t).ok());
+        if latest_root.is_some() && latest_root == store::read().ok() { return Ok(None); }
+    }
+    snapshot_now().map(Some)
+}
+
+pub fn load_backups() -> BackupsState {
+    let snapshots = list_snapshot_paths()
+        .into_iter()
+        .filter_map(|path| {
+            let root: Value = serde_json::from_str(&fs::read_to_string(&path).ok()?).ok()?;
+            Some(Snapshot { label: label_of(&path), path, root })
+        })
+        .collect();
+    BackupsState { snapshots, current: read_store(), ..Default::default() }
+}
+
+fn providers_of(root: &Value) -> Vec<Value> {
+    root.get("providers").and_then(|x| x.as_array()).cloned().unwrap_or_default()
+}
+
+fn label_of(path: &std::path::Path) -> String {
+    path.file_stem().map(|s| s.to_string_lossy().trim_start_matches("chi.tmp-").to_string()).unwrap_or_default()
+}
+
+fn pid(p: &Value) -> String {
+    p.get("id").and_then(|x| x.as_str()).unwrap_or("").to_string()
+}
+
+impl BackupsState {
+    pub fn current_snapshot(&self) -> Option<&Snapshot> {
+        self.snapshots.get(self.selected)
+    }
+
+    /// Union of provider ids in the selected snapshot and the current store,
+    /// with how each differs.
+    pub fn provider_diffs(&self) -> Vec<(String, ProviderDiff)> {
+        let Some(snap) = self.current_snapshot() else { return Vec::new() };
+        let old = providers_of(&snap.root);
+        let cur = providers_of(&self.current);
+        let mut out: Vec<(String, ProviderDiff)> = Vec::new();
+        for p in &old {
+            let id = pid(p);
+            let d = match cur.iter().find(|c| pid(c) == id) {
+                Some(c) if c == p => ProviderDiff::Same,
+                Some(_) => ProviderDiff::Changed,
+                None => ProviderDiff::Removed,
+            };
+            out.push((id, d));
+        }
+        for c in &cur {
+            let id = pid(c);
+            if !old.iter().any(|p| pid(p) == id) { out.push((id, ProviderDiff::Added)); }
+        }
+        out
+    }
+
+    pub fn move_up(&mut self) {
+        if self.focus_providers {
+            if self.provider_sel > 0 { self.provider_sel -= 1; }
+        } else if self.selected > 0 {
+            self.selected -= 1;
+            self.provider_sel = 0;
+        }
+    }
+
+    pub fn move_down(&mut self) {
+        if self.focus_providers {
+            if self.provider_sel + 1 < self.provider_diffs().len() { self.provider_sel += 1; }
+        } else if self.selected + 1 < self.snapshots.len() {
+            self.selected += 1;
+            self.provider_sel = 0;
+        }
+    }
+
+    /// Restore one provider from the selected snapshot into the store.
+    /// Providers added after the snapshot are left untouched.
+    pub fn restore_selected_provider(&mut self) -> Result<String> {
+        let snap = self.current_snapshot().ok_or_else(|| anyhow!("no snapshot selected"))?.clone();
+        let (id, diff) = self.provider_diffs().get(self.provider_sel).cloned().ok_or_else(|| anyhow!("no provider selected"))?;
+        if diff == ProviderDiff::Added { return Err(anyhow!("{} does not exist in this snapshot", id)); }
+        let from = providers_of(&snap.root).into_iter().find(|p| pid(p) == id).ok_or_else(|| anyhow!("provider {} not found", id))?;
+        let before = read_store();
+        let mut root = before.clone();
+        if !root.is_object() { root = serde_json::json!({}); }
+        let mut list = providers_of(&root);
+        match list.iter().position(|p| pid(p) == id) {
+            Some(i) => list[i] = from,
+            None => list.push(from),
+        }
+        if let Some(obj) = root.as_object_mut() { obj.insert("providers".to_string(), Value::Array(list)); }
+        let kept = self.snapshot_before_restore()?;
+        let path = store::write(&root)?;
+        let _ = audit::record("backup.restore", &path, &before, &root);
+        self.current = root;
+        Ok(format!("Restored {} from {}{}", id, snap.label, kept))
+    }
+
+    pub fn restore_all(&mut self) -> Result<String> {
+        let snap = self.current_snapshot().ok_or_else(|| anyhow!("no snapshot selected"))?.clone();
+        let before = read_store();
+        let kept = self.snapshot_before_restore()?;
+        let path = store::write(&snap.root)?;
+        let _ = audit::record("backup.restore", &path, &before, &snap.root);
+        self.current = snap.root.clone();
+        Ok(format!("Restored snapshot {}{}", snap.label, kept))
+    }
+
+    /// Snapshot the store a restore is about to overwrite, so the restore
+    /// can itself be undone, and reload the list with the same snapshot
+    /// selected. Returns a note for the status line.
+    fn snapshot_before_restore(&mut self) -> Result<String> {
+        if !store::exists() { return Ok(String::new()); }
+        let label = self.current_snapshot().map(|s| s.label.clone());
+        let path = snapshot_now().map_err(|e| anyhow!("snapshot before restore failed, nothing restored: {}", e))?;
+        let (focus_providers, provider_sel) = (self.focus_providers, self.provider_sel);
+        *self = BackupsState { focus_providers, provider_sel, ..load_backups() };
+        self.selected = label.and_then(|l| self.snapshots.iter().position(|s| s.label == l)).unwrap_or(0);
+        Ok(format!(" (previous store kept as {})", label_of(&path)))
+    }
+}
+
+pub fn draw_backups(f: &mut Frame, area: Rect, app: &App) {
+    let cols = split_panes(app.stacked(), 40, area);
+    let Some(st) = &app.backups else {
+        f.render_widget(Paragraph::new("Loading backups...").block(Block::default().borders(Borders::ALL)), area);
+        return;
+    };
+    let sel_style = Style::default().fg(app.theme.selected).add_modifier(Modifier::BOLD);
+    let mut items: Vec<ListItem> = Vec::new();
+    for (i, s) in st.snapshots.iter().enumerate() {
+        let style = if i == st.selected { sel_style } else { Style::default().fg(app.theme.fg) };
+        // Labels are the UTC stamp from the file name; show its age next to it
+        let age = locale::ago_utc(&s.label, "%Y%m%d-%H%M%S").map(|a| format!("  {}", a)).unwrap_or_default();
+        items.push(ListItem::new(Line::from(Span::styled(format!("{} {}{}", if i == st.selected { '›' } else { ' ' }, s.label, age), style))));
+    }
+    if st.snapshots.is_empty() { items.push(ListItem::new("No snapshots yet (n: snapshot now)")); }
+    let list = List::new(items).block(pane_block(&app.theme, &format!("Snapshots (keep {})", KEEP_SNAPSHOTS), !st.focus_providers));
+    f.render_widget(list, cols[0]);
+
+    let mut lines: Vec<Line> = Vec::new();
+    for (i, (id, d)) in st.provider_diffs().iter().enumerate() {
+        let (tag, color) = match d {
+            ProviderDiff::Same => ("=", app.theme.fg),
+            ProviderDiff::Changed => ("~ changed", app.theme.warn),
+            ProviderDiff::Removed => ("- missing now", app.theme.err),
+            ProviderDiff::Added => ("+ added since", app.theme.ok),
+        };
+        let mut style = Style::default().fg(color);
+        if st.focus_providers && i == st.provider_sel { style = style.add_modifier(Modifier::BOLD | Modifier::UNDERLINED); }
+        lines.push(Line::from(Span::styled(format!("{} {}  {}", if st.focus_providers && i == st.provider_sel { '›' } else { ' ' }, id, tag), style)));
+    }
+    if let Some(msg) = &st.status {
+        lines.push(Line::from(""));
+        let (txt, style) = app.theme.status_text(msg);
+        lines.push(Line::from(Span::styled(txt, style)));
+    }
+    let p = Paragraph::new(lines)
+        .style(Style::default().bg(app.theme.bg).fg(app.theme.fg))
+        .block(pane_block(&app.theme, "Providers vs current", st.focus_providers))
+        .wrap(Wrap { trim: true });
+    f.render_widget(p, cols[1]);
+}
+
+#[cfg(all(test, unix))]
+mod tests {
+    use super::*;
+
+    fn store_with(ids: &[&str]) -> Value {
+        let providers: Vec<Value> = ids.iter().map(|id| serde_json::json!({"id": id, "type": "ollama", "config": {}})).collect();
+        serde_json::json!({"providers": providers})
+    }
+
+    fn ids(root: &Value) -> Vec<String> {
+        providers_of(root).iter().map(pid).collect()
+    }
+
+    #[test]
+    fn only_the_newest_snapshots_are_kept() {
+        let _fake = crate::testing::FakeCli::new();
+        store::write(&store_with(&["a"])).expect("store");
+        let first = snapshot_now().expect("first");
+        let mut last = first.clone();
+        for _ in 0..KEEP_SNAPSHOTS + 2 {
+            last = snapshot_now().expect("snapshot");
+        }
+        let paths = list_snapshot_paths();
+        assert_eq!(paths.len(), KEEP_SNAPSHOTS);
+        assert_eq!(paths[0], last);
+        assert!(!first.exists());
+    }
+
+    #[test]
+    fn a_daily_snapshot_is_skipped_when_one_is_recent() {
+        let _fake = crate::testing::FakeCli::new();
+        assert_eq!(maybe_snapshot().expect("no store"), None);
+        store::write(&store_with(&["a"])).expect("store");
+        assert!(maybe_snapshot().expect("first").is_some());
+        assert_eq!(maybe_snapshot().expect("recent"), None);
+    }
+
+    #[test]
+    fn restoring_keeps_the_overwritten_store_as_a_snapshot() {
+        let _fake = crate::testing::FakeCli::new();
+        store::write(&store_with(&["a", "b"])).expect("store");
+        snapshot_now().expect("snapshot");
+        store::write(&store_with(&["a", "c"])).expect("edit");
+
+        let mut st = load_backups();
+        let diffs = st.provider_diffs();
+        assert!(diffs.contains(&("b".to_string(), ProviderDiff::Removed)), "{:?}", diffs);
+        assert!(diffs.contains(&("c".to_string(), ProviderDiff::Added)), "{:?}", diffs);
+        let restored_label = st.current_snapshot().expect("selected").label.clone();
+
+        let msg = st.restore_all().expect("restore");
+        assert!(msg.contains("previous store kept"), "{}", msg);
+        assert_eq!(ids(&store::read().expect("read")), ["a", "b"]);
+        // The list now starts with the pre-restore store; the selection stays
+        assert_eq!(st.snapshots.len(), 2);
+        assert_eq!(ids(&st.snapshots[0].root), ["a", "c"]);
+        assert_eq!(st.current_snapshot().expect("selected").label, restored_label);
+
+        // Undo: restore the snapshot taken before the restore
+        st.selected = 0;
+        st.restore_all().expect("undo");
+        assert_eq!(ids(&store::read().expect("read")), ["a", "c"]);
+    }
+
+    #[test]
+    fn restoring_one_provider_leaves_the_others() {
+        let _fake = crate::testing::FakeCli::new();
+        store::write(&store_with(&["a", "b"])).expect("store");
+        snapshot_now().expect("snapshot");
+        store::write(&store_with(&["c"])).expect("edit");
+
+        let mut st = load_backups();
+        st.focus_providers = true;
+        st.provider_sel = st.provider_diffs().iter().position(|(id, _)| id == "b").expect("b");
+        st.restore_selected_provider().expect("restore b");
+        assert_eq!(ids(&store::read().expect("read")), ["c", "b"]);
+        assert!(st.focus_providers);
+
+        st.provider_sel = st.provider_diffs().iter().position(|(id, _)| id == "c").expect("c");
+        assert!(st.restore_selected_provider().is_err(), "c is not in the snapshot");
+    }
+}
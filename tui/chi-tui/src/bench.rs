@@ -0,0 +1,119 @@
+//! Frame-time benchmarks for the render path: every page at several
+//! terminal sizes, the header gradient and overlays, drawn into ratatui's
+//! `TestBackend`. They are `#[ignore]`d so plain `cargo test` stays fast;
+//! run them in release to compare before/after a change:
+//!
+//!     cargo test --release bench_ -- --ignored --nocapture --test-threads=1
+//!
+//! Each benchmark fails when the median frame exceeds its budget.
+
+use std::time::{Duration, Instant};
+
+use ratatui::backend::TestBackend;
+use ratatui::Terminal;
+
+use crate::app::{App, Page};
+use crate::testing::FakeCli;
+use crate::theme::Theme;
+use crate::util::neon_gradient_line;
+
+/// Terminal sizes: compact layout, the classic 80×24, a laptop and a wide
+/// monitor.
+const SIZES: [(u16, u16); 4] = [(50, 16), (80, 24), (120, 40), (240, 70)];
+const ITERATIONS: u32 = 200;
+
+/// Median frame budget in release builds: well under one 60 Hz frame, so
+/// input never waits on rendering. Debug builds get ten times as much.
+const FRAME_BUDGET: Duration = Duration::from_millis(4);
+
+fn budget(d: Duration) -> Duration {
+    if cfg!(debug_assertions) { d * 10 } else { d }
+}
+
+/// Median of `ITERATIONS` runs of `f`, after a few warm-up runs.
+fn median(mut f: impl FnMut()) -> Duration {
+    for _ in 0..5 { f(); }
+    let mut times: Vec<Duration> = (0..ITERATIONS)
+        .map(|_| {
+            let t = Instant::now();
+            f();
+            t.elapsed()
+        })
+        .collect();
+    times.sort();
+    times[times.len() / 2]
+}
+
+fn frame_time(app: &mut App, w: u16, h: u16) -> Duration {
+    let mut term = Terminal::new(TestBackend::new(w, h)).expect("test terminal");
+    app.fit(w, h);
+    median(|| {
+        // A full redraw each time, as after a resize or page switch
+        term.clear().expect("clear");
+        term.draw(|f| crate::ui::draw(f, app)).expect("draw");
+    })
+}
+
+/// A long README so the Readme page has real scrolling work.
+fn write_readme() {
+    let mut text = String::from("# chi_llm\n\n");
+    for s in 0..60 {
+        text.push_str(&format!("## Section {}\n\n", s));
+        for l in 0..30 {
+            text.push_str(&format!("- item {} of section {}: `code`, **bold** and a longer tail of prose to wrap on narrow terminals\n", l, s));
+        }
+        text.push('\n');
+    }
+    std::fs::write("README.md", text).expect("write README");
+}
+
+/// App with every page's state loaded the way opening it from the menu does.
+fn loaded_app(page: Page) -> App {
+    let mut app = App::new(false);
+    crate::open_page_loaded(&mut app, page);
+    app
+}
+
+#[test]
+#[ignore]
+fn bench_pages() {
+    let _fake = FakeCli::new();
+    write_readme();
+    let mut slow = Vec::new();
+    println!("{:<16} {}", "page", SIZES.map(|(w, h)| format!("{:>10}", format!("{}x{}", w, h))).join(""));
+    for page in Page::ALL {
+        let mut app = loaded_app(page);
+        let mut row = format!("{:<16}", page.key());
+        for (w, h) in SIZES {
+            let t = frame_time(&mut app, w, h);
+            row.push_str(&format!("{:>10}", format!("{:.2?}", t)));
+            if t > budget(FRAME_BUDGET) { slow.push(format!("{} at {}x{}: {:.2?}", page.key(), w, h, t)); }
+        }
+        println!("{}", row);
+    }
+    assert!(slow.is_empty(), "over the {:?} frame budget: {}", budget(FRAME_BUDGET), slow.join("; "));
+}
+
+#[test]
+#[ignore]
+fn bench_overlays() {
+    let _fake = FakeCli::new();
+    let mut app = loaded_app(Page::Welcome);
+    app.show_help = true;
+    let help = frame_time(&mut app, 120, 40);
+    app.show_help = false;
+    app.shutdown = Some(Default::default());
+    let quit = frame_time(&mut app, 120, 40);
+    println!("help overlay {:.2?} • quit dialog {:.2?}", help, quit);
+    assert!(help.max(quit) <= budget(FRAME_BUDGET), "overlay over the frame budget: help {:.2?}, quit {:.2?}", help, quit);
+}
+
+#[test]
+#[ignore]
+fn bench_header_gradient() {
+    let theme = Theme::synthwave_dark();
+    let title = " chi_llm — micro‑LLM • TUI vNext ".repeat(8);
+    let t = median(|| { std::hint::black_box(neon_gradient_line(std::hint::black_box(&title), &theme)); });
+    println!("neon_gradient_line ({} chars): {:.2?}", title.chars().count(), t);
+    assert!(t <= budget(Duration::from_micros(200)), "neon_gradient_line took {:.2?}", t);
+}
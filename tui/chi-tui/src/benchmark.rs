@@ -0,0 +1,358 @@
+//! Model benchmark: a small fixed prompt set streamed from one provider's
+//! configured model, timing the first token and the reply of each prompt.
+//! Runs are kept in `~/.cache/chi_llm/benchmarks.json` so local models can
+//! be compared before one becomes the default.
+
+use std::fs;
+use std::path::PathBuf;
+use std::sync::mpsc::{channel, Receiver, TryRecvError};
+use std::thread;
+use std::time::Duration;
+
+use anyhow::{anyhow, Result};
+use ratatui::layout::{Constraint, Direction, Layout, Rect};
+use ratatui::prelude::Frame;
+use ratatui::style::{Modifier, Style};
+use ratatui::text::{Line, Span};
+use ratatui::widgets::{Block, Borders, Cell, Paragraph, Row, Table, TableState};
+use serde_json::Value;
+
+use crate::app::App;
+use crate::deeptest;
+use crate::inspector;
+use crate::locale;
+use crate::log;
+use crate::providers::{read_scratch_entries, ProviderScratchEntry};
+use crate::theme::StatusKind;
+use crate::variables;
+
+/// The standard set: a short explanation, some code and a summary, so
+/// replies are long enough for a stable tokens/sec figure.
+pub const PROMPTS: [&str; 3] = [
+    "Explain in three sentences what a hash map is.",
+    "Write a Python function that returns the n-th Fibonacci number iteratively.",
+    "Summarize the plot of Romeo and Juliet in one paragraph.",
+];
+const MAX_TOKENS: u32 = 160;
+/// Per prompt; the first one may wait for the model to load
+const TIMEOUT: Duration = Duration::from_secs(180);
+/// Runs kept in the history file.
+const MAX_HISTORY: usize = 200;
+
+/// One benchmark run of a provider's model over `PROMPTS`.
+#[derive(Clone, Debug, PartialEq)]
+pub struct BenchRecord {
+    /// RFC 3339
+    pub at: String,
+    pub provider_id: String,
+    pub provider: String,
+    pub ptype: String,
+    pub model: String,
+    pub tokens: u64,
+    /// Generated tokens over generation time (after the first token)
+    pub tokens_per_sec: Option<f64>,
+    /// Median time to first token across the prompts
+    pub ttft_ms: Option<u64>,
+    /// Slowest complete reply
+    pub peak_ms: u64,
+}
+
+impl BenchRecord {
+    fn to_json(&self) -> Value {
+        serde_json::json!({
+            "at": self.at,
+            "provider_id": self.provider_id,
+            "provider": self.provider,
+            "type": self.ptype,
+            "model": self.model,
+            "tokens": self.tokens,
+            "tokens_per_sec": self.tokens_per_sec,
+            "ttft_ms": self.ttft_ms,
+            "peak_ms": self.peak_ms,
+        })
+    }
+
+    fn from_json(v: &Value) -> Option<BenchRecord> {
+        let text = |k: &str| v.get(k).and_then(|x| x.as_str()).map(|s| s.to_string());
+        Some(BenchRecord {
+            at: text("at")?,
+            provider_id: text("provider_id").unwrap_or_default(),
+            provider: text("provider").unwrap_or_default(),
+            ptype: text("type").unwrap_or_default(),
+            model: text("model")?,
+            tokens: v.get("tokens").and_then(|x| x.as_u64()).unwrap_or(0),
+            tokens_per_sec: v.get("tokens_per_sec").and_then(|x| x.as_f64()),
+            ttft_ms: v.get("ttft_ms").and_then(|x| x.as_u64()),
+            peak_ms: v.get("peak_ms").and_then(|x| x.as_u64()).unwrap_or(0),
+        })
+    }
+}
+
+/// Combine the per-prompt results of one run.
+pub fn summarize(entry: &ProviderScratchEntry, results: &[deeptest::DeepResult]) -> BenchRecord {
+    let tokens: u64 = results.iter().map(|r| r.tokens).sum();
+    // Generation time excludes the wait for the first token, which TTFT covers
+    let gen_secs: f64 = results.iter().map(|r| (r.total - r.first_token.unwrap_or_default()).as_secs_f64()).sum();
+    let mut ttfts: Vec<u64> = results.iter().filter_map(|r| r.first_token).map(|d| d.as_millis() as u64).collect();
+    ttfts.sort_unstable();
+    BenchRecord {
+        at: chrono::Utc::now().to_rfc3339(),
+        provider_id: entry.id.clone(),
+        provider: entry.name.clone(),
+        ptype: entry.ptype.clone(),
+        model: results.first().map(|r| r.model.clone()).unwrap_or_default(),
+        tokens,
+        tokens_per_sec: Some(tokens as f64 / gen_secs).filter(|t| tokens > 0 && t.is_finite()),
+        ttft_ms: ttfts.get(ttfts.len() / 2).copied(),
+        peak_ms: results.iter().map(|r| r.total.as_millis() as u64).max().unwrap_or(0),
+    }
+}
+
+pub fn history_path() -> Result<PathBuf> {
+    let home = dirs::home_dir().ok_or_else(|| anyhow!("home dir not found"))?;
+    Ok(home.join(".cache").join("chi_llm").join("benchmarks.json"))
+}
+
+/// Saved runs, oldest first; a missing or unreadable file is an empty history.
+pub fn load_history() -> Vec<BenchRecord> {
+    let Ok(path) = history_path() else { return Vec::new() };
+    let v: Value = fs::read_to_string(path).ok().and_then(|t| serde_json::from_str(&t).ok()).unwrap_or(Value::Null);
+    v.as_array().map(|a| a.iter().filter_map(BenchRecord::from_json).collect()).unwrap_or_default()
+}
+
+pub fn save_history(history: &[BenchRecord]) -> Result<()> {
+    let path = history_path()?;
+    if let Some(dir) = path.parent() { fs::create_dir_all(dir)?; }
+    let keep = &history[history.len().saturating_sub(MAX_HISTORY)..];
+    let list: Vec<Value> = keep.iter().map(BenchRecord::to_json).collect();
+    fs::write(path, serde_json::to_vec_pretty(&Value::Array(list))?)?;
+    Ok(())
+}
+
+enum BenchMsg {
+    /// Prompts finished so far
+    Progress(usize),
+    Done(Result<BenchRecord, String>),
+}
+
+/// Benchmark page: providers with a chat API, the saved runs and the one
+/// running in the background.
+pub struct BenchmarkState {
+    pub entries: Vec<ProviderScratchEntry>,
+    pub provider_idx: usize,
+    /// Oldest first; drawn newest first
+    pub history: Vec<BenchRecord>,
+    /// Selected history row, counted from the newest
+    pub selected: usize,
+    /// (provider id, prompts done) while a run is going
+    pub running: Option<(String, usize)>,
+    pub status: Option<String>,
+    rx: Option<Receiver<BenchMsg>>,
+}
+
+pub fn load_benchmark() -> BenchmarkState {
+    let (entries, status) = match read_scratch_entries() {
+        Ok(e) => {
+            let e: Vec<_> = e.into_iter().filter(inspector::supports_chat).collect();
+            let status = if e.is_empty() { Some("No providers with a chat API configured".to_string()) } else { None };
+            (e, status)
+        }
+        Err(e) => (Vec::new(), Some(format!("Error: {}", e))),
+    };
+    BenchmarkState { entries, provider_idx: 0, history: load_history(), selected: 0, running: None, status, rx: None }
+}
+
+impl BenchmarkState {
+    pub fn next_provider(&mut self) {
+        if !self.entries.is_empty() { self.provider_idx = (self.provider_idx + 1) % self.entries.len(); }
+    }
+
+    pub fn prev_provider(&mut self) {
+        if !self.entries.is_empty() { self.provider_idx = (self.provider_idx + self.entries.len() - 1) % self.entries.len(); }
+    }
+
+    pub fn move_up(&mut self) {
+        self.selected = self.selected.saturating_sub(1);
+    }
+
+    pub fn move_down(&mut self) {
+        if self.selected + 1 < self.history.len() { self.selected += 1; }
+    }
+
+    /// Benchmark the selected provider's model off the UI thread.
+    pub fn start(&mut self) {
+        if let Some((id, _)) = &self.running {
+            self.status = Some(format!("Warning: benchmark of {} still running", id));
+            return;
+        }
+        let Some(entry) = self.entries.get(self.provider_idx) else { return };
+        let entry = variables::resolve_entry(entry);
+        let (tx, rx) = channel();
+        self.running = Some((entry.id.clone(), 0));
+        self.status = Some(format!("Benchmarking {}: prompt 1/{}…", entry.id, PROMPTS.len()));
+        self.rx = Some(rx);
+        thread::spawn(move || {
+            let mut results = Vec::new();
+            for (i, prompt) in PROMPTS.iter().enumerate() {
+                match deeptest::stream(&entry, prompt, MAX_TOKENS, TIMEOUT) {
+                    Ok(r) => results.push(r),
+                    Err(e) => {
+                        let _ = tx.send(BenchMsg::Done(Err(format!("prompt {}: {}", i + 1, e))));
+                        return;
+                    }
+                }
+                let _ = tx.send(BenchMsg::Progress(i + 1));
+            }
+            let _ = tx.send(BenchMsg::Done(Ok(summarize(&entry, &results))));
+        });
+    }
+
+    /// Apply progress from the runner; true when something changed.
+    pub fn poll(&mut self) -> bool {
+        let mut changed = false;
+        while let Some(rx) = &self.rx {
+            let msg = match rx.try_recv() {
+                Ok(m) => m,
+                Err(TryRecvError::Empty) => break,
+                Err(TryRecvError::Disconnected) => BenchMsg::Done(Err("benchmark stopped unexpectedly".to_string())),
+            };
+            changed = true;
+            let id = self.running.as_ref().map(|(id, _)| id.clone()).unwrap_or_default();
+            match msg {
+                BenchMsg::Progress(done) => {
+                    self.running = Some((id.clone(), done));
+                    if done < PROMPTS.len() { self.status = Some(format!("Benchmarking {}: prompt {}/{}…", id, done + 1, PROMPTS.len())); }
+                }
+                BenchMsg::Done(res) => {
+                    self.rx = None;
+                    self.running = None;
+                    self.status = Some(match res {
+                        Ok(rec) => {
+                            log::info(&format!("benchmark {} {}: {:?} tok/s, ttft {:?} ms, peak {} ms", id, rec.model, rec.tokens_per_sec, rec.ttft_ms, rec.peak_ms));
+                            self.history.push(rec);
+                            self.selected = 0;
+                            match save_history(&self.history) {
+                                Ok(()) => format!("Benchmark of {} saved", id),
+                                Err(e) => format!("Error: benchmark done, but saving the history failed: {}", e),
+                            }
+                        }
+                        Err(e) => {
+                            log::warn(&format!("benchmark {} failed: {}", id, e));
+                            format!("Error: benchmark {}: {}", id, e)
+                        }
+                    });
+                }
+            }
+        }
+        changed
+    }
+
+    /// Remove the selected history row.
+    pub fn delete_selected(&mut self) -> Result<()> {
+        if self.history.is_empty() { return Ok(()); }
+        let idx = self.history.len() - 1 - self.selected.min(self.history.len() - 1);
+        self.history.remove(idx);
+        if self.selected >= self.history.len() { self.selected = self.history.len().saturating_sub(1); }
+        save_history(&self.history)
+    }
+}
+
+fn ms(v: u64) -> String {
+    format!("{} ms", locale::count(v))
+}
+
+pub fn draw_benchmark(f: &mut Frame, area: Rect, app: &App) {
+    let Some(st) = &app.benchmark else {
+        f.render_widget(Paragraph::new("Loading...").block(Block::default().borders(Borders::ALL)), area);
+        return;
+    };
+    let chunks = Layout::default().direction(Direction::Vertical).constraints([Constraint::Length(4), Constraint::Min(3)]).split(area);
+
+    let provider = match st.entries.get(st.provider_idx) {
+        Some(e) => {
+            let model = e.config.get("model").and_then(|v| v.as_str()).filter(|m| !m.trim().is_empty()).unwrap_or("(no model set)");
+            Line::from(vec![
+                Span::raw("Provider: "),
+                Span::styled(format!("‹ {} ›", e.name), Style::default().fg(app.theme.selected).add_modifier(Modifier::BOLD)),
+                Span::raw(format!("  {} • {}  ({} prompts, up to {} tokens each)", e.ptype, model, PROMPTS.len(), MAX_TOKENS)),
+            ])
+        }
+        None => Line::from("Provider: none"),
+    };
+    let mut lines = vec![provider];
+    if let Some(msg) = &st.status {
+        let (txt, style) = app.theme.status_text(msg);
+        lines.push(Line::from(Span::styled(txt, style)));
+    }
+    let top = Paragraph::new(lines)
+        .style(Style::default().bg(app.theme.bg).fg(app.theme.fg))
+        .block(Block::default().borders(Borders::ALL).border_style(Style::default().fg(app.theme.frame)).title("Benchmark"));
+    f.render_widget(top, chunks[0]);
+
+    // Best tokens/sec per model is starred, to compare runs at a glance
+    let best = |r: &BenchRecord| {
+        r.tokens_per_sec.map_or(false, |t| {
+            st.history.iter().filter(|o| o.model == r.model).filter_map(|o| o.tokens_per_sec).all(|o| o <= t)
+        })
+    };
+    let header = Row::new(["When", "Provider", "Model", "tok/s", "TTFT", "Peak"].map(Cell::from))
+        .style(Style::default().fg(app.theme.primary).add_modifier(Modifier::BOLD));
+    let rows: Vec<Row> = st
+        .history
+        .iter()
+        .rev()
+        .enumerate()
+        .map(|(i, r)| {
+            let when = locale::ago_rfc3339(&r.at);
+            let tps = r.tokens_per_sec.map_or("-".to_string(), |t| locale::decimal(t, 1));
+            let tps = if best(r) { Span::styled(format!("{} ★", tps), app.theme.status_style(StatusKind::Ok)) } else { Span::raw(tps) };
+            let style = if i == st.selected { Style::default().fg(app.theme.selected).add_modifier(Modifier::BOLD) } else { Style::default().fg(app.theme.fg) };
+            Row::new(vec![
+                Cell::from(when),
+                Cell::from(r.provider.clone()),
+                Cell::from(r.model.clone()),
+                Cell::from(tps),
+                Cell::from(r.ttft_ms.map_or("-".to_string(), ms)),
+                Cell::from(ms(r.peak_ms)),
+            ])
+            .style(style)
+        })
+        .collect();
+    let widths = [Constraint::Length(14), Constraint::Percentage(20), Constraint::Percentage(30), Constraint::Length(10), Constraint::Length(10), Constraint::Length(10)];
+    let title = format!("History ({} runs, {})", st.history.len(), history_path().map(|p| p.display().to_string()).unwrap_or_default());
+    let table = Table::new(rows, widths)
+        .header(header)
+        .style(Style::default().bg(app.theme.bg).fg(app.theme.fg))
+        .block(Block::default().borders(Borders::ALL).border_style(Style::default().fg(app.theme.frame)).title(title));
+    let mut state = TableState::default().with_selected(Some(st.selected).filter(|_| !st.history.is_empty()));
+    f.render_stateful_widget(table, chunks[1], &mut state);
+}
+
+#[cfg(all(test, unix))]
+mod tests {
+    use super::*;
+    use crate::config_cli::run_config;
+    use crate::deeptest::DeepResult;
+    use crate::testing::{add_provider, FakeCli};
+
+    #[test]
+    fn benchmark_runs_are_summarised_and_kept() {
+        let _fake = FakeCli::new();
+        run_config(add_provider("ollama", "home", &[("model", "qwen3:4b")], false)).expect("add provider");
+        let entry = read_scratch_entries().expect("entries").remove(0);
+        let reply = |first: u64, total: u64, tokens: u64| DeepResult {
+            model: "qwen3:4b".to_string(),
+            first_token: Some(Duration::from_millis(first)),
+            total: Duration::from_millis(total),
+            text: String::new(),
+            tokens,
+        };
+        let rec = summarize(&entry, &[reply(300, 2300, 40), reply(100, 1100, 20), reply(200, 2200, 40)]);
+        assert_eq!((rec.tokens, rec.ttft_ms, rec.peak_ms), (100, Some(200), 2300));
+        assert_eq!(rec.tokens_per_sec, Some(20.0));
+
+        assert!(load_history().is_empty());
+        save_history(&[rec.clone()]).expect("save history");
+        assert_eq!(load_history(), vec![rec]);
+    }
+}
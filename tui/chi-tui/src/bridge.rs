@@ -0,0 +1,195 @@
+//! One long-lived `chi-llm serve --stdio` answering JSON-RPC on its
+//! stdin/stdout, so the many short CLI calls (schema, discovery, tags) pay
+//! Python's startup once. `run_cli_json` asks it first and spawns `chi-llm`
+//! per call as before when the installed CLI has no `--stdio`, when the
+//! bridge is busy with another thread's call, or with `CHI_TUI_BRIDGE=off`.
+
+use std::io::{BufRead, BufReader, Write};
+use std::process::{Child, ChildStdin, Command, Stdio};
+use std::sync::mpsc::{self, Receiver, RecvTimeoutError};
+use std::sync::Mutex;
+use std::time::Duration;
+
+use anyhow::{anyhow, Result};
+use serde_json::{json, Value};
+
+use crate::log;
+
+/// How long a fresh `chi-llm serve --stdio` gets to answer `chi.hello`.
+const HELLO_TIMEOUT: Duration = Duration::from_secs(10);
+
+static BRIDGE: Mutex<State> = Mutex::new(State::Idle);
+
+enum State {
+    Idle,
+    Running(Bridge),
+    /// The `chi-llm` found with this key has no `--stdio`; not retried
+    Unsupported(String),
+}
+
+struct Bridge {
+    child: Child,
+    stdin: ChildStdin,
+    lines: Receiver<String>,
+    next_id: u64,
+    key: String,
+}
+
+impl Bridge {
+    fn spawn(key: String) -> Result<Bridge> {
+        let mut child = Command::new("chi-llm")
+            .args(["serve", "--stdio"])
+            .stdin(Stdio::piped())
+            .stdout(Stdio::piped())
+            .stderr(Stdio::null())
+            .spawn()?;
+        let stdin = child.stdin.take().ok_or_else(|| anyhow!("no stdin"))?;
+        let stdout = child.stdout.take().ok_or_else(|| anyhow!("no stdout"))?;
+        let (tx, lines) = mpsc::channel();
+        std::thread::spawn(move || {
+            for line in BufReader::new(stdout).lines().map_while(|l| l.ok()) {
+                if tx.send(line).is_err() { break; }
+            }
+        });
+        let mut bridge = Bridge { child, stdin, lines, next_id: 0, key };
+        match bridge.request("chi.hello", json!({}), HELLO_TIMEOUT) {
+            Ok(hello) if hello.get("version").is_some() => Ok(bridge),
+            Ok(_) => { bridge.stop(); Err(anyhow!("no version in chi.hello")) }
+            Err(e) => { bridge.stop(); Err(e) }
+        }
+    }
+
+    /// The `result` of one request. Replies to earlier, timed-out requests
+    /// are skipped by id.
+    fn request(&mut self, method: &str, params: Value, timeout: Duration) -> Result<Value> {
+        let id = self.next_id;
+        self.next_id += 1;
+        let line = json!({"jsonrpc": "2.0", "id": id, "method": method, "params": params});
+        writeln!(self.stdin, "{}", line)?;
+        self.stdin.flush()?;
+        let deadline = std::time::Instant::now() + timeout;
+        loop {
+            let left = deadline.saturating_duration_since(std::time::Instant::now());
+            let reply: Value = match self.lines.recv_timeout(left) {
+                Ok(text) => serde_json::from_str(&text)?,
+                Err(RecvTimeoutError::Timeout) => return Err(RecvTimeoutError::Timeout.into()),
+                Err(RecvTimeoutError::Disconnected) => return Err(anyhow!("chi-llm serve --stdio exited")),
+            };
+            if reply.get("id").and_then(|v| v.as_u64()) != Some(id) { continue; }
+            if let Some(err) = reply.get("error") {
+                return Err(anyhow!("{}", err.get("message").and_then(|m| m.as_str()).unwrap_or("bridge error")));
+            }
+            return Ok(reply.get("result").cloned().unwrap_or(Value::Null));
+        }
+    }
+
+    fn stop(&mut self) {
+        let _ = self.child.kill();
+        let _ = self.child.wait();
+    }
+}
+
+fn disabled() -> bool {
+    std::env::var("CHI_TUI_BRIDGE").map_or(false, |v| matches!(v.trim().to_lowercase().as_str(), "off" | "0" | "false"))
+}
+
+/// Which `chi-llm` a bridge belongs to: a changed PATH or HOME (tests,
+/// profiles) gets a fresh one.
+fn key() -> String {
+    format!("{}\n{}", std::env::var("PATH").unwrap_or_default(), std::env::var("HOME").unwrap_or_default())
+}
+
+/// `chi-llm <args>` through the bridge, with the same errors as a separate
+/// run. `None` when the bridge cannot take the call and the caller should
+/// spawn `chi-llm` itself.
+pub fn run(args: &[&str], timeout: Duration) -> Option<Result<Value>> {
+    if disabled() { return None; }
+    // Another thread's call is in flight: a separate process is quicker
+    let mut state = BRIDGE.try_lock().ok()?;
+    let key = key();
+    match &mut *state {
+        State::Unsupported(k) if *k == key => return None,
+        State::Running(b) if b.key != key => { b.stop(); *state = State::Idle; }
+        State::Unsupported(_) => *state = State::Idle,
+        _ => {}
+    }
+    if let State::Idle = *state {
+        match Bridge::spawn(key.clone()) {
+            Ok(b) => { log::debug("chi-llm serve --stdio started"); *state = State::Running(b); }
+            Err(e) => {
+                log::debug(&format!("chi-llm serve --stdio unavailable, running chi-llm per call: {}", e));
+                *state = State::Unsupported(key);
+                return None;
+            }
+        }
+    }
+    let State::Running(bridge) = &mut *state else { return None };
+    let cwd = std::env::current_dir().map(|d| d.display().to_string()).unwrap_or_default();
+    let result = match bridge.request("cli.run", json!({"args": args, "cwd": cwd}), timeout) {
+        Ok(r) => r,
+        Err(e) => {
+            // A hung or dead bridge is replaced on the next call
+            bridge.stop();
+            *state = State::Idle;
+            return if matches!(e.downcast_ref::<RecvTimeoutError>(), Some(RecvTimeoutError::Timeout)) {
+                Some(Err(anyhow!("chi-llm {:?} timed out after {:?}", args, timeout)))
+            } else {
+                None
+            };
+        }
+    };
+    if result.get("code").and_then(|c| c.as_i64()) != Some(0) {
+        let stderr = result.get("stderr").and_then(|s| s.as_str()).unwrap_or_default();
+        return Some(Err(anyhow!("chi-llm {:?} failed: {}", args, stderr)));
+    }
+    let stdout = result.get("stdout").and_then(|s| s.as_str()).unwrap_or_default();
+    Some(serde_json::from_str(stdout).map_err(Into::into))
+}
+
+#[cfg(all(test, unix))]
+mod tests {
+    use super::*;
+    use crate::providers::load_providers_state;
+    use crate::testing::FakeCli;
+    use crate::util::run_cli_json;
+
+    #[test]
+    fn cli_calls_share_one_stdio_bridge() {
+        let mut fake = FakeCli::new();
+        fake.set_env("CHI_TUI_BRIDGE", "on".to_string());
+        // A chi-llm that only speaks `serve --stdio` and echoes what it ran
+        let script = r#"#!/usr/bin/env python3
+import json, os, sys
+if sys.argv[1:] != ["serve", "--stdio"]:
+    sys.exit(2)
+for line in sys.stdin:
+    req = json.loads(line)
+    if req["method"] == "chi.hello":
+        result = {"version": "fake"}
+    else:
+        args = req["params"]["args"]
+        out = json.dumps({"args": args, "pid": os.getpid(), "cwd": req["params"]["cwd"]})
+        result = {"code": 1 if args[0] == "fail" else 0, "stdout": out, "stderr": "boom"}
+    print(json.dumps({"jsonrpc": "2.0", "id": req["id"], "result": result}), flush=True)
+"#;
+        std::fs::write(fake.root.join("bin").join("chi-llm"), script).expect("write bridge script");
+
+        let first = run_cli_json(&["tags", "--json"], Duration::from_secs(10)).expect("through the bridge");
+        let second = run_cli_json(&["providers", "schema", "--json"], Duration::from_secs(10)).expect("same bridge");
+        assert_eq!(first["args"], json!(["tags", "--json"]));
+        assert_eq!(first["pid"], second["pid"]);
+        assert_eq!(first["cwd"], json!(std::env::current_dir().expect("cwd").display().to_string()));
+        let err = run_cli_json(&["fail"], Duration::from_secs(10)).expect_err("exit code is kept");
+        assert!(err.to_string().ends_with("failed: boom"), "{}", err);
+    }
+
+    #[test]
+    fn cli_calls_run_one_process_each_without_a_stdio_bridge() {
+        let mut fake = FakeCli::new();
+        fake.set_env("CHI_TUI_BRIDGE", "on".to_string());
+        load_providers_state().expect("schema");
+        load_providers_state().expect("schema again");
+        // The fake has no `serve --stdio`: asked once, then plain calls
+        assert_eq!(fake.calls(), vec!["serve --stdio", "providers schema --json", "providers schema --json"]);
+    }
+}
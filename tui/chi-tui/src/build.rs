@@ -1,12 +1,20 @@
+use std::path::PathBuf;
+
 use anyhow::{anyhow, Result};
 use ratatui::layout::Rect;
 use ratatui::prelude::Frame;
-use ratatui::style::{Color, Modifier, Style};
+use ratatui::style::{Modifier, Style};
 use ratatui::text::{Line, Span};
 use ratatui::widgets::{Block, Borders, Paragraph, Wrap};
 use serde_json::Value;
 
 use crate::app::App;
+use crate::audit;
+use crate::buildconflict::{draw_build_conflict, BuildConflict};
+use crate::configmerge::{draw_config_merge, ConfigMerge};
+use crate::portforward::K8S_FIELD_PREFIX;
+use crate::store::{self, Format};
+use crate::theme::StatusKind;
 
 #[derive(Copy, Clone, Debug, PartialEq, Eq, Default)]
 pub enum BuildTarget {
@@ -19,6 +27,10 @@ pub enum BuildTarget {
 pub struct BuildState {
     pub target: BuildTarget,
     pub status: Option<String>,
+    /// Existing project config to overwrite, merge or keep
+    pub conflict: Option<BuildConflict>,
+    /// Per-field merge of the project config and the global one
+    pub merge: Option<ConfigMerge>,
 }
 
 impl BuildState {
@@ -30,13 +42,66 @@ impl BuildState {
     }
 }
 
+/// What Build needs before it can write a config.
+#[derive(Copy, Clone, Debug, PartialEq, Eq)]
+pub enum Missing {
+    /// No provider in the project or global store
+    Providers,
+    /// Providers, but no `default_provider_id` naming one of them
+    Default,
+}
+
+/// The first unmet prerequisite of a write, if any. An unreadable store is
+/// left for Enter to report.
+pub fn missing_prerequisite() -> Option<Missing> {
+    let v = store::read_layered().ok()?;
+    if v.get("providers").and_then(|x| x.as_array()).map_or(true, |a| a.is_empty()) {
+        return Some(Missing::Providers);
+    }
+    get_default_provider_summary().is_err().then_some(Missing::Default)
+}
+
+/// Shown instead of the Build page until there is something to write.
+fn draw_build_guard(f: &mut Frame, area: Rect, app: &App, missing: Missing) {
+    let step = |done: bool, text: &str| {
+        let kind = if done { StatusKind::Ok } else { StatusKind::Err };
+        Line::from(Span::styled(format!("  {} {}", kind.symbol(), text), app.theme.status_style(kind)))
+    };
+    let key = |k: &str, text: &str| Line::from(vec![Span::styled(format!("  {:<4}", k), Style::default().fg(app.theme.selected).add_modifier(Modifier::BOLD)), Span::raw(text.to_string())]);
+    let mut lines = vec![
+        Line::from(Span::styled("Nothing to build yet", Style::default().fg(app.theme.primary).add_modifier(Modifier::BOLD))),
+        Line::from(""),
+        Line::from("Build writes the default provider into the chi_llm config. It needs:"),
+        step(missing != Missing::Providers, "at least one provider (Configure Providers)"),
+        step(false, "a default provider (Select Default; the first saved provider becomes it)"),
+        Line::from(""),
+    ];
+    if missing == Missing::Providers {
+        lines.push(key("c", "open Configure Providers to add one"));
+        lines.push(key("f", "find local servers (Ollama, LM Studio, vLLM, llama.cpp…) and add them"));
+    } else {
+        lines.push(key("d", "open Select Default to pick one"));
+        lines.push(key("c", "open Configure Providers"));
+    }
+    lines.push(key("Esc", "back"));
+    let p = Paragraph::new(lines)
+        .style(Style::default().bg(app.theme.bg).fg(app.theme.fg))
+        .block(Block::default().borders(Borders::ALL).border_style(Style::default().fg(app.theme.frame)).title("Build"))
+        .wrap(Wrap { trim: false });
+    f.render_widget(p, area);
+}
+
 pub fn draw_build_config(f: &mut Frame, area: Rect, app: &App) {
+    if let Some(missing) = missing_prerequisite() {
+        draw_build_guard(f, area, app, missing);
+        return;
+    }
     let mut lines: Vec<Line> = Vec::new();
     let target = app
         .build
         .as_ref()
         .map(|b| b.target)
-        .unwrap_or(BuildTarget::Project);
+        .unwrap_or(app.prefs.build_target);
     lines.push(Line::from(Span::styled(
         "Build/Write Configuration",
         Style::default()
@@ -44,8 +109,22 @@ pub fn draw_build_config(f: &mut Frame, area: Rect, app: &App) {
             .add_modifier(Modifier::BOLD),
     )));
     lines.push(Line::from(match target {
-        BuildTarget::Project => "Target: Project (.chi_llm.json)",
-        BuildTarget::Global => "Target: Global (~/.cache/chi_llm/model_config.json)",
+        BuildTarget::Project => format!("Target: Project ({})", project_config_path(app.config_format)),
+        BuildTarget::Global => format!(
+            "Target: Global ({})",
+            global_config_path()
+                .map(|p| p.display().to_string())
+                .unwrap_or_else(|e| e.to_string())
+        ),
+    }));
+    let source = active_config_source();
+    lines.push(Line::from(match &source {
+        ConfigSource::Project(p) => Span::styled(
+            format!("Active settings: project {} (overrides global) — u use global here • p pin globally • m merge per field", p),
+            app.theme.status_style(StatusKind::Warn),
+        ),
+        ConfigSource::Global(g) => Span::styled(format!("Active settings: global ({})", g.display()), app.theme.status_style(StatusKind::Ok)),
+        ConfigSource::Defaults => Span::styled("Active settings: chi_llm defaults (no project or global config)", Style::default().fg(app.theme.secondary)),
     }));
     // Show default provider summary
     match get_default_provider_summary() {
@@ -54,20 +133,18 @@ pub fn draw_build_config(f: &mut Frame, area: Rect, app: &App) {
             id, ptype
         ))),
         Err(e) => lines.push(Line::from(Span::styled(
-            format!("Default provider not set: {}", e),
-            Style::default().fg(Color::Red),
+            format!("{} Default provider not set: {}", StatusKind::Err.symbol(), e),
+            app.theme.status_style(StatusKind::Err),
         ))),
     }
     if let Some(st) = &app.build {
         if let Some(msg) = &st.status {
-            lines.push(Line::from(Span::styled(
-                msg.clone(),
-                Style::default().fg(app.theme.secondary),
-            )));
+            let (txt, style) = app.theme.status_text(msg);
+            lines.push(Line::from(Span::styled(txt, style)));
         }
     }
     lines.push(Line::from(
-        "Press Enter to write; 'g' toggles target.",
+        "Press Enter to write (an existing project config that differs opens a diff first); 'g' toggles target; 'e' writes .env/.envrc.",
     ));
     let p = Paragraph::new(lines)
         .style(Style::default().bg(app.theme.bg).fg(app.theme.fg))
@@ -80,16 +157,16 @@ pub fn draw_build_config(f: &mut Frame, area: Rect, app: &App) {
         .alignment(ratatui::layout::Alignment::Left)
         .wrap(Wrap { trim: true });
     f.render_widget(p, area);
+    draw_build_conflict(f, area, app);
+    draw_config_merge(f, area, app);
 }
 
 pub fn get_default_provider_summary() -> Result<(String, String)> {
-    let path = "chi.tmp.json";
-    let text = std::fs::read_to_string(path).map_err(|e| anyhow!("{}", e))?;
-    let v: Value = serde_json::from_str(&text)?;
+    let v = store::read_layered()?;
     let def = v
         .get("default_provider_id")
         .and_then(|x| x.as_str())
-        .ok_or_else(|| anyhow!("no default_provider_id in chi.tmp.json"))?;
+        .ok_or_else(|| anyhow!("no default_provider_id in the provider store"))?;
     if let Some(arr) = v.get("providers").and_then(|x| x.as_array()) {
         for p in arr {
             let id = p.get("id").and_then(|x| x.as_str()).unwrap_or("");
@@ -106,18 +183,16 @@ pub fn get_default_provider_summary() -> Result<(String, String)> {
     Err(anyhow!("default provider entry not found"))
 }
 
-pub fn write_active_config(target: BuildTarget) -> Result<String> {
-    let path = "chi.tmp.json";
-    let text = std::fs::read_to_string(path).map_err(|e| anyhow!("{}", e))?;
-    let v: Value = serde_json::from_str(&text)?;
+/// What Build writes: the default provider as chi_llm's `provider` block.
+pub fn planned_config(v: &Value) -> Result<Value> {
     let def = v
         .get("default_provider_id")
         .and_then(|x| x.as_str())
-        .ok_or_else(|| anyhow!("no default_provider_id in chi.tmp.json"))?;
+        .ok_or_else(|| anyhow!("no default_provider_id in the provider store"))?;
     let arr = v
         .get("providers")
         .and_then(|x| x.as_array())
-        .ok_or_else(|| anyhow!("no providers array in chi.tmp.json"))?;
+        .ok_or_else(|| anyhow!("no providers array in the provider store"))?;
     let mut ptype = String::new();
     let mut cfg = serde_json::Map::new();
     for p in arr {
@@ -128,9 +203,11 @@ pub fn write_active_config(target: BuildTarget) -> Result<String> {
                 .and_then(|x| x.as_str())
                 .unwrap_or("")
                 .to_string();
-            if let Some(c) = p.get("config").and_then(|x| x.as_object()) {
+            // chi_llm reads plain values: substitute {{ variables }} and secret: references here
+            let resolved = p.get("config").map(|c| crate::secrets::resolve_value(&crate::variables::resolve_value(c, &crate::variables::load_variables())));
+            if let Some(c) = resolved.as_ref().and_then(|x| x.as_object()) {
                 for (k, val) in c {
-                    if k == "type" {
+                    if k == "type" || k.starts_with(K8S_FIELD_PREFIX) {
                         continue;
                     }
                     // include only non-empty fields
@@ -162,21 +239,207 @@ pub fn write_active_config(target: BuildTarget) -> Result<String> {
         pmap.insert(k, v);
     }
     out.insert("provider".to_string(), Value::Object(pmap));
-    let json = Value::Object(out);
+    Ok(Value::Object(out))
+}
+
+pub fn write_active_config(target: BuildTarget) -> Result<String> {
+    let v = store::read_layered()?;
+    let json = planned_config(&v)?;
     let written = match target {
-        BuildTarget::Project => {
-            let p = ".chi_llm.json";
-            std::fs::write(p, serde_json::to_vec_pretty(&json)?)?;
-            p.to_string()
-        }
-        BuildTarget::Global => {
-            let home = dirs::home_dir().ok_or_else(|| anyhow!("home dir not found"))?;
-            let dir = home.join(".cache").join("chi_llm");
-            std::fs::create_dir_all(&dir)?;
-            let p = dir.join("model_config.json");
-            std::fs::write(&p, serde_json::to_vec_pretty(&json)?)?;
-            p.to_string_lossy().to_string()
-        }
+        BuildTarget::Project => write_project(&json, store::write_format(&v))?,
+        // Keep the user's other global settings; only `provider` is ours
+        BuildTarget::Global => write_global_keys(&json, "build.write")?,
     };
     Ok(written)
 }
+
+/// Set the top-level keys of `json` in the global config, keeping the rest.
+fn write_global_keys(json: &Value, action: &str) -> Result<String> {
+    let p = global_config_path()?;
+    if let Some(dir) = p.parent() { std::fs::create_dir_all(dir)?; }
+    let before = match std::fs::read_to_string(&p) {
+        Ok(t) => store::parse(&t).ok().filter(|v| v.is_object()).ok_or_else(|| anyhow!("{} is not a JSON object; not overwriting", p.display()))?,
+        Err(_) => Value::Object(Default::default()),
+    };
+    let mut merged = before.clone();
+    if let (Some(m), Some(o)) = (merged.as_object_mut(), json.as_object()) {
+        for (k, val) in o { m.insert(k.clone(), val.clone()); }
+    }
+    std::fs::write(&p, serde_json::to_vec_pretty(&merged)?)?;
+    let _ = audit::record(action, &p.to_string_lossy(), &before, &merged);
+    Ok(p.to_string_lossy().to_string())
+}
+
+/// Where chi_llm takes its settings from in the working directory.
+#[derive(Clone, Debug, PartialEq, Eq)]
+pub enum ConfigSource {
+    /// A project config, which overrides the global one
+    Project(&'static str),
+    Global(PathBuf),
+    /// Neither exists: chi_llm's built-in defaults
+    Defaults,
+}
+
+impl ConfigSource {
+    /// Short form for the header: "project .chi_llm.json".
+    pub fn label(&self) -> String {
+        match self {
+            ConfigSource::Project(p) => format!("project {}", p),
+            ConfigSource::Global(_) => "global".to_string(),
+            ConfigSource::Defaults => "defaults".to_string(),
+        }
+    }
+}
+
+pub fn active_config_source() -> ConfigSource {
+    if let Some(p) = project_config() { return ConfigSource::Project(p); }
+    match global_config_path() {
+        Ok(g) if g.exists() => ConfigSource::Global(g),
+        _ => ConfigSource::Defaults,
+    }
+}
+
+/// The project config chi_llm reads, first in its lookup order.
+pub fn project_config() -> Option<&'static str> {
+    PROJECT_CONFIGS.iter().copied().find(|c| std::path::Path::new(c).exists())
+}
+
+/// "Use global here": move every project config aside to `<name>.bak` so
+/// the global settings apply. Returns the files moved.
+pub fn use_global_here() -> Result<Vec<String>> {
+    let mut moved = Vec::new();
+    for p in PROJECT_CONFIGS.iter().filter(|c| std::path::Path::new(c).exists()) {
+        let before = read_json_or_empty(std::path::Path::new(p));
+        let bak = format!("{}.bak", p);
+        std::fs::rename(p, &bak)?;
+        let _ = audit::record("build.use_global", p, &before, &Value::Object(Default::default()));
+        moved.push(bak);
+    }
+    if moved.is_empty() { return Err(anyhow!("no project config here; global settings already apply")); }
+    Ok(moved)
+}
+
+/// "Pin globally": copy the project config's keys into the global config,
+/// so other repos get the same settings. Returns the global path.
+pub fn pin_globally() -> Result<String> {
+    let p = project_config().ok_or_else(|| anyhow!("no project config to pin"))?;
+    let text = std::fs::read_to_string(p)?;
+    let v = store::parse(&text).map_err(|e| anyhow!("{}: {}", p, e))?;
+    if !v.is_object() { return Err(anyhow!("{} is not an object", p)); }
+    write_global_keys(&v, "build.pin_global")
+}
+
+pub fn write_project(json: &Value, fmt: Format) -> Result<String> {
+    let p = project_config_path(fmt);
+    let before = read_json_or_empty(std::path::Path::new(p));
+    std::fs::write(p, store::to_text(json, fmt)?)?;
+    let _ = audit::record("build.write", p, &before, json);
+    // chi_llm reads .chi_llm.yaml/.yml before .chi_llm.json
+    Ok(match PROJECT_CONFIGS.iter().take_while(|c| **c != p).find(|c| std::path::Path::new(c).exists()) {
+        Some(other) => format!("{} (note: {} also exists and takes precedence)", p, other),
+        None => p.to_string(),
+    })
+}
+
+/// Global chi_llm config: `$XDG_CACHE_HOME/chi_llm` on Linux (default
+/// `~/.cache/chi_llm`), `%APPDATA%\chi_llm` on Windows, `~/.cache/chi_llm`
+/// elsewhere.
+pub fn global_config_path() -> Result<PathBuf> {
+    let env_dir = |k: &str| std::env::var_os(k).map(PathBuf::from).filter(|p| p.is_absolute());
+    let base = if cfg!(windows) {
+        env_dir("APPDATA")
+    } else if cfg!(target_os = "linux") {
+        env_dir("XDG_CACHE_HOME")
+    } else {
+        None
+    };
+    let base = match base {
+        Some(b) => b,
+        None => dirs::home_dir().ok_or_else(|| anyhow!("home dir not found"))?.join(".cache"),
+    };
+    Ok(base.join("chi_llm").join("model_config.json"))
+}
+
+/// Project configs in chi_llm's lookup order.
+const PROJECT_CONFIGS: [&str; 3] = [".chi_llm.yaml", ".chi_llm.yml", ".chi_llm.json"];
+
+pub fn project_config_path(fmt: Format) -> &'static str {
+    match fmt {
+        Format::Json => ".chi_llm.json",
+        Format::Yaml => ".chi_llm.yaml",
+    }
+}
+
+fn read_json_or_empty(path: &std::path::Path) -> Value {
+    std::fs::read_to_string(path)
+        .ok()
+        .and_then(|t| store::parse(&t).ok())
+        .unwrap_or_else(|| Value::Object(Default::default()))
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+    use serde_json::json;
+
+    #[test]
+    fn the_default_provider_becomes_the_provider_block() {
+        let v = json!({
+            "providers": [
+                {"id": "a", "type": "openai", "config": {"model": "gpt"}},
+                {"id": "b", "type": "local-zeroconfig", "config": {"type": "local-zeroconfig", "model": "qwen", "empty": "", "none": null, "k8s_namespace": "ml", "ctx": 4096}},
+            ],
+            "default_provider_id": "b",
+        });
+        assert_eq!(planned_config(&v).expect("config"), json!({"provider": {"type": "local", "model": "qwen", "ctx": 4096}}));
+        assert!(planned_config(&json!({"providers": []})).expect_err("no default").to_string().contains("no default_provider_id"));
+        assert!(planned_config(&json!({"providers": [], "default_provider_id": "x"})).expect_err("missing").to_string().contains("type missing"));
+    }
+
+    #[cfg(unix)]
+    #[test]
+    fn the_global_config_lives_in_the_cache_dir() {
+        let fake = crate::testing::FakeCli::new();
+        let cache = std::env::var_os("XDG_CACHE_HOME").map(PathBuf::from).expect("cache dir");
+        assert!(cache.starts_with(&fake.root));
+        assert_eq!(global_config_path().expect("path"), cache.join("chi_llm").join("model_config.json"));
+    }
+
+    #[cfg(unix)]
+    #[test]
+    fn building_globally_replaces_only_the_provider_key() {
+        let _fake = crate::testing::FakeCli::new();
+        store::write(&json!({"providers": [{"id": "box", "type": "ollama", "config": {"host": "10.0.0.5"}}], "default_provider_id": "box"})).expect("store");
+        assert_eq!(active_config_source(), ConfigSource::Defaults);
+        let path = global_config_path().expect("path");
+        std::fs::create_dir_all(path.parent().expect("dir")).expect("mkdir");
+        std::fs::write(&path, r#"{"provider": {"type": "openai", "model": "gpt"}, "generation": {"temperature": 0.2}}"#).expect("global");
+
+        assert_eq!(write_active_config(BuildTarget::Global).expect("write"), path.to_string_lossy());
+        let written: Value = serde_json::from_str(&std::fs::read_to_string(&path).expect("read")).expect("json");
+        assert_eq!(written, json!({"provider": {"type": "ollama", "host": "10.0.0.5"}, "generation": {"temperature": 0.2}}));
+        assert_eq!(active_config_source(), ConfigSource::Global(path.clone()));
+        assert!(!std::path::Path::new(".chi_llm.json").exists(), "the project is left alone");
+
+        std::fs::write(&path, "[1, 2]").expect("not an object");
+        assert!(write_active_config(BuildTarget::Global).expect_err("refuses").to_string().contains("not overwriting"));
+        assert_eq!(std::fs::read_to_string(&path).expect("read"), "[1, 2]");
+    }
+
+    #[cfg(unix)]
+    #[test]
+    fn project_override_can_be_pinned_or_dropped() {
+        let fake = crate::testing::FakeCli::new();
+        assert_eq!(active_config_source(), ConfigSource::Defaults);
+        std::fs::write(".chi_llm.json", r#"{"default_model": "qwen3-1.7b"}"#).expect("write project config");
+        assert_eq!(active_config_source(), ConfigSource::Project(".chi_llm.json"));
+
+        let global = pin_globally().expect("pin");
+        let pinned: serde_json::Value = serde_json::from_str(&std::fs::read_to_string(&global).expect("global config")).expect("json");
+        assert_eq!(pinned["default_model"], "qwen3-1.7b");
+
+        assert_eq!(use_global_here().expect("use global"), vec![".chi_llm.json.bak".to_string()]);
+        assert!(matches!(active_config_source(), ConfigSource::Global(_)));
+        assert!(fake.exists(".chi_llm.json.bak"));
+    }
+}
@@ -0,0 +1,251 @@
+//! Build onto an existing project config: when the file on disk differs
+//! from what Build would write, old and new are shown side by side and the
+//! user overwrites, merges (keys only on disk are kept) or cancels.
+
+use anyhow::{anyhow, Result};
+use ratatui::layout::Rect;
+use ratatui::prelude::Frame;
+use ratatui::style::Style;
+use ratatui::text::{Line, Span};
+use ratatui::widgets::{Block, Borders, Clear, Paragraph};
+use serde_json::Value;
+
+use crate::app::App;
+use crate::build::{planned_config, project_config_path, write_project};
+use crate::store::{self, Format};
+use crate::theme::StatusKind;
+use crate::util::{overlay_rect, split_panes};
+
+/// An existing project config that differs from what Build would write.
+#[derive(Clone, Debug)]
+pub struct BuildConflict {
+    pub path: &'static str,
+    fmt: Format,
+    /// The file as it is on disk
+    pub old_text: String,
+    pub new: Value,
+    /// `new` over the old file; None when the old file does not parse
+    pub merged: Option<Value>,
+    /// Right pane shows the merge result instead of the overwrite
+    pub show_merged: bool,
+    pub scroll: u16,
+}
+
+impl BuildConflict {
+    /// Text that `o` (or `m` when previewing the merge) would write.
+    pub fn right_text(&self) -> String {
+        let v = if self.show_merged { self.merged.as_ref().unwrap_or(&self.new) } else { &self.new };
+        store::to_text(v, self.fmt).unwrap_or_default()
+    }
+
+    pub fn overwrite(&self) -> Result<String> {
+        write_project(&self.new, self.fmt)
+    }
+
+    pub fn merge(&self) -> Result<String> {
+        let merged = self.merged.as_ref().ok_or_else(|| anyhow!("{} does not parse; only overwrite is possible", self.path))?;
+        write_project(merged, self.fmt)
+    }
+}
+
+/// Before writing the project config: the conflict to resolve when the file
+/// exists with other content, None when writing cannot lose anything.
+pub fn plan_project_write() -> Result<Option<BuildConflict>> {
+    let v = store::read_layered()?;
+    let new = planned_config(&v)?;
+    let fmt = store::write_format(&v);
+    let path = project_config_path(fmt);
+    let Ok(old_text) = std::fs::read_to_string(path) else { return Ok(None) };
+    let old = store::parse(&old_text).ok();
+    if old.as_ref() == Some(&new) { return Ok(None); }
+    let merged = old.filter(|o| o.is_object()).map(|o| merge_config(&o, &new));
+    Ok(Some(BuildConflict { path, fmt, old_text, new, merged, show_merged: false, scroll: 0 }))
+}
+
+/// `new` laid over `old`: keys only in `old` are kept, objects merge
+/// recursively and `new` wins everywhere else. A provider of another type
+/// replaces the old one whole, since its fields would not apply.
+pub fn merge_config(old: &Value, new: &Value) -> Value {
+    match (old, new) {
+        (Value::Object(o), Value::Object(n)) => {
+            if matches!((o.get("type"), n.get("type")), (Some(a), Some(b)) if a != b) { return new.clone(); }
+            let mut out = o.clone();
+            for (k, v) in n {
+                let m = match o.get(k) {
+                    Some(ov) => merge_config(ov, v),
+                    None => v.clone(),
+                };
+                out.insert(k.clone(), m);
+            }
+            Value::Object(out)
+        }
+        _ => new.clone(),
+    }
+}
+
+#[derive(Copy, Clone, Debug, PartialEq, Eq)]
+pub enum DiffKind {
+    Same,
+    Changed,
+    Removed,
+    Added,
+}
+
+/// One row of a side-by-side diff; a missing side is a blank line.
+#[derive(Clone, Debug, PartialEq, Eq)]
+pub struct DiffRow {
+    pub kind: DiffKind,
+    pub left: Option<String>,
+    pub right: Option<String>,
+}
+
+/// Line diff (longest common subsequence) aligned for two columns: runs of
+/// removed and added lines between unchanged ones pair up as changes.
+pub fn side_by_side(old: &str, new: &str) -> Vec<DiffRow> {
+    let (a, b): (Vec<&str>, Vec<&str>) = (old.lines().collect(), new.lines().collect());
+    // lcs[i][j]: common lines of a[i..] and b[j..]
+    let mut lcs = vec![vec![0u32; b.len() + 1]; a.len() + 1];
+    for i in (0..a.len()).rev() {
+        for j in (0..b.len()).rev() {
+            lcs[i][j] = if a[i] == b[j] { lcs[i + 1][j + 1] + 1 } else { lcs[i + 1][j].max(lcs[i][j + 1]) };
+        }
+    }
+    let mut rows = Vec::new();
+    let (mut removed, mut added): (Vec<&str>, Vec<&str>) = (Vec::new(), Vec::new());
+    let flush = |rows: &mut Vec<DiffRow>, removed: &mut Vec<&str>, added: &mut Vec<&str>| {
+        for k in 0..removed.len().max(added.len()) {
+            let (l, r) = (removed.get(k).map(|s| s.to_string()), added.get(k).map(|s| s.to_string()));
+            let kind = match (&l, &r) {
+                (Some(_), Some(_)) => DiffKind::Changed,
+                (Some(_), None) => DiffKind::Removed,
+                _ => DiffKind::Added,
+            };
+            rows.push(DiffRow { kind, left: l, right: r });
+        }
+        removed.clear();
+        added.clear();
+    };
+    let (mut i, mut j) = (0, 0);
+    while i < a.len() || j < b.len() {
+        if i < a.len() && j < b.len() && a[i] == b[j] {
+            flush(&mut rows, &mut removed, &mut added);
+            rows.push(DiffRow { kind: DiffKind::Same, left: Some(a[i].to_string()), right: Some(b[j].to_string()) });
+            i += 1;
+            j += 1;
+        } else if j >= b.len() || (i < a.len() && lcs[i + 1][j] >= lcs[i][j + 1]) {
+            removed.push(a[i]);
+            i += 1;
+        } else {
+            added.push(b[j]);
+            j += 1;
+        }
+    }
+    flush(&mut rows, &mut removed, &mut added);
+    rows
+}
+
+pub fn draw_build_conflict(f: &mut Frame, area: Rect, app: &App) {
+    let Some(c) = app.build.as_ref().and_then(|b| b.conflict.as_ref()) else { return };
+    let pop = overlay_rect(app.compact, 92, 85, area);
+    let merge_hint = if c.merged.is_some() { "m merge • Tab preview merge/overwrite • " } else { "" };
+    let title = format!("{} differs — o overwrite • {}↑/↓ scroll • Esc cancel", c.path, merge_hint);
+    let block = Block::default().borders(Borders::ALL).border_style(Style::default().fg(app.theme.accent)).title(title);
+    let inner = block.inner(pop);
+    f.render_widget(Clear, pop);
+    f.render_widget(block.style(Style::default().bg(app.theme.bg)), pop);
+    let rows = side_by_side(&c.old_text, &c.right_text());
+    let (del, add) = (app.theme.status_style(StatusKind::Err), app.theme.status_style(StatusKind::Ok));
+    let side = |text: &Option<String>, kind: DiffKind, changed: Style| -> Line<'static> {
+        let style = if kind == DiffKind::Same { Style::default().fg(app.theme.fg) } else { changed };
+        Line::from(Span::styled(text.clone().unwrap_or_default(), style))
+    };
+    let left: Vec<Line> = rows.iter().map(|r| side(&r.left, r.kind, del)).collect();
+    let right: Vec<Line> = rows.iter().map(|r| side(&r.right, r.kind, add)).collect();
+    let right_title = if c.show_merged { "Merged (m)" } else { "New (o)" };
+    let cols = split_panes(app.stacked(), 50, inner);
+    for (lines, title, col) in [(left, "On disk", cols[0]), (right, right_title, cols[1])] {
+        let p = Paragraph::new(lines)
+            .style(Style::default().bg(app.theme.bg).fg(app.theme.fg))
+            .block(Block::default().borders(Borders::ALL).border_style(Style::default().fg(app.theme.frame)).title(title))
+            .scroll((c.scroll, 0));
+        f.render_widget(p, col);
+    }
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+    use serde_json::json;
+
+    #[test]
+    fn merge_keeps_old_keys_unless_the_provider_type_changes() {
+        let old = json!({"provider": {"type": "ollama", "host": "box", "port": 11434}, "log_level": "debug"});
+        assert_eq!(
+            merge_config(&old, &json!({"provider": {"type": "ollama", "port": 8080}})),
+            json!({"provider": {"type": "ollama", "host": "box", "port": 8080}, "log_level": "debug"})
+        );
+        assert_eq!(
+            merge_config(&old, &json!({"provider": {"type": "openai", "model": "gpt"}})),
+            json!({"provider": {"type": "openai", "model": "gpt"}, "log_level": "debug"})
+        );
+    }
+
+    #[test]
+    fn the_diff_pairs_changed_lines_and_keeps_the_rest() {
+        let rows = side_by_side("a\nb\nc\nd", "a\nB\nc\nd\ne");
+        let kinds: Vec<DiffKind> = rows.iter().map(|r| r.kind).collect();
+        assert_eq!(kinds, [DiffKind::Same, DiffKind::Changed, DiffKind::Same, DiffKind::Same, DiffKind::Added]);
+        assert_eq!((rows[1].left.as_deref(), rows[1].right.as_deref()), (Some("b"), Some("B")));
+        assert_eq!((rows[4].left.as_deref(), rows[4].right.as_deref()), (None, Some("e")));
+        assert_eq!(side_by_side("x\ny", "y")[0], DiffRow { kind: DiffKind::Removed, left: Some("x".to_string()), right: None });
+    }
+
+    #[cfg(unix)]
+    fn store_with_box() {
+        store::write(&json!({"providers": [{"id": "box", "type": "ollama", "config": {"host": "10.0.0.5", "port": 8080}}], "default_provider_id": "box"})).expect("store");
+    }
+
+    #[cfg(unix)]
+    #[test]
+    fn a_hand_edited_config_is_a_conflict_with_a_diff() {
+        let _fake = crate::testing::FakeCli::new();
+        store_with_box();
+        assert!(plan_project_write().expect("plan").is_none(), "nothing on disk yet");
+        std::fs::write(".chi_llm.json", r#"{"provider": {"type": "ollama", "host": "old", "num_ctx": 4096}, "log_level": "debug"}"#).expect("edit");
+        let c = plan_project_write().expect("plan").expect("conflict");
+        assert_eq!(c.path, ".chi_llm.json");
+        let rows = side_by_side(&c.old_text, &c.right_text());
+        assert!(rows.iter().any(|r| r.kind != DiffKind::Same), "{:?}", rows);
+        assert!(rows.iter().any(|r| r.left.as_deref().map_or(false, |l| l.contains("log_level"))));
+        assert!(!c.right_text().contains("log_level"), "overwrite drops the user's keys");
+
+        // Once written, the same plan is not a conflict any more
+        c.overwrite().expect("overwrite");
+        assert!(plan_project_write().expect("plan").is_none());
+    }
+
+    #[cfg(unix)]
+    #[test]
+    fn merge_keeps_the_users_keys_and_overwrite_replaces_the_file() {
+        let _fake = crate::testing::FakeCli::new();
+        store_with_box();
+        let edited = r#"{"provider": {"type": "ollama", "host": "old", "num_ctx": 4096}, "log_level": "debug"}"#;
+        let read = || serde_json::from_str::<Value>(&std::fs::read_to_string(".chi_llm.json").expect("read")).expect("json");
+
+        std::fs::write(".chi_llm.json", edited).expect("edit");
+        let mut c = plan_project_write().expect("plan").expect("conflict");
+        c.show_merged = true;
+        assert!(c.right_text().contains("log_level"));
+        assert_eq!(c.merge().expect("merge"), ".chi_llm.json");
+        assert_eq!(read(), json!({"provider": {"type": "ollama", "host": "10.0.0.5", "port": 8080, "num_ctx": 4096}, "log_level": "debug"}));
+
+        std::fs::write(".chi_llm.json", edited).expect("edit");
+        plan_project_write().expect("plan").expect("conflict").overwrite().expect("overwrite");
+        assert_eq!(read(), json!({"provider": {"type": "ollama", "host": "10.0.0.5", "port": 8080}}));
+
+        std::fs::write(".chi_llm.json", "{not json").expect("broken");
+        let c = plan_project_write().expect("plan").expect("conflict");
+        assert!(c.merged.is_none());
+        assert!(c.merge().expect_err("no merge").to_string().contains("does not parse"));
+    }
+}
@@ -0,0 +1,174 @@
+use std::fs;
+use std::path::PathBuf;
+
+use ratatui::layout::{Constraint, Direction, Layout, Rect};
+use ratatui::prelude::Frame;
+use ratatui::style::{Modifier, Style};
+use ratatui::text::{Line, Span};
+use ratatui::widgets::{Block, Borders, List, ListItem, Paragraph, Wrap};
+
+use crate::app::App;
+use crate::downloads::model_dir;
+use crate::locale;
+
+#[derive(Clone, Debug)]
+pub struct CacheFile {
+    pub name: String,
+    pub path: PathBuf,
+    pub bytes: u64,
+}
+
+impl CacheFile {
+    /// Leftover of an interrupted or cancelled download
+    pub fn partial(&self) -> bool {
+        self.name.ends_with(".part")
+    }
+}
+
+#[derive(Clone, Debug, Default)]
+pub struct CacheState {
+    pub files: Vec<CacheFile>, // largest first
+    pub selected: usize,
+    pub free: Option<u64>,
+    /// Set by the first Del press; a second Del deletes
+    pub confirm_delete: bool,
+    pub status: Option<String>,
+}
+
+/// Free bytes on the filesystem holding `dir` (or its nearest existing parent).
+pub fn free_space(dir: &std::path::Path) -> Option<u64> {
+    let probe = dir.ancestors().find(|p| p.exists())?;
+    fs2::available_space(probe).ok()
+}
+
+pub fn load_cache() -> CacheState {
+    let dir = match model_dir() {
+        Ok(d) => d,
+        Err(e) => return CacheState { status: Some(format!("Error: {}", e)), ..Default::default() },
+    };
+    let mut files: Vec<CacheFile> = fs::read_dir(&dir)
+        .map(|rd| {
+            rd.filter_map(|e| e.ok())
+                .filter_map(|e| {
+                    let md = e.metadata().ok()?;
+                    if !md.is_file() { return None; }
+                    let name = e.file_name().to_string_lossy().to_string();
+                    (name.ends_with(".gguf") || name.ends_with(".part")).then(|| CacheFile { name, path: e.path(), bytes: md.len() })
+                })
+                .collect()
+        })
+        .unwrap_or_default();
+    files.sort_by(|a, b| b.bytes.cmp(&a.bytes));
+    CacheState { files, free: free_space(&dir), ..Default::default() }
+}
+
+impl CacheState {
+    pub fn move_up(&mut self) {
+        if self.selected > 0 { self.selected -= 1; }
+        self.confirm_delete = false;
+    }
+    pub fn move_down(&mut self) {
+        if self.selected + 1 < self.files.len() { self.selected += 1; }
+        self.confirm_delete = false;
+    }
+    pub fn total(&self) -> u64 {
+        self.files.iter().map(|f| f.bytes).sum()
+    }
+
+    /// First call arms the confirmation, second deletes. Returns true when a
+    /// file was removed.
+    pub fn delete_selected(&mut self) -> bool {
+        let Some(file) = self.files.get(self.selected).cloned() else { return false };
+        if !self.confirm_delete {
+            self.confirm_delete = true;
+            self.status = Some(format!("Warning: press Del again to delete {} ({})", file.name, locale::bytes(file.bytes)));
+            return false;
+        }
+        self.confirm_delete = false;
+        match fs::remove_file(&file.path) {
+            Ok(()) => {
+                let mut reloaded = load_cache();
+                reloaded.selected = self.selected.min(reloaded.files.len().saturating_sub(1));
+                reloaded.status = Some(format!("Deleted {} — freed {}", file.name, locale::bytes(file.bytes)));
+                *self = reloaded;
+                true
+            }
+            Err(e) => {
+                self.status = Some(format!("Error: delete failed: {}", e));
+                false
+            }
+        }
+    }
+}
+
+pub fn draw_cache(f: &mut Frame, area: Rect, app: &App) {
+    let chunks = Layout::default()
+        .direction(Direction::Vertical)
+        .constraints([Constraint::Min(3), Constraint::Length(4)])
+        .split(area);
+    let mut items: Vec<ListItem> = Vec::new();
+    let mut info: Vec<Line> = Vec::new();
+    if let Some(st) = &app.cache {
+        for (i, file) in st.files.iter().enumerate() {
+            let mut label = format!("{} {:>9}  {}", if i == st.selected { '›' } else { ' ' }, locale::bytes(file.bytes), file.name);
+            if file.partial() { label.push_str("  [partial]"); }
+            let style = if i == st.selected { Style::default().fg(app.theme.selected).add_modifier(Modifier::BOLD) } else { Style::default().fg(app.theme.fg) };
+            items.push(ListItem::new(Line::from(Span::styled(label, style))));
+        }
+        if st.files.is_empty() { items.push(ListItem::new("No model files in ~/.cache/chi_llm")); }
+        let free = st.free.map(locale::bytes).unwrap_or_else(|| "unknown".to_string());
+        info.push(Line::from(format!("{} files, {} used • {} free", st.files.len(), locale::bytes(st.total()), free)));
+        if let Some(msg) = &st.status {
+            let (txt, style) = app.theme.status_text(msg);
+            info.push(Line::from(Span::styled(txt, style)));
+        }
+    } else {
+        items.push(ListItem::new("Scanning cache..."));
+    }
+    let list = List::new(items)
+        .block(Block::default().borders(Borders::ALL).border_style(Style::default().fg(app.theme.frame)).title("Model Cache (~/.cache/chi_llm)"));
+    f.render_widget(list, chunks[0]);
+    let p = Paragraph::new(info)
+        .style(Style::default().bg(app.theme.bg).fg(app.theme.fg))
+        .block(Block::default().borders(Borders::ALL).border_style(Style::default().fg(app.theme.frame)).title("Disk"))
+        .wrap(Wrap { trim: true });
+    f.render_widget(p, chunks[1]);
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+
+    #[test]
+    fn free_space_is_read_from_the_nearest_existing_parent() {
+        let missing = std::env::temp_dir().join("chi-tui-no-such-dir").join("models");
+        assert!(free_space(&missing).is_some());
+    }
+
+    #[cfg(unix)]
+    #[test]
+    fn models_are_listed_largest_first_and_deleted_on_the_second_press() {
+        let _fake = crate::testing::FakeCli::new();
+        let dir = model_dir().expect("model dir");
+        fs::create_dir_all(&dir).expect("mkdir");
+        fs::write(dir.join("small.gguf"), vec![0u8; 10]).expect("small");
+        fs::write(dir.join("big.gguf.part"), vec![0u8; 100]).expect("partial");
+        fs::write(dir.join("notes.txt"), "not a model").expect("other");
+
+        let mut st = load_cache();
+        let names: Vec<&str> = st.files.iter().map(|f| f.name.as_str()).collect();
+        assert_eq!(names, ["big.gguf.part", "small.gguf"]);
+        assert!(st.files[0].partial() && !st.files[1].partial());
+        assert_eq!(st.total(), 110);
+        assert!(st.free.is_some());
+
+        assert!(!st.delete_selected());
+        assert!(st.confirm_delete && dir.join("big.gguf.part").exists());
+        st.move_down();
+        assert!(!st.confirm_delete, "moving disarms the delete");
+        assert!(!st.delete_selected());
+        assert!(st.delete_selected());
+        assert!(!dir.join("small.gguf").exists() && dir.join("big.gguf.part").exists());
+        assert_eq!((st.files.len(), st.selected), (1, 0));
+    }
+}
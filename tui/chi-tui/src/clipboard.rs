@@ -0,0 +1,60 @@
+use std::io::{self, Write};
+use std::process::{Command, Stdio};
+
+use anyhow::{anyhow, Result};
+
+/// Clipboard helpers over the platform tools, tried in order. Copy falls back
+/// to an OSC 52 escape so it also works over SSH in supporting terminals.
+const COPY_TOOLS: &[(&str, &[&str])] = &[
+    ("pbcopy", &[]),
+    ("wl-copy", &[]),
+    ("xclip", &["-selection", "clipboard"]),
+    ("xsel", &["--clipboard", "--input"]),
+    ("clip.exe", &[]),
+];
+
+const PASTE_TOOLS: &[(&str, &[&str])] = &[
+    ("pbpaste", &[]),
+    ("wl-paste", &["--no-newline"]),
+    ("xclip", &["-selection", "clipboard", "-o"]),
+    ("xsel", &["--clipboard", "--output"]),
+    ("powershell.exe", &["-NoProfile", "-Command", "Get-Clipboard"]),
+];
+
+fn pipe_to(tool: &str, args: &[&str], text: &str) -> bool {
+    let Ok(mut child) = Command::new(tool).args(args).stdin(Stdio::piped()).stdout(Stdio::null()).stderr(Stdio::null()).spawn() else { return false };
+    let wrote = child.stdin.take().map_or(false, |mut stdin| stdin.write_all(text.as_bytes()).is_ok());
+    child.wait().map_or(false, |st| st.success()) && wrote
+}
+
+fn base64(data: &[u8]) -> String {
+    const ALPHABET: &[u8; 64] = b"ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz0123456789+/";
+    let mut out = String::with_capacity((data.len() + 2) / 3 * 4);
+    for chunk in data.chunks(3) {
+        let n = (chunk[0] as u32) << 16 | (*chunk.get(1).unwrap_or(&0) as u32) << 8 | *chunk.get(2).unwrap_or(&0) as u32;
+        for i in 0..4 {
+            if i <= chunk.len() { out.push(ALPHABET[(n >> (18 - 6 * i) & 63) as usize] as char); } else { out.push('='); }
+        }
+    }
+    out
+}
+
+/// Copy `text`; returns the mechanism used (tool name or "OSC 52").
+pub fn copy(text: &str) -> Result<&'static str> {
+    for (tool, args) in COPY_TOOLS {
+        if pipe_to(tool, args, text) { return Ok(tool); }
+    }
+    let mut out = io::stdout();
+    write!(out, "\x1b]52;c;{}\x07", base64(text.as_bytes()))?;
+    out.flush()?;
+    Ok("OSC 52")
+}
+
+pub fn paste() -> Result<String> {
+    for (tool, args) in PASTE_TOOLS {
+        if let Ok(out) = Command::new(tool).args(*args).stdin(Stdio::null()).stderr(Stdio::null()).output() {
+            if out.status.success() { return Ok(String::from_utf8_lossy(&out.stdout).to_string()); }
+        }
+    }
+    Err(anyhow!("no clipboard tool found (pbpaste, wl-paste, xclip, xsel or powershell)"))
+}
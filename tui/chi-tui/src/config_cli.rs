@@ -0,0 +1,379 @@
+use anyhow::{anyhow, Result};
+use clap::Subcommand;
+use serde_json::{Map, Value};
+
+use crate::backup::maybe_snapshot;
+use crate::health::endpoint_of;
+use crate::hooks;
+use crate::model_defaults;
+use crate::providers::{
+    apply_import, export_entry, load_providers_state, parse_import, read_scratch_entries, read_scratch_entries_raw, save_default_provider, FieldSchema,
+    ProviderScratchEntry, ProvidersState,
+};
+use crate::text;
+
+/// `chi-tui config ...` (alias `chi-tui providers ...`): edit the provider
+/// store (chi.tmp.json) without the UI, e.g. from CI or dotfiles. Writes go
+/// through the same save path as the TUI (audit log, snapshot, post-save
+/// hook). With `--json` every command prints one JSON object or array on
+/// stdout; failures still exit non-zero with the message on stderr.
+#[derive(Subcommand, Debug)]
+pub enum ConfigCmd {
+    /// List configured providers
+    List {
+        /// Output JSON (secrets omitted)
+        #[arg(long)]
+        json: bool,
+    },
+    /// Add a provider; prints its id
+    Add {
+        /// Provider type, e.g. ollama, lmstudio, openai, openai-compatible
+        #[arg(long = "type")]
+        ptype: String,
+        /// Id (default: the type; a -N suffix is added when taken)
+        #[arg(long)]
+        id: Option<String>,
+        #[arg(long)]
+        name: Option<String>,
+        #[arg(long)]
+        host: Option<String>,
+        #[arg(long)]
+        port: Option<String>,
+        #[arg(long)]
+        model: Option<String>,
+        #[arg(long = "base-url")]
+        base_url: Option<String>,
+        /// Prefer --set api_key=$VAR in scripts to keep keys out of history
+        #[arg(long = "api-key")]
+        api_key: Option<String>,
+        /// Tag (repeatable)
+        #[arg(long = "tag")]
+        tags: Vec<String>,
+        /// Any other field as key=value (repeatable)
+        #[arg(long = "set", value_name = "KEY=VALUE")]
+        set: Vec<String>,
+        /// Also make it the default provider
+        #[arg(long)]
+        default: bool,
+        /// Skip schema validation (when chi-llm is not installed)
+        #[arg(long = "no-validate")]
+        no_validate: bool,
+        /// Output JSON: the new id, whether it is the default, warnings
+        #[arg(long)]
+        json: bool,
+    },
+    /// Remove a provider
+    Remove {
+        id: String,
+        #[arg(long)]
+        json: bool,
+    },
+    /// Set the default provider
+    SetDefault {
+        id: String,
+        #[arg(long)]
+        json: bool,
+    },
+}
+
+/// Field value typed per schema: numbers for int/float fields, strings otherwise.
+fn typed_value(schema: Option<&FieldSchema>, raw: &str) -> Value {
+    let numeric = schema.map_or_else(|| raw.parse::<i64>().is_ok(), |f| f.is_numeric());
+    if numeric {
+        if let Ok(n) = raw.parse::<i64>() { return Value::Number(n.into()); }
+        if let Some(n) = raw.parse::<f64>().ok().and_then(serde_json::Number::from_f64) { return Value::Number(n); }
+    }
+    Value::String(raw.to_string())
+}
+
+fn load_state(ptype: &str, no_validate: bool) -> Result<ProvidersState> {
+    if no_validate {
+        let mut st = ProvidersState::empty();
+        st.entries = read_scratch_entries_raw()?;
+        // No schema: accept the type with no known fields
+        st.schema_map.insert(ptype.to_string(), Vec::new());
+        return Ok(st);
+    }
+    load_providers_state().map_err(|e| anyhow!("cannot load provider schema ({}); is chi-llm installed? Use --no-validate to skip", e))
+}
+
+fn after_write() {
+    if let Err(e) = crate::envfile::sync_on_save() { eprintln!("warning: .env sync failed: {}", e); }
+    if let Err(e) = hooks::run_post_save(&crate::store::path()) { eprintln!("warning: post-save hook failed: {}", e); }
+}
+
+/// `list --json`: each provider as exported (secrets omitted) with a
+/// `default` flag.
+fn list_json(entries: &[ProviderScratchEntry], default: Option<&str>) -> Value {
+    entries
+        .iter()
+        .map(|e| {
+            let (mut v, _) = export_entry(e, None);
+            v["default"] = Value::Bool(default == Some(e.id.as_str()));
+            v
+        })
+        .collect()
+}
+
+fn list(json: bool) -> Result<()> {
+    let entries = read_scratch_entries()?;
+    let default = crate::rules::resolve_from_store().ok().map(|r| r.provider_id);
+    if json { return print_json(&list_json(&entries, default.as_deref())); }
+    if entries.is_empty() { println!("no providers configured"); }
+    for e in &entries {
+        let mark = if default.as_deref() == Some(e.id.as_str()) { '*' } else { ' ' };
+        let endpoint = endpoint_of(e).map(|(h, p)| format!("{}:{}", h, p)).unwrap_or_else(|| "-".to_string());
+        let model = e.config.get("model").and_then(|v| v.as_str()).unwrap_or("-");
+        let tags = if e.tags.is_empty() { String::new() } else { format!("  [{}]", e.tags.join(",")) };
+        println!("{} {} {} {} {}{}", mark, text::pad(&e.id, 20), text::pad(&e.ptype, 12), text::pad(&endpoint, 28), model, tags);
+    }
+    Ok(())
+}
+
+fn print_json(v: &Value) -> Result<()> {
+    println!("{}", serde_json::to_string_pretty(v)?);
+    Ok(())
+}
+
+struct AddOpts {
+    make_default: bool,
+    no_validate: bool,
+    json: bool,
+}
+
+fn add(ptype: String, id: Option<String>, name: Option<String>, fields: Vec<(String, String)>, tags: Vec<String>, opts: AddOpts) -> Result<()> {
+    let AddOpts { make_default, no_validate, json } = opts;
+    let mut st = load_state(&ptype, no_validate)?;
+    let schema = st.schema_map.get(&ptype);
+    let mut config = Map::new();
+    for (k, v) in fields {
+        let f = schema.and_then(|fs| fs.iter().find(|f| f.name == k));
+        config.insert(k, typed_value(f, &v));
+    }
+    // No model given: the per-type default from Settings (m), if any
+    if !config.contains_key("model") && schema.map_or(false, |fs| fs.iter().any(|f| f.name == "model")) {
+        if let Some(model) = model_defaults::for_type(&ptype) { config.insert("model".to_string(), Value::String(model)); }
+    }
+    let item = serde_json::json!({
+        "id": id.unwrap_or_else(|| ptype.clone()),
+        "name": name.unwrap_or_else(|| ptype.clone()),
+        "type": ptype,
+        "tags": tags,
+        "config": config,
+    });
+    let preview = parse_import(&item.to_string(), &st)?;
+    let Some(c) = preview.candidates.first() else { return Err(anyhow!("nothing to add")) };
+    if !c.errors.is_empty() { return Err(anyhow!("invalid provider: {}", c.errors.join("; "))); }
+    let warnings = if no_validate { Vec::new() } else { c.warnings.clone() };
+    if !json {
+        for w in &warnings { eprintln!("warning: {}", w); }
+    }
+    let new_id = c.entry.id.clone();
+    let _ = maybe_snapshot();
+    apply_import(&mut st, preview);
+    st.save()?;
+    if make_default { save_default_provider(&new_id)?; }
+    after_write();
+    if json { return print_json(&serde_json::json!({"id": new_id, "default": make_default, "warnings": warnings})); }
+    println!("{}", new_id);
+    Ok(())
+}
+
+fn remove(id: &str, json: bool) -> Result<()> {
+    let mut st = ProvidersState::empty();
+    st.entries = read_scratch_entries_raw()?;
+    let before = st.entries.len();
+    st.entries.retain(|e| e.id != id);
+    if st.entries.len() == before { return Err(anyhow!("provider \"{}\" not found", id)); }
+    let _ = maybe_snapshot();
+    st.save()?;
+    after_write();
+    let was_default = crate::rules::resolve_from_store().map_or(false, |r| r.provider_id == id);
+    if json { return print_json(&serde_json::json!({"removed": id, "was_default": was_default})); }
+    if was_default {
+        eprintln!("warning: \"{}\" was the default provider; run `chi-tui config set-default <id>`", id);
+    }
+    Ok(())
+}
+
+fn set_default(id: &str, json: bool) -> Result<()> {
+    if !read_scratch_entries()?.iter().any(|e| e.id == id) { return Err(anyhow!("provider \"{}\" not found", id)); }
+    save_default_provider(id)?;
+    after_write();
+    if json { return print_json(&serde_json::json!({"default": id})); }
+    Ok(())
+}
+
+pub fn run_config(cmd: ConfigCmd) -> Result<()> {
+    match cmd {
+        ConfigCmd::List { json } => list(json),
+        ConfigCmd::Add { ptype, id, name, host, port, model, base_url, api_key, tags, set, default, no_validate, json } => {
+            let mut fields: Vec<(String, String)> = Vec::new();
+            for (k, v) in [("host", host), ("port", port), ("model", model), ("base_url", base_url), ("api_key", api_key)] {
+                if let Some(v) = v { fields.push((k.to_string(), v)); }
+            }
+            for kv in set {
+                let (k, v) = kv.split_once('=').ok_or_else(|| anyhow!("--set expects KEY=VALUE, got \"{}\"", kv))?;
+                fields.push((k.trim().to_string(), v.to_string()));
+            }
+            add(ptype, id, name, fields, tags, AddOpts { make_default: default, no_validate, json })
+        }
+        ConfigCmd::Remove { id, json } => remove(&id, json),
+        ConfigCmd::SetDefault { id, json } => set_default(&id, json),
+    }
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+
+    fn field(ftype: &str) -> FieldSchema {
+        FieldSchema { name: "x".to_string(), ftype: ftype.to_string(), required: false, default: None, help: None, options: None, advanced: false, min: None, max: None, step: None }
+    }
+
+    #[test]
+    fn values_are_typed_per_schema() {
+        assert_eq!(typed_value(Some(&field("int")), "8080"), serde_json::json!(8080));
+        assert_eq!(typed_value(Some(&field("float")), "0.7"), serde_json::json!(0.7));
+        assert_eq!(typed_value(Some(&field("int")), "many"), serde_json::json!("many"));
+        assert_eq!(typed_value(Some(&field("string")), "0123"), serde_json::json!("0123"));
+        // Without a schema only integers look numeric
+        assert_eq!(typed_value(None, "42"), serde_json::json!(42));
+        assert_eq!(typed_value(None, "0.7"), serde_json::json!("0.7"));
+    }
+
+    #[cfg(unix)]
+    fn add_cmd(ptype: &str, set: &[&str], default: bool) -> ConfigCmd {
+        ConfigCmd::Add {
+            ptype: ptype.to_string(),
+            id: None,
+            name: None,
+            host: Some("10.0.0.5".to_string()),
+            port: Some("11434".to_string()),
+            model: None,
+            base_url: None,
+            api_key: None,
+            tags: vec!["lan".to_string()],
+            set: set.iter().map(|s| s.to_string()).collect(),
+            default,
+            no_validate: true,
+            json: false,
+        }
+    }
+
+    #[cfg(unix)]
+    #[test]
+    fn add_set_default_and_remove_edit_the_store() {
+        let _fake = crate::testing::FakeCli::new();
+        run_config(add_cmd("ollama", &["num_ctx=4096"], false)).expect("add");
+        run_config(add_cmd("ollama", &[], true)).expect("add again");
+        let entries = read_scratch_entries_raw().expect("entries");
+        let ids: Vec<&str> = entries.iter().map(|e| e.id.as_str()).collect();
+        assert_eq!(ids, ["ollama", "ollama-2"]);
+        assert_eq!(entries[0].config["port"], 11434);
+        assert_eq!(entries[0].config["num_ctx"], 4096);
+        assert_eq!(entries[0].tags, vec!["lan".to_string()]);
+        assert_eq!(crate::rules::resolve_from_store().expect("default").provider_id, "ollama-2");
+
+        run_config(ConfigCmd::SetDefault { id: "ollama".to_string(), json: false }).expect("set default");
+        assert_eq!(crate::rules::resolve_from_store().expect("default").provider_id, "ollama");
+        assert!(run_config(ConfigCmd::SetDefault { id: "nope".to_string(), json: false }).is_err());
+
+        run_config(ConfigCmd::Remove { id: "ollama-2".to_string(), json: false }).expect("remove");
+        assert_eq!(read_scratch_entries_raw().expect("entries").len(), 1);
+        let err = run_config(ConfigCmd::Remove { id: "ollama-2".to_string(), json: false }).expect_err("gone");
+        assert_eq!(err.to_string(), "provider \"ollama-2\" not found");
+    }
+
+    #[cfg(unix)]
+    #[test]
+    fn malformed_set_is_rejected_before_anything_is_written() {
+        let _fake = crate::testing::FakeCli::new();
+        let err = run_config(add_cmd("ollama", &["num_ctx"], false)).expect_err("no =");
+        assert!(err.to_string().contains("--set expects KEY=VALUE"));
+        assert!(!crate::store::exists());
+    }
+
+    fn parse(argv: &[&str]) -> Option<ConfigCmd> {
+        use clap::Parser;
+        match crate::Args::try_parse_from(argv).expect("parses").command {
+            Some(crate::Cmd::Config { action }) => Some(action),
+            _ => None,
+        }
+    }
+
+    #[test]
+    fn providers_is_an_alias_and_every_command_takes_json() {
+        assert!(matches!(parse(&["chi-tui", "providers", "list", "--json"]), Some(ConfigCmd::List { json: true })));
+        assert!(matches!(parse(&["chi-tui", "config", "list"]), Some(ConfigCmd::List { json: false })));
+        assert!(matches!(parse(&["chi-tui", "providers", "remove", "x", "--json"]), Some(ConfigCmd::Remove { id, json: true }) if id == "x"));
+        assert!(matches!(parse(&["chi-tui", "providers", "set-default", "x", "--json"]), Some(ConfigCmd::SetDefault { id, json: true }) if id == "x"));
+        let add = parse(&["chi-tui", "providers", "add", "--type", "ollama", "--no-validate", "--json"]);
+        assert!(matches!(add, Some(ConfigCmd::Add { ptype, no_validate: true, json: true, .. }) if ptype == "ollama"));
+    }
+
+    #[cfg(unix)]
+    #[test]
+    fn list_json_omits_secrets_and_flags_the_default() {
+        let entries = vec![
+            crate::testing::entry("local", "ollama", serde_json::json!({"port": 11434})),
+            crate::testing::entry("cloud", "openai", serde_json::json!({"model": "gpt-4o-mini", "api_key": "sk-secret"})),
+        ];
+        let out = list_json(&entries, Some("cloud"));
+        assert_eq!(out[0]["id"], "local");
+        assert_eq!(out[0]["default"], false);
+        assert_eq!(out[1]["default"], true);
+        assert_eq!(out[1]["config"], serde_json::json!({"model": "gpt-4o-mini"}));
+        assert!(list_json(&entries, None).as_array().expect("array").iter().all(|v| v["default"] == false));
+    }
+
+    #[cfg(unix)]
+    #[test]
+    fn save_keeps_api_keys_out_of_the_store() {
+        let mut fake = crate::testing::FakeCli::new();
+        fake.respond("providers discover-models --type openai", serde_json::json!({"provider": "openai", "models": [{"id": "gpt-4o-mini"}]}));
+        run_config(crate::testing::add_provider("openai", "oa", &[("api_key", "sk-test"), ("model", "gpt-4o-mini")], false)).expect("add provider");
+
+        let store = fake.store();
+        let key = store["providers"][0]["config"]["api_key"].as_str().expect("api_key");
+        assert!(key.starts_with(crate::secrets::REF_PREFIX), "stored in clear: {}", key);
+        assert!(!serde_json::to_string(&store).unwrap().contains("sk-test"));
+
+        // Test connection resolves the reference before calling the CLI
+        let entry = crate::variables::resolve_entry(&read_scratch_entries().expect("entries")[0]);
+        assert_eq!(crate::providers::probe_provider(&entry).expect("test connection"), "openai: 1 models");
+        assert!(fake.calls().iter().any(|c| c.contains("--api-key sk-test")));
+    }
+
+    #[cfg(unix)]
+    #[test]
+    fn projects_with_the_same_provider_id_keep_their_own_api_keys() {
+        let mut fake = crate::testing::FakeCli::new();
+        fake.respond("providers discover-models --type openai", serde_json::json!({"provider": "openai", "models": [{"id": "gpt-4o-mini"}]}));
+        let other = fake.root.join("other");
+        std::fs::create_dir_all(&other).expect("other project");
+        let key_of = |dir: &std::path::Path| {
+            std::env::set_current_dir(dir).expect("cd");
+            let entry = crate::variables::resolve_entry(&read_scratch_entries().expect("entries")[0]);
+            entry.config["api_key"].as_str().unwrap_or_default().to_string()
+        };
+        run_config(crate::testing::add_provider("openai", "oa", &[("api_key", "sk-one"), ("model", "gpt-4o-mini")], false)).expect("add in one");
+        std::env::set_current_dir(&other).expect("cd other");
+        run_config(crate::testing::add_provider("openai", "oa", &[("api_key", "sk-two"), ("model", "gpt-4o-mini")], false)).expect("add in other");
+        assert_eq!((key_of(&fake.root), key_of(&other)), ("sk-one".to_string(), "sk-two".to_string()));
+
+        // Removing the provider in one project leaves the other's key alone
+        std::env::set_current_dir(&other).expect("cd other");
+        run_config(ConfigCmd::Remove { id: "oa".to_string(), json: false }).expect("remove");
+        assert_eq!(key_of(&fake.root), "sk-one");
+    }
+
+    #[cfg(unix)]
+    #[test]
+    fn invalid_provider_is_not_saved() {
+        let fake = crate::testing::FakeCli::new();
+        let err = run_config(crate::testing::add_provider("openai", "oa", &[("api_key", "sk-test")], false)).expect_err("model is required");
+        assert!(err.to_string().contains("model is required"), "{}", err);
+        assert!(!fake.exists("chi.tmp.json"));
+    }
+}
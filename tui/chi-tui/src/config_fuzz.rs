@@ -0,0 +1,228 @@
+//! Fuzz and round-trip tests for the config layer: the provider store is
+//! edited by hand and by other tools, so reading it must never panic and
+//! saving it must keep whatever chi-tui does not understand. Inputs come
+//! from a seeded generator, so a failure names the seed that reproduces it.
+
+use serde_json::{json, Map, Value};
+
+use crate::buildconflict::plan_project_write;
+use crate::providers::{read_scratch_entries_raw, ProvidersState};
+use crate::store::{self, Format};
+use crate::testing::FakeCli;
+
+const CASES: u64 = 300;
+
+/// xorshift64*: small, deterministic, no extra dependency.
+struct Rng(u64);
+
+impl Rng {
+    fn new(seed: u64) -> Self {
+        Rng(seed.wrapping_mul(0x9E37_79B9_7F4A_7C15) | 1)
+    }
+
+    fn next(&mut self) -> u64 {
+        self.0 ^= self.0 >> 12;
+        self.0 ^= self.0 << 25;
+        self.0 ^= self.0 >> 27;
+        self.0.wrapping_mul(0x2545_F491_4F6C_DD1D)
+    }
+
+    fn below(&mut self, n: u64) -> u64 {
+        self.next() % n
+    }
+
+    fn pick<'a>(&mut self, items: &[&'a str]) -> &'a str {
+        items[self.below(items.len() as u64) as usize]
+    }
+
+    /// Text mixing ASCII, accents, CJK, emoji, quotes and control characters.
+    fn string(&mut self) -> String {
+        const PARTS: [&str; 16] = ["a", "Z", "7", " ", "-", "_", "zażółć", "日本語", "🦀", "é", "\"", "'", "\\", "\n", "\t", "{{ x }}"];
+        (0..self.below(6)).map(|_| self.pick(&PARTS)).collect()
+    }
+
+    /// Keys that are not ones chi-tui gives a meaning to.
+    fn key(&mut self) -> String {
+        format!("x{}", self.string())
+    }
+
+    fn number(&mut self) -> Value {
+        match self.below(6) {
+            0 => json!(self.next() as i64),
+            1 => json!(u64::MAX),
+            2 => json!(i64::MIN),
+            3 => json!(1e308),
+            4 => json!(-0.5),
+            _ => json!(self.below(70000)),
+        }
+    }
+
+    fn value(&mut self, depth: u32) -> Value {
+        let scalar = if depth == 0 { 4 } else { 6 };
+        match self.below(scalar) {
+            0 => Value::Null,
+            1 => Value::Bool(self.below(2) == 0),
+            2 => self.number(),
+            3 => Value::String(self.string()),
+            4 => Value::Array((0..self.below(4)).map(|_| self.value(depth - 1)).collect()),
+            _ => Value::Object((0..self.below(4)).map(|_| (self.key(), self.value(depth - 1))).collect()),
+        }
+    }
+
+    /// A well-formed provider with unknown keys next to the known ones.
+    fn provider(&mut self, i: usize) -> Value {
+        let ptype = self.pick(&["local", "ollama", "openai", "someday-new-type"]);
+        let mut config: Map<String, Value> = (0..self.below(4)).map(|_| (self.key(), self.value(2))).collect();
+        config.insert("type".to_string(), json!(ptype));
+        config.insert("port".to_string(), self.number());
+        let mut p: Map<String, Value> = (0..self.below(3)).map(|_| (self.key(), self.value(2))).collect();
+        p.insert("id".to_string(), json!(format!("p{}{}", i, self.string())));
+        p.insert("name".to_string(), json!(self.string()));
+        p.insert("type".to_string(), json!(ptype));
+        p.insert("tags".to_string(), json!((0..self.below(3)).map(|_| self.string()).collect::<Vec<_>>()));
+        p.insert("config".to_string(), Value::Object(config));
+        Value::Object(p)
+    }
+
+    /// A store root: providers plus top-level keys of other tools.
+    fn store(&mut self) -> Value {
+        let mut root: Map<String, Value> = (0..self.below(4)).map(|_| (self.key(), self.value(3))).collect();
+        let providers: Vec<Value> = (0..self.below(4)).map(|i| self.provider(i as usize)).collect();
+        if let Some(Value::Object(first)) = providers.first() {
+            root.insert("default_provider_id".to_string(), first["id"].clone());
+        }
+        root.insert("providers".to_string(), Value::Array(providers));
+        Value::Object(root)
+    }
+
+    /// `text` truncated, with characters dropped, or with bytes spliced in.
+    fn mutate(&mut self, text: &str) -> String {
+        let chars: Vec<char> = text.chars().collect();
+        if chars.is_empty() { return self.string(); }
+        let at = self.below(chars.len() as u64) as usize;
+        match self.below(4) {
+            0 => chars[..at].iter().collect(),
+            1 => chars.iter().enumerate().filter(|(i, _)| *i != at).map(|(_, c)| *c).collect(),
+            2 => {
+                let junk = self.pick(&["{", "}", "[", "]", ":", ",", "\"", "-", "\u{0}", "\u{feff}", "1e999", "&a", "*a", "!!binary", "? "]);
+                let mut out: String = chars[..at].iter().collect();
+                out.push_str(junk);
+                out.extend(&chars[at..]);
+                out
+            }
+            _ => chars.iter().map(|c| if self.below(20) == 0 { char::from_u32(self.below(0x3000) as u32).unwrap_or('?') } else { *c }).collect(),
+        }
+    }
+}
+
+/// Store text that should not parse, or parses into something unexpected.
+const AWKWARD: [&str; 18] = [
+    "",
+    "null",
+    "[]",
+    "\"providers\"",
+    "1e400",
+    "123456789012345678901234567890",
+    "{\"providers\": 5}",
+    "{\"providers\": [1, \"a\", null, [], {}]}",
+    "{\"providers\": [{\"id\": 7, \"type\": [], \"tags\": \"x\", \"config\": \"y\"}]}",
+    "{\"providers\": [{\"config\": {\"port\": 1e308}}], \"default_provider_id\": {}}",
+    "{\"config_format\": 3, \"providers\": {}}",
+    "\u{feff}{}",
+    "{\"a\": \"\\ud800\"}",
+    "providers:\n  - id: a\n    type: ollama\n    config: [1, 2]\n",
+    "providers: &a [*a]",
+    "- - - -",
+    "{\"providers\": [{\"id\": \"\u{1F980}\", \"name\": \"日本語\", \"type\": \"ollama\", \"config\": {\"type\": \"ollama\"}}]}",
+    "\t\n",
+];
+
+fn deep(n: usize) -> String {
+    format!("{}{}", "[".repeat(n), "]".repeat(n))
+}
+
+/// Everything that reads the store, on whatever `text` is.
+fn read_all(text: &str, name: &str) {
+    std::fs::write(name, text).expect("write store");
+    let _ = store::read();
+    let root = store::read_or_empty();
+    assert!(root.is_object());
+    let _ = store::write_format(&root);
+    if let Ok(entries) = read_scratch_entries_raw() {
+        for e in entries {
+            assert!(e.config.is_object(), "config of {:?} is not an object", e.id);
+        }
+    }
+    let _ = crate::rules::resolve_from_store();
+    let _ = plan_project_write();
+    std::fs::remove_file(name).expect("remove store");
+}
+
+#[test]
+fn parse_never_panics() {
+    for text in AWKWARD.iter().map(|s| s.to_string()).chain([deep(64), deep(100_000)]) {
+        let _ = store::parse(&text);
+    }
+    for seed in 0..CASES {
+        let mut rng = Rng::new(seed);
+        let valid = store::to_text(&rng.store(), if seed % 2 == 0 { Format::Json } else { Format::Yaml }).expect("serialize");
+        let text = rng.mutate(&valid);
+        let _ = store::parse(&text);
+        let bytes: Vec<u8> = (0..rng.below(64)).map(|_| rng.next() as u8).collect();
+        let _ = store::parse(&String::from_utf8_lossy(&bytes));
+    }
+}
+
+#[test]
+fn awkward_stores_load_without_panicking() {
+    let _fake = FakeCli::new();
+    for text in AWKWARD {
+        read_all(text, "chi.tmp.json");
+        read_all(text, "chi.tmp.yaml");
+    }
+    for seed in 0..CASES {
+        let mut rng = Rng::new(seed);
+        let valid = serde_json::to_string(&rng.store()).expect("serialize");
+        read_all(&rng.mutate(&valid), "chi.tmp.json");
+    }
+}
+
+#[test]
+fn wrong_types_fall_back_to_defaults() {
+    let _fake = FakeCli::new();
+    std::fs::write("chi.tmp.json", AWKWARD[8]).expect("write store");
+    let entries = read_scratch_entries_raw().expect("entries");
+    assert_eq!(entries.len(), 1);
+    assert_eq!(entries[0].id, "7");
+    assert!(entries[0].tags.is_empty());
+    assert_eq!(entries[0].config, json!({"type": ""}));
+    // Non-objects in the list are not providers
+    std::fs::write("chi.tmp.json", AWKWARD[7]).expect("write store");
+    assert_eq!(read_scratch_entries_raw().expect("entries").len(), 1);
+}
+
+/// Load the store and save it straight back, as the Configure page does.
+fn load_and_save() {
+    let mut st = ProvidersState::empty();
+    st.entries = read_scratch_entries_raw().expect("entries");
+    st.save().expect("save");
+}
+
+#[test]
+fn save_round_trips_unknown_keys() {
+    let _fake = FakeCli::new();
+    for seed in 0..CASES {
+        let mut rng = Rng::new(seed);
+        let mut root = rng.store();
+        let fmt = if seed % 3 == 0 { Format::Yaml } else { Format::Json };
+        if fmt == Format::Yaml { root["config_format"] = json!("yaml"); }
+        let path = store::write(&root).expect("write store");
+        assert_eq!(store::read().expect("read store"), root, "seed {} ({})", seed, path);
+
+        load_and_save();
+        assert_eq!(store::read().expect("read store"), root, "seed {}: save changed the store", seed);
+        let first = std::fs::read_to_string(&path).expect("store text");
+        load_and_save();
+        assert_eq!(std::fs::read_to_string(&path).expect("store text"), first, "seed {}: second save differs", seed);
+    }
+}
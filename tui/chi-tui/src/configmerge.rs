@@ -0,0 +1,224 @@
+//! Field-by-field merge of the project config and the global one. chi_llm
+//! lets the project file win wholesale; here every field that differs is
+//! listed with both values, the user picks a side per field, and the result
+//! is written to the project file (global picks are copied in, or removed
+//! from the project file when only the global config has the field).
+
+use anyhow::{anyhow, Result};
+use ratatui::layout::{Constraint, Rect};
+use ratatui::prelude::Frame;
+use ratatui::style::{Modifier, Style};
+use ratatui::text::Span;
+use ratatui::widgets::{Block, Borders, Cell, Clear, Row, Table, TableState};
+use serde_json::{Map, Value};
+
+use crate::app::App;
+use crate::audit;
+use crate::build::{global_config_path, project_config};
+use crate::store::{self, Format};
+use crate::theme::StatusKind;
+use crate::util::overlay_rect;
+
+#[derive(Copy, Clone, Debug, PartialEq, Eq)]
+pub enum Pick {
+    Project,
+    Global,
+}
+
+/// A leaf that differs between the two configs; None is "not set".
+#[derive(Clone, Debug)]
+pub struct MergeField {
+    pub path: Vec<String>,
+    pub project: Option<Value>,
+    pub global: Option<Value>,
+    pub pick: Pick,
+}
+
+impl MergeField {
+    pub fn label(&self) -> String {
+        self.path.join(".")
+    }
+}
+
+#[derive(Clone, Debug)]
+pub struct ConfigMerge {
+    pub project_path: &'static str,
+    project: Value,
+    pub fields: Vec<MergeField>,
+    pub selected: usize,
+}
+
+impl ConfigMerge {
+    /// Differences between the project config and the global one. Errors
+    /// when there is no project config or the two agree.
+    pub fn load() -> Result<Self> {
+        let project_path = project_config().ok_or_else(|| anyhow!("no project config here; nothing overrides the global one"))?;
+        let project = read_object(project_path)?;
+        let global_path = global_config_path()?;
+        let global = match std::fs::read_to_string(&global_path) {
+            Ok(_) => read_object(&global_path.to_string_lossy())?,
+            Err(_) => Value::Object(Map::new()),
+        };
+        let (mut p, mut g) = (Vec::new(), Vec::new());
+        leaves(&project, &mut Vec::new(), &mut p);
+        leaves(&global, &mut Vec::new(), &mut g);
+        let mut fields: Vec<MergeField> = Vec::new();
+        for (path, v) in &p {
+            let other = g.iter().find(|(gp, _)| gp == path).map(|(_, gv)| gv.clone());
+            if other.as_ref() != Some(v) {
+                fields.push(MergeField { path: path.clone(), project: Some(v.clone()), global: other, pick: Pick::Project });
+            }
+        }
+        for (path, v) in g.iter().filter(|(gp, _)| !p.iter().any(|(pp, _)| pp == gp)) {
+            // Shadowed by a project value that is not an object (e.g. provider: null)
+            if p.iter().any(|(pp, _)| path.starts_with(pp)) { continue; }
+            fields.push(MergeField { path: path.clone(), project: None, global: Some(v.clone()), pick: Pick::Project });
+        }
+        if fields.is_empty() { return Err(anyhow!("{} and the global config agree", project_path)); }
+        fields.sort_by(|a, b| a.path.cmp(&b.path));
+        Ok(ConfigMerge { project_path, project, fields, selected: 0 })
+    }
+
+    pub fn set_selected(&mut self, pick: Pick) {
+        if let Some(f) = self.fields.get_mut(self.selected) { f.pick = pick; }
+    }
+
+    pub fn toggle_selected(&mut self) {
+        if let Some(f) = self.fields.get_mut(self.selected) {
+            f.pick = if f.pick == Pick::Project { Pick::Global } else { Pick::Project };
+        }
+    }
+
+    pub fn set_all(&mut self, pick: Pick) {
+        for f in &mut self.fields { f.pick = pick; }
+    }
+
+    /// The project config with every global pick applied.
+    pub fn result(&self) -> Value {
+        let mut out = self.project.clone();
+        for f in self.fields.iter().filter(|f| f.pick == Pick::Global) {
+            set_path(&mut out, &f.path, f.global.clone());
+        }
+        out
+    }
+
+    /// Write the result to the project config, in its own format.
+    pub fn write(&self) -> Result<String> {
+        let after = self.result();
+        std::fs::write(self.project_path, store::to_text(&after, Format::of_path(self.project_path))?)?;
+        let _ = audit::record("build.merge", self.project_path, &self.project, &after);
+        Ok(self.project_path.to_string())
+    }
+}
+
+fn read_object(path: &str) -> Result<Value> {
+    let text = std::fs::read_to_string(path)?;
+    let v = store::parse(&text).map_err(|e| anyhow!("{}: {}", path, e))?;
+    if !v.is_object() { return Err(anyhow!("{} is not an object", path)); }
+    Ok(v)
+}
+
+/// Every non-object value with its key path; empty objects count as values.
+fn leaves(v: &Value, path: &mut Vec<String>, out: &mut Vec<(Vec<String>, Value)>) {
+    match v {
+        Value::Object(m) if !m.is_empty() => {
+            for (k, x) in m {
+                path.push(k.clone());
+                leaves(x, path, out);
+                path.pop();
+            }
+        }
+        _ if path.is_empty() => {}
+        _ => out.push((path.clone(), v.clone())),
+    }
+}
+
+/// Set (or with None, remove) the value at `path`, creating objects on the way.
+fn set_path(root: &mut Value, path: &[String], value: Option<Value>) {
+    let Some((last, parents)) = path.split_last() else { return };
+    let mut cur = root;
+    for k in parents {
+        if !cur.is_object() { *cur = Value::Object(Map::new()); }
+        let Value::Object(m) = cur else { return };
+        cur = m.entry(k.clone()).or_insert_with(|| Value::Object(Map::new()));
+    }
+    if !cur.is_object() { *cur = Value::Object(Map::new()); }
+    let Value::Object(m) = cur else { return };
+    match value {
+        Some(v) => { m.insert(last.clone(), v); }
+        None => { m.remove(last); }
+    }
+}
+
+fn show(v: &Option<Value>) -> String {
+    match v {
+        None => "—".to_string(),
+        Some(Value::String(s)) => s.clone(),
+        Some(v) => v.to_string(),
+    }
+}
+
+pub fn draw_config_merge(f: &mut Frame, area: Rect, app: &App) {
+    let Some(m) = app.build.as_ref().and_then(|b| b.merge.as_ref()) else { return };
+    let pop = overlay_rect(app.compact, 92, 80, area);
+    let chosen = app.theme.status_style(StatusKind::Ok).add_modifier(Modifier::BOLD);
+    let other = Style::default().fg(app.theme.secondary).add_modifier(Modifier::CROSSED_OUT);
+    let rows: Vec<Row> = m
+        .fields
+        .iter()
+        .enumerate()
+        .map(|(i, fld)| {
+            let (ps, gs) = if fld.pick == Pick::Project { (chosen, other) } else { (other, chosen) };
+            let name = format!("{} {}", if i == m.selected { '›' } else { ' ' }, fld.label());
+            let name_style = if i == m.selected { Style::default().fg(app.theme.selected).add_modifier(Modifier::BOLD) } else { Style::default().fg(app.theme.fg) };
+            Row::new(vec![
+                Cell::from(Span::styled(name, name_style)),
+                Cell::from(Span::styled(show(&fld.project), ps)),
+                Cell::from(Span::styled(show(&fld.global), gs)),
+            ])
+        })
+        .collect();
+    let header = Row::new(vec!["Field", "Project", "Global"]).style(Style::default().fg(app.theme.accent).add_modifier(Modifier::BOLD));
+    let widths = [Constraint::Percentage(30), Constraint::Percentage(35), Constraint::Percentage(35)];
+    let wins = m.fields.iter().filter(|f| f.pick == Pick::Global).count();
+    let title = format!(
+        "Merge {} ↔ global ({}/{} global) — ←/→ pick • Space toggle • p/g all • w write • Esc cancel",
+        m.project_path,
+        wins,
+        m.fields.len()
+    );
+    let table = Table::new(rows, widths)
+        .header(header)
+        .style(Style::default().bg(app.theme.bg).fg(app.theme.fg))
+        .block(Block::default().borders(Borders::ALL).border_style(Style::default().fg(app.theme.accent)).title(title));
+    f.render_widget(Clear, pop);
+    // Keeps the selected field in view on long configs
+    let mut state = TableState::default().with_selected(Some(m.selected));
+    f.render_stateful_widget(table, pop, &mut state);
+}
+
+#[cfg(all(test, unix))]
+mod tests {
+    use serde_json::json;
+
+    use super::*;
+    use crate::testing::FakeCli;
+
+    #[test]
+    fn merge_tool_picks_per_field() {
+        let _fake = FakeCli::new();
+        std::fs::write(".chi_llm.json", r#"{"provider": {"type": "ollama", "port": 11434}, "default_model": "a"}"#).expect("project config");
+        let global = crate::build::global_config_path().expect("global path");
+        std::fs::create_dir_all(global.parent().expect("dir")).expect("mkdir");
+        std::fs::write(&global, r#"{"provider": {"type": "ollama", "port": 11500, "host": "gpu"}, "default_model": "a"}"#).expect("global config");
+
+        let mut m = ConfigMerge::load().expect("differences");
+        let labels: Vec<String> = m.fields.iter().map(|f| f.label()).collect();
+        assert_eq!(labels, vec!["provider.host", "provider.port"]);
+        m.set_all(Pick::Global);
+        m.write().expect("write");
+        let written: serde_json::Value = serde_json::from_str(&std::fs::read_to_string(".chi_llm.json").expect("read")).expect("json");
+        assert_eq!(written, json!({"provider": {"type": "ollama", "port": 11500, "host": "gpu"}, "default_model": "a"}));
+        assert!(ConfigMerge::load().is_err(), "configs agree after taking every global value");
+    }
+}
@@ -0,0 +1,134 @@
+use crossterm::event::{KeyCode, KeyEvent};
+use ratatui::layout::Rect;
+use ratatui::prelude::Frame;
+use ratatui::style::{Modifier, Style};
+use ratatui::text::{Line, Span};
+use ratatui::widgets::{Block, Borders, Clear, Paragraph, Wrap};
+
+use crate::app::App;
+use crate::theme::StatusKind;
+use crate::util::overlay_rect;
+
+/// What a confirmed dialog goes on to do; the caller runs it.
+#[derive(Clone, Debug, PartialEq, Eq)]
+pub enum ConfirmAction {
+    /// Remove the Configure entry with this id
+    DeleteProvider(String),
+    /// Make this provider the default although its pre-flight failed
+    SetDefault(String),
+    /// Download this gated model although no Hugging Face token is set
+    DownloadModel(String),
+    /// Move the project config aside so the global one applies
+    UseGlobalHere,
+    /// Copy the project config into the global one
+    PinGlobally,
+    /// Switch to this profile, dropping unsaved provider changes
+    SwitchProfile(String),
+    /// Delete this saved profile
+    DeleteProfile(String),
+    /// Quit, dropping unsaved provider changes
+    Quit,
+}
+
+/// Yes/no question before a destructive action. While open it takes every
+/// key: y/Enter confirms, n/Esc cancels, anything else is ignored.
+#[derive(Clone, Debug)]
+pub struct ConfirmDialog {
+    pub title: String,
+    pub message: Vec<String>,
+    /// Label of the confirming choice, e.g. "delete"
+    pub yes: String,
+    pub action: ConfirmAction,
+}
+
+impl ConfirmDialog {
+    pub fn new(title: impl Into<String>, message: Vec<String>, yes: impl Into<String>, action: ConfirmAction) -> Self {
+        ConfirmDialog { title: title.into(), message, yes: yes.into(), action }
+    }
+}
+
+/// Dialog keys. Returns the action once confirmed; the dialog is closed on
+/// y/n/Enter/Esc.
+pub fn handle_confirm_key(app: &mut App, key: KeyEvent) -> Option<ConfirmAction> {
+    match key.code {
+        KeyCode::Char('y') | KeyCode::Char('Y') | KeyCode::Enter => app.confirm.take().map(|d| d.action),
+        KeyCode::Char('n') | KeyCode::Char('N') | KeyCode::Esc => { app.confirm = None; None }
+        _ => None,
+    }
+}
+
+pub fn draw_confirm(f: &mut Frame, area: Rect, app: &App) {
+    let Some(dlg) = &app.confirm else { return };
+    let pop = overlay_rect(app.compact, 56, 30, area);
+    let mut lines: Vec<Line> = dlg.message.iter().map(|m| Line::from(m.clone())).collect();
+    lines.push(Line::from(""));
+    lines.push(Line::from(vec![
+        Span::styled(format!("y/Enter  {}", dlg.yes), app.theme.status_style(StatusKind::Warn).add_modifier(Modifier::BOLD)),
+        Span::raw("   "),
+        Span::styled("n/Esc  cancel", Style::default().fg(app.theme.secondary)),
+    ]));
+    let p = Paragraph::new(lines)
+        .style(Style::default().bg(app.theme.bg).fg(app.theme.fg))
+        .block(Block::default().borders(Borders::ALL).border_style(Style::default().fg(app.theme.accent)).title(dlg.title.clone()))
+        .wrap(Wrap { trim: false });
+    f.render_widget(Clear, pop);
+    f.render_widget(p, pop);
+}
+
+#[cfg(all(test, unix))]
+mod tests {
+    use crossterm::event::KeyModifiers;
+
+    use super::*;
+
+    fn press(app: &mut App, code: KeyCode) -> Option<ConfirmAction> {
+        handle_confirm_key(app, KeyEvent::new(code, KeyModifiers::NONE))
+    }
+
+    fn asking(app: &mut App) {
+        app.confirm = Some(ConfirmDialog::new("Delete box?", vec!["It is the default.".to_string()], "delete", ConfirmAction::DeleteProvider("box".to_string())));
+    }
+
+    #[test]
+    fn y_and_enter_confirm_and_close() {
+        let _fake = crate::testing::FakeCli::new();
+        let mut app = App::new(false);
+        for code in [KeyCode::Char('y'), KeyCode::Char('Y'), KeyCode::Enter] {
+            asking(&mut app);
+            assert_eq!(press(&mut app, code), Some(ConfirmAction::DeleteProvider("box".to_string())));
+            assert!(app.confirm.is_none());
+        }
+    }
+
+    #[test]
+    fn n_and_esc_cancel_and_other_keys_are_ignored() {
+        let _fake = crate::testing::FakeCli::new();
+        let mut app = App::new(false);
+        asking(&mut app);
+        assert_eq!(press(&mut app, KeyCode::Char('x')), None);
+        assert_eq!(press(&mut app, KeyCode::Down), None);
+        assert!(app.confirm.is_some(), "still open");
+        assert_eq!(press(&mut app, KeyCode::Esc), None);
+        assert!(app.confirm.is_none());
+        asking(&mut app);
+        assert_eq!(press(&mut app, KeyCode::Char('n')), None);
+        assert!(app.confirm.is_none());
+    }
+
+    #[test]
+    fn quitting_with_unsaved_changes_asks_first() {
+        let _fake = crate::testing::FakeCli::new();
+        let mut app = App::new(false);
+        let mut st = crate::providers::ProvidersState::empty();
+        st.entries.push(crate::testing::entry("box", "ollama", serde_json::json!({"host": "box"})));
+        app.providers = Some(st);
+        let key = |code| KeyEvent::new(code, KeyModifiers::NONE);
+        crate::keys::handle_key(&mut app, key(KeyCode::Char('q')));
+        assert_eq!(app.confirm.as_ref().map(|d| d.action.clone()), Some(ConfirmAction::Quit));
+        crate::keys::handle_key(&mut app, key(KeyCode::Esc));
+        assert!(!app.should_quit && app.confirm.is_none());
+        crate::keys::handle_key(&mut app, key(KeyCode::Char('q')));
+        crate::keys::handle_key(&mut app, key(KeyCode::Enter));
+        assert!(app.should_quit);
+    }
+}
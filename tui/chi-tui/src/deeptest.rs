@@ -0,0 +1,257 @@
+use std::io::{BufRead, BufReader};
+use std::sync::mpsc::{channel, Receiver};
+use std::thread;
+use std::time::{Duration, Instant};
+
+use anyhow::{anyhow, Result};
+use serde_json::Value;
+
+use crate::http::{self, provider_request};
+use crate::inspector;
+use crate::locale;
+use crate::log;
+use crate::providers::ProviderScratchEntry;
+
+const PROMPT: &str = "Say ok.";
+const MAX_TOKENS: u32 = 16;
+/// Generous: Ollama and LM Studio load the model on the first request
+const TIMEOUT: Duration = Duration::from_secs(120);
+/// Reply text shown in the status line is cut here.
+const MAX_TEXT_CHARS: usize = 60;
+
+/// Outcome of a streamed completion against the configured model.
+#[derive(Clone, Debug)]
+pub struct DeepResult {
+    pub model: String,
+    /// None when the server answered without streaming
+    pub first_token: Option<Duration>,
+    pub total: Duration,
+    pub text: String,
+    /// Completion tokens: the server's `usage` when it sends one, else the
+    /// number of streamed text chunks
+    pub tokens: u64,
+}
+
+impl DeepResult {
+    pub fn summary(&self, provider_id: &str) -> String {
+        let ms = |d: Duration| format!("{} ms", locale::count(d.as_millis() as u64));
+        let mut text: String = self.text.split_whitespace().collect::<Vec<_>>().join(" ");
+        if text.chars().count() > MAX_TEXT_CHARS { text = text.chars().take(MAX_TEXT_CHARS).collect::<String>() + "…"; }
+        let first = self.first_token.map_or("not streamed".to_string(), ms);
+        format!("Deep test {} ✓ {}: first token {}, total {} — \"{}\"", provider_id, self.model, first, ms(self.total), text)
+    }
+}
+
+/// Message of an API error body (`{"error": {"message": ...}}` and the
+/// like), else the start of the body.
+fn error_message(body: &str) -> String {
+    let v: Value = serde_json::from_str(body).unwrap_or(Value::Null);
+    v.pointer("/error/message")
+        .or_else(|| v.get("error").filter(|e| e.is_string()))
+        .or_else(|| v.get("message"))
+        .and_then(|m| m.as_str())
+        .map(|m| m.to_string())
+        .unwrap_or_else(|| body.trim().chars().take(200).collect())
+}
+
+/// Text of one stream event: a Chat Completions chunk or an Anthropic
+/// `content_block_delta`.
+fn delta_text(v: &Value) -> Option<&str> {
+    v.pointer("/choices/0/delta/content").or_else(|| v.pointer("/delta/text")).and_then(|t| t.as_str())
+}
+
+/// Completion token count of a `usage` object (Chat Completions or Anthropic).
+fn usage_tokens(v: &Value) -> Option<u64> {
+    v.pointer("/usage/completion_tokens").or_else(|| v.pointer("/usage/output_tokens")).and_then(|n| n.as_u64())
+}
+
+/// Send a tiny streamed completion and time it. Blocking; see `DeepTest`.
+pub fn run(entry: &ProviderScratchEntry) -> Result<DeepResult> {
+    stream(entry, PROMPT, MAX_TOKENS, TIMEOUT)
+}
+
+/// Stream a completion of `prompt` from the configured model, timing the
+/// first token and the whole reply. Also used by the benchmark runner.
+pub fn stream(entry: &ProviderScratchEntry, prompt: &str, max_tokens: u32, timeout: Duration) -> Result<DeepResult> {
+    let model = entry.config.get("model").and_then(|v| v.as_str()).map(|s| s.trim()).unwrap_or("").to_string();
+    if model.is_empty() { return Err(anyhow!("no model configured; set one to run a deep test")); }
+    let (url, mut body) = inspector::chat_request(entry, prompt, Some(max_tokens)).ok_or_else(|| anyhow!("{} has no HTTP chat API to test", entry.ptype))?;
+    body["stream"] = Value::Bool(true);
+    let req = provider_request(entry, "POST", url, Some(body.to_string()), timeout);
+    let client = http::builder(&http::provider_settings(entry))?.timeout(timeout).build()?;
+    let mut rb = client.post(&req.url);
+    for (k, v) in &req.headers { rb = rb.header(k.as_str(), v.as_str()); }
+    let start = Instant::now();
+    let sent = rb.body(req.body.clone().unwrap_or_default()).send();
+    if let Some(requests) = http::RequestLog::of(entry) {
+        // The streamed body is not logged, only the response head
+        let head = sent.as_ref().map_err(|e| anyhow!("{}", e)).map(|r| http::Response {
+            status: r.status().as_u16(),
+            headers: r.headers().iter().map(|(k, v)| (k.to_string(), v.to_str().unwrap_or("<binary>").to_string())).collect(),
+            body: String::new(),
+        });
+        requests.record(&req, &head, start.elapsed());
+    }
+    let resp = sent?;
+    let status = resp.status().as_u16();
+    if !resp.status().is_success() {
+        return Err(anyhow!("HTTP {}: {}", status, error_message(&resp.text().unwrap_or_default())));
+    }
+    let mut first_token = None;
+    let mut text = String::new();
+    let (mut chunks, mut usage) = (0u64, None);
+    // Lines that are not SSE data, in case the server ignored `stream`
+    let mut plain = String::new();
+    for line in BufReader::new(resp).lines() {
+        let line = line?;
+        let Some(data) = line.strip_prefix("data:").map(str::trim) else {
+            plain.push_str(&line);
+            continue;
+        };
+        if data == "[DONE]" { break; }
+        let Ok(v) = serde_json::from_str::<Value>(data) else { continue };
+        if v.get("error").is_some() { return Err(anyhow!(error_message(data))); }
+        if let Some(t) = delta_text(&v).filter(|t| !t.is_empty()) {
+            first_token.get_or_insert_with(|| start.elapsed());
+            text.push_str(t);
+            chunks += 1;
+        }
+        usage = usage_tokens(&v).or(usage);
+        if v.get("type").and_then(|t| t.as_str()) == Some("message_stop") { break; }
+    }
+    if first_token.is_none() {
+        let v: Value = serde_json::from_str(&plain).unwrap_or(Value::Null);
+        match v.pointer("/choices/0/message/content").or_else(|| v.pointer("/content/0/text")).and_then(|c| c.as_str()) {
+            Some(t) => text = t.to_string(),
+            None => return Err(anyhow!("the model returned no text")),
+        }
+        usage = usage_tokens(&v);
+    }
+    Ok(DeepResult { model, first_token, total: start.elapsed(), text, tokens: usage.unwrap_or(chunks) })
+}
+
+/// One deep test at a time, run off the UI thread.
+#[derive(Default)]
+pub struct DeepTest {
+    rx: Option<Receiver<Result<DeepResult, String>>>,
+    /// Provider id under test
+    pub running: Option<String>,
+}
+
+impl DeepTest {
+    pub fn start(&mut self, entry: ProviderScratchEntry) {
+        let (tx, rx) = channel();
+        self.running = Some(entry.id.clone());
+        self.rx = Some(rx);
+        thread::spawn(move || {
+            let _ = tx.send(run(&entry).map_err(|e| e.to_string()));
+        });
+    }
+
+    /// Status line of a finished test, once.
+    pub fn poll(&mut self) -> Option<String> {
+        let result = match self.rx.as_ref()?.try_recv() {
+            Ok(r) => r,
+            Err(std::sync::mpsc::TryRecvError::Empty) => return None,
+            Err(std::sync::mpsc::TryRecvError::Disconnected) => Err("test stopped unexpectedly".to_string()),
+        };
+        self.rx = None;
+        let id = self.running.take().unwrap_or_default();
+        Some(match result {
+            Ok(r) => {
+                log::info(&format!("deep test {}: first token {:?}, total {:?}", id, r.first_token, r.total));
+                r.summary(&id)
+            }
+            Err(e) => {
+                log::warn(&format!("deep test {} failed: {}", id, e));
+                format!("Error: deep test {}: {}", id, e)
+            }
+        })
+    }
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+
+    #[test]
+    fn the_summary_times_the_reply_and_cuts_long_text() {
+        let r = DeepResult { model: "qwen".to_string(), first_token: Some(Duration::from_millis(80)), total: Duration::from_millis(420), text: " ok\n".to_string(), tokens: 1 };
+        assert_eq!(r.summary("box"), "Deep test box ✓ qwen: first token 80 ms, total 420 ms — \"ok\"");
+        let long = DeepResult { first_token: None, text: "x".repeat(100), ..r };
+        let s = long.summary("box");
+        assert!(s.contains("first token not streamed") && s.ends_with(&format!("\"{}…\"", "x".repeat(MAX_TEXT_CHARS))));
+    }
+
+    #[test]
+    fn stream_events_and_errors_are_read() {
+        assert_eq!(error_message(r#"{"error": {"message": "bad key"}}"#), "bad key");
+        assert_eq!(error_message(r#"{"error": "model not found"}"#), "model not found");
+        assert_eq!(error_message(r#"{"message": "overloaded"}"#), "overloaded");
+        assert_eq!(error_message(" Bad Gateway "), "Bad Gateway");
+        let chunk = serde_json::json!({"choices": [{"delta": {"content": "Hi"}}]});
+        assert_eq!(delta_text(&chunk), Some("Hi"));
+        assert_eq!(delta_text(&serde_json::json!({"type": "content_block_delta", "delta": {"text": "Yo"}})), Some("Yo"));
+        assert_eq!(usage_tokens(&serde_json::json!({"usage": {"completion_tokens": 7}})), Some(7));
+        assert_eq!(usage_tokens(&serde_json::json!({"usage": {"output_tokens": 4}})), Some(4));
+        assert_eq!(usage_tokens(&chunk), None);
+    }
+
+    #[cfg(unix)]
+    fn entry_at(base: &str, model: &str) -> ProviderScratchEntry {
+        crate::testing::entry("box", "openai-compatible", serde_json::json!({"base_url": format!("{}/v1", base), "model": model}))
+    }
+
+    #[cfg(unix)]
+    #[test]
+    fn a_streamed_reply_is_timed_and_counted() {
+        let _fake = crate::testing::FakeCli::new();
+        let sse = [
+            r#"data: {"choices": [{"delta": {"role": "assistant"}}]}"#,
+            r#"data: {"choices": [{"delta": {"content": "o"}}]}"#,
+            r#"data: {"choices": [{"delta": {"content": "k"}}]}"#,
+            r#"data: {"choices": [], "usage": {"completion_tokens": 3}}"#,
+            "data: [DONE]",
+        ]
+        .join("\n\n");
+        let url = crate::testing::http_server("200 OK", "text/event-stream", &sse);
+        let r = run(&entry_at(&url, "m")).expect("deep test");
+        assert_eq!((r.model.as_str(), r.text.as_str(), r.tokens), ("m", "ok", 3));
+        assert!(r.first_token.expect("streamed") <= r.total);
+    }
+
+    #[cfg(unix)]
+    #[test]
+    fn a_reply_without_streaming_still_counts() {
+        let _fake = crate::testing::FakeCli::new();
+        let body = r#"{"choices": [{"message": {"content": "ok"}}], "usage": {"completion_tokens": 1}}"#;
+        let url = crate::testing::http_server("200 OK", "application/json", body);
+        let r = run(&entry_at(&url, "m")).expect("deep test");
+        assert_eq!((r.first_token, r.text.as_str(), r.tokens), (None, "ok", 1));
+
+        let url = crate::testing::http_server("200 OK", "application/json", r#"{"choices": []}"#);
+        assert_eq!(run(&entry_at(&url, "m")).expect_err("empty").to_string(), "the model returned no text");
+    }
+
+    #[cfg(unix)]
+    #[test]
+    fn failures_name_the_cause() {
+        let _fake = crate::testing::FakeCli::new();
+        let url = crate::testing::http_server("401 Unauthorized", "application/json", r#"{"error": {"message": "invalid api key"}}"#);
+        assert_eq!(run(&entry_at(&url, "m")).expect_err("auth").to_string(), "HTTP 401: invalid api key");
+        let local = crate::testing::entry("box", "local", serde_json::json!({"model": "qwen"}));
+        assert!(run(&local).expect_err("no api").to_string().contains("no HTTP chat API"));
+
+        let mut dt = DeepTest::default();
+        dt.start(entry_at(&url, " "));
+        assert_eq!(dt.running.as_deref(), Some("box"));
+        let started = Instant::now();
+        let line = loop {
+            if let Some(line) = dt.poll() { break line; }
+            assert!(started.elapsed() < Duration::from_secs(5));
+            thread::sleep(Duration::from_millis(10));
+        };
+        assert_eq!(line, "Error: deep test box: no model configured; set one to run a deep test");
+        assert!(dt.running.is_none() && dt.poll().is_none());
+    }
+}
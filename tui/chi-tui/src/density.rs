@@ -0,0 +1,110 @@
+use anyhow::Result;
+use ratatui::widgets::Padding;
+use serde_json::Value;
+
+use crate::store;
+
+/// UI density: `Compact` drops paddings, spacer lines and menu help lines so
+/// small windows fit more; `Comfortable` spreads content on large displays.
+/// Independent of the compact *layout*, which is about terminal size.
+#[derive(Copy, Clone, Debug, PartialEq, Eq, Default)]
+pub enum Density {
+    Compact,
+    #[default]
+    Comfortable,
+}
+
+impl Density {
+    pub fn from_key(key: &str) -> Density {
+        match key {
+            "compact" => Density::Compact,
+            _ => Density::Comfortable,
+        }
+    }
+
+    pub fn key(self) -> &'static str {
+        match self {
+            Density::Compact => "compact",
+            Density::Comfortable => "comfortable",
+        }
+    }
+
+    pub fn toggled(self) -> Density {
+        match self {
+            Density::Compact => Density::Comfortable,
+            Density::Comfortable => Density::Compact,
+        }
+    }
+
+    pub fn is_compact(self) -> bool {
+        self == Density::Compact
+    }
+
+    /// Inner padding for content blocks.
+    pub fn padding(self) -> Padding {
+        match self {
+            Density::Compact => Padding::zero(),
+            Density::Comfortable => Padding::new(2, 2, 1, 0),
+        }
+    }
+
+    /// Blank spacer lines between groups (0 or 1).
+    pub fn spacer(self) -> usize {
+        if self.is_compact() { 0 } else { 1 }
+    }
+
+    /// Show one-line descriptions under menu items.
+    pub fn menu_help(self) -> bool {
+        !self.is_compact()
+    }
+}
+
+/// Stored as `ui_density` in chi.tmp.json.
+pub fn load_density() -> Density {
+    store::read()
+        .ok()
+        .and_then(|v| v.get("ui_density").and_then(|x| x.as_str()).map(Density::from_key))
+        .unwrap_or_default()
+}
+
+pub fn save_density(d: Density) -> Result<()> {
+    let mut root = store::read_or_empty();
+    let before = root.clone();
+    if let Some(obj) = root.as_object_mut() { obj.insert("ui_density".to_string(), Value::String(d.key().to_string())); }
+    let path = store::write(&root)?;
+    let _ = crate::audit::record("settings.ui_density", &path, &before, &root);
+    Ok(())
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+
+    #[test]
+    fn keys_round_trip_and_unknown_keys_are_comfortable() {
+        for d in [Density::Compact, Density::Comfortable] {
+            assert_eq!(Density::from_key(d.key()), d);
+            assert_eq!(d.toggled().toggled(), d);
+            assert_ne!(d.toggled(), d);
+        }
+        assert_eq!(Density::from_key("cozy"), Density::Comfortable);
+    }
+
+    #[test]
+    fn compact_drops_padding_spacers_and_help() {
+        let (compact, comfortable) = (Density::Compact, Density::Comfortable);
+        assert_eq!((compact.padding(), compact.spacer(), compact.menu_help()), (Padding::zero(), 0, false));
+        assert_eq!((comfortable.spacer(), comfortable.menu_help()), (1, true));
+        assert_ne!(comfortable.padding(), Padding::zero());
+    }
+
+    #[cfg(unix)]
+    #[test]
+    fn the_setting_is_kept_in_the_store() {
+        let _fake = crate::testing::FakeCli::new();
+        assert_eq!(load_density(), Density::Comfortable);
+        save_density(Density::Compact).expect("save");
+        assert_eq!(load_density(), Density::Compact);
+        assert_eq!(store::read().expect("store")["ui_density"], "compact");
+    }
+}
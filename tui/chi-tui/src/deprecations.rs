@@ -0,0 +1,60 @@
+//! Deprecated and retired hosted models, from the `deprecated_models` list
+//! in `chi-llm providers schema` (`chi-llm providers deprecations --refresh`
+//! downloads the current one). Configure marks providers that use one and
+//! says which model the vendor recommends instead.
+
+use serde_json::Value;
+
+#[derive(Clone, Debug, PartialEq)]
+pub struct Deprecation {
+    pub ptype: String,
+    pub id: String,
+    /// Retirement date (YYYY-MM-DD) when announced
+    pub retires: Option<String>,
+    pub replacement: Option<String>,
+    /// Past its retirement date: requests fail, not just warn
+    pub retired: bool,
+}
+
+impl Deprecation {
+    pub fn label(&self) -> &'static str {
+        if self.retired { "retired" } else { "deprecated" }
+    }
+
+    /// "gpt-4-32k was retired on 2025-06-06; use gpt-4o instead"
+    pub fn describe(&self) -> String {
+        let when = match (&self.retires, self.retired) {
+            (Some(d), true) => format!("was retired on {}", d),
+            (Some(d), false) => format!("is deprecated and retires on {}", d),
+            (None, _) => format!("is {}", self.label()),
+        };
+        match &self.replacement {
+            Some(r) => format!("{} {}; use {} instead", self.id, when, r),
+            None => format!("{} {}", self.id, when),
+        }
+    }
+}
+
+/// The `deprecated_models` array of the schema output; malformed items are skipped.
+pub fn parse(schema: &Value) -> Vec<Deprecation> {
+    let Some(arr) = schema.get("deprecated_models").and_then(|v| v.as_array()) else { return Vec::new() };
+    let text = |m: &Value, key: &str| m.get(key).and_then(|v| v.as_str()).map(str::trim).filter(|s| !s.is_empty()).map(str::to_string);
+    arr.iter()
+        .filter_map(|m| {
+            Some(Deprecation {
+                ptype: text(m, "type")?,
+                id: text(m, "id")?,
+                retires: text(m, "retires"),
+                replacement: text(m, "replacement"),
+                retired: m.get("status").and_then(|v| v.as_str()) == Some("retired"),
+            })
+        })
+        .collect()
+}
+
+/// The entry for `model` of a `ptype` provider; model ids compare case-insensitively.
+pub fn find<'a>(list: &'a [Deprecation], ptype: &str, model: &str) -> Option<&'a Deprecation> {
+    let model = model.trim();
+    if model.is_empty() { return None; }
+    list.iter().find(|d| d.ptype == ptype && d.id.eq_ignore_ascii_case(model))
+}
@@ -1,15 +1,20 @@
+use std::path::{Path, PathBuf};
+use std::process::{Command, Stdio};
 use std::time::Duration;
 
 use anyhow::Result;
 use ratatui::layout::Rect;
-use ratatui::style::{Color, Modifier, Style};
+use ratatui::style::{Modifier, Style};
 use ratatui::text::{Line, Span};
 use ratatui::widgets::{Block, Borders, Paragraph, Wrap};
 use ratatui::prelude::Frame;
 use serde_json::Value;
 
 use crate::app::App;
-use crate::theme::Theme;
+use crate::hf;
+use crate::latency::measure_latencies;
+use crate::locale;
+use crate::theme::StatusKind;
 use crate::util::run_cli_json;
 
 #[derive(Clone, Debug)]
@@ -18,6 +23,132 @@ pub struct DiagState {
     pub diagnostics: Value,
     pub model_explain: Value,
     pub saved_path: Option<String>,
+    pub fetched_at: chrono::DateTime<chrono::Utc>,
+    pub install: Install,
+}
+
+/// How chi-llm is installed: the CLI found on PATH, its version and the
+/// Python it runs in. Mismatches explain most "works in my shell" reports.
+#[derive(Clone, Debug, Default)]
+pub struct Install {
+    /// `chi-llm --version`
+    pub version: Option<String>,
+    /// Every chi-llm on PATH, in PATH order; the first one runs
+    pub on_path: Vec<PathBuf>,
+    /// `#!` line of the one that runs (pip-installed scripts name their Python)
+    pub interpreter: Option<String>,
+    pub warnings: Vec<String>,
+}
+
+impl Install {
+    pub fn to_json(&self) -> Value {
+        serde_json::json!({
+            "version": self.version,
+            "on_path": self.on_path.iter().map(|p| p.display().to_string()).collect::<Vec<_>>(),
+            "interpreter": self.interpreter,
+            "warnings": self.warnings,
+        })
+    }
+}
+
+/// Executables named `name` in PATH order, each resolved file listed once.
+fn find_on_path(name: &str) -> Vec<PathBuf> {
+    let names: Vec<String> = if cfg!(windows) {
+        ["exe", "cmd", "bat"].iter().map(|ext| format!("{}.{}", name, ext)).collect()
+    } else {
+        vec![name.to_string()]
+    };
+    let mut found: Vec<PathBuf> = Vec::new();
+    let mut seen: Vec<PathBuf> = Vec::new();
+    for dir in std::env::split_paths(&std::env::var_os("PATH").unwrap_or_default()) {
+        for n in &names {
+            let p = dir.join(n);
+            if !is_executable(&p) { continue; }
+            let real = p.canonicalize().unwrap_or_else(|_| p.clone());
+            if seen.contains(&real) { continue; }
+            seen.push(real);
+            found.push(p);
+        }
+    }
+    found
+}
+
+#[cfg(unix)]
+fn is_executable(p: &Path) -> bool {
+    use std::os::unix::fs::PermissionsExt;
+    p.metadata().map_or(false, |m| m.is_file() && m.permissions().mode() & 0o111 != 0)
+}
+
+#[cfg(not(unix))]
+fn is_executable(p: &Path) -> bool {
+    p.is_file()
+}
+
+fn shebang(p: &Path) -> Option<String> {
+    use std::io::Read;
+    let mut head = Vec::new();
+    std::fs::File::open(p).ok()?.take(512).read_to_end(&mut head).ok()?;
+    let line = head.split(|b| *b == b'\n').next()?;
+    let line = String::from_utf8_lossy(line.strip_prefix(b"#!")?).trim().to_string();
+    (!line.is_empty()).then_some(line)
+}
+
+/// First line `chi-llm --version` prints (argparse used stderr before 3.4).
+fn cli_version(timeout: Duration) -> Option<String> {
+    use std::io::Read;
+    use wait_timeout::ChildExt;
+    let mut child = Command::new("chi-llm").arg("--version").stdout(Stdio::piped()).stderr(Stdio::piped()).spawn().ok()?;
+    if child.wait_timeout(timeout).ok()?.is_none() {
+        let _ = child.kill();
+        return None;
+    }
+    let mut out = String::new();
+    child.stdout.take()?.read_to_string(&mut out).ok()?;
+    if out.trim().is_empty() { child.stderr.take()?.read_to_string(&mut out).ok()?; }
+    out.lines().map(str::trim).find(|l| !l.is_empty()).map(str::to_string)
+}
+
+fn same_dir(a: &str, b: &str) -> bool {
+    let canon = |s: &str| Path::new(s).canonicalize().unwrap_or_else(|_| PathBuf::from(s));
+    canon(a) == canon(b)
+}
+
+/// Look at the install around the `diagnostics --json` report (which
+/// carries the Python executable, venv and package version).
+pub fn check_install(diag: &Value, timeout: Duration) -> Install {
+    if crate::mock::enabled() { return Install { version: Some(crate::mock::VERSION.to_string()), ..Default::default() }; }
+    let on_path = find_on_path("chi-llm");
+    let mut install = Install {
+        version: cli_version(timeout),
+        interpreter: on_path.first().and_then(|p| shebang(p)),
+        on_path,
+        warnings: Vec::new(),
+    };
+    match install.on_path.as_slice() {
+        [] => install.warnings.push("Warning: chi-llm is not on PATH".to_string()),
+        [_] => {}
+        [used, rest @ ..] => install.warnings.push(format!(
+            "Warning: {} chi-llm installs on PATH; {} runs, {} {} shadowed",
+            rest.len() + 1,
+            used.display(),
+            rest.iter().map(|p| p.display().to_string()).collect::<Vec<_>>().join(", "),
+            if rest.len() == 1 { "is" } else { "are" }
+        )),
+    }
+    let python = diag.get("python");
+    let prefix = python.and_then(|p| p.get("prefix")).and_then(|v| v.as_str());
+    if let (Ok(active), Some(prefix)) = (std::env::var("VIRTUAL_ENV"), prefix) {
+        if !active.is_empty() && !same_dir(&active, prefix) {
+            install.warnings.push(format!("Warning: VIRTUAL_ENV is {} but chi-llm runs in {}", active, prefix));
+        }
+    }
+    let package = diag.get("chi_llm").and_then(|c| c.get("version")).and_then(|v| v.as_str());
+    if let (Some(cli), Some(pkg)) = (&install.version, package) {
+        if !cli.contains(pkg) {
+            install.warnings.push(format!("Warning: chi-llm --version says \"{}\" but the Python package is {}", cli, pkg));
+        }
+    }
+    install
 }
 
 pub fn fetch_diagnostics(timeout: Duration) -> Result<DiagState> {
@@ -41,21 +172,40 @@ pub fn fetch_diagnostics(timeout: Duration) -> Result<DiagState> {
         summary.push(format!("recommended_model: {}", rec));
     }
     if let Some(ram) = explain.get("available_ram_gb").and_then(|v| v.as_f64()) {
-        summary.push(format!("available_ram_gb: {:.1}", ram));
+        summary.push(format!("available RAM: {} GB", locale::decimal(ram, 1)));
+    }
+    summary.push(format!("huggingface token: {}", hf::check_token(timeout).label()));
+    let install = check_install(&diag, timeout);
+    let exe = install.on_path.first().map(|p| p.display().to_string()).unwrap_or_else(|| "not on PATH".to_string());
+    summary.push(format!("chi-llm: {} ({})", install.version.as_deref().unwrap_or("version unknown"), exe));
+    if let Some(py) = diag.get("python").and_then(|p| p.get("executable")).and_then(|v| v.as_str()) {
+        let env = match diag["python"].get("venv").and_then(|v| v.as_str()) {
+            Some(venv) => format!("venv {}", venv),
+            None => "no venv".to_string(),
+        };
+        summary.push(format!("python interpreter: {} ({})", py, env));
     }
+    summary.extend(install.warnings.iter().cloned());
     Ok(DiagState {
         summary,
         diagnostics: diag,
         model_explain: explain,
         saved_path: None,
+        fetched_at: chrono::Utc::now(),
+        install,
     })
 }
 
+/// Writes the CLI diagnostics plus a fresh connection check of every
+/// network provider (`error_code` per provider, see `health::ErrorCode`).
 pub fn export_diagnostics(d: &DiagState) -> Result<String> {
+    let connections: Vec<Value> = measure_latencies().iter().map(|r| r.status.to_json()).collect();
     let obj = serde_json::json!({
         "timestamp": chrono::Utc::now().to_rfc3339(),
         "diagnostics": d.diagnostics,
         "model_explain": d.model_explain,
+        "install": d.install.to_json(),
+        "connections": connections,
     });
     let path = "chi_llm_diagnostics.json".to_string();
     std::fs::write(&path, serde_json::to_vec_pretty(&obj)?)?;
@@ -66,8 +216,8 @@ pub fn draw_diagnostics(f: &mut Frame, area: Rect, app: &App) {
     let mut lines: Vec<Line> = Vec::new();
     if let Some(err) = &app.last_error {
         lines.push(Line::from(Span::styled(
-            err.clone(),
-            Style::default().fg(Color::Red),
+            format!("{} {}", StatusKind::Err.symbol(), err),
+            app.theme.status_style(StatusKind::Err),
         )));
     }
     if let Some(diag) = &app.diag {
@@ -78,8 +228,13 @@ pub fn draw_diagnostics(f: &mut Frame, area: Rect, app: &App) {
                 .add_modifier(Modifier::BOLD),
         )));
         for s in &diag.summary {
-            lines.push(Line::from(s.as_str()));
+            let (text, style) = app.theme.status_text(s);
+            lines.push(Line::from(Span::styled(text, style)));
         }
+        lines.push(Line::from(Span::styled(
+            format!("fetched {} (r: refresh)", locale::ago(diag.fetched_at)),
+            Style::default().fg(app.theme.secondary),
+        )));
         if let Some(path) = &diag.saved_path {
             lines.push(Line::from(Span::styled(
                 format!("Exported: {}", path),
@@ -102,3 +257,48 @@ pub fn draw_diagnostics(f: &mut Frame, area: Rect, app: &App) {
     f.render_widget(p, area);
 }
 
+#[cfg(all(test, unix))]
+mod tests {
+    use serde_json::json;
+
+    use super::*;
+    use crate::testing::FakeCli;
+
+    #[test]
+    fn diagnostics_summarise_cli_output() {
+        let _fake = FakeCli::new();
+        let d = fetch_diagnostics(Duration::from_secs(5)).expect("diagnostics");
+        assert!(d.summary.contains(&"python: 3.11.9".to_string()), "{:?}", d.summary);
+        assert!(d.summary.contains(&"current_model: qwen3-1.7b".to_string()));
+        // No token: reported without asking the Hub
+        assert!(d.summary.iter().any(|l| l.starts_with("huggingface token: not set")), "{:?}", d.summary);
+    }
+
+    #[test]
+    fn diagnostics_flag_install_mismatches() {
+        let mut fake = FakeCli::new();
+        fake.respond("diagnostics", json!({
+            "python": {"version": "3.11.9", "executable": "/opt/venv/bin/python", "prefix": "/opt/venv", "venv": "/opt/venv"},
+            "chi_llm": {"version": "2.1.0"},
+        }));
+        let d = fetch_diagnostics(Duration::from_secs(5)).expect("diagnostics");
+        assert!(d.summary.contains(&"python interpreter: /opt/venv/bin/python (venv /opt/venv)".to_string()), "{:?}", d.summary);
+        assert!(d.summary.iter().any(|l| l.starts_with("chi-llm: chi-llm 0.0.0 (fake) (")), "{:?}", d.summary);
+        assert_eq!(d.install.on_path.first(), Some(&fake.root.join("bin").join("chi-llm")));
+        assert_eq!(d.install.interpreter.as_deref(), Some("/bin/sh"));
+        // The fake's version is not the package's
+        assert!(d.summary.iter().any(|l| l.starts_with("Warning: chi-llm --version says")), "{:?}", d.summary);
+
+        // A second install further down PATH and another venv active in the shell
+        let other = fake.root.join("other-bin");
+        std::fs::create_dir_all(&other).expect("mkdir");
+        std::fs::copy(fake.root.join("bin").join("chi-llm"), other.join("chi-llm")).expect("copy fake");
+        let path = format!("{}:{}", std::env::var("PATH").unwrap_or_default(), other.display());
+        fake.set_env("PATH", path);
+        fake.set_env("VIRTUAL_ENV", fake.root.join("venv").display().to_string());
+        let d = fetch_diagnostics(Duration::from_secs(5)).expect("diagnostics");
+        assert!(d.install.on_path.contains(&other.join("chi-llm")));
+        assert!(d.install.warnings.iter().any(|w| w.contains("chi-llm installs on PATH;") && w.contains("shadowed")), "{:?}", d.install.warnings);
+        assert!(d.install.warnings.iter().any(|w| w.contains("but chi-llm runs in /opt/venv")), "{:?}", d.install.warnings);
+    }
+}
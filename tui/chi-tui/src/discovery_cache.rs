@@ -0,0 +1,185 @@
+//! Discovery results kept for a while: the provider schema, the model
+//! catalog, `discover-models` listings and Ollama's installed models. They
+//! live in memory and in `~/.cache/chi_llm/discovery/`, so opening the
+//! Model Browser or a model dropdown does not start chi-llm and wait on
+//! the network every time. F5 in the Model Browser forgets them all;
+//! `CHI_TUI_CACHE=off` turns the cache off.
+
+use std::collections::HashMap;
+use std::fs;
+use std::path::PathBuf;
+use std::sync::Mutex;
+use std::time::{Duration, SystemTime, UNIX_EPOCH};
+
+use anyhow::Result;
+use serde_json::{json, Value};
+use sha2::{Digest, Sha256};
+
+use crate::downloads::model_dir;
+use crate::util::run_cli_json;
+
+/// Ollama's installed models change on `ollama pull`/`rm` only.
+const OLLAMA_TTL: Duration = Duration::from_secs(120);
+
+/// How long Ollama's installed models stay fresh: the settings' cache
+/// lifetime (`prefs`) if set.
+pub fn ollama_ttl() -> Duration {
+    crate::prefs::load().cache_ttl.unwrap_or(OLLAMA_TTL)
+}
+
+/// The model catalog call, `forget`-able after downloads and saves.
+pub const MODELS_LIST: &[&str] = &["models", "list", "--json"];
+
+/// key -> (fetched, unix seconds; value)
+static MEMORY: Mutex<Option<HashMap<String, (u64, Value)>>> = Mutex::new(None);
+
+fn disabled() -> bool {
+    std::env::var("CHI_TUI_CACHE").map_or(false, |v| matches!(v.trim().to_lowercase().as_str(), "off" | "0" | "false"))
+}
+
+/// How long a CLI result stays fresh; `None` for calls that are not cached.
+/// The settings' cache lifetime replaces the catalog and listing ones.
+fn cli_ttl(args: &[&str]) -> Option<Duration> {
+    let listing = |own: u64| Some(crate::prefs::load().cache_ttl.unwrap_or(Duration::from_secs(own)));
+    match args {
+        ["providers", "schema", ..] => Some(Duration::from_secs(24 * 3600)),
+        // Also says which models are downloaded and current: saves and
+        // downloads `forget` it
+        ["models", "list", ..] => listing(600),
+        ["providers", "discover-models", ..] => listing(300),
+        _ => None,
+    }
+}
+
+fn dir() -> Result<PathBuf> {
+    Ok(model_dir()?.join("discovery"))
+}
+
+fn now() -> u64 {
+    SystemTime::now().duration_since(UNIX_EPOCH).map_or(0, |d| d.as_secs())
+}
+
+/// The `chi-llm` on PATH and when it was installed, so an upgrade (new
+/// schema, new catalog) misses the cache.
+fn cli_identity() -> String {
+    let path = std::env::var_os("PATH").unwrap_or_default();
+    std::env::split_paths(&path)
+        .map(|d| d.join("chi-llm"))
+        .find_map(|p| {
+            let modified = fs::metadata(&p).and_then(|m| m.modified()).ok()?;
+            Some(format!("{}@{:?}", p.display(), modified.duration_since(UNIX_EPOCH).ok()?))
+        })
+        .unwrap_or_default()
+}
+
+/// File name of an entry. Parts may hold API keys, so only their hash is
+/// written; the working directory and chi-llm install are part of it.
+fn key(parts: &[&str]) -> String {
+    let mut h = Sha256::new();
+    for p in parts { h.update(p.as_bytes()); h.update([0]); }
+    h.update(std::env::current_dir().map(|d| d.display().to_string()).unwrap_or_default().as_bytes());
+    h.update([0]);
+    h.update(cli_identity().as_bytes());
+    h.finalize().iter().map(|b| format!("{:02x}", b)).collect()
+}
+
+fn lookup(key: &str, ttl: Duration) -> Option<Value> {
+    let fresh = |fetched: u64| now().saturating_sub(fetched) < ttl.as_secs();
+    let mut memory = MEMORY.lock().unwrap_or_else(|e| e.into_inner());
+    let memory = memory.get_or_insert_with(HashMap::new);
+    if let Some((fetched, v)) = memory.get(key) {
+        if fresh(*fetched) { return Some(v.clone()); }
+    }
+    let text = fs::read_to_string(dir().ok()?.join(format!("{}.json", key))).ok()?;
+    let stored: Value = serde_json::from_str(&text).ok()?;
+    let fetched = stored.get("fetched").and_then(|v| v.as_u64()).filter(|f| fresh(*f))?;
+    let value = stored.get("value")?.clone();
+    memory.insert(key.to_string(), (fetched, value.clone()));
+    Some(value)
+}
+
+fn store(key: &str, value: &Value) {
+    let fetched = now();
+    MEMORY.lock().unwrap_or_else(|e| e.into_inner()).get_or_insert_with(HashMap::new).insert(key.to_string(), (fetched, value.clone()));
+    let Ok(dir) = dir() else { return };
+    if fs::create_dir_all(&dir).is_ok() {
+        let _ = fs::write(dir.join(format!("{}.json", key)), json!({"fetched": fetched, "value": value}).to_string());
+    }
+}
+
+/// The cached value for `parts` while younger than `ttl`, else `fetch`'s
+/// (stored when it succeeds).
+pub fn get_or(parts: &[&str], ttl: Duration, fetch: impl FnOnce() -> Result<Value>) -> Result<Value> {
+    if disabled() { return fetch(); }
+    let key = key(parts);
+    if let Some(v) = lookup(&key, ttl) { return Ok(v); }
+    let v = fetch()?;
+    store(&key, &v);
+    Ok(v)
+}
+
+/// The last stored value for `parts` however old, e.g. to show while a
+/// refresh runs.
+pub fn peek(parts: &[&str]) -> Option<Value> {
+    if disabled() { return None; }
+    lookup(&key(parts), Duration::MAX)
+}
+
+/// `run_cli_json` for discovery calls, answered from the cache while fresh.
+pub fn cli(args: &[&str], timeout: Duration) -> Result<Value> {
+    match cli_ttl(args) {
+        Some(ttl) => get_or(args, ttl, || run_cli_json(args, timeout)),
+        None => run_cli_json(args, timeout),
+    }
+}
+
+/// Run a discovery call now (Test connection must reach the server) and
+/// keep the answer for `cli`.
+pub fn refresh_cli(args: &[&str], timeout: Duration) -> Result<Value> {
+    let v = run_cli_json(args, timeout)?;
+    if cli_ttl(args).is_some() && !disabled() { store(&key(args), &v); }
+    Ok(v)
+}
+
+/// Drop one entry, e.g. the model catalog after a download or a save.
+pub fn forget(parts: &[&str]) {
+    let key = key(parts);
+    if let Some(memory) = MEMORY.lock().unwrap_or_else(|e| e.into_inner()).as_mut() { memory.remove(&key); }
+    if let Ok(dir) = dir() { let _ = fs::remove_file(dir.join(format!("{}.json", key))); }
+}
+
+/// Forget everything (F5).
+pub fn clear() {
+    *MEMORY.lock().unwrap_or_else(|e| e.into_inner()) = None;
+    if let Ok(dir) = dir() { let _ = fs::remove_dir_all(dir); }
+}
+
+#[cfg(all(test, unix))]
+mod tests {
+    use super::*;
+    use crate::app::{App, Page};
+    use crate::models::fetch_models;
+    use crate::testing::FakeCli;
+
+    #[test]
+    fn discovery_results_are_cached_until_refreshed() {
+        use crossterm::event::{KeyCode, KeyEvent, KeyModifiers};
+        let mut fake = FakeCli::new();
+        fake.set_env("CHI_TUI_CACHE", "on".to_string());
+        let catalog_calls = |fake: &FakeCli| fake.calls().iter().filter(|c| *c == "models list --json").count();
+
+        fetch_models(Duration::from_secs(5)).expect("catalog");
+        fetch_models(Duration::from_secs(5)).expect("cached catalog");
+        assert_eq!(catalog_calls(&fake), 1);
+        assert!(fake.exists(".cache/chi_llm/discovery"));
+        // A finished download or a save may change what the catalog says
+        forget(MODELS_LIST);
+        let mut app = App::new(false);
+        crate::open_page_loaded(&mut app, Page::ModelBrowser);
+        assert_eq!(catalog_calls(&fake), 2);
+
+        crate::keys::handle_key(&mut app, KeyEvent::new(KeyCode::F(5), KeyModifiers::NONE));
+        assert_eq!(catalog_calls(&fake), 3);
+        assert_eq!(app.toast.as_ref().map(|t| t.text.as_str()), Some("Refreshed: 2 models"));
+    }
+}
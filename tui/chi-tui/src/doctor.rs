@@ -0,0 +1,178 @@
+//! `chi-tui --doctor`: what the Diagnostics page collects plus the Status
+//! page's check of every configured provider, printed for scripts and bug
+//! reports. Exits 1 when the default provider in effect is unreachable.
+
+use std::sync::mpsc::channel;
+use std::thread;
+use std::time::Duration;
+
+use serde_json::{json, Value};
+
+use crate::diagnostics::{fetch_diagnostics, DiagState};
+use crate::portforward::PortForwards;
+use crate::providers::{read_scratch_entries, ProviderScratchEntry};
+use crate::rules::{resolve_from_store, Resolution};
+use crate::status::{check, StatusRow};
+use crate::text;
+
+pub struct Report {
+    pub diagnostics: Result<DiagState, String>,
+    pub default: Result<Resolution, String>,
+    /// In chi.tmp.json order
+    pub providers: Vec<StatusRow>,
+}
+
+impl Report {
+    fn default_row(&self) -> Option<&StatusRow> {
+        let id = &self.default.as_ref().ok()?.provider_id;
+        self.providers.iter().find(|r| &r.id == id)
+    }
+
+    /// The default provider resolved and answered its connection test.
+    pub fn ok(&self) -> bool {
+        self.default_row().map_or(false, |r| r.reachable == Some(true))
+    }
+
+    pub fn to_json(&self) -> Value {
+        let default = match &self.default {
+            Ok(r) => json!({"provider_id": r.provider_id, "reason": r.reason, "configured": self.default_row().is_some()}),
+            Err(e) => json!({"error": e}),
+        };
+        let diagnostics = match &self.diagnostics {
+            Ok(d) => json!({"summary": d.summary, "diagnostics": d.diagnostics, "model_explain": d.model_explain, "install": d.install.to_json()}),
+            Err(e) => json!({"error": e}),
+        };
+        let providers: Vec<Value> = self
+            .providers
+            .iter()
+            .map(|r| {
+                json!({
+                    "id": r.id,
+                    "name": r.name,
+                    "type": r.ptype,
+                    "reachable": r.reachable == Some(true),
+                    "latency_ms": r.latency.map(|d| d.as_millis() as u64),
+                    "models": r.models,
+                    "detail": r.detail,
+                })
+            })
+            .collect();
+        json!({"ok": self.ok(), "default": default, "diagnostics": diagnostics, "providers": providers})
+    }
+
+    pub fn render_text(&self) -> String {
+        let mut out = vec!["chi-tui doctor".to_string(), String::new(), "Diagnostics".to_string()];
+        match &self.diagnostics {
+            Ok(d) => out.extend(d.summary.iter().map(|s| format!("  {}", s))),
+            Err(e) => out.push(format!("  Error: {}", e)),
+        }
+        out.push(String::new());
+        out.push("Default provider".to_string());
+        match (&self.default, self.default_row()) {
+            (Ok(r), Some(_)) => out.push(format!("  {} ({})", r.provider_id, r.reason)),
+            (Ok(r), None) => out.push(format!("  Error: {} is not configured ({})", r.provider_id, r.reason)),
+            (Err(e), _) => out.push(format!("  Error: {}", e)),
+        }
+        out.push(String::new());
+        out.push(format!("Providers ({})", self.providers.len()));
+        if self.providers.is_empty() { out.push("  none configured".to_string()); }
+        let default_id = self.default.as_ref().ok().map(|r| r.provider_id.as_str());
+        let id_w = self.providers.iter().map(|r| text::width(&r.id)).max().unwrap_or(0).min(24);
+        for r in &self.providers {
+            let mark = if Some(r.id.as_str()) == default_id { "*" } else { " " };
+            let state = if r.reachable == Some(true) { "ok  " } else { "FAIL" };
+            let latency = r.latency.map(|d| format!(" {} ms", d.as_millis())).unwrap_or_default();
+            out.push(format!("{} {} {} {}{}  {}", mark, state, text::fit(&r.id, id_w), r.ptype, latency, r.detail));
+        }
+        out.push(String::new());
+        out.push(if self.ok() { "Result: default provider reachable".to_string() } else { "Result: default provider unreachable".to_string() });
+        out.join("\n")
+    }
+}
+
+/// Test every provider in parallel, as the Status page does.
+fn check_all() -> Vec<StatusRow> {
+    let entries = read_scratch_entries().unwrap_or_default();
+    // Killed on drop, once every check has finished
+    let mut portfw = PortForwards::default();
+    let (tx, rx) = channel();
+    let mut pending = 0;
+    let mut rows: Vec<Option<StatusRow>> = vec![None; entries.len()];
+    for (i, e) in entries.iter().enumerate() {
+        if let Err(err) = portfw.ensure(e) {
+            rows[i] = Some(failed_row(e, format!("port-forward: {}", err)));
+            continue;
+        }
+        pending += 1;
+        let (tx, e) = (tx.clone(), e.clone());
+        thread::spawn(move || {
+            let _ = tx.send((i, check(&e)));
+        });
+    }
+    for (i, row) in rx.iter().take(pending) {
+        rows[i] = Some(row);
+    }
+    rows.into_iter().flatten().collect()
+}
+
+fn failed_row(e: &ProviderScratchEntry, detail: String) -> StatusRow {
+    StatusRow {
+        id: e.id.clone(),
+        name: e.name.clone(),
+        ptype: e.ptype.clone(),
+        reachable: Some(false),
+        latency: None,
+        models: None,
+        model_ids: Vec::new(),
+        detail,
+        checked_at: Some(chrono::Utc::now()),
+        checking: false,
+    }
+}
+
+pub fn collect(timeout: Duration) -> Report {
+    Report {
+        diagnostics: fetch_diagnostics(timeout).map_err(|e| e.to_string()),
+        default: resolve_from_store().map_err(|e| e.to_string()),
+        providers: check_all(),
+    }
+}
+
+/// `chi-tui --doctor [--json]`: exit 0 when the default provider answers,
+/// 1 otherwise. A failing diagnostics call is reported but does not fail.
+pub fn run_doctor(json: bool) -> ! {
+    let report = collect(Duration::from_secs(5));
+    if json {
+        println!("{}", serde_json::to_string_pretty(&report.to_json()).unwrap_or_default());
+    } else {
+        println!("{}", report.render_text());
+    }
+    std::process::exit(if report.ok() { 0 } else { 1 })
+}
+
+#[cfg(all(test, unix))]
+mod tests {
+    use super::*;
+    use crate::config_cli::{run_config, ConfigCmd};
+    use crate::testing::{add_provider, FakeCli};
+
+    #[test]
+    fn doctor_fails_when_the_default_is_unreachable() {
+        let _fake = FakeCli::new();
+        run_config(add_provider("local", "offline", &[], true)).expect("add provider");
+        run_config(add_provider("ollama", "gone", &[("host", "127.0.0.1"), ("port", "1")], false)).expect("add provider");
+        let report = collect(Duration::from_secs(5));
+        assert!(report.ok(), "{}", report.render_text());
+        let v = report.to_json();
+        assert_eq!(v["default"]["provider_id"], "offline");
+        assert_eq!(v["providers"].as_array().map(|a| a.len()), Some(2));
+        assert_eq!(v["providers"][1]["reachable"], false);
+        assert!(v["diagnostics"]["summary"].as_array().map_or(false, |s| s.iter().any(|l| l == "python: 3.11.9")), "{}", v);
+
+        run_config(ConfigCmd::SetDefault { id: "gone".to_string(), json: false }).expect("set default");
+        let report = collect(Duration::from_secs(5));
+        assert!(!report.ok());
+        let text = report.render_text();
+        assert!(text.contains("* FAIL gone") && text.ends_with("Result: default provider unreachable"), "{}", text);
+    }
+}
@@ -0,0 +1,205 @@
+//! Unfinished model downloads, kept in `~/.cache/chi_llm/downloads.json` so
+//! the next start resumes them from their `.part` file instead of leaving
+//! gigabytes behind. The `.part` file's length is the resume offset; the
+//! journal keeps what cannot be read back from it: the sources, the
+//! expected size and checksum, and the validator (`ETag`/`Last-Modified`)
+//! that tells whether the server still has the same file.
+
+use std::fs;
+use std::path::PathBuf;
+use std::sync::Mutex;
+
+use anyhow::Result;
+use fs2::FileExt;
+use serde_json::{json, Map, Value};
+
+use crate::downloads::model_dir;
+
+/// Download threads update the journal concurrently.
+static LOCK: Mutex<()> = Mutex::new(());
+
+#[derive(Clone, Debug, Default, PartialEq)]
+pub struct Pending {
+    pub id: String,
+    pub filename: String,
+    pub urls: Vec<String>,
+    pub total: Option<u64>,
+    /// Lower-case hex SHA-256 from the catalog or the server's ETag
+    pub sha256: Option<String>,
+    /// `If-Range` value for resuming
+    pub validator: Option<String>,
+}
+
+impl Pending {
+    fn from_json(id: &str, v: &Value) -> Option<Pending> {
+        let text = |k: &str| v.get(k).and_then(|x| x.as_str()).map(str::to_string);
+        Some(Pending {
+            id: id.to_string(),
+            filename: text("filename")?,
+            urls: v.get("urls")?.as_array()?.iter().filter_map(|u| u.as_str().map(str::to_string)).collect(),
+            total: v.get("total").and_then(|x| x.as_u64()),
+            sha256: text("sha256"),
+            validator: text("validator"),
+        })
+    }
+
+    fn to_json(&self) -> Value {
+        json!({"filename": self.filename, "urls": self.urls, "total": self.total, "sha256": self.sha256, "validator": self.validator})
+    }
+
+    pub fn target(&self) -> Result<PathBuf> {
+        Ok(model_dir()?.join(&self.filename))
+    }
+
+    /// A `chi-tui fetch` (or another chi-tui) is writing the `.part` file.
+    pub fn in_use(&self) -> bool {
+        let Ok(part) = self.target().map(|t| part_path(&t)) else { return false };
+        fs::OpenOptions::new().write(true).open(part).map_or(false, |f| f.try_lock_exclusive().is_err())
+    }
+
+    /// Bytes already on disk.
+    pub fn offset(&self) -> u64 {
+        self.target().ok().and_then(|t| fs::metadata(part_path(&t)).ok()).map_or(0, |m| m.len())
+    }
+}
+
+pub fn part_path(target: &std::path::Path) -> PathBuf {
+    PathBuf::from(format!("{}.part", target.display()))
+}
+
+fn path() -> Result<PathBuf> {
+    Ok(model_dir()?.join("downloads.json"))
+}
+
+fn read_all() -> Map<String, Value> {
+    path().ok().and_then(|p| fs::read_to_string(p).ok()).and_then(|t| serde_json::from_str::<Value>(&t).ok()).and_then(|v| v.as_object().cloned()).unwrap_or_default()
+}
+
+fn write_all(all: &Map<String, Value>) -> Result<()> {
+    let path = path()?;
+    if all.is_empty() {
+        if path.exists() { fs::remove_file(path)?; }
+        return Ok(());
+    }
+    fs::create_dir_all(model_dir()?)?;
+    let tmp = path.with_extension("json.tmp");
+    fs::write(&tmp, serde_json::to_vec_pretty(&Value::Object(all.clone()))?)?;
+    fs::rename(tmp, path)?;
+    Ok(())
+}
+
+/// Downloads to resume: journal entries whose file is not in place yet.
+/// Entries for finished files are dropped.
+pub fn load() -> Vec<Pending> {
+    let _guard = LOCK.lock();
+    let all = read_all();
+    let out: Vec<Pending> = all
+        .iter()
+        .filter_map(|(id, v)| Pending::from_json(id, v))
+        .filter(|p| !p.urls.is_empty() && p.target().map_or(false, |t| !t.exists()))
+        .collect();
+    if out.len() != all.len() {
+        let kept = all.into_iter().filter(|(id, _)| out.iter().any(|p| &p.id == id)).collect();
+        let _ = write_all(&kept);
+    }
+    out
+}
+
+pub fn get(id: &str) -> Option<Pending> {
+    let _guard = LOCK.lock();
+    Pending::from_json(id, read_all().get(id)?)
+}
+
+/// The journal id of the download writing `filename` (for `chi-tui fetch`).
+pub fn id_of(filename: &str) -> Option<String> {
+    let _guard = LOCK.lock();
+    read_all().iter().find(|(id, v)| Pending::from_json(id, v).map_or(false, |p| p.filename == filename)).map(|(id, _)| id.clone())
+}
+
+/// Record a download as started. An existing entry keeps its size,
+/// checksum and validator so its `.part` file stays usable.
+pub fn record(p: &Pending) -> Result<()> {
+    let _guard = LOCK.lock();
+    let mut all = read_all();
+    let mut entry = p.clone();
+    if let Some(old) = all.get(&p.id).and_then(|v| Pending::from_json(&p.id, v)).filter(|o| o.filename == p.filename) {
+        entry.total = entry.total.or(old.total);
+        entry.sha256 = entry.sha256.or(old.sha256);
+        entry.validator = entry.validator.or(old.validator);
+    }
+    all.insert(p.id.clone(), entry.to_json());
+    write_all(&all)
+}
+
+/// What the server said about the file, once its response arrived.
+pub fn update(id: &str, total: Option<u64>, sha256: Option<String>, validator: Option<String>) -> Result<()> {
+    let _guard = LOCK.lock();
+    let mut all = read_all();
+    let Some(mut p) = all.get(id).and_then(|v| Pending::from_json(id, v)) else { return Ok(()) };
+    p.total = total.or(p.total);
+    p.sha256 = p.sha256.or(sha256);
+    p.validator = validator;
+    all.insert(id.to_string(), p.to_json());
+    write_all(&all)
+}
+
+/// Forget a download (finished or cancelled).
+pub fn remove(id: &str) {
+    let _guard = LOCK.lock();
+    let mut all = read_all();
+    if all.remove(id).is_some() { let _ = write_all(&all); }
+}
+
+#[cfg(all(test, unix))]
+mod tests {
+    use std::time::Duration;
+
+    use super::*;
+    use crate::downloads::{DownloadManager, DownloadState};
+    use crate::testing::{serve_file, FakeCli};
+
+    fn wait_for(dm: &mut DownloadManager, id: &str) -> DownloadState {
+        // Retries wait 2 s between attempts
+        for _ in 0..400 {
+            dm.poll();
+            let state = dm.status(id).map(|j| j.state.clone());
+            if let Some(state) = state.filter(|s| *s != DownloadState::Running) { return state; }
+            std::thread::sleep(Duration::from_millis(25));
+        }
+        panic!("download {} did not finish", id);
+    }
+
+    #[test]
+    fn unfinished_downloads_resume_from_their_part_file() {
+        let _fake = FakeCli::new();
+        const BODY: &[u8] = b"GGUF model bytes, long enough to be cut in half";
+        let (url, seen) = serve_file(BODY, "\"v1\"");
+        let dir = model_dir().expect("model dir");
+        std::fs::create_dir_all(&dir).expect("model dir");
+
+        // A previous run got the first 10 bytes, then chi-tui was closed
+        std::fs::write(dir.join("model.gguf.part"), &BODY[..10]).expect("write part");
+        let pending = Pending { id: "tiny".to_string(), filename: "model.gguf".to_string(), urls: vec![url.clone()], validator: Some("\"v1\"".to_string()), ..Default::default() };
+        record(&pending).expect("record");
+        assert_eq!(load(), vec![pending]);
+
+        let mut dm = DownloadManager::default();
+        assert_eq!(dm.resume_pending(), vec!["tiny".to_string()]);
+        assert_eq!(dm.status("tiny").map(|j| j.base), Some(10));
+        assert_eq!(wait_for(&mut dm, "tiny"), DownloadState::Done);
+        assert_eq!(std::fs::read(dir.join("model.gguf")).expect("model"), BODY);
+        assert!(seen.lock().expect("log")[0].to_lowercase().contains("range: bytes=10-"));
+        assert!(load().is_empty());
+
+        // A known checksum that does not match fails and drops the bad file
+        std::fs::remove_file(dir.join("model.gguf")).expect("remove");
+        dm.start("tiny", vec![url], "model.gguf", Some("0".repeat(64))).expect("start");
+        match wait_for(&mut dm, "tiny") {
+            DownloadState::Failed(e) => assert!(e.contains("checksum mismatch"), "{}", e),
+            other => panic!("{:?}", other),
+        }
+        assert!(!dir.join("model.gguf").exists() && !dir.join("model.gguf.part").exists());
+        // Failed downloads stay in the journal; cancelling forgets them
+        assert_eq!(load().len(), 1);
+    }
+}
@@ -0,0 +1,599 @@
+use std::collections::HashMap;
+use std::fs::{self, File, OpenOptions};
+use std::io::{Read, Seek, SeekFrom, Write};
+use std::path::{Path, PathBuf};
+use std::process::{Command, Stdio};
+use std::sync::atomic::{AtomicBool, Ordering};
+use std::sync::mpsc::{channel, Receiver, Sender};
+use std::sync::Arc;
+use std::thread;
+use std::time::{Duration, Instant};
+
+use anyhow::{anyhow, Result};
+use fs2::FileExt;
+use serde_json::Value;
+use sha2::{Digest, Sha256};
+
+use crate::download_journal::{self, part_path, Pending};
+use crate::hf;
+use crate::http;
+use crate::locale;
+use crate::log;
+
+/// Progress messages are throttled to this interval per download.
+const PROGRESS_EVERY: Duration = Duration::from_millis(250);
+
+/// Set on quit: stopped downloads keep their `.part` file to resume from.
+static KEEP_PARTIAL: AtomicBool = AtomicBool::new(false);
+
+/// chi_llm's model cache; a model counts as downloaded when its file exists here.
+pub fn model_dir() -> Result<PathBuf> {
+    let home = dirs::home_dir().ok_or_else(|| anyhow!("home dir not found"))?;
+    Ok(home.join(".cache").join("chi_llm"))
+}
+
+pub fn hf_url(repo: &str, filename: &str) -> String {
+    format!("{}/{}/resolve/main/{}", hf::HUB, repo, filename)
+}
+
+/// Which source last worked per model id, tried first next time.
+fn mirrors_path() -> Result<PathBuf> {
+    Ok(model_dir()?.join("download_mirrors.json"))
+}
+
+fn recorded_mirror(id: &str) -> Option<String> {
+    let v: Value = serde_json::from_str(&fs::read_to_string(mirrors_path().ok()?).ok()?).ok()?;
+    v.get(id).and_then(|u| u.as_str()).map(|s| s.to_string())
+}
+
+fn record_mirror(id: &str, url: &str) -> Result<()> {
+    let path = mirrors_path()?;
+    let mut v: Value = fs::read_to_string(&path).ok().and_then(|t| serde_json::from_str(&t).ok()).unwrap_or_else(|| serde_json::json!({}));
+    if !v.is_object() { v = serde_json::json!({}); }
+    v[id] = Value::String(url.to_string());
+    fs::write(path, serde_json::to_vec_pretty(&v)?)?;
+    Ok(())
+}
+
+/// Download URLs for a model: the Hub file, then the catalog's `mirrors`
+/// (`{"url": ...}` or `{"repo": ..., "filename": ...}`), with the one that
+/// worked last time moved to the front.
+pub fn sources(id: &str, repo: &str, filename: &str, raw: &Value) -> Vec<String> {
+    let mut urls = vec![hf_url(repo, filename)];
+    for m in raw.get("mirrors").and_then(|m| m.as_array()).into_iter().flatten() {
+        let text = |k: &str| m.get(k).and_then(|x| x.as_str()).map(str::trim).filter(|s| !s.is_empty());
+        let url = match (text("url"), text("repo")) {
+            (Some(url), _) => url.to_string(),
+            (None, Some(r)) => hf_url(r, text("filename").unwrap_or(filename)),
+            _ => continue,
+        };
+        if !urls.contains(&url) { urls.push(url); }
+    }
+    if let Some(pos) = recorded_mirror(id).and_then(|u| urls.iter().position(|x| *x == u)) {
+        let url = urls.remove(pos);
+        urls.insert(0, url);
+    }
+    urls
+}
+
+/// Messages sent from download threads to the UI loop.
+#[derive(Debug)]
+pub enum DownloadMsg {
+    Progress { id: String, done: u64, total: Option<u64> },
+    /// `url` failed; moving on to `next`
+    Retry { id: String, url: String, next: String, error: String },
+    Finished { id: String, path: PathBuf },
+    Failed { id: String, error: String },
+}
+
+#[derive(Clone, Debug, PartialEq, Eq)]
+pub enum DownloadState {
+    Running,
+    Done,
+    Failed(String),
+    Cancelled,
+}
+
+#[derive(Clone, Debug)]
+pub struct DownloadStatus {
+    pub id: String,
+    pub done: u64,
+    /// Bytes already on disk when this run started (resumed downloads)
+    pub base: u64,
+    pub total: Option<u64>,
+    pub started: Instant,
+    pub state: DownloadState,
+}
+
+impl DownloadStatus {
+    pub fn percent(&self) -> Option<f64> {
+        let total = self.total.filter(|t| *t > 0)?;
+        Some((self.done as f64 / total as f64 * 100.0).min(100.0))
+    }
+
+    /// Remaining time at the average rate so far.
+    pub fn eta(&self) -> Option<Duration> {
+        let total = self.total?;
+        let secs = self.started.elapsed().as_secs_f64();
+        let fetched = self.done.saturating_sub(self.base);
+        if fetched == 0 || secs < 1.0 { return None; }
+        let rate = fetched as f64 / secs;
+        Some(Duration::from_secs_f64(total.saturating_sub(self.done) as f64 / rate))
+    }
+
+    /// Short inline label, e.g. "↓ 42% ETA 1m05s".
+    pub fn label(&self) -> String {
+        match &self.state {
+            DownloadState::Running => {
+                let pct = self.percent().map(|p| format!("{:.0}%", p)).unwrap_or_else(|| locale::bytes(self.done));
+                match self.eta() {
+                    Some(eta) => format!("↓ {} ETA {}", pct, format_eta(eta)),
+                    None => format!("↓ {}", pct),
+                }
+            }
+            DownloadState::Done => "✓ downloaded".to_string(),
+            DownloadState::Failed(e) => format!("✗ {}", e),
+            DownloadState::Cancelled => "cancelled".to_string(),
+        }
+    }
+
+    /// Status for the control socket (`chi-llm models download`).
+    pub fn to_json(&self) -> Value {
+        let (state, error) = match &self.state {
+            DownloadState::Running => ("running", None),
+            DownloadState::Done => ("done", None),
+            DownloadState::Failed(e) => ("failed", Some(e.clone())),
+            DownloadState::Cancelled => ("cancelled", None),
+        };
+        serde_json::json!({
+            "id": self.id,
+            "state": state,
+            "error": error,
+            "done": self.done,
+            "total": self.total,
+            "percent": self.percent(),
+            "eta_s": self.eta().map(|d| d.as_secs()),
+        })
+    }
+}
+
+pub fn format_eta(d: Duration) -> String {
+    let s = d.as_secs();
+    if s >= 3600 { format!("{}h{:02}m", s / 3600, (s % 3600) / 60) } else { format!("{}m{:02}s", s / 60, s % 60) }
+}
+
+/// Background model downloads. Threads report over a channel; the UI loop
+/// calls `poll` each tick to fold messages into `jobs`.
+pub struct DownloadManager {
+    tx: Sender<DownloadMsg>,
+    rx: Receiver<DownloadMsg>,
+    jobs: HashMap<String, DownloadStatus>,
+    cancels: HashMap<String, Arc<AtomicBool>>,
+    /// (url, target) per download, for handing off on quit
+    sources: HashMap<String, (String, PathBuf)>,
+    finished: Vec<String>,
+}
+
+impl Default for DownloadManager {
+    fn default() -> Self {
+        let (tx, rx) = channel();
+        Self { tx, rx, jobs: HashMap::new(), cancels: HashMap::new(), sources: HashMap::new(), finished: Vec::new() }
+    }
+}
+
+impl DownloadManager {
+    /// Download `filename` from the first of `urls` that works (see
+    /// `sources`). The file keeps the catalog's name whichever source
+    /// served it, so chi_llm finds it. A `.part` file left by an earlier
+    /// run is resumed; `sha256`, when known, is checked at the end.
+    pub fn start(&mut self, id: &str, urls: Vec<String>, filename: &str, sha256: Option<String>) -> Result<()> {
+        if self.status(id).map_or(false, |s| s.state == DownloadState::Running) {
+            return Err(anyhow!("{} is already downloading", id));
+        }
+        let url = urls.first().cloned().ok_or_else(|| anyhow!("no download source for {}", id))?;
+        let dir = model_dir()?;
+        fs::create_dir_all(&dir)?;
+        let target = dir.join(filename);
+        let pending = Pending { id: id.to_string(), filename: filename.to_string(), urls: urls.clone(), sha256: sha256.map(|s| s.to_ascii_lowercase()), ..Default::default() };
+        if let Err(e) = download_journal::record(&pending) { log::warn(&format!("recording download {} failed: {}", id, e)); }
+        let base = pending.offset();
+        let total = download_journal::get(id).and_then(|p| p.total);
+        let cancel = Arc::new(AtomicBool::new(false));
+        self.cancels.insert(id.to_string(), cancel.clone());
+        self.sources.insert(id.to_string(), (url.clone(), target.clone()));
+        self.jobs.insert(id.to_string(), DownloadStatus { id: id.to_string(), done: base, base, total, started: Instant::now(), state: DownloadState::Running });
+        let tx = self.tx.clone();
+        let id = id.to_string();
+        if base > 0 { log::info(&format!("download {} resumed at {} bytes from {}", id, base, url)); } else { log::info(&format!("download {} started from {}", id, url)); }
+        thread::spawn(move || {
+            let msg = match fetch_any(&id, &urls, &target, &cancel, &tx) {
+                Ok(()) => DownloadMsg::Finished { id: id.clone(), path: target },
+                Err(e) => DownloadMsg::Failed { id: id.clone(), error: e.to_string() },
+            };
+            let _ = tx.send(msg);
+        });
+        Ok(())
+    }
+
+    pub fn cancel(&mut self, id: &str) {
+        if let Some(flag) = self.cancels.get(id) { flag.store(true, Ordering::Relaxed); }
+    }
+
+    pub fn cancel_all(&mut self) {
+        for flag in self.cancels.values() { flag.store(true, Ordering::Relaxed); }
+    }
+
+    /// Stop every download but keep what was fetched: the next start (or a
+    /// detached `chi-tui fetch`) resumes from there.
+    pub fn pause_all(&mut self) {
+        KEEP_PARTIAL.store(true, Ordering::Relaxed);
+        self.cancel_all();
+    }
+
+    /// Resume the downloads an earlier run left unfinished (see
+    /// `download_journal`). Returns the ids started.
+    pub fn resume_pending(&mut self) -> Vec<String> {
+        let mut started = Vec::new();
+        for p in download_journal::load().into_iter().filter(|p| !p.in_use()) {
+            match self.start(&p.id, p.urls.clone(), &p.filename, p.sha256.clone()) {
+                Ok(()) => started.push(p.id),
+                Err(e) => log::warn(&format!("resuming download {} failed: {}", p.id, e)),
+            }
+        }
+        started
+    }
+
+    /// Running downloads, oldest first.
+    pub fn running(&self) -> Vec<&DownloadStatus> {
+        let mut v: Vec<&DownloadStatus> = self.jobs.values().filter(|j| j.state == DownloadState::Running).collect();
+        v.sort_by_key(|j| j.started);
+        v
+    }
+
+    pub fn source(&self, id: &str) -> Option<(String, PathBuf)> {
+        self.sources.get(id).cloned()
+    }
+
+    /// Apply pending messages. Returns true when anything changed.
+    pub fn poll(&mut self) -> bool {
+        let mut changed = false;
+        while let Ok(msg) = self.rx.try_recv() {
+            changed = true;
+            match msg {
+                DownloadMsg::Progress { id, done, total } => {
+                    if let Some(j) = self.jobs.get_mut(&id) { j.done = done; j.total = total; }
+                }
+                DownloadMsg::Retry { id, url, next, error } => {
+                    log::warn(&format!("download {} from {} failed, trying {}: {}", id, url, next, error));
+                    if let Some(j) = self.jobs.get_mut(&id) { j.done = 0; j.base = 0; j.total = None; }
+                    // A hand-off on quit continues from the source in use
+                    if let Some(src) = self.sources.get_mut(&id) { src.0 = next; }
+                }
+                DownloadMsg::Finished { id, path } => {
+                    log::info(&format!("download {} finished: {}", id, path.display()));
+                    if let Some(j) = self.jobs.get_mut(&id) { j.state = DownloadState::Done; }
+                    self.cancels.remove(&id);
+                    self.finished.push(id);
+                }
+                DownloadMsg::Failed { id, error } => {
+                    let cancelled = self.cancels.remove(&id).map_or(false, |c| c.load(Ordering::Relaxed));
+                    log::warn(&format!("download {} failed: {}", id, error));
+                    // A failed download stays in the journal to be resumed
+                    if cancelled && !KEEP_PARTIAL.load(Ordering::Relaxed) { download_journal::remove(&id); }
+                    if let Some(j) = self.jobs.get_mut(&id) {
+                        j.state = if cancelled { DownloadState::Cancelled } else { DownloadState::Failed(error) };
+                    }
+                }
+            }
+        }
+        changed
+    }
+
+    /// Ids that finished since the last call.
+    pub fn take_finished(&mut self) -> Vec<String> {
+        std::mem::take(&mut self.finished)
+    }
+
+    pub fn status(&self, id: &str) -> Option<&DownloadStatus> {
+        self.jobs.get(id)
+    }
+
+    pub fn active_count(&self) -> usize {
+        self.jobs.values().filter(|j| j.state == DownloadState::Running).count()
+    }
+
+    /// `download.start` / `download.status` / `download.cancel` from another
+    /// process, so the CLI can hand a download to this TUI and follow it.
+    /// Starting a model that is already downloading attaches to it.
+    pub fn handle_remote(&mut self, req: &Value) -> Result<Value> {
+        let text = |k: &str| req.get(k).and_then(|v| v.as_str()).map(|s| s.trim().to_string()).filter(|s| !s.is_empty());
+        let cmd = text("cmd").unwrap_or_default();
+        if cmd == "download.status" && text("id").is_none() {
+            let mut jobs: Vec<&DownloadStatus> = self.jobs.values().collect();
+            jobs.sort_by_key(|j| j.started);
+            return Ok(serde_json::json!({"ok": true, "jobs": jobs.iter().map(|j| j.to_json()).collect::<Vec<_>>()}));
+        }
+        let id = text("id").ok_or_else(|| anyhow!("missing \"id\""))?;
+        match cmd.as_str() {
+            "download.start" => {
+                let running = self.status(&id).map_or(false, |j| j.state == DownloadState::Running);
+                if !running {
+                    let repo = text("repo").ok_or_else(|| anyhow!("missing \"repo\""))?;
+                    let filename = text("filename").ok_or_else(|| anyhow!("missing \"filename\""))?;
+                    // The file lands in the model dir; no paths from outside
+                    if filename.contains(['/', '\\']) || filename.starts_with('.') {
+                        return Err(anyhow!("invalid filename \"{}\"", filename));
+                    }
+                    self.start(&id, sources(&id, &repo, &filename, &Value::Null), &filename, text("sha256"))?;
+                }
+            }
+            "download.cancel" => self.cancel(&id),
+            "download.status" => {}
+            other => return Err(anyhow!("unknown command \"{}\"", other)),
+        }
+        let job = self.status(&id).map(|j| j.to_json()).ok_or_else(|| anyhow!("no download \"{}\"", id))?;
+        Ok(serde_json::json!({"ok": true, "job": job}))
+    }
+}
+
+/// Continue a download in a separate `chi-tui fetch` process that outlives
+/// the TUI. Returns its pid.
+pub fn spawn_detached(url: &str, target: &Path) -> Result<u32> {
+    let exe = std::env::current_exe()?;
+    let mut cmd = Command::new(exe);
+    cmd.arg("fetch").arg("--url").arg(url).arg("--target").arg(target);
+    cmd.stdin(Stdio::null()).stdout(Stdio::null()).stderr(Stdio::null());
+    // Own process group: a Ctrl+C in the terminal must not reach it
+    #[cfg(unix)]
+    {
+        use std::os::unix::process::CommandExt;
+        cmd.process_group(0);
+    }
+    let child = cmd.spawn()?;
+    log::info(&format!("download of {} detached to pid {}", target.display(), child.id()));
+    Ok(child.id())
+}
+
+/// `chi-tui fetch`: the detached side of `spawn_detached`.
+pub fn run_fetch(url: &str, target: &Path) -> Result<()> {
+    let (tx, _rx) = channel();
+    let filename = target.file_name().map(|n| n.to_string_lossy().to_string()).unwrap_or_default();
+    let id = download_journal::id_of(&filename).unwrap_or(filename);
+    let result = fetch(&id, url, target, &AtomicBool::new(false), &tx);
+    match &result {
+        Ok(()) => {
+            download_journal::remove(&id);
+            log::info(&format!("detached download {} finished", id));
+        }
+        Err(e) => log::warn(&format!("detached download {} failed: {}", id, e)),
+    }
+    result
+}
+
+/// Attempts per source; only errors that may pass (network, HTTP 5xx) are retried.
+const ATTEMPTS: usize = 2;
+
+/// Errors no retry will fix: the server refused or does not have the file.
+fn is_permanent(e: &anyhow::Error) -> bool {
+    e.to_string().starts_with("HTTP 4")
+}
+
+/// Try each source in turn, retrying transient failures, and record the one
+/// that worked when there was a choice. The error names every source tried.
+fn fetch_any(id: &str, urls: &[String], target: &Path, cancel: &AtomicBool, tx: &Sender<DownloadMsg>) -> Result<()> {
+    let mut errors = Vec::new();
+    for (i, url) in urls.iter().enumerate() {
+        for attempt in 1..=ATTEMPTS {
+            if cancel.load(Ordering::Relaxed) { return Err(anyhow!("cancelled")); }
+            match fetch(id, url, target, cancel, tx) {
+                Ok(()) => {
+                    download_journal::remove(id);
+                    if urls.len() > 1 {
+                        if let Err(e) = record_mirror(id, url) { log::warn(&format!("recording the mirror of {} failed: {}", id, e)); }
+                    }
+                    return Ok(());
+                }
+                Err(e) if cancel.load(Ordering::Relaxed) => return Err(e),
+                Err(e) if attempt < ATTEMPTS && !is_permanent(&e) => {
+                    log::warn(&format!("download {} from {} failed (attempt {}), retrying: {}", id, url, attempt, e));
+                    thread::sleep(Duration::from_secs(2));
+                }
+                Err(e) => {
+                    if let Some(next) = urls.get(i + 1) {
+                        let _ = tx.send(DownloadMsg::Retry { id: id.to_string(), url: url.clone(), next: next.clone(), error: e.to_string() });
+                    }
+                    errors.push(e.to_string());
+                    break;
+                }
+            }
+        }
+    }
+    match errors.len() {
+        0 => Err(anyhow!("no download source")),
+        1 => Err(anyhow!(errors.remove(0))),
+        n => Err(anyhow!("all {} sources failed: {}", n, errors.join("; "))),
+    }
+}
+
+/// Stream `url` to `<target>.part`, then rename into place. An existing
+/// `.part` file is continued with a range request when the server still
+/// has the same file (`If-Range`); otherwise it starts over.
+fn fetch(id: &str, url: &str, target: &Path, cancel: &AtomicBool, tx: &Sender<DownloadMsg>) -> Result<()> {
+    let client = http::builder(&http::load_http_settings())?
+        .connect_timeout(Duration::from_secs(15))
+        .timeout(None::<Duration>)
+        .build()?;
+    let part = part_path(target);
+    // Locked while written: a detached `chi-tui fetch` may own it
+    let mut out = OpenOptions::new().create(true).write(true).open(&part)?;
+    if out.try_lock_exclusive().is_err() {
+        return Err(anyhow!("{} is being downloaded by another chi-tui process", part.display()));
+    }
+    let offset = out.metadata()?.len();
+    let mut req = client.get(url);
+    // Gated and private repos need the token; other hosts never see it
+    if url.starts_with(hf::HUB) {
+        if let Some((token, _)) = hf::token() { req = req.bearer_auth(token); }
+    }
+    // Only with a validator: a mirror's file must not be appended to another's
+    if let Some(v) = download_journal::get(id).and_then(|p| p.validator).filter(|_| offset > 0) {
+        req = req.header("Range", format!("bytes={}-", offset)).header("If-Range", v);
+    }
+    let mut resp = req.send()?;
+    match resp.status().as_u16() {
+        // The part is no prefix of what the server has: start over
+        416 if offset > 0 => {
+            drop(out);
+            fs::remove_file(&part)?;
+            return fetch(id, url, target, cancel, tx);
+        }
+        401 | 403 if hf::token().is_none() => {
+            return Err(anyhow!("HTTP {}: gated or private repo; set a Hugging Face token (Settings → h)", resp.status().as_u16()))
+        }
+        401 | 403 => return Err(anyhow!("HTTP {}: the Hugging Face token has no access to this repo", resp.status().as_u16())),
+        _ if !resp.status().is_success() => return Err(anyhow!("HTTP {}", resp.status().as_u16())),
+        _ => {}
+    }
+    let resumed = resp.status().as_u16() == 206;
+    let mut done: u64 = if resumed { offset } else { 0 };
+    let total = resp.content_length().map(|n| n + done);
+    {
+        let header = |name: &str| resp.headers().get(name).and_then(|v| v.to_str().ok()).map(str::to_string);
+        let etag = header("etag");
+        // Hugging Face and many CDNs use the file's SHA-256 as its ETag
+        let sha = etag.as_deref().map(|e| e.trim_start_matches("W/").trim_matches('"').to_ascii_lowercase()).filter(|e| e.len() == 64 && e.chars().all(|c| c.is_ascii_hexdigit()));
+        let validator = etag.filter(|e| !e.starts_with("W/")).or_else(|| header("last-modified"));
+        if let Err(e) = download_journal::update(id, total, sha, validator) { log::warn(&format!("recording download {} failed: {}", id, e)); }
+    }
+    if resumed { out.seek(SeekFrom::End(0))?; } else { out.set_len(0)?; }
+    let mut buf = vec![0u8; 64 * 1024];
+    let mut last = Instant::now();
+    loop {
+        if cancel.load(Ordering::Relaxed) {
+            drop(out);
+            if !KEEP_PARTIAL.load(Ordering::Relaxed) { let _ = fs::remove_file(&part); }
+            return Err(anyhow!("cancelled"));
+        }
+        let n = resp.read(&mut buf)?;
+        if n == 0 { break; }
+        out.write_all(&buf[..n])?;
+        done += n as u64;
+        if last.elapsed() >= PROGRESS_EVERY {
+            last = Instant::now();
+            let _ = tx.send(DownloadMsg::Progress { id: id.to_string(), done, total });
+        }
+    }
+    out.flush()?;
+    drop(out);
+    if let Some(t) = total {
+        // Kept: the next attempt continues from here
+        if done != t { return Err(anyhow!("incomplete download ({} of {} bytes)", done, t)); }
+    }
+    if let Some(expected) = download_journal::get(id).and_then(|p| p.sha256) {
+        let actual = sha256_of(&part)?;
+        if actual != expected {
+            let _ = fs::remove_file(&part);
+            return Err(anyhow!("checksum mismatch: expected sha256 {}, got {}", expected, actual));
+        }
+    }
+    fs::rename(&part, target)?;
+    Ok(())
+}
+
+fn sha256_of(path: &Path) -> Result<String> {
+    let mut hasher = Sha256::new();
+    std::io::copy(&mut File::open(path)?, &mut hasher)?;
+    Ok(hasher.finalize().iter().map(|b| format!("{:02x}", b)).collect())
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+
+    fn status(done: u64, total: Option<u64>, state: DownloadState) -> DownloadStatus {
+        DownloadStatus { id: "m".to_string(), done, base: 0, total, started: Instant::now(), state }
+    }
+
+    #[test]
+    fn labels_show_progress_and_outcome() {
+        assert_eq!(format_eta(Duration::from_secs(65)), "1m05s");
+        assert_eq!(format_eta(Duration::from_secs(3 * 3600 + 120)), "3h02m");
+        let running = status(42, Some(100), DownloadState::Running);
+        assert_eq!(running.percent(), Some(42.0));
+        assert_eq!(running.label(), "↓ 42%", "no ETA in the first second");
+        assert_eq!(status(5, Some(0), DownloadState::Running).percent(), None);
+        assert_eq!(status(0, None, DownloadState::Done).label(), "✓ downloaded");
+        assert_eq!(status(0, None, DownloadState::Failed("HTTP 404".to_string())).label(), "✗ HTTP 404");
+        let json = status(0, None, DownloadState::Cancelled).to_json();
+        assert_eq!((json["state"].as_str(), json["error"].is_null()), (Some("cancelled"), true));
+    }
+
+    #[cfg(unix)]
+    #[test]
+    fn sources_list_the_hub_then_mirrors_with_the_last_good_one_first() {
+        let _fake = crate::testing::FakeCli::new();
+        let raw = serde_json::json!({"mirrors": [{"url": "https://cdn.example/m.gguf"}, {"repo": "org/alt"}, {"url": " "}]});
+        let hub = hf_url("org/m", "m.gguf");
+        let alt = hf_url("org/alt", "m.gguf");
+        assert_eq!(sources("m", "org/m", "m.gguf", &raw), vec![hub.clone(), "https://cdn.example/m.gguf".to_string(), alt.clone()]);
+        fs::create_dir_all(model_dir().expect("dir")).expect("mkdir");
+        record_mirror("m", &alt).expect("record");
+        assert_eq!(sources("m", "org/m", "m.gguf", &raw)[0], alt);
+        assert_eq!(sources("m", "org/m", "m.gguf", &Value::Null), vec![hub]);
+    }
+
+    fn wait_for(dm: &mut DownloadManager, id: &str, state: DownloadState) {
+        let started = Instant::now();
+        while dm.status(id).map(|j| &j.state) != Some(&state) {
+            assert!(started.elapsed() < Duration::from_secs(10), "{} never became {:?}", id, state);
+            dm.poll();
+            thread::sleep(Duration::from_millis(20));
+        }
+    }
+
+    #[cfg(unix)]
+    #[test]
+    fn a_download_lands_in_the_model_dir() {
+        let _fake = crate::testing::FakeCli::new();
+        let url = crate::testing::slow_server(4096, Duration::ZERO);
+        let mut dm = DownloadManager::default();
+        dm.start("m", vec![format!("{}/m.gguf", url)], "m.gguf", None).expect("start");
+        assert_eq!(dm.active_count(), 1);
+        assert!(dm.start("m", vec![url.clone()], "m.gguf", None).is_err(), "already downloading");
+        wait_for(&mut dm, "m", DownloadState::Done);
+        let target = model_dir().expect("dir").join("m.gguf");
+        assert_eq!(fs::metadata(&target).expect("file").len(), 4096);
+        assert!(!part_path(&target).exists());
+        assert_eq!(dm.take_finished(), vec!["m".to_string()]);
+        assert!(dm.take_finished().is_empty());
+    }
+
+    #[cfg(unix)]
+    #[test]
+    fn a_cancelled_download_removes_its_partial_file() {
+        let _fake = crate::testing::FakeCli::new();
+        let url = crate::testing::slow_server(1 << 20, Duration::from_millis(20));
+        let mut dm = DownloadManager::default();
+        dm.start("big", vec![format!("{}/big.gguf", url)], "big.gguf", None).expect("start");
+        let target = model_dir().expect("dir").join("big.gguf");
+        assert_eq!(dm.running().len(), 1);
+        assert_eq!(dm.source("big"), Some((format!("{}/big.gguf", url), target.clone())));
+        dm.cancel_all();
+        wait_for(&mut dm, "big", DownloadState::Cancelled);
+        assert_eq!(dm.active_count(), 0);
+        assert!(!target.exists() && !part_path(&target).exists());
+    }
+
+    #[cfg(unix)]
+    #[test]
+    fn remote_requests_are_checked() {
+        let _fake = crate::testing::FakeCli::new();
+        let mut dm = DownloadManager::default();
+        assert_eq!(dm.handle_remote(&serde_json::json!({"cmd": "download.status"})).expect("list")["jobs"], serde_json::json!([]));
+        let bad = serde_json::json!({"cmd": "download.start", "id": "m", "repo": "org/m", "filename": "../m.gguf"});
+        assert!(dm.handle_remote(&bad).expect_err("path").to_string().contains("invalid filename"));
+        assert!(dm.handle_remote(&serde_json::json!({"cmd": "download.start"})).is_err());
+        assert!(dm.handle_remote(&serde_json::json!({"cmd": "download.status", "id": "nope"})).expect_err("unknown").to_string().contains("no download"));
+        assert!(dm.handle_remote(&serde_json::json!({"cmd": "download.pause", "id": "m"})).expect_err("cmd").to_string().contains("unknown command"));
+    }
+}
@@ -0,0 +1,228 @@
+//! End-to-end flows against a fake `chi-llm` (see `testing.rs`) that cross
+//! pages or runs: startup, configure and save, Test connection, the model
+//! browser, the Build guard, saved settings and whole-frame layouts, each
+//! through the same functions the UI and headless commands call. Tests of
+//! one feature live next to it.
+
+use std::time::Duration;
+
+use serde_json::json;
+
+use crate::app::{App, Page};
+use crate::audit::AUDIT_PATH;
+use crate::config_cli::run_config;
+use crate::mock;
+use crate::models::fetch_models;
+use crate::prefs::{self, Prefs};
+use crate::providers::{load_providers_state, probe_provider, read_scratch_entries};
+use crate::testing::{add_provider, assert_golden, screen, FakeCli};
+use crate::text;
+use crate::util::ensure_chi_llm;
+
+#[test]
+fn startup_finds_chi_llm_and_loads_schema() {
+    let fake = FakeCli::new();
+    ensure_chi_llm().expect("fake chi-llm is on PATH");
+    let st = load_providers_state().expect("schema from the fake");
+    assert_eq!(st.schema_types, vec!["local", "ollama", "openai"]);
+    assert!(st.entries.is_empty());
+    let port = st.schema_map["ollama"].iter().find(|f| f.name == "port").expect("port field");
+    assert!(port.is_numeric());
+    assert_eq!(fake.calls(), vec!["--version", "providers schema --json"]);
+}
+
+#[test]
+fn startup_without_chi_llm_explains_how_to_install() {
+    let fake = FakeCli::new();
+    fake.uninstall();
+    let err = ensure_chi_llm().expect_err("no chi-llm on PATH").to_string();
+    assert!(err.contains("not found in PATH"), "{}", err);
+}
+
+#[test]
+fn configure_save_and_test_connection() {
+    let mut fake = FakeCli::new();
+    fake.respond("providers discover-models --type ollama", json!({"provider": "ollama", "models": [{"id": "llama3.2"}, {"id": "qwen3"}]}));
+    run_config(add_provider("ollama", "home", &[("host", "10.0.0.5"), ("port", "11500")], true)).expect("add provider");
+
+    let store = fake.store();
+    assert_eq!(store["default_provider_id"], "home");
+    let p = &store["providers"][0];
+    assert_eq!(p["type"], "ollama");
+    // Typed per schema: int field stored as a number
+    assert_eq!(p["config"]["port"], 11500);
+    assert!(fake.exists(AUDIT_PATH));
+
+    let entries = read_scratch_entries().expect("entries");
+    assert_eq!(probe_provider(&entries[0]).expect("test connection"), "ollama: 2 models");
+    assert!(fake.calls().iter().any(|c| c == "providers discover-models --type ollama --host 10.0.0.5 --port 11500 --json"), "{:?}", fake.calls());
+}
+
+#[test]
+fn model_browser_search_and_apply_to_provider() {
+    let fake = FakeCli::new();
+    run_config(add_provider("local", "laptop", &[], true)).expect("add provider");
+
+    let mut mb = fetch_models(Duration::from_secs(5)).expect("models from the fake");
+    assert_eq!(mb.entries.len(), 2);
+    mb.set_search("phi".to_string());
+    let picked = mb.current_entry().expect("phi3 matches").clone();
+    assert_eq!(picked.id, "phi3-mini");
+    assert!(!picked.downloaded);
+
+    // Enter in the browser: the Configure page applies the id and saves
+    let mut st = load_providers_state().expect("providers");
+    st.apply_model_to_selected(&picked.id);
+    st.save().expect("save");
+    assert_eq!(fake.store()["providers"][0]["config"]["model"], "phi3-mini");
+}
+
+#[test]
+fn shift_enter_in_the_model_browser_sets_the_model_and_saves() {
+    use crossterm::event::{KeyCode, KeyEvent, KeyModifiers};
+    let fake = FakeCli::new();
+    run_config(add_provider("local", "laptop", &[], true)).expect("add provider");
+    let mut app = App::new(false);
+    crate::open_page_loaded(&mut app, Page::Configure);
+    crate::open_page_loaded(&mut app, Page::ModelBrowser);
+    app.model.as_mut().expect("models").set_search("phi".to_string());
+
+    crate::keys::handle_key(&mut app, KeyEvent::new(KeyCode::Enter, KeyModifiers::SHIFT));
+    assert_eq!(app.page, Page::Configure);
+    assert_eq!(fake.store()["providers"][0]["config"]["model"], "phi3-mini");
+    let toast = app.toast.as_ref().expect("save toast");
+    assert_eq!(toast.text, "Saved local with model phi3-mini");
+    // The form shows the saved values with nothing left to save
+    let form = app.providers.as_ref().and_then(|st| st.form.as_ref()).expect("form");
+    assert!(form.changes().is_empty());
+    assert_eq!(form.message.as_deref(), Some("Saved local with model phi3-mini"));
+}
+
+#[test]
+fn build_without_providers_explains_and_jumps_to_configure() {
+    use crossterm::event::{KeyCode, KeyEvent, KeyModifiers};
+    let fake = FakeCli::new();
+    let mut app = App::new(false);
+    crate::open_page_loaded(&mut app, Page::Build);
+    let text = screen(&app, 100, 30);
+    assert!(text.contains("Nothing to build yet"), "{}", text);
+    assert!(text.contains("f   find local servers"), "{}", text);
+
+    crate::keys::handle_key(&mut app, KeyEvent::new(KeyCode::Enter, KeyModifiers::NONE));
+    assert!(app.toast.as_ref().map_or(false, |t| t.text.starts_with("Nothing to write: add a provider first")));
+    assert!(!fake.exists(".chi_llm.json"));
+
+    crate::keys::handle_key(&mut app, KeyEvent::new(KeyCode::Char('c'), KeyModifiers::NONE));
+    assert_eq!(app.page, Page::Configure);
+    assert!(app.providers.is_some());
+
+    // With a provider but no default, the guard points at Select Default
+    run_config(add_provider("ollama", "home", &[], false)).expect("add provider");
+    assert_eq!(crate::build::missing_prerequisite(), Some(crate::build::Missing::Default));
+    crate::open_page_loaded(&mut app, Page::Build);
+    crate::keys::handle_key(&mut app, KeyEvent::new(KeyCode::Char('d'), KeyModifiers::NONE));
+    assert_eq!(app.page, Page::SelectDefault);
+}
+
+#[test]
+fn golden_frames_keep_their_layout() {
+    let mut fake = FakeCli::new();
+    fake.uninstall();
+    fake.set_env("CHI_TUI_MOCK", String::new());
+    let root = mock::start().expect("start mock mode");
+    let frame = |page: Page, w: u16, h: u16| {
+        let mut app = App::new(false);
+        crate::open_page_loaded(&mut app, page);
+        crate::script::render(&mut app, w, h).expect("render")
+    };
+    // Wide and compact layouts of the pages most edits touch
+    let welcome = frame(Page::Welcome, 100, 30);
+    assert_eq!(welcome.lines().count(), 30);
+    assert_golden("welcome-100x30", &welcome);
+    assert_golden("welcome-60x16", &frame(Page::Welcome, 60, 16));
+    assert_golden("welcome-72x30", &frame(Page::Welcome, 72, 30));
+    assert_golden("configure-72x30", &frame(Page::Configure, 72, 30));
+    assert_golden("configure-100x30", &frame(Page::Configure, 100, 30));
+    assert_golden("select-default-100x30", &frame(Page::SelectDefault, 100, 30));
+    assert_golden("settings-100x30", &frame(Page::Settings, 100, 30));
+
+    let _ = std::env::set_current_dir(&fake.root);
+    let _ = std::fs::remove_dir_all(root);
+}
+
+#[test]
+fn settings_are_saved_in_tui_json_and_applied_at_startup() {
+    use crossterm::event::{KeyCode, KeyEvent, KeyModifiers};
+    let fake = FakeCli::new();
+    let press = |app: &mut App, c: char| crate::keys::handle_key(app, KeyEvent::new(KeyCode::Char(c), KeyModifiers::NONE));
+    let mut app = App::new(false);
+    crate::load_preferences(&mut app);
+    assert_eq!(app.prefs, Prefs::default());
+    crate::open_page_loaded(&mut app, Page::Settings);
+    for c in ['a', 'c', 'w', 'o', 'l', 'l', 'f'] { press(&mut app, c); }
+
+    let path = prefs::path().expect("tui.json path");
+    assert!(path.ends_with("chi_llm/tui.json"), "{}", path.display());
+    let saved: serde_json::Value = serde_json::from_str(&std::fs::read_to_string(&path).expect("tui.json")).expect("json");
+    assert_eq!(saved["animation"], false);
+    assert_eq!(saved["colorblind"], true);
+    assert_eq!(saved["build_target"], "global");
+    assert_eq!(saved["discovery_timeout_secs"], 10);
+    assert_eq!(saved["cache_ttl_secs"], 600);
+    assert_eq!(saved["config_format"], "yaml");
+    let text = screen(&app, 120, 40);
+    assert!(text.contains("w  Build writes to: global") && text.contains("l  Cache lifetime: 10m"), "{}", text);
+
+    // A fresh run picks everything up; Build starts on the saved target
+    run_config(add_provider("ollama", "home", &[], true)).expect("add provider");
+    let mut next = App::new(false);
+    crate::load_preferences(&mut next);
+    assert!(!next.anim && next.theme.colorblind);
+    assert_eq!(next.prefs, prefs::load());
+    crate::open_page_loaded(&mut next, Page::Build);
+    assert!(screen(&next, 100, 30).contains("Target: Global"));
+
+    // New projects start in the saved format; unknown keys survive a save
+    std::fs::write(&path, r#"{"config_format": "yaml", "future": 1}"#).expect("write tui.json");
+    std::fs::remove_file(fake.root.join("chi.tmp.yaml")).expect("start over");
+    run_config(add_provider("ollama", "home", &[], true)).expect("add provider");
+    assert!(fake.exists("chi.tmp.yaml") && !fake.exists("chi.tmp.json"));
+    prefs::save(&prefs::load()).expect("save");
+    let saved: serde_json::Value = serde_json::from_str(&std::fs::read_to_string(&path).expect("tui.json")).expect("json");
+    assert_eq!((saved["future"].clone(), saved.get("cache_ttl_secs")), (json!(1), None));
+}
+
+#[test]
+fn narrow_terminals_get_a_page_bar_and_stacked_panes() {
+    let _fake = FakeCli::new();
+    run_config(add_provider("ollama", "home", &[], true)).expect("add provider");
+    let mut app = App::new(false);
+    app.fit(79, 30);
+    assert!(app.narrow && !app.compact && app.stacked());
+    app.fit(80, 30);
+    assert!(!app.narrow && !app.stacked());
+
+    // No hero header or menu help lines; the shortcut pages sit in a bar
+    let welcome = crate::script::render(&mut app, 72, 30).expect("render");
+    let rows: Vec<&str> = welcome.lines().collect();
+    assert!(rows[0].contains("chi_llm TUI • ? help"), "{}", welcome);
+    assert!(rows[1].contains("1 README") && rows[1].contains("2 Configure") && rows[1].contains("s Settings"), "{}", welcome);
+    assert!(!welcome.contains("micro‑LLM") && !welcome.contains("Project docs with a table of contents"), "{}", welcome);
+
+    // Configure: the list above the form, each the full width
+    crate::open_page_loaded(&mut app, Page::Configure);
+    let text = crate::script::render(&mut app, 72, 30).expect("render");
+    let top = |needle: &str| text.lines().position(|l| l.contains(needle));
+    let (list, form) = (top("Configure Providers").expect("list"), top("Provider Details").expect("form"));
+    assert!(list < form, "{}", text);
+    assert!(text.lines().all(|l| text::width(l) <= 72));
+
+    // Pages without a shortcut are named at the end of the bar
+    crate::open_page_loaded(&mut app, Page::Logs);
+    let text = crate::script::render(&mut app, 72, 30).expect("render");
+    assert!(text.lines().nth(1).map_or(false, |l| l.contains("› Logs")), "{}", text);
+
+    // Wide terminals keep the hero and side-by-side panes
+    crate::open_page_loaded(&mut app, Page::Welcome);
+    assert!(crate::script::render(&mut app, 100, 30).expect("render").contains("micro‑LLM"));
+}
@@ -0,0 +1,285 @@
+use std::fs;
+
+use anyhow::{anyhow, Result};
+use serde_json::Value;
+
+use crate::audit;
+use crate::portforward::K8S_FIELD_PREFIX;
+use crate::secrets::{self, REF_PREFIX};
+use crate::store;
+use crate::variables;
+
+pub const DOTENV: &str = ".env";
+pub const ENVRC: &str = ".envrc";
+/// First line of generated files; others are never overwritten
+const MARKER: &str = "# Generated by chi-tui";
+
+/// Provider config keys chi_llm's `load_config` also reads from the
+/// environment, in the order they are written.
+const ENV_KEYS: &[(&str, &str)] = &[
+    ("host", "CHI_LLM_PROVIDER_HOST"),
+    ("port", "CHI_LLM_PROVIDER_PORT"),
+    ("model", "CHI_LLM_PROVIDER_MODEL"),
+    ("model_path", "CHI_LLM_PROVIDER_MODEL_PATH"),
+    ("context_window", "CHI_LLM_PROVIDER_CONTEXT_WINDOW"),
+    ("n_gpu_layers", "CHI_LLM_PROVIDER_N_GPU_LAYERS"),
+    ("output_tokens", "CHI_LLM_PROVIDER_OUTPUT_TOKENS"),
+    ("api_key", "CHI_LLM_PROVIDER_API_KEY"),
+];
+
+enum EnvValue {
+    Plain(String),
+    /// Name of a keychain secret; never written in clear
+    Secret(String),
+}
+
+/// The default provider as chi_llm environment variables.
+struct EnvExport {
+    provider_id: String,
+    vars: Vec<(&'static str, EnvValue)>,
+    /// Config keys chi_llm has no environment variable for
+    skipped: Vec<String>,
+}
+
+fn scalar(v: &Value) -> Option<String> {
+    match v {
+        Value::String(s) => Some(s.clone()),
+        Value::Number(n) => Some(n.to_string()),
+        Value::Bool(b) => Some(b.to_string()),
+        _ => None,
+    }
+}
+
+/// Same provider Build writes: `default_provider_id`, with `{{ variables }}`
+/// filled in and secrets kept as references.
+fn collect(root: &Value) -> Result<EnvExport> {
+    let def = root
+        .get("default_provider_id")
+        .and_then(|x| x.as_str())
+        .ok_or_else(|| anyhow!("no default_provider_id in the provider store"))?;
+    let p = root
+        .get("providers")
+        .and_then(|x| x.as_array())
+        .and_then(|a| a.iter().find(|p| p.get("id").and_then(|x| x.as_str()) == Some(def)))
+        .ok_or_else(|| anyhow!("default provider \"{}\" not found", def))?;
+    let ptype = match p.get("type").and_then(|x| x.as_str()).unwrap_or("") {
+        "" => return Err(anyhow!("default provider type missing")),
+        "local-zeroconfig" | "local-custom" => "local",
+        other => other,
+    };
+    let cfg = p.get("config").map(|c| variables::resolve_value(c, &variables::load_variables())).unwrap_or(Value::Null);
+    let obj = cfg.as_object().cloned().unwrap_or_default();
+    let mut vars = vec![("CHI_LLM_PROVIDER_TYPE", EnvValue::Plain(ptype.to_string()))];
+    for (key, name) in ENV_KEYS {
+        let Some(text) = obj.get(*key).and_then(scalar).filter(|s| !s.is_empty()) else { continue };
+        vars.push((*name, match text.strip_prefix(REF_PREFIX) {
+            Some(secret) => EnvValue::Secret(secret.to_string()),
+            None => EnvValue::Plain(text),
+        }));
+    }
+    let skipped = obj
+        .iter()
+        .filter(|(k, v)| *k != "type" && !k.starts_with(K8S_FIELD_PREFIX) && !ENV_KEYS.iter().any(|(e, _)| e == k) && scalar(v).map_or(false, |s| !s.is_empty()))
+        .map(|(k, _)| k.clone())
+        .collect();
+    Ok(EnvExport { provider_id: def.to_string(), vars, skipped })
+}
+
+fn is_bare(s: &str) -> bool {
+    !s.is_empty() && s.chars().all(|c| c.is_ascii_alphanumeric() || "_-./:@,+".contains(c))
+}
+
+fn dotenv_quote(s: &str) -> String {
+    if is_bare(s) { s.to_string() } else { format!("\"{}\"", s.replace('\\', "\\\\").replace('"', "\\\"").replace('\n', "\\n")) }
+}
+
+pub fn shell_quote(s: &str) -> String {
+    if is_bare(s) { s.to_string() } else { format!("'{}'", s.replace('\'', "'\\''")) }
+}
+
+pub fn secret_cmd(name: &str) -> String {
+    format!("$(chi-tui secret {})", shell_quote(name))
+}
+
+fn header(ex: &EnvExport) -> Vec<String> {
+    let mut out = vec![
+        format!("{} from {} (default provider \"{}\").", MARKER, store::path(), ex.provider_id),
+        "# Overwritten when env sync is on (Settings, e); change the provider instead.".to_string(),
+    ];
+    if !ex.skipped.is_empty() {
+        out.push(format!("# No chi_llm env var for: {} (use Build for those)", ex.skipped.join(", ")));
+    }
+    out
+}
+
+/// `.env` for dotenv loaders: plain `KEY=value`. Loaders do not run
+/// commands, so keychain secrets are left as a commented hint.
+fn render_dotenv(ex: &EnvExport) -> String {
+    let mut out = header(ex);
+    for (name, v) in &ex.vars {
+        match v {
+            EnvValue::Plain(s) => out.push(format!("{}={}", name, dotenv_quote(s))),
+            EnvValue::Secret(s) => {
+                out.push(format!("# {} is in the keychain; export it with:", name));
+                out.push(format!("# {}=\"{}\"", name, secret_cmd(s)));
+            }
+        }
+    }
+    out.join("\n") + "\n"
+}
+
+/// `.envrc` for direnv: a shell script, so secrets are fetched on load.
+fn render_envrc(ex: &EnvExport) -> String {
+    let mut out = header(ex);
+    for (name, v) in &ex.vars {
+        out.push(match v {
+            EnvValue::Plain(s) => format!("export {}={}", name, shell_quote(s)),
+            EnvValue::Secret(s) => format!("export {}=\"{}\"", name, secret_cmd(s)),
+        });
+    }
+    out.join("\n") + "\n"
+}
+
+/// `.env` or `.envrc` text for the default provider.
+pub fn render(envrc: bool) -> Result<String> {
+    let ex = collect(&store::read_layered()?)?;
+    Ok(if envrc { render_envrc(&ex) } else { render_dotenv(&ex) })
+}
+
+/// `NAME=value` lines of a generated file as `{NAME: value}`, so the audit
+/// log diffs (and masks) per variable instead of storing whole files.
+fn audit_vars(text: &str) -> Value {
+    let vars = text
+        .lines()
+        .filter(|l| !l.starts_with('#'))
+        .filter_map(|l| l.strip_prefix("export ").unwrap_or(l).split_once('='))
+        .map(|(name, value)| (name.to_string(), Value::String(value.to_string())))
+        .collect();
+    Value::Object(vars)
+}
+
+/// Write `.env` and `.envrc` in the working directory; returns the paths
+/// that changed. Hand-written files (no generated header) are left alone.
+pub fn write_files() -> Result<Vec<String>> {
+    let ex = collect(&store::read_layered()?)?;
+    let mut written = Vec::new();
+    for (path, text) in [(DOTENV, render_dotenv(&ex)), (ENVRC, render_envrc(&ex))] {
+        let before = fs::read_to_string(path).unwrap_or_default();
+        if before == text { continue; }
+        if !before.is_empty() && !before.starts_with(MARKER) {
+            return Err(anyhow!("{} exists and was not generated by chi-tui; not overwriting", path));
+        }
+        fs::write(path, &text)?;
+        let _ = audit::record("env.write", path, &audit_vars(&before), &audit_vars(&text));
+        written.push(path.to_string());
+    }
+    Ok(written)
+}
+
+pub fn load_sync() -> bool {
+    store::read().ok().and_then(|v| v.get("env_sync").and_then(|x| x.as_bool())).unwrap_or(false)
+}
+
+pub fn save_sync(on: bool) -> Result<()> {
+    let mut root = store::read_or_empty();
+    let before = root.clone();
+    if let Some(obj) = root.as_object_mut() { obj.insert("env_sync".to_string(), Value::Bool(on)); }
+    let path = store::write(&root)?;
+    let _ = audit::record("settings.env_sync", &path, &before, &root);
+    Ok(())
+}
+
+/// After a store write: regenerate the files when `env_sync` is on.
+pub fn sync_on_save() -> Result<Vec<String>> {
+    if !load_sync() { return Ok(Vec::new()); }
+    write_files()
+}
+
+/// `chi-tui env`: print one file, or write both.
+pub fn run_env(envrc: bool, write: bool) -> Result<()> {
+    if write {
+        let written = write_files()?;
+        if written.is_empty() { println!("{} and {} are up to date", DOTENV, ENVRC); } else { println!("Written: {}", written.join(", ")); }
+        return Ok(());
+    }
+    print!("{}", render(envrc)?);
+    Ok(())
+}
+
+/// `chi-tui secret <name>`: print a stored secret (used by `.envrc`).
+pub fn run_secret(name: &str) -> Result<()> {
+    let name = name.strip_prefix(REF_PREFIX).unwrap_or(name);
+    println!("{}", secrets::get(name)?);
+    Ok(())
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+    use serde_json::json;
+
+    fn store_with(config: Value) -> Value {
+        json!({"providers": [{"id": "box", "type": "local-zeroconfig", "config": config}], "default_provider_id": "box"})
+    }
+
+    #[test]
+    fn values_are_quoted_only_when_needed() {
+        assert_eq!(shell_quote("http://box:8000/v1"), "http://box:8000/v1");
+        assert_eq!(shell_quote("it's"), "'it'\\''s'");
+        assert_eq!(shell_quote(""), "''");
+        assert_eq!(dotenv_quote("a b\"c"), "\"a b\\\"c\"");
+        assert_eq!(dotenv_quote("line\nbreak"), "\"line\\nbreak\"");
+        assert_eq!(secret_cmd("chi llm/box"), "$(chi-tui secret 'chi llm/box')");
+    }
+
+    #[test]
+    fn the_default_provider_maps_to_chi_llm_variables() {
+        let root = store_with(json!({"port": 8080, "model": "qwen", "api_key": format!("{}box/api_key", REF_PREFIX), "temperature": 0.2, "host": "", "k8s_namespace": "ml"}));
+        let ex = collect(&root).expect("export");
+        let names: Vec<&str> = ex.vars.iter().map(|(n, _)| *n).collect();
+        assert_eq!(names, ["CHI_LLM_PROVIDER_TYPE", "CHI_LLM_PROVIDER_PORT", "CHI_LLM_PROVIDER_MODEL", "CHI_LLM_PROVIDER_API_KEY"]);
+        assert_eq!(ex.skipped, vec!["temperature".to_string()]);
+
+        let dotenv = render_dotenv(&ex);
+        assert!(dotenv.starts_with(MARKER));
+        assert!(dotenv.contains("\nCHI_LLM_PROVIDER_TYPE=local\n"));
+        assert!(dotenv.contains("\nCHI_LLM_PROVIDER_PORT=8080\n"));
+        assert!(dotenv.contains("# No chi_llm env var for: temperature"));
+        assert!(dotenv.contains("\n# CHI_LLM_PROVIDER_API_KEY=\"$(chi-tui secret box/api_key)\"\n"));
+        let envrc = render_envrc(&ex);
+        assert!(envrc.contains("\nexport CHI_LLM_PROVIDER_MODEL=qwen\n"));
+        assert!(envrc.contains("\nexport CHI_LLM_PROVIDER_API_KEY=\"$(chi-tui secret box/api_key)\"\n"));
+
+        assert!(collect(&json!({"providers": []})).is_err());
+        assert!(collect(&json!({"providers": [], "default_provider_id": "x"})).expect_err("missing").to_string().contains("\"x\" not found"));
+    }
+
+    #[test]
+    fn the_audit_sees_variables_and_masks_the_key() {
+        let ex = collect(&store_with(json!({"model": "qwen", "api_key": "sk-plain-123"}))).expect("export");
+        let (dotenv, envrc) = (audit_vars(&render_dotenv(&ex)), audit_vars(&render_envrc(&ex)));
+        assert_eq!(dotenv, json!({"CHI_LLM_PROVIDER_TYPE": "local", "CHI_LLM_PROVIDER_MODEL": "qwen", "CHI_LLM_PROVIDER_API_KEY": "sk-plain-123"}));
+        assert_eq!(envrc, dotenv);
+        let changes = audit::diff_values(&audit_vars(""), &dotenv);
+        let key = changes.iter().find(|c| c.path == "CHI_LLM_PROVIDER_API_KEY").expect("key change");
+        assert_ne!(key.new.as_deref(), Some("sk-plain-123"));
+        assert!(changes.iter().all(|c| !c.path.is_empty()));
+    }
+
+    #[cfg(unix)]
+    #[test]
+    fn hand_written_files_are_never_overwritten() {
+        let _fake = crate::testing::FakeCli::new();
+        store::write(&store_with(json!({"model": "qwen"}))).expect("store");
+        assert!(sync_on_save().expect("sync off").is_empty());
+        assert!(!std::path::Path::new(DOTENV).exists());
+        save_sync(true).expect("sync on");
+        assert_eq!(sync_on_save().expect("sync"), vec![DOTENV.to_string(), ENVRC.to_string()]);
+        assert!(write_files().expect("unchanged").is_empty());
+
+        fs::write(ENVRC, "use nix\n").expect("own envrc");
+        let err = write_files().expect_err("refuses");
+        assert!(err.to_string().contains(".envrc exists and was not generated"));
+        assert_eq!(fs::read_to_string(ENVRC).expect("envrc"), "use nix\n");
+    }
+}
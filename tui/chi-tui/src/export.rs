@@ -0,0 +1,216 @@
+//! Snippets that point other tools at a provider (Configure, `x`):
+//! OpenAI-style env vars, aider, continue.dev and a curl call. Keys never
+//! appear in clear: where a shell runs the snippet a keychain secret is
+//! fetched with `chi-tui secret`, elsewhere the tool's usual env var is
+//! named instead.
+
+use anyhow::{anyhow, Result};
+
+use crate::envfile::{secret_cmd, shell_quote};
+use crate::http::ANTHROPIC_VERSION;
+use crate::inspector::{api_base, chat_request};
+use crate::providers::ProviderScratchEntry;
+use crate::secrets::REF_PREFIX;
+use crate::variables;
+
+#[derive(Copy, Clone, Debug, PartialEq, Eq)]
+pub enum Format {
+    Env,
+    Aider,
+    Continue,
+    Curl,
+}
+
+impl Format {
+    pub const ALL: [Format; 4] = [Format::Env, Format::Aider, Format::Continue, Format::Curl];
+
+    pub fn label(self) -> &'static str {
+        match self {
+            Format::Env => "OpenAI-compatible env vars (.env)",
+            Format::Aider => "aider (.aider.conf.yml)",
+            Format::Continue => "continue.dev (config.yaml)",
+            Format::Curl => "curl example",
+        }
+    }
+}
+
+/// Where the provider's key comes from.
+enum Key {
+    /// None needed (local servers)
+    Unset,
+    /// Keychain secret with this name
+    Secret(String),
+    /// Stored in clear in the provider config; never exported
+    Plain,
+}
+
+fn key_of(entry: &ProviderScratchEntry) -> Key {
+    match entry.config.get("api_key").and_then(|v| v.as_str()).map(str::trim) {
+        None | Some("") => Key::Unset,
+        Some(s) => s.strip_prefix(REF_PREFIX).map_or(Key::Plain, |n| Key::Secret(n.to_string())),
+    }
+}
+
+/// Env var clients read the key from.
+fn key_var(entry: &ProviderScratchEntry) -> &'static str {
+    if entry.ptype == "anthropic" { "ANTHROPIC_API_KEY" } else { "OPENAI_API_KEY" }
+}
+
+/// Comment telling where the key for `key_var` comes from, if one is needed.
+fn key_hint(entry: &ProviderScratchEntry, key: &Key) -> Option<String> {
+    match key {
+        Key::Unset => None,
+        Key::Secret(name) => Some(format!("# {}: run `chi-tui secret {}` (kept in the keychain)", key_var(entry), name)),
+        Key::Plain => Some(format!("# {}: the api_key in this provider's config (not exported)", key_var(entry))),
+    }
+}
+
+fn model_of(entry: &ProviderScratchEntry) -> String {
+    entry.config.get("model").and_then(|v| v.as_str()).map(str::trim).unwrap_or("").to_string()
+}
+
+/// Plain scalar or a double-quoted one (JSON strings are valid YAML).
+fn yaml_str(s: &str) -> String {
+    if !s.is_empty() && s.chars().all(|c| c.is_ascii_alphanumeric() || "_-./@".contains(c)) {
+        s.to_string()
+    } else {
+        serde_json::Value::String(s.to_string()).to_string()
+    }
+}
+
+/// The selected provider as `fmt`: `{{ variables }}` filled in, `secret:`
+/// references kept (`resolve_entry` would put keys in clear).
+pub fn render(entry: &ProviderScratchEntry, fmt: Format) -> Result<String> {
+    let config = variables::resolve_value(&entry.config, &variables::load_variables());
+    let entry = &ProviderScratchEntry { config, ..entry.clone() };
+    let base = api_base(entry).ok_or_else(|| anyhow!("{} ({}) has no HTTP API to point other tools at; `chi-llm serve` can front it", entry.name, entry.ptype))?;
+    let key = key_of(entry);
+    let model = model_of(entry);
+    let mut out = vec![format!("# {} ({}), exported by chi-tui", entry.name, entry.ptype)];
+    match fmt {
+        Format::Env => {
+            if entry.ptype == "anthropic" {
+                out.push(format!("ANTHROPIC_BASE_URL={}", base.trim_end_matches("/v1")));
+            } else {
+                out.push(format!("OPENAI_BASE_URL={}", base));
+                // Older clients (and LiteLLM) read this name
+                out.push(format!("OPENAI_API_BASE={}", base));
+            }
+            match &key {
+                // Most clients refuse an empty key even when the server ignores it
+                Key::Unset => out.push(format!("{}=not-needed", key_var(entry))),
+                k => out.extend(key_hint(entry, k).into_iter().chain([format!("{}=", key_var(entry))])),
+            }
+            if !model.is_empty() { out.push(format!("# model: {}", model)); }
+        }
+        Format::Aider => {
+            let prefix = match entry.ptype.as_str() {
+                "ollama" => "ollama_chat/",
+                "anthropic" => "anthropic/",
+                _ => "openai/",
+            };
+            if !model.is_empty() {
+                let name = if model.starts_with(prefix) { model.clone() } else { format!("{}{}", prefix, model) };
+                out.push(format!("model: {}", yaml_str(&name)));
+            }
+            match entry.ptype.as_str() {
+                "ollama" => out.push(format!("set-env:\n  - {}", yaml_str(&format!("OLLAMA_API_BASE={}", base.trim_end_matches("/v1"))))),
+                "anthropic" => {}
+                _ => out.push(format!("openai-api-base: {}", yaml_str(&base))),
+            }
+            match &key {
+                Key::Unset if !matches!(entry.ptype.as_str(), "ollama" | "anthropic") => out.push("openai-api-key: not-needed".to_string()),
+                Key::Unset => {}
+                k => out.extend(key_hint(entry, k).map(|h| format!("{} — aider reads it from the environment", h))),
+            }
+        }
+        Format::Continue => {
+            let (provider, api_base) = match entry.ptype.as_str() {
+                "ollama" => ("ollama", base.trim_end_matches("/v1").to_string()),
+                "lmstudio" => ("lmstudio", format!("{}/", base)),
+                "anthropic" => ("anthropic", format!("{}/", base)),
+                _ => ("openai", format!("{}/", base)),
+            };
+            out.push("models:".to_string());
+            out.push(format!("  - name: {}", yaml_str(&entry.name)));
+            out.push(format!("    provider: {}", provider));
+            out.push(format!("    model: {}", yaml_str(if model.is_empty() { "AUTODETECT" } else { &model })));
+            out.push(format!("    apiBase: {}", yaml_str(&api_base)));
+            if !matches!(key, Key::Unset) {
+                out.push(format!("    apiKey: {}", yaml_str(&format!("${{{{ secrets.{} }}}}", key_var(entry)))));
+            }
+            out.push("    roles:\n      - chat\n      - edit".to_string());
+            if let Some(h) = key_hint(entry, &key) { out.push(format!("{} — add it to Continue's secrets", h)); }
+        }
+        Format::Curl => {
+            let (url, body) = chat_request(entry, "Hello", Some(64)).ok_or_else(|| anyhow!("{} has no chat API", entry.name))?;
+            let key_expr = match &key {
+                Key::Secret(name) => Some(secret_cmd(name)),
+                Key::Plain => Some(format!("${}", key_var(entry))),
+                Key::Unset => None,
+            };
+            let mut cmd = vec![format!("curl -s {}", shell_quote(&url)), "-H 'Content-Type: application/json'".to_string()];
+            if entry.ptype == "anthropic" {
+                if let Some(k) = key_expr { cmd.push(format!("-H \"x-api-key: {}\"", k)); }
+                cmd.push(format!("-H 'anthropic-version: {}'", ANTHROPIC_VERSION));
+            } else if let Some(k) = key_expr {
+                cmd.push(format!("-H \"Authorization: Bearer {}\"", k));
+            }
+            cmd.push(format!("-d {}", shell_quote(&body.to_string())));
+            if matches!(key, Key::Plain) { out.extend(key_hint(entry, &key)); }
+            out.push(cmd.join(" \\\n  "));
+        }
+    }
+    Ok(out.join("\n") + "\n")
+}
+
+/// Configure `x`: format list with a live preview of the selected one.
+#[derive(Clone, Debug)]
+pub struct ExportPicker {
+    pub provider_id: String,
+    pub selected: usize,
+    pub scroll: u16,
+}
+
+impl ExportPicker {
+    pub fn new(provider_id: &str) -> Self {
+        ExportPicker { provider_id: provider_id.to_string(), selected: 0, scroll: 0 }
+    }
+
+    pub fn format(&self) -> Format {
+        Format::ALL[self.selected.min(Format::ALL.len() - 1)]
+    }
+}
+
+#[cfg(all(test, unix))]
+mod tests {
+    use super::*;
+    use crate::config_cli::run_config;
+    use crate::providers::read_scratch_entries;
+    use crate::testing::{add_provider, FakeCli};
+
+    #[test]
+    fn providers_export_to_other_tools() {
+        let _fake = FakeCli::new();
+        run_config(add_provider("ollama", "home", &[("model", "llama3.2")], true)).expect("add provider");
+        run_config(add_provider("openai", "oa", &[("api_key", "sk-test"), ("model", "gpt-4o-mini")], false)).expect("add provider");
+        let entries = read_scratch_entries().expect("entries");
+        let home = entries.iter().find(|e| e.id == "home").expect("home");
+        let oa = entries.iter().find(|e| e.id == "oa").expect("oa");
+
+        let env = render(home, Format::Env).expect("env");
+        assert!(env.contains("\nOPENAI_BASE_URL=http://") && env.contains(":11434/v1\n"), "{}", env);
+        assert!(env.contains("OPENAI_API_KEY=not-needed\n"));
+        let aider = render(home, Format::Aider).expect("aider");
+        assert!(aider.contains("model: ollama_chat/llama3.2\n") && aider.contains("OLLAMA_API_BASE=http://"), "{}", aider);
+        assert!(render(home, Format::Continue).expect("continue").contains("    provider: ollama\n"));
+
+        // Keys stay out of every snippet; curl fetches the keychain secret
+        for fmt in Format::ALL {
+            assert!(!render(oa, fmt).expect("render").contains("sk-test"), "{:?}", fmt);
+        }
+        let curl = render(oa, Format::Curl).expect("curl");
+        assert!(curl.contains("https://api.openai.com/v1/chat/completions") && curl.contains("Bearer $(chi-tui secret "), "{}", curl);
+        assert!(render(oa, Format::Continue).expect("continue").contains("apiKey: \"${{ secrets.OPENAI_API_KEY }}\""));
+    }
+}
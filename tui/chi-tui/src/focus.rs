@@ -0,0 +1,148 @@
+//! Focus: which part of a page gets the keys. Pages with two panes (the
+//! Configure list and form, the README TOC and text, Backups' snapshots
+//! and providers) move focus with Tab and Shift+Tab, and the focused pane
+//! has a thick border with `▶` before its title. While a text field is
+//! being typed into, letters are text: global shortcuts (`q`, `t`, `1`…)
+//! are off until Enter or Esc ends the edit.
+
+use ratatui::style::{Modifier, Style};
+use ratatui::widgets::{Block, BorderType, Borders};
+
+use crate::app::{App, Page};
+use crate::theme::Theme;
+
+#[derive(Copy, Clone, Debug, PartialEq, Eq)]
+pub enum Focus {
+    /// The list that picks what the page shows: Welcome's menu,
+    /// Configure's providers, README's TOC, Backups' snapshots
+    Menu,
+    /// The page body or the detail pane
+    Content,
+    /// A text field being typed into
+    Field,
+}
+
+pub fn current(app: &App) -> Focus {
+    if editing(app) { return Focus::Field; }
+    let content = match app.page {
+        Page::Welcome => false,
+        Page::Configure => app.providers.as_ref().map_or(false, |st| st.focus_right),
+        Page::Readme => app.readme.as_ref().map_or(true, |rm| !(rm.show_toc && rm.focus_toc)),
+        Page::Backups => app.backups.as_ref().map_or(false, |st| st.focus_providers),
+        _ => true,
+    };
+    if content { Focus::Content } else { Focus::Menu }
+}
+
+/// A text field has the keys.
+pub fn editing(app: &App) -> bool {
+    match app.page {
+        Page::Configure => app.providers.as_ref().map_or(false, |st| {
+            st.paste_input.is_some() || (st.focus_right && st.form.as_ref().map_or(false, |f| f.editing))
+        }),
+        Page::ModelBrowser => app.model.as_ref().map_or(false, |m| m.searching),
+        Page::Settings => app.hf_token_input.is_some() || app.default_models.as_ref().map_or(false, |d| d.input.is_some()),
+        Page::Variables => app.variables.as_ref().map_or(false, |v| v.edit.is_some()),
+        Page::Welcome => app.profiles.as_ref().map_or(false, |p| p.name_input.is_some()),
+        _ => false,
+    }
+}
+
+/// Tab or Shift+Tab: focus the page's other pane (no page has more than
+/// two, so both directions switch). Returns false on single-pane pages.
+pub fn cycle(app: &mut App) -> bool {
+    match app.page {
+        Page::Configure => {
+            let Some(st) = app.providers.as_mut() else { return false };
+            if !st.focus_right {
+                // The add row opens a new provider's form
+                if st.is_add_row() { st.add_default(); }
+                if st.selected < st.entries.len() { crate::keys::ensure_form_for_selected(st); }
+            }
+            st.focus_right = !st.focus_right;
+            true
+        }
+        Page::Readme => match app.readme.as_mut() {
+            Some(rm) if rm.show_toc => {
+                rm.focus_toc = !rm.focus_toc;
+                true
+            }
+            _ => false,
+        },
+        Page::Backups => match app.backups.as_mut() {
+            Some(st) => {
+                st.focus_providers = !st.focus_providers;
+                true
+            }
+            None => false,
+        },
+        _ => false,
+    }
+}
+
+/// A pane's block: focused panes get a thick border in the selection
+/// color and `▶` before the title, so focus shows without color too.
+pub fn pane_block(theme: &Theme, title: &str, focused: bool) -> Block<'static> {
+    let block = Block::default().borders(Borders::ALL);
+    if focused {
+        block
+            .border_type(BorderType::Thick)
+            .border_style(Style::default().fg(theme.selected).add_modifier(Modifier::BOLD))
+            .title(format!("▶ {}", title))
+    } else {
+        block.border_style(Style::default().fg(theme.frame)).title(title.to_string())
+    }
+}
+
+#[cfg(all(test, unix))]
+mod tests {
+    use super::*;
+    use crate::config_cli::run_config;
+    use crate::testing::{add_provider, screen, FakeCli};
+
+    #[test]
+    fn tab_moves_focus_between_panes_and_fields_swallow_shortcuts() {
+        use crossterm::event::{KeyCode, KeyEvent, KeyModifiers};
+        let _fake = FakeCli::new();
+        run_config(add_provider("ollama", "home", &[], true)).expect("add provider");
+        let press = |app: &mut App, code: KeyCode| crate::keys::handle_key(app, KeyEvent::new(code, KeyModifiers::NONE));
+        let mut app = App::new(false);
+        crate::open_page_loaded(&mut app, Page::Configure);
+        assert_eq!(current(&app), Focus::Menu);
+        assert!(screen(&app, 100, 30).contains("▶ Configure Providers"));
+
+        press(&mut app, KeyCode::Tab);
+        assert_eq!(current(&app), Focus::Content);
+        let text = screen(&app, 100, 30);
+        assert!(text.contains("▶ Provider Details — ollama") && !text.contains("▶ Configure Providers"), "{}", text);
+
+        // While a field is edited, q/t/a/1 are text, not quit/theme/animation/README
+        if let Some(form) = app.providers.as_mut().and_then(|st| st.form.as_mut()) {
+            form.selected = 1;
+            form.editing = true;
+        }
+        assert_eq!(current(&app), Focus::Field);
+        for c in ['q', 't', 'a', '1'] { press(&mut app, KeyCode::Char(c)); }
+        assert!(!app.should_quit && app.shutdown.is_none());
+        assert_eq!((app.page, app.theme.name.as_str(), app.anim), (Page::Configure, "dark", true));
+        let form = app.providers.as_ref().and_then(|st| st.form.as_ref()).expect("form");
+        assert!(form.fields.iter().any(|f| f.buffer.contains("qta1")), "{:?}", form.fields.iter().map(|f| &f.buffer).collect::<Vec<_>>());
+        // Esc ends the edit and stays on the page
+        press(&mut app, KeyCode::Esc);
+        assert_eq!((app.page, current(&app)), (Page::Configure, Focus::Content));
+
+        crate::keys::handle_key(&mut app, KeyEvent::new(KeyCode::BackTab, KeyModifiers::SHIFT));
+        assert_eq!(current(&app), Focus::Menu);
+
+        // README: TOC and text
+        crate::open_page_loaded(&mut app, Page::Readme);
+        assert!(!cycle(&mut app), "no TOC, one pane");
+        press(&mut app, KeyCode::Char('h'));
+        press(&mut app, KeyCode::Tab);
+        assert_eq!(current(&app), Focus::Menu);
+        assert!(screen(&app, 100, 30).contains("▶ TOC"));
+        crate::keys::handle_key(&mut app, KeyEvent::new(KeyCode::BackTab, KeyModifiers::SHIFT));
+        assert_eq!(current(&app), Focus::Content);
+        assert!(screen(&app, 100, 30).contains("▶ README"));
+    }
+}
@@ -0,0 +1,96 @@
+//! Small fuzzy matcher for incremental list filters.
+//!
+//! A query is split on whitespace; every term must match (case-insensitive)
+//! either as a substring or as an in-order subsequence. Substring hits score
+//! higher, earlier and tighter hits score higher still.
+
+/// Score `term` against `hay` and return the matched char positions.
+/// `term` must already be lowercase.
+pub fn match_term(term: &str, hay: &str) -> Option<(i64, Vec<usize>)> {
+    let hay: Vec<char> = hay.to_lowercase().chars().collect();
+    let needle: Vec<char> = term.chars().collect();
+    if needle.is_empty() {
+        return Some((0, Vec::new()));
+    }
+    if let Some(start) = (0..hay.len()).find(|&i| hay[i..].starts_with(&needle)) {
+        let boundary = start == 0 || !hay[start - 1].is_alphanumeric();
+        let score = 1000 + needle.len() as i64 * 10 - start as i64 + if boundary { 50 } else { 0 };
+        return Some((score, (start..start + needle.len()).collect()));
+    }
+    let mut positions = Vec::with_capacity(needle.len());
+    let mut next = 0;
+    for c in &needle {
+        let pos = (next..hay.len()).find(|&i| hay[i] == *c)?;
+        positions.push(pos);
+        next = pos + 1;
+    }
+    let span = (positions[positions.len() - 1] - positions[0]) as i64;
+    Some((needle.len() as i64 * 10 - span - positions[0] as i64, positions))
+}
+
+/// Lowercased whitespace-separated terms of a query.
+pub fn terms(query: &str) -> Vec<String> {
+    query.split_whitespace().map(|t| t.to_lowercase()).collect()
+}
+
+/// Best score of each term over `fields`, summed; None if any term misses
+/// every field.
+pub fn score(terms: &[String], fields: &[&str]) -> Option<i64> {
+    let mut total = 0;
+    for t in terms {
+        total += fields.iter().filter_map(|f| match_term(t, f).map(|(s, _)| s)).max()?;
+    }
+    Some(total)
+}
+
+/// Char positions in `text` matched by any of `terms`, for highlighting.
+pub fn highlights(terms: &[String], text: &str) -> Vec<usize> {
+    let mut out: Vec<usize> = terms.iter().filter_map(|t| match_term(t, text)).flat_map(|(_, p)| p).collect();
+    out.sort_unstable();
+    out.dedup();
+    out
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+
+    #[test]
+    fn substrings_beat_subsequences_and_word_starts_beat_the_middle() {
+        let (sub, pos) = match_term("coder", "Qwen2.5-Coder-7B").expect("substring");
+        assert_eq!(pos, vec![8, 9, 10, 11, 12]);
+        let (seq, pos) = match_term("qc7", "Qwen2.5-Coder-7B").expect("subsequence");
+        assert_eq!(pos, vec![0, 8, 14]);
+        assert!(sub > seq);
+        let start = match_term("lla", "llama").expect("start").0;
+        let middle = match_term("lla", "tinyllama").expect("middle").0;
+        let word = match_term("lla", "tiny-llama").expect("word").0;
+        assert!(start > word && word > middle);
+        assert_eq!(match_term("xyz", "llama"), None);
+        assert_eq!(match_term("", "llama"), Some((0, Vec::new())));
+    }
+
+    #[test]
+    fn tighter_subsequences_score_higher() {
+        let tight = match_term("pm", "phi-mini").expect("tight").0;
+        let loose = match_term("pm", "phi-3-medium").expect("loose").0;
+        assert!(tight > loose);
+    }
+
+    #[test]
+    fn every_term_must_match_some_field() {
+        let t = terms("  Qwen  CODER ");
+        assert_eq!(t, vec!["qwen".to_string(), "coder".to_string()]);
+        assert!(score(&t, &["qwen2.5-coder-7b"]).is_some());
+        assert!(score(&t, &["qwen2.5", "tags: coder"]).is_some());
+        assert_eq!(score(&t, &["qwen2.5", "instruct"]), None);
+        assert_eq!(score(&[], &["anything"]), Some(0));
+    }
+
+    #[test]
+    fn highlights_merge_the_positions_of_all_terms() {
+        let t = terms("ab bc");
+        assert_eq!(highlights(&t, "abc"), vec![0, 1, 2]);
+        assert_eq!(highlights(&terms("zz"), "abc"), Vec::<usize>::new());
+    }
+}
@@ -0,0 +1,282 @@
+use std::io::ErrorKind;
+use std::net::{SocketAddr, TcpStream, ToSocketAddrs};
+use std::time::{Duration, Instant};
+
+use anyhow::{anyhow, Result};
+use serde_json::Value;
+
+use crate::providers::ProviderScratchEntry;
+
+/// Network endpoint (host, port) a provider talks to; None for local types.
+pub fn endpoint_of(entry: &ProviderScratchEntry) -> Option<(String, u16)> {
+    let cfg = &entry.config;
+    let host_port = |default_port: u16| {
+        let host = cfg.get("host").and_then(|v| v.as_str()).filter(|s| !s.is_empty()).unwrap_or("127.0.0.1").to_string();
+        let port = cfg
+            .get("port")
+            .and_then(|v| v.as_u64().or_else(|| v.as_str().and_then(|s| s.parse().ok())))
+            .map(|p| p as u16)
+            .unwrap_or(default_port);
+        Some((host, port))
+    };
+    match entry.ptype.as_str() {
+        "lmstudio" => host_port(1234),
+        "ollama" => host_port(11434),
+        "openai" => Some(parse_base_url(cfg.get("base_url").and_then(|v| v.as_str()).unwrap_or("https://api.openai.com"))),
+        "anthropic" => Some(parse_base_url(cfg.get("base_url").and_then(|v| v.as_str()).unwrap_or("https://api.anthropic.com"))),
+        _ => cfg.get("base_url").and_then(|v| v.as_str()).map(parse_base_url),
+    }
+}
+
+/// Split `scheme://host[:port]/path` into host and port (scheme default).
+pub fn parse_base_url(url: &str) -> (String, u16) {
+    let (scheme, rest) = match url.split_once("://") {
+        Some((s, r)) => (s.to_lowercase(), r),
+        None => ("http".to_string(), url),
+    };
+    let authority = rest.split('/').next().unwrap_or(rest);
+    let default_port = if scheme == "https" { 443 } else { 80 };
+    match authority.rsplit_once(':') {
+        Some((h, p)) if p.chars().all(|c| c.is_ascii_digit()) && !p.is_empty() => {
+            (h.to_string(), p.parse().unwrap_or(default_port))
+        }
+        _ => (authority.to_string(), default_port),
+    }
+}
+
+/// Host name resolution failed; kept as a type so it classifies as `Dns`.
+#[derive(Debug)]
+pub struct DnsError(pub String);
+
+impl std::fmt::Display for DnsError {
+    fn fmt(&self, f: &mut std::fmt::Formatter<'_>) -> std::fmt::Result {
+        write!(f, "cannot resolve {}", self.0)
+    }
+}
+
+impl std::error::Error for DnsError {}
+
+/// First address of `host:port`; failures are `DnsError`.
+pub fn resolve(host: &str, port: u16) -> Result<SocketAddr> {
+    match (host, port).to_socket_addrs() {
+        Ok(mut addrs) => addrs.next().ok_or_else(|| DnsError(host.to_string()).into()),
+        Err(e) => Err(anyhow::Error::new(DnsError(format!("{}: {}", host, e)))),
+    }
+}
+
+/// Machine-readable class of a failed connection check, for exports and for
+/// UI decisions that must not depend on error wording.
+#[derive(Copy, Clone, Debug, PartialEq, Eq)]
+pub enum ErrorCode {
+    Timeout,
+    Refused,
+    Dns,
+    Auth,
+    BadResponse,
+    Other,
+}
+
+impl ErrorCode {
+    pub fn key(self) -> &'static str {
+        match self {
+            ErrorCode::Timeout => "timeout",
+            ErrorCode::Refused => "refused",
+            ErrorCode::Dns => "dns",
+            ErrorCode::Auth => "auth",
+            ErrorCode::BadResponse => "bad-response",
+            ErrorCode::Other => "other",
+        }
+    }
+
+    /// None for 2xx; 401/403 are auth failures, anything else a bad response.
+    pub fn from_http(status: u16) -> Option<Self> {
+        match status {
+            200..=299 => None,
+            401 | 403 => Some(ErrorCode::Auth),
+            _ => Some(ErrorCode::BadResponse),
+        }
+    }
+
+    /// Classify an error by the typed causes in its chain (io, reqwest,
+    /// `DnsError`). reqwest reports DNS failures only as text, hence the
+    /// fallback on the message.
+    pub fn classify(err: &anyhow::Error) -> Self {
+        for cause in err.chain() {
+            if cause.downcast_ref::<DnsError>().is_some() { return ErrorCode::Dns; }
+            if let Some(e) = cause.downcast_ref::<std::io::Error>() {
+                match e.kind() {
+                    ErrorKind::TimedOut | ErrorKind::WouldBlock => return ErrorCode::Timeout,
+                    ErrorKind::ConnectionRefused => return ErrorCode::Refused,
+                    _ => {}
+                }
+            }
+            if let Some(e) = cause.downcast_ref::<reqwest::Error>() {
+                if e.is_timeout() { return ErrorCode::Timeout; }
+                if let Some(code) = e.status().and_then(|s| ErrorCode::from_http(s.as_u16())) { return code; }
+                if e.is_decode() || e.is_body() { return ErrorCode::BadResponse; }
+            }
+        }
+        let text = format!("{:#}", err).to_lowercase();
+        if text.contains("dns error") || text.contains("failed to lookup address") { return ErrorCode::Dns; }
+        if text.contains("connection refused") { return ErrorCode::Refused; }
+        if text.contains("timed out") { return ErrorCode::Timeout; }
+        ErrorCode::Other
+    }
+}
+
+/// Outcome of one connection check.
+#[derive(Clone, Debug, Default)]
+pub struct ConnectionStatus {
+    pub provider_id: String,
+    /// What was attempted: `tcp://host:port` or the request URL
+    pub endpoint: String,
+    pub latency: Option<Duration>,
+    pub http_status: Option<u16>,
+    pub code: Option<ErrorCode>,
+    pub message: Option<String>,
+}
+
+impl ConnectionStatus {
+    pub fn ok(&self) -> bool {
+        self.code.is_none()
+    }
+
+    pub fn fail(&mut self, err: &anyhow::Error) {
+        self.code = Some(ErrorCode::classify(err));
+        self.message = Some(format!("{:#}", err));
+    }
+
+    /// "refused: Connection refused (os error 111)"
+    pub fn error_label(&self) -> Option<String> {
+        let code = self.code?;
+        Some(match &self.message {
+            Some(m) => format!("{}: {}", code.key(), m),
+            None => code.key().to_string(),
+        })
+    }
+
+    pub fn to_json(&self) -> Value {
+        serde_json::json!({
+            "provider_id": self.provider_id,
+            "endpoint": self.endpoint,
+            "ok": self.ok(),
+            "latency_ms": self.latency.map(|d| d.as_secs_f64() * 1000.0),
+            "http_status": self.http_status,
+            "error_code": self.code.map(|c| c.key()),
+            "error": self.message,
+        })
+    }
+}
+
+/// TCP round trip to a provider endpoint as a `ConnectionStatus`.
+pub fn check_tcp(provider_id: &str, host: &str, port: u16, timeout: Duration) -> ConnectionStatus {
+    let mut st = ConnectionStatus { provider_id: provider_id.to_string(), endpoint: format!("tcp://{}:{}", host, port), ..Default::default() };
+    match tcp_rtt(host, port, timeout) {
+        Ok(d) => st.latency = Some(d),
+        Err(e) => st.fail(&e),
+    }
+    st
+}
+
+/// Round-trip estimate: best of three TCP connects to the provider endpoint.
+pub fn tcp_rtt(host: &str, port: u16, timeout: Duration) -> Result<Duration> {
+    if crate::mock::enabled() { return crate::mock::tcp_rtt(host, port); }
+    let addr = resolve(host, port)?;
+    let mut best: Option<Duration> = None;
+    let mut last_err = None;
+    for _ in 0..3 {
+        let start = Instant::now();
+        match TcpStream::connect_timeout(&addr, timeout) {
+            Ok(_) => {
+                let d = start.elapsed();
+                best = Some(best.map_or(d, |b| b.min(d)));
+            }
+            Err(e) => last_err = Some(e),
+        }
+    }
+    match (best, last_err) {
+        (Some(d), _) => Ok(d),
+        (None, Some(e)) => Err(e.into()),
+        (None, None) => Err(anyhow!("no attempts")),
+    }
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+
+    #[test]
+    fn base_urls_split_into_host_and_port() {
+        assert_eq!(parse_base_url("https://api.openai.com/v1"), ("api.openai.com".to_string(), 443));
+        assert_eq!(parse_base_url("http://gpu-box:8000/v1"), ("gpu-box".to_string(), 8000));
+        assert_eq!(parse_base_url("HTTP://host"), ("host".to_string(), 80));
+        assert_eq!(parse_base_url("10.0.0.5:1234"), ("10.0.0.5".to_string(), 1234));
+    }
+
+    #[test]
+    fn endpoints_use_the_type_default_port() {
+        let entry = |ptype: &str, config: Value| ProviderScratchEntry {
+            id: "p".into(),
+            name: "p".into(),
+            ptype: ptype.into(),
+            tags: Vec::new(),
+            config,
+            extra: Default::default(),
+            scope: Default::default(),
+        };
+        assert_eq!(endpoint_of(&entry("ollama", serde_json::json!({}))), Some(("127.0.0.1".to_string(), 11434)));
+        assert_eq!(endpoint_of(&entry("lmstudio", serde_json::json!({"host": "box", "port": "4321"}))), Some(("box".to_string(), 4321)));
+        assert_eq!(endpoint_of(&entry("anthropic", serde_json::json!({}))), Some(("api.anthropic.com".to_string(), 443)));
+        assert_eq!(endpoint_of(&entry("local", serde_json::json!({}))), None);
+    }
+
+    #[test]
+    fn errors_are_classified_by_cause() {
+        let io = |kind| anyhow::Error::new(std::io::Error::new(kind, "x"));
+        assert_eq!(ErrorCode::classify(&io(ErrorKind::ConnectionRefused)), ErrorCode::Refused);
+        assert_eq!(ErrorCode::classify(&io(ErrorKind::TimedOut)), ErrorCode::Timeout);
+        assert_eq!(ErrorCode::classify(&anyhow::Error::new(DnsError("nowhere".into()))), ErrorCode::Dns);
+        assert_eq!(ErrorCode::classify(&anyhow!("something else")), ErrorCode::Other);
+        assert_eq!(ErrorCode::from_http(403), Some(ErrorCode::Auth));
+        assert_eq!(ErrorCode::from_http(204), None);
+    }
+
+    #[test]
+    fn text_and_wrapped_causes_classify_too() {
+        let wrapped = anyhow::Error::new(std::io::Error::new(ErrorKind::ConnectionRefused, "x")).context("connecting to box");
+        assert_eq!(ErrorCode::classify(&wrapped), ErrorCode::Refused);
+        assert_eq!(ErrorCode::classify(&anyhow!("error sending request: dns error: no record")), ErrorCode::Dns);
+        assert_eq!(ErrorCode::classify(&anyhow!("operation timed out")), ErrorCode::Timeout);
+        assert_eq!(ErrorCode::from_http(500), Some(ErrorCode::BadResponse));
+        assert_eq!(resolve("no-such-host.invalid", 80).map(|_| ()).map_err(|e| ErrorCode::classify(&e)), Err(ErrorCode::Dns));
+    }
+
+    #[test]
+    fn status_json_carries_the_error_code() {
+        let mut st = ConnectionStatus { provider_id: "box".into(), endpoint: "tcp://box:11434".into(), ..Default::default() };
+        assert!(st.ok() && st.error_label().is_none());
+        st.fail(&anyhow::Error::new(DnsError("box".into())));
+        assert_eq!(st.error_label().as_deref(), Some("dns: cannot resolve box"));
+        assert_eq!(
+            st.to_json(),
+            serde_json::json!({"provider_id": "box", "endpoint": "tcp://box:11434", "ok": false, "latency_ms": null, "http_status": null, "error_code": "dns", "error": "cannot resolve box"})
+        );
+        let ok = ConnectionStatus { latency: Some(Duration::from_millis(500)), http_status: Some(200), ..Default::default() };
+        assert_eq!((ok.to_json()["ok"].clone(), ok.to_json()["latency_ms"].clone()), (Value::Bool(true), serde_json::json!(500.0)));
+        assert_eq!(ConnectionStatus { code: Some(ErrorCode::Auth), ..Default::default() }.error_label().as_deref(), Some("auth"));
+    }
+
+    #[cfg(unix)]
+    #[test]
+    fn tcp_checks_report_latency_or_the_failure() {
+        let _fake = crate::testing::FakeCli::new();
+        let listener = std::net::TcpListener::bind("127.0.0.1:0").expect("listen");
+        let port = listener.local_addr().expect("addr").port();
+        let up = check_tcp("up", "127.0.0.1", port, Duration::from_secs(2));
+        assert!(up.ok() && up.latency.is_some());
+        assert_eq!(up.endpoint, format!("tcp://127.0.0.1:{}", port));
+        drop(listener);
+        let down = check_tcp("down", "127.0.0.1", port, Duration::from_secs(2));
+        assert_eq!((down.code, down.latency), (Some(ErrorCode::Refused), None));
+    }
+}
@@ -0,0 +1,281 @@
+use std::sync::mpsc::{channel, Receiver};
+use std::thread;
+use std::time::{Duration, Instant};
+
+use crate::health::{check_tcp, endpoint_of, tcp_rtt};
+use crate::locale;
+use crate::log;
+use crate::providers::{read_scratch_entries, ProviderScratchEntry};
+use crate::store;
+
+/// How often the default provider is probed.
+const CHECK_EVERY: Duration = Duration::from_secs(30);
+/// Failing at least this long (and `MIN_FAILURES` checks) triggers a suggestion.
+const FAILING_FOR: Duration = Duration::from_secs(120);
+const MIN_FAILURES: u32 = 3;
+const PROBE_TIMEOUT: Duration = Duration::from_secs(2);
+
+/// Proposed switch of `default_provider_id`.
+#[derive(Clone, Debug)]
+pub struct Suggestion {
+    pub from: String,
+    pub to: String,
+    pub to_name: String,
+    /// Why this candidate, e.g. "fallback chain #2" or "12 ms"
+    pub reason: String,
+}
+
+#[derive(Debug)]
+struct Probe {
+    default_id: String,
+    error: Option<String>,
+    best: Option<Suggestion>,
+}
+
+/// Candidate health: network providers by TCP round trip; local types have no
+/// endpoint and count as reachable, ranked after measured ones.
+fn health(entry: &ProviderScratchEntry) -> Result<Duration, String> {
+    match endpoint_of(entry) {
+        Some((host, port)) => tcp_rtt(&host, port, PROBE_TIMEOUT).map_err(|e| e.to_string()),
+        None => Ok(Duration::MAX),
+    }
+}
+
+/// One check, run off the UI thread. Alternatives are measured only when the
+/// default fails. With `fallback_chain` in chi.tmp.json the first healthy
+/// entry in chain order wins; otherwise the fastest healthy provider.
+fn run_probe() -> Option<Probe> {
+    let root = store::read_layered().ok()?;
+    let default_id = root.get("default_provider_id").and_then(|v| v.as_str())?.to_string();
+    let entries = read_scratch_entries().ok()?;
+    let current = entries.iter().find(|e| e.id == default_id)?;
+    // Only network defaults can go unhealthy
+    let (host, port) = endpoint_of(current)?;
+    let error = check_tcp(&default_id, &host, port, PROBE_TIMEOUT).error_label();
+    let mut best = None;
+    if error.is_some() {
+        let chain: Vec<String> = root
+            .get("fallback_chain")
+            .and_then(|v| v.as_array())
+            .map(|a| a.iter().filter_map(|x| x.as_str().map(|s| s.to_string())).collect())
+            .unwrap_or_default();
+        let suggest = |e: &ProviderScratchEntry, reason: String| Suggestion { from: default_id.clone(), to: e.id.clone(), to_name: e.name.clone(), reason };
+        if chain.is_empty() {
+            let measured: Vec<(&ProviderScratchEntry, Duration)> = entries
+                .iter()
+                .filter(|e| e.id != default_id)
+                .filter_map(|e| health(e).ok().map(|d| (e, d)))
+                .collect();
+            best = measured.into_iter().min_by_key(|(_, d)| *d).map(|(e, d)| {
+                let reason = if d == Duration::MAX { "local, no network check".to_string() } else { format!("{} ms", d.as_millis()) };
+                suggest(e, reason)
+            });
+        } else {
+            best = chain
+                .iter()
+                .enumerate()
+                .filter(|(_, id)| **id != default_id)
+                .filter_map(|(i, id)| entries.iter().find(|e| &e.id == id).map(|e| (i, e)))
+                .find(|(_, e)| health(e).is_ok())
+                .map(|(i, e)| suggest(e, format!("fallback chain #{}", i + 1)));
+        }
+    }
+    Some(Probe { default_id, error, best })
+}
+
+/// Background health watch of the default provider.
+#[derive(Default)]
+pub struct DefaultWatch {
+    rx: Option<Receiver<Option<Probe>>>,
+    last_check: Option<Instant>,
+    watching: Option<String>,
+    failing_since: Option<Instant>,
+    failures: u32,
+    last_error: Option<String>,
+    /// Default id whose suggestion was dismissed; cleared when it recovers
+    dismissed: Option<String>,
+    pub suggestion: Option<Suggestion>,
+}
+
+impl DefaultWatch {
+    /// Start a check when due and collect finished ones. Returns true when
+    /// the suggestion appeared or changed.
+    pub fn poll(&mut self) -> bool {
+        if let Some(rx) = &self.rx {
+            match rx.try_recv() {
+                Ok(probe) => {
+                    self.rx = None;
+                    return self.apply(probe);
+                }
+                Err(std::sync::mpsc::TryRecvError::Empty) => return false,
+                Err(std::sync::mpsc::TryRecvError::Disconnected) => self.rx = None,
+            }
+        }
+        if self.last_check.map_or(true, |t| t.elapsed() >= CHECK_EVERY) {
+            self.last_check = Some(Instant::now());
+            let (tx, rx) = channel();
+            thread::spawn(move || {
+                let _ = tx.send(run_probe());
+            });
+            self.rx = Some(rx);
+        }
+        false
+    }
+
+    fn apply(&mut self, probe: Option<Probe>) -> bool {
+        let Some(probe) = probe else {
+            // No network default configured: nothing to watch
+            self.reset(None);
+            return self.suggestion.take().is_some();
+        };
+        if self.watching.as_deref() != Some(probe.default_id.as_str()) { self.reset(Some(probe.default_id.clone())); }
+        match probe.error {
+            None => {
+                if self.failures > 0 { log::info(&format!("default provider {} healthy again", probe.default_id)); }
+                self.reset(Some(probe.default_id));
+                self.suggestion.take().is_some()
+            }
+            Some(err) => {
+                self.failures += 1;
+                let since = *self.failing_since.get_or_insert_with(Instant::now);
+                if self.failures == 1 { log::warn(&format!("default provider {} health check failed: {}", probe.default_id, err)); }
+                self.last_error = Some(err);
+                let due = self.failures >= MIN_FAILURES && since.elapsed() >= FAILING_FOR;
+                if !due || self.dismissed.as_deref() == Some(probe.default_id.as_str()) { return false; }
+                let changed = probe.best.as_ref().map(|b| &b.to) != self.suggestion.as_ref().map(|s| &s.to);
+                self.suggestion = probe.best;
+                changed
+            }
+        }
+    }
+
+    fn reset(&mut self, watching: Option<String>) {
+        self.watching = watching;
+        self.failing_since = None;
+        self.failures = 0;
+        self.last_error = None;
+        self.dismissed = None;
+    }
+
+    /// Hide the suggestion until the current default recovers or changes.
+    pub fn dismiss(&mut self) {
+        if let Some(s) = self.suggestion.take() { self.dismissed = Some(s.from); }
+    }
+
+    /// Forget the failure history, e.g. after the default was switched.
+    pub fn accepted(&mut self) {
+        self.suggestion = None;
+        self.reset(None);
+        self.last_check = None;
+    }
+
+    /// "failing for 3 minutes (connection refused)" for the suggestion banner.
+    pub fn failing_label(&self) -> String {
+        let span = locale::duration(self.failing_since.map_or(Duration::ZERO, |t| t.elapsed()));
+        format!("failing for {}{}", span, self.last_error.as_ref().map(|e| format!(" ({})", e)).unwrap_or_default())
+    }
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+
+    fn failing(best: Option<&str>) -> Option<Probe> {
+        let best = best.map(|to| Suggestion { from: "box".to_string(), to: to.to_string(), to_name: to.to_string(), reason: "12 ms".to_string() });
+        Some(Probe { default_id: "box".to_string(), error: Some("connection refused".to_string()), best })
+    }
+
+    fn healthy() -> Option<Probe> {
+        Some(Probe { default_id: "box".to_string(), error: None, best: None })
+    }
+
+    /// Pretend the default has been failing for longer than `FAILING_FOR`.
+    fn age(w: &mut DefaultWatch) {
+        w.failing_since = Instant::now().checked_sub(FAILING_FOR + Duration::from_secs(1));
+    }
+
+    #[test]
+    fn a_suggestion_needs_repeated_failures_over_time() {
+        let mut w = DefaultWatch::default();
+        assert!(!w.apply(failing(Some("spare"))));
+        assert!(!w.apply(failing(Some("spare"))));
+        age(&mut w);
+        assert!(w.apply(failing(Some("spare"))));
+        assert_eq!(w.suggestion.as_ref().map(|s| s.to.as_str()), Some("spare"));
+        assert!(w.failing_label().starts_with("failing for ") && w.failing_label().ends_with(" (connection refused)"));
+        assert!(!w.apply(failing(Some("spare"))), "same suggestion again is no change");
+        assert!(w.apply(failing(Some("other"))));
+    }
+
+    #[test]
+    fn recovery_and_a_new_default_reset_the_history() {
+        let mut w = DefaultWatch::default();
+        w.apply(failing(Some("spare")));
+        w.apply(failing(Some("spare")));
+        age(&mut w);
+        assert!(w.apply(failing(Some("spare"))));
+        assert!(w.apply(healthy()), "recovery clears the suggestion");
+        assert!(w.suggestion.is_none() && w.failures == 0 && w.failing_since.is_none());
+
+        w.apply(failing(None));
+        let other = Probe { default_id: "other".to_string(), error: Some("timeout".to_string()), best: None };
+        w.apply(Some(other));
+        assert_eq!((w.watching.as_deref(), w.failures), (Some("other"), 1));
+        assert!(!w.apply(None));
+        assert!(w.watching.is_none() && w.failures == 0);
+    }
+
+    #[test]
+    fn a_dismissed_suggestion_stays_hidden_until_the_default_recovers() {
+        let mut w = DefaultWatch::default();
+        for _ in 0..MIN_FAILURES { w.apply(failing(Some("spare"))); }
+        age(&mut w);
+        assert!(w.apply(failing(Some("spare"))));
+        w.dismiss();
+        assert!(w.suggestion.is_none());
+        assert!(!w.apply(failing(Some("spare"))));
+        assert!(w.suggestion.is_none());
+        w.apply(healthy());
+        assert!(w.dismissed.is_none());
+        w.accepted();
+        assert!(w.last_check.is_none() && w.watching.is_none());
+    }
+
+    #[cfg(unix)]
+    #[test]
+    fn probes_suggest_the_fastest_provider_or_the_first_healthy_one_in_the_chain() {
+        let _fake = crate::testing::FakeCli::new();
+        let up = std::net::TcpListener::bind("127.0.0.1:0").expect("listen");
+        let up_port = up.local_addr().expect("addr").port();
+        let down_port = std::net::TcpListener::bind("127.0.0.1:0").expect("listen").local_addr().expect("addr").port();
+        let mut root = serde_json::json!({
+            "providers": [
+                {"id": "box", "name": "Box", "type": "ollama", "config": {"host": "127.0.0.1", "port": down_port}},
+                {"id": "built-in", "name": "Built-in", "type": "local", "config": {}},
+                {"id": "spare", "name": "Spare", "type": "ollama", "config": {"host": "127.0.0.1", "port": up_port}},
+            ],
+            "default_provider_id": "box",
+        });
+        store::write(&root).expect("store");
+        let probe = run_probe().expect("probe");
+        assert!(probe.error.is_some());
+        let best = probe.best.expect("suggestion");
+        assert_eq!(best.to, "spare");
+        assert!(best.reason.ends_with(" ms"), "{}", best.reason);
+
+        root["fallback_chain"] = serde_json::json!(["box", "built-in", "spare"]);
+        store::write(&root).expect("store");
+        let best = run_probe().expect("probe").best.expect("suggestion");
+        assert_eq!((best.to.as_str(), best.reason.as_str()), ("built-in", "fallback chain #2"));
+
+        // A healthy default needs no alternatives
+        root["default_provider_id"] = serde_json::json!("spare");
+        store::write(&root).expect("store");
+        let probe = run_probe().expect("probe");
+        assert!(probe.error.is_none() && probe.best.is_none());
+        // A local default has no endpoint and is not watched
+        root["default_provider_id"] = serde_json::json!("built-in");
+        store::write(&root).expect("store");
+        assert!(run_probe().is_none());
+    }
+}
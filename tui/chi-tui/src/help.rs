@@ -0,0 +1,156 @@
+//! Key help: the `?` overlay and the one-line hint in the footer.
+
+use ratatui::layout::Alignment;
+use ratatui::prelude::Frame;
+use ratatui::style::{Modifier, Style};
+use ratatui::text::{Line, Span};
+use ratatui::widgets::{Block, Borders, Clear, Paragraph, Wrap};
+
+use crate::app::{App, Page};
+use crate::util::overlay_rect;
+use crate::{build, focus, menu};
+
+/// Keys that work on every page, before the page shortcuts.
+const GLOBAL: &str = "Up/Down: navigate • Enter: select • Esc: back • q: quit (asks while downloads/server run: w wait • c cancel • d detach downloads) • Ctrl+C: quit now";
+
+/// After the page shortcuts: the other global keys, focus and mouse.
+const GENERAL: &[&str] = &[
+    "?: help overlay • t: theme • a: animation",
+    "Focus: Tab/Shift+Tab move between a page's panes (Configure list/form, README TOC/text, Backups snapshots/providers); \
+     the focused one has a thick border and ▶ • while a field is being edited, keys are text and global shortcuts are off until Enter or Esc",
+    "Mouse: click a menu item, page bar entry, button or TOC entry to open it • click a provider or field to select it, \
+     click again to open or edit • the wheel moves like ↑/↓",
+];
+
+/// One entry per page, in menu order where it matters.
+const PAGES: &[&str] = &[
+    "Diagnostics: e export • b support bundle (zip of summary, versions, doctor report, logs and configs, secrets redacted; \
+     also `chi-tui --support-bundle`) • r refresh",
+    "Model Browser: table of name, size, context, RAM, tags and status • Enter sets the model on the provider selected in Configure; \
+     Shift+Enter (Alt+Enter in terminals that do not report Shift) also validates and saves it • \
+     s cycles the sort column (then back to catalog order), S flips ascending/descending; models without a value sort last • \
+     / fuzzy search (id/name/tags, e.g. \"qwen 7b\") • d download (progress inline) • x cancel download • r downloaded-only • \
+     f cycle tag • i info; for models installed on a configured Ollama server, i loads /api/show: parameter size, quantization, \
+     family, context length and template • LM Studio models show [loaded]/[not loaded] (from /api/v0/models) and l asks \
+     LM Studio to load one now • catalog models: i also shows the license and whether the repo is gated (from the catalog or \
+     the Hub); downloading a gated model without a Hugging Face token asks first • h searches the Hugging Face Hub for GGUF \
+     repos matching the / filter and adds them (tag hf; the token is sent, so gated and private repos you can access are \
+     included) • F5 lists again, skipping the discovery cache (catalog, schema, server model lists are otherwise reused for \
+     a few minutes)",
+    "Configure: Tab/Shift+Tab • ↑/↓ field • Enter edit/Test/Save/Cancel • ←/→/Home/End • Del/Backspace • \
+     −/+ or ←/→ step numeric fields • t test connection • T deep test: stream a short completion from the model, showing \
+     time to first token, total time and the reply • e add a session provider: kept in memory only, usable in the \
+     Playground, Model Browser and benchmarks until you quit • g move the provider between the global list and this \
+     project (saved with s); on a session provider, save it to this project now • i HTTP inspector (last test call) • \
+     f auto-detect local servers (Ollama, LM Studio, vLLM, llama.cpp…) • y copy provider JSON (no secrets) • \
+     p import from clipboard • P paste/type JSON • x export as .env vars, aider or continue.dev config, or a curl call \
+     (no secrets) • c clone the provider (new id, \"(copy)\" name) • C QR code (no secrets) • u after the first provider \
+     was saved and made the default automatically: undo that",
+    "README: Up/Down/PgUp/PgDn scroll • n/p next/previous heading • h TOC (opens at the section being read) • \
+     Tab switch TOC/Content • Enter jump • l or Enter in the text: links to other .md files (Esc returns to the previous document)",
+    "Build: g toggle Project/Global • Enter write • e write .env/.envrc for the default provider • \
+     u use global here (moves the project config to .bak) • p pin globally (copies the project config into the global one) • \
+     m merge tool: every field where project and global differ, side by side; pick the winner per field and write it to \
+     the project config • without providers or a default, Build lists what is missing instead: c Configure Providers, \
+     f find local servers, d Select Default",
+    "Build conflicts: an existing project config that differs opens old vs new side by side • o overwrite • \
+     m merge (keys only on disk are kept) • Tab preview merge • Esc cancel",
+    "Confirmations (delete provider, quit with unsaved changes): y/Enter confirm • n/Esc cancel",
+    SETTINGS,
+    "Audit Log: r reload • e export",
+    "Model Cache: Del/x delete file (press twice) • r rescan",
+    "API Server: Enter start/stop chi-llm serve • ←/→ backing provider • Ctrl+T generate token • Ctrl+V show token",
+    "Variables: {{ name }} in provider fields • Enter edit • n new • d delete (env vars of the same name are a fallback)",
+    "Latency Map: r re-measure • Enter set default",
+    "Provider Status: r refresh now • i cycle auto-refresh (off/10 s/30 s/1 min/5 min) • Enter set default",
+    "Recommend for this machine: RAM from /proc/meminfo (sysctl on macOS) and VRAM from nvidia-smi; catalog models \
+     sorted by fit, largest that fits first, each with the reason • Enter open in Model Browser • r re-read memory",
+    "Logs: the tail of ~/.cache/chi_llm/chi-llm.log (every chi-llm run, discovery errors with the reason; \
+     CHI_LLM_LOG_FILE moves it, off disables it) or chi-tui.log • f follow new lines • \
+     l show all / warnings and errors / errors • Tab switch log • y copy the shown lines",
+    "Benchmark: Tab/←/→ pick a provider • Enter streams 3 standard prompts from its model and records tokens/sec, \
+     median time to first token and the slowest reply • history in ~/.cache/chi_llm/benchmarks.json, \
+     best tok/s per model starred • d delete a run",
+    "Playground: Enter run (cached) • F5/Ctrl+R force re-run • Tab provider • F2 inspector",
+    "Backups: Tab focus • Enter restore provider • A restore all • n snapshot now",
+    "Select Default: the provider under the cursor is tested in the background (results kept 1 min) • \
+     Enter on one that is unreachable or lacks its model asks first",
+    "Default health: when the default provider keeps failing, a banner offers the healthiest alternative \
+     (or next in fallback_chain) • Ctrl+Y switch • Ctrl+N keep",
+    "Welcome: Up/Down + Enter to open a section • p profiles: switch between named provider sets (work, home, offline…), \
+     save the current one with n",
+];
+
+/// Settings: what is kept for all projects, then what is per project.
+const SETTINGS: &str = "Settings: saved for all projects in ~/.config/chi_llm/tui.json: \
+    t theme picker (live preview; custom themes in ~/.config/chi_llm/themes) • a animation • \
+    w Build default target project/global • o discovery timeout • l cache lifetime of catalogs and model listings • \
+    c color-blind palette • per project: d density compact/comfortable • p prefer private providers (sorts local/LAN first) • \
+    e regenerate .env/.envrc on save • f config format json/yaml • \
+    h Hugging Face token (stored in the keychain or encrypted secrets file; HF_TOKEN wins when set; \
+    sent with Hub searches and downloads, and checked on the Diagnostics page) • \
+    m default model per provider type, e.g. openai gpt-4o-mini: kept in the global config and pre-filled into \
+    new providers in every project (form and `config add`)";
+
+pub fn draw_help_overlay(f: &mut Frame, app: &App) {
+    let area = overlay_rect(app.compact, 70, 60, f.size());
+    let mut lines = vec![
+        Line::from(Span::styled("Global keys:", Style::default().fg(app.theme.primary).add_modifier(Modifier::BOLD))),
+        Line::from(GLOBAL),
+        Line::from(menu::shortcuts_help()),
+    ];
+    lines.extend(GENERAL.iter().chain(PAGES).map(|l| Line::from(*l)));
+    lines.push(Line::from("—").style(Style::default().fg(app.theme.frame)));
+    lines.push(Line::from("This is a scaffold. Pages will be implemented in tasks 003–009."));
+    let block = Block::default().title("Help").borders(Borders::ALL).border_style(Style::default().fg(app.theme.frame));
+    let content = Paragraph::new(lines).style(Style::default().bg(app.theme.bg).fg(app.theme.fg)).alignment(Alignment::Left).wrap(Wrap { trim: true }).block(block);
+    f.render_widget(Clear, area);
+    f.render_widget(content, area);
+}
+
+/// The footer line: the keys of the page, or of the input or overlay open on it.
+pub fn footer_hint(app: &App) -> String {
+    let hint = match app.page {
+        Page::Welcome if app.profiles.as_ref().map_or(false, |p| p.name_input.is_some()) => "type a profile name • Enter save • Esc cancel",
+        Page::Welcome if app.profiles.is_some() => "Up/Down select • Enter switch to profile • n save current providers as… • d delete • Esc/p close",
+        Page::Diagnostics => "Esc: back • q: quit • e: export • b: support bundle • r: refresh • ?: help",
+        Page::Readme if app.readme.as_ref().map_or(false, |rm| rm.link_picker.is_some()) => "↑/↓ select • Enter open the document • Esc/l close",
+        Page::Readme => "Up/Down scroll • PgUp/PgDn • n/p next/previous heading • h TOC • Tab switch TOC/Content • Enter jump (TOC) or links (text) • l links • Esc back",
+        Page::ModelBrowser if app.model.as_ref().map_or(false, |m| m.searching) => "type to filter (id/name/tags) • ↑/↓ select • Enter keep filter • Ctrl+U clear • Esc clear & close",
+        Page::ModelBrowser => "Up/Down select • Enter choose • Shift+Enter choose and save • / search • s sort column • S sort direction • \
+            d download • x cancel • r downloaded-only • f tag filter • i info (Ollama: /api/show details) • l load in LM Studio • \
+            h search the Hub for the filter • F5 refresh (skip the cache) • Esc back",
+        Page::Configure if focus::editing(app) => "editing: keys type text (shortcuts off) • ←/→/Home/End • Backspace/Del • Enter/Esc done",
+        Page::Configure => "Tab/Shift+Tab switch • ↑/↓ field • Enter edit/Test/Save/Cancel • ←/→/Home/End • Del/Backspace • −/+ numbers • \
+            s save • t test • T deep test (stream a reply) • e session provider • g global/project/promote • i inspector • \
+            f find local servers • y copy • p paste/import • P type JSON • x export for other tools • c clone • C QR • \
+            u undo auto-default • Esc back",
+        Page::Build if app.build.as_ref().map_or(false, |b| b.merge.is_some()) => "↑/↓ field • ← project • → global • Space toggle • p/g all project/global • w/Enter write to the project config • Esc cancel",
+        Page::Build if app.build.as_ref().map_or(false, |b| b.conflict.is_some()) => "o overwrite • m merge (keep keys only on disk) • Tab preview merge • ↑/↓/PgUp/PgDn scroll • Esc cancel",
+        Page::Build if build::missing_prerequisite() == Some(build::Missing::Providers) => "c Configure Providers • f find local servers • Esc back",
+        Page::Build if build::missing_prerequisite() == Some(build::Missing::Default) => "d Select Default • c Configure Providers • Esc back",
+        Page::Build => "g toggle target • Enter write (shows a diff if the project config differs) • e write .env/.envrc • u use global here • \
+            p pin globally • m merge with global per field • Esc back",
+        Page::SelectDefault => "Up/Down select (reachability checked as you move) • Enter set default • Esc back",
+        Page::Latency => "Up/Down select • Enter set as default • r re-measure • Esc back",
+        Page::Status => "Up/Down select • r refresh now • i auto-refresh interval • Enter set as default • Esc back",
+        Page::Benchmark => "Tab/←/→ provider • Enter run benchmark • Up/Down select run • d delete run • Esc back",
+        Page::Recommend => "Up/Down select • Enter open in Model Browser • r re-read memory • Esc back",
+        Page::Logs => "↑/↓/PgUp/PgDn/Home/End scroll • f follow • l level • Tab chi-llm/chi-tui log • y copy • r reload • Esc back",
+        Page::Playground => "type prompt • Enter run (cached) • F5/Ctrl+R force re-run • Tab provider • F2 inspector • Ctrl+U clear • Esc back",
+        Page::Backups => "Up/Down select • Tab snapshots/providers • Enter restore provider • A restore all • n snapshot now • Esc back",
+        Page::Audit => "Up/Down select • r reload • e export • Esc back",
+        Page::Cache => "Up/Down select • Del/x delete (press twice) • r rescan • Esc back",
+        Page::Server => "↑/↓ field • type port/token • ←/→ provider • Ctrl+T new token • Ctrl+V show token • Enter start/stop • Esc back",
+        Page::Variables if app.variables.as_ref().map_or(false, |v| v.edit.is_some()) => "type value • Tab name/value (new) • Enter save • Esc cancel",
+        Page::Variables => "Up/Down select • Enter edit value • n new • d delete • r reload • Esc back",
+        Page::Settings if app.hf_token_input.is_some() => "type or paste the token • Enter save (empty removes it) • Esc cancel",
+        Page::Settings if app.default_models.as_ref().map_or(false, |d| d.input.is_some()) => "type a model id • Enter save (empty clears it) • Esc cancel",
+        Page::Settings if app.theme_picker.is_some() => "↑/↓ preview • Enter keep • Esc/t back to the previous theme",
+        Page::Settings if app.default_models.is_some() => "↑/↓ type • Enter edit • x clear • Esc/m close",
+        Page::Settings => "t theme • a animation • c color-blind • d density • p prefer private • e .env sync • f format • \
+            w Build target • o timeout • l cache • h HF token • m default models • Esc back",
+        _ => return format!("Esc: back • q: quit • {}: sections • ?: help", menu::shortcut_keys()),
+    };
+    hint.to_string()
+}
@@ -0,0 +1,255 @@
+//! Hugging Face Hub: the access token (kept by the secrets layer, like
+//! provider API keys, and sent with Hub searches and downloads), a repo's
+//! license and gating, read from `/api/models/<repo>` or from the catalog
+//! entry when it carries them, and GGUF search.
+
+use std::time::Duration;
+
+use anyhow::{anyhow, Result};
+use serde_json::Value;
+
+use crate::http::{Client, Request};
+use crate::secrets::{self, Backend};
+
+/// Secret name of the token.
+pub const TOKEN_SECRET: &str = "huggingface.token";
+
+/// Environment variables the Hub tools read; they win over the stored token.
+const TOKEN_ENV: [&str; 2] = ["HF_TOKEN", "HUGGING_FACE_HUB_TOKEN"];
+
+pub const HUB: &str = "https://huggingface.co";
+
+/// Where the token in use comes from.
+#[derive(Clone, Debug, PartialEq, Eq)]
+pub enum TokenSource {
+    Env(&'static str),
+    Stored,
+}
+
+/// The token to send, with its source; None when none is configured.
+pub fn token() -> Option<(String, TokenSource)> {
+    for k in TOKEN_ENV {
+        if let Some(v) = std::env::var(k).ok().map(|v| v.trim().to_string()).filter(|v| !v.is_empty()) {
+            return Some((v, TokenSource::Env(k)));
+        }
+    }
+    secrets::get(TOKEN_SECRET).ok().filter(|v| !v.is_empty()).map(|v| (v, TokenSource::Stored))
+}
+
+pub fn save_token(value: &str) -> Result<Backend> {
+    secrets::put(TOKEN_SECRET, value.trim())
+}
+
+pub fn clear_token() {
+    secrets::delete(TOKEN_SECRET);
+}
+
+/// How a repo restricts downloads.
+#[derive(Copy, Clone, Debug, PartialEq, Eq)]
+pub enum Gating {
+    Open,
+    /// Access is granted on accepting the terms
+    Auto,
+    /// The authors approve each request
+    Manual,
+}
+
+impl Gating {
+    fn parse(v: Option<&Value>) -> Gating {
+        match v {
+            Some(Value::String(s)) if s == "manual" => Gating::Manual,
+            Some(Value::String(_)) | Some(Value::Bool(true)) => Gating::Auto,
+            _ => Gating::Open,
+        }
+    }
+
+    pub fn is_gated(self) -> bool {
+        self != Gating::Open
+    }
+
+    pub fn label(self) -> &'static str {
+        match self {
+            Gating::Open => "open",
+            Gating::Auto => "gated (accept the terms on huggingface.co)",
+            Gating::Manual => "gated (authors approve each request)",
+        }
+    }
+}
+
+#[derive(Clone, Debug, PartialEq, Eq)]
+pub struct RepoInfo {
+    /// SPDX-style id, e.g. "apache-2.0", "llama3.1"
+    pub license: Option<String>,
+    pub gating: Gating,
+}
+
+/// License family for the detail view: "Apache 2.0", "MIT", "Llama community"…
+pub fn license_label(license: &str) -> String {
+    let l = license.to_lowercase();
+    let family = match l.as_str() {
+        "apache-2.0" => "Apache 2.0",
+        "mit" => "MIT",
+        "cc-by-4.0" | "cc-by-sa-4.0" => "Creative Commons",
+        "cc-by-nc-4.0" | "cc-by-nc-sa-4.0" => "Creative Commons, non-commercial",
+        "openrail" | "openrail++" | "bigscience-openrail-m" | "creativeml-openrail-m" => "OpenRAIL",
+        _ if l.starts_with("llama") => "Llama community",
+        _ if l.starts_with("gemma") => "Gemma terms",
+        "other" => "other (see the model card)",
+        _ => return license.to_string(),
+    };
+    if family.eq_ignore_ascii_case(license) { family.to_string() } else { format!("{} ({})", family, license) }
+}
+
+/// `license`/`gated` on a catalog entry, when the catalog has them.
+pub fn from_catalog(raw: &Value) -> Option<RepoInfo> {
+    if raw.get("license").is_none() && raw.get("gated").is_none() { return None; }
+    Some(RepoInfo { license: raw.get("license").and_then(|x| x.as_str()).map(|s| s.to_string()), gating: Gating::parse(raw.get("gated")) })
+}
+
+/// Hub API model info: `gated` and the license from the card data, or
+/// else the `license:<id>` tag.
+pub fn parse_repo_info(v: &Value) -> RepoInfo {
+    let card = v.get("cardData").and_then(|c| c.get("license")).and_then(|x| x.as_str()).map(|s| s.to_string());
+    let tag = || {
+        v.get("tags")
+            .and_then(|t| t.as_array())
+            .and_then(|a| a.iter().filter_map(|t| t.as_str()).find_map(|t| t.strip_prefix("license:").map(|s| s.to_string())))
+    };
+    RepoInfo { license: card.or_else(tag), gating: Gating::parse(v.get("gated")) }
+}
+
+/// `Authorization` header for Hub requests, when a token is configured.
+pub fn auth_header() -> Option<(String, String)> {
+    token().map(|(t, _)| ("Authorization".to_string(), format!("Bearer {}", t)))
+}
+
+fn hub_request(path: &str, timeout: Duration) -> Request {
+    let mut headers = vec![("Accept".to_string(), "application/json".to_string())];
+    headers.extend(auth_header());
+    Request { method: "GET".to_string(), url: format!("{}{}", HUB, path), headers, body: None, timeout: Some(timeout) }
+}
+
+/// GET on the Hub API with the token, when one is configured.
+pub fn hub_get(path: &str, timeout: Duration) -> Result<Value> {
+    let resp = Client::from_settings().send(&hub_request(path, timeout))?;
+    if !resp.ok() { return Err(anyhow!("{}: HTTP {}", path, resp.status)); }
+    Ok(serde_json::from_str(&resp.body)?)
+}
+
+/// What the Hub says about the configured token.
+#[derive(Clone, Debug, PartialEq, Eq)]
+pub enum TokenCheck {
+    Missing,
+    Valid { user: String, source: TokenSource },
+    /// The Hub rejected it (HTTP status)
+    Invalid(u16),
+    /// The Hub could not be asked
+    Unverified(String),
+}
+
+impl TokenCheck {
+    pub fn label(&self) -> String {
+        match self {
+            TokenCheck::Missing => "not set (gated models cannot be downloaded)".to_string(),
+            TokenCheck::Valid { user, source: TokenSource::Env(var) } => format!("valid, user {} (from {})", user, var),
+            TokenCheck::Valid { user, .. } => format!("valid, user {}", user),
+            TokenCheck::Invalid(status) => format!("rejected by the Hub (HTTP {}); set a new one in Settings → h", status),
+            TokenCheck::Unverified(e) => format!("set, not verified: {}", e),
+        }
+    }
+}
+
+/// Ask `/api/whoami-v2` whether the token is accepted.
+pub fn check_token(timeout: Duration) -> TokenCheck {
+    let Some((_, source)) = token() else { return TokenCheck::Missing };
+    match Client::from_settings().send(&hub_request("/api/whoami-v2", timeout)) {
+        Ok(resp) if resp.ok() => {
+            let user = serde_json::from_str::<Value>(&resp.body).ok().and_then(|v| v.get("name").and_then(|n| n.as_str()).map(|s| s.to_string()));
+            TokenCheck::Valid { user: user.unwrap_or_else(|| "?".to_string()), source }
+        }
+        Ok(resp) if resp.status == 401 || resp.status == 403 => TokenCheck::Invalid(resp.status),
+        Ok(resp) => TokenCheck::Unverified(format!("HTTP {}", resp.status)),
+        Err(e) => TokenCheck::Unverified(e.to_string()),
+    }
+}
+
+/// A GGUF repo found on the Hub, with the file to download from it.
+#[derive(Clone, Debug, PartialEq, Eq)]
+pub struct HubModel {
+    pub repo: String,
+    pub filename: String,
+    pub downloads: u64,
+    /// `gated` and `license` from the search result, for `from_catalog`
+    pub raw: Value,
+}
+
+/// Quantizations in order of preference when a repo has several files.
+const PREFERRED_QUANTS: [&str; 4] = ["q4_k_m", "q4_k_s", "q5_k_m", "q4_0"];
+
+/// The file to offer: a preferred quantization, else the first `.gguf`.
+/// Split files (`-00001-of-00003`) are skipped; the downloader fetches one file.
+pub fn pick_gguf(files: &[String]) -> Option<String> {
+    let single: Vec<&String> = files.iter().filter(|f| f.to_lowercase().ends_with(".gguf") && !f.contains("-of-")).collect();
+    PREFERRED_QUANTS
+        .iter()
+        .find_map(|q| single.iter().find(|f| f.to_lowercase().contains(q)))
+        .or_else(|| single.first())
+        .map(|f| f.to_string())
+}
+
+/// GGUF repos matching `query`, most downloaded first. Uses the token, so
+/// private and gated repos the user can see are included.
+pub fn search(query: &str, limit: usize) -> Result<Vec<HubModel>> {
+    let q: String = query.trim().split_whitespace().collect::<Vec<_>>().join("+");
+    let path = format!("/api/models?search={}&filter=gguf&sort=downloads&direction=-1&limit={}&full=true", q, limit);
+    let v = hub_get(&path, Duration::from_secs(10))?;
+    let list = v.as_array().ok_or_else(|| anyhow!("Hub search: unexpected reply"))?;
+    Ok(list
+        .iter()
+        .filter_map(|m| {
+            let repo = m.get("id").or_else(|| m.get("modelId")).and_then(|x| x.as_str())?.to_string();
+            let files: Vec<String> = m
+                .get("siblings")
+                .and_then(|s| s.as_array())
+                .map(|a| a.iter().filter_map(|f| f.get("rfilename").and_then(|x| x.as_str()).map(|s| s.to_string())).collect())
+                .unwrap_or_default();
+            let filename = pick_gguf(&files)?;
+            // `full=true` carries `gated` and the license tags
+            let raw = serde_json::json!({"gated": m.get("gated").cloned().unwrap_or(Value::Bool(false)), "license": parse_repo_info(m).license});
+            Some(HubModel { repo, filename, downloads: m.get("downloads").and_then(|x| x.as_u64()).unwrap_or(0), raw })
+        })
+        .collect())
+}
+
+pub fn repo_info(repo: &str, timeout: Duration) -> Result<RepoInfo> {
+    Ok(parse_repo_info(&hub_get(&format!("/api/models/{}", repo), timeout)?))
+}
+
+#[cfg(all(test, unix))]
+mod tests {
+    use serde_json::json;
+
+    use super::*;
+    use crate::testing::FakeCli;
+
+    #[test]
+    fn gated_repos_and_the_hf_token() {
+        let _fake = FakeCli::new();
+        let hub = json!({"gated": "manual", "cardData": {"license": "llama3.1"}, "tags": ["gguf", "license:other"]});
+        let info = parse_repo_info(&hub);
+        assert_eq!(info.gating, Gating::Manual);
+        assert_eq!(license_label(info.license.as_deref().expect("license")), "Llama community (llama3.1)");
+        assert_eq!(parse_repo_info(&json!({"gated": false, "tags": ["license:mit"]})).license.as_deref(), Some("mit"));
+        assert!(from_catalog(&json!({"id": "phi3-mini"})).is_none());
+
+        assert!(token().is_none());
+        save_token(" hf_abc \n").expect("store token");
+        assert_eq!(token(), Some(("hf_abc".to_string(), TokenSource::Stored)));
+        clear_token();
+        assert!(token().is_none());
+
+        let files: Vec<String> = ["README.md", "m-Q8_0.gguf", "m-Q4_K_M-00001-of-00002.gguf", "m-Q4_K_M.gguf"].iter().map(|s| s.to_string()).collect();
+        assert_eq!(pick_gguf(&files).as_deref(), Some("m-Q4_K_M.gguf"));
+        assert_eq!(pick_gguf(&files[..2]).as_deref(), Some("m-Q8_0.gguf"));
+    }
+}
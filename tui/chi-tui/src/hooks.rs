@@ -0,0 +1,195 @@
+use std::io::Read;
+use std::process::{Command, Stdio};
+use std::sync::mpsc::{channel, Receiver, TryRecvError};
+use std::thread;
+use std::time::Duration;
+
+use anyhow::{anyhow, Result};
+use serde_json::Value;
+
+use crate::log;
+
+const HOOK_TIMEOUT: Duration = Duration::from_secs(10);
+
+/// Post-save hook command: `CHI_TUI_POST_SAVE_HOOK`, else `hooks.post_save`
+/// in the user's `chi_llm/tui.json`. Never from the project store: a cloned
+/// repository must not be able to run commands on save.
+pub fn post_save_command() -> Option<String> {
+    if let Ok(cmd) = std::env::var("CHI_TUI_POST_SAVE_HOOK") {
+        if !cmd.trim().is_empty() { return Some(cmd); }
+    }
+    if crate::store::read().ok().and_then(|v| v.get("hooks")?.get("post_save").cloned()).is_some() {
+        log::warn("hooks.post_save in the project store is ignored; set it in the user tui.json or CHI_TUI_POST_SAVE_HOOK");
+    }
+    let text = std::fs::read_to_string(crate::prefs::path().ok()?).ok()?;
+    command_in(&crate::store::parse(&text).ok()?)
+}
+
+/// `hooks.post_save` of a settings object, when set.
+fn command_in(v: &Value) -> Option<String> {
+    v.get("hooks")
+        .and_then(|h| h.get("post_save"))
+        .and_then(|x| x.as_str())
+        .filter(|s| !s.trim().is_empty())
+        .map(|s| s.to_string())
+}
+
+fn shell(cmd: &str) -> Command {
+    if cfg!(windows) {
+        let mut c = Command::new("cmd");
+        c.arg("/C").arg(cmd);
+        c
+    } else {
+        let mut c = Command::new("sh");
+        c.arg("-c").arg(cmd);
+        c
+    }
+}
+
+/// Read a pipe to the end on its own thread, so a chatty hook never blocks
+/// on a full pipe while we wait for it to exit.
+fn drain(pipe: Option<impl Read + Send + 'static>) -> Receiver<String> {
+    let (tx, rx) = channel();
+    thread::spawn(move || {
+        let mut out = String::new();
+        if let Some(mut p) = pipe { let _ = p.read_to_string(&mut out); }
+        let _ = tx.send(out);
+    });
+    rx
+}
+
+/// Run the configured hook after `written` was saved. Returns Ok(None) when
+/// no hook is configured. Output goes to the TUI log. Blocks for up to
+/// `HOOK_TIMEOUT`; the TUI uses `HookRunner` instead.
+pub fn run_post_save(written: &str) -> Result<Option<String>> {
+    let Some(cmd) = post_save_command() else { return Ok(None) };
+    run_hook(&cmd, written).map(|_| Some(cmd))
+}
+
+fn run_hook(cmd: &str, written: &str) -> Result<()> {
+    use wait_timeout::ChildExt;
+    log::info(&format!("post-save hook for {}: {}", written, cmd));
+    let mut child = shell(cmd)
+        .env("CHI_CONFIG_PATH", written)
+        .stdin(Stdio::null())
+        .stdout(Stdio::piped())
+        .stderr(Stdio::piped())
+        .spawn()?;
+    let (out, err) = (drain(child.stdout.take()), drain(child.stderr.take()));
+    let status = match child.wait_timeout(HOOK_TIMEOUT)? {
+        Some(s) => s,
+        None => {
+            let _ = child.kill();
+            let _ = child.wait();
+            log::warn(&format!("post-save hook timed out after {:?}", HOOK_TIMEOUT));
+            return Err(anyhow!("hook timed out after {:?}", HOOK_TIMEOUT));
+        }
+    };
+    // A background child of the hook may keep the pipes open: do not wait for it
+    let grace = Duration::from_secs(1);
+    let (out, err) = (out.recv_timeout(grace).unwrap_or_default(), err.recv_timeout(grace).unwrap_or_default());
+    if !out.trim().is_empty() { log::info(out.trim_end()); }
+    if !err.trim().is_empty() { log::warn(err.trim_end()); }
+    if !status.success() {
+        let first = err.lines().next().unwrap_or("").to_string();
+        return Err(anyhow!("hook exited with {}: {}", status, first));
+    }
+    Ok(())
+}
+
+/// Post-save hooks started by the TUI, each on its own thread so a slow
+/// hook does not freeze the screen; `tick` polls for the results.
+#[derive(Default)]
+pub struct HookRunner {
+    running: Vec<Receiver<Result<()>>>,
+}
+
+impl HookRunner {
+    /// Start the configured hook for `written`; false when there is none.
+    pub fn start(&mut self, written: &str) -> bool {
+        let Some(cmd) = post_save_command() else { return false };
+        let (tx, rx) = channel();
+        let written = written.to_string();
+        thread::spawn(move || {
+            let _ = tx.send(run_hook(&cmd, &written));
+        });
+        self.running.push(rx);
+        true
+    }
+
+    pub fn running(&self) -> bool {
+        !self.running.is_empty()
+    }
+
+    /// Results of the hooks that finished since the last poll.
+    pub fn poll(&mut self) -> Vec<Result<()>> {
+        let mut done = Vec::new();
+        self.running.retain(|rx| match rx.try_recv() {
+            Ok(res) => {
+                done.push(res);
+                false
+            }
+            Err(TryRecvError::Empty) => true,
+            Err(TryRecvError::Disconnected) => false,
+        });
+        done
+    }
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+
+    #[test]
+    fn command_is_read_from_hooks_post_save() {
+        assert_eq!(command_in(&serde_json::json!({"hooks": {"post_save": "make reload"}})), Some("make reload".to_string()));
+        assert_eq!(command_in(&serde_json::json!({"hooks": {"post_save": "  "}})), None);
+        assert_eq!(command_in(&serde_json::json!({"post_save": "x"})), None);
+    }
+
+    #[cfg(unix)]
+    #[test]
+    fn project_store_hooks_are_ignored_and_user_hooks_run() {
+        let mut fake = crate::testing::FakeCli::new();
+        let marker = fake.root.join("ran");
+        let cmd = format!("touch {}", marker.display());
+        std::fs::write("chi.tmp.json", serde_json::json!({"hooks": {"post_save": cmd}}).to_string()).expect("store");
+        assert_eq!(post_save_command(), None);
+        assert_eq!(run_post_save("chi.tmp.json").expect("no hook"), None);
+        assert!(!marker.exists());
+
+        let prefs = crate::prefs::path().expect("prefs path");
+        std::fs::create_dir_all(prefs.parent().expect("dir")).expect("mkdir");
+        std::fs::write(&prefs, serde_json::json!({"hooks": {"post_save": cmd}}).to_string()).expect("tui.json");
+        assert_eq!(run_post_save("chi.tmp.json").expect("hook"), Some(cmd));
+        assert!(marker.exists());
+        fake.set_env("CHI_TUI_POST_SAVE_HOOK", "exit 3".to_string());
+        assert!(run_post_save("chi.tmp.json").expect_err("fails").to_string().contains("exited"));
+    }
+
+    #[cfg(unix)]
+    #[test]
+    fn a_hook_writing_more_than_a_pipe_buffer_finishes() {
+        let _fake = crate::testing::FakeCli::new();
+        let started = std::time::Instant::now();
+        run_hook("head -c 1000000 /dev/zero; head -c 1000000 /dev/zero >&2", "x").expect("hook");
+        assert!(started.elapsed() < Duration::from_secs(5));
+    }
+
+    #[cfg(unix)]
+    #[test]
+    fn the_runner_returns_at_once_and_reports_when_done() {
+        let mut fake = crate::testing::FakeCli::new();
+        fake.set_env("CHI_TUI_POST_SAVE_HOOK", "sleep 1; exit 1".to_string());
+        let mut runner = HookRunner::default();
+        let started = std::time::Instant::now();
+        assert!(runner.start("chi.tmp.json"));
+        assert!(started.elapsed() < Duration::from_millis(500) && runner.running());
+        let mut results = Vec::new();
+        while results.is_empty() && started.elapsed() < Duration::from_secs(5) {
+            results = runner.poll();
+            thread::sleep(Duration::from_millis(20));
+        }
+        assert!(results.len() == 1 && results[0].is_err() && !runner.running());
+    }
+}
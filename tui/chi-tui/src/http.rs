@@ -0,0 +1,521 @@
+use std::fs;
+use std::sync::Arc;
+use std::thread;
+use std::time::{Duration, Instant};
+
+use anyhow::{anyhow, Context, Result};
+use serde_json::Value;
+
+use crate::log;
+use crate::providers::ProviderScratchEntry;
+use crate::store;
+
+/// Provider HTTP in one place: request building (auth headers), transport
+/// (proxy, TLS) and retries. Callers hand a `Request` to a `Client`; the
+/// `Transport` behind it is swappable, so tests can answer with canned
+/// responses and record what was sent.
+#[derive(Clone, Debug, Default)]
+pub struct Request {
+    pub method: String,
+    pub url: String,
+    pub headers: Vec<(String, String)>,
+    pub body: Option<String>,
+    pub timeout: Option<Duration>,
+}
+
+#[derive(Clone, Debug, Default)]
+pub struct Response {
+    pub status: u16,
+    pub headers: Vec<(String, String)>,
+    pub body: String,
+}
+
+impl Response {
+    pub fn ok(&self) -> bool {
+        (200..300).contains(&self.status)
+    }
+}
+
+/// One round trip, no retries.
+pub trait Transport: Send + Sync {
+    fn round_trip(&self, req: &Request) -> Result<Response>;
+}
+
+/// `http` object in chi.tmp.json:
+/// `{"proxy": "http://proxy:3128", "ca_cert": "/path/ca.pem", "insecure_tls": false, "retries": 1}`.
+/// Without `proxy`, the HTTP(S)_PROXY/ALL_PROXY/NO_PROXY environment
+/// applies; a provider's own `proxy` field overrides both.
+#[derive(Clone, Debug, Default)]
+pub struct HttpSettings {
+    pub proxy: Option<String>,
+    pub ca_cert: Option<String>,
+    pub insecure_tls: bool,
+    /// Extra attempts for idempotent requests on connect errors and 502/503/504
+    pub retries: u32,
+}
+
+pub fn load_http_settings() -> HttpSettings {
+    let http = store::read()
+        .ok()
+        .and_then(|v| v.get("http").cloned())
+        .unwrap_or(Value::Null);
+    let text = |k: &str| http.get(k).and_then(|v| v.as_str()).map(|s| s.trim().to_string()).filter(|s| !s.is_empty());
+    HttpSettings {
+        proxy: text("proxy"),
+        ca_cert: text("ca_cert"),
+        insecure_tls: http.get("insecure_tls").and_then(|v| v.as_bool()).unwrap_or(false),
+        retries: http.get("retries").and_then(|v| v.as_u64()).map_or(0, |n| n.min(5) as u32),
+    }
+}
+
+/// Sent on every request unless a provider sets its own `user_agent`;
+/// lets gateways allow-list the TUI and servers tell it apart in logs.
+pub const USER_AGENT: &str = concat!("chi-tui/", env!("CARGO_PKG_VERSION"));
+
+/// Client builder with proxy, TLS and the User-Agent applied; also used for
+/// streaming downloads, which do not fit the buffered `Transport`.
+pub fn builder(settings: &HttpSettings) -> Result<reqwest::blocking::ClientBuilder> {
+    let mut b = reqwest::blocking::Client::builder().user_agent(USER_AGENT);
+    if let Some(p) = &settings.proxy {
+        // NO_PROXY still exempts hosts from a configured proxy
+        let proxy = reqwest::Proxy::all(p.as_str()).with_context(|| format!("invalid proxy {}", p))?;
+        b = b.proxy(proxy.no_proxy(reqwest::NoProxy::from_env()));
+    }
+    if let Some(path) = &settings.ca_cert {
+        let pem = fs::read(path).with_context(|| format!("reading ca_cert {}", path))?;
+        b = b.add_root_certificate(reqwest::Certificate::from_pem(&pem)?);
+    }
+    if settings.insecure_tls { b = b.danger_accept_invalid_certs(true); }
+    Ok(b)
+}
+
+/// The real network, via reqwest.
+pub struct ReqwestTransport {
+    settings: HttpSettings,
+}
+
+impl ReqwestTransport {
+    pub fn new(settings: HttpSettings) -> Self {
+        ReqwestTransport { settings }
+    }
+}
+
+impl Transport for ReqwestTransport {
+    fn round_trip(&self, req: &Request) -> Result<Response> {
+        let mut b = builder(&self.settings)?;
+        if let Some(t) = req.timeout { b = b.timeout(t); }
+        let client = b.build()?;
+        let method = reqwest::Method::from_bytes(req.method.as_bytes()).map_err(|_| anyhow!("invalid method {}", req.method))?;
+        let mut rb = client.request(method, &req.url);
+        for (k, v) in &req.headers { rb = rb.header(k.as_str(), v.as_str()); }
+        if let Some(body) = &req.body { rb = rb.body(body.clone()); }
+        let resp = rb.send()?;
+        let status = resp.status().as_u16();
+        let headers = resp.headers().iter().map(|(k, v)| (k.to_string(), v.to_str().unwrap_or("<binary>").to_string())).collect();
+        let body = resp.text().map_err(|e| anyhow!("reading body: {}", e))?;
+        Ok(Response { status, headers, body })
+    }
+}
+
+#[derive(Clone)]
+pub struct Client {
+    transport: Arc<dyn Transport>,
+    retries: u32,
+    backoff: Duration,
+    requests: Option<RequestLog>,
+}
+
+/// A provider's `debug_requests` flag: every round trip goes to chi-tui's
+/// log with method, URL, headers (secrets masked), status and timing, so a
+/// misbehaving backend can be captured without global verbosity.
+#[derive(Clone, Debug)]
+pub struct RequestLog {
+    provider_id: String,
+    auth_header: String,
+}
+
+impl RequestLog {
+    /// None unless the provider turned the flag on.
+    pub fn of(entry: &ProviderScratchEntry) -> Option<RequestLog> {
+        if !cfg_flag(entry, "debug_requests") { return None; }
+        Some(RequestLog { provider_id: entry.id.clone(), auth_header: cfg_str(entry, "auth_header").to_string() })
+    }
+
+    /// Header value fit for the log or the inspector.
+    pub fn redact(&self, name: &str, value: &str) -> String {
+        if !self.auth_header.is_empty() && name.eq_ignore_ascii_case(&self.auth_header) { return "••••••".to_string(); }
+        redact(name, value)
+    }
+
+    pub fn record(&self, req: &Request, result: &Result<Response>, elapsed: Duration) {
+        let id = &self.provider_id;
+        let mut lines = vec![format!("[{}] > {} {}", id, req.method, req.url)];
+        lines.extend(req.headers.iter().map(|(k, v)| format!("[{}] > {}: {}", id, k, self.redact(k, v))));
+        if let Some(body) = &req.body { lines.push(format!("[{}] > body: {} bytes", id, body.len())); }
+        match result {
+            Ok(resp) => {
+                lines.push(format!("[{}] < HTTP {} in {} ms", id, resp.status, elapsed.as_millis()));
+                lines.extend(resp.headers.iter().map(|(k, v)| format!("[{}] < {}: {}", id, k, self.redact(k, v))));
+                if !resp.body.is_empty() { lines.push(format!("[{}] < body: {} bytes", id, resp.body.len())); }
+            }
+            Err(e) => lines.push(format!("[{}] < failed after {} ms: {}", id, elapsed.as_millis(), e)),
+        }
+        log::debug(&lines.join("\n"));
+    }
+}
+
+fn is_secret_header(name: &str) -> bool {
+    let n = name.to_lowercase();
+    n == "authorization" || n.contains("api-key") || n.contains("token") || n == "cookie" || n == "set-cookie"
+}
+
+/// Secret header values keep only their scheme: `Bearer ••••••`.
+pub fn redact(name: &str, value: &str) -> String {
+    if !is_secret_header(name) { return value.to_string(); }
+    match value.split_once(' ') {
+        Some((scheme, _)) => format!("{} ••••••", scheme),
+        None => "••••••".to_string(),
+    }
+}
+
+impl Client {
+    /// Network client configured from chi.tmp.json.
+    pub fn from_settings() -> Self {
+        Client::with_settings(load_http_settings())
+    }
+
+    /// Client for talking to one provider, with its own TLS options and
+    /// request logging.
+    pub fn for_provider(entry: &ProviderScratchEntry) -> Self {
+        Client { requests: RequestLog::of(entry), ..Client::with_settings(provider_settings(entry)) }
+    }
+
+    fn with_settings(settings: HttpSettings) -> Self {
+        let retries = settings.retries;
+        let transport: Arc<dyn Transport> = if crate::mock::enabled() { Arc::new(crate::mock::MockTransport) } else { Arc::new(ReqwestTransport::new(settings)) };
+        Client { transport, retries, backoff: Duration::from_millis(300), requests: None }
+    }
+
+    /// Client over any transport, e.g. a fake in tests.
+    pub fn with_transport(transport: Arc<dyn Transport>, retries: u32) -> Self {
+        Client { transport, retries, backoff: Duration::ZERO, requests: None }
+    }
+
+    /// Send, retrying GET/HEAD on transport errors and gateway statuses.
+    pub fn send(&self, req: &Request) -> Result<Response> {
+        let idempotent = matches!(req.method.as_str(), "GET" | "HEAD");
+        let mut attempt = 0;
+        loop {
+            let start = Instant::now();
+            let result = self.transport.round_trip(req);
+            if let Some(requests) = &self.requests { requests.record(req, &result, start.elapsed()); }
+            let retry = match &result {
+                Ok(r) => matches!(r.status, 502 | 503 | 504),
+                Err(_) => true,
+            };
+            if !retry || !idempotent || attempt >= self.retries { return result; }
+            attempt += 1;
+            thread::sleep(self.backoff * attempt);
+        }
+    }
+}
+
+fn cfg_str<'a>(entry: &'a ProviderScratchEntry, key: &str) -> &'a str {
+    entry.config.get(key).and_then(|v| v.as_str()).map(|s| s.trim()).unwrap_or("")
+}
+
+/// `true` or `"true"` (the form stores option fields as text).
+fn cfg_flag(entry: &ProviderScratchEntry, key: &str) -> bool {
+    match entry.config.get(key) {
+        Some(Value::Bool(b)) => *b,
+        Some(Value::String(s)) => matches!(s.trim().to_lowercase().as_str(), "true" | "yes" | "1"),
+        _ => false,
+    }
+}
+
+/// The global settings with a provider's `proxy` (e.g. only the hosted
+/// APIs go through the corporate proxy), `ca_cert` and `insecure_tls`
+/// (LM Studio or Ollama behind a TLS reverse proxy) applied.
+pub fn provider_settings(entry: &ProviderScratchEntry) -> HttpSettings {
+    let mut settings = load_http_settings();
+    let proxy = cfg_str(entry, "proxy");
+    if !proxy.is_empty() { settings.proxy = Some(proxy.to_string()); }
+    let ca_cert = cfg_str(entry, "ca_cert");
+    if !ca_cert.is_empty() { settings.ca_cert = Some(ca_cert.to_string()); }
+    if cfg_flag(entry, "insecure_tls") { settings.insecure_tls = true; }
+    settings
+}
+
+/// `http(s)://host:port` of a host/port provider (LM Studio, Ollama); the
+/// `scheme` field picks https.
+pub fn server_url(entry: &ProviderScratchEntry) -> Option<String> {
+    let (host, port) = crate::health::endpoint_of(entry)?;
+    let scheme = if cfg_str(entry, "scheme").eq_ignore_ascii_case("https") { "https" } else { "http" };
+    Some(format!("{}://{}:{}", scheme, host, port))
+}
+
+/// Version header Anthropic requires on every API call.
+pub const ANTHROPIC_VERSION: &str = "2023-06-01";
+
+/// Request to a provider's HTTP API with its auth headers: `api_key` as
+/// bearer token and `org_id` as OpenAI-Organization, or for Anthropic as
+/// `x-api-key` plus `anthropic-version`. With `auth_header` set (servers
+/// behind a proxy), `api_key` is sent as that header's value instead. A
+/// `user_agent` in the provider config replaces [`USER_AGENT`].
+pub fn provider_request(entry: &ProviderScratchEntry, method: &str, url: String, body: Option<String>, timeout: Duration) -> Request {
+    let mut headers: Vec<(String, String)> = vec![("Accept".to_string(), "application/json".to_string())];
+    if body.is_some() { headers.push(("Content-Type".to_string(), "application/json".to_string())); }
+    let agent = cfg_str(entry, "user_agent");
+    if !agent.is_empty() { headers.push(("User-Agent".to_string(), agent.to_string())); }
+    let key = cfg_str(entry, "api_key");
+    if entry.ptype == "anthropic" {
+        if !key.is_empty() { headers.push(("x-api-key".to_string(), key.to_string())); }
+        headers.push(("anthropic-version".to_string(), ANTHROPIC_VERSION.to_string()));
+        return Request { method: method.to_string(), url, headers, body, timeout: Some(timeout) };
+    }
+    let auth_header = cfg_str(entry, "auth_header");
+    if !key.is_empty() {
+        if auth_header.is_empty() {
+            headers.push(("Authorization".to_string(), format!("Bearer {}", key)));
+        } else {
+            headers.push((auth_header.to_string(), key.to_string()));
+        }
+    }
+    let org = cfg_str(entry, "org_id");
+    if !org.is_empty() { headers.push(("OpenAI-Organization".to_string(), org.to_string())); }
+    Request { method: method.to_string(), url, headers, body, timeout: Some(timeout) }
+}
+
+/// JSON from a host/port provider's own API (Ollama, LM Studio), e.g.
+/// `GET /api/tags`.
+pub fn local_api(entry: &ProviderScratchEntry, method: &str, path: &str, body: Option<Value>, timeout: Duration) -> Result<Value> {
+    let base = server_url(entry).ok_or_else(|| anyhow!("{} has no host/port", entry.id))?;
+    let req = provider_request(entry, method, format!("{}{}", base, path), body.map(|b| b.to_string()), timeout);
+    let resp = Client::for_provider(entry).send(&req)?;
+    if !resp.ok() { return Err(anyhow!("{} {}: HTTP {}", method, path, resp.status)); }
+    Ok(serde_json::from_str(&resp.body)?)
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+    use serde_json::json;
+
+    fn entry(ptype: &str, config: Value) -> ProviderScratchEntry {
+        ProviderScratchEntry { id: "p".to_string(), name: "P".to_string(), ptype: ptype.to_string(), tags: Vec::new(), config, extra: Default::default(), scope: Default::default() }
+    }
+
+    fn header<'a>(req: &'a Request, name: &str) -> Option<&'a str> {
+        req.headers.iter().find(|(k, _)| k.eq_ignore_ascii_case(name)).map(|(_, v)| v.as_str())
+    }
+
+    #[test]
+    fn secret_headers_keep_only_their_scheme() {
+        assert_eq!(redact("Authorization", "Bearer sk-123"), "Bearer ••••••");
+        assert_eq!(redact("x-api-key", "sk-123"), "••••••");
+        assert_eq!(redact("X-Auth-Token", "t"), "••••••");
+        assert_eq!(redact("Content-Type", "application/json"), "application/json");
+        let log = RequestLog::of(&entry("openai", json!({"debug_requests": "yes", "auth_header": "X-Gateway"}))).expect("flag on");
+        assert_eq!(log.redact("x-gateway", "secret"), "••••••");
+        assert_eq!(log.redact("Accept", "*/*"), "*/*");
+        assert!(RequestLog::of(&entry("openai", json!({"debug_requests": false}))).is_none());
+    }
+
+    #[test]
+    fn requests_carry_the_provider_auth_headers() {
+        let t = Duration::from_secs(5);
+        let openai = entry("openai", json!({"api_key": "sk-1", "org_id": "org-9", "user_agent": "ci/1"}));
+        let req = provider_request(&openai, "POST", "https://x/v1/chat".to_string(), Some("{}".to_string()), t);
+        assert_eq!(header(&req, "Authorization"), Some("Bearer sk-1"));
+        assert_eq!(header(&req, "OpenAI-Organization"), Some("org-9"));
+        assert_eq!(header(&req, "User-Agent"), Some("ci/1"));
+        assert_eq!(header(&req, "Content-Type"), Some("application/json"));
+        assert_eq!(req.timeout, Some(t));
+
+        let proxied = entry("openai-compatible", json!({"api_key": "k", "auth_header": "X-Gateway"}));
+        let req = provider_request(&proxied, "GET", "u".to_string(), None, t);
+        assert_eq!((header(&req, "X-Gateway"), header(&req, "Authorization"), header(&req, "Content-Type")), (Some("k"), None, None));
+
+        let anthropic = entry("anthropic", json!({"api_key": "a-1", "org_id": "ignored"}));
+        let req = provider_request(&anthropic, "GET", "u".to_string(), None, t);
+        assert_eq!(header(&req, "x-api-key"), Some("a-1"));
+        assert_eq!(header(&req, "anthropic-version"), Some(ANTHROPIC_VERSION));
+        assert_eq!((header(&req, "Authorization"), header(&req, "OpenAI-Organization")), (None, None));
+    }
+
+    #[test]
+    fn server_urls_follow_the_scheme_field() {
+        assert_eq!(server_url(&entry("ollama", json!({"host": "box"}))), Some("http://box:11434".to_string()));
+        assert_eq!(server_url(&entry("lmstudio", json!({"host": "box", "port": "443", "scheme": "HTTPS"}))), Some("https://box:443".to_string()));
+        assert_eq!(server_url(&entry("local", json!({}))), None);
+    }
+
+    /// Fails every round trip, counting attempts.
+    struct Down(std::sync::atomic::AtomicUsize);
+
+    impl Transport for Down {
+        fn round_trip(&self, _req: &Request) -> Result<Response> {
+            self.0.fetch_add(1, std::sync::atomic::Ordering::SeqCst);
+            Err(anyhow!("connection refused"))
+        }
+    }
+
+    #[test]
+    fn transport_errors_are_retried_for_gets_only() {
+        let down = Arc::new(Down(Default::default()));
+        let client = Client::with_transport(down.clone(), 2);
+        let get = Request { method: "GET".to_string(), ..Default::default() };
+        assert!(client.send(&get).is_err());
+        assert_eq!(down.0.load(std::sync::atomic::Ordering::SeqCst), 3);
+        let post = Request { method: "POST".to_string(), ..Default::default() };
+        assert!(client.send(&post).is_err());
+        assert_eq!(down.0.load(std::sync::atomic::Ordering::SeqCst), 4);
+    }
+
+    #[cfg(unix)]
+    #[test]
+    fn gateway_statuses_are_retried_up_to_the_limit() {
+        let canned = crate::testing::Canned::new(&[(503, ""), (502, ""), (200, "{}")]);
+        let client = Client::with_transport(canned.clone(), 1);
+        let get = Request { method: "GET".to_string(), ..Default::default() };
+        assert_eq!(client.send(&get).expect("response").status, 502);
+        assert_eq!(client.send(&get).expect("response").status, 200);
+        assert_eq!(canned.sent().len(), 3);
+
+        let canned = crate::testing::Canned::new(&[(500, "")]);
+        let client = Client::with_transport(canned.clone(), 3);
+        assert_eq!(client.send(&get).expect("response").status, 500);
+        assert_eq!(canned.sent().len(), 1, "500 is not a gateway status");
+    }
+
+    #[cfg(unix)]
+    #[test]
+    fn settings_come_from_the_store_and_the_provider() {
+        let _fake = crate::testing::FakeCli::new();
+        store::write(&json!({"http": {"proxy": " http://proxy:3128 ", "ca_cert": "", "retries": 9}})).expect("store");
+        let s = load_http_settings();
+        assert_eq!((s.proxy.as_deref(), s.ca_cert, s.insecure_tls, s.retries), (Some("http://proxy:3128"), None, false, 5));
+        let s = provider_settings(&entry("lmstudio", json!({"proxy": "http://other:8080", "ca_cert": "/ca.pem", "insecure_tls": "true"})));
+        assert_eq!((s.proxy.as_deref(), s.ca_cert.as_deref(), s.insecure_tls), (Some("http://other:8080"), Some("/ca.pem"), true));
+    }
+
+    #[cfg(unix)]
+    #[test]
+    fn provider_requests_identify_chi_tui_unless_overridden() {
+        let mut fake = crate::testing::FakeCli::new();
+        fake.respond("providers discover-models --type ollama", json!({"provider": "ollama", "models": []}));
+        let (url, seen) = crate::testing::serve_file(b"{}", "\"v1\"");
+        crate::config_cli::run_config(crate::testing::add_provider("ollama", "gw", &[("host", "10.0.0.5")], true)).expect("add provider");
+        let mut entry = crate::providers::read_scratch_entries().expect("entries").remove(0);
+
+        let send = |entry: &ProviderScratchEntry| {
+            let req = provider_request(entry, "GET", url.clone(), None, Duration::from_secs(5));
+            Client::from_settings().send(&req).expect("send");
+            seen.lock().expect("log").last().cloned().expect("request").to_ascii_lowercase()
+        };
+        assert!(send(&entry).contains(&format!("user-agent: {}", USER_AGENT)));
+
+        entry.config["user_agent"] = json!("corp-gw/1");
+        assert!(send(&entry).contains("user-agent: corp-gw/1"));
+        // Test connection hands the override to the CLI's discovery request
+        crate::providers::probe_provider(&entry).expect("test connection");
+        assert!(fake.calls().iter().any(|c| c.ends_with("--json --user-agent corp-gw/1")), "{:?}", fake.calls());
+    }
+
+    #[cfg(unix)]
+    #[test]
+    fn server_providers_can_use_https_behind_a_proxy() {
+        let mut fake = crate::testing::FakeCli::new();
+        fake.respond("providers discover-models --type ollama", json!({"provider": "ollama", "models": [{"id": "qwen3"}]}));
+        crate::config_cli::run_config(crate::testing::add_provider("ollama", "proxied", &[("host", "llm.internal"), ("port", "443")], true)).expect("add provider");
+        let mut entry = crate::providers::read_scratch_entries().expect("entries").remove(0);
+        assert_eq!(server_url(&entry).as_deref(), Some("http://llm.internal:443"));
+
+        entry.config["scheme"] = json!("https");
+        entry.config["ca_cert"] = json!("/etc/ssl/corp-ca.pem");
+        entry.config["insecure_tls"] = json!("true");
+        assert_eq!(server_url(&entry).as_deref(), Some("https://llm.internal:443"));
+        let settings = provider_settings(&entry);
+        assert_eq!(settings.ca_cert.as_deref(), Some("/etc/ssl/corp-ca.pem"));
+        assert!(settings.insecure_tls);
+
+        // Test connection asks the CLI for the same URL and TLS options
+        assert_eq!(crate::providers::probe_provider(&entry).expect("test connection"), "ollama: 1 models");
+        assert!(fake.calls().iter().any(|c| c.ends_with("--scheme https --ca-cert /etc/ssl/corp-ca.pem --insecure")), "{:?}", fake.calls());
+    }
+
+    #[cfg(unix)]
+    #[test]
+    fn proxied_servers_send_their_token() {
+        let mut fake = crate::testing::FakeCli::new();
+        fake.respond("providers discover-models --type ollama", json!({"provider": "ollama", "models": []}));
+        crate::config_cli::run_config(crate::testing::add_provider("ollama", "nginx", &[("host", "llm.internal")], true)).expect("add provider");
+        let mut entry = crate::providers::read_scratch_entries().expect("entries").remove(0);
+        entry.config["api_key"] = json!("tok-1");
+        let header = |entry: &ProviderScratchEntry, name: &str| {
+            provider_request(entry, "GET", "http://llm.internal/api/tags".to_string(), None, Duration::from_secs(1))
+                .headers
+                .into_iter()
+                .find(|(k, _)| k == name)
+                .map(|(_, v)| v)
+        };
+        assert_eq!(header(&entry, "Authorization").as_deref(), Some("Bearer tok-1"));
+
+        entry.config["auth_header"] = json!("X-Auth-Token");
+        assert_eq!(header(&entry, "X-Auth-Token").as_deref(), Some("tok-1"));
+        assert_eq!(header(&entry, "Authorization"), None);
+
+        crate::providers::probe_provider(&entry).expect("test connection");
+        assert!(fake.calls().iter().any(|c| c.ends_with("--auth-header X-Auth-Token --api-key tok-1")), "{:?}", fake.calls());
+    }
+
+    #[cfg(unix)]
+    #[test]
+    fn debug_requests_logs_one_providers_traffic_with_secrets_masked() {
+        let mut fake = crate::testing::FakeCli::new();
+        fake.respond("providers discover-models --type ollama", json!({"provider": "ollama", "models": []}));
+        let (url, _) = crate::testing::serve_file(b"{}", "\"v1\"");
+        crate::config_cli::run_config(crate::testing::add_provider("ollama", "flaky", &[("host", "127.0.0.1")], true)).expect("add provider");
+        let mut entry = crate::providers::read_scratch_entries().expect("entries").remove(0);
+        entry.config["api_key"] = json!("tok-secret");
+        let send = |entry: &ProviderScratchEntry| {
+            Client::for_provider(entry).send(&provider_request(entry, "GET", url.clone(), None, Duration::from_secs(5))).expect("send");
+            std::fs::read_to_string(crate::log::log_path()).unwrap_or_default()
+        };
+        assert!(!send(&entry).contains("[flaky] >"), "logged without the flag");
+
+        entry.config["debug_requests"] = json!("true");
+        let log = send(&entry);
+        assert!(log.contains(&format!("[DEBUG] [flaky] > GET {}", url)), "{}", log);
+        assert!(log.contains("[flaky] > Authorization: Bearer ••••••"), "{}", log);
+        assert!(log.contains("[flaky] < HTTP 200 in "), "{}", log);
+        assert!(!log.contains("tok-secret"));
+
+        // Test connection turns on the CLI's logging for its discovery request
+        crate::providers::probe_provider(&entry).expect("test connection");
+        assert!(fake.calls().iter().any(|c| c.contains("--debug-requests")), "{:?}", fake.calls());
+    }
+
+    #[cfg(unix)]
+    #[test]
+    fn hosted_providers_can_use_their_own_proxy() {
+        let mut fake = crate::testing::FakeCli::new();
+        fake.respond("providers discover-models --type openai", json!({"provider": "openai", "models": [{"id": "gpt-4o"}]}));
+        // The local server stands in for the corporate proxy
+        let (proxy_url, seen) = crate::testing::serve_file(b"{}", "\"v1\"");
+        let proxy = proxy_url.trim_end_matches("/model.gguf").to_string();
+        crate::config_cli::run_config(crate::testing::add_provider("openai", "corp", &[("api_key", "sk-test"), ("model", "gpt-4o")], true)).expect("add provider");
+        let mut entry = crate::variables::resolve_entry(&crate::providers::read_scratch_entries().expect("entries")[0]);
+        assert_eq!(provider_settings(&entry).proxy, None);
+
+        entry.config["proxy"] = json!(proxy);
+        assert_eq!(provider_settings(&entry).proxy.as_deref(), Some(proxy.as_str()));
+        let req = provider_request(&entry, "GET", "http://api.corp.invalid/v1/models".to_string(), None, Duration::from_secs(5));
+        assert!(Client::for_provider(&entry).send(&req).expect("send through the proxy").ok());
+        let head = seen.lock().expect("log").last().cloned().expect("proxied request");
+        assert!(head.starts_with("GET http://api.corp.invalid/v1/models "), "{}", head);
+
+        crate::providers::probe_provider(&entry).expect("test connection");
+        assert!(fake.calls().iter().any(|c| c.ends_with(&format!("--proxy {}", proxy))), "{:?}", fake.calls());
+    }
+}
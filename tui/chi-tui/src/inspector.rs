@@ -0,0 +1,278 @@
+use std::time::{Duration, Instant};
+
+use ratatui::layout::Rect;
+use ratatui::prelude::Frame;
+use ratatui::style::{Modifier, Style};
+use ratatui::text::{Line, Span};
+use ratatui::widgets::{Block, Borders, Clear, Paragraph, Wrap};
+
+use crate::app::App;
+use crate::http::{provider_request, redact, Client};
+use crate::providers::ProviderScratchEntry;
+use crate::theme::StatusKind;
+use crate::util::overlay_rect;
+
+/// Response bodies beyond this are cut in the pane.
+const MAX_BODY_CHARS: usize = 16 * 1024;
+const TEST_TIMEOUT: Duration = Duration::from_secs(10);
+
+/// One HTTP round trip as sent on the wire, secrets redacted.
+#[derive(Clone, Debug, Default)]
+pub struct HttpExchange {
+    pub provider_id: String,
+    pub method: String,
+    pub url: String,
+    pub request_headers: Vec<(String, String)>,
+    pub request_body: Option<String>,
+    pub status: Option<u16>,
+    pub response_headers: Vec<(String, String)>,
+    pub response_body: String,
+    pub elapsed: Duration,
+    pub error: Option<String>,
+}
+
+#[derive(Clone, Debug, Default)]
+pub struct InspectorState {
+    pub last: Option<HttpExchange>,
+    pub visible: bool,
+    pub scroll: u16,
+}
+
+fn cfg_str<'a>(entry: &'a ProviderScratchEntry, key: &str) -> &'a str {
+    entry.config.get(key).and_then(|v| v.as_str()).map(|s| s.trim()).unwrap_or("")
+}
+
+/// Base URL ending in `/v1` for providers with an HTTP chat API: the
+/// OpenAI-compatible ones and Anthropic's Messages API.
+pub fn api_base(entry: &ProviderScratchEntry) -> Option<String> {
+    let base = match entry.ptype.as_str() {
+        "openai" | "anthropic" => {
+            let b = cfg_str(entry, "base_url");
+            if !b.is_empty() {
+                b.to_string()
+            } else if entry.ptype == "anthropic" {
+                "https://api.anthropic.com".to_string()
+            } else {
+                "https://api.openai.com".to_string()
+            }
+        }
+        "openai-compatible" => {
+            let b = cfg_str(entry, "base_url");
+            if b.is_empty() { return None; }
+            b.to_string()
+        }
+        "lmstudio" | "ollama" => crate::http::server_url(entry)?,
+        _ => return None,
+    };
+    let base = base.trim_end_matches('/');
+    Some(if base.ends_with("/v1") { base.to_string() } else { format!("{}/v1", base) })
+}
+
+/// Providers the Playground and deep test can talk to.
+pub fn supports_chat(entry: &ProviderScratchEntry) -> bool {
+    api_base(entry).is_some()
+}
+
+fn pretty(body: &str) -> String {
+    match serde_json::from_str::<serde_json::Value>(body) {
+        Ok(v) => serde_json::to_string_pretty(&v).unwrap_or_else(|_| body.to_string()),
+        Err(_) => body.to_string(),
+    }
+}
+
+/// Send the call a deep test makes: a one-token chat completion when a model
+/// is configured, otherwise `GET /v1/models`. Returns None for providers
+/// without an HTTP chat API.
+pub fn capture(client: &Client, entry: &ProviderScratchEntry) -> Option<HttpExchange> {
+    let base = api_base(entry)?;
+    let model = cfg_str(entry, "model");
+    if model.is_empty() {
+        Some(send(client, entry, "GET", format!("{}/models", base), None, TEST_TIMEOUT))
+    } else {
+        chat(client, entry, "ping", Some(1), TEST_TIMEOUT)
+    }
+}
+
+/// Anthropic's Messages API requires `max_tokens`; used when none is given.
+const ANTHROPIC_MAX_TOKENS: u32 = 1024;
+
+/// URL and JSON body of a single-turn chat request (Chat Completions, or
+/// Anthropic's Messages API) against the provider's configured model.
+pub fn chat_request(entry: &ProviderScratchEntry, prompt: &str, max_tokens: Option<u32>) -> Option<(String, serde_json::Value)> {
+    let base = api_base(entry)?;
+    let mut body = serde_json::json!({
+        "model": cfg_str(entry, "model"),
+        "messages": [{"role": "user", "content": prompt}],
+    });
+    let path = if entry.ptype == "anthropic" {
+        body["max_tokens"] = serde_json::json!(max_tokens.unwrap_or(ANTHROPIC_MAX_TOKENS));
+        "messages"
+    } else {
+        if let Some(n) = max_tokens { body["max_tokens"] = serde_json::json!(n); }
+        "chat/completions"
+    };
+    Some((format!("{}/{}", base, path), body))
+}
+
+/// Single-turn chat completion against the provider's configured model.
+pub fn chat(client: &Client, entry: &ProviderScratchEntry, prompt: &str, max_tokens: Option<u32>, timeout: Duration) -> Option<HttpExchange> {
+    let (url, body) = chat_request(entry, prompt, max_tokens)?;
+    Some(send(client, entry, "POST", url, Some(serde_json::to_string_pretty(&body).unwrap_or_default()), timeout))
+}
+
+/// Assistant text of a chat completion (or Anthropic message), if any.
+pub fn completion_text(ex: &HttpExchange) -> Option<String> {
+    let v: serde_json::Value = serde_json::from_str(&ex.response_body).ok()?;
+    v.pointer("/choices/0/message/content")
+        .or_else(|| v.pointer("/content/0/text"))
+        .and_then(|c| c.as_str())
+        .map(|c| c.to_string())
+}
+
+fn send(client: &Client, entry: &ProviderScratchEntry, method: &str, url: String, body: Option<String>, timeout: Duration) -> HttpExchange {
+    let req = provider_request(entry, method, url, body, timeout);
+    let mut ex = HttpExchange {
+        provider_id: entry.id.clone(),
+        method: req.method.clone(),
+        url: req.url.clone(),
+        // A proxy's own auth header may have any name
+        request_headers: req.headers.iter().map(|(k, v)| (k.clone(), if k.eq_ignore_ascii_case(cfg_str(entry, "auth_header")) { "••••••".to_string() } else { redact(k, v) })).collect(),
+        request_body: req.body.clone(),
+        ..Default::default()
+    };
+    let start = Instant::now();
+    match client.send(&req) {
+        Ok(resp) => {
+            ex.status = Some(resp.status);
+            ex.response_headers = resp.headers.iter().map(|(k, v)| (k.clone(), redact(k, v))).collect();
+            ex.response_body = pretty(&resp.body);
+        }
+        Err(e) => ex.error = Some(e.to_string()),
+    }
+    ex.elapsed = start.elapsed();
+    ex
+}
+
+impl HttpExchange {
+    pub fn ok(&self) -> bool {
+        self.error.is_none() && self.status.map_or(false, |s| (200..300).contains(&s))
+    }
+}
+
+impl InspectorState {
+    pub fn toggle(&mut self) {
+        self.visible = !self.visible;
+        self.scroll = 0;
+    }
+    pub fn scroll_up(&mut self, n: u16) {
+        self.scroll = self.scroll.saturating_sub(n);
+    }
+    pub fn scroll_down(&mut self, n: u16) {
+        self.scroll = self.scroll.saturating_add(n);
+    }
+}
+
+pub fn draw_inspector(f: &mut Frame, area: Rect, app: &App) {
+    let st = &app.inspector;
+    let area_pop = overlay_rect(app.compact, 90, 90, area);
+    let head = Style::default().fg(app.theme.primary).add_modifier(Modifier::BOLD);
+    let key_style = Style::default().fg(app.theme.accent);
+    let mut lines: Vec<Line> = Vec::new();
+    match &st.last {
+        None => lines.push(Line::from("No request captured yet. Run a provider test or a Playground prompt first.")),
+        Some(ex) => {
+            lines.push(Line::from(Span::styled(format!("Request ({})", ex.provider_id), head)));
+            lines.push(Line::from(format!("{} {}", ex.method, ex.url)));
+            for (k, v) in &ex.request_headers {
+                lines.push(Line::from(vec![Span::styled(format!("{}: ", k), key_style), Span::raw(v.clone())]));
+            }
+            if let Some(b) = &ex.request_body {
+                lines.push(Line::from(""));
+                for l in b.lines() { lines.push(Line::from(l.to_string())); }
+            }
+            lines.push(Line::from(""));
+            let (kind, status) = match (ex.status, &ex.error) {
+                (_, Some(e)) => (StatusKind::Err, format!("error: {}", e)),
+                (Some(s), None) if (200..300).contains(&s) => (StatusKind::Ok, format!("HTTP {}", s)),
+                (Some(s), None) => (StatusKind::Err, format!("HTTP {}", s)),
+                (None, None) => (StatusKind::Warn, "no response".to_string()),
+            };
+            lines.push(Line::from(vec![
+                Span::styled("Response ", head),
+                Span::styled(format!("{} {} ", kind.symbol(), status), app.theme.status_style(kind)),
+                Span::styled(format!("({} ms)", ex.elapsed.as_millis()), Style::default().fg(app.theme.secondary)),
+            ]));
+            for (k, v) in &ex.response_headers {
+                lines.push(Line::from(vec![Span::styled(format!("{}: ", k), key_style), Span::raw(v.clone())]));
+            }
+            if !ex.response_body.is_empty() {
+                lines.push(Line::from(""));
+                let body: String = ex.response_body.chars().take(MAX_BODY_CHARS).collect();
+                for l in body.lines() { lines.push(Line::from(l.to_string())); }
+                if ex.response_body.chars().count() > MAX_BODY_CHARS {
+                    lines.push(Line::from(Span::styled("… (truncated)", Style::default().fg(app.theme.secondary))));
+                }
+            }
+        }
+    }
+    let p = Paragraph::new(lines)
+        .style(Style::default().bg(app.theme.bg).fg(app.theme.fg))
+        .block(Block::default().borders(Borders::ALL).border_style(Style::default().fg(app.theme.frame)).title("HTTP Inspector (↑/↓ PgUp/PgDn scroll • i/Esc close)"))
+        .wrap(Wrap { trim: false })
+        .scroll((st.scroll, 0));
+    f.render_widget(Clear, area_pop);
+    f.render_widget(p, area_pop);
+}
+
+#[cfg(all(test, unix))]
+mod tests {
+    use serde_json::json;
+
+    use super::*;
+    use crate::testing::{entry, Canned};
+
+    #[test]
+    fn api_bases_end_in_v1() {
+        assert_eq!(api_base(&entry("o", "openai", json!({}))).as_deref(), Some("https://api.openai.com/v1"));
+        assert_eq!(api_base(&entry("a", "anthropic", json!({}))).as_deref(), Some("https://api.anthropic.com/v1"));
+        assert_eq!(api_base(&entry("c", "openai-compatible", json!({"base_url": "http://gpu:8000/v1/"}))).as_deref(), Some("http://gpu:8000/v1"));
+        assert_eq!(api_base(&entry("c", "openai-compatible", json!({}))), None);
+        assert_eq!(api_base(&entry("l", "lmstudio", json!({"port": 1234}))).as_deref(), Some("http://127.0.0.1:1234/v1"));
+        assert_eq!(api_base(&entry("l", "local", json!({}))), None);
+    }
+
+    #[test]
+    fn without_a_model_the_capture_lists_models() {
+        let canned = Canned::new(&[(200, r#"{"data":[]}"#)]);
+        let client = Client::with_transport(canned.clone(), 0);
+        let ex = capture(&client, &entry("o", "openai", json!({"api_key": "sk-secret"}))).expect("exchange");
+        assert!(ex.ok());
+        assert_eq!((ex.method.as_str(), ex.url.as_str()), ("GET", "https://api.openai.com/v1/models"));
+        assert!(ex.request_headers.contains(&("Authorization".to_string(), "Bearer ••••••".to_string())), "{:?}", ex.request_headers);
+        // Redaction is for display only; the real key is sent
+        assert!(canned.sent()[0].headers.contains(&("Authorization".to_string(), "Bearer sk-secret".to_string())));
+    }
+
+    #[test]
+    fn with_a_model_the_capture_is_a_one_token_chat() {
+        let canned = Canned::new(&[(200, r#"{"choices":[{"message":{"content":"pong"}}]}"#)]);
+        let client = Client::with_transport(canned.clone(), 0);
+        let e = entry("s", "lmstudio", json!({"model": "qwen", "api_key": "t0k", "auth_header": "X-Proxy-Key"}));
+        let ex = capture(&client, &e).expect("exchange");
+        assert_eq!((ex.method.as_str(), ex.url.as_str()), ("POST", "http://127.0.0.1:1234/v1/chat/completions"));
+        assert!(ex.request_headers.contains(&("X-Proxy-Key".to_string(), "••••••".to_string())), "{:?}", ex.request_headers);
+        let body: serde_json::Value = serde_json::from_str(&canned.sent()[0].body.clone().expect("body")).expect("json");
+        assert_eq!((body["model"].as_str(), body["max_tokens"].as_u64()), (Some("qwen"), Some(1)));
+        assert_eq!(completion_text(&ex).as_deref(), Some("pong"));
+    }
+
+    #[test]
+    fn anthropic_requests_always_carry_max_tokens() {
+        let (url, body) = chat_request(&entry("a", "anthropic", json!({"model": "claude"})), "hi", None).expect("request");
+        assert_eq!(url, "https://api.anthropic.com/v1/messages");
+        assert_eq!(body["max_tokens"], json!(ANTHROPIC_MAX_TOKENS));
+        let ex = HttpExchange { response_body: r#"{"content":[{"type":"text","text":"hello"}]}"#.into(), status: Some(200), ..Default::default() };
+        assert_eq!(completion_text(&ex).as_deref(), Some("hello"));
+        assert!(!HttpExchange { status: Some(401), ..Default::default() }.ok());
+    }
+}
@@ -0,0 +1,227 @@
+use std::fs::{self, File, OpenOptions};
+use std::io::{BufRead, BufReader, Write};
+use std::net::{SocketAddr, TcpListener, TcpStream};
+use std::path::PathBuf;
+use std::sync::mpsc::{channel, Receiver, Sender};
+use std::thread;
+use std::time::Duration;
+
+use anyhow::{anyhow, Result};
+use fs2::FileExt;
+use serde_json::Value;
+
+use crate::app::Page;
+use crate::log;
+use crate::server::random_token;
+use crate::util::fnv1a;
+
+/// What a running instance publishes next to its lock: where its control
+/// socket listens and the token a client must send.
+#[derive(Clone, Debug)]
+pub struct InstanceInfo {
+    pub pid: u32,
+    pub port: u16,
+    pub token: String,
+    pub cwd: String,
+    pub started: String,
+}
+
+impl InstanceInfo {
+    fn to_json(&self) -> Value {
+        serde_json::json!({"pid": self.pid, "port": self.port, "token": self.token, "cwd": self.cwd, "started": self.started})
+    }
+
+    fn from_json(v: &Value) -> Option<Self> {
+        Some(InstanceInfo {
+            pid: v.get("pid")?.as_u64()? as u32,
+            port: v.get("port")?.as_u64()? as u16,
+            token: v.get("token")?.as_str()?.to_string(),
+            cwd: v.get("cwd").and_then(|x| x.as_str()).unwrap_or("").to_string(),
+            started: v.get("started").and_then(|x| x.as_str()).unwrap_or("").to_string(),
+        })
+    }
+}
+
+/// Commands received from other processes (chi-tui, `chi-llm` CLI).
+#[derive(Clone, Debug)]
+pub enum Remote {
+    Open(Page),
+    /// A `download.*` request; the UI loop answers on `reply`
+    Download { req: Value, reply: Sender<Result<Value, String>> },
+}
+
+/// This process's claim on the provider store in the working directory.
+/// The store is identified by directory, since `chi.tmp.*` is relative to it.
+/// The first instance holds an exclusive lock and answers one-line JSON
+/// commands on a loopback socket; later ones see `other` instead.
+#[derive(Default)]
+pub struct Instance {
+    lock: Option<File>,
+    info_path: Option<PathBuf>,
+    rx: Option<Receiver<Remote>>,
+    /// Another instance already owns this store
+    pub other: Option<InstanceInfo>,
+}
+
+fn instances_dir() -> Result<PathBuf> {
+    let home = dirs::home_dir().ok_or_else(|| anyhow!("home dir not found"))?;
+    Ok(home.join(".cache").join("chi_llm").join("instances"))
+}
+
+fn store_key() -> Result<(String, String)> {
+    let cwd = std::env::current_dir()?;
+    let cwd = fs::canonicalize(&cwd).unwrap_or(cwd).display().to_string();
+    Ok((format!("{:016x}", fnv1a(&cwd)), cwd))
+}
+
+fn read_info(path: &PathBuf) -> Option<InstanceInfo> {
+    let text = fs::read_to_string(path).ok()?;
+    InstanceInfo::from_json(&serde_json::from_str(&text).ok()?)
+}
+
+impl Instance {
+    /// Lock the store and start the control socket, or record the owner
+    /// when another instance has it. Failures leave an unlocked instance:
+    /// coordination is best-effort and never blocks startup.
+    pub fn acquire() -> Self {
+        match Self::try_acquire() {
+            Ok(inst) => inst,
+            Err(e) => {
+                log::warn(&format!("instance lock unavailable: {}", e));
+                Instance::default()
+            }
+        }
+    }
+
+    fn try_acquire() -> Result<Self> {
+        let (key, cwd) = store_key()?;
+        let dir = instances_dir()?;
+        fs::create_dir_all(&dir)?;
+        let lock = OpenOptions::new().create(true).write(true).open(dir.join(format!("{}.lock", key)))?;
+        let info_path = dir.join(format!("{}.json", key));
+        if lock.try_lock_exclusive().is_err() {
+            let other = read_info(&info_path).ok_or_else(|| anyhow!("store locked by another chi-tui; no instance info"))?;
+            return Ok(Instance { other: Some(other), ..Default::default() });
+        }
+        let listener = TcpListener::bind(("127.0.0.1", 0))?;
+        let info = InstanceInfo {
+            pid: std::process::id(),
+            port: listener.local_addr()?.port(),
+            token: random_token(),
+            cwd,
+            started: chrono::Local::now().to_rfc3339(),
+        };
+        // Holds the control-socket token: user-only
+        crate::secrets::write_private(&info_path, serde_json::to_string_pretty(&info.to_json())?.as_bytes())?;
+        let (tx, rx) = channel();
+        let token = info.token.clone();
+        thread::spawn(move || serve(listener, token, tx));
+        Ok(Instance { lock: Some(lock), info_path: Some(info_path), rx: Some(rx), other: None })
+    }
+
+    /// Commands received since the last call; the UI loop applies them.
+    pub fn poll(&self) -> Vec<Remote> {
+        self.rx.as_ref().map(|rx| rx.try_iter().collect()).unwrap_or_default()
+    }
+}
+
+impl Drop for Instance {
+    fn drop(&mut self) {
+        if self.lock.take().is_some() {
+            if let Some(p) = &self.info_path { let _ = fs::remove_file(p); }
+        }
+    }
+}
+
+fn serve(listener: TcpListener, token: String, tx: Sender<Remote>) {
+    for stream in listener.incoming().flatten() {
+        let _ = stream.set_read_timeout(Some(Duration::from_secs(2)));
+        if let Err(e) = answer(stream, &token, &tx) { log::warn(&format!("instance socket: {}", e)); }
+    }
+}
+
+/// One request line in, one reply line out:
+/// `{"token": "...", "cmd": "open", "page": "configure"}` → `{"ok": true}`.
+fn answer(stream: TcpStream, token: &str, tx: &Sender<Remote>) -> Result<()> {
+    let mut line = String::new();
+    BufReader::new(&stream).read_line(&mut line)?;
+    let reply = match handle(&line, token, tx) {
+        Ok(v) => v,
+        Err(e) => serde_json::json!({"ok": false, "error": e.to_string()}),
+    };
+    let mut out = &stream;
+    writeln!(out, "{}", reply)?;
+    Ok(())
+}
+
+fn handle(line: &str, token: &str, tx: &Sender<Remote>) -> Result<Value> {
+    let req: Value = serde_json::from_str(line.trim())?;
+    if req.get("token").and_then(|t| t.as_str()) != Some(token) { return Err(anyhow!("bad token")); }
+    match req.get("cmd").and_then(|c| c.as_str()).unwrap_or("") {
+        "ping" => Ok(serde_json::json!({"ok": true, "pid": std::process::id()})),
+        "open" => {
+            let name = req.get("page").and_then(|p| p.as_str()).unwrap_or("");
+            let page = Page::from_key(name).ok_or_else(|| anyhow!("unknown page \"{}\"", name))?;
+            tx.send(Remote::Open(page)).map_err(|_| anyhow!("instance is shutting down"))?;
+            Ok(serde_json::json!({"ok": true}))
+        }
+        cmd if cmd.starts_with("download.") => {
+            // Downloads live on the UI thread; ask it and wait for the answer
+            let (reply, answer) = channel();
+            tx.send(Remote::Download { req: req.clone(), reply }).map_err(|_| anyhow!("instance is shutting down"))?;
+            answer.recv_timeout(Duration::from_secs(5)).map_err(|_| anyhow!("chi-tui did not answer"))?.map_err(|e| anyhow!(e))
+        }
+        other => Err(anyhow!("unknown command \"{}\"", other)),
+    }
+}
+
+/// Send one command to a running instance and return its reply.
+pub fn send(info: &InstanceInfo, mut cmd: Value) -> Result<Value> {
+    if let Some(obj) = cmd.as_object_mut() { obj.insert("token".to_string(), Value::String(info.token.clone())); }
+    let addr = SocketAddr::from(([127, 0, 0, 1], info.port));
+    let stream = TcpStream::connect_timeout(&addr, Duration::from_secs(1))?;
+    stream.set_read_timeout(Some(Duration::from_secs(5)))?;
+    let mut out = &stream;
+    writeln!(out, "{}", cmd)?;
+    let mut line = String::new();
+    BufReader::new(&stream).read_line(&mut line)?;
+    let reply: Value = serde_json::from_str(line.trim()).map_err(|e| anyhow!("bad reply from pid {}: {}", info.pid, e))?;
+    if reply.get("ok").and_then(|v| v.as_bool()) != Some(true) {
+        return Err(anyhow!("{}", reply.get("error").and_then(|v| v.as_str()).unwrap_or("request failed")));
+    }
+    Ok(reply)
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+
+    #[test]
+    fn requests_need_the_token() {
+        let (tx, rx) = channel();
+        assert!(handle(r#"{"token": "nope", "cmd": "ping"}"#, "t0k", &tx).is_err());
+        assert_eq!(handle(r#"{"token": "t0k", "cmd": "ping"}"#, "t0k", &tx).expect("ping")["ok"], true);
+        assert!(handle(r#"{"token": "t0k", "cmd": "open", "page": "configure"}"#, "t0k", &tx).is_ok());
+        assert!(matches!(rx.try_recv(), Ok(Remote::Open(Page::Configure))));
+        assert!(handle(r#"{"token": "t0k", "cmd": "open", "page": "nowhere"}"#, "t0k", &tx).is_err());
+        assert!(handle(r#"{"token": "t0k", "cmd": "reboot"}"#, "t0k", &tx).is_err());
+    }
+
+    #[cfg(unix)]
+    #[test]
+    fn the_second_instance_sees_the_first_and_the_info_file_is_private() {
+        use std::os::unix::fs::PermissionsExt;
+        let _fake = crate::testing::FakeCli::new();
+        let first = Instance::acquire();
+        assert!(first.other.is_none());
+        let info_path = first.info_path.clone().expect("info file");
+        assert_eq!(fs::metadata(&info_path).expect("info").permissions().mode() & 0o777, 0o600);
+
+        let second = Instance::acquire();
+        let other = second.other.clone().expect("owner");
+        assert_eq!(other.pid, std::process::id());
+        assert_eq!(send(&other, serde_json::json!({"cmd": "ping"})).expect("ping")["ok"], true);
+        drop(first);
+        assert!(!info_path.exists());
+    }
+}
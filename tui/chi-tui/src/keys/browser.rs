@@ -0,0 +1,229 @@
+//! Model Browser and Recommend keys: selecting, searching, sorting and
+//! downloading models, and handing the chosen one to Configure.
+
+use std::time::Duration;
+
+use crossterm::event::{KeyCode, KeyEvent, KeyModifiers};
+
+use crate::app::{App, Page};
+use crate::backup::maybe_snapshot;
+use crate::cache::{free_space, load_cache};
+use crate::confirm::{ConfirmAction, ConfirmDialog};
+use crate::models::{self, fetch_models, DiskWarning};
+use crate::theme::StatusKind;
+use crate::toast::Toast;
+use crate::{discovery_cache, downloads, recommend, store};
+
+use super::configure::first_default_toast;
+use super::form::{ensure_form_for_selected, write_form_fields};
+
+/// Model Browser keys. Returns true when the key must not reach the page
+/// it switched to.
+pub(super) fn handle_model_browser_key(app: &mut App, key: KeyEvent) -> bool {
+    // While the prefetch runs: the last cached catalog (or "Loading"), not a second fetch
+    if app.model.is_none() && app.prefetch.running() { app.model = models::cached_models(); }
+    if app.model.is_none() && !app.prefetch.running() {
+        match fetch_models(Duration::from_secs(5)) {
+            Ok(mut m) => { m.add_server_entries(); app.model = Some(m) }
+            Err(e) => app.last_error = Some(format!("Models failed: {e}")),
+        }
+    }
+    let Some(m) = &mut app.model else { return false };
+    match key.code {
+        KeyCode::Up => m.move_up(),
+        KeyCode::Down => m.move_down(),
+        KeyCode::Char('r') | KeyCode::Char('R') => m.toggle_downloaded_only(),
+        KeyCode::Char('f') | KeyCode::Char('F') => m.cycle_tag(),
+        // Fetching details (Ollama /api/show, Hub license) opens the pane; `i` again closes it
+        KeyCode::Char('i') | KeyCode::Char('I') => {
+            if m.load_details() { m.show_info = true; } else { m.show_info = !m.show_info; }
+        }
+        KeyCode::Char('/') => m.searching = true,
+        // Forget cached catalogs and server listings, then list again
+        KeyCode::F(5) => {
+            discovery_cache::clear();
+            let search = m.search.clone();
+            app.toast = Some(match fetch_models(Duration::from_secs(5)) {
+                Ok(mut fresh) => {
+                    fresh.add_server_entries();
+                    fresh.set_search(search);
+                    let n = fresh.entries.len();
+                    *m = fresh;
+                    Toast::new(StatusKind::Ok, format!("Refreshed: {} models", n))
+                }
+                Err(e) => Toast::new(StatusKind::Err, format!("Refresh failed: {}", e)),
+            });
+        }
+        // Hub search with the `/` filter text (and the token, when set)
+        KeyCode::Char('h') | KeyCode::Char('H') => {
+            app.toast = Some(if m.search.trim().is_empty() {
+                Toast::new(StatusKind::Warn, "Type a filter with / first, then h searches the Hugging Face Hub for it".to_string())
+            } else {
+                match m.add_hub_results() {
+                    Ok(0) => Toast::new(StatusKind::Warn, format!("No new GGUF repos on the Hub for \"{}\"", m.search.trim())),
+                    Ok(n) => Toast::new(StatusKind::Ok, format!("Added {} GGUF repos from the Hub (tag hf)", n)),
+                    Err(e) => Toast::new(StatusKind::Err, format!("Hub search failed: {}", e)),
+                }
+            });
+        }
+        KeyCode::Char('d') | KeyCode::Char('D') => request_model_download(app),
+        KeyCode::Char('x') | KeyCode::Char('X') => {
+            if let Some(cur) = m.current_entry() { app.downloads.cancel(&cur.id); }
+        }
+        // LM Studio: load the model now instead of on first use
+        KeyCode::Char('l') | KeyCode::Char('L') => {
+            if let Some(cur) = m.current_entry() {
+                app.toast = Some(match (&cur.server, cur.loaded) {
+                    (Some(_), Some(true)) => Toast::new(StatusKind::Ok, format!("{} is already loaded", cur.id)),
+                    (Some(p), Some(false)) if app.lms_loader.start(p, &cur.id) => Toast::new(StatusKind::Ok, format!("Loading {} in LM Studio…", cur.id)),
+                    (Some(_), Some(false)) => Toast::new(StatusKind::Ok, format!("{} is still loading", cur.id)),
+                    _ => Toast::new(StatusKind::Warn, format!("{} is not an LM Studio model with a known load state", cur.id)),
+                });
+            }
+        }
+        // Shift+Enter (Alt+Enter where the terminal cannot report Shift): set and save
+        KeyCode::Enter if key.modifiers.intersects(KeyModifiers::SHIFT | KeyModifiers::ALT) => {
+            if let Some(id) = m.current_entry().map(|cur| cur.id.clone()) {
+                if let Some(path) = use_model_and_save(app, &id) { crate::run_save_hook(app, &path); }
+            }
+            // Configure must not see this Enter
+            return true;
+        }
+        KeyCode::Enter => {
+            if let Some(cur) = m.current_entry() { app.selected_model_id = Some(cur.id.clone()); }
+            app.page = Page::Configure; // return to configure with selected model id
+        }
+        _ => {}
+    }
+    false
+}
+
+/// Model browser search input (`/`): typing narrows the list live; Enter
+/// keeps the filter, Esc clears it.
+pub(super) fn handle_model_search_key(app: &mut App, key: KeyEvent) {
+    let Some(m) = app.model.as_mut() else { return };
+    match key.code {
+        KeyCode::Esc => { m.searching = false; m.set_search(String::new()); }
+        KeyCode::Enter => { m.searching = false; }
+        KeyCode::Up => m.move_up(),
+        KeyCode::Down => m.move_down(),
+        KeyCode::Backspace => { let mut q = m.search.clone(); q.pop(); m.set_search(q); }
+        KeyCode::Char('u') if key.modifiers.contains(KeyModifiers::CONTROL) => m.set_search(String::new()),
+        KeyCode::Char(c) if !key.modifiers.contains(KeyModifiers::CONTROL) => { let q = format!("{}{}", m.search, c); m.set_search(q); }
+        _ => {}
+    }
+}
+
+/// Keys for the low-disk-space prompt; it is modal, so every key is consumed.
+pub(super) fn handle_disk_warning_key(app: &mut App, key: KeyEvent) {
+    let Some(m) = &mut app.model else { return };
+    match key.code {
+        KeyCode::Char('y') | KeyCode::Char('Y') => { m.disk_warning = None; start_model_download(app, true); }
+        KeyCode::Char('c') | KeyCode::Char('C') => { m.disk_warning = None; app.cache = Some(load_cache()); app.page = Page::Cache; }
+        KeyCode::Esc | KeyCode::Char('n') | KeyCode::Char('N') => { m.disk_warning = None; }
+        _ => {}
+    }
+}
+
+/// Model Browser Shift+Enter: set the model on the provider selected in
+/// Configure, validate it like Save and write the store, all in one step.
+/// Returns the written path for the save hook.
+fn use_model_and_save(app: &mut App, model_id: &str) -> Option<String> {
+    let Some(st) = app.providers.as_mut().filter(|s| s.selected < s.entries.len()) else {
+        app.toast = Some(Toast::new(StatusKind::Warn, "Select a provider in Configure first; Shift+Enter then sets its model and saves".to_string()));
+        return None;
+    };
+    app.page = Page::Configure;
+    st.apply_model_to_selected(model_id);
+    ensure_form_for_selected(st);
+    st.focus_right = true;
+    let name = st.entries[st.selected].name.clone();
+    let form = st.form.as_mut()?;
+    if let Some(problem) = form.problem() {
+        app.toast = Some(Toast::new(StatusKind::Err, format!("Model set on {} but not saved: {}", name, problem)));
+        form.message = Some(problem);
+        return None;
+    }
+    write_form_fields(form, &mut st.entries[st.selected].config);
+    form.mark_saved();
+    match st.save() {
+        Ok(()) => {
+            let _ = maybe_snapshot();
+            let (kind, msg) = match st.deprecation_warning() {
+                Some(w) => (StatusKind::Warn, w),
+                None => (StatusKind::Ok, format!("Saved {} with model {}", name, model_id)),
+            };
+            if let Some(form) = &mut st.form { form.message = Some(msg.clone()); }
+            app.toast = Some(Toast::new(kind, msg));
+            if let Some(first) = st.claim_first_default() {
+                app.defaultp = None;
+                app.toast = Some(first_default_toast(&first));
+            }
+            Some(store::path())
+        }
+        Err(e) => {
+            app.last_error = Some(format!("Save failed: {e}"));
+            None
+        }
+    }
+}
+
+/// `d` in the Model Browser: a gated repo without a Hugging Face token asks
+/// first, since the download would be refused.
+fn request_model_download(app: &mut App) {
+    let Some(m) = &mut app.model else { return };
+    if app.hf_token.is_none() {
+        m.load_details();
+        let gated = m.current_entry().and_then(|e| Some((e.id.clone(), m.repo_info_of(e)?.ok()?))).filter(|(_, info)| info.gating.is_gated());
+        if let Some((id, info)) = gated {
+            let msg = vec![
+                format!("{} is {}.", id, info.gating.label()),
+                "No Hugging Face token is configured (Settings → h, or HF_TOKEN), so the download will most likely be refused.".to_string(),
+            ];
+            app.confirm = Some(ConfirmDialog::new("Gated model", msg, "download anyway", ConfirmAction::DownloadModel(id)));
+            return;
+        }
+    }
+    start_model_download(app, false);
+}
+
+/// Download the selected model. Unless `force` is set, a model larger than
+/// the free space in the cache dir opens a warning instead of starting.
+pub(super) fn start_model_download(app: &mut App, force: bool) {
+    let Some(m) = &mut app.model else { return };
+    let Some(cur) = m.current_entry().cloned() else { return };
+    let (Some(repo), Some(file)) = (&cur.repo, &cur.filename) else {
+        app.toast = Some(Toast::new(StatusKind::Err, format!("No download source for {}", cur.id)));
+        return;
+    };
+    if cur.downloaded { app.toast = Some(Toast::new(StatusKind::Ok, format!("{} is already downloaded", cur.id))); return; }
+    if !force {
+        let required = cur.file_size_mb.unwrap_or(0) * 1024 * 1024;
+        let available = downloads::model_dir().ok().and_then(|d| free_space(&d));
+        if let Some(available) = available.filter(|a| required > *a) {
+            m.disk_warning = Some(DiskWarning { id: cur.id.clone(), required, available });
+            return;
+        }
+    }
+    let urls = downloads::sources(&cur.id, repo, file, &cur.raw);
+    let sha256 = cur.raw.get("sha256").and_then(|v| v.as_str()).map(str::to_string);
+    if let Err(e) = app.downloads.start(&cur.id, urls, file, sha256) { app.toast = Some(Toast::new(StatusKind::Warn, e.to_string())); }
+}
+
+/// Recommend page keys.
+pub(super) fn handle_recommend_key(app: &mut App, key: KeyEvent) {
+    let Some(st) = &mut app.recommend else { return };
+    match key.code {
+        KeyCode::Up => st.move_up(),
+        KeyCode::Down => st.move_down(),
+        KeyCode::Char('r') | KeyCode::Char('R') => { *st = recommend::RecommendState::new(app.model.as_ref().map_or(&[][..], |m| &m.entries)); }
+        // Open it in the Model Browser to download or choose it
+        KeyCode::Enter => {
+            if let (Some(cur), Some(m)) = (st.current(), &mut app.model) {
+                m.select_id(&cur.id);
+                app.page = Page::ModelBrowser;
+            }
+        }
+        _ => {}
+    }
+}
@@ -0,0 +1,148 @@
+//! Build/Write Configuration keys, including the project config diff, the
+//! per-field merge tool and the guard shown while providers are missing.
+
+use crossterm::event::{KeyCode, KeyEvent};
+
+use crate::app::{App, Page};
+use crate::build::{self, BuildState, BuildTarget, write_active_config};
+use crate::configmerge::{ConfigMerge, Pick};
+use crate::confirm::{ConfirmAction, ConfirmDialog};
+use crate::theme::StatusKind;
+use crate::toast::Toast;
+use crate::{buildconflict, envfile, open_page_loaded};
+
+use super::configure::find_local_servers;
+
+/// Build page keys. Returns the path written.
+pub(super) fn handle_build_key(app: &mut App, key: KeyEvent) -> Option<String> {
+    if app.build.is_none() {
+        app.build = Some(BuildState { target: app.prefs.build_target, ..Default::default() });
+    }
+    if let Some(missing) = build::missing_prerequisite() {
+        handle_build_guard_key(app, key, missing);
+        return None;
+    }
+    let st = app.build.as_mut()?;
+    match key.code {
+        KeyCode::Char('g') | KeyCode::Char('G') => { st.toggle_target(); }
+        KeyCode::Char('u') | KeyCode::Char('U') => match build::active_config_source() {
+            build::ConfigSource::Project(p) => {
+                let msg = vec![format!("Move {} aside to {}.bak?", p, p), "chi_llm then uses the global config in this directory.".to_string()];
+                app.confirm = Some(ConfirmDialog::new("Use global here", msg, "use global", ConfirmAction::UseGlobalHere));
+            }
+            _ => st.status = Some("No project override here; global settings already apply".to_string()),
+        },
+        KeyCode::Char('p') | KeyCode::Char('P') => match build::active_config_source() {
+            build::ConfigSource::Project(p) => {
+                let global = build::global_config_path().map(|g| g.display().to_string()).unwrap_or_else(|e| e.to_string());
+                let msg = vec![format!("Copy the settings in {} to {}?", p, global), "Other keys in the global config are kept.".to_string()];
+                app.confirm = Some(ConfirmDialog::new("Pin globally", msg, "pin", ConfirmAction::PinGlobally));
+            }
+            _ => st.status = Some("Warning: no project config to pin".to_string()),
+        },
+        KeyCode::Char('m') | KeyCode::Char('M') => match ConfigMerge::load() {
+            Ok(m) => st.merge = Some(m),
+            Err(e) => st.status = Some(format!("Warning: {}", e)),
+        },
+        KeyCode::Char('e') | KeyCode::Char('E') => {
+            st.status = Some(match envfile::write_files() {
+                Ok(w) if w.is_empty() => format!("{} and {} are up to date", envfile::DOTENV, envfile::ENVRC),
+                Ok(w) => format!("Written: {}", w.join(", ")),
+                Err(e) => format!("Error: {}", e),
+            });
+        }
+        KeyCode::Enter if st.target == BuildTarget::Project => match buildconflict::plan_project_write() {
+            Ok(Some(conflict)) => st.conflict = Some(conflict),
+            Ok(None) => return write_build(st),
+            Err(e) => st.status = Some(format!("Error: {}", e)),
+        },
+        KeyCode::Enter => return write_build(st),
+        _ => {}
+    }
+    None
+}
+
+/// Build keys while `build::missing_prerequisite` holds: jump to the page
+/// that fixes it.
+fn handle_build_guard_key(app: &mut App, key: KeyEvent, missing: build::Missing) {
+    match key.code {
+        KeyCode::Char('c') | KeyCode::Char('C') => open_page_loaded(app, Page::Configure),
+        KeyCode::Char('f') | KeyCode::Char('F') if missing == build::Missing::Providers => {
+            open_page_loaded(app, Page::Configure);
+            if let Some(st) = app.providers.as_mut() { find_local_servers(st); }
+        }
+        KeyCode::Char('d') | KeyCode::Char('D') if missing == build::Missing::Default => open_page_loaded(app, Page::SelectDefault),
+        KeyCode::Enter => {
+            let hint = match missing {
+                build::Missing::Providers => "Nothing to write: add a provider first (c Configure, f find local servers)",
+                build::Missing::Default => "Nothing to write: pick a default provider first (d Select Default)",
+            };
+            app.toast = Some(Toast::new(StatusKind::Warn, hint.to_string()));
+        }
+        _ => {}
+    }
+}
+
+/// Build page Enter: write the target and report it. Returns the written path.
+fn write_build(st: &mut BuildState) -> Option<String> {
+    match write_active_config(st.target) {
+        Ok(path) => { st.status = Some(format!("Written: {}", path)); Some(path) }
+        Err(e) => { st.status = Some(format!("Error: {}", e)); None }
+    }
+}
+
+/// Keys for the project config diff; it is modal, so every key is consumed.
+/// Returns the path written.
+pub(super) fn handle_build_conflict_key(app: &mut App, key: KeyEvent) -> Option<String> {
+    let st = app.build.as_mut()?;
+    let c = st.conflict.as_mut()?;
+    let res = match key.code {
+        KeyCode::Char('o') | KeyCode::Char('O') => c.overwrite(),
+        KeyCode::Char('m') | KeyCode::Char('M') if c.merged.is_some() => c.merge().map(|p| format!("{} (merged)", p)),
+        KeyCode::Tab if c.merged.is_some() => { c.show_merged = !c.show_merged; return None; }
+        KeyCode::Up => { c.scroll = c.scroll.saturating_sub(1); return None; }
+        KeyCode::Down => { c.scroll = c.scroll.saturating_add(1); return None; }
+        KeyCode::PageUp => { c.scroll = c.scroll.saturating_sub(10); return None; }
+        KeyCode::PageDown => { c.scroll = c.scroll.saturating_add(10); return None; }
+        KeyCode::Esc | KeyCode::Char('c') | KeyCode::Char('C') => {
+            st.conflict = None;
+            st.status = Some("Cancelled; project config left as it was".to_string());
+            return None;
+        }
+        _ => return None,
+    };
+    st.conflict = None;
+    match res {
+        Ok(path) => { st.status = Some(format!("Written: {}", path)); Some(path) }
+        Err(e) => { st.status = Some(format!("Error: {}", e)); None }
+    }
+}
+
+/// Build merge tool keys. Returns the path written.
+pub(super) fn handle_config_merge_key(app: &mut App, key: KeyEvent) -> Option<String> {
+    let st = app.build.as_mut()?;
+    let m = st.merge.as_mut()?;
+    match key.code {
+        KeyCode::Up => { if m.selected > 0 { m.selected -= 1; } }
+        KeyCode::Down => { if m.selected + 1 < m.fields.len() { m.selected += 1; } }
+        KeyCode::Left => m.set_selected(Pick::Project),
+        KeyCode::Right => m.set_selected(Pick::Global),
+        KeyCode::Char(' ') => m.toggle_selected(),
+        KeyCode::Char('p') | KeyCode::Char('P') => m.set_all(Pick::Project),
+        KeyCode::Char('g') | KeyCode::Char('G') => m.set_all(Pick::Global),
+        KeyCode::Char('w') | KeyCode::Char('W') | KeyCode::Enter => {
+            let res = m.write();
+            st.merge = None;
+            return match res {
+                Ok(path) => { st.status = Some(format!("Merged into {}", path)); Some(path) }
+                Err(e) => { st.status = Some(format!("Error: {}", e)); None }
+            };
+        }
+        KeyCode::Esc => {
+            st.merge = None;
+            st.status = Some("Merge cancelled; project config left as it was".to_string());
+        }
+        _ => {}
+    }
+    None
+}
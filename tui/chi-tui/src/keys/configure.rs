@@ -0,0 +1,360 @@
+//! Configure Providers keys: the provider list on the left, the type and
+//! model dropdowns, and the import, QR, export and paste overlays. The form
+//! on the right is in `form`.
+
+use crossterm::event::{KeyCode, KeyEvent, KeyModifiers};
+
+use crate::app::{App, Page};
+use crate::backup::maybe_snapshot;
+use crate::confirm::{ConfirmAction, ConfirmDialog};
+use crate::providers::{self, ProvidersState, load_providers_state, test_connection};
+use crate::theme::StatusKind;
+use crate::toast::Toast;
+use crate::{clipboard, export, focus, http, inspector, qr, store, variables};
+
+use super::form::{self, ensure_form_for_selected};
+
+/// Says why a provider became the default on its own and how to undo it.
+pub(super) fn first_default_toast(name: &str) -> Toast {
+    Toast::new(StatusKind::Ok, format!("{} is now the default provider (the first one configured) • u undo", name))
+}
+
+/// `f` in Configure: probe for local servers and preview them for import.
+pub(super) fn find_local_servers(st: &mut ProvidersState) {
+    match providers::detect_preview(st) {
+        Ok(preview) => st.import = Some(preview),
+        Err(e) => st.test_status = Some(format!("Warning: {}", e)),
+    }
+}
+
+/// Configure page keys. Returns the store path written.
+pub(super) fn handle_configure_key(app: &mut App, key: KeyEvent) -> Option<String> {
+    if app.providers.is_none() {
+        app.providers = Some(match load_providers_state() {
+            Ok(s) => s,
+            Err(e) => { app.last_error = Some(format!("Load providers failed: {e}")); ProvidersState::empty() }
+        });
+    }
+    // HTTP inspector overlay swallows keys while open
+    if app.inspector.visible {
+        match key.code {
+            KeyCode::Up => app.inspector.scroll_up(1),
+            KeyCode::Down => app.inspector.scroll_down(1),
+            KeyCode::PageUp => app.inspector.scroll_up(10),
+            KeyCode::PageDown => app.inspector.scroll_down(10),
+            KeyCode::Char('i') | KeyCode::Char('I') => app.inspector.toggle(),
+            _ => {}
+        }
+        return None;
+    }
+    let st = app.providers.as_mut()?;
+    if st.dropdown.is_some() {
+        handle_dropdown_key(st, key);
+        return None;
+    }
+    if matches!(key.code, KeyCode::Tab | KeyCode::BackTab) {
+        focus::cycle(app);
+        return None;
+    }
+    if st.focus_right {
+        form::handle_form_key(app, key);
+        return None;
+    }
+    let wrote = handle_list_key(app, key);
+    // If a model was picked in model browser, apply to selected provider
+    if let Some(model_id) = app.selected_model_id.take() {
+        if let Some(st) = &mut app.providers { st.apply_model_to_selected(&model_id); }
+    }
+    // A save can set (or undo) the first provider's default
+    if wrote.is_some() { app.defaultp = None; }
+    wrote
+}
+
+/// Dropdown (provider type, or a field's options); modal, so every key is
+/// consumed.
+fn handle_dropdown_key(st: &mut ProvidersState, key: KeyEvent) {
+    let Some(dd) = &mut st.dropdown else { return };
+    match key.code {
+        KeyCode::Up => { if dd.selected > 0 { dd.selected -= 1; } }
+        KeyCode::Down => { if dd.selected + 1 < dd.items.len() { dd.selected += 1; } }
+        KeyCode::Enter => {
+            if dd.selected < dd.items.len() {
+                let chosen = dd.items[dd.selected].clone();
+                match dd.target_field {
+                    None => {
+                        if st.selected < st.entries.len() {
+                            st.entries[st.selected].ptype = chosen.clone();
+                            ensure_form_for_selected(st);
+                            if let Some(form) = &mut st.form {
+                                form.selected = 1.min(form.fields.len());
+                                form.editing = false;
+                                form.message = Some("Type changed".to_string());
+                            }
+                        }
+                    }
+                    Some(fi) => {
+                        if let Some(form) = &mut st.form {
+                            if fi < form.fields.len() {
+                                form.fields[fi].buffer = chosen.clone();
+                                form.editing = false;
+                                form.message = Some(format!("{} set", form.fields[fi].schema.name));
+                            }
+                        }
+                    }
+                }
+            }
+            st.dropdown = None;
+        }
+        KeyCode::Esc => { st.dropdown = None; }
+        _ => {}
+    }
+}
+
+/// Left pane: list navigation and actions. Returns the store path written.
+fn handle_list_key(app: &mut App, key: KeyEvent) -> Option<String> {
+    let st = app.providers.as_mut()?;
+    let mut wrote = None;
+    match key.code {
+        KeyCode::Up => { if st.selected > 0 { st.selected -= 1; st.form = None; } },
+        KeyCode::Down => { if st.selected + 1 < st.len_with_add() { st.selected += 1; st.form = None; } },
+        KeyCode::Enter => {
+            if st.is_add_row() { st.add_default(); }
+            ensure_form_for_selected(st);
+            st.focus_right = true;
+        }
+        KeyCode::Char('a') | KeyCode::Char('A') => { st.add_default(); ensure_form_for_selected(st); st.focus_right = true; }
+        KeyCode::Char('d') | KeyCode::Char('D') => {
+            if let Some(e) = st.entries.get(st.selected) {
+                let msg = vec![format!("Delete provider \"{}\" ({}, id {})?", e.name, e.ptype, e.id), "The store changes when you save (s).".to_string()];
+                app.confirm = Some(ConfirmDialog::new("Delete provider", msg, "delete", ConfirmAction::DeleteProvider(e.id.clone())));
+            }
+        }
+        KeyCode::Char('m') | KeyCode::Char('M') => { app.page = Page::ModelBrowser; }
+        // Session provider: add one that is never written to disk
+        KeyCode::Char('e') | KeyCode::Char('E') => {
+            st.add_session();
+            ensure_form_for_selected(st);
+            st.focus_right = true;
+            st.test_status = Some("Session provider: usable everywhere until you quit; g saves it to this project".to_string());
+        }
+        // Promote to the global list / demote to this project; written on save.
+        // A session provider is saved to the project right away.
+        KeyCode::Char('g') | KeyCode::Char('G') => {
+            let was_session = st.entries.get(st.selected).map_or(false, |e| e.scope == providers::Scope::Session);
+            if let Some(scope) = st.toggle_scope_selected() {
+                let id = st.entries[st.selected].id.clone();
+                st.test_status = Some(match scope {
+                    providers::Scope::Project if was_session => match st.save() {
+                        Ok(()) => {
+                            let _ = maybe_snapshot();
+                            wrote = Some(store::path());
+                            if let Some(first) = st.claim_first_default() { app.toast = Some(first_default_toast(&first)); }
+                            format!("{} saved to this project", id)
+                        }
+                        Err(e) => format!("Error: save failed: {}", e),
+                    },
+                    providers::Scope::Global => format!("{} moves to the global list on save (s)", id),
+                    _ => format!("{} moves to this project on save (s)", id),
+                });
+            }
+        }
+        KeyCode::Char('i') | KeyCode::Char('I') => { app.inspector.toggle(); }
+        KeyCode::Char('y') | KeyCode::Char('Y') => copy_provider(st),
+        KeyCode::Char('p') => {
+            match clipboard::paste() {
+                Ok(text) => match providers::parse_import(&text, st) {
+                    Ok(preview) => st.import = Some(preview),
+                    Err(e) => st.test_status = Some(format!("Error: {}", e)),
+                },
+                // No clipboard tool (e.g. over SSH): take the JSON as typed input
+                Err(_) => st.paste_input = Some(String::new()),
+            }
+        }
+        KeyCode::Char('P') => { st.paste_input = Some(String::new()); }
+        KeyCode::Char('f') | KeyCode::Char('F') => find_local_servers(st),
+        KeyCode::Char('x') | KeyCode::Char('X') => {
+            if let Some(entry) = st.entries.get(st.selected) { st.export = Some(export::ExportPicker::new(&entry.id)); }
+        }
+        // Clone for a variant (e.g. another model); the form opens on the copy
+        KeyCode::Char('c') => {
+            if let Some(id) = st.clone_selected() {
+                ensure_form_for_selected(st);
+                st.focus_right = true;
+                st.test_status = Some(format!("Cloned as {}; change what differs, then save (s)", id));
+            }
+        }
+        KeyCode::Char('C') => show_qr(st),
+        KeyCode::Char('T') => {
+            if let Some(entry) = st.entries.get(st.selected) {
+                let entry = variables::resolve_entry(entry);
+                if let Some(id) = &app.deep_test.running {
+                    st.test_status = Some(format!("Warning: deep test of {} still running", id));
+                } else if let Err(e) = app.portfw.ensure(&entry) {
+                    st.test_status = Some(format!("Error: {}", e));
+                } else {
+                    st.test_status = Some(format!("Deep test {}: waiting for the model's first token…", entry.id));
+                    app.deep_test.start(entry);
+                }
+            }
+        }
+        KeyCode::Char('t') => {
+            if st.selected < st.entries.len() {
+                let entry = &variables::resolve_entry(&st.entries[st.selected]);
+                st.test_status = Some(match app.portfw.ensure(entry).and_then(|_| test_connection(entry)) {
+                    Ok(t) => { app.model_index.record(&entry.id, &entry.name, &t.model_ids); t.message }
+                    Err(e) => format!("Error: {}", e),
+                });
+                if let Some(ex) = inspector::capture(&http::Client::for_provider(entry), entry) { app.inspector.last = Some(ex); }
+            }
+        }
+        // Save from left pane
+        KeyCode::Char('s') | KeyCode::Char('S') => {
+            match st.save() {
+                Ok(()) => {
+                    let _ = maybe_snapshot();
+                    wrote = Some(store::path());
+                    if let Some(w) = st.deprecation_warning() { st.test_status = Some(w); }
+                    if let Some(first) = st.claim_first_default() { app.toast = Some(first_default_toast(&first)); }
+                }
+                Err(e) => app.last_error = Some(format!("Save failed: {e}")),
+            }
+        }
+        // Undo the automatic default of the first saved provider
+        KeyCode::Char('u') | KeyCode::Char('U') if st.auto_default.is_some() => {
+            match st.undo_auto_default() {
+                Ok(Some(id)) => {
+                    wrote = Some(store::path());
+                    app.toast = Some(Toast::new(StatusKind::Ok, format!("{} is no longer the default; pick one in Select Default", id)));
+                }
+                Ok(None) => app.toast = Some(Toast::new(StatusKind::Warn, "The default was changed since; nothing to undo".to_string())),
+                Err(e) => app.last_error = Some(format!("Undo default failed: {e}")),
+            }
+        }
+        _ => {}
+    }
+    wrote
+}
+
+/// `y`: the selected provider as JSON on the clipboard, without secrets.
+fn copy_provider(st: &mut ProvidersState) {
+    let Some(entry) = st.entries.get(st.selected) else { return };
+    // Shared JSON carries values; the recipient has no variables
+    let entry = &variables::resolve_entry(entry);
+    let (json, omitted) = providers::export_entry(entry, st.schema_map.get(&entry.ptype));
+    let text = serde_json::to_string_pretty(&json).unwrap_or_default();
+    st.test_status = Some(match clipboard::copy(&text) {
+        Ok(via) if omitted.is_empty() => format!("Copied {} via {}", entry.id, via),
+        Ok(via) => format!("Copied {} via {} ({} omitted)", entry.id, via, omitted.join(", ")),
+        Err(e) => format!("Error: copy failed: {}", e),
+    });
+}
+
+/// `C`: the selected provider as a QR code, without secrets.
+fn show_qr(st: &mut ProvidersState) {
+    let Some(entry) = st.entries.get(st.selected) else { return };
+    let entry = &variables::resolve_entry(entry);
+    let (payload, omitted) = providers::share_payload(entry, st.schema_map.get(&entry.ptype));
+    match qr::render(&payload) {
+        Ok(lines) => st.qr = Some(qr::ShareQr { title: format!("{} [{}]", entry.name, entry.ptype), lines, payload, omitted }),
+        Err(e) => st.test_status = Some(format!("Error: {}", e)),
+    }
+}
+
+/// Clipboard import preview on Configure; modal, so every key is consumed.
+pub(super) fn handle_import_key(app: &mut App, key: KeyEvent) {
+    let Some(st) = app.providers.as_mut() else { return };
+    match key.code {
+        KeyCode::Up => { if let Some(p) = st.import.as_mut() { p.scroll = p.scroll.saturating_sub(1); } }
+        KeyCode::Down => { if let Some(p) = st.import.as_mut() { p.scroll = p.scroll.saturating_add(1); } }
+        KeyCode::Enter => {
+            let Some(preview) = st.import.take() else { return };
+            let added = providers::apply_import(st, preview);
+            st.test_status = Some(if added > 0 { format!("Imported {} provider(s) — press s to save", added) } else { "Warning: nothing imported".to_string() });
+        }
+        KeyCode::Esc => { st.import = None; st.test_status = Some("Import cancelled".to_string()); }
+        _ => {}
+    }
+}
+
+/// QR code overlay on Configure: `y` copies the encoded JSON, any other key closes.
+pub(super) fn handle_qr_key(app: &mut App, key: KeyEvent) {
+    let Some(st) = app.providers.as_mut() else { return };
+    let Some(q) = st.qr.take() else { return };
+    if let KeyCode::Char('y') | KeyCode::Char('Y') = key.code {
+        st.test_status = Some(match clipboard::copy(&q.payload) {
+            Ok(via) => format!("Copied QR payload via {}", via),
+            Err(e) => format!("Error: copy failed: {}", e),
+        });
+    }
+}
+
+/// Export picker on Configure: ↑/↓ format, PgUp/PgDn scroll the preview,
+/// Enter/y copies the snippet, Esc closes.
+pub(super) fn handle_export_key(app: &mut App, key: KeyEvent) {
+    let Some(st) = app.providers.as_mut() else { return };
+    let Some(picker) = st.export.as_mut() else { return };
+    match key.code {
+        KeyCode::Esc | KeyCode::Char('x') | KeyCode::Char('X') => { st.export = None; }
+        KeyCode::Up => { picker.selected = picker.selected.saturating_sub(1); picker.scroll = 0; }
+        KeyCode::Down => { picker.selected = (picker.selected + 1).min(export::Format::ALL.len() - 1); picker.scroll = 0; }
+        KeyCode::PageUp => { picker.scroll = picker.scroll.saturating_sub(5); }
+        KeyCode::PageDown => { picker.scroll = picker.scroll.saturating_add(5); }
+        KeyCode::Enter | KeyCode::Char('y') | KeyCode::Char('Y') => {
+            let fmt = picker.format();
+            let Some(entry) = st.entries.iter().find(|e| e.id == picker.provider_id) else { st.export = None; return };
+            st.test_status = Some(match export::render(entry, fmt).and_then(|text| clipboard::copy(&text)) {
+                Ok(via) => format!("Copied {} snippet for {} via {}", fmt.label(), entry.name, via),
+                Err(e) => format!("Error: export failed: {}", e),
+            });
+            st.export = None;
+        }
+        _ => {}
+    }
+}
+
+/// One-line JSON input on Configure (decoded QR text or a terminal paste);
+/// Enter opens the usual import preview.
+pub(super) fn handle_paste_input_key(app: &mut App, key: KeyEvent) {
+    let Some(st) = app.providers.as_mut() else { return };
+    let Some(buf) = st.paste_input.as_mut() else { return };
+    match key.code {
+        KeyCode::Esc => { st.paste_input = None; }
+        KeyCode::Backspace => { buf.pop(); }
+        KeyCode::Enter => {
+            let text = st.paste_input.take().unwrap_or_default();
+            match providers::parse_import(&text, st) {
+                Ok(preview) => st.import = Some(preview),
+                Err(e) => st.test_status = Some(format!("Error: {}", e)),
+            }
+        }
+        KeyCode::Char(c) if !key.modifiers.contains(KeyModifiers::CONTROL) => buf.push(c),
+        _ => {}
+    }
+}
+
+#[cfg(all(test, unix))]
+mod tests {
+    use super::*;
+    use crate::config_cli::run_config;
+    use crate::testing::{add_provider, FakeCli};
+
+    #[test]
+    fn the_first_saved_provider_becomes_the_default_until_undone() {
+        let fake = FakeCli::new();
+        run_config(add_provider("local", "laptop", &[], false)).expect("add provider");
+        let mut app = App::new(false);
+        crate::open_page_loaded(&mut app, Page::Configure);
+
+        crate::keys::handle_key(&mut app, KeyEvent::new(KeyCode::Char('s'), KeyModifiers::NONE));
+        assert_eq!(fake.store()["default_provider_id"], "laptop");
+        let toast = app.toast.as_ref().expect("default toast");
+        assert!(toast.text.starts_with("local is now the default provider"), "{}", toast.text);
+
+        crate::keys::handle_key(&mut app, KeyEvent::new(KeyCode::Char('u'), KeyModifiers::NONE));
+        assert!(fake.store().get("default_provider_id").is_none());
+        assert!(app.providers.as_ref().expect("providers").auto_default.is_none());
+        // The undo sticks: saving again does not pick it a second time
+        crate::keys::handle_key(&mut app, KeyEvent::new(KeyCode::Char('s'), KeyModifiers::NONE));
+        assert!(fake.store().get("default_provider_id").is_none());
+    }
+}
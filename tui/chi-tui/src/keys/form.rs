@@ -0,0 +1,337 @@
+//! Configure's right pane: the provider form. Fields follow the provider
+//! schema; Test checks the typed values and Save requires a passing test
+//! of exactly those values.
+
+use crossterm::event::{KeyCode, KeyEvent};
+use serde_json::Value;
+
+use crate::app::App;
+use crate::providers::{self, DropdownState, FormField, FormState, ProvidersState, test_connection};
+use crate::{deprecations, discovery_cache, http, inspector, model_defaults, modelname, prefs, secrets, variables};
+
+/// Provider types whose connection test and model list go over the network.
+const SERVER_TYPES: [&str; 5] = ["lmstudio", "ollama", "openai", "openai-compatible", "anthropic"];
+
+pub fn ensure_form_for_selected(st: &mut ProvidersState) {
+    if st.selected >= st.entries.len() { st.form = None; return; }
+    let entry = &st.entries[st.selected];
+    let mut ff = Vec::new();
+    if let Some(sfields) = st.schema_map.get(&entry.ptype) {
+        for sc in sfields.iter() {
+            let mut value = String::new();
+            if let Some(cfg) = entry.config.as_object() {
+                if let Some(v) = cfg.get(&sc.name) {
+                    value = match v { Value::String(s) => s.clone(), other => other.to_string() };
+                }
+            }
+            // New providers start from the per-type default model (Settings, m)
+            if value.is_empty() && sc.name == "model" { value = model_defaults::for_type(&entry.ptype).unwrap_or_default(); }
+            if value.is_empty() { if let Some(d) = &sc.default { value = d.clone(); } }
+            ff.push(FormField { schema: sc.clone(), buffer: value, cursor: 0 });
+        }
+    }
+    // Basic fields first; required fields are never hidden
+    let is_basic = |f: &FormField| !f.schema.advanced || f.schema.required;
+    let (mut basic, advanced): (Vec<_>, Vec<_>) = ff.into_iter().partition(is_basic);
+    let basic_len = basic.len();
+    basic.extend(advanced);
+    let ff = basic;
+    let init_hash = providers::compute_form_hash(&ff);
+    let initial = ff.iter().map(|f| f.buffer.clone()).collect();
+    st.form = Some(FormState { fields: ff, selected: 0, editing: false, message: None, scroll: 0, initial_hash: init_hash, initial, last_test_ok_hash: None, basic_len, show_advanced: false });
+}
+
+/// Form values into a provider config, typed per schema.
+pub(super) fn write_form_fields(form: &FormState, config: &mut Value) {
+    let Some(obj) = config.as_object_mut() else { return };
+    for ff in &form.fields {
+        let key = ff.schema.name.clone();
+        if ff.schema.ftype == "int" {
+            if let Ok(n) = ff.buffer.parse::<i64>() { obj.insert(key, Value::Number(n.into())); } else { obj.insert(key, Value::String(ff.buffer.clone())); }
+        } else if ff.schema.ftype == "float" {
+            match ff.buffer.trim().parse::<f64>().ok().and_then(serde_json::Number::from_f64) { Some(n) => { obj.insert(key, Value::Number(n)); } None => { obj.insert(key, Value::String(ff.buffer.clone())); } }
+        } else {
+            obj.insert(key, Value::String(ff.buffer.clone()));
+        }
+    }
+}
+
+/// +/- and ←/→ on a numeric field (not editing): step within schema bounds.
+fn step_numeric_field(form: &mut FormState, dir: f64) {
+    let visible = form.visible_len();
+    let Some(ff) = form.selected.checked_sub(1).filter(|i| *i < visible).and_then(|i| form.fields.get_mut(i)) else { return };
+    if !ff.schema.is_numeric() { return; }
+    ff.buffer = ff.schema.step_value(&ff.buffer, dir);
+    ff.cursor = ff.buffer.chars().count();
+    form.last_test_ok_hash = None;
+}
+
+fn focus_form_field(st: &mut ProvidersState, field_name: &str) {
+    if st.selected >= st.entries.len() { return; }
+    ensure_form_for_selected(st);
+    if let Some(form) = &mut st.form {
+        if let Some(idx) = form.fields.iter().position(|f| f.schema.name == field_name) {
+            form.selected = idx;
+            form.editing = true;
+            st.focus_right = true;
+        } else {
+            st.focus_right = true;
+        }
+    }
+}
+
+/// Keys while the form has focus; every key is consumed.
+pub(super) fn handle_form_key(app: &mut App, key: KeyEvent) {
+    let Some(st) = app.providers.as_mut() else { return };
+    if st.form.is_none() && st.selected < st.entries.len() { ensure_form_for_selected(st); }
+    let Some(form) = &mut st.form else { return };
+    match key.code {
+        KeyCode::Esc => { if form.editing { form.editing = false; } else { st.focus_right = false; } }
+        // Up/Down navigate between form groups. Treat [Test|Save|Cancel] as one group.
+        KeyCode::Up => {
+            let test_idx = form.test_idx();
+            if form.selected >= test_idx {
+                // Jump to the row above the buttons (expander, last field or Type)
+                form.selected = test_idx - 1;
+            } else if form.selected > 0 {
+                form.selected -= 1;
+            }
+        }
+        KeyCode::Down => {
+            if form.selected >= form.test_idx() {
+                // Already in the last group; stay within group on Down
+            } else if form.selected + 1 < form.total() {
+                form.selected += 1;
+            }
+        }
+        KeyCode::Enter => press_form_enter(app),
+        // Left/Right: within button group, switch between Test/Save/Cancel. In fields, move cursor when editing.
+        KeyCode::Left => {
+            if form.selected > form.test_idx() {
+                form.selected -= 1;
+            } else if form.editing {
+                if let Some(ff) = form.fields.get_mut(form.selected) {
+                    if ff.cursor > 0 { ff.cursor -= 1; }
+                }
+            } else {
+                step_numeric_field(form, -1.0);
+            }
+        }
+        KeyCode::Right => {
+            if form.selected >= form.test_idx() && form.selected < form.cancel_idx() {
+                form.selected += 1;
+            } else if form.editing {
+                if let Some(ff) = form.fields.get_mut(form.selected) {
+                    if ff.cursor < ff.buffer.chars().count() { ff.cursor += 1; }
+                }
+            } else {
+                step_numeric_field(form, 1.0);
+            }
+        }
+        KeyCode::Home => { if form.editing { if let Some(ff) = form.fields.get_mut(form.selected) { ff.cursor = 0; } } }
+        KeyCode::End => { if form.editing { if let Some(ff) = form.fields.get_mut(form.selected) { ff.cursor = ff.buffer.chars().count(); } } }
+        KeyCode::Backspace if form.editing => {
+            if let Some(ff) = form.fields.get_mut(form.selected).filter(|ff| ff.cursor > 0) {
+                ff.cursor -= 1;
+                remove_char(ff);
+                form.last_test_ok_hash = None;
+            }
+        }
+        KeyCode::Delete if form.editing => {
+            if let Some(ff) = form.fields.get_mut(form.selected).filter(|ff| ff.cursor < ff.buffer.chars().count()) {
+                remove_char(ff);
+                form.last_test_ok_hash = None;
+            }
+        }
+        KeyCode::Char('+') | KeyCode::Char('=') if !form.editing => { step_numeric_field(form, 1.0); }
+        KeyCode::Char('-') if !form.editing => { step_numeric_field(form, -1.0); }
+        KeyCode::Tab => { let total = form.total(); form.selected = (form.selected + 1) % total; }
+        KeyCode::BackTab => { let total = form.total(); form.selected = if form.selected == 0 { total - 1 } else { form.selected - 1 }; }
+        KeyCode::Char(c) if form.editing => {
+            if let Some(ff) = form.fields.get_mut(form.selected) {
+                let mut s = ff.buffer.clone();
+                let idx = s.char_indices().nth(ff.cursor).map(|(i, _)| i).unwrap_or(s.len());
+                s.insert(idx, c);
+                ff.buffer = s;
+                ff.cursor += 1;
+                form.last_test_ok_hash = None;
+            }
+        }
+        _ => {}
+    }
+}
+
+/// Remove the character under the cursor.
+fn remove_char(ff: &mut FormField) {
+    let start = ff.buffer.char_indices().nth(ff.cursor).map(|(i, _)| i).unwrap_or(ff.buffer.len());
+    let end = ff.buffer.char_indices().nth(ff.cursor + 1).map(|(i, _)| i).unwrap_or(ff.buffer.len());
+    ff.buffer.replace_range(start..end, "");
+}
+
+/// Enter in the form: the Type dropdown, the advanced-fields expander, the
+/// Test/Save/Cancel buttons, or a field.
+fn press_form_enter(app: &mut App) {
+    let prefer_private = app.prefer_private;
+    let Some(st) = app.providers.as_mut() else { return };
+    let Some(form) = &mut st.form else { return };
+    if form.selected == 0 {
+        let current = st.entries.get(st.selected).map(|e| e.ptype.clone()).unwrap_or_default();
+        let items = providers::type_choices(&st.schema_types, &st.privacy_map, prefer_private);
+        let idx = items.iter().position(|t| *t == current).unwrap_or(0);
+        st.dropdown = Some(DropdownState { items, selected: idx, title: "Select Provider Type".to_string(), target_field: None });
+        return;
+    }
+    if Some(form.selected) == form.expander_idx() {
+        form.toggle_advanced();
+        return;
+    }
+    if form.selected == form.test_idx() {
+        test_form(app);
+    } else if form.selected == form.save_idx() {
+        save_form(st);
+    } else if form.selected == form.cancel_idx() {
+        form.editing = false;
+        st.focus_right = false;
+    } else {
+        open_field(app);
+    }
+}
+
+/// Test button: run the connection test (via the CLI where applicable) and
+/// remember which values passed.
+fn test_form(app: &mut App) {
+    let Some(st) = app.providers.as_mut() else { return };
+    let mut status = String::new();
+    let mut ptype_cur = String::new();
+    if st.selected < st.entries.len() {
+        let entry = &variables::resolve_entry(&st.entries[st.selected]);
+        ptype_cur = entry.ptype.clone();
+        match app.portfw.ensure(entry).and_then(|_| test_connection(entry)) {
+            Ok(t) => { app.model_index.record(&entry.id, &entry.name, &t.model_ids); status = t.message; },
+            Err(e) => { status = format!("Error: {}", e); },
+        }
+        if let Some(ex) = inspector::capture(&http::Client::for_provider(entry), entry) { app.inspector.last = Some(ex); }
+    }
+    let Some(form) = &mut st.form else { return };
+    let cur_hash = providers::compute_form_hash(&form.fields);
+    let low = status.to_lowercase();
+    if SERVER_TYPES.contains(&ptype_cur.as_str()) && !low.starts_with("error") && !low.contains("http ") {
+        form.last_test_ok_hash = Some(cur_hash);
+    } else {
+        form.last_test_ok_hash = None;
+    }
+    form.message = Some(status);
+}
+
+/// Save button: copy the form into the provider entry. The store itself is
+/// written by `s` on the list.
+fn save_form(st: &mut ProvidersState) {
+    let Some(form) = &mut st.form else { return };
+    if let Some(problem) = form.problem() {
+        form.message = Some(problem);
+        return;
+    }
+    // Enforce: if dirty and not tested ok, prevent save
+    let cur_hash = providers::compute_form_hash(&form.fields);
+    let dirty = cur_hash != form.initial_hash;
+    let tested_ok = form.last_test_ok_hash.as_ref().map_or(false, |h| *h == cur_hash);
+    if dirty && !tested_ok {
+        form.message = Some("Run Test connection first".to_string());
+        return;
+    }
+    if st.selected < st.entries.len() { write_form_fields(form, &mut st.entries[st.selected].config); }
+    // The form is borrowed: look the model up without `st.deprecation_of`
+    let deprecated = st.entries.get(st.selected).and_then(|e| deprecations::find(&st.deprecated, &e.ptype, e.config.get("model")?.as_str()?)).map(|d| d.describe());
+    form.message = Some(if st.entries.get(st.selected).map_or(false, |e| e.scope == providers::Scope::Session) {
+        providers::publish_session(&st.entries);
+        "Saved for this session (not written to disk)".to_string()
+    } else {
+        "Saved".to_string()
+    });
+    if let Some(d) = deprecated { form.message = Some(format!("Warning: saved, but {}", d)); }
+    // New baseline for the hash and the changed-fields summary
+    form.mark_saved();
+}
+
+/// Enter on a field: a dropdown of the server's models for `model`, of the
+/// schema options where there are some, else start or end editing.
+fn open_field(app: &mut App) {
+    let Some(st) = app.providers.as_mut() else { return };
+    let Some(form) = &mut st.form else { return };
+    let fi = form.selected - 1; // map to fields index
+    if let Some(ff) = form.fields.get(fi) {
+        let ptype = st.entries.get(st.selected).map(|e| e.ptype.clone()).unwrap_or_default();
+        if ff.schema.name == "model" && SERVER_TYPES.contains(&ptype.as_str()) {
+            if discover_models(app, fi, &ptype) { return; }
+        } else if let Some(opts) = &ff.schema.options {
+            let items = opts.clone();
+            let sel = items.iter().position(|x| *x == ff.buffer).unwrap_or(0);
+            st.dropdown = Some(DropdownState { items, selected: sel, title: format!("Select {}", ff.schema.name), target_field: Some(fi) });
+            return;
+        }
+    }
+    if let Some(form) = app.providers.as_mut().and_then(|s| s.form.as_mut()) { form.editing = !form.editing; }
+}
+
+/// List the models of a server/API provider with the form's current values
+/// (CLI discover-models) into a dropdown for field `fi`. Returns true when
+/// the dropdown opened; otherwise the form message says why not.
+fn discover_models(app: &mut App, fi: usize, ptype: &str) -> bool {
+    let Some(st) = app.providers.as_mut() else { return false };
+    let Some(form) = &mut st.form else { return false };
+    if let Some(entry) = st.entries.get(st.selected) {
+        if let Err(e) = app.portfw.ensure(&variables::resolve_entry(entry)) { form.message = Some(format!("Error: {}", e)); }
+    }
+    let vars = variables::load_variables();
+    let field = |name: &str| {
+        let v = form.fields.iter().find(|f| f.schema.name == name).map(|f| variables::render(&f.buffer, &vars).0).unwrap_or_default();
+        secrets::resolve_value(&Value::String(v)).as_str().unwrap_or("").to_string()
+    };
+    let (host, port, base_url, api_key, agent) = (field("host"), field("port"), field("base_url"), field("api_key"), field("user_agent"));
+    let mut args = vec!["providers", "discover-models", "--type", ptype, "--json"];
+    let (scheme, ca_cert, insecure, auth_header) = (field("scheme"), field("ca_cert"), field("insecure_tls"), field("auth_header"));
+    if !agent.trim().is_empty() { args.push("--user-agent"); args.push(agent.trim()); }
+    if !scheme.is_empty() { args.push("--scheme"); args.push(&scheme); }
+    if !ca_cert.is_empty() { args.push("--ca-cert"); args.push(&ca_cert); }
+    if !auth_header.is_empty() { args.push("--auth-header"); args.push(&auth_header); }
+    let proxy = field("proxy");
+    if !proxy.is_empty() { args.push("--proxy"); args.push(&proxy); }
+    if insecure.eq_ignore_ascii_case("true") { args.push("--insecure"); }
+    if field("debug_requests").eq_ignore_ascii_case("true") { args.push("--debug-requests"); }
+    if ptype == "lmstudio" || ptype == "ollama" {
+        args.push("--host");
+        args.push(if host.is_empty() { "localhost" } else { &host });
+        if !port.is_empty() { args.push("--port"); args.push(&port); }
+        if !api_key.is_empty() { args.push("--api-key"); args.push(&api_key); }
+    } else {
+        if !base_url.is_empty() { args.push("--base-url"); args.push(&base_url); }
+        if !api_key.is_empty() { args.push("--api-key"); args.push(&api_key); }
+    }
+    let v = match discovery_cache::cli(&args, prefs::load().discovery_timeout) {
+        Ok(v) => v,
+        Err(e) => { form.message = Some(format!("Discover failed: {}", e)); return false; }
+    };
+    let mut items: Vec<String> = Vec::new();
+    if let Some(arr) = v.get("models").and_then(|x| x.as_array()) {
+        for it in arr { if let Some(id) = it.get("id").and_then(|x| x.as_str()) { items.push(id.to_string()); } }
+    }
+    if let Some(err) = v.get("error").and_then(|x| x.as_str()) {
+        form.message = Some(format!("Discover failed: {}", err));
+        return false;
+    }
+    if items.is_empty() {
+        form.message = Some(format!("No models discovered for {}", ptype));
+        return false;
+    }
+    if let Some(e) = st.entries.get(st.selected) { app.model_index.record(&e.id, &e.name, &items); }
+    // Same model under this provider's name (e.g. after switching type)
+    let current = form.fields.get(fi).map(|f| f.buffer.clone()).unwrap_or_default();
+    let aliases = modelname::Aliases::load();
+    let sel = aliases.equivalent(&current, &items).and_then(|m| items.iter().position(|x| x == m)).unwrap_or(0);
+    let title = match items.get(sel) {
+        Some(m) if *m != current && aliases.same(m, &current) => format!("Select model ({}) — {} is {} here:", ptype, current, m),
+        _ => format!("Select model ({}):", ptype),
+    };
+    st.dropdown = Some(DropdownState { items, selected: sel, title, target_field: Some(fi) });
+    true
+}
@@ -0,0 +1,155 @@
+//! Keys of the pages that talk to providers while open: Playground, API
+//! Server, Benchmark, Latency Map and Provider Status.
+
+use crossterm::event::{KeyCode, KeyEvent, KeyModifiers};
+
+use crate::app::App;
+use crate::latency::LatencyState;
+use crate::providers::save_default_provider;
+use crate::theme::StatusKind;
+use crate::toast::Toast;
+use crate::{playground, server, status, store};
+
+/// Playground keys. The prompt takes free text, so this runs before the
+/// global shortcuts; returns false for keys left to the global handler.
+pub(super) fn handle_playground_key(app: &mut App, key: KeyEvent) -> bool {
+    if app.inspector.visible {
+        match key.code {
+            KeyCode::Up => app.inspector.scroll_up(1),
+            KeyCode::Down => app.inspector.scroll_down(1),
+            KeyCode::PageUp => app.inspector.scroll_up(10),
+            KeyCode::PageDown => app.inspector.scroll_down(10),
+            KeyCode::F(2) | KeyCode::Esc => app.inspector.toggle(),
+            _ => {}
+        }
+        return true;
+    }
+    let ctrl = key.modifiers.contains(KeyModifiers::CONTROL);
+    match key.code {
+        KeyCode::Esc => return false,
+        KeyCode::Enter => playground::run(app, false),
+        KeyCode::F(5) => playground::run(app, true),
+        KeyCode::Char('r') if ctrl => playground::run(app, true),
+        KeyCode::F(2) => app.inspector.toggle(),
+        _ => {
+            let Some(st) = app.playground.as_mut() else { return false };
+            match key.code {
+                KeyCode::Tab => st.next_provider(),
+                KeyCode::Backspace => { st.prompt.pop(); }
+                KeyCode::Char('u') if ctrl => st.prompt.clear(),
+                KeyCode::Char(c) if !ctrl => st.prompt.push(c),
+                _ => {}
+            }
+        }
+    }
+    true
+}
+
+/// API server page keys. Port and token take free text, so this runs before
+/// the global shortcuts; Esc is left to the global handler.
+pub(super) fn handle_server_key(app: &mut App, key: KeyEvent) -> bool {
+    let ctrl = key.modifiers.contains(KeyModifiers::CONTROL);
+    let Some(form) = app.server_form.as_mut() else { return false };
+    match key.code {
+        KeyCode::Esc => return false,
+        KeyCode::Up => form.field = form.field.saturating_sub(1),
+        KeyCode::Down | KeyCode::Tab => form.field = (form.field + 1).min(server::FIELD_PROVIDER),
+        KeyCode::Left if form.field == server::FIELD_PROVIDER => form.cycle_provider(false),
+        KeyCode::Right if form.field == server::FIELD_PROVIDER => form.cycle_provider(true),
+        KeyCode::Char('t') if ctrl => { form.token = server::random_token(); form.show_token = true; }
+        KeyCode::Char('v') if ctrl => form.show_token = !form.show_token,
+        KeyCode::Backspace => match form.field {
+            server::FIELD_PORT => { form.port.pop(); }
+            server::FIELD_TOKEN => { form.token.pop(); }
+            _ => {}
+        },
+        KeyCode::Char(c) if !ctrl => match form.field {
+            server::FIELD_PORT if c.is_ascii_digit() && form.port.len() < 5 => form.port.push(c),
+            server::FIELD_TOKEN if !c.is_whitespace() => form.token.push(c),
+            _ => {}
+        },
+        KeyCode::Enter if app.api_server.running() => {
+            app.api_server.stop();
+            form.status = Some("Server stopped".to_string());
+        }
+        KeyCode::Enter => {
+            let entry = form.selected_entry().cloned();
+            let started = form.port().and_then(|port| {
+                if let Some(e) = &entry { app.portfw.ensure(e)?; }
+                app.api_server.start(port, &form.token, entry.as_ref())
+            });
+            form.status = Some(match started {
+                Ok(()) => format!("Starting chi-llm serve on :{}…", app.api_server.port),
+                Err(e) => format!("Error: {}", e),
+            });
+        }
+        _ => {}
+    }
+    true
+}
+
+/// Benchmark page keys.
+pub(super) fn handle_benchmark_key(app: &mut App, key: KeyEvent) {
+    let Some(st) = &mut app.benchmark else { return };
+    match key.code {
+        KeyCode::Tab | KeyCode::Right => st.next_provider(),
+        KeyCode::BackTab | KeyCode::Left => st.prev_provider(),
+        KeyCode::Up => st.move_up(),
+        KeyCode::Down => st.move_down(),
+        KeyCode::Enter => st.start(),
+        KeyCode::Char('d') | KeyCode::Char('D') | KeyCode::Delete => {
+            if let Err(e) = st.delete_selected() { st.status = Some(format!("Error: {}", e)); }
+        }
+        _ => {}
+    }
+}
+
+/// Latency map keys; measuring starts on the first visit. Returns the
+/// store path written.
+pub(super) fn handle_latency_key(app: &mut App, key: KeyEvent, first_visit: bool) -> Option<String> {
+    if app.latency.is_none() { app.latency = Some(LatencyState::start()); }
+    let st = app.latency.as_mut().filter(|_| !first_visit)?;
+    match key.code {
+        KeyCode::Up => st.move_up(),
+        KeyCode::Down => st.move_down(),
+        KeyCode::Char('r') | KeyCode::Char('R') => st.refresh(),
+        KeyCode::Enter => {
+            let row = st.current().cloned()?;
+            let mut wrote = None;
+            st.status = Some(match save_default_provider(&row.id) {
+                Ok(()) => { wrote = Some(store::path()); format!("Default set to {}", row.name) }
+                Err(e) => format!("Error: save default failed: {}", e),
+            });
+            return wrote;
+        }
+        _ => {}
+    }
+    None
+}
+
+/// Provider status keys; the board is polled by `tick`. Returns the store
+/// path written.
+pub(super) fn handle_status_key(app: &mut App, key: KeyEvent, first_visit: bool) -> Option<String> {
+    if app.status_board.is_none() { app.status_board = Some(status::StatusBoard::new()); }
+    let st = app.status_board.as_mut().filter(|_| !first_visit)?;
+    match key.code {
+        KeyCode::Up => st.move_up(),
+        KeyCode::Down => st.move_down(),
+        KeyCode::Char('r') | KeyCode::Char('R') => st.refresh(&mut app.portfw),
+        KeyCode::Char('i') | KeyCode::Char('I') => {
+            if let Err(e) = st.cycle_interval() { app.last_error = Some(format!("Save setting failed: {e}")); }
+        }
+        KeyCode::Enter => {
+            let row = st.rows.get(st.selected)?;
+            match save_default_provider(&row.id) {
+                Ok(()) => {
+                    app.toast = Some(Toast::new(StatusKind::Ok, format!("Default set to {}", row.name)));
+                    return Some(store::path());
+                }
+                Err(e) => app.toast = Some(Toast::new(StatusKind::Err, format!("Save default failed: {}", e))),
+            }
+        }
+        _ => {}
+    }
+    None
+}
@@ -0,0 +1,220 @@
+//! Key handling. `handle_key` offers a key to the open dialog or overlay,
+//! then to the global shortcuts, then to the page; each page's keys are in
+//! a submodule.
+
+use std::time::Duration;
+
+use crossterm::event::{KeyCode, KeyEvent, KeyModifiers};
+
+use crate::app::{App, Page};
+use crate::confirm::{self, ConfirmAction};
+use crate::models::fetch_models;
+use crate::playground::load_playground;
+use crate::providers::save_default_provider;
+use crate::server::load_server_form;
+use crate::theme::StatusKind;
+use crate::toast::Toast;
+use crate::{benchmark, focus, logs, menu, open_page, profiles, recommend, run_save_hook, save_prefs, shutdown, store, variables};
+
+mod browser;
+mod build;
+mod configure;
+mod form;
+mod live;
+mod pages;
+mod settings;
+mod welcome;
+
+pub use form::ensure_form_for_selected;
+pub use pages::handle_diagnostics_key;
+
+pub fn handle_key(app: &mut App, key: KeyEvent) {
+    // Page shown before this key; keys that open a page must not also act on it
+    let page_before = app.page;
+    // Ctrl+C quits from anywhere, asking first like q does
+    if key.code == KeyCode::Char('c') && key.modifiers.contains(KeyModifiers::CONTROL) { shutdown::interrupt(app); return; }
+    if app.shutdown.is_some() { shutdown::handle_shutdown_key(app, key); return; }
+    if app.confirm.is_some() {
+        if let Some(action) = confirm::handle_confirm_key(app, key) { run_confirmed(app, action); }
+        return;
+    }
+    // Default-switch suggestion answers work on every page
+    if app.default_watch.suggestion.is_some() && key.modifiers.contains(KeyModifiers::CONTROL) {
+        match key.code {
+            KeyCode::Char('y') => { accept_default_suggestion(app); return; }
+            KeyCode::Char('n') => { app.default_watch.dismiss(); return; }
+            _ => {}
+        }
+    }
+    if handle_overlay_key(app, key) { return; }
+    // Esc in a linked document returns to the one that linked it
+    if app.page == Page::Readme && key.code == KeyCode::Esc && !app.show_help && app.readme.as_mut().map_or(false, |rm| rm.go_back()) { return; }
+    // Typing into a field: letters are text, Esc ends the edit
+    if !focus::editing(app) {
+        match key.code {
+            KeyCode::Char('q') => shutdown::request_quit(app),
+            KeyCode::Char('?') => { app.show_help = !app.show_help; }
+            // Settings opens the theme picker instead
+            KeyCode::Char('t') if app.page != Page::Settings => {
+                app.theme.toggle();
+                app.prefs.theme = app.theme.name.clone();
+                save_prefs(app);
+            }
+            KeyCode::Char('a') => {
+                app.anim = !app.anim;
+                app.prefs.animation = app.anim;
+                save_prefs(app);
+            }
+            // s/S save in Configure instead of opening Settings
+            KeyCode::Char(c) if app.page == Page::Configure && c.eq_ignore_ascii_case(&'s') => {}
+            KeyCode::Char(c) if menu::shortcut(c).is_some() => { if let Some(p) = menu::shortcut(c) { open_page(app, p); } }
+            KeyCode::Esc => {
+                if app.show_help { app.show_help = false; }
+                else if app.inspector.visible { app.inspector.visible = false; return; }
+                else if app.page != Page::Welcome { app.page = Page::Welcome; }
+                else { shutdown::request_quit(app); }
+            }
+            _ => {}
+        }
+    }
+
+    if app.page == Page::Welcome { welcome::handle_welcome_key(app, key); }
+    load_page_state(app);
+    // Enter here goes on to Configure with the chosen model
+    if app.page == Page::ModelBrowser && browser::handle_model_browser_key(app, key) { return; }
+    let wrote = match app.page {
+        Page::Readme => { pages::handle_readme_key(app, key); None }
+        Page::SelectDefault => pages::handle_select_default_key(app, key, page_before),
+        Page::Configure => configure::handle_configure_key(app, key),
+        Page::Logs if page_before == Page::Logs => { pages::handle_logs_key(app, key); None }
+        Page::Recommend if page_before == Page::Recommend => { browser::handle_recommend_key(app, key); None }
+        Page::Benchmark if page_before == Page::Benchmark => { live::handle_benchmark_key(app, key); None }
+        Page::Variables if page_before == Page::Variables => pages::handle_variables_key(app, key),
+        Page::Audit => { pages::handle_audit_key(app, key); None }
+        Page::Cache => { pages::handle_cache_key(app, key); None }
+        Page::Backups => pages::handle_backups_key(app, key),
+        Page::Latency => live::handle_latency_key(app, key, page_before != Page::Latency),
+        Page::Status => live::handle_status_key(app, key, page_before != Page::Status),
+        Page::Settings => settings::handle_settings_key(app, key),
+        Page::Build => build::handle_build_key(app, key),
+        _ => None,
+    };
+    // Select Default shows the new default
+    if wrote.is_some() && matches!(app.page, Page::Latency | Page::Status) { app.defaultp = None; }
+    if let Some(path) = wrote { run_save_hook(app, &path); }
+}
+
+/// Dialogs, inputs and overlays a page opened; they take the key before
+/// the global shortcuts. Returns true when the key was consumed.
+fn handle_overlay_key(app: &mut App, key: KeyEvent) -> bool {
+    let wrote = match app.page {
+        Page::Playground if app.playground.is_some() => return live::handle_playground_key(app, key),
+        Page::Server if app.server_form.is_some() => return live::handle_server_key(app, key),
+        Page::Variables if app.variables.as_ref().map_or(false, |v| v.edit.is_some()) => pages::handle_variables_edit_key(app, key),
+        Page::Settings if app.hf_token_input.is_some() => { settings::handle_hf_token_key(app, key); None }
+        Page::Settings if app.theme_picker.is_some() => settings::handle_theme_picker_key(app, key),
+        Page::Settings if app.default_models.is_some() => settings::handle_default_models_key(app, key),
+        Page::Welcome if app.profiles.is_some() => welcome::handle_profiles_key(app, key),
+        Page::Build if app.build.as_ref().map_or(false, |b| b.merge.is_some()) => build::handle_config_merge_key(app, key),
+        Page::Build if app.build.as_ref().map_or(false, |b| b.conflict.is_some()) => build::handle_build_conflict_key(app, key),
+        Page::ModelBrowser if app.model.as_ref().map_or(false, |m| m.disk_warning.is_some()) => { browser::handle_disk_warning_key(app, key); None }
+        Page::ModelBrowser if app.model.as_ref().map_or(false, |m| m.searching) => { browser::handle_model_search_key(app, key); None }
+        // s/S sort the model table here instead of opening Settings
+        Page::ModelBrowser if app.model.is_some() && matches!(key.code, KeyCode::Char('s') | KeyCode::Char('S')) => {
+            if let Some(m) = &mut app.model {
+                if key.code == KeyCode::Char('s') { m.cycle_sort(); } else { m.toggle_sort_direction(); }
+            }
+            None
+        }
+        Page::Configure if app.providers.as_ref().map_or(false, |s| s.import.is_some()) => { configure::handle_import_key(app, key); None }
+        Page::Configure if app.providers.as_ref().map_or(false, |s| s.qr.is_some()) => { configure::handle_qr_key(app, key); None }
+        Page::Configure if app.providers.as_ref().map_or(false, |s| s.export.is_some()) => { configure::handle_export_key(app, key); None }
+        Page::Configure if app.providers.as_ref().map_or(false, |s| s.paste_input.is_some()) => { configure::handle_paste_input_key(app, key); None }
+        Page::Readme if app.readme.as_ref().map_or(false, |rm| rm.link_picker.is_some()) => { pages::handle_readme_links_key(app, key); None }
+        _ => return false,
+    };
+    if let Some(path) = wrote { run_save_hook(app, &path); }
+    true
+}
+
+/// State a page needs before its keys run, loaded on the first key there.
+fn load_page_state(app: &mut App) {
+    if app.page == Page::Playground && app.playground.is_none() { app.playground = Some(load_playground()); }
+    if app.page == Page::Server && app.server_form.is_none() { app.server_form = Some(load_server_form()); }
+    if app.page == Page::Variables && app.variables.is_none() { app.variables = Some(variables::load_variables_state()); }
+    if app.page == Page::Benchmark && app.benchmark.is_none() { app.benchmark = Some(benchmark::load_benchmark()); }
+    if app.page == Page::Logs && app.logs.is_none() { app.logs = Some(logs::LogView::new()); }
+    if app.page == Page::Recommend && app.recommend.is_none() {
+        if app.model.is_none() {
+            match fetch_models(Duration::from_secs(5)) {
+                Ok(mut m) => { m.add_server_entries(); app.model = Some(m) }
+                Err(e) => app.last_error = Some(format!("Models failed: {e}")),
+            }
+        }
+        app.recommend = Some(recommend::RecommendState::new(app.model.as_ref().map_or(&[][..], |m| &m.entries)));
+    }
+}
+
+/// Make the suggested healthy provider the default.
+fn accept_default_suggestion(app: &mut App) {
+    let Some(s) = app.default_watch.suggestion.clone() else { return };
+    match save_default_provider(&s.to) {
+        Ok(()) => {
+            app.default_watch.accepted();
+            app.defaultp = None;
+            app.toast = Some(Toast::new(StatusKind::Ok, format!("Default switched to {}", s.to_name)));
+            run_save_hook(app, &store::path());
+        }
+        Err(e) => app.toast = Some(Toast::new(StatusKind::Err, format!("Switch default failed: {}", e))),
+    }
+}
+
+/// Carry out what a confirmation dialog asked about.
+fn run_confirmed(app: &mut App, action: ConfirmAction) {
+    match action {
+        ConfirmAction::DeleteProvider(id) => {
+            if let Some(st) = &mut app.providers {
+                if let Some(i) = st.entries.iter().position(|e| e.id == id) {
+                    st.selected = i;
+                    st.delete_selected();
+                    st.form = None;
+                    st.publish_session();
+                }
+            }
+        }
+        ConfirmAction::SetDefault(id) => {
+            let Some(s) = &mut app.defaultp else { return };
+            if let Some(path) = pages::set_default(s, &id, &mut app.last_error) { run_save_hook(app, &path); }
+        }
+        ConfirmAction::DownloadModel(id) => {
+            // The selection cannot move while the dialog is open
+            if app.model.as_ref().and_then(|m| m.current_entry()).map_or(false, |e| e.id == id) { browser::start_model_download(app, false); }
+        }
+        ConfirmAction::UseGlobalHere => {
+            let status = match crate::build::use_global_here() {
+                Ok(moved) => format!("Project override moved to {}; global settings apply here", moved.join(", ")),
+                Err(e) => format!("Error: {}", e),
+            };
+            app.build.get_or_insert_with(Default::default).status = Some(status);
+        }
+        ConfirmAction::PinGlobally => {
+            let (status, wrote) = match crate::build::pin_globally() {
+                Ok(path) => (format!("Pinned globally: {}", path), Some(path)),
+                Err(e) => (format!("Error: {}", e), None),
+            };
+            app.build.get_or_insert_with(Default::default).status = Some(status);
+            if let Some(path) = wrote { run_save_hook(app, &path); }
+        }
+        ConfirmAction::SwitchProfile(name) => {
+            if let Some(path) = welcome::switch_profile(app, &name) { run_save_hook(app, &path); }
+        }
+        ConfirmAction::DeleteProfile(name) => {
+            let status = match profiles::delete(&name) {
+                Ok(()) => format!("Deleted profile {}", name),
+                Err(e) => format!("Error: {}", e),
+            };
+            if let Some(sw) = &mut app.profiles { sw.reload(); sw.status = Some(status); }
+        }
+        ConfirmAction::Quit => app.should_quit = true,
+    }
+}
@@ -0,0 +1,320 @@
+//! Keys of the reading and bookkeeping pages: README, Select Default,
+//! Variables, Logs, Audit Log, Model Cache, Backups and Diagnostics.
+
+use std::time::Duration;
+
+use crossterm::event::{KeyCode, KeyEvent, KeyModifiers};
+
+use crate::app::{App, Page};
+use crate::audit::{export_audit, load_audit};
+use crate::backup::{load_backups, snapshot_now};
+use crate::cache::load_cache;
+use crate::confirm::{ConfirmAction, ConfirmDialog};
+use crate::diagnostics::{export_diagnostics, fetch_diagnostics};
+use crate::providers::{DefaultProviderState, load_providers_scratch, save_default_provider};
+use crate::readme::load_readme;
+use crate::theme::StatusKind;
+use crate::toast::Toast;
+use crate::{bundle, clipboard, focus, logs, privacy, store, variables};
+
+/// README keys. Up/Down move in whichever of TOC and text has focus.
+pub(super) fn handle_readme_key(app: &mut App, key: KeyEvent) {
+    if app.readme.is_none() {
+        app.readme = Some(load_readme());
+    }
+    if matches!(key.code, KeyCode::Tab | KeyCode::BackTab) { focus::cycle(app); }
+    let Some(rm) = &mut app.readme else { return };
+    match key.code {
+        KeyCode::Char('h') | KeyCode::Char('H') => {
+            rm.show_toc = !rm.show_toc;
+            if !rm.show_toc { rm.focus_toc = false; }
+            // Start at the section being read
+            rm.toc_selected = rm.section().unwrap_or(0);
+        }
+        KeyCode::Char('n') => { rm.next_heading(); }
+        KeyCode::Char('p') => { rm.prev_heading(); }
+        KeyCode::Char('l') | KeyCode::Char('L') => {
+            if !rm.open_links() { app.toast = Some(Toast::new(StatusKind::Warn, "No links to other markdown files here".to_string())); }
+        }
+        KeyCode::Up => {
+            if rm.show_toc && rm.focus_toc {
+                if rm.toc_selected > 0 { rm.toc_selected -= 1; }
+            } else {
+                rm.scroll_up(1);
+            }
+        }
+        KeyCode::Down => {
+            if rm.show_toc && rm.focus_toc {
+                if rm.toc_selected + 1 < rm.toc.len() { rm.toc_selected += 1; }
+            } else {
+                rm.scroll_down(1);
+            }
+        }
+        KeyCode::PageUp => rm.scroll_up(8),
+        KeyCode::PageDown => rm.scroll_down(8),
+        KeyCode::Enter => {
+            if rm.show_toc && rm.focus_toc {
+                if rm.toc_selected < rm.toc.len() {
+                    rm.jump(rm.toc_selected);
+                    rm.focus_toc = false; // jump to content focus
+                }
+            } else {
+                // In the text: pick one of its links
+                rm.open_links();
+            }
+        }
+        _ => {}
+    }
+}
+
+/// README link list: ↑/↓ select, Enter opens the linked document, Esc/l close.
+pub(super) fn handle_readme_links_key(app: &mut App, key: KeyEvent) {
+    let Some(rm) = app.readme.as_mut() else { return };
+    let Some(sel) = rm.link_picker else { return };
+    match key.code {
+        KeyCode::Up => rm.link_picker = Some(sel.saturating_sub(1)),
+        KeyCode::Down => rm.link_picker = Some((sel + 1).min(rm.links.len().saturating_sub(1))),
+        KeyCode::Enter => {
+            if let Err(e) = rm.follow(sel) {
+                rm.link_picker = None;
+                app.toast = Some(Toast::new(StatusKind::Err, format!("Open link failed: {}", e)));
+            }
+        }
+        KeyCode::Esc | KeyCode::Char('l') => rm.link_picker = None,
+        _ => {}
+    }
+}
+
+/// Select Default keys; the key that opened the page is not acted on.
+/// Returns the store path written.
+pub(super) fn handle_select_default_key(app: &mut App, key: KeyEvent, page_before: Page) -> Option<String> {
+    if app.defaultp.is_none() {
+        match load_providers_scratch() {
+            Ok(mut s) => {
+                // Schema-declared privacy (e.g. provider manifests) wins over the built-in table
+                if let Some(ps) = &app.providers {
+                    for p in &mut s.providers { p.privacy = privacy::classify(&p.ptype, &p.config, ps.privacy_map.get(&p.ptype).copied()); }
+                }
+                if app.prefer_private { s.sort_private_first(); }
+                app.defaultp = Some(s);
+            }
+            Err(e) => app.last_error = Some(format!("Load providers failed: {e}")),
+        }
+    }
+    let s = app.defaultp.as_mut()?;
+    let mut wrote = None;
+    match key.code {
+        _ if page_before != Page::SelectDefault => {}
+        KeyCode::Up => { if !s.providers.is_empty() && s.selected > 0 { s.selected -= 1; } },
+        KeyCode::Down => { if !s.providers.is_empty() && s.selected + 1 < s.providers.len() { s.selected += 1; } },
+        KeyCode::Enter | KeyCode::Char('s') | KeyCode::Char('S') => {
+            if let Some(p) = s.providers.get(s.selected) {
+                let id = p.id.clone();
+                match app.preflight.problem(p) {
+                    Some(problem) => {
+                        let msg = vec![format!("{} did not pass the pre-flight check:", p.name), problem, "Make it the default anyway?".to_string()];
+                        app.confirm = Some(ConfirmDialog::new("Pre-flight check failed", msg, "set anyway", ConfirmAction::SetDefault(id)));
+                    }
+                    None => wrote = set_default(s, &id, &mut app.last_error),
+                }
+            }
+        }
+        _ => {}
+    }
+    // Pre-flight whatever the cursor is on (no-op while fresh)
+    if let Some(p) = s.providers.get(s.selected) { app.preflight.check(p, &mut app.portfw); }
+    wrote
+}
+
+/// Select Default: save `id` as the default. Returns the store path written.
+pub(super) fn set_default(s: &mut DefaultProviderState, id: &str, last_error: &mut Option<String>) -> Option<String> {
+    s.current_default_id = Some(id.to_string());
+    match save_default_provider(id) {
+        Ok(()) => Some(store::path()),
+        Err(e) => { *last_error = Some(format!("Save default failed: {e}")); None }
+    }
+}
+
+/// Variables page keys. Returns the store path written.
+pub(super) fn handle_variables_key(app: &mut App, key: KeyEvent) -> Option<String> {
+    let st = app.variables.as_mut()?;
+    match key.code {
+        KeyCode::Up => { if st.selected > 0 { st.selected -= 1; } }
+        KeyCode::Down => { if st.selected + 1 < st.rows.len() { st.selected += 1; } }
+        KeyCode::Enter => st.start_edit(),
+        KeyCode::Char('n') | KeyCode::Char('N') => st.start_new(),
+        KeyCode::Char('r') | KeyCode::Char('R') => { *st = variables::load_variables_state(); }
+        KeyCode::Char('d') | KeyCode::Char('D') | KeyCode::Delete => {
+            let mut wrote = None;
+            st.status = Some(match st.delete_selected() {
+                Ok(Some(name)) => { wrote = Some(store::path()); format!("Deleted {}", name) }
+                Ok(None) => "Warning: not defined in the provider store".to_string(),
+                Err(e) => format!("Error: {}", e),
+            });
+            return wrote;
+        }
+        _ => {}
+    }
+    None
+}
+
+/// Variables page while a name/value is being typed; consumes every key.
+pub(super) fn handle_variables_edit_key(app: &mut App, key: KeyEvent) -> Option<String> {
+    let st = app.variables.as_mut()?;
+    let ed = st.edit.as_mut()?;
+    match key.code {
+        KeyCode::Esc => { st.edit = None; }
+        KeyCode::Tab | KeyCode::BackTab if ed.is_new => { ed.on_name = !ed.on_name; }
+        KeyCode::Backspace => { if ed.on_name { ed.name.pop(); } else { ed.value.pop(); } }
+        KeyCode::Enter if ed.on_name => { ed.on_name = false; }
+        KeyCode::Enter => {
+            match st.commit_edit() {
+                Ok(()) => { st.status = Some("Saved — providers pick it up on next use".to_string()); return Some(store::path()); }
+                Err(e) => st.status = Some(format!("Error: {}", e)),
+            }
+        }
+        KeyCode::Char(c) if !key.modifiers.contains(KeyModifiers::CONTROL) => { if ed.on_name { ed.name.push(c); } else { ed.value.push(c); } }
+        _ => {}
+    }
+    None
+}
+
+/// Logs page keys.
+pub(super) fn handle_logs_key(app: &mut App, key: KeyEvent) {
+    let Some(v) = &mut app.logs else { return };
+    match key.code {
+        KeyCode::Up => v.scroll_up(1),
+        KeyCode::Down => v.scroll_down(1),
+        KeyCode::PageUp => v.scroll_up(logs::PAGE),
+        KeyCode::PageDown => v.scroll_down(logs::PAGE),
+        KeyCode::Home => v.scroll_up(usize::MAX),
+        KeyCode::End => v.to_end(),
+        KeyCode::Char('f') | KeyCode::Char('F') => v.toggle_follow(),
+        KeyCode::Char('l') | KeyCode::Char('L') => v.cycle_filter(),
+        KeyCode::Tab => v.switch_source(),
+        KeyCode::Char('r') | KeyCode::Char('R') => v.reload(),
+        KeyCode::Char('y') | KeyCode::Char('Y') => {
+            let n = v.visible().len();
+            v.status = Some(match clipboard::copy(&v.text()) {
+                Ok(via) => format!("Copied {} lines via {}", n, via),
+                Err(e) => format!("Error: copy failed: {}", e),
+            });
+        }
+        _ => {}
+    }
+}
+
+/// Audit log keys.
+pub(super) fn handle_audit_key(app: &mut App, key: KeyEvent) {
+    if app.audit.is_none() { app.audit = Some(load_audit()); }
+    let Some(st) = &mut app.audit else { return };
+    match key.code {
+        KeyCode::Up => st.move_up(),
+        KeyCode::Down => st.move_down(),
+        KeyCode::Char('r') | KeyCode::Char('R') => { *st = load_audit(); }
+        KeyCode::Char('e') | KeyCode::Char('E') => {
+            st.status = Some(match export_audit(st) {
+                Ok(path) => format!("Exported: {}", path),
+                Err(e) => format!("Error: export failed: {}", e),
+            });
+        }
+        _ => {}
+    }
+}
+
+/// Model cache keys.
+pub(super) fn handle_cache_key(app: &mut App, key: KeyEvent) {
+    if app.cache.is_none() { app.cache = Some(load_cache()); }
+    let mut deleted = false;
+    if let Some(st) = &mut app.cache {
+        match key.code {
+            KeyCode::Up => st.move_up(),
+            KeyCode::Down => st.move_down(),
+            KeyCode::Char('r') | KeyCode::Char('R') => { *st = load_cache(); }
+            KeyCode::Delete | KeyCode::Char('x') | KeyCode::Char('X') => { deleted = st.delete_selected(); }
+            _ => {}
+        }
+    }
+    // Downloaded flags come from the files; reload the browser on next visit
+    if deleted { app.model = None; }
+}
+
+/// Backups keys. Returns the store path written by a restore.
+pub(super) fn handle_backups_key(app: &mut App, key: KeyEvent) -> Option<String> {
+    if app.backups.is_none() { app.backups = Some(load_backups()); }
+    let mut restored = false;
+    if matches!(key.code, KeyCode::Tab | KeyCode::BackTab) { focus::cycle(app); }
+    if let Some(st) = &mut app.backups {
+        match key.code {
+            KeyCode::Up => st.move_up(),
+            KeyCode::Down => st.move_down(),
+            KeyCode::Char('r') | KeyCode::Char('R') => { *st = load_backups(); }
+            KeyCode::Char('n') | KeyCode::Char('N') => {
+                let msg = match snapshot_now() {
+                    Ok(p) => format!("Snapshot written: {}", p.display()),
+                    Err(e) => format!("Error: snapshot failed: {}", e),
+                };
+                *st = load_backups();
+                st.status = Some(msg);
+            }
+            KeyCode::Enter if st.focus_providers => {
+                st.status = Some(match st.restore_selected_provider() {
+                    Ok(m) => { restored = true; m }
+                    Err(e) => format!("Error: {}", e),
+                });
+            }
+            KeyCode::Char('A') => {
+                st.status = Some(match st.restore_all() {
+                    Ok(m) => { restored = true; m }
+                    Err(e) => format!("Error: {}", e),
+                });
+            }
+            _ => {}
+        }
+    }
+    if !restored { return None; }
+    // Force Configure/Select Default to reload from disk
+    app.providers = None;
+    app.defaultp = None;
+    Some(store::path())
+}
+
+/// Diagnostics page keys: e export, b support bundle, r refresh. Returns
+/// false for keys left to `handle_key`.
+pub fn handle_diagnostics_key(app: &mut App, key: KeyEvent) -> bool {
+    if app.page == Page::Diagnostics {
+        match key.code {
+            KeyCode::Char('e') | KeyCode::Char('E') => {
+                if let Some(diag) = &app.diag {
+                    match export_diagnostics(diag) {
+                        Ok(path) => {
+                            if let Some(d) = &mut app.diag.clone() {
+                                let mut d2 = d.clone();
+                                d2.saved_path = Some(path);
+                                app.diag = Some(d2);
+                            }
+                        }
+                        Err(e) => app.last_error = Some(format!("Export failed: {e}")),
+                    }
+                }
+                return true;
+            }
+            KeyCode::Char('b') | KeyCode::Char('B') => {
+                app.toast = Some(match bundle::create(Duration::from_secs(5)) {
+                    Ok(path) => Toast::new(StatusKind::Ok, format!("Support bundle: {} (secrets redacted)", path.display())),
+                    Err(e) => Toast::new(StatusKind::Err, format!("Support bundle failed: {e}")),
+                });
+                return true;
+            }
+            KeyCode::Char('r') | KeyCode::Char('R') => {
+                match fetch_diagnostics(Duration::from_secs(5)) {
+                    Ok(d) => app.diag = Some(d),
+                    Err(e) => app.last_error = Some(format!("Diagnostics failed: {e}")),
+                }
+                return true;
+            }
+            _ => {}
+        }
+    }
+    false
+}
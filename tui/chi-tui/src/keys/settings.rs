@@ -0,0 +1,182 @@
+//! Settings keys: the toggles saved per project or in tui.json, and the
+//! theme picker, Hugging Face token and default-model overlays.
+
+use crossterm::event::{KeyCode, KeyEvent, KeyModifiers};
+
+use crate::app::App;
+use crate::build::BuildTarget;
+use crate::providers::load_providers_state;
+use crate::theme::StatusKind;
+use crate::toast::Toast;
+use crate::{density, envfile, hf, model_defaults, prefs, privacy, save_prefs, store, theme_picker};
+
+/// Settings page keys. Returns the path written.
+pub(super) fn handle_settings_key(app: &mut App, key: KeyEvent) -> Option<String> {
+    let KeyCode::Char(c) = key.code else { return None };
+    let mut wrote = None;
+    match c.to_ascii_lowercase() {
+        'c' => {
+            let on = !app.theme.colorblind;
+            app.theme.set_colorblind(on);
+            app.prefs.colorblind = on;
+            save_prefs(app);
+        }
+        'd' => {
+            app.density = app.density.toggled();
+            match density::save_density(app.density) {
+                Ok(()) => wrote = Some(store::path()),
+                Err(e) => app.last_error = Some(format!("Save setting failed: {e}")),
+            }
+        }
+        'p' => {
+            app.prefer_private = !app.prefer_private;
+            match privacy::save_prefer_private(app.prefer_private) {
+                Ok(()) => wrote = Some(store::path()),
+                Err(e) => app.last_error = Some(format!("Save setting failed: {e}")),
+            }
+            app.defaultp = None;
+        }
+        'e' => {
+            app.env_sync = !app.env_sync;
+            match envfile::save_sync(app.env_sync) {
+                Ok(()) => wrote = Some(store::path()),
+                Err(e) => app.last_error = Some(format!("Save setting failed: {e}")),
+            }
+        }
+        't' => { app.theme_picker = Some(theme_picker::ThemePicker::open(&app.theme)); }
+        'h' => { app.hf_token_input = Some(String::new()); }
+        'w' => {
+            app.prefs.build_target = match app.prefs.build_target {
+                BuildTarget::Project => BuildTarget::Global,
+                BuildTarget::Global => BuildTarget::Project,
+            };
+            app.build = None;
+            save_prefs(app);
+        }
+        'o' => {
+            app.prefs.discovery_timeout = app.prefs.next_discovery_timeout();
+            save_prefs(app);
+        }
+        'l' => {
+            app.prefs.cache_ttl = app.prefs.next_cache_ttl();
+            save_prefs(app);
+        }
+        'm' => {
+            // Types come from the provider schema Configure already loaded
+            let types = match &app.providers {
+                Some(st) => Ok(model_defaults::model_types(st)),
+                None => load_providers_state().map(|st| model_defaults::model_types(&st)),
+            };
+            match types {
+                Ok(types) => app.default_models = Some(model_defaults::DefaultModels::new(types)),
+                Err(e) => app.last_error = Some(format!("Loading provider types failed: {e}")),
+            }
+        }
+        'f' => {
+            let fmt = app.config_format.toggled();
+            match store::save_write_format(fmt) {
+                Ok(path) => {
+                    app.config_format = fmt;
+                    // Also what new projects start with
+                    app.prefs.config_format = fmt;
+                    save_prefs(app);
+                    app.toast = Some(Toast::new(StatusKind::Ok, format!("Config format: {} (saved as {})", fmt.key(), path)));
+                    wrote = Some(path);
+                }
+                Err(e) => app.last_error = Some(format!("Save setting failed: {e}")),
+            }
+        }
+        _ => {}
+    }
+    wrote
+}
+
+/// Settings: Hugging Face token input. Enter stores it in the secrets
+/// layer; Enter on an empty input removes the stored token.
+pub(super) fn handle_hf_token_key(app: &mut App, key: KeyEvent) {
+    let Some(buf) = app.hf_token_input.as_mut() else { return };
+    match key.code {
+        KeyCode::Esc => { app.hf_token_input = None; }
+        KeyCode::Backspace => { buf.pop(); }
+        KeyCode::Enter => {
+            let value = app.hf_token_input.take().unwrap_or_default();
+            app.toast = Some(if value.trim().is_empty() {
+                hf::clear_token();
+                Toast::new(StatusKind::Ok, "Hugging Face token removed".to_string())
+            } else {
+                match hf::save_token(&value) {
+                    Ok(backend) => Toast::new(StatusKind::Ok, format!("Hugging Face token saved ({})", backend.label())),
+                    Err(e) => Toast::new(StatusKind::Err, format!("Saving the token failed: {}", e)),
+                }
+            });
+            app.hf_token = hf::token().map(|(_, src)| src);
+        }
+        KeyCode::Char(c) if !key.modifiers.contains(KeyModifiers::CONTROL) => buf.push(c),
+        _ => {}
+    }
+}
+
+/// Settings: default model per provider type (`m`). Enter edits the
+/// selected type's model; saving an empty one clears it. Returns the
+/// store path written.
+pub(super) fn handle_default_models_key(app: &mut App, key: KeyEvent) -> Option<String> {
+    let dm = app.default_models.as_mut()?;
+    if let Some(buf) = dm.input.as_mut() {
+        match key.code {
+            KeyCode::Esc => { dm.input = None; }
+            KeyCode::Backspace => { buf.pop(); }
+            KeyCode::Enter => {
+                let model = dm.input.take().unwrap_or_default();
+                return dm.save(&model);
+            }
+            KeyCode::Char(c) if !key.modifiers.contains(KeyModifiers::CONTROL) => buf.push(c),
+            _ => {}
+        }
+        return None;
+    }
+    match key.code {
+        KeyCode::Esc | KeyCode::Char('m') | KeyCode::Char('M') => { app.default_models = None; }
+        KeyCode::Up => { dm.selected = dm.selected.saturating_sub(1); }
+        KeyCode::Down => { if dm.selected + 1 < dm.types.len() { dm.selected += 1; } }
+        KeyCode::Enter => {
+            if let Some(t) = dm.current() { dm.input = Some(dm.models.get(t).cloned().unwrap_or_default()); }
+        }
+        KeyCode::Delete | KeyCode::Char('x') | KeyCode::Char('X') => return dm.save(""),
+        _ => {}
+    }
+    None
+}
+
+/// Settings: theme picker (`t`). Up/Down apply the selection live, Enter
+/// saves it, Esc restores the theme from before. Returns the path written.
+pub(super) fn handle_theme_picker_key(app: &mut App, key: KeyEvent) -> Option<String> {
+    let tp = app.theme_picker.as_mut()?;
+    match key.code {
+        KeyCode::Esc | KeyCode::Char('t') | KeyCode::Char('T') => {
+            app.theme = tp.original.clone();
+            app.theme_picker = None;
+        }
+        KeyCode::Up => {
+            tp.selected = tp.selected.saturating_sub(1);
+            app.theme = tp.preview();
+        }
+        KeyCode::Down => {
+            if tp.selected + 1 < tp.themes.len() { tp.selected += 1; }
+            app.theme = tp.preview();
+        }
+        KeyCode::Enter => {
+            app.theme = tp.preview();
+            app.theme_picker = None;
+            app.prefs.theme = app.theme.name.clone();
+            match prefs::save(&app.prefs) {
+                Ok(path) => {
+                    app.toast = Some(Toast::new(StatusKind::Ok, format!("Theme: {}", app.theme.name)));
+                    return Some(path);
+                }
+                Err(e) => app.toast = Some(Toast::new(StatusKind::Err, format!("Saving the theme failed: {}", e))),
+            }
+        }
+        _ => {}
+    }
+    None
+}
@@ -0,0 +1,91 @@
+//! Welcome keys: the section menu and the profile switcher (`p`).
+
+use crossterm::event::{KeyCode, KeyEvent, KeyModifiers};
+
+use crate::app::App;
+use crate::confirm::{ConfirmAction, ConfirmDialog};
+use crate::menu::MenuAction;
+use crate::profiles::{self, ProfileSwitcher};
+use crate::{open_page, shutdown, store};
+
+/// Welcome menu navigation.
+pub(super) fn handle_welcome_key(app: &mut App, key: KeyEvent) {
+    match key.code {
+        KeyCode::Up => { if app.menu_idx > 0 { app.menu_idx -= 1; } },
+        KeyCode::Down => { if app.menu_idx + 1 < app.menu.len() { app.menu_idx += 1; } },
+        KeyCode::Char('p') | KeyCode::Char('P') => { app.profiles = Some(ProfileSwitcher::load()); }
+        KeyCode::Enter => match app.menu.action(app.menu_idx) {
+            Some(MenuAction::Open(p)) => open_page(app, p),
+            Some(MenuAction::Quit) => shutdown::request_quit(app),
+            None => {}
+        },
+        _ => {}
+    }
+}
+
+/// Welcome profile switcher (`p`): Enter switches, n saves the current
+/// providers as a profile, d deletes one. Returns the store path written.
+pub(super) fn handle_profiles_key(app: &mut App, key: KeyEvent) -> Option<String> {
+    let sw = app.profiles.as_mut()?;
+    if let Some(buf) = sw.name_input.as_mut() {
+        match key.code {
+            KeyCode::Esc => { sw.name_input = None; }
+            KeyCode::Backspace => { buf.pop(); }
+            KeyCode::Enter => {
+                let name = sw.name_input.take().unwrap_or_default().trim().to_string();
+                let saved = profiles::save_current(&name);
+                sw.reload();
+                match saved {
+                    Ok(p) => {
+                        if let Some(i) = sw.profiles.iter().position(|x| x.name == p.name) { sw.selected = i; }
+                        sw.status = Some(format!("Saved profile {} ({})", p.name, p.summary()));
+                        return store::exists().then(store::path);
+                    }
+                    Err(e) => sw.status = Some(format!("Error: {}", e)),
+                }
+            }
+            KeyCode::Char(c) if !key.modifiers.contains(KeyModifiers::CONTROL) => buf.push(c),
+            _ => {}
+        }
+        return None;
+    }
+    match key.code {
+        KeyCode::Esc | KeyCode::Char('p') | KeyCode::Char('P') => { app.profiles = None; }
+        KeyCode::Up => { sw.selected = sw.selected.saturating_sub(1); }
+        KeyCode::Down => { if sw.selected + 1 < sw.profiles.len() { sw.selected += 1; } }
+        KeyCode::Char('n') | KeyCode::Char('N') => { sw.name_input = Some(sw.active.clone().unwrap_or_default()); }
+        KeyCode::Char('d') | KeyCode::Char('D') => {
+            if let Some(p) = sw.current() {
+                let msg = vec![format!("Delete profile \"{}\" ({})?", p.name, p.summary()), "The providers in this project are not changed.".to_string()];
+                app.confirm = Some(ConfirmDialog::new("Delete profile?", msg, "delete", ConfirmAction::DeleteProfile(p.name.clone())));
+            }
+        }
+        KeyCode::Enter => {
+            let name = sw.current()?.name.clone();
+            if app.providers.as_ref().map_or(false, |s| s.has_unsaved_changes()) {
+                let msg = vec!["Configure has unsaved provider changes.".to_string(), format!("Switching to \"{}\" discards them.", name)];
+                app.confirm = Some(ConfirmDialog::new("Switch profile?", msg, "discard and switch", ConfirmAction::SwitchProfile(name)));
+                return None;
+            }
+            return switch_profile(app, &name);
+        }
+        _ => {}
+    }
+    None
+}
+
+/// Write profile `name` into the store and drop the pages that cached the
+/// old providers. Returns the store path written.
+pub(super) fn switch_profile(app: &mut App, name: &str) -> Option<String> {
+    let (status, wrote) = match profiles::switch(name) {
+        Ok(path) => {
+            // Force Configure/Select Default to reload from disk
+            app.providers = None;
+            app.defaultp = None;
+            (format!("Switched to profile {}; the previous providers are in Backups", name), Some(path))
+        }
+        Err(e) => (format!("Error: switching to {} failed: {}", name, e), None),
+    };
+    if let Some(sw) = &mut app.profiles { sw.reload(); sw.status = Some(status); }
+    wrote
+}
@@ -0,0 +1,190 @@
+use std::sync::mpsc::{channel, Receiver, TryRecvError};
+use std::thread;
+use std::time::Duration;
+
+use ratatui::layout::Rect;
+use ratatui::prelude::Frame;
+use ratatui::style::{Modifier, Style};
+use ratatui::text::{Line, Span};
+use ratatui::widgets::{Block, Borders, Paragraph};
+
+use crate::app::App;
+use crate::health::{check_tcp, endpoint_of, ConnectionStatus};
+use crate::providers::read_scratch_entries;
+use crate::text;
+use crate::theme::StatusKind;
+
+#[derive(Clone, Debug)]
+pub struct LatencyRow {
+    pub id: String,
+    pub name: String,
+    pub endpoint: String,
+    pub status: ConnectionStatus,
+}
+
+/// The Latency page. Measuring takes up to a few seconds per unreachable
+/// provider, so it runs on a thread; `tick` polls for the rows.
+#[derive(Debug, Default)]
+pub struct LatencyState {
+    pub rows: Vec<LatencyRow>, // fastest first, failures last
+    pub selected: usize,
+    pub status: Option<String>,
+    measuring: Option<Receiver<Vec<LatencyRow>>>,
+}
+
+impl LatencyState {
+    /// A page that starts measuring right away.
+    pub fn start() -> Self {
+        let mut st = LatencyState::default();
+        st.refresh();
+        st
+    }
+
+    /// Measure again; the current rows stay until the new ones arrive.
+    pub fn refresh(&mut self) {
+        let (tx, rx) = channel();
+        thread::spawn(move || {
+            let _ = tx.send(measure_latencies());
+        });
+        self.measuring = Some(rx);
+    }
+
+    pub fn measuring(&self) -> bool {
+        self.measuring.is_some()
+    }
+
+    /// Take the rows of a finished measurement. Returns true when they arrived.
+    pub fn poll(&mut self) -> bool {
+        let rows = match self.measuring.as_ref().map(|rx| rx.try_recv()) {
+            Some(Ok(rows)) => rows,
+            Some(Err(TryRecvError::Empty)) | None => return false,
+            Some(Err(TryRecvError::Disconnected)) => Vec::new(),
+        };
+        self.measuring = None;
+        self.rows = rows;
+        self.selected = self.selected.min(self.rows.len().saturating_sub(1));
+        true
+    }
+
+    pub fn move_up(&mut self) { if self.selected > 0 { self.selected -= 1; } }
+    pub fn move_down(&mut self) { if self.selected + 1 < self.rows.len() { self.selected += 1; } }
+    pub fn current(&self) -> Option<&LatencyRow> { self.rows.get(self.selected) }
+}
+
+/// Measure every network provider in parallel (one thread each) and wait
+/// for all of them.
+pub fn measure_latencies() -> Vec<LatencyRow> {
+    let entries = read_scratch_entries().unwrap_or_default();
+    let handles: Vec<_> = entries
+        .into_iter()
+        .filter_map(|e| endpoint_of(&e).map(|ep| (e, ep)))
+        .map(|(e, (host, port))| {
+            thread::spawn(move || LatencyRow {
+                id: e.id.clone(),
+                name: e.name.clone(),
+                endpoint: format!("{}:{}", host, port),
+                status: check_tcp(&e.id, &host, port, Duration::from_secs(1)),
+            })
+        })
+        .collect();
+    let mut rows: Vec<LatencyRow> = handles.into_iter().filter_map(|h| h.join().ok()).collect();
+    rows.sort_by_key(|r| match r.status.latency { Some(d) => (0, d), None => (1, Duration::ZERO) });
+    rows
+}
+
+fn kind_for(d: Duration) -> StatusKind {
+    match d.as_millis() {
+        0..=49 => StatusKind::Ok,
+        50..=199 => StatusKind::Warn,
+        _ => StatusKind::Err,
+    }
+}
+
+pub fn draw_latency(f: &mut Frame, area: Rect, app: &App) {
+    let mut lines: Vec<Line> = Vec::new();
+    match &app.latency {
+        None => lines.push(Line::from("Measuring...")),
+        Some(st) if st.measuring() && st.rows.is_empty() => lines.push(Line::from("Measuring...")),
+        Some(st) => {
+            let max = st.rows.iter().filter_map(|r| r.status.latency).max().unwrap_or(Duration::from_millis(1));
+            let name_w = st.rows.iter().map(|r| text::width(&r.name)).max().unwrap_or(4).min(24);
+            let bar_w = (area.width as usize).saturating_sub(name_w + 36).max(10);
+            for (i, r) in st.rows.iter().enumerate() {
+                let marker = if i == st.selected { '›' } else { ' ' };
+                let head = Span::styled(
+                    format!("{} {} ", marker, text::fit(&r.name, name_w)),
+                    if i == st.selected { Style::default().fg(app.theme.selected).add_modifier(Modifier::BOLD) } else { Style::default().fg(app.theme.fg) },
+                );
+                let line = match r.status.latency {
+                    Some(d) => {
+                        let kind = kind_for(d);
+                        let len = ((d.as_secs_f64() / max.as_secs_f64()) * bar_w as f64).ceil().max(1.0) as usize;
+                        Line::from(vec![
+                            head,
+                            Span::styled("█".repeat(len.min(bar_w)), app.theme.status_style(kind)),
+                            Span::raw(format!(" {} {:.1} ms  {}", kind.symbol(), d.as_secs_f64() * 1000.0, r.endpoint)),
+                        ])
+                    }
+                    None => Line::from(vec![
+                        head,
+                        Span::styled(
+                            format!("{} unreachable ({}) {}", StatusKind::Err.symbol(), r.endpoint, r.status.error_label().unwrap_or_default()),
+                            app.theme.status_style(StatusKind::Err),
+                        ),
+                    ]),
+                };
+                lines.push(line);
+            }
+            if st.rows.is_empty() { lines.push(Line::from("No network providers configured.")); }
+            if st.measuring() {
+                lines.push(Line::from(""));
+                lines.push(Line::from("Measuring again..."));
+            }
+            if let Some(msg) = &st.status {
+                lines.push(Line::from(""));
+                let (txt, style) = app.theme.status_text(msg);
+                lines.push(Line::from(Span::styled(txt, style)));
+            }
+        }
+    }
+    let p = Paragraph::new(lines)
+        .style(Style::default().bg(app.theme.bg).fg(app.theme.fg))
+        .block(Block::default().borders(Borders::ALL).border_style(Style::default().fg(app.theme.frame)).title("Latency Map (TCP round-trip, best of 3)"));
+    f.render_widget(p, area);
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+
+    #[test]
+    fn bars_are_colored_by_round_trip() {
+        assert_eq!(kind_for(Duration::from_millis(3)), StatusKind::Ok);
+        assert_eq!(kind_for(Duration::from_millis(50)), StatusKind::Warn);
+        assert_eq!(kind_for(Duration::from_millis(200)), StatusKind::Err);
+    }
+
+    #[cfg(unix)]
+    #[test]
+    fn measuring_runs_in_the_background_fastest_first() {
+        let mut fake = crate::testing::FakeCli::new();
+        fake.set_env("CHI_TUI_MOCK", "on".to_string());
+        crate::store::write(&crate::mock::providers()).expect("store");
+        let mut st = LatencyState::start();
+        assert!(st.measuring() && st.rows.is_empty());
+        let started = std::time::Instant::now();
+        while !st.poll() && started.elapsed() < Duration::from_secs(5) {
+            thread::sleep(Duration::from_millis(10));
+        }
+        assert!(!st.measuring());
+        let ids: Vec<&str> = st.rows.iter().map(|r| r.id.as_str()).collect();
+        // The built-in provider has no endpoint; the .invalid host is down
+        assert!(!ids.contains(&"local"), "{:?}", ids);
+        assert_eq!(ids.last(), Some(&"lab-vllm"));
+        assert!(st.rows.windows(2).all(|w| w[1].status.latency.is_none() || w[0].status.latency <= w[1].status.latency));
+
+        st.selected = st.rows.len() - 1;
+        st.refresh();
+        assert!(st.measuring() && !st.rows.is_empty(), "old rows stay while measuring");
+    }
+}
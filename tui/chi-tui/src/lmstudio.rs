@@ -0,0 +1,184 @@
+//! LM Studio's native REST API for the Model Browser: `/api/v0/models`
+//! says which downloaded models are loaded, and a one-token completion
+//! asks LM Studio to load (JIT) a model before it is used. Servers without
+//! the native API fall back to `/v1/models`, with the load state unknown.
+
+use std::collections::HashSet;
+use std::sync::mpsc::{channel, Receiver, Sender};
+use std::thread;
+use std::time::Duration;
+
+use anyhow::{anyhow, Result};
+use serde_json::Value;
+
+use crate::http::local_api;
+use crate::providers::ProviderScratchEntry;
+
+/// Loading a large model can take a while.
+const LOAD_TIMEOUT: Duration = Duration::from_secs(180);
+
+#[derive(Clone, Debug, PartialEq, Eq)]
+pub struct LmsModel {
+    pub id: String,
+    /// None when the server has no native API
+    pub loaded: Option<bool>,
+    /// "llm", "vlm", "embeddings"
+    pub kind: Option<String>,
+    pub quantization: Option<String>,
+    pub max_context_length: Option<u64>,
+}
+
+/// Models LM Studio has downloaded, with their load state when known.
+pub fn models(entry: &ProviderScratchEntry, timeout: Duration) -> Result<Vec<LmsModel>> {
+    match local_api(entry, "GET", "/api/v0/models", None, timeout) {
+        Ok(v) => Ok(parse_native(&v)),
+        Err(_) => {
+            let v = local_api(entry, "GET", "/v1/models", None, timeout)?;
+            let data = v.get("data").and_then(|d| d.as_array()).ok_or_else(|| anyhow!("/v1/models: no model list"))?;
+            Ok(data
+                .iter()
+                .filter_map(|m| m.get("id").and_then(|x| x.as_str()))
+                .map(|id| LmsModel { id: id.to_string(), loaded: None, kind: None, quantization: None, max_context_length: None })
+                .collect())
+        }
+    }
+}
+
+/// `{"data": [{"id", "type", "state": "loaded" | "not-loaded", "quantization", "max_context_length"}]}`
+pub fn parse_native(v: &Value) -> Vec<LmsModel> {
+    let text = |m: &Value, k: &str| m.get(k).and_then(|x| x.as_str()).map(|s| s.to_string());
+    v.get("data")
+        .and_then(|d| d.as_array())
+        .map(|a| {
+            a.iter()
+                .filter_map(|m| {
+                    Some(LmsModel {
+                        id: text(m, "id")?,
+                        loaded: text(m, "state").map(|s| s == "loaded"),
+                        kind: text(m, "type"),
+                        quantization: text(m, "quantization"),
+                        max_context_length: m.get("max_context_length").and_then(|x| x.as_u64()),
+                    })
+                })
+                .collect()
+        })
+        .unwrap_or_default()
+}
+
+/// Ask LM Studio to load `model`: a one-token completion triggers its
+/// just-in-time loading and returns once the model answered.
+fn load(entry: &ProviderScratchEntry, model: &str) -> Result<()> {
+    let body = serde_json::json!({"model": model, "prompt": "", "max_tokens": 1});
+    match local_api(entry, "POST", "/api/v0/completions", Some(body.clone()), LOAD_TIMEOUT) {
+        Ok(_) => Ok(()),
+        Err(_) => local_api(entry, "POST", "/v1/completions", Some(body), LOAD_TIMEOUT).map(|_| ()),
+    }
+}
+
+/// Model loads running off the UI thread.
+pub struct Loader {
+    loading: HashSet<String>,
+    tx: Sender<(String, Result<(), String>)>,
+    rx: Receiver<(String, Result<(), String>)>,
+}
+
+impl Default for Loader {
+    fn default() -> Self {
+        let (tx, rx) = channel();
+        Loader { loading: HashSet::new(), tx, rx }
+    }
+}
+
+impl Loader {
+    /// Start loading `model`; false when a load of it is already running.
+    pub fn start(&mut self, entry: &ProviderScratchEntry, model: &str) -> bool {
+        if !self.loading.insert(model.to_string()) { return false; }
+        let (entry, model, tx) = (entry.clone(), model.to_string(), self.tx.clone());
+        thread::spawn(move || {
+            let res = load(&entry, &model).map_err(|e| e.to_string());
+            let _ = tx.send((model, res));
+        });
+        true
+    }
+
+    pub fn is_loading(&self, model: &str) -> bool {
+        self.loading.contains(model)
+    }
+
+    /// Finished loads as (model, result).
+    pub fn poll(&mut self) -> Vec<(String, Result<(), String>)> {
+        let mut done = Vec::new();
+        while let Ok((model, res)) = self.rx.try_recv() {
+            self.loading.remove(&model);
+            done.push((model, res));
+        }
+        done
+    }
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+    use serde_json::json;
+
+    #[test]
+    fn native_models_carry_their_load_state() {
+        let v = json!({"object": "list", "data": [
+            {"id": "qwen2.5-7b-instruct", "object": "model", "type": "llm", "publisher": "lmstudio-community", "state": "loaded", "quantization": "Q4_K_M", "max_context_length": 32768},
+            {"id": "text-embedding-nomic-embed-text-v1.5", "type": "embeddings", "state": "not-loaded"},
+            {"type": "llm", "state": "loaded"},
+        ]});
+        assert_eq!(parse_native(&v), vec![
+            LmsModel { id: "qwen2.5-7b-instruct".to_string(), loaded: Some(true), kind: Some("llm".to_string()), quantization: Some("Q4_K_M".to_string()), max_context_length: Some(32768) },
+            LmsModel { id: "text-embedding-nomic-embed-text-v1.5".to_string(), loaded: Some(false), kind: Some("embeddings".to_string()), quantization: None, max_context_length: None },
+        ]);
+        assert!(parse_native(&json!({"error": "unknown route"})).is_empty());
+    }
+
+    #[cfg(unix)]
+    fn server(status: &'static str, body: &str) -> ProviderScratchEntry {
+        let url = crate::testing::http_server(status, "application/json", body);
+        let port = url.rsplit(':').next().expect("port").to_string();
+        crate::testing::entry("studio", "lmstudio", json!({"host": "127.0.0.1", "port": port}))
+    }
+
+    #[cfg(unix)]
+    fn wait(loader: &mut Loader) -> Vec<(String, Result<(), String>)> {
+        let started = std::time::Instant::now();
+        loop {
+            let done = loader.poll();
+            if !done.is_empty() || started.elapsed() > Duration::from_secs(10) { return done; }
+            thread::sleep(Duration::from_millis(20));
+        }
+    }
+
+    #[cfg(unix)]
+    #[test]
+    fn models_are_listed_from_the_native_api() {
+        let _fake = crate::testing::FakeCli::new();
+        let entry = server("200 OK", r#"{"data": [{"id": "phi-3", "state": "not-loaded"}]}"#);
+        let models = models(&entry, Duration::from_secs(5)).expect("models");
+        assert_eq!(models.len(), 1);
+        assert_eq!((models[0].id.as_str(), models[0].loaded), ("phi-3", Some(false)));
+    }
+
+    #[cfg(unix)]
+    #[test]
+    fn a_jit_load_runs_once_and_reports_back() {
+        let _fake = crate::testing::FakeCli::new();
+        let entry = server("200 OK", r#"{"choices": [{"text": ""}]}"#);
+        let mut loader = Loader::default();
+        assert!(loader.start(&entry, "phi-3"));
+        assert!(!loader.start(&entry, "phi-3"), "already loading");
+        assert!(loader.is_loading("phi-3"));
+        let done = wait(&mut loader);
+        assert_eq!(done, vec![("phi-3".to_string(), Ok(()))]);
+        assert!(!loader.is_loading("phi-3"));
+
+        let entry = server("500 Internal Server Error", r#"{"error": "no such model"}"#);
+        assert!(loader.start(&entry, "nope"));
+        let done = wait(&mut loader);
+        assert_eq!(done.len(), 1);
+        assert!(done[0].1.as_ref().expect_err("fails").contains("HTTP 500"));
+    }
+}
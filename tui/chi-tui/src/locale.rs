@@ -0,0 +1,199 @@
+use std::sync::OnceLock;
+use std::time::Duration;
+
+use chrono::{DateTime, NaiveDateTime, Utc};
+
+/// Number separators for the user's locale. Only display text uses these;
+/// exports and audit records keep raw values (bytes, RFC3339).
+#[derive(Clone, Copy, Debug, PartialEq)]
+pub struct NumberFormat {
+    pub decimal: char,
+    pub thousands: char,
+}
+
+const EN: NumberFormat = NumberFormat { decimal: '.', thousands: ',' };
+
+/// Language of `LC_ALL`, `LC_NUMERIC` or `LANG` (first one set), e.g. "pl"
+/// for "pl_PL.UTF-8". "C" and "POSIX" count as unset.
+fn language() -> Option<String> {
+    ["LC_ALL", "LC_NUMERIC", "LANG"]
+        .iter()
+        .filter_map(|k| std::env::var(k).ok())
+        .find(|v| !v.is_empty())
+        .map(|v| v.split(|c| c == '_' || c == '.' || c == '@' || c == '-').next().unwrap_or("").to_ascii_lowercase())
+        .filter(|l| !l.is_empty() && l != "c" && l != "posix")
+}
+
+fn for_language(lang: &str) -> NumberFormat {
+    match lang {
+        "de" | "es" | "it" | "nl" | "pt" | "da" | "tr" | "id" | "el" => NumberFormat { decimal: ',', thousands: '.' },
+        "fr" | "pl" | "ru" | "uk" | "cs" | "sk" | "sv" | "fi" | "nb" | "no" | "hu" | "bg" => NumberFormat { decimal: ',', thousands: ' ' },
+        _ => EN,
+    }
+}
+
+pub fn number_format() -> NumberFormat {
+    static FORMAT: OnceLock<NumberFormat> = OnceLock::new();
+    *FORMAT.get_or_init(|| language().map_or(EN, |l| for_language(&l)))
+}
+
+fn group(digits: &str, sep: char) -> String {
+    let mut out = String::new();
+    for (i, c) in digits.chars().enumerate() {
+        if i > 0 && (digits.len() - i) % 3 == 0 { out.push(sep); }
+        out.push(c);
+    }
+    out
+}
+
+fn count_with(n: u64, nf: NumberFormat) -> String {
+    group(&n.to_string(), nf.thousands)
+}
+
+fn decimal_with(x: f64, places: usize, nf: NumberFormat) -> String {
+    let text = format!("{:.*}", places, x.abs());
+    let (int, frac) = text.split_once('.').unwrap_or((text.as_str(), ""));
+    let mut out = if x < 0.0 { "-".to_string() } else { String::new() };
+    out.push_str(&group(int, nf.thousands));
+    if !frac.is_empty() {
+        out.push(nf.decimal);
+        out.push_str(frac);
+    }
+    out
+}
+
+fn bytes_with(bytes: u64, nf: NumberFormat) -> String {
+    const UNITS: [&str; 4] = ["KB", "MB", "GB", "TB"];
+    if bytes < 1024 { return format!("{} B", bytes); }
+    let mut v = bytes as f64 / 1024.0;
+    let mut unit = 0;
+    while v >= 1024.0 && unit + 1 < UNITS.len() {
+        v /= 1024.0;
+        unit += 1;
+    }
+    // MB and below read better whole; GB and up need the decimal
+    let places = if unit >= 2 { 1 } else { 0 };
+    format!("{} {}", decimal_with(v, places, nf), UNITS[unit])
+}
+
+/// "12,345" (or "12.345" / "12 345", per locale).
+pub fn count(n: u64) -> String {
+    count_with(n, number_format())
+}
+
+/// "7.8" / "7,8" with `places` decimals.
+pub fn decimal(x: f64, places: usize) -> String {
+    decimal_with(x, places, number_format())
+}
+
+/// Binary sizes for display: "512 MB", "1,4 GB".
+pub fn bytes(b: u64) -> String {
+    bytes_with(b, number_format())
+}
+
+fn plural(n: u64, unit: &str) -> String {
+    format!("{} {}{}", n, unit, if n == 1 { "" } else { "s" })
+}
+
+/// Coarse span: "45 seconds", "3 minutes", "2 hours", "5 days".
+pub fn duration(d: Duration) -> String {
+    let s = d.as_secs();
+    match s {
+        0..=59 => plural(s, "second"),
+        60..=3599 => plural(s / 60, "minute"),
+        3600..=86_399 => plural(s / 3600, "hour"),
+        _ => plural(s / 86_400, "day"),
+    }
+}
+
+fn ago_secs(secs: i64) -> String {
+    match secs {
+        s if s < -59 => format!("in {}", duration(Duration::from_secs(s.unsigned_abs()))),
+        s if s < 60 => "just now".to_string(),
+        // Past a month the date says more than "43 days ago"
+        s if s < 30 * 86_400 => format!("{} ago", duration(Duration::from_secs(s as u64))),
+        _ => String::new(),
+    }
+}
+
+/// "2 minutes ago"; the date (YYYY-MM-DD) once it is over a month old.
+pub fn ago(t: DateTime<Utc>) -> String {
+    let text = ago_secs((Utc::now() - t).num_seconds());
+    if text.is_empty() { t.format("%Y-%m-%d").to_string() } else { text }
+}
+
+/// `ago` for an RFC3339 string; unparseable input is shown unchanged.
+pub fn ago_rfc3339(ts: &str) -> String {
+    DateTime::parse_from_rfc3339(ts).map(|t| ago(t.with_timezone(&Utc))).unwrap_or_else(|_| ts.to_string())
+}
+
+/// `ago` for a UTC timestamp in the given chrono format, e.g. the
+/// "%Y%m%d-%H%M%S" in backup names.
+pub fn ago_utc(ts: &str, fmt: &str) -> Option<String> {
+    NaiveDateTime::parse_from_str(ts, fmt).ok().map(|t| ago(t.and_utc()))
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+
+    const DE: NumberFormat = NumberFormat { decimal: ',', thousands: '.' };
+    const PL: NumberFormat = NumberFormat { decimal: ',', thousands: ' ' };
+
+    #[test]
+    fn numbers_use_the_locale_separators() {
+        assert_eq!(count_with(1_234_567, EN), "1,234,567");
+        assert_eq!(count_with(123, DE), "123");
+        assert_eq!(count_with(1_000, PL), "1 000");
+        assert_eq!(decimal_with(12_345.678, 2, DE), "12.345,68");
+        assert_eq!(decimal_with(-7.8, 1, PL), "-7,8");
+        assert_eq!(decimal_with(3.0, 0, EN), "3");
+    }
+
+    #[test]
+    fn sizes_keep_a_decimal_from_gigabytes_up() {
+        assert_eq!(bytes_with(512, EN), "512 B");
+        assert_eq!(bytes_with(1536, EN), "2 KB");
+        assert_eq!(bytes_with(512 << 20, EN), "512 MB");
+        assert_eq!(bytes_with(1_503_238_554, PL), "1,4 GB");
+        assert_eq!(bytes_with(3 << 40, EN), "3.0 TB");
+    }
+
+    #[test]
+    fn languages_pick_their_separators() {
+        assert_eq!(for_language("de"), DE);
+        assert_eq!(for_language("pl"), PL);
+        assert_eq!(for_language("en"), EN);
+        assert_eq!(for_language("ja"), EN);
+    }
+
+    #[cfg(unix)]
+    #[test]
+    fn the_language_comes_from_the_first_locale_variable_set() {
+        let mut fake = crate::testing::FakeCli::new();
+        fake.set_env("LC_ALL", String::new());
+        fake.set_env("LC_NUMERIC", "pl_PL.UTF-8".to_string());
+        fake.set_env("LANG", "de_DE.UTF-8".to_string());
+        assert_eq!(language().as_deref(), Some("pl"));
+        fake.set_env("LC_NUMERIC", String::new());
+        assert_eq!(language().as_deref(), Some("de"));
+        fake.set_env("LANG", "C.UTF-8".to_string());
+        assert_eq!(language(), None);
+    }
+
+    #[test]
+    fn spans_are_coarse_and_old_dates_are_shown_as_dates() {
+        assert_eq!(duration(Duration::from_secs(1)), "1 second");
+        assert_eq!(duration(Duration::from_secs(150)), "2 minutes");
+        assert_eq!(duration(Duration::from_secs(7200)), "2 hours");
+        assert_eq!(duration(Duration::from_secs(86_400)), "1 day");
+        assert_eq!(ago_secs(30), "just now");
+        assert_eq!(ago_secs(-120), "in 2 minutes");
+        assert_eq!(ago_secs(3 * 86_400), "3 days ago");
+        assert_eq!(ago_secs(40 * 86_400), "");
+        assert_eq!(ago_rfc3339("2020-01-02T03:04:05Z"), "2020-01-02");
+        assert_eq!(ago_rfc3339("yesterday"), "yesterday");
+        assert_eq!(ago_utc("20200102-030405", "%Y%m%d-%H%M%S").as_deref(), Some("2020-01-02"));
+        assert_eq!(ago_utc("garbage", "%Y%m%d-%H%M%S"), None);
+    }
+}
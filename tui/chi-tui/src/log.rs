@@ -0,0 +1,26 @@
+use std::fs::{self, OpenOptions};
+use std::io::Write;
+use std::path::PathBuf;
+
+/// The TUI's own debug log, alongside chi-llm's cache directory.
+pub fn log_path() -> PathBuf {
+    match dirs::home_dir() {
+        Some(home) => home.join(".cache").join("chi_llm").join("chi-tui.log"),
+        None => PathBuf::from("chi-tui.log"),
+    }
+}
+
+/// Append one line; logging never fails the caller.
+pub fn append(level: &str, msg: &str) {
+    let path = log_path();
+    if let Some(dir) = path.parent() { let _ = fs::create_dir_all(dir); }
+    if let Ok(mut f) = OpenOptions::new().create(true).append(true).open(&path) {
+        for line in msg.lines() {
+            let _ = writeln!(f, "{} [{}] {}", chrono::Utc::now().to_rfc3339(), level, line);
+        }
+    }
+}
+
+pub fn debug(msg: &str) { append("DEBUG", msg); }
+pub fn info(msg: &str) { append("INFO", msg); }
+pub fn warn(msg: &str) { append("WARN", msg); }
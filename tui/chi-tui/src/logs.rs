@@ -0,0 +1,296 @@
+//! Logs page: the tail of chi-llm's log (`~/.cache/chi_llm/chi-llm.log`,
+//! or `CHI_LLM_LOG_FILE`) and of chi-tui's own debug log, so a failed
+//! discovery or test can be explained without leaving the app. Follow mode
+//! keeps the view on the newest line as the file grows.
+
+use std::fs::File;
+use std::io::{Read, Seek, SeekFrom};
+use std::path::PathBuf;
+use std::time::{Duration, Instant, SystemTime};
+
+use ratatui::layout::Rect;
+use ratatui::prelude::Frame;
+use ratatui::style::Style;
+use ratatui::text::{Line, Span};
+use ratatui::widgets::{Block, Borders, Paragraph};
+
+use crate::app::App;
+use crate::theme::StatusKind;
+
+/// Only the end of a large log is read.
+const TAIL_BYTES: u64 = 256 * 1024;
+const POLL_EVERY: Duration = Duration::from_millis(750);
+/// Lines PgUp/PgDn move
+pub const PAGE: usize = 20;
+
+#[derive(Copy, Clone, Debug, PartialEq, Eq)]
+pub enum Source {
+    Cli,
+    Tui,
+}
+
+impl Source {
+    pub fn label(self) -> &'static str {
+        match self {
+            Source::Cli => "chi-llm",
+            Source::Tui => "chi-tui",
+        }
+    }
+
+    /// Same rules as the CLI's `log_path()`; None when logging is off.
+    pub fn path(self) -> Option<PathBuf> {
+        match self {
+            Source::Tui => Some(crate::log::log_path()),
+            Source::Cli => match std::env::var("CHI_LLM_LOG_FILE").unwrap_or_default().trim() {
+                s if s.eq_ignore_ascii_case("off") => None,
+                "" => dirs::home_dir().map(|h| h.join(".cache").join("chi_llm").join("chi-llm.log")),
+                s => Some(PathBuf::from(s)),
+            },
+        }
+    }
+
+    fn toggled(self) -> Self {
+        match self {
+            Source::Cli => Source::Tui,
+            Source::Tui => Source::Cli,
+        }
+    }
+}
+
+/// Severity, most severe first; the filter shows lines at or above it.
+#[derive(Copy, Clone, Debug, PartialEq, Eq, PartialOrd, Ord)]
+pub enum Level {
+    Error,
+    Warn,
+    Info,
+}
+
+impl Level {
+    pub fn label(self) -> &'static str {
+        match self {
+            Level::Error => "errors",
+            Level::Warn => "warnings and errors",
+            Level::Info => "all",
+        }
+    }
+
+    fn cycled(self) -> Self {
+        match self {
+            Level::Info => Level::Warn,
+            Level::Warn => Level::Error,
+            Level::Error => Level::Info,
+        }
+    }
+
+    /// `[ERROR]`, `[WARNING]`… as written by Python logging and `log.rs`.
+    pub fn of_line(line: &str) -> Option<Level> {
+        let tag = line.split('[').nth(1)?.split(']').next()?;
+        match tag {
+            "ERROR" | "CRITICAL" => Some(Level::Error),
+            "WARN" | "WARNING" => Some(Level::Warn),
+            "INFO" | "DEBUG" => Some(Level::Info),
+            _ => None,
+        }
+    }
+}
+
+pub struct LogView {
+    pub source: Source,
+    pub filter: Level,
+    /// Each line with its level; tracebacks take their record's level
+    pub lines: Vec<(Level, String)>,
+    /// Filtered lines between the bottom of the view and the newest one
+    pub back: usize,
+    pub follow: bool,
+    pub status: Option<String>,
+    seen: Option<(u64, SystemTime)>,
+    last_poll: Option<Instant>,
+}
+
+impl LogView {
+    pub fn new() -> Self {
+        let mut v = LogView { source: Source::Cli, filter: Level::Info, lines: Vec::new(), back: 0, follow: true, status: None, seen: None, last_poll: None };
+        v.reload();
+        v
+    }
+
+    /// Re-read the tail of the current source.
+    pub fn reload(&mut self) {
+        let before = self.visible().len();
+        self.lines.clear();
+        self.seen = None;
+        if let Some(path) = self.source.path() {
+            if let Ok((text, meta)) = read_tail(&path) {
+                self.seen = Some(meta);
+                let mut level = Level::Info;
+                for line in text.lines() {
+                    if let Some(l) = Level::of_line(line) { level = l; }
+                    self.lines.push((level, line.to_string()));
+                }
+            }
+        }
+        // Keep the same lines in view when not following
+        if self.follow { self.back = 0; } else { self.back = (self.back + self.visible().len()).saturating_sub(before); }
+        self.last_poll = Some(Instant::now());
+    }
+
+    /// Re-read when the file changed since the last look. Returns true
+    /// when the lines changed.
+    pub fn poll(&mut self) -> bool {
+        if self.last_poll.map_or(false, |t| t.elapsed() < POLL_EVERY) { return false; }
+        self.last_poll = Some(Instant::now());
+        let now = self.source.path().and_then(|p| std::fs::metadata(p).ok()).map(|m| (m.len(), m.modified().unwrap_or(SystemTime::UNIX_EPOCH)));
+        if now == self.seen { return false; }
+        self.reload();
+        true
+    }
+
+    pub fn visible(&self) -> Vec<&(Level, String)> {
+        self.lines.iter().filter(|(l, _)| *l <= self.filter).collect()
+    }
+
+    pub fn scroll_up(&mut self, n: usize) {
+        self.back = self.back.saturating_add(n).min(self.visible().len());
+        self.follow = false;
+    }
+
+    pub fn scroll_down(&mut self, n: usize) {
+        self.back = self.back.saturating_sub(n);
+        if self.back == 0 { self.follow = true; }
+    }
+
+    pub fn to_end(&mut self) {
+        self.back = 0;
+        self.follow = true;
+    }
+
+    pub fn toggle_follow(&mut self) {
+        self.follow = !self.follow;
+        if self.follow { self.back = 0; }
+    }
+
+    pub fn cycle_filter(&mut self) {
+        self.filter = self.filter.cycled();
+        self.to_end();
+    }
+
+    pub fn switch_source(&mut self) {
+        self.source = self.source.toggled();
+        self.status = None;
+        self.to_end();
+        self.reload();
+    }
+
+    /// The filtered lines, for the clipboard.
+    pub fn text(&self) -> String {
+        self.visible().iter().map(|(_, l)| l.as_str()).collect::<Vec<_>>().join("\n")
+    }
+}
+
+/// The last `TAIL_BYTES` of `path`, starting at a line boundary.
+pub fn read_tail(path: &std::path::Path) -> std::io::Result<(String, (u64, SystemTime))> {
+    let mut f = File::open(path)?;
+    let meta = f.metadata()?;
+    let len = meta.len();
+    let start = len.saturating_sub(TAIL_BYTES);
+    f.seek(SeekFrom::Start(start))?;
+    let mut buf = Vec::new();
+    f.read_to_end(&mut buf)?;
+    let mut text = String::from_utf8_lossy(&buf).to_string();
+    if start > 0 {
+        // The first line is probably cut
+        text = text.split_once('\n').map(|(_, rest)| rest.to_string()).unwrap_or_default();
+    }
+    Ok((text, (len, meta.modified().unwrap_or(SystemTime::UNIX_EPOCH))))
+}
+
+pub fn draw_logs(f: &mut Frame, area: Rect, app: &App) {
+    let Some(v) = &app.logs else {
+        f.render_widget(Paragraph::new("Reading logs...").block(Block::default().borders(Borders::ALL)), area);
+        return;
+    };
+    let height = area.height.saturating_sub(2) as usize;
+    let visible = v.visible();
+    let end = visible.len().saturating_sub(v.back);
+    let start = end.saturating_sub(height.saturating_sub(usize::from(v.status.is_some())));
+    let mut lines: Vec<Line> = Vec::new();
+    let path = v.source.path();
+    if visible.is_empty() {
+        lines.push(Line::from(match (&path, v.lines.is_empty()) {
+            (None, _) => "chi-llm logging is off (CHI_LLM_LOG_FILE=off).".to_string(),
+            (Some(p), true) => format!("No log yet at {}", p.display()),
+            (Some(_), false) => format!("No {} in the last {} lines.", v.filter.label(), v.lines.len()),
+        }));
+    }
+    for (level, line) in &visible[start..end] {
+        let style = match level {
+            Level::Error => app.theme.status_style(StatusKind::Err),
+            Level::Warn => app.theme.status_style(StatusKind::Warn),
+            Level::Info => Style::default().fg(app.theme.fg),
+        };
+        lines.push(Line::from(Span::styled(line.clone(), style)));
+    }
+    if let Some(s) = &v.status {
+        let (text, style) = app.theme.status_text(s);
+        lines.push(Line::from(Span::styled(text, style)));
+    }
+    let where_ = path.map(|p| p.display().to_string()).unwrap_or_else(|| "off".to_string());
+    let title = format!(
+        "Logs — {} ({}) • showing {} • {}",
+        v.source.label(),
+        where_,
+        v.filter.label(),
+        if v.follow { "following".to_string() } else { format!("{} lines above the end", v.back) }
+    );
+    let p = Paragraph::new(lines)
+        .style(Style::default().bg(app.theme.bg).fg(app.theme.fg))
+        .block(Block::default().borders(Borders::ALL).border_style(Style::default().fg(app.theme.frame)).title(title));
+    f.render_widget(p, area);
+}
+
+#[cfg(all(test, unix))]
+mod tests {
+    use super::*;
+    use crate::testing::FakeCli;
+
+    #[test]
+    fn logs_page_filters_and_follows_the_cli_log() {
+        let mut fake = FakeCli::new();
+        let log = fake.root.join("chi-llm.log");
+        fake.set_env("CHI_LLM_LOG_FILE", log.display().to_string());
+        let mut v = LogView::new();
+        assert_eq!(v.source, Source::Cli);
+        assert!(v.lines.is_empty());
+
+        std::fs::write(&log, concat!(
+            "2026-10-16 10:00:00 [INFO] chi_llm.cli: chi-llm providers discover\n",
+            "2026-10-16 10:00:01 [WARNING] chi_llm.cli: ollama: no models listed\n",
+            "2026-10-16 10:00:02 [ERROR] chi_llm.cli: command failed\n",
+            "Traceback (most recent call last):\n",
+            "2026-10-16 10:00:03 [INFO] chi_llm.cli: chi-llm diagnostics\n",
+        )).expect("write log");
+        v.reload();
+        assert_eq!(v.visible().len(), 5);
+        v.cycle_filter();
+        assert_eq!(v.filter, Level::Warn);
+        assert_eq!(v.visible().len(), 3);
+        v.cycle_filter();
+        // The traceback stays with its error
+        assert_eq!(v.text(), "2026-10-16 10:00:02 [ERROR] chi_llm.cli: command failed\nTraceback (most recent call last):");
+
+        // Scrolling up stops following; new lines keep the view in place
+        v.cycle_filter();
+        v.scroll_up(2);
+        assert!(!v.follow);
+        let mut f = std::fs::OpenOptions::new().append(true).open(&log).expect("open log");
+        std::io::Write::write_all(&mut f, b"2026-10-16 10:00:04 [INFO] chi_llm.cli: chi-llm models list\n").expect("append");
+        v.reload();
+        assert_eq!(v.back, 3);
+        v.scroll_down(usize::MAX);
+        assert!(v.follow);
+
+        fake.set_env("CHI_LLM_LOG_FILE", "off".to_string());
+        v.reload();
+        assert!(v.source.path().is_none() && v.lines.is_empty());
+    }
+}
@@ -1,70 +1,104 @@
-use std::io::{self, Stdout};
+use std::io::Stdout;
 use std::time::Duration;
 
 use anyhow::Result;
-use clap::Parser;
-use crossterm::event::{self, DisableMouseCapture, EnableMouseCapture, Event, KeyCode, KeyEvent, KeyModifiers};
-use crossterm::execute;
-use crossterm::terminal::{disable_raw_mode, enable_raw_mode, EnterAlternateScreen, LeaveAlternateScreen};
+use clap::{Parser, Subcommand};
+use crossterm::event::{self, Event, KeyCode, KeyEvent, KeyModifiers};
 use ratatui::backend::CrosstermBackend;
-use ratatui::layout::{Alignment, Constraint, Direction, Layout, Rect};
-use ratatui::style::{Color, Modifier, Style};
-use ratatui::text::{Line, Span};
-use ratatui::widgets::{Block, Borders, Clear, List, ListItem, Paragraph, Wrap};
 use ratatui::Terminal;
-use ratatui::prelude::Frame;
-use serde_json::Value;
 
 mod theme;
+mod theme_picker;
 mod util;
+mod variables;
 mod app;
+mod audit;
+mod backup;
+mod benchmark;
+mod bridge;
+mod bundle;
 mod diagnostics;
+mod discovery_cache;
+mod doctor;
+mod download_journal;
+mod downloads;
 mod readme;
+mod recommend;
 mod models;
 mod providers;
 mod build;
+mod buildconflict;
+mod confirm;
+mod configmerge;
+mod deeptest;
+mod deprecations;
+mod clipboard;
+mod config_cli;
+mod qr;
+mod cache;
+mod render;
+mod script;
+mod rules;
+mod secrets;
+mod envfile;
+mod export;
+mod server;
+mod hooks;
+mod http;
+mod health;
+mod health_watch;
+mod help;
+mod hf;
+mod inspector;
+mod instance;
+mod keys;
+mod latency;
+mod locale;
+mod playground;
+mod log;
+mod logs;
+mod portforward;
+mod prefs;
+mod privacy;
+mod profile;
+mod profiles;
+mod probe;
+mod density;
+mod focus;
+mod fuzzy;
+mod menu;
+mod mock;
+mod modelname;
+mod model_defaults;
+mod mouse;
+mod lmstudio;
+mod ollama;
+mod toast;
+mod settings;
+mod shutdown;
+mod status;
+mod store;
+mod term;
+mod text;
+mod ui;
+#[cfg(all(test, unix))]
+mod testing;
+#[cfg(all(test, unix))]
+mod e2e;
+#[cfg(all(test, unix))]
+mod config_fuzz;
+#[cfg(all(test, unix))]
+mod bench;
 
-use app::{App, Page, WELCOME_ITEMS};
-use build::{BuildState, BuildTarget, draw_build_config, write_active_config};
-use diagnostics::{draw_diagnostics, export_diagnostics, fetch_diagnostics};
-use models::{fetch_models, draw_model_browser};
-use providers::{ProvidersState, FormState, DropdownState, load_providers_state, draw_providers_catalog, probe_provider, load_providers_scratch, save_default_provider, draw_select_default};
-use readme::{load_readme, draw_readme};
-use util::{ensure_chi_llm, centered_rect, neon_gradient_line};
-
-fn ensure_form_for_selected(st: &mut ProvidersState) {
-    if st.selected >= st.entries.len() { st.form = None; return; }
-    let entry = &st.entries[st.selected];
-    let mut ff = Vec::new();
-    if let Some(sfields) = st.schema_map.get(&entry.ptype) {
-        for sc in sfields.iter() {
-            let mut value = String::new();
-            if let Some(cfg) = entry.config.as_object() {
-                if let Some(v) = cfg.get(&sc.name) {
-                    value = match v { Value::String(s) => s.clone(), other => other.to_string() };
-                }
-            }
-            if value.is_empty() { if let Some(d) = &sc.default { value = d.clone(); } }
-            ff.push(providers::FormField { schema: providers::FieldSchema { name: sc.name.clone(), ftype: sc.ftype.clone(), required: sc.required, default: sc.default.clone(), help: sc.help.clone(), options: sc.options.clone() }, buffer: value, cursor: 0 });
-        }
-    }
-    let init_hash = providers::compute_form_hash(&ff);
-    st.form = Some(FormState { fields: ff, selected: 0, editing: false, message: None, scroll: 0, initial_hash: init_hash, last_test_ok_hash: None });
-}
-
-fn focus_form_field(st: &mut ProvidersState, field_name: &str) {
-    if st.selected >= st.entries.len() { return; }
-    ensure_form_for_selected(st);
-    if let Some(form) = &mut st.form {
-        if let Some(idx) = form.fields.iter().position(|f| f.schema.name == field_name) {
-            form.selected = idx;
-            form.editing = true;
-            st.focus_right = true;
-        } else {
-            st.focus_right = true;
-        }
-    }
-}
+use app::{App, Page};
+use backup::maybe_snapshot;
+use diagnostics::fetch_diagnostics;
+use keys::{handle_diagnostics_key, handle_key};
+use models::fetch_models;
+use render::FrameGate;
+use theme::StatusKind;
+use toast::Toast;
+use util::ensure_chi_llm;
 
 #[derive(Parser, Debug)]
 #[command(name = "chi-tui")] 
@@ -73,642 +107,422 @@ struct Args {
     /// Do not use alternate screen buffer
     #[arg(long = "no-alt")]
     no_alt: bool,
+    /// Do not enable mouse reporting (IDE/web terminals)
+    #[arg(long = "no-mouse")]
+    no_mouse: bool,
+    /// Do not set the window title or report download/benchmark progress
+    /// to the taskbar (OSC 9;4)
+    #[arg(long = "no-title")]
+    no_title: bool,
+    /// Always use the compact single-column layout
+    #[arg(long)]
+    compact: bool,
+    /// Open this page on start (configure, models, diagnostics, ...). If
+    /// chi-tui is already running in this directory, ask it to open the
+    /// page instead of starting a second instance.
+    #[arg(long, value_parser = parse_page)]
+    page: Option<Page>,
+    /// Time each startup phase (config load, provider listing, model
+    /// catalog, first render) and print the breakdown on exit
+    #[arg(long = "profile-startup")]
+    profile_startup: bool,
+    /// With --profile-startup: also write a Chrome trace (Perfetto, chrome://tracing)
+    #[arg(long = "profile-out", requires = "profile_startup")]
+    profile_out: Option<std::path::PathBuf>,
+    /// Run the diagnostics and test every configured provider, print a
+    /// report and exit 1 if the default provider is unreachable
+    #[arg(long)]
+    doctor: bool,
+    /// Run against a fixed set of fake providers, canned model lists and
+    /// simulated latencies, in a throwaway directory; no chi-llm or servers
+    /// needed (development, demos, screenshots)
+    #[arg(long)]
+    mock: bool,
+    /// Play keys without a terminal and print the final screen, e.g.
+    /// "2,A,tab,s" or a file with one step per line (see script.rs)
+    #[arg(long, value_name = "KEYS|FILE")]
+    script: Option<String>,
+    /// Print one rendered frame of the start page (see --page) and exit;
+    /// with --mock the state is fixed, for snapshot comparisons
+    #[arg(long = "dump-frame")]
+    dump_frame: bool,
+    /// With --script or --dump-frame: screen size, WIDTHxHEIGHT
+    #[arg(long, alias = "script-size", value_parser = script::parse_size, default_value = "100x30")]
+    size: (u16, u16),
+    /// With --doctor: print the report as JSON
+    #[arg(long, requires = "doctor")]
+    json: bool,
+    /// Write a support bundle (summary, versions, doctor report, logs and
+    /// configs with secrets redacted) to a zip in the working directory
+    /// and print its path
+    #[arg(long = "support-bundle")]
+    support_bundle: bool,
+    #[command(subcommand)]
+    command: Option<Cmd>,
+}
+
+#[derive(Subcommand, Debug)]
+enum Cmd {
+    /// Print the default provider in effect now (evaluates default_rules)
+    ResolveDefault {
+        /// Output JSON with the matching rule and reason
+        #[arg(long)]
+        json: bool,
+    },
+    /// Exit 0 if a provider answers a connection test, 1 otherwise (for
+    /// systemd/Kubernetes readiness and liveness probes)
+    Probe {
+        /// Provider id from chi.tmp.json (default: the default provider in effect)
+        #[arg(long)]
+        provider: Option<String>,
+        /// Connect timeout, e.g. 5s, 500ms, 1m
+        #[arg(long, default_value = "5s")]
+        timeout: String,
+        /// Also require GET /v1/models to succeed (OpenAI-compatible servers, Anthropic)
+        #[arg(long)]
+        http: bool,
+        /// Print nothing; only the exit code
+        #[arg(long, short)]
+        quiet: bool,
+        /// Print the result as JSON (endpoint, latency, HTTP status, error_code)
+        #[arg(long)]
+        json: bool,
+    },
+    /// Manage providers without the UI (list, add, remove, set-default)
+    #[command(visible_alias = "providers")]
+    Config {
+        #[command(subcommand)]
+        action: config_cli::ConfigCmd,
+    },
+    /// Print the default provider as a .env file (chi_llm's CHI_LLM_PROVIDER_* variables)
+    Env {
+        /// Print the direnv .envrc form instead
+        #[arg(long)]
+        envrc: bool,
+        /// Write .env and .envrc in the working directory
+        #[arg(long)]
+        write: bool,
+    },
+    /// Print a secret stored by chi-tui, e.g. `chi-tui secret openai1.api_key`
+    Secret { name: String },
+    /// Download a file (used for downloads detached on quit)
+    #[command(hide = true)]
+    Fetch {
+        #[arg(long)]
+        url: String,
+        #[arg(long)]
+        target: std::path::PathBuf,
+    },
+}
+
+fn parse_page(s: &str) -> std::result::Result<Page, String> {
+    Page::from_key(s).ok_or_else(|| format!("unknown page (one of: {})", Page::ALL.iter().map(|p| p.key()).collect::<Vec<_>>().join(", ")))
 }
 
 fn main() -> Result<()> {
     let args = Args::parse();
+    if args.mock { mock::start()?; }
+    if args.doctor { doctor::run_doctor(args.json); }
+    if args.support_bundle { bundle::run_support_bundle(); }
+    if let Some(cmd) = args.command {
+        return match cmd {
+            Cmd::ResolveDefault { json } => rules::run_resolve_default(json),
+            Cmd::Probe { provider, timeout, http, quiet, json } => probe::run_probe(provider, &timeout, http, quiet, json),
+            Cmd::Config { action } => config_cli::run_config(action),
+            Cmd::Env { envrc, write } => envfile::run_env(envrc, write),
+            Cmd::Secret { name } => envfile::run_secret(&name),
+            Cmd::Fetch { url, target } => downloads::run_fetch(&url, &target),
+        };
+    }
+    let mut profile = args.profile_startup.then(profile::StartupProfile::new);
+    let mut mark = |name| if let Some(p) = &mut profile { p.mark(name) };
     ensure_chi_llm()?;
+    mark("chi-llm check");
+    if args.script.is_some() || args.dump_frame {
+        let steps = match &args.script { Some(keys) => script::load(keys)?, None => Vec::new() };
+        let mut app = App::new(false);
+        app.force_compact = args.compact;
+        load_preferences(&mut app);
+        if let Some(page) = args.page { open_page_loaded(&mut app, page); }
+        print!("{}", script::run(app, &steps, args.size)?);
+        return Ok(());
+    }
+    let instance = instance::Instance::acquire();
+    if let (Some(other), Some(page)) = (&instance.other, args.page) {
+        match instance::send(other, serde_json::json!({"cmd": "open", "page": page.key()})) {
+            Ok(_) => {
+                println!("chi-tui is already running here (pid {}); asked it to open {}.", other.pid, page.key());
+                return Ok(());
+            }
+            Err(e) => eprintln!("Could not reach the running chi-tui (pid {}): {}; starting a second instance.", other.pid, e),
+        }
+    }
+    mark("instance lock");
+    // Daily snapshot of the provider store (best-effort)
+    let _ = maybe_snapshot();
+    mark("store snapshot");
 
     // Terminal setup
-    enable_raw_mode()?;
-    let mut stdout = io::stdout();
-    if !args.no_alt {
-        execute!(stdout, EnterAlternateScreen, EnableMouseCapture)?;
-    } else {
-        execute!(stdout, EnableMouseCapture)?;
+    let opts = term::TermOptions { alt_screen: !args.no_alt, mouse: !args.no_mouse, title: !args.no_title };
+    term::install_panic_hook(opts);
+    let mut terminal = term::setup(opts)?;
+    mark("terminal setup");
+    let mut app = App::new(!args.no_alt);
+    mark("app state");
+    app.force_compact = args.compact;
+    load_preferences(&mut app);
+    mark("config load");
+    // The Model Browser opens at once when its lists are already here
+    app.prefetch.start();
+    if let Some(other) = &instance.other {
+        app.toast = Some(Toast::new(StatusKind::Warn, format!("chi-tui pid {} is also editing this directory's config; saves may overwrite each other", other.pid)));
     }
-    let backend = CrosstermBackend::new(stdout);
-    let mut terminal = Terminal::new(backend)?;
-    let res = run_app(&mut terminal, App::new(!args.no_alt));
-
-    // Restore terminal
-    disable_raw_mode()?;
-    let mut stdout = io::stdout();
-    if !args.no_alt {
-        execute!(stdout, LeaveAlternateScreen, DisableMouseCapture)?;
-    } else {
-        execute!(stdout, DisableMouseCapture)?;
+    app.instance = instance;
+    // Downloads an earlier run left unfinished continue from their .part file
+    let resumed = app.downloads.resume_pending();
+    if !resumed.is_empty() && app.toast.is_none() {
+        app.toast = Some(Toast::new(StatusKind::Ok, format!("Resuming {} unfinished download(s): {} (x in the Model Browser cancels)", resumed.len(), resumed.join(", "))));
     }
-    terminal.show_cursor()?;
-
-    if let Err(err) = res {
-        eprintln!("\nError: {err}");
-        std::process::exit(1);
+    if let Some(p) = &mut profile {
+        // Paid on first use of Configure / Model Browser; measured here up
+        // front. The catalog is kept, so the Model Browser opens instantly.
+        let _ = p.time("provider listing", providers::read_scratch_entries);
+        if let Ok(mut m) = p.time("model catalog", || fetch_models(Duration::from_secs(5))) {
+            p.time("local servers", || m.add_server_entries());
+            app.model = Some(m);
+        }
     }
-    Ok(())
-}
+    if let Some(page) = args.page {
+        open_page_loaded(&mut app, page);
+        if let Some(p) = &mut profile { p.mark("open page"); }
+    }
+    app.startup = profile;
+    let res = run_app(&mut terminal, app, opts.title);
 
-fn run_app(terminal: &mut Terminal<CrosstermBackend<Stdout>>, mut app: App) -> Result<()> {
-    let tick_rate = Duration::from_millis(100);
-    loop {
-        terminal.draw(|f| ui(f, &app))?;
-        if event::poll(tick_rate)? {
-            if let Event::Key(key) = event::read()? {
-                // Diagnostics page extra keys
-                if app.page == Page::Diagnostics {
-                    match key.code {
-                        KeyCode::Char('e') | KeyCode::Char('E') => {
-                            if let Some(diag) = &app.diag {
-                                match export_diagnostics(diag) {
-                                    Ok(path) => {
-                                        if let Some(d) = &mut app.diag.clone() {
-                                            let mut d2 = d.clone();
-                                            d2.saved_path = Some(path);
-                                            app.diag = Some(d2);
-                                        }
-                                    }
-                                    Err(e) => app.last_error = Some(format!("Export failed: {e}")),
-                                }
-                            }
-                            continue;
-                        }
-                        KeyCode::Char('r') | KeyCode::Char('R') => {
-                            match fetch_diagnostics(Duration::from_secs(5)) {
-                                Ok(d) => app.diag = Some(d),
-                                Err(e) => app.last_error = Some(format!("Diagnostics failed: {e}")),
-                            }
-                            continue;
-                        }
-                        _ => {}
-                    }
-                }
-                handle_key(&mut app, key);
+    // Restore terminal
+    term::restore(opts)?;
+
+    let profile = match res {
+        Ok(profile) => profile,
+        Err(err) => {
+            eprintln!("\nError: {err}");
+            std::process::exit(1);
+        }
+    };
+    if let Some(p) = profile {
+        eprint!("{}", p.report());
+        if let Some(path) = &args.profile_out {
+            match p.write_trace(path) {
+                Ok(()) => eprintln!("Trace written to {} (open in Perfetto or chrome://tracing)", path.display()),
+                Err(e) => eprintln!("Could not write {}: {}", path.display(), e),
             }
         }
-        if app.should_quit { break; }
     }
     Ok(())
 }
 
-fn handle_key(app: &mut App, key: KeyEvent) {
-    // Ctrl+C / q always quits
-    if key.code == KeyCode::Char('c') && key.modifiers.contains(KeyModifiers::CONTROL) { app.should_quit = true; return; }
-    match key.code {
-        KeyCode::Char('q') => { app.should_quit = true; }
-        KeyCode::Char('?') => { app.show_help = !app.show_help; }
-        KeyCode::Char('t') => { app.theme.toggle(); }
-        KeyCode::Char('a') => { app.anim = !app.anim; }
-        KeyCode::Char('1') => app.page = Page::Readme,
-        KeyCode::Char('2') => app.page = Page::Configure,
-        KeyCode::Char('3') => app.page = Page::SelectDefault,
-        KeyCode::Char('4') => {
-            app.page = Page::Diagnostics;
-            if app.diag.is_none() {
-                match fetch_diagnostics(Duration::from_secs(5)) {
-                    Ok(d) => app.diag = Some(d),
-                    Err(e) => app.last_error = Some(format!("Diagnostics failed: {e}")),
-                }
-            }
-        }
-        KeyCode::Char('b') | KeyCode::Char('B') => app.page = Page::Build,
-        KeyCode::Char('s') | KeyCode::Char('S') => app.page = Page::Settings,
-        KeyCode::Esc => {
-            if app.show_help { app.show_help = false; }
-            else if app.page != Page::Welcome { app.page = Page::Welcome; }
-            else { app.should_quit = true; }
-        }
-        _ => {}
-    }
+/// Preferences saved in the store and the global config.
+fn load_preferences(app: &mut App) {
+    app.prefer_private = privacy::load_prefer_private();
+    app.density = density::load_density();
+    app.config_format = store::write_format(&store::read_or_empty());
+    app.env_sync = envfile::load_sync();
+    app.prefs = prefs::load();
+    app.anim = app.prefs.animation;
+    app.theme = theme::by_name(&app.prefs.theme);
+    app.theme.set_colorblind(app.prefs.colorblind);
+}
 
-    // Welcome-specific navigation
-    if app.page == Page::Welcome {
-        match key.code {
-            KeyCode::Up => { if app.menu_idx > 0 { app.menu_idx -= 1; } },
-            KeyCode::Down => { if app.menu_idx < WELCOME_ITEMS.len() - 1 { app.menu_idx += 1; } },
-            KeyCode::Enter => {
-                app.page = WELCOME_ITEMS[app.menu_idx].1;
-                if app.page == Page::Diagnostics && app.diag.is_none() {
-                    match fetch_diagnostics(Duration::from_secs(5)) {
-                        Ok(d) => app.diag = Some(d),
-                        Err(e) => app.last_error = Some(format!("Diagnostics failed: {e}")),
-                    }
-                }
-            }
-            _ => {}
-        }
+/// Write `app.prefs` to tui.json; a failure is shown, the change stays
+/// for this run.
+fn save_prefs(app: &mut App) {
+    if let Err(e) = prefs::save(&app.prefs) {
+        app.toast = Some(Toast::new(StatusKind::Err, format!("Saving settings failed: {}", e)));
     }
+}
 
-    // README keys
-    if app.page == Page::Readme {
-        if app.readme.is_none() {
-            app.readme = Some(load_readme());
-        }
-        if let Some(rm) = &mut app.readme {
-            // When TOC visible, allow Tab to switch focus and Up/Down to navigate TOC
-            match key.code {
-                KeyCode::Char('h') | KeyCode::Char('H') => {
-                    rm.show_toc = !rm.show_toc;
-                    if !rm.show_toc { rm.focus_toc = false; }
-                }
-                KeyCode::Tab => {
-                    if rm.show_toc { rm.focus_toc = !rm.focus_toc; }
-                }
-                KeyCode::BackTab => {
-                    if rm.show_toc { rm.focus_toc = !rm.focus_toc; }
-                }
-                KeyCode::Up => {
-                    if rm.show_toc && rm.focus_toc {
-                        if rm.toc_selected > 0 { rm.toc_selected -= 1; }
-                    } else {
-                        rm.scroll_up(1);
-                    }
-                }
-                KeyCode::Down => {
-                    if rm.show_toc && rm.focus_toc {
-                        if rm.toc_selected + 1 < rm.toc.len() { rm.toc_selected += 1; }
-                    } else {
-                        rm.scroll_down(1);
-                    }
-                }
-                KeyCode::PageUp => rm.scroll_up(8),
-                KeyCode::PageDown => rm.scroll_down(8),
-                KeyCode::Enter => {
-                    if rm.show_toc && rm.focus_toc {
-                        if let Some(entry) = rm.toc.get(rm.toc_selected) {
-                            rm.scroll = entry.line;
-                            rm.focus_toc = false; // jump to content focus
-                        }
-                    }
-                }
-                _ => {}
-            }
-        }
-    }
+/// Window title and taskbar progress for the current state: the page and
+/// the provider it is about, plus running downloads or a benchmark run.
+fn window_status(app: &App) -> (String, term::Progress) {
+    let provider = match app.page {
+        Page::Configure => app.providers.as_ref().and_then(|s| s.entries.get(s.selected)).map(|e| e.name.clone()),
+        Page::SelectDefault => app.defaultp.as_ref().and_then(|s| s.providers.get(s.selected)).map(|p| p.name.clone()),
+        Page::Benchmark => app.benchmark.as_ref().and_then(|b| b.entries.get(b.provider_idx)).map(|e| e.name.clone()),
+        _ => None,
+    };
+    let mut title = format!("chi-tui — {}", menu::label(app.page));
+    if let Some(p) = provider { title.push_str(&format!(" • {}", p)); }
+    let running = app.downloads.running();
+    let progress = if let Some((_, done)) = app.benchmark.as_ref().and_then(|b| b.running.as_ref()) {
+        term::Progress::Percent((done * 100 / benchmark::PROMPTS.len()) as u8)
+    } else if running.is_empty() {
+        term::Progress::Off
+    } else if running.iter().all(|j| j.total.map_or(false, |t| t > 0)) {
+        let (done, total) = running.iter().fold((0u64, 0u64), |(d, t), j| (d + j.done, t + j.total.unwrap_or(0)));
+        term::Progress::Percent((done.min(total) * 100 / total) as u8)
+    } else {
+        term::Progress::Indeterminate
+    };
+    if !running.is_empty() { title.push_str(&format!(" • {} downloading", running.len())); }
+    (title, progress)
+}
 
-    // Model Browser keys
-    if app.page == Page::ModelBrowser {
-        if app.model.is_none() {
-            match fetch_models(Duration::from_secs(5)) {
-                Ok(m) => app.model = Some(m),
-                Err(e) => app.last_error = Some(format!("Models failed: {e}")),
-            }
-        }
-        if let Some(m) = &mut app.model {
-            match key.code {
-                KeyCode::Up => m.move_up(),
-                KeyCode::Down => m.move_down(),
-                KeyCode::Char('r') | KeyCode::Char('R') => m.toggle_downloaded_only(),
-                KeyCode::Char('f') | KeyCode::Char('F') => m.cycle_tag(),
-                KeyCode::Char('i') | KeyCode::Char('I') => m.show_info = !m.show_info,
-                KeyCode::Enter => {
-                    if let Some(cur) = m.current_entry() { app.selected_model_id = Some(cur.id.clone()); }
-                    app.page = Page::Configure; // return to configure with selected model id
-                }
-                _ => {}
-            }
+/// Returns the startup profile, when `--profile-startup` is on. With
+/// `title`, the window title and taskbar progress follow the app state.
+fn run_app(terminal: &mut Terminal<CrosstermBackend<Stdout>>, mut app: App, title: bool) -> Result<Option<profile::StartupProfile>> {
+    let tick_rate = Duration::from_millis(100);
+    let mut gate = FrameGate::new();
+    let mut window = title.then(term::WindowStatus::default);
+    loop {
+        let size = terminal.size()?;
+        app.fit(size.width, size.height);
+        let inputs = menu::inputs(&app);
+        if app.menu.refresh(inputs) { gate.invalidate(); }
+        gate.draw(terminal, |f| ui::draw(f, &app))?;
+        if let Some(w) = &mut window {
+            let (title, progress) = window_status(&app);
+            let _ = w.update(terminal.backend_mut(), &title, progress);
         }
-    }
-
-    // Select Default provider keys
-    if app.page == Page::SelectDefault {
-        if app.defaultp.is_none() {
-            match load_providers_scratch() {
-                Ok(s) => app.defaultp = Some(s),
-                Err(e) => app.last_error = Some(format!("Load providers failed: {e}")),
-            }
+        if let Some(p) = app.startup.as_mut().filter(|p| !p.rendered) {
+            p.mark("first render");
+            p.rendered = true;
         }
-        if let Some(s) = &mut app.defaultp {
-            match key.code {
-                KeyCode::Up => { if !s.providers.is_empty() && s.selected > 0 { s.selected -= 1; } },
-                KeyCode::Down => { if !s.providers.is_empty() && s.selected + 1 < s.providers.len() { s.selected += 1; } },
-                KeyCode::Enter | KeyCode::Char('s') | KeyCode::Char('S') => {
-                    if let Some(p) = s.providers.get(s.selected) {
-                        s.current_default_id = Some(p.id.clone());
-                        if let Err(e) = save_default_provider(&p.id) {
-                            app.last_error = Some(format!("Save default failed: {e}"));
-                        }
-                    }
+        if event::poll(tick_rate)? {
+            let ev = event::read()?;
+            // Any input or resize may change what is on screen
+            gate.invalidate();
+            match ev {
+                Event::Key(key) => {
+                    if !handle_diagnostics_key(&mut app, key) { handle_key(&mut app, key); }
                 }
+                Event::Mouse(m) => mouse::handle_mouse(&mut app, m),
                 _ => {}
             }
+        } else if app.toast.as_ref().map_or(false, |t| t.expired()) {
+            app.toast = None;
+            gate.invalidate();
         }
+        if tick(&mut app) { gate.invalidate(); }
+        if app.should_quit { break; }
     }
+    Ok(app.startup.take())
+}
 
-    // Configure Providers keys
-    if app.page == Page::Configure {
-        if app.providers.is_none() {
-            app.providers = Some(match load_providers_state() {
-                Ok(s) => s,
-                Err(e) => { app.last_error = Some(format!("Load providers failed: {e}")); ProvidersState::empty() }
-            });
-        }
-        if let Some(st) = &mut app.providers {
-            // Dropdown handling (e.g., type selector)
-            if let Some(dd) = &mut st.dropdown {
-                match key.code {
-                    KeyCode::Up => { if dd.selected > 0 { dd.selected -= 1; } }
-                    KeyCode::Down => { if dd.selected + 1 < dd.items.len() { dd.selected += 1; } }
-                    KeyCode::Enter => {
-                        if dd.selected < dd.items.len() {
-                            let chosen = dd.items[dd.selected].clone();
-                            match dd.target_field {
-                                None => {
-                                    if st.selected < st.entries.len() {
-                                        st.entries[st.selected].ptype = chosen.clone();
-                                        ensure_form_for_selected(st);
-                                        if let Some(form) = &mut st.form {
-                                            form.selected = 1.min(form.fields.len());
-                                            form.editing = false;
-                                            form.message = Some("Type changed".to_string());
-                                        }
-                                    }
-                                }
-                                Some(fi) => {
-                                    if let Some(form) = &mut st.form {
-                                        if fi < form.fields.len() {
-                                            form.fields[fi].buffer = chosen.clone();
-                                            form.editing = false;
-                                            form.message = Some(format!("{} set", form.fields[fi].schema.name));
-                                        }
-                                    }
-                                }
-                            }
-                        }
-                        st.dropdown = None;
-        			return;
-                    }
-                    KeyCode::Esc => { st.dropdown = None; return; }
-                    _ => { return; }
-                }
-                return;
-            }
-            // Pane focus shortcuts: Tab cycles focus between panes; Shift+Tab cycles backward
-            match key.code {
-                KeyCode::Tab => {
-                    if !st.focus_right {
-                        if st.is_add_row() { st.add_default(); }
-                        if st.selected < st.entries.len() { ensure_form_for_selected(st); }
-                        st.focus_right = true;
-                    } else {
-                        st.focus_right = false;
-                    }
-                },
-                KeyCode::BackTab => {
-                    if st.focus_right {
-                        st.focus_right = false;
-                    } else {
-                        if st.is_add_row() { st.add_default(); }
-                        if st.selected < st.entries.len() { ensure_form_for_selected(st); }
-                        st.focus_right = true;
-                    }
-                },
-                _ => {}
-            }
-            if st.focus_right {
-                // Right pane: inline form
-                if st.form.is_none() && st.selected < st.entries.len() { ensure_form_for_selected(st); }
-                if let Some(form) = &mut st.form {
-                    match key.code {
-                        KeyCode::Esc => { if form.editing { form.editing = false; } else { st.focus_right = false; } }
-                        // Up/Down navigate between form groups. Treat [Test|Save|Cancel] as one group.
-                        KeyCode::Up => {
-                            let fields_len = form.fields.len();
-                            let test_idx = fields_len + 1;
-                            let save_idx = fields_len + 2;
-                            let cancel_idx = fields_len + 3;
-                            if form.selected == test_idx || form.selected == save_idx || form.selected == cancel_idx {
-                                // Jump to last field (or Type if no fields)
-                                form.selected = if fields_len > 0 { fields_len } else { 0 };
-                            } else if form.selected > 0 {
-                                form.selected -= 1;
-                            }
-                        }
-                        KeyCode::Down => {
-                            let fields_len = form.fields.len();
-                            let test_idx = fields_len + 1;
-                            let save_idx = fields_len + 2;
-                            let cancel_idx = fields_len + 3;
-                            let total = fields_len + 4;
-                            if form.selected == test_idx || form.selected == save_idx || form.selected == cancel_idx {
-                                // Already in the last group; stay within group on Down
-                            } else if form.selected + 1 < total {
-                                form.selected += 1;
-                            }
-                        }
-                        KeyCode::Enter => {
-                            // If on Type row: open dropdown
-                            if form.selected == 0 {
-                                let current = st.entries.get(st.selected).map(|e| e.ptype.clone()).unwrap_or_default();
-                                let idx = st.schema_types.iter().position(|t| *t == current).unwrap_or(0);
-                                st.dropdown = Some(DropdownState { items: st.schema_types.clone(), selected: idx, title: "Select Provider Type".to_string(), target_field: None });
-                                return;
-                            }
-                            // If on Test/Save/Cancel buttons, act; else toggle edit
-                            let test_idx = form.fields.len() + 1;
-                            let save_idx = form.fields.len() + 2;
-                            let cancel_idx = form.fields.len() + 3;
-                            let total = form.fields.len() + 4;
-                            if form.selected == test_idx {
-                                // Run test: use CLI where applicable
-                                let mut status = String::new();
-                                let mut ptype_cur = String::new();
-                                if st.selected < st.entries.len() {
-                                    let entry = &st.entries[st.selected];
-                                    ptype_cur = entry.ptype.clone();
-                                    match probe_provider(entry) {
-                                        Ok(msg) => { status = msg; },
-                                        Err(e) => { status = format!("Error: {}", e); },
-                                    }
-                                }
-                                let cur_hash = providers::compute_form_hash(&form.fields);
-                                let low = status.to_lowercase();
-                                if (ptype_cur == "lmstudio" || ptype_cur == "ollama" || ptype_cur == "openai") && !low.starts_with("error") && !low.contains("http ") {
-                                    form.last_test_ok_hash = Some(cur_hash);
-                                } else {
-                                    form.last_test_ok_hash = None;
-                                }
-                                form.message = Some(status);
-                            } else if form.selected == save_idx {
-                                let mut missing: Vec<String> = Vec::new();
-                                for ff in &form.fields { if ff.schema.required && ff.buffer.trim().is_empty() { missing.push(ff.schema.name.clone()); } }
-                                if !missing.is_empty() {
-                                    form.message = Some(format!("Missing required: {}", missing.join(", ")));
-                                } else {
-                                    // Enforce: if dirty and not tested ok, prevent save
-                                    let cur_hash = providers::compute_form_hash(&form.fields);
-                                    let dirty = cur_hash != form.initial_hash;
-                                    let tested_ok = form.last_test_ok_hash.as_ref().map_or(false, |h| *h == cur_hash);
-                                    if dirty && !tested_ok {
-                                        form.message = Some("Run Test connection first".to_string());
-                                        return;
-                                    }
-                                    if st.selected < st.entries.len() {
-                                        if let Some(obj) = st.entries[st.selected].config.as_object_mut() {
-                                            for ff in &form.fields {
-                                                let key2 = ff.schema.name.clone();
-                                                if ff.schema.ftype == "int" {
-                                                    if let Ok(n) = ff.buffer.parse::<i64>() { obj.insert(key2, Value::Number(n.into())); } else { obj.insert(key2, Value::String(ff.buffer.clone())); }
-                                                } else {
-                                                    obj.insert(key2, Value::String(ff.buffer.clone()));
-                                                }
-                                            }
-                                        }
-                                    }
-                                    form.message = Some("Saved".to_string());
-                                    // Update baseline hash after save
-                                    form.initial_hash = cur_hash;
-                                    form.last_test_ok_hash = Some(form.initial_hash.clone());
-                                }
-                            } else if form.selected == cancel_idx { // Cancel
-                                form.editing = false;
-                                st.focus_right = false;
-                            } else {
-                                // If field has options, open dropdown, else toggle edit
-                                let fi = form.selected - 1; // map to fields index
-                                if let Some(ff) = form.fields.get(fi) {
-                                    // Special-case: dynamic model list for lmstudio/ollama using CLI
-                                    let ptype = st.entries.get(st.selected).map(|e| e.ptype.clone()).unwrap_or_default();
-                                    if ff.schema.name == "model" && (ptype == "lmstudio" || ptype == "ollama") {
-                                        // Use CLI discover-models
-                                        let host = form.fields.iter().find(|f| f.schema.name == "host").map(|f| f.buffer.clone()).unwrap_or_else(|| "localhost".to_string());
-                                        let port = form.fields.iter().find(|f| f.schema.name == "port").map(|f| f.buffer.clone()).unwrap_or_default();
-                                        let mut args = vec!["providers", "discover-models", "--type", &ptype, "--host", &host, "--json"];
-                                        if !port.is_empty() { args.push("--port"); args.push(&port); }
-                                        match util::run_cli_json(&args, Duration::from_secs(5)) {
-                                            Ok(v) => {
-                                                let mut items: Vec<String> = Vec::new();
-                                                if let Some(arr) = v.get("models").and_then(|x| x.as_array()) {
-                                                    for it in arr { if let Some(id) = it.get("id").and_then(|x| x.as_str()) { items.push(id.to_string()); } }
-                                                }
-                                                if items.is_empty() {
-                                                    form.message = Some(format!("No models discovered for {}", ptype));
-                                                } else {
-                                                    let sel = items.iter().position(|x| *x == ff.buffer).unwrap_or(0);
-                                                    st.dropdown = Some(DropdownState { items, selected: sel, title: format!("Select model ({}):", ptype), target_field: Some(fi) });
-                                                    return;
-                                                }
-                                            }
-                                            Err(e) => { form.message = Some(format!("Discover failed: {}", e)); }
-                                        }
-                                    } else if let Some(opts) = &ff.schema.options {
-                                        let mut items = opts.clone();
-                                        let current_val = ff.buffer.clone();
-                                        let mut sel = 0usize;
-                                        if let Some(i) = items.iter().position(|x| *x == current_val) { sel = i; }
-                                        st.dropdown = Some(DropdownState { items, selected: sel, title: format!("Select {}", ff.schema.name), target_field: Some(fi) });
-                                        return;
-                                    }
-                                }
-                                form.editing = !form.editing;
-                            }
-                        }
-                        // Left/Right: within button group, switch between Test/Save/Cancel. In fields, move cursor when editing.
-                        KeyCode::Left => {
-                            let fields_len = form.fields.len();
-                            let test_idx = fields_len + 1;
-                            let save_idx = fields_len + 2;
-                            let cancel_idx = fields_len + 3;
-                            if form.selected > test_idx {
-                                form.selected -= 1;
-                            } else if form.editing {
-                                if let Some(ff) = form.fields.get_mut(form.selected) {
-                                    if ff.cursor > 0 { ff.cursor -= 1; }
-                                }
-                            }
-                        }
-                        KeyCode::Right => {
-                            let fields_len = form.fields.len();
-                            let test_idx = fields_len + 1;
-                            let save_idx = fields_len + 2;
-                            let cancel_idx = fields_len + 3;
-                            if form.selected >= test_idx && form.selected < cancel_idx {
-                                form.selected += 1;
-                            } else if form.editing {
-                                if let Some(ff) = form.fields.get_mut(form.selected) {
-                                    if ff.cursor < ff.buffer.chars().count() { ff.cursor += 1; }
-                                }
-                            }
-                        }
-                        KeyCode::Home => { if form.editing { if let Some(ff) = form.fields.get_mut(form.selected) { ff.cursor = 0; } } }
-                        KeyCode::End => { if form.editing { if let Some(ff) = form.fields.get_mut(form.selected) { ff.cursor = ff.buffer.chars().count(); } } }
-                        KeyCode::Backspace => { if form.editing { if let Some(ff) = form.fields.get_mut(form.selected) { if ff.cursor > 0 { let mut s = ff.buffer.clone(); let idx = s.char_indices().nth(ff.cursor-1).map(|(i, _)| i).unwrap_or(0); let idx2 = s.char_indices().nth(ff.cursor).map(|(i, _)| i).unwrap_or(s.len()); s.replace_range(idx..idx2, ""); ff.buffer = s; ff.cursor -= 1; form.last_test_ok_hash = None; } } } }
-                        KeyCode::Delete => { if form.editing { if let Some(ff) = form.fields.get_mut(form.selected) { let len = ff.buffer.chars().count(); if ff.cursor < len { let mut s = ff.buffer.clone(); let idx = s.char_indices().nth(ff.cursor).map(|(i, _)| i).unwrap_or(s.len()); let idx2 = s.char_indices().nth(ff.cursor+1).map(|(i, _)| i).unwrap_or(s.len()); s.replace_range(idx..idx2, ""); ff.buffer = s; form.last_test_ok_hash = None; } } } }
-                        KeyCode::Tab => { let total = form.fields.len() + 4; form.selected = (form.selected + 1) % total; }
-                        KeyCode::BackTab => { let total = form.fields.len() + 4; form.selected = if form.selected == 0 { total - 1 } else { form.selected - 1 }; }
-                        _ => {}
-                    }
-                    if let KeyCode::Char(c) = key.code {
-                        if form.editing {
-                            if let Some(ff) = form.fields.get_mut(form.selected) {
-                                let mut s = ff.buffer.clone();
-                                let idx = s.char_indices().nth(ff.cursor).map(|(i, _)| i).unwrap_or(s.len());
-                                s.insert(idx, c);
-                                ff.buffer = s;
-                                ff.cursor += 1;
-                                form.last_test_ok_hash = None;
-                            }
-                        }
-                    }
-                }
-                return;
-            }
 
-            // Left pane: list navigation and actions
-            match key.code {
-                KeyCode::Up => { if st.selected > 0 { st.selected -= 1; st.form = None; } },
-                KeyCode::Down => { if st.selected + 1 < st.len_with_add() { st.selected += 1; st.form = None; } },
-                KeyCode::Enter => {
-                    if st.is_add_row() {
-                        st.add_default();
-                        ensure_form_for_selected(st);
-                        st.focus_right = true;
-                    } else {
-                        ensure_form_for_selected(st);
-                        st.focus_right = true;
-                    }
-                }
-                KeyCode::Char('a') | KeyCode::Char('A') => { st.add_default(); ensure_form_for_selected(st); st.focus_right = true; }
-                KeyCode::Char('d') | KeyCode::Char('D') => { st.delete_selected(); st.form = None; }
-                KeyCode::Char('m') | KeyCode::Char('M') => { app.page = Page::ModelBrowser; }
-                KeyCode::Char('t') | KeyCode::Char('T') => {
-                    if st.selected < st.entries.len() {
-                        match probe_provider(&st.entries[st.selected]) {
-                            Ok(msg) => st.test_status = Some(msg),
-                            Err(e) => st.test_status = Some(format!("Error: {}", e)),
-                        }
-                    }
-                }
-                // Save from left pane
-                KeyCode::Char('s') | KeyCode::Char('S') => { if let Err(e) = st.save() { app.last_error = Some(format!("Save failed: {e}")); } }
-                _ => {}
+/// Background work between key presses: remote commands, port forwards,
+/// health checks, downloads. Returns true when the screen changed.
+fn tick(app: &mut App) -> bool {
+    let mut changed = false;
+    for cmd in app.instance.poll() {
+        match cmd {
+            instance::Remote::Open(page) => {
+                open_page_loaded(app, page);
+                app.toast = Some(Toast::new(StatusKind::Ok, format!("Opened {} (requested by another chi-tui)", page.key())));
             }
-            // If a model was picked in model browser, apply to selected provider
-            if let Some(model_id) = app.selected_model_id.take() {
-                st.apply_model_to_selected(&model_id);
+            instance::Remote::Download { req, reply } => {
+                let started = req.get("cmd").and_then(|c| c.as_str()) == Some("download.start");
+                let res = app.downloads.handle_remote(&req);
+                if let (true, Ok(v)) = (started, &res) {
+                    let id = v["job"]["id"].as_str().unwrap_or_default();
+                    app.toast = Some(Toast::new(StatusKind::Ok, format!("Downloading {} for chi-llm CLI", id)));
+                }
+                let _ = reply.send(res.map_err(|e| e.to_string()));
             }
         }
+        changed = true;
     }
-
-    // Build/Write Configuration keys
-    if app.page == Page::Build {
-        if app.build.is_none() {
-            app.build = Some(BuildState::default());
+    if app.portfw.refresh() { changed = true; }
+    if app.api_server.refresh() { changed = true; }
+    if app.default_watch.poll() { changed = true; }
+    let checked = app.preflight.poll();
+    for r in &checked { app.model_index.record(&r.id, &r.name, &r.model_ids); }
+    if !checked.is_empty() && app.page == Page::SelectDefault { changed = true; }
+    if app.page == Page::Status {
+        // Also covers --page status and remote opens, which skip handle_key
+        let board = app.status_board.get_or_insert_with(status::StatusBoard::new);
+        if board.poll(&mut app.portfw) {
+            for r in &board.rows { app.model_index.record(&r.id, &r.name, &r.model_ids); }
+            changed = true;
         }
-        if let Some(st) = &mut app.build {
-            match key.code {
-                KeyCode::Char('g') | KeyCode::Char('G') => { st.toggle_target(); }
-                KeyCode::Enter => {
-                    match write_active_config(st.target) {
-                        Ok(path) => st.status = Some(format!("Written: {}", path)),
-                        Err(e) => st.status = Some(format!("Error: {}", e)),
-                    }
-                }
-                _ => {}
+    }
+    if app.benchmark.as_mut().map_or(false, |b| b.poll()) { changed = true; }
+    if app.latency.as_mut().map_or(false, |l| l.poll()) { changed = true; }
+    if app.page == Page::Logs && app.logs.get_or_insert_with(logs::LogView::new).poll() { changed = true; }
+    if let Some(msg) = app.deep_test.poll() {
+        if let Some(st) = &mut app.providers { st.test_status = Some(msg); }
+        changed = true;
+    }
+    for (id, res) in app.lms_loader.poll() {
+        app.toast = Some(match res {
+            Ok(()) => {
+                if let Some(m) = &mut app.model { m.mark_loaded(&id); }
+                Toast::new(StatusKind::Ok, format!("LM Studio loaded {}", id))
             }
+            Err(e) => Toast::new(StatusKind::Err, format!("LM Studio could not load {}: {}", id, e)),
+        });
+        changed = true;
+    }
+    for res in app.hooks.poll() {
+        if let Err(e) = res { app.toast = Some(Toast::new(StatusKind::Warn, format!("Post-save hook failed: {}", e))); }
+        changed = true;
+    }
+    if shutdown::tick(app) { changed = true; }
+    if poll_prefetch(app) { changed = true; }
+    if app.downloads.poll() {
+        for id in app.downloads.take_finished() {
+            if let Some(m) = &mut app.model { m.mark_downloaded(&id); }
+            discovery_cache::forget(discovery_cache::MODELS_LIST);
+            app.toast = Some(Toast::new(StatusKind::Ok, format!("Downloaded {}", id)));
         }
+        changed = true;
+    } else if app.downloads.active_count() > 0 && app.page == Page::ModelBrowser {
+        // ETA moves even between progress messages
+        changed = true;
     }
+    changed
 }
 
-fn ui(f: &mut Frame, app: &App) {
-    let chunks = Layout::default()
-        .direction(Direction::Vertical)
-        .constraints([
-            Constraint::Length(6), // header with animation space
-            Constraint::Min(3),
-            Constraint::Length(1), // footer
-        ]).split(f.size());
 
-    draw_header(f, chunks[0], app);
-    match app.page {
-        Page::Welcome => draw_welcome(f, chunks[1], app),
-        Page::Readme => draw_readme(f, chunks[1], app),
-        Page::Configure => draw_providers_catalog(f, chunks[1], app),
-        Page::SelectDefault => draw_select_default(f, chunks[1], app),
-        Page::ModelBrowser => draw_model_browser(f, chunks[1], app),
-        Page::Diagnostics => draw_diagnostics(f, chunks[1], app),
-        Page::Build => draw_build_config(f, chunks[1], app),
-        Page::Settings => draw_stub(f, chunks[1], app, "Settings (stub) — t/a toggles"),
+/// Take the prefetched model lists once they arrived, keeping the Model
+/// Browser's view. Returns true when something arrived.
+fn poll_prefetch(app: &mut App) -> bool {
+    let Some(res) = app.prefetch.poll() else { return false };
+    match res {
+        Ok(mut fresh) => {
+            if let Some(old) = &app.model { fresh.keep_view_of(old); }
+            app.model = Some(fresh);
+        }
+        // The page fetches again when opened
+        Err(e) => log::warn(&format!("model list prefetch failed: {}", e)),
     }
-    draw_footer(f, chunks[2], app);
-
-    if app.show_help { draw_help_overlay(f, app); }
-}
-
-fn draw_header(f: &mut Frame, area: Rect, app: &App) {
-    let title = neon_gradient_line(" chi_llm — micro‑LLM • TUI vNext ", &app.theme);
-    let sub = Line::from(vec![
-        Span::styled("  retro/synthwave • arrows + enter • ? help ", Style::default().fg(app.theme.secondary)),
-    ]);
-    let block = Block::default()
-        .borders(Borders::BOTTOM)
-        .border_style(Style::default().fg(app.theme.frame))
-        .title(Span::styled("CHI_TUI", Style::default().fg(app.theme.primary).add_modifier(Modifier::BOLD)))
-        .title_alignment(Alignment::Center);
-    let v = vec![title, sub];
-    let p = Paragraph::new(v)
-        .style(Style::default().bg(app.theme.bg).fg(app.theme.fg))
-        .block(block)
-        .alignment(Alignment::Center)
-        .wrap(Wrap { trim: true });
-    f.render_widget(p, area);
+    true
 }
 
-fn draw_footer(f: &mut Frame, area: Rect, app: &App) {
-    let msg_text = match app.page {
-        Page::Diagnostics => "Esc: back • q: quit • e: export • r: refresh • ?: help",
-        Page::Readme => "Up/Down scroll • PgUp/PgDn • h TOC • Tab switch TOC/Content • Enter jump • Esc back",
-        Page::ModelBrowser => "Up/Down select • Enter choose • r downloaded-only • f tag filter • i info • Esc back",
-        Page::Configure => "Tab/Shift+Tab switch • ↑/↓ field • Enter edit/Test/Save/Cancel • ←/→/Home/End • Del/Backspace • Esc back",
-        Page::Build => "g toggle target • Enter write • Esc back",
-        Page::SelectDefault => "Up/Down select • Enter set default • Esc back",
-        _ => "Esc: back • q: quit • 1/2/3/4/b/s: sections • ?: help",
-    };
-    let msg = Line::from(Span::styled(msg_text, Style::default().fg(app.theme.secondary)));
-    let p = Paragraph::new(msg)
-        .style(Style::default().bg(app.theme.bg).fg(app.theme.fg))
-        .block(Block::default())
-        .alignment(Alignment::Center);
-    f.render_widget(p, area);
-}
-
-fn draw_welcome(f: &mut Frame, area: Rect, app: &App) {
-    let items: Vec<ListItem> = WELCOME_ITEMS.iter().enumerate().map(|(i, (label, _))| {
-        let style = if i == app.menu_idx { Style::default().fg(app.theme.selected).add_modifier(Modifier::BOLD) } else { Style::default().fg(app.theme.fg) };
-        ListItem::new(Line::from(Span::styled(format!("{} {}", if i == app.menu_idx {"›"} else {" "}, label), style)))
-    }).collect();
-    let list = List::new(items)
-        .block(Block::default().borders(Borders::ALL).border_style(Style::default().fg(app.theme.frame)).title("Welcome"))
-        .highlight_style(Style::default().fg(app.theme.selected));
-    f.render_widget(list, area);
+/// Switch pages, loading data a page needs before its first draw.
+fn open_page(app: &mut App, page: Page) {
+    app.page = page;
+    if page == Page::Diagnostics && app.diag.is_none() {
+        match fetch_diagnostics(Duration::from_secs(5)) {
+            Ok(d) => app.diag = Some(d),
+            Err(e) => app.last_error = Some(format!("Diagnostics failed: {e}")),
+        }
+    }
 }
 
-fn draw_stub(f: &mut Frame, area: Rect, app: &App, text: &str) {
-    let p = Paragraph::new(text)
-        .style(Style::default().bg(app.theme.bg).fg(app.theme.fg))
-        .block(Block::default().borders(Borders::ALL).border_style(Style::default().fg(app.theme.frame)))
-        .alignment(Alignment::Center)
-        .wrap(Wrap { trim: true });
-    f.render_widget(p, area);
+/// Open a page from outside the key handler (`--page`, remote command) and
+/// run its lazy loaders, as a key press on the page would.
+fn open_page_loaded(app: &mut App, page: Page) {
+    app.show_help = false;
+    open_page(app, page);
+    handle_key(app, KeyEvent::new(KeyCode::Null, KeyModifiers::NONE));
 }
 
-fn draw_help_overlay(f: &mut Frame, app: &App) {
-    let area = centered_rect(70, 60, f.size());
-    let lines = vec![
-        Line::from(Span::styled("Global keys:", Style::default().fg(app.theme.primary).add_modifier(Modifier::BOLD))),
-        Line::from("Up/Down: navigate • Enter: select • Esc: back • q/Ctrl+C: quit"),
-        Line::from("1: README • 2: Configure • 3: Select Default • 4: Diagnostics • b: Build • s: Settings"),
-        Line::from("?: help overlay • t: theme • a: animation"),
-        Line::from("Diagnostics: e export • r refresh"),
-        Line::from("Model Browser: r downloaded-only • f cycle tag • i info"),
-        Line::from("Configure: Tab/Shift+Tab • ↑/↓ field • Enter edit/Test/Save/Cancel • ←/→/Home/End • Del/Backspace"),
-        Line::from("README: Up/Down/PgUp/PgDn scroll • h TOC • Tab switch TOC/Content • Enter jump"),
-        Line::from("Build: g toggle Project/Global • Enter write"),
-        Line::from("Welcome: Up/Down + Enter to open a section"),
-        Line::from("—").style(Style::default().fg(app.theme.frame)),
-        Line::from("This is a scaffold. Pages will be implemented in tasks 003–009."),
-    ];
-    let block = Block::default().title("Help").borders(Borders::ALL).border_style(Style::default().fg(app.theme.frame));
-    let content = Paragraph::new(lines).style(Style::default().bg(app.theme.bg).fg(app.theme.fg)).alignment(Alignment::Left).wrap(Wrap { trim: true }).block(block);
-    f.render_widget(Clear, area);
-    f.render_widget(content, area);
+fn run_save_hook(app: &mut App, path: &str) {
+    // The catalog says which model is current
+    discovery_cache::forget(discovery_cache::MODELS_LIST);
+    if let Err(e) = envfile::sync_on_save() {
+        app.toast = Some(Toast::new(StatusKind::Warn, format!(".env sync failed: {}", e)));
+    }
+    // Off the UI thread: `tick` reports a failure
+    app.hooks.start(path);
 }
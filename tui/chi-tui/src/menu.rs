@@ -0,0 +1,234 @@
+use crate::app::{App, Page};
+use crate::theme::StatusKind;
+
+#[derive(Copy, Clone, Debug, PartialEq, Eq)]
+pub enum MenuAction {
+    Open(Page),
+    Quit,
+}
+
+/// One registered page or action. The Welcome menu, global shortcuts, footer
+/// and help overlay are all generated from `REGISTRY`.
+#[derive(Copy, Clone, Debug)]
+pub struct MenuSpec {
+    pub action: MenuAction,
+    pub label: &'static str,
+    /// Shown under the item in comfortable density
+    pub help: &'static str,
+    /// Global shortcut (case-insensitive)
+    pub key: Option<char>,
+}
+
+const fn page(page: Page, label: &'static str, help: &'static str, key: Option<char>) -> MenuSpec {
+    MenuSpec { action: MenuAction::Open(page), label, help, key }
+}
+
+pub const REGISTRY: &[MenuSpec] = &[
+    page(Page::Readme, "README", "Project docs with a table of contents", Some('1')),
+    page(Page::Configure, "Configure Providers", "Add, edit, test and share providers", Some('2')),
+    page(Page::SelectDefault, "Select Default", "Pick the provider chi_llm uses by default", Some('3')),
+    page(Page::Diagnostics, "Diagnostics", "Environment and config checks", Some('4')),
+    page(Page::Build, "Build Configuration", "Write the project or global config file", Some('b')),
+    page(Page::Settings, "Settings", "Theme, palette, density and privacy preferences", Some('s')),
+    page(Page::ModelBrowser, "Model Browser", "Browse and download local models", None),
+    page(Page::Audit, "Audit Log", "History of config writes", None),
+    page(Page::Backups, "Backups", "Snapshots and provider restore", None),
+    page(Page::Latency, "Latency Map", "Measure provider response times", None),
+    page(Page::Status, "Provider Status", "Live reachability, latency and model count of every provider", None),
+    page(Page::Playground, "Playground", "Try prompts against a provider", None),
+    page(Page::Cache, "Model Cache", "Disk usage of downloaded models", None),
+    page(Page::Server, "API Server", "Run an OpenAI-compatible local endpoint", None),
+    page(Page::Variables, "Variables", "Shared values for {{ name }} in provider fields", None),
+    page(Page::Benchmark, "Benchmark", "Compare tokens/sec and latency of models", None),
+    page(Page::Recommend, "Recommend for this machine", "Catalog models ranked by how they fit in RAM and VRAM", None),
+    page(Page::Logs, "Logs", "Tail the chi-llm and chi-tui logs, filtered by level", None),
+    MenuSpec { action: MenuAction::Quit, label: "EXIT", help: "Quit chi-tui", key: Some('q') },
+];
+
+/// Page opened by a global shortcut key.
+pub fn shortcut(c: char) -> Option<Page> {
+    let c = c.to_ascii_lowercase();
+    REGISTRY.iter().find(|s| s.key == Some(c)).and_then(|s| match s.action {
+        MenuAction::Open(p) => Some(p),
+        MenuAction::Quit => None,
+    })
+}
+
+/// Menu label of a page; the menu itself is "Welcome".
+pub fn label(page: Page) -> &'static str {
+    REGISTRY.iter().find(|s| s.action == MenuAction::Open(page)).map_or("Welcome", |s| s.label)
+}
+
+/// "1: README • 2: Configure Providers • ..." for the help overlay.
+pub fn shortcuts_help() -> String {
+    REGISTRY
+        .iter()
+        .filter_map(|s| match (s.key, s.action) {
+            (Some(k), MenuAction::Open(_)) => Some(format!("{}: {}", k, s.label)),
+            _ => None,
+        })
+        .collect::<Vec<_>>()
+        .join(" • ")
+}
+
+/// "1/2/3/4/b/s" for the footer.
+pub fn shortcut_keys() -> String {
+    REGISTRY
+        .iter()
+        .filter_map(|s| match (s.key, s.action) {
+            (Some(k), MenuAction::Open(_)) => Some(k.to_string()),
+            _ => None,
+        })
+        .collect::<Vec<_>>()
+        .join("/")
+}
+
+/// The pages with a shortcut, for the narrow layout's page bar: (key,
+/// first word of the label, page).
+pub fn top_bar() -> Vec<(char, &'static str, Page)> {
+    REGISTRY
+        .iter()
+        .filter_map(|s| match (s.key, s.action) {
+            (Some(k), MenuAction::Open(p)) => Some((k, s.label.split(' ').next().unwrap_or(s.label), p)),
+            _ => None,
+        })
+        .collect()
+}
+
+/// A rendered menu row: the registry entry plus live state.
+#[derive(Clone, Debug)]
+pub struct MenuItem {
+    pub spec: MenuSpec,
+    pub badge: Option<(StatusKind, String)>,
+    /// Background work for the page, with a spinner frame
+    pub busy: Option<String>,
+}
+
+/// The app state the menu depends on. Items are rebuilt only when this
+/// changes, so adding a badge means adding its input here.
+#[derive(Clone, Debug, Default, PartialEq, Eq)]
+pub struct MenuInputs {
+    providers: Option<usize>,
+    downloads: usize,
+    server: Option<(u16, bool)>,
+    error: bool,
+    /// Spinner frame of the model list prefetch
+    prefetch: Option<char>,
+}
+
+pub fn inputs(app: &App) -> MenuInputs {
+    MenuInputs {
+        providers: app.providers.as_ref().map(|p| p.entries.len()),
+        downloads: app.downloads.active_count(),
+        server: app.api_server.running().then_some((app.api_server.port, app.api_server.ready)),
+        error: app.last_error.is_some(),
+        prefetch: app.prefetch.spinner(),
+    }
+}
+
+fn badge(spec: &MenuSpec, inp: &MenuInputs) -> Option<(StatusKind, String)> {
+    match spec.action {
+        MenuAction::Open(Page::Configure) => inp.providers.map(|n| (StatusKind::Ok, format!("{} configured", n))),
+        MenuAction::Open(Page::ModelBrowser) if inp.downloads > 0 => Some((StatusKind::Warn, format!("{} downloading", inp.downloads))),
+        MenuAction::Open(Page::Server) => inp.server.map(|(port, ready)| {
+            if ready { (StatusKind::Ok, format!("running :{}", port)) } else { (StatusKind::Warn, format!("starting :{}", port)) }
+        }),
+        MenuAction::Open(Page::Diagnostics) if inp.error => Some((StatusKind::Err, "last action failed".to_string())),
+        _ => None,
+    }
+}
+
+fn busy(spec: &MenuSpec, inp: &MenuInputs) -> Option<String> {
+    match spec.action {
+        MenuAction::Open(Page::ModelBrowser) => inp.prefetch.map(|frame| format!("{} fetching model lists", frame)),
+        _ => None,
+    }
+}
+
+/// Welcome menu items, cached against `MenuInputs`.
+#[derive(Default)]
+pub struct MenuCache {
+    inputs: Option<MenuInputs>,
+    pub items: Vec<MenuItem>,
+}
+
+impl MenuCache {
+    /// Drop the cached items; the next `refresh` rebuilds them.
+    pub fn invalidate(&mut self) {
+        self.inputs = None;
+    }
+
+    /// Rebuild when the inputs changed. Returns true when the items changed
+    /// and the screen should redraw.
+    pub fn refresh(&mut self, inputs: MenuInputs) -> bool {
+        if self.inputs.as_ref() == Some(&inputs) {
+            return false;
+        }
+        self.items = REGISTRY.iter().map(|spec| MenuItem { spec: *spec, badge: badge(spec, &inputs), busy: busy(spec, &inputs) }).collect();
+        self.inputs = Some(inputs);
+        true
+    }
+
+    pub fn len(&self) -> usize {
+        self.items.len()
+    }
+
+    pub fn action(&self, idx: usize) -> Option<MenuAction> {
+        self.items.get(idx).map(|i| i.spec.action)
+    }
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+
+    #[test]
+    fn shortcuts_are_unique_and_case_insensitive() {
+        let keys: Vec<char> = REGISTRY.iter().filter_map(|s| s.key).collect();
+        assert!(keys.iter().enumerate().all(|(i, k)| !keys[..i].contains(k)), "{:?}", keys);
+        assert_eq!(shortcut('2'), Some(Page::Configure));
+        assert_eq!(shortcut('S'), Some(Page::Settings));
+        assert_eq!(shortcut('q'), None, "quit is not a page");
+        assert_eq!(shortcut('z'), None);
+    }
+
+    #[test]
+    fn help_footer_and_page_bar_come_from_the_registry() {
+        assert_eq!(label(Page::Cache), "Model Cache");
+        assert_eq!(label(Page::Welcome), "Welcome");
+        assert_eq!(shortcut_keys(), "1/2/3/4/b/s");
+        assert!(shortcuts_help().starts_with("1: README • 2: Configure Providers • "));
+        assert!(!shortcuts_help().contains("EXIT"));
+        let bar = top_bar();
+        assert_eq!(bar[1], ('2', "Configure", Page::Configure));
+        assert_eq!(bar.len(), 6);
+    }
+
+    #[test]
+    fn items_are_rebuilt_only_when_the_inputs_change() {
+        let mut cache = MenuCache::default();
+        assert!(cache.refresh(MenuInputs::default()));
+        assert_eq!(cache.len(), REGISTRY.len());
+        assert_eq!(cache.action(cache.len() - 1), Some(MenuAction::Quit));
+        assert_eq!(cache.action(cache.len()), None);
+        assert!(cache.items.iter().all(|i| i.badge.is_none() && i.busy.is_none()));
+        assert!(!cache.refresh(MenuInputs::default()));
+
+        let inputs = MenuInputs { providers: Some(3), downloads: 1, server: Some((8000, false)), error: true, prefetch: Some('⠋') };
+        assert!(cache.refresh(inputs.clone()));
+        assert_eq!(item(&cache, Page::Configure).badge, Some((StatusKind::Ok, "3 configured".to_string())));
+        assert_eq!(item(&cache, Page::ModelBrowser).badge, Some((StatusKind::Warn, "1 downloading".to_string())));
+        assert_eq!(item(&cache, Page::ModelBrowser).busy, Some("⠋ fetching model lists".to_string()));
+        assert_eq!(item(&cache, Page::Server).badge, Some((StatusKind::Warn, "starting :8000".to_string())));
+        assert_eq!(item(&cache, Page::Diagnostics).badge, Some((StatusKind::Err, "last action failed".to_string())));
+        assert!(!cache.refresh(inputs.clone()));
+        cache.invalidate();
+        assert!(cache.refresh(inputs), "invalidate forces a rebuild");
+        assert!(cache.refresh(MenuInputs { server: Some((8000, true)), ..MenuInputs::default() }));
+        assert_eq!(item(&cache, Page::Server).badge, Some((StatusKind::Ok, "running :8000".to_string())));
+    }
+
+    fn item(cache: &MenuCache, page: Page) -> MenuItem {
+        cache.items.iter().find(|i| i.spec.action == MenuAction::Open(page)).expect("item").clone()
+    }
+}
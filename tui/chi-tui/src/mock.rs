@@ -0,0 +1,293 @@
+//! `chi-tui --mock`: a deterministic world for development, screenshots and
+//! integration tests without chi-llm or any server. It runs in a throwaway
+//! project (also HOME) seeded with a fixed provider list, answers the CLI
+//! calls from canned JSON, and stands in for the network: TCP checks and
+//! provider HTTP APIs get simulated latencies and canned model lists.
+//! Hosts ending in `.invalid` are down. Model downloads still need the
+//! network.
+//!
+//! `CHI_TUI_MOCK=on` is the switch; `--mock` sets it up and turns it on.
+
+use std::io::ErrorKind;
+use std::path::PathBuf;
+use std::time::Duration;
+
+use anyhow::{anyhow, Result};
+use serde_json::{json, Value};
+
+use crate::http::{Request, Response, Transport};
+use crate::store;
+use crate::util::fnv1a;
+
+pub const VERSION: &str = "chi-llm 2.1.0 (mock)";
+
+pub fn enabled() -> bool {
+    std::env::var("CHI_TUI_MOCK").map_or(false, |v| matches!(v.trim().to_lowercase().as_str(), "on" | "1" | "true"))
+}
+
+/// The providers every mock session starts with: one of each kind, the
+/// default on Ollama, one server down.
+pub fn providers() -> Value {
+    json!({
+        "providers": [
+            {"id": "local", "name": "Built-in llama.cpp", "type": "local", "tags": ["offline"], "config": {"model": "qwen3-1.7b"}},
+            {"id": "home-ollama", "name": "Ollama (this machine)", "type": "ollama", "tags": ["local"],
+             "config": {"host": "127.0.0.1", "port": 11434, "model": "qwen2.5-coder:7b"}},
+            {"id": "studio", "name": "LM Studio", "type": "lmstudio", "tags": ["local"],
+             "config": {"host": "127.0.0.1", "port": 1234, "model": "qwen2.5-7b-instruct"}},
+            {"id": "openai", "name": "OpenAI", "type": "openai", "tags": ["cloud"],
+             "config": {"api_key": "mock-not-a-real-key", "model": "gpt-4o-mini"}},
+            {"id": "lab-vllm", "name": "Lab vLLM", "type": "openai-compatible", "tags": ["lan"],
+             "config": {"base_url": "http://gpu-box.invalid:8000/v1", "model": "meta-llama/Llama-3.1-8B-Instruct"}},
+        ],
+        "default_provider_id": "home-ollama",
+    })
+}
+
+/// Enter a fresh mock project: HOME, config and cache point into it, so
+/// nothing outside is read or written, then turn mock mode on.
+pub fn start() -> Result<PathBuf> {
+    let root = std::env::temp_dir().join(format!("chi-tui-mock-{}", std::process::id()));
+    let _ = std::fs::remove_dir_all(&root);
+    std::fs::create_dir_all(&root)?;
+    std::env::set_var("HOME", &root);
+    std::env::set_var("XDG_CONFIG_HOME", root.join(".config"));
+    std::env::set_var("XDG_CACHE_HOME", root.join(".cache"));
+    std::env::set_var("CHI_TUI_SECRETS", "file");
+    std::env::set_var("CHI_TUI_MOCK", "on");
+    for var in ["CHI_TUI_POST_SAVE_HOOK", "CHI_LLM_LOG_FILE", "HF_TOKEN", "HUGGING_FACE_HUB_TOKEN"] {
+        std::env::remove_var(var);
+    }
+    std::env::set_current_dir(&root)?;
+    store::write(&providers())?;
+    crate::log::info(&format!("mock mode in {}", root.display()));
+    Ok(root)
+}
+
+/// Simulated round trip to `host:port`: stable per endpoint, longer for
+/// hosts that are not this machine.
+pub fn latency(host: &str, port: u16) -> Duration {
+    let local = matches!(host, "127.0.0.1" | "localhost" | "::1");
+    let jitter = fnv1a(&format!("{}:{}", host, port)) % 40;
+    Duration::from_millis(if local { 2 + jitter / 8 } else { 35 + jitter * 3 })
+}
+
+fn down(host: &str) -> bool {
+    host.ends_with(".invalid")
+}
+
+fn refused(host: &str, port: u16) -> anyhow::Error {
+    std::io::Error::new(ErrorKind::ConnectionRefused, format!("connection refused by {}:{} (mock)", host, port)).into()
+}
+
+/// `health::tcp_rtt` in mock mode.
+pub fn tcp_rtt(host: &str, port: u16) -> Result<Duration> {
+    if down(host) { return Err(refused(host, port)); }
+    let d = latency(host, port);
+    std::thread::sleep(d);
+    Ok(d)
+}
+
+/// `providers schema --json`: the types the mock providers use.
+fn schema() -> Value {
+    let conn = |port: u16| json!([
+        {"name": "host", "type": "string", "default": "localhost"},
+        {"name": "port", "type": "int", "default": port, "min": 1, "max": 65535},
+        {"name": "model", "type": "string"},
+        {"name": "timeout", "type": "float", "default": 30, "advanced": true},
+    ]);
+    json!({"providers": [
+        {"type": "local", "privacy": "local", "fields": [
+            {"name": "model", "type": "string"},
+            {"name": "context_window", "type": "int", "advanced": true},
+        ]},
+        {"type": "ollama", "privacy": "local", "fields": conn(11434)},
+        {"type": "lmstudio", "privacy": "local", "fields": conn(1234)},
+        {"type": "openai", "privacy": "cloud-paid", "fields": [
+            {"name": "api_key", "type": "secret", "required": true},
+            {"name": "base_url", "type": "string"},
+            {"name": "model", "type": "string"},
+        ]},
+        {"type": "openai-compatible", "privacy": "cloud-paid", "fields": [
+            {"name": "base_url", "type": "string", "required": true},
+            {"name": "api_key", "type": "secret"},
+            {"name": "model", "type": "string", "required": true},
+        ]},
+        {"type": "anthropic", "privacy": "cloud-paid", "fields": [
+            {"name": "api_key", "type": "secret", "required": true},
+            {"name": "model", "type": "string", "required": true},
+        ]},
+    ]})
+}
+
+/// `models list --json`: the catalog, one model downloaded and current.
+fn catalog() -> Value {
+    json!([
+        {"id": "qwen3-1.7b", "name": "Qwen3 1.7B", "size": "1.7B", "file_size_mb": 1100, "context_window": 32768,
+         "tags": ["small", "chat"], "downloaded": true, "current": true, "recommended_ram_gb": 4,
+         "repo": "Qwen/Qwen3-1.7B-GGUF", "filename": "qwen3-1.7b-q4_k_m.gguf"},
+        {"id": "qwen3-4b", "name": "Qwen3 4B", "size": "4B", "file_size_mb": 2500, "context_window": 32768,
+         "tags": ["chat", "reasoning"], "downloaded": false, "current": false, "recommended_ram_gb": 8,
+         "repo": "Qwen/Qwen3-4B-GGUF", "filename": "qwen3-4b-q4_k_m.gguf"},
+        {"id": "qwen2.5-coder-7b", "name": "Qwen2.5 Coder 7B", "size": "7B", "file_size_mb": 4700, "context_window": 32768,
+         "tags": ["coding"], "downloaded": false, "current": false, "recommended_ram_gb": 12,
+         "repo": "Qwen/Qwen2.5-Coder-7B-Instruct-GGUF", "filename": "qwen2.5-coder-7b-instruct-q4_k_m.gguf"},
+        {"id": "phi3-mini", "name": "Phi-3 Mini", "size": "3.8B", "file_size_mb": 2300, "context_window": 4096,
+         "tags": ["small", "coding"], "downloaded": false, "current": false, "recommended_ram_gb": 6,
+         "repo": "microsoft/Phi-3-mini-4k-instruct-gguf", "filename": "Phi-3-mini-4k-instruct-q4.gguf"},
+    ])
+}
+
+/// Model ids each kind of server lists.
+fn served_models(ptype: &str) -> &'static [&'static str] {
+    match ptype {
+        "ollama" => &["qwen2.5-coder:7b", "llama3.2:3b", "nomic-embed-text:latest"],
+        "lmstudio" => &["qwen2.5-7b-instruct", "mistral-7b-instruct-v0.3", "text-embedding-nomic-embed-text-v1.5"],
+        "openai" => &["gpt-4o-mini", "gpt-4o", "o3-mini"],
+        "anthropic" => &["claude-3-5-haiku-latest", "claude-3-5-sonnet-latest"],
+        _ => &["meta-llama/Llama-3.1-8B-Instruct"],
+    }
+}
+
+/// (host, port, path) of a URL.
+fn split_url(url: &str) -> (String, u16, String) {
+    let (scheme, rest) = url.split_once("://").unwrap_or(("http", url));
+    let (authority, path) = rest.find('/').map_or((rest, "/"), |i| (&rest[..i], &rest[i..]));
+    let default_port = if scheme == "https" { 443 } else { 80 };
+    let (host, port) = match authority.rsplit_once(':') {
+        Some((h, p)) => (h, p.parse().unwrap_or(default_port)),
+        None => (authority, default_port),
+    };
+    let path = path.split('?').next().unwrap_or("/");
+    (host.to_string(), port, path.to_string())
+}
+
+fn arg<'a>(args: &[&'a str], flag: &str) -> Option<&'a str> {
+    args.iter().position(|a| *a == flag).and_then(|i| args.get(i + 1)).copied()
+}
+
+/// `providers discover-models`: the type's models after the simulated
+/// round trip, or the error chi-llm reports for a server that is down.
+fn discover(args: &[&str]) -> Result<Value> {
+    let ptype = arg(args, "--type").unwrap_or("openai");
+    let (host, port) = match arg(args, "--base-url") {
+        Some(url) => { let (h, p, _) = split_url(url); (h, p) }
+        None => match arg(args, "--host") {
+            Some(h) => (h.to_string(), arg(args, "--port").and_then(|p| p.parse().ok()).unwrap_or(80)),
+            None => (if ptype == "anthropic" { "api.anthropic.com" } else { "api.openai.com" }.to_string(), 443),
+        },
+    };
+    if down(&host) {
+        return match ptype {
+            "ollama" | "lmstudio" => Err(anyhow!("chi-llm {:?} failed: {}", args, refused(&host, port))),
+            _ => Ok(json!({"models": [], "error": refused(&host, port).to_string()})),
+        };
+    }
+    std::thread::sleep(latency(&host, port) * 2);
+    let models: Vec<Value> = served_models(ptype).iter().map(|id| json!({"id": id})).collect();
+    Ok(json!({"models": models}))
+}
+
+/// `run_cli_json` in mock mode.
+pub fn cli(args: &[&str]) -> Result<Value> {
+    match args {
+        ["providers", "schema", ..] => Ok(schema()),
+        ["providers", "discover-models", ..] => discover(args),
+        ["models", "list", ..] => Ok(catalog()),
+        ["models", "current", ..] => Ok(json!({
+            "config_source": "mock", "current_model": "qwen3-1.7b",
+            "recommended_model": "qwen3-4b", "available_ram_gb": 16.0,
+        })),
+        ["diagnostics", ..] => Ok(json!({
+            "python": {"version": "3.11.9", "executable": "/usr/bin/python3"},
+            "chi_llm": {"version": "2.1.0"},
+        })),
+        _ => Err(anyhow!("chi-llm {:?} failed: not available in --mock mode", args)),
+    }
+}
+
+/// Provider HTTP APIs: Ollama, LM Studio and OpenAI-style model lists.
+pub struct MockTransport;
+
+impl Transport for MockTransport {
+    fn round_trip(&self, req: &Request) -> Result<Response> {
+        let (host, port, path) = split_url(&req.url);
+        if down(&host) { return Err(refused(&host, port)); }
+        std::thread::sleep(latency(&host, port) * 2);
+        let ptype = match (host.as_str(), port) {
+            ("api.openai.com", _) => "openai",
+            ("api.anthropic.com", _) => "anthropic",
+            (_, 11434) => "ollama",
+            (_, 1234) => "lmstudio",
+            _ => "openai-compatible",
+        };
+        let models = served_models(ptype);
+        let body = match (req.method.as_str(), path.as_str()) {
+            ("GET", "/api/tags") => json!({"models": models.iter().enumerate().map(|(i, m)| json!({
+                "name": m, "model": m, "size": [4_683_087_332u64, 2_019_393_189, 274_302_450][i % 3],
+            })).collect::<Vec<_>>()}),
+            ("POST", "/api/show") => json!({
+                "details": {"parameter_size": "7.6B", "quantization_level": "Q4_K_M", "family": "qwen2"},
+                "model_info": {"qwen2.context_length": 32768},
+                "template": "{{ .Prompt }}",
+            }),
+            ("GET", "/api/version") => json!({"version": "0.5.7"}),
+            ("GET", "/api/v0/models") => json!({"data": models.iter().enumerate().map(|(i, m)| json!({
+                "id": m, "type": if m.contains("embed") { "embeddings" } else { "llm" },
+                "state": if i == 0 { "loaded" } else { "not-loaded" },
+                "quantization": "Q4_K_M", "max_context_length": 32768,
+            })).collect::<Vec<_>>()}),
+            ("GET", "/v1/models") | ("GET", "/models") => json!({"data": models.iter().map(|m| json!({"id": m})).collect::<Vec<_>>()}),
+            _ => return Ok(Response { status: 404, headers: Vec::new(), body: json!({"error": "not found (mock)"}).to_string() }),
+        };
+        Ok(Response { status: 200, headers: vec![("content-type".to_string(), "application/json".to_string())], body: body.to_string() })
+    }
+}
+
+#[cfg(all(test, unix))]
+mod tests {
+    use super::*;
+    use crate::doctor;
+    use crate::models::fetch_models;
+    use crate::providers::{read_scratch_entries, test_connection};
+    use crate::testing::FakeCli;
+    use crate::util::{ensure_chi_llm, run_cli_json};
+
+    #[test]
+    fn mock_mode_runs_without_chi_llm_or_servers() {
+        let mut fake = FakeCli::new();
+        fake.uninstall();
+        fake.set_env("CHI_TUI_MOCK", String::new());
+        fake.set_env("CHI_LLM_LOG_FILE", String::new());
+        let root = start().expect("start mock mode");
+        assert!(enabled());
+        assert_eq!(std::env::current_dir().expect("cwd"), root);
+        ensure_chi_llm().expect("no chi-llm needed");
+
+        let entries = read_scratch_entries().expect("providers");
+        let ids: Vec<&str> = entries.iter().map(|e| e.id.as_str()).collect();
+        assert_eq!(ids, ["local", "home-ollama", "studio", "openai", "lab-vllm"]);
+        assert_eq!(fetch_models(Duration::from_secs(5)).expect("catalog").len(), 4);
+        let ollama = &entries[1];
+        let installed: Vec<String> = crate::ollama::installed(ollama, Duration::from_secs(5)).expect("tags").into_iter().map(|(n, _)| n).collect();
+        assert_eq!(installed, ["qwen2.5-coder:7b", "llama3.2:3b", "nomic-embed-text:latest"]);
+        assert_eq!(test_connection(&entries[3]).expect("openai").message, "openai: 3 models");
+
+        // Same simulated latency every run; .invalid hosts are down
+        let report = doctor::collect(Duration::from_secs(5));
+        assert!(report.ok(), "{}", report.render_text());
+        let row = |id: &str| report.providers.iter().find(|r| r.id == id).expect("row");
+        assert_eq!(row("home-ollama").models, Some(3));
+        assert_eq!(row("home-ollama").latency, Some(latency("127.0.0.1", 11434)));
+        assert_eq!(row("lab-vllm").reachable, Some(false));
+        assert!(row("lab-vllm").detail.contains("gpu-box.invalid:8000"), "{}", row("lab-vllm").detail);
+        let summary = &report.diagnostics.as_ref().expect("diagnostics").summary;
+        assert!(summary.iter().any(|l| l.starts_with("chi-llm: chi-llm 2.1.0 (mock)")), "{:?}", summary);
+        assert!(!summary.iter().any(|l| l.starts_with("Warning")), "{:?}", summary);
+        assert!(run_cli_json(&["models", "download", "x"], Duration::from_secs(1)).is_err());
+        assert!(!fake.exists("chi.tmp.json"));
+
+        let _ = std::env::set_current_dir(&fake.root);
+        let _ = std::fs::remove_dir_all(root);
+    }
+}
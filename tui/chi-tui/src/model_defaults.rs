@@ -0,0 +1,181 @@
+//! Default model per provider type (Settings, `m`), kept in the global
+//! store under `default_models` so new providers in every project start
+//! from it: an empty `model` field in the Configure form and `chi-tui
+//! config add` without a model are pre-filled with it.
+
+use std::collections::BTreeMap;
+
+use anyhow::Result;
+use ratatui::layout::Rect;
+use ratatui::prelude::Frame;
+use ratatui::style::{Modifier, Style};
+use ratatui::text::{Line, Span};
+use ratatui::widgets::{Block, Borders, Clear, Paragraph, Wrap};
+use serde_json::Value;
+
+use crate::app::App;
+use crate::audit;
+use crate::providers::ProvidersState;
+use crate::store;
+use crate::text;
+use crate::util::overlay_rect;
+
+pub const KEY: &str = "default_models";
+
+/// Type → model, as saved; unreadable stores count as empty.
+pub fn load() -> BTreeMap<String, String> {
+    let root = store::read_global().unwrap_or_default();
+    let Some(obj) = root.get(KEY).and_then(|v| v.as_object()) else { return BTreeMap::new() };
+    obj.iter()
+        .filter_map(|(k, v)| v.as_str().map(str::trim).filter(|m| !m.is_empty()).map(|m| (k.clone(), m.to_string())))
+        .collect()
+}
+
+pub fn for_type(ptype: &str) -> Option<String> {
+    load().remove(ptype)
+}
+
+/// Set the default model for `ptype`; an empty model removes it. Returns
+/// the global store path.
+pub fn set(ptype: &str, model: &str) -> Result<String> {
+    let mut root = store::read_global()?;
+    let before = root.clone();
+    if let Some(obj) = root.as_object_mut() {
+        let map = obj.entry(KEY.to_string()).or_insert_with(|| Value::Object(Default::default()));
+        if !map.is_object() { *map = Value::Object(Default::default()); }
+        if let Some(m) = map.as_object_mut() {
+            match model.trim() {
+                "" => { m.remove(ptype); }
+                model => { m.insert(ptype.to_string(), Value::String(model.to_string())); }
+            }
+            if m.is_empty() { obj.remove(KEY); }
+        }
+    }
+    let path = store::write_global(&root)?;
+    let _ = audit::record("settings.default_models", &path, &before, &root);
+    Ok(path)
+}
+
+/// Types whose schema has a `model` field, plus any with a saved default.
+pub fn model_types(st: &ProvidersState) -> Vec<String> {
+    let mut types: Vec<String> = st
+        .schema_types
+        .iter()
+        .filter(|t| st.schema_map.get(*t).map_or(false, |fs| fs.iter().any(|f| f.name == "model")))
+        .cloned()
+        .collect();
+    for t in load().into_keys() {
+        if !types.contains(&t) { types.push(t); }
+    }
+    types.sort();
+    types
+}
+
+/// Settings `m`: one row per provider type, Enter edits its default.
+#[derive(Clone, Debug, Default)]
+pub struct DefaultModels {
+    pub types: Vec<String>,
+    pub models: BTreeMap<String, String>,
+    pub selected: usize,
+    /// Model being typed for the selected type
+    pub input: Option<String>,
+    pub status: Option<String>,
+}
+
+impl DefaultModels {
+    pub fn new(types: Vec<String>) -> Self {
+        DefaultModels { types, models: load(), ..Default::default() }
+    }
+
+    pub fn current(&self) -> Option<&str> {
+        self.types.get(self.selected).map(String::as_str)
+    }
+
+    /// Save `model` for the selected type and re-read the saved set.
+    pub fn save(&mut self, model: &str) -> Option<String> {
+        let ptype = self.current()?.to_string();
+        let result = set(&ptype, model);
+        self.models = load();
+        match result {
+            Ok(path) => {
+                self.status = Some(match self.models.get(&ptype) {
+                    Some(m) => format!("New {} providers start with {}", ptype, m),
+                    None => format!("{}: default model cleared", ptype),
+                });
+                Some(path)
+            }
+            Err(e) => {
+                self.status = Some(format!("Error: {}", e));
+                None
+            }
+        }
+    }
+}
+
+pub fn draw_default_models(f: &mut Frame, area: Rect, app: &App) {
+    let Some(dm) = &app.default_models else { return };
+    let pop = overlay_rect(app.compact, 60, 60, area);
+    let type_w = dm.types.iter().map(|t| text::width(t)).max().unwrap_or(0).clamp(8, 20);
+    let mut lines: Vec<Line> = Vec::new();
+    if dm.types.is_empty() {
+        lines.push(Line::from("No provider types with a model field (is chi-llm installed?)."));
+    }
+    for (i, t) in dm.types.iter().enumerate() {
+        let sel = i == dm.selected;
+        let style = if sel { Style::default().fg(app.theme.selected).add_modifier(Modifier::BOLD) } else { Style::default().fg(app.theme.fg) };
+        let model = match (&dm.input, dm.models.get(t)) {
+            (Some(buf), _) if sel => Span::styled(format!("{}▏", buf), Style::default().fg(app.theme.accent)),
+            (_, Some(m)) => Span::styled(m.clone(), Style::default().fg(app.theme.fg)),
+            (_, None) => Span::styled("(provider default)", Style::default().fg(app.theme.secondary)),
+        };
+        lines.push(Line::from(vec![Span::styled(format!("{} {}  ", if sel { '›' } else { ' ' }, text::fit(t, type_w)), style), model]));
+    }
+    lines.push(Line::from(""));
+    if let Some(s) = &dm.status {
+        let (text, style) = app.theme.status_text(s);
+        lines.push(Line::from(Span::styled(text, style)));
+    }
+    let p = Paragraph::new(lines)
+        .style(Style::default().bg(app.theme.bg).fg(app.theme.fg))
+        .block(Block::default().borders(Borders::ALL).border_style(Style::default().fg(app.theme.accent)).title("Default model per provider type (all projects)"))
+        .wrap(Wrap { trim: false });
+    f.render_widget(Clear, pop);
+    f.render_widget(p, pop);
+}
+
+#[cfg(all(test, unix))]
+mod tests {
+    use super::*;
+    use crate::config_cli::run_config;
+    use crate::providers::load_providers_state;
+    use crate::testing::{add_provider, FakeCli};
+
+    #[test]
+    fn new_providers_start_from_the_type_default_model() {
+        let fake = FakeCli::new();
+        let st = load_providers_state().expect("state");
+        let mut dm = DefaultModels::new(model_types(&st));
+        assert_eq!(dm.types, vec!["local", "ollama", "openai"]);
+        dm.selected = 2;
+        assert!(dm.save(" gpt-4.1-mini ").is_some());
+        assert_eq!(store::read_global().expect("global")["default_models"]["openai"], "gpt-4.1-mini");
+
+        // Headless add without a model, and with one
+        run_config(add_provider("openai", "oa", &[("api_key", "sk-test")], false)).expect("add provider");
+        run_config(add_provider("openai", "oa2", &[("api_key", "sk-test"), ("model", "o4-mini")], false)).expect("add provider");
+        assert_eq!(fake.store()["providers"][0]["config"]["model"], "gpt-4.1-mini");
+        assert_eq!(fake.store()["providers"][1]["config"]["model"], "o4-mini");
+
+        // The Configure form pre-fills an empty model field
+        let mut st = load_providers_state().expect("state");
+        st.selected = 0;
+        st.entries[0].config.as_object_mut().expect("config").remove("model");
+        crate::keys::ensure_form_for_selected(&mut st);
+        let form = st.form.as_ref().expect("form");
+        assert_eq!(form.fields.iter().find(|f| f.schema.name == "model").map(|f| f.buffer.as_str()), Some("gpt-4.1-mini"));
+
+        dm.save("");
+        assert!(load().is_empty());
+        assert!(store::read_global().expect("global").get("default_models").is_none());
+    }
+}
@@ -0,0 +1,132 @@
+//! One model under many names: "qwen2.5-coder:7b" on Ollama,
+//! "lmstudio-community/Qwen2.5-Coder-7B-Instruct-GGUF" in LM Studio and
+//! "qwen2.5-coder-7b" in the local catalog. `canonical` reduces a name to a
+//! comparable key; `model_aliases` in the provider store covers the names
+//! it cannot (`{"my-coder": "qwen2.5-coder:7b"}`).
+
+use std::collections::HashMap;
+
+use serde_json::Value;
+
+use crate::store;
+
+/// Name parts that describe packaging, not the model.
+const NOISE: [&str; 12] = ["instruct", "chat", "it", "gguf", "ggml", "latest", "hf", "mlx", "awq", "gptq", "exl2", "gpt4all"];
+
+/// Quantization and precision tags: q4_k_m, iq3_xs, f16, bf16, int8, 4bit…
+fn is_quant(t: &str) -> bool {
+    let b = t.as_bytes();
+    let q = |i: usize| b.len() > i + 1 && b[i + 1].is_ascii_digit();
+    (b.first() == Some(&b'q') && q(0))
+        || (t.starts_with("iq") && q(1))
+        || matches!(t, "f16" | "f32" | "fp16" | "fp8" | "bf16" | "int4" | "int8")
+        || (t.ends_with("bit") && t[..t.len() - 3].chars().all(|c| c.is_ascii_digit()) && t.len() > 3)
+}
+
+/// Comparable key: publisher, file extension, tags like `instruct`/`gguf`
+/// and quantization dropped, separators and case ignored
+/// ("Llama-3.2-3B-Instruct" and "llama3.2:3b" both give "llama323b").
+pub fn canonical(name: &str) -> String {
+    let lower = name.trim().to_lowercase();
+    let base = lower.rsplit('/').next().unwrap_or(&lower);
+    let base = base.strip_suffix(".gguf").unwrap_or(base);
+    let key: String = base
+        .split(|c: char| matches!(c, '-' | ':' | ' ' | '@'))
+        .filter(|t| !t.is_empty() && !NOISE.contains(t) && !is_quant(t))
+        .flat_map(|t| t.chars().filter(|c| c.is_alphanumeric()))
+        .collect();
+    if key.is_empty() { lower } else { key }
+}
+
+/// `model_aliases` from the store, keyed and valued by canonical names.
+#[derive(Clone, Debug, Default)]
+pub struct Aliases(HashMap<String, String>);
+
+impl Aliases {
+    pub fn load() -> Self {
+        Self::from_store(&store::read_or_empty())
+    }
+
+    pub fn from_store(root: &Value) -> Self {
+        let map = root.get("model_aliases").and_then(|v| v.as_object()).map(|m| {
+            m.iter().filter_map(|(k, v)| v.as_str().map(|v| (canonical(k), canonical(v)))).collect()
+        });
+        Aliases(map.unwrap_or_default())
+    }
+
+    /// Key two names share when they are the same model.
+    pub fn key(&self, name: &str) -> String {
+        let c = canonical(name);
+        self.0.get(&c).cloned().unwrap_or(c)
+    }
+
+    pub fn same(&self, a: &str, b: &str) -> bool {
+        self.key(a) == self.key(b)
+    }
+
+    /// `name` itself when listed, else the first candidate that is the same model.
+    pub fn equivalent<'a>(&self, name: &str, candidates: &'a [String]) -> Option<&'a String> {
+        if name.trim().is_empty() { return None; }
+        candidates.iter().find(|c| *c == name).or_else(|| {
+            let k = self.key(name);
+            candidates.iter().find(|c| self.key(c) == k)
+        })
+    }
+}
+
+/// Models seen on each provider (Test connection, Provider Status,
+/// pre-flight), for "also available on".
+#[derive(Clone, Debug, Default)]
+pub struct ModelIndex {
+    /// (provider id, provider name, model ids)
+    providers: Vec<(String, String, Vec<String>)>,
+    aliases: Aliases,
+}
+
+impl ModelIndex {
+    pub fn record(&mut self, id: &str, name: &str, models: &[String]) {
+        if models.is_empty() { return; }
+        self.aliases = Aliases::load();
+        self.providers.retain(|(pid, _, _)| pid != id);
+        self.providers.push((id.to_string(), name.to_string(), models.to_vec()));
+    }
+
+    /// (provider name, that provider's name for it) for every provider that
+    /// lists `model`.
+    pub fn available_on(&self, model: &str) -> Vec<(String, String)> {
+        self.providers
+            .iter()
+            .filter_map(|(_, pname, models)| self.aliases.equivalent(model, models).map(|m| (pname.clone(), m.clone())))
+            .collect()
+    }
+}
+
+#[cfg(all(test, unix))]
+mod tests {
+    use serde_json::json;
+
+    use super::*;
+    use crate::config_cli::run_config;
+    use crate::providers::{read_scratch_entries, test_connection};
+    use crate::testing::{add_provider, FakeCli};
+
+    #[test]
+    fn models_match_across_provider_naming() {
+        let mut fake = FakeCli::new();
+        fake.respond("providers discover-models --type ollama", json!({"provider": "ollama", "models": [{"id": "qwen2.5-coder:7b"}, {"id": "llama3.2:3b"}]}));
+        run_config(add_provider("ollama", "home", &[], false)).expect("add provider");
+
+        let entry = &read_scratch_entries().expect("entries")[0];
+        let t = test_connection(entry).expect("test connection");
+        let mut index = ModelIndex::default();
+        index.record(&entry.id, &entry.name, &t.model_ids);
+        // Catalog id and LM Studio/Hugging Face names find the Ollama tag
+        assert_eq!(index.available_on("qwen2.5-coder-7b"), vec![("ollama".to_string(), "qwen2.5-coder:7b".to_string())]);
+        assert_eq!(index.available_on("Llama-3.2-3B-Instruct-Q4_K_M.gguf").len(), 1);
+        assert!(index.available_on("qwen2.5-coder:14b").is_empty());
+
+        let aliases = Aliases::from_store(&json!({"model_aliases": {"my-coder": "qwen2.5-coder:7b"}}));
+        assert_eq!(aliases.equivalent("my-coder", &t.model_ids), Some(&t.model_ids[0]));
+        assert!(aliases.same("lmstudio-community/Qwen2.5-Coder-7B-Instruct-GGUF", "my-coder"));
+    }
+}
@@ -0,0 +1,201 @@
+use std::collections::HashMap;
+use std::sync::mpsc::{channel, Receiver, TryRecvError};
+use std::thread;
+use std::time::{Duration, Instant};
+
+use anyhow::Result;
+use serde_json::Value;
+
+use crate::discovery_cache;
+use crate::health::{check_tcp, endpoint_of};
+use crate::ollama;
+use crate::providers::{read_scratch_entries, ProviderScratchEntry};
+use super::{ModelBrowser, ModelEntry};
+
+/// Ollama's installed models, from the discovery cache while fresh. LM
+/// Studio's list is not cached: its load state changes under the user.
+pub(super) fn installed_cached(p: &ProviderScratchEntry, timeout: Duration) -> Result<Vec<(String, u64)>> {
+    let config = p.config.to_string();
+    let v = discovery_cache::get_or(&["ollama /api/tags", &p.id, &config], discovery_cache::ollama_ttl(), || {
+        Ok(serde_json::to_value(ollama::installed(p, timeout)?)?)
+    })?;
+    Ok(serde_json::from_value(v)?)
+}
+
+/// Configured Ollama and LM Studio providers whose server accepts connections.
+pub(super) fn reachable_servers() -> Vec<ProviderScratchEntry> {
+    read_scratch_entries()
+        .unwrap_or_default()
+        .into_iter()
+        .filter(|e| e.ptype == "ollama" || e.ptype == "lmstudio")
+        .filter(|e| endpoint_of(e).map_or(false, |(host, port)| check_tcp(&e.id, &host, port, Duration::from_millis(300)).ok()))
+        .collect()
+}
+
+pub fn fetch_models(timeout: Duration) -> Result<ModelBrowser> {
+    Ok(from_catalog(&discovery_cache::cli(discovery_cache::MODELS_LIST, timeout)?))
+}
+
+/// The last catalog in the discovery cache however old, without servers:
+/// shown while the background refresh runs.
+pub fn cached_models() -> Option<ModelBrowser> {
+    discovery_cache::peek(discovery_cache::MODELS_LIST).map(|v| from_catalog(&v))
+}
+
+pub(super) fn from_catalog(arr: &Value) -> ModelBrowser {
+    let mut entries: Vec<ModelEntry> = Vec::new();
+    let mut tagset: std::collections::BTreeSet<String> =
+        std::collections::BTreeSet::new();
+    if let Some(list) = arr.as_array() {
+        for v in list {
+            let id = v.get("id").and_then(|x| x.as_str()).unwrap_or("").to_string();
+            let name = v
+                .get("name")
+                .and_then(|x| x.as_str())
+                .unwrap_or(&id)
+                .to_string();
+            let size = v
+                .get("size")
+                .and_then(|x| x.as_str())
+                .map(|s| s.to_string());
+            let file_size_mb = v.get("file_size_mb").and_then(|x| x.as_u64());
+            let context_window = v.get("context_window").and_then(|x| x.as_u64());
+            let tags: Vec<String> = v
+                .get("tags")
+                .and_then(|x| x.as_array())
+                .map(|a| {
+                    a.iter()
+                        .filter_map(|t| t.as_str().map(|s| s.to_string()))
+                        .collect()
+                })
+                .unwrap_or_default();
+            for t in &tags {
+                tagset.insert(t.clone());
+            }
+            let downloaded = v
+                .get("downloaded")
+                .and_then(|x| x.as_bool())
+                .unwrap_or(false);
+            let current = v
+                .get("current")
+                .and_then(|x| x.as_bool())
+                .unwrap_or(false);
+            let repo = v.get("repo").and_then(|x| x.as_str()).map(|s| s.to_string());
+            let filename = v
+                .get("filename")
+                .and_then(|x| x.as_str())
+                .map(|s| s.to_string());
+            entries.push(ModelEntry {
+                id,
+                name,
+                size,
+                file_size_mb,
+                context_window,
+                tags,
+                downloaded,
+                current,
+                repo,
+                filename,
+                raw: v.clone(),
+                server: None,
+                loaded: None,
+            });
+        }
+    }
+    let all_tags = tagset.into_iter().collect();
+    let mut mb = ModelBrowser {
+        entries,
+        filtered: Vec::new(),
+        selected: 0,
+        downloaded_only: false,
+        tag_filter: None,
+        show_info: false,
+        all_tags,
+        disk_warning: None,
+        search: String::new(),
+        searching: false,
+        details: HashMap::new(),
+        repo_info: HashMap::new(),
+        sort: None,
+        sort_desc: false,
+    };
+    mb.compute_filtered();
+    mb
+}
+
+/// Spinner shown in the menu while the prefetch runs.
+const SPINNER: [char; 10] = ['⠋', '⠙', '⠹', '⠸', '⠼', '⠴', '⠦', '⠧', '⠇', '⠏'];
+
+/// The Model Browser's lists (catalog plus every reachable Ollama and LM
+/// Studio server), fetched off the UI thread at startup so the page opens
+/// at once.
+#[derive(Default)]
+pub struct ModelPrefetch {
+    rx: Option<Receiver<Result<ModelBrowser>>>,
+    started: Option<Instant>,
+}
+
+impl ModelPrefetch {
+    pub fn start(&mut self) {
+        let (tx, rx) = channel();
+        thread::spawn(move || {
+            let _ = tx.send(fetch_models(Duration::from_secs(10)).map(|mut m| { m.add_server_entries(); m }));
+        });
+        self.rx = Some(rx);
+        self.started = Some(Instant::now());
+    }
+
+    pub fn running(&self) -> bool {
+        self.rx.is_some()
+    }
+
+    /// The lists, once they arrived.
+    pub fn poll(&mut self) -> Option<Result<ModelBrowser>> {
+        let res = match self.rx.as_ref()?.try_recv() {
+            Ok(res) => Some(res),
+            Err(TryRecvError::Empty) => return None,
+            Err(TryRecvError::Disconnected) => None,
+        };
+        self.rx = None;
+        res
+    }
+
+    /// The current spinner frame while running.
+    pub fn spinner(&self) -> Option<char> {
+        let started = self.started.filter(|_| self.running())?;
+        Some(SPINNER[(started.elapsed().as_millis() / 100) as usize % SPINNER.len()])
+    }
+}
+
+#[cfg(all(test, unix))]
+mod tests {
+    use serde_json::json;
+
+    use super::*;
+    use crate::downloads;
+    use crate::testing::FakeCli;
+
+    #[test]
+    fn downloads_fall_back_to_catalog_mirrors() {
+        let _fake = FakeCli::new();
+        let raw = json!({"mirrors": [
+            {"repo": "bartowski/gemma-3-270m-it-GGUF"},
+            {"url": "https://mirror.example/gemma.gguf"},
+            {"note": "no source"}
+        ]});
+        let urls = downloads::sources("gemma-270m", "lmstudio-community/gemma-3-270m-it-GGUF", "gemma.gguf", &raw);
+        assert_eq!(urls, vec![
+            "https://huggingface.co/lmstudio-community/gemma-3-270m-it-GGUF/resolve/main/gemma.gguf",
+            "https://huggingface.co/bartowski/gemma-3-270m-it-GGUF/resolve/main/gemma.gguf",
+            "https://mirror.example/gemma.gguf",
+        ]);
+
+        // The source that worked last time goes first
+        let dir = downloads::model_dir().expect("model dir");
+        std::fs::create_dir_all(&dir).expect("model dir");
+        std::fs::write(dir.join("download_mirrors.json"), r#"{"gemma-270m": "https://mirror.example/gemma.gguf"}"#).expect("write");
+        let urls = downloads::sources("gemma-270m", "lmstudio-community/gemma-3-270m-it-GGUF", "gemma.gguf", &raw);
+        assert_eq!(urls[0], "https://mirror.example/gemma.gguf");
+        assert_eq!(urls.len(), 3);
+    }
+}
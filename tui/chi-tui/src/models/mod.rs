@@ -0,0 +1,504 @@
+//! Model Browser state: the catalog plus models on reachable Ollama and
+//! LM Studio servers and Hub search results, filtered (downloaded-only, tag,
+//! fuzzy search) and sorted by a column. Fetching is in `catalog`, drawing
+//! in `view`.
+
+use std::collections::HashMap;
+use std::time::Duration;
+
+use anyhow::Result;
+use serde_json::Value;
+
+use crate::downloads;
+use crate::fuzzy;
+use crate::hf::{self, RepoInfo};
+use crate::lmstudio;
+use crate::ollama::{self, ModelDetails};
+use crate::providers::ProviderScratchEntry;
+use crate::recommend;
+
+mod catalog;
+mod view;
+
+use catalog::{installed_cached, reachable_servers};
+pub use catalog::{cached_models, fetch_models, ModelPrefetch};
+pub use view::{draw_disk_warning, draw_model_browser};
+
+#[derive(Clone, Debug)]
+pub struct ModelEntry {
+    pub id: String,
+    pub name: String,
+    pub size: Option<String>,
+    pub file_size_mb: Option<u64>,
+    pub context_window: Option<u64>,
+    pub tags: Vec<String>,
+    pub downloaded: bool,
+    pub current: bool,
+    /// Hugging Face source, used by the in-TUI downloader
+    pub repo: Option<String>,
+    pub filename: Option<String>,
+    pub raw: Value,
+    /// Installed on this Ollama or LM Studio provider (listed from its API)
+    pub server: Option<ProviderScratchEntry>,
+    /// LM Studio: whether the model is loaded; None when unknown
+    pub loaded: Option<bool>,
+}
+
+/// Model table column the list is sorted by (`s` cycles, `S` flips).
+#[derive(Copy, Clone, Debug, PartialEq, Eq)]
+pub enum SortColumn {
+    Name,
+    Size,
+    Context,
+    Ram,
+    Tags,
+    Downloaded,
+}
+
+impl SortColumn {
+    const ALL: [SortColumn; 6] = [SortColumn::Name, SortColumn::Size, SortColumn::Context, SortColumn::Ram, SortColumn::Tags, SortColumn::Downloaded];
+
+    pub fn label(self) -> &'static str {
+        match self {
+            SortColumn::Name => "Name",
+            SortColumn::Size => "Size",
+            SortColumn::Context => "Context",
+            SortColumn::Ram => "RAM",
+            SortColumn::Tags => "Tags",
+            SortColumn::Downloaded => "Status",
+        }
+    }
+
+    /// Next column; after the last, back to catalog order (None).
+    fn next(cur: Option<SortColumn>) -> Option<SortColumn> {
+        match cur {
+            None => Some(SortColumn::ALL[0]),
+            Some(c) => SortColumn::ALL.iter().position(|x| *x == c).and_then(|i| SortColumn::ALL.get(i + 1)).copied(),
+        }
+    }
+
+    /// Order of two entries by this column, ascending. Entries without a
+    /// value sort after those with one in either direction (see `compute_filtered`).
+    fn key_cmp(self, a: &ModelEntry, b: &ModelEntry) -> std::cmp::Ordering {
+        let num = |x: Option<f64>, y: Option<f64>| x.partial_cmp(&y).unwrap_or(std::cmp::Ordering::Equal);
+        match self {
+            SortColumn::Name => a.name.to_lowercase().cmp(&b.name.to_lowercase()),
+            SortColumn::Size => a.file_size_mb.cmp(&b.file_size_mb),
+            SortColumn::Context => a.context_window.cmp(&b.context_window),
+            SortColumn::Ram => num(recommend::need_gb(a), recommend::need_gb(b)),
+            SortColumn::Tags => a.tags.join(",").to_lowercase().cmp(&b.tags.join(",").to_lowercase()),
+            // Downloaded first
+            SortColumn::Downloaded => b.downloaded.cmp(&a.downloaded),
+        }
+    }
+
+    fn has_value(self, e: &ModelEntry) -> bool {
+        match self {
+            SortColumn::Size => e.file_size_mb.is_some(),
+            SortColumn::Context => e.context_window.is_some(),
+            SortColumn::Ram => recommend::need_gb(e).is_some(),
+            SortColumn::Tags => !e.tags.is_empty(),
+            SortColumn::Name | SortColumn::Downloaded => true,
+        }
+    }
+}
+
+/// Shown instead of starting a download that would not fit on disk.
+#[derive(Clone, Debug)]
+pub struct DiskWarning {
+    pub id: String,
+    pub required: u64,
+    pub available: u64,
+}
+
+#[derive(Clone, Debug)]
+pub struct ModelBrowser {
+    pub entries: Vec<ModelEntry>,
+    pub filtered: Vec<usize>,
+    pub selected: usize, // index in filtered
+    pub downloaded_only: bool,
+    pub tag_filter: Option<String>,
+    pub show_info: bool,
+    pub all_tags: Vec<String>,
+    pub disk_warning: Option<DiskWarning>,
+    /// Fuzzy filter over id, name and tags (`/`)
+    pub search: String,
+    /// Search input has focus
+    pub searching: bool,
+    /// `/api/show` results by "provider id/model", or the error text
+    pub details: HashMap<String, Result<ModelDetails, String>>,
+    /// Hub license/gating by repo, or the error text
+    pub repo_info: HashMap<String, Result<RepoInfo, String>>,
+    /// None keeps catalog order (or match quality while searching)
+    pub sort: Option<SortColumn>,
+    pub sort_desc: bool,
+}
+
+impl ModelBrowser {
+    pub fn compute_filtered(&mut self) {
+        self.filtered.clear();
+        let terms = fuzzy::terms(&self.search);
+        let mut scores: Vec<i64> = vec![0; self.entries.len()];
+        for (i, e) in self.entries.iter().enumerate() {
+            if self.downloaded_only && !e.downloaded {
+                continue;
+            }
+            if let Some(tag) = &self.tag_filter {
+                if !e.tags.iter().any(|t| t == tag) {
+                    continue;
+                }
+            }
+            if !terms.is_empty() {
+                let tags = e.tags.join(" ");
+                match fuzzy::score(&terms, &[&e.id, &e.name, &tags]) {
+                    Some(s) => scores[i] = s,
+                    None => continue,
+                }
+            }
+            self.filtered.push(i);
+        }
+        // Best matches first while searching; stable, so ties keep list order
+        if !terms.is_empty() {
+            self.filtered.sort_by_key(|&i| std::cmp::Reverse(scores[i]));
+        }
+        // An explicit sort column wins over match quality
+        if let Some(col) = self.sort {
+            let (entries, desc) = (&self.entries, self.sort_desc);
+            self.filtered.sort_by(|&a, &b| {
+                let (ea, eb) = (&entries[a], &entries[b]);
+                let ord = col.key_cmp(ea, eb);
+                col.has_value(eb).cmp(&col.has_value(ea)).then(if desc { ord.reverse() } else { ord })
+            });
+        }
+        if self.filtered.is_empty() {
+            self.selected = 0;
+        } else if self.selected >= self.filtered.len() {
+            self.selected = self.filtered.len() - 1;
+        }
+    }
+    pub fn move_up(&mut self) {
+        if !self.filtered.is_empty() && self.selected > 0 {
+            self.selected -= 1;
+        }
+    }
+    pub fn move_down(&mut self) {
+        if !self.filtered.is_empty() && self.selected + 1 < self.filtered.len() {
+            self.selected += 1;
+        }
+    }
+    pub fn toggle_downloaded_only(&mut self) {
+        self.downloaded_only = !self.downloaded_only;
+        self.compute_filtered();
+    }
+    pub fn cycle_tag(&mut self) {
+        if self.all_tags.is_empty() {
+            return;
+        }
+        match &self.tag_filter {
+            None => {
+                self.tag_filter = Some(self.all_tags[0].clone());
+            }
+            Some(cur) => {
+                let mut idx = self
+                    .all_tags
+                    .iter()
+                    .position(|t| t == cur)
+                    .unwrap_or(0);
+                idx = (idx + 1) % (self.all_tags.len() + 1); // +1 to allow none state
+                if idx >= self.all_tags.len() {
+                    self.tag_filter = None;
+                } else {
+                    self.tag_filter = Some(self.all_tags[idx].clone());
+                }
+            }
+        }
+        self.compute_filtered();
+    }
+    /// `s`: next sort column, keeping the selected model selected.
+    pub fn cycle_sort(&mut self) {
+        self.sort = SortColumn::next(self.sort);
+        self.resort();
+    }
+
+    /// `S`: flip the sort direction.
+    pub fn toggle_sort_direction(&mut self) {
+        self.sort_desc = !self.sort_desc;
+        self.resort();
+    }
+
+    fn resort(&mut self) {
+        let cur = self.current_entry().map(|e| e.id.clone());
+        self.compute_filtered();
+        if let Some(id) = cur { self.select_id(&id); }
+    }
+
+    pub fn set_search(&mut self, query: String) {
+        self.search = query;
+        self.selected = 0;
+        self.compute_filtered();
+    }
+    /// Add the models on each reachable Ollama and LM Studio provider,
+    /// tagged with the provider type. Servers that do not answer are skipped.
+    pub fn add_server_entries(&mut self) {
+        let timeout = Duration::from_secs(2);
+        for p in reachable_servers() {
+            let listed: Vec<(String, Option<u64>, Option<bool>, Option<u64>)> = match p.ptype.as_str() {
+                "ollama" => match installed_cached(&p, timeout) {
+                    Ok(m) => m.into_iter().map(|(name, bytes)| (name, Some(bytes / (1024 * 1024)).filter(|mb| *mb > 0), None, None)).collect(),
+                    Err(_) => continue,
+                },
+                _ => match lmstudio::models(&p, timeout) {
+                    Ok(m) => m.into_iter().filter(|m| m.kind.as_deref() != Some("embeddings")).map(|m| (m.id, None, m.loaded, m.max_context_length)).collect(),
+                    Err(_) => continue,
+                },
+            };
+            for (id, file_size_mb, loaded, context_window) in listed {
+                self.entries.push(ModelEntry {
+                    name: format!("{} ({})", id, p.name),
+                    id,
+                    size: None,
+                    file_size_mb,
+                    context_window,
+                    tags: vec![p.ptype.clone()],
+                    downloaded: true,
+                    current: false,
+                    repo: None,
+                    filename: None,
+                    raw: Value::Null,
+                    server: Some(p.clone()),
+                    loaded,
+                });
+            }
+            if !self.all_tags.contains(&p.ptype) {
+                self.all_tags.push(p.ptype.clone());
+                self.all_tags.sort();
+            }
+        }
+        self.compute_filtered();
+    }
+
+    /// Search the Hugging Face Hub for GGUF repos matching the `/` filter and
+    /// add them, tagged "hf", so they can be inspected and downloaded like
+    /// catalog models. Repos already listed are skipped; returns how many
+    /// were added.
+    pub fn add_hub_results(&mut self) -> Result<usize> {
+        let found = hf::search(&self.search, 20)?;
+        let dir = downloads::model_dir().ok();
+        let mut added = 0;
+        for h in found {
+            if self.entries.iter().any(|e| e.repo.as_deref() == Some(h.repo.as_str())) { continue; }
+            let downloaded = dir.as_ref().map_or(false, |d| d.join(&h.filename).exists());
+            self.entries.push(ModelEntry {
+                name: format!("{} ({})", h.repo, h.filename),
+                id: h.repo.clone(),
+                size: None,
+                file_size_mb: None,
+                context_window: None,
+                tags: vec!["hf".to_string()],
+                downloaded,
+                current: false,
+                repo: Some(h.repo),
+                filename: Some(h.filename),
+                raw: h.raw,
+                server: None,
+                loaded: None,
+            });
+            added += 1;
+        }
+        if added > 0 && !self.all_tags.iter().any(|t| t == "hf") {
+            self.all_tags.push("hf".to_string());
+            self.all_tags.sort();
+        }
+        self.compute_filtered();
+        Ok(added)
+    }
+
+    /// Mark `id` on LM Studio servers as loaded.
+    pub fn mark_loaded(&mut self, id: &str) {
+        for e in self.entries.iter_mut().filter(|e| e.id == id && e.loaded.is_some()) {
+            e.loaded = Some(true);
+        }
+    }
+
+    fn details_key(p: &ProviderScratchEntry, model: &str) -> String {
+        format!("{}/{}", p.id, model)
+    }
+
+    /// Details of the selected model, fetched once: `/api/show` for Ollama
+    /// models, license and gating from the Hub for catalog models the
+    /// catalog says nothing about. True when something was fetched.
+    pub fn load_details(&mut self) -> bool {
+        let Some(e) = self.current_entry().cloned() else { return false };
+        match (&e.server, &e.repo) {
+            (Some(p), _) if p.ptype == "ollama" => {
+                let key = Self::details_key(p, &e.id);
+                if self.details.contains_key(&key) { return false; }
+                let res = ollama::show(p, &e.id, Duration::from_secs(3)).map_err(|err| err.to_string());
+                self.details.insert(key, res);
+                true
+            }
+            (None, Some(repo)) => {
+                if self.repo_info.contains_key(repo) || hf::from_catalog(&e.raw).is_some() { return false; }
+                let res = hf::repo_info(repo, Duration::from_secs(3)).map_err(|err| err.to_string());
+                self.repo_info.insert(repo.clone(), res);
+                true
+            }
+            _ => false,
+        }
+    }
+
+    pub fn current_details(&self) -> Option<&Result<ModelDetails, String>> {
+        let e = self.current_entry()?;
+        self.details.get(&Self::details_key(e.server.as_ref()?, &e.id))
+    }
+
+    /// License and gating of `e`: the catalog's own, else what the Hub said.
+    pub fn repo_info_of(&self, e: &ModelEntry) -> Option<Result<RepoInfo, String>> {
+        hf::from_catalog(&e.raw).map(Ok).or_else(|| self.repo_info.get(e.repo.as_ref()?).cloned())
+    }
+
+    pub fn current_entry(&self) -> Option<&ModelEntry> {
+        self.filtered.get(self.selected).map(|&i| &self.entries[i])
+    }
+    /// Select `id`, clearing filters that hide it.
+    pub fn select_id(&mut self, id: &str) {
+        let Some(idx) = self.entries.iter().position(|e| e.id == id) else { return };
+        if !self.filtered.contains(&idx) {
+            self.search.clear();
+            self.downloaded_only = false;
+            self.tag_filter = None;
+            self.compute_filtered();
+        }
+        if let Some(pos) = self.filtered.iter().position(|&i| i == idx) { self.selected = pos; }
+    }
+
+    /// Carry the filters, sort, selection and fetched details of `old` over
+    /// to this fresher list, so a background refresh does not reset the view.
+    pub fn keep_view_of(&mut self, old: &ModelBrowser) {
+        let cur = old.current_entry().map(|e| e.id.clone());
+        self.downloaded_only = old.downloaded_only;
+        self.tag_filter = old.tag_filter.clone().filter(|t| self.all_tags.contains(t));
+        self.search = old.search.clone();
+        self.searching = old.searching;
+        self.sort = old.sort;
+        self.sort_desc = old.sort_desc;
+        self.show_info = old.show_info;
+        self.disk_warning = old.disk_warning.clone();
+        self.details = old.details.clone();
+        self.repo_info = old.repo_info.clone();
+        self.compute_filtered();
+        let idx = cur.and_then(|id| self.entries.iter().position(|e| e.id == id));
+        if let Some(pos) = idx.and_then(|idx| self.filtered.iter().position(|&i| i == idx)) { self.selected = pos; }
+    }
+
+    pub fn mark_downloaded(&mut self, id: &str) {
+        if let Some(e) = self.entries.iter_mut().find(|e| e.id == id) {
+            e.downloaded = true;
+        }
+        self.compute_filtered();
+    }
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+    use serde_json::json;
+
+    fn browser() -> ModelBrowser {
+        catalog::from_catalog(&json!([
+            {"id": "qwen3-1.7b", "name": "Qwen3 1.7B", "file_size_mb": 1100, "context_window": 32768, "tags": ["small", "chat"], "downloaded": true},
+            {"id": "phi3-mini", "name": "Phi-3 Mini", "file_size_mb": 2300, "context_window": 4096, "tags": ["coding"]},
+            {"id": "mystery", "name": "Mystery"},
+        ]))
+    }
+
+    fn order(mb: &ModelBrowser) -> Vec<&str> {
+        mb.filtered.iter().map(|&i| mb.entries[i].id.as_str()).collect()
+    }
+
+    #[test]
+    fn the_catalog_keeps_its_order_and_collects_tags() {
+        let mb = browser();
+        assert_eq!(order(&mb), ["qwen3-1.7b", "phi3-mini", "mystery"]);
+        assert_eq!(mb.all_tags, ["chat", "coding", "small"]);
+        assert_eq!(mb.current_entry().map(|e| e.id.as_str()), Some("qwen3-1.7b"));
+    }
+
+    #[test]
+    fn filters_narrow_the_list_and_clamp_the_selection() {
+        let mut mb = browser();
+        mb.move_down();
+        mb.move_down();
+        mb.toggle_downloaded_only();
+        assert_eq!(order(&mb), ["qwen3-1.7b"]);
+        assert_eq!(mb.selected, 0);
+        mb.toggle_downloaded_only();
+
+        // Tags cycle in order, then back to none
+        mb.cycle_tag();
+        assert_eq!((mb.tag_filter.as_deref(), order(&mb)), (Some("chat"), vec!["qwen3-1.7b"]));
+        mb.cycle_tag();
+        assert_eq!((mb.tag_filter.as_deref(), order(&mb)), (Some("coding"), vec!["phi3-mini"]));
+        mb.cycle_tag();
+        mb.cycle_tag();
+        assert_eq!(mb.tag_filter, None);
+        assert_eq!(order(&mb).len(), 3);
+
+        mb.set_search("phi".to_string());
+        assert_eq!(order(&mb), ["phi3-mini"]);
+        mb.set_search("nothing like it".to_string());
+        assert!(order(&mb).is_empty() && mb.current_entry().is_none());
+    }
+
+    #[test]
+    fn sorting_cycles_columns_and_keeps_missing_values_last() {
+        let mut mb = browser();
+        mb.cycle_sort();
+        assert_eq!(mb.sort, Some(SortColumn::Name));
+        assert_eq!(order(&mb), ["mystery", "phi3-mini", "qwen3-1.7b"]);
+        // The selected model stays selected
+        assert_eq!(mb.current_entry().map(|e| e.id.as_str()), Some("qwen3-1.7b"));
+
+        mb.cycle_sort();
+        assert_eq!(mb.sort, Some(SortColumn::Size));
+        assert_eq!(order(&mb), ["qwen3-1.7b", "phi3-mini", "mystery"]);
+        mb.toggle_sort_direction();
+        assert_eq!(order(&mb), ["phi3-mini", "qwen3-1.7b", "mystery"]);
+        mb.toggle_sort_direction();
+
+        mb.cycle_sort();
+        assert_eq!(order(&mb), ["phi3-mini", "qwen3-1.7b", "mystery"], "by context");
+        mb.cycle_sort();
+        assert_eq!(order(&mb), ["qwen3-1.7b", "phi3-mini", "mystery"], "by RAM");
+        mb.cycle_sort();
+        mb.cycle_sort();
+        assert_eq!(mb.sort, Some(SortColumn::Downloaded));
+        assert_eq!(order(&mb)[0], "qwen3-1.7b");
+        mb.cycle_sort();
+        assert_eq!(mb.sort, None);
+        assert_eq!(order(&mb), ["qwen3-1.7b", "phi3-mini", "mystery"]);
+    }
+
+    #[test]
+    fn selecting_a_hidden_model_clears_the_filters() {
+        let mut mb = browser();
+        mb.toggle_downloaded_only();
+        mb.select_id("phi3-mini");
+        assert!(!mb.downloaded_only);
+        assert_eq!(mb.current_entry().map(|e| e.id.as_str()), Some("phi3-mini"));
+        mb.select_id("not-listed");
+        assert_eq!(mb.current_entry().map(|e| e.id.as_str()), Some("phi3-mini"));
+    }
+
+    #[test]
+    fn a_refreshed_list_keeps_the_view() {
+        let mut old = browser();
+        old.cycle_sort();
+        old.set_search("mini".to_string());
+        let mut fresh = browser();
+        fresh.mark_downloaded("phi3-mini");
+        fresh.keep_view_of(&old);
+        assert_eq!((fresh.sort, fresh.search.as_str()), (Some(SortColumn::Name), "mini"));
+        assert_eq!(fresh.current_entry().map(|e| (e.id.as_str(), e.downloaded)), Some(("phi3-mini", true)));
+    }
+}
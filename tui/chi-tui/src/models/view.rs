@@ -0,0 +1,305 @@
+use ratatui::layout::{Constraint, Direction, Layout, Rect};
+use ratatui::prelude::Frame;
+use ratatui::style::{Modifier, Style};
+use ratatui::text::{Line, Span};
+use ratatui::widgets::{Block, Borders, Cell, Clear, Paragraph, Row, Table, TableState, Wrap};
+
+use crate::app::App;
+use crate::downloads::DownloadState;
+use crate::fuzzy;
+use crate::hf;
+use crate::locale;
+use crate::recommend;
+use crate::text;
+use crate::util::overlay_rect;
+use super::SortColumn;
+
+pub fn draw_model_browser(f: &mut Frame, area: Rect, app: &App) {
+    let mut upper = area;
+    let mut lower = area;
+    let show_info = app.model.as_ref().map(|m| m.show_info).unwrap_or(false);
+    if show_info {
+        let chunks = Layout::default()
+            .direction(Direction::Vertical)
+            .constraints([Constraint::Percentage(70), Constraint::Percentage(30)])
+            .split(area);
+        upper = chunks[0];
+        lower = chunks[1];
+    }
+    let title = if let Some(mb) = &app.model {
+        let mut t = String::from("Models");
+        if mb.downloaded_only {
+            t.push_str(" • downloaded-only");
+        }
+        if let Some(tag) = &mb.tag_filter {
+            t.push_str(&format!(" • tag:{}", tag));
+        }
+        if app.prefetch.running() {
+            t.push_str(" • refreshing…");
+        }
+        if let Some(col) = mb.sort {
+            t.push_str(&format!(" • sorted by {} {}", col.label(), if mb.sort_desc { "▼" } else { "▲" }));
+        }
+        if mb.searching || !mb.search.is_empty() {
+            let cursor = if mb.searching { "▏" } else { "" };
+            t.push_str(&format!(
+                " • /{}{} ({}/{})",
+                mb.search,
+                cursor,
+                mb.filtered.len(),
+                mb.entries.len()
+            ));
+        }
+        t
+    } else {
+        String::from("Models")
+    };
+    let block = Block::default()
+        .borders(Borders::ALL)
+        .border_style(Style::default().fg(app.theme.frame))
+        .title(title);
+    let Some(mb) = &app.model else {
+        f.render_widget(Paragraph::new("Loading models...").block(block), upper);
+        return;
+    };
+    let terms = fuzzy::terms(&mb.search);
+    // What the Min(24) name column gets next to the fixed ones; `i` shows the full name
+    let inner = upper.width.saturating_sub(2) as usize;
+    let name_w = inner.saturating_sub(10 + 9 + 8 + 18 + inner * 18 / 100 + 5 + 2).max(22);
+    let mut rows: Vec<Row> = Vec::new();
+    for (pos, &idx) in mb.filtered.iter().enumerate() {
+        let e = &mb.entries[idx];
+        let mut status: Vec<String> = Vec::new();
+        if e.current {
+            status.push("current".to_string());
+        }
+        match app.downloads.status(&e.id) {
+            Some(st) if st.state != DownloadState::Done => status.push(st.label()),
+            _ if e.loaded.is_some() && app.lms_loader.is_loading(&e.id) => status.push("loading…".to_string()),
+            _ if e.loaded == Some(true) => status.push("loaded".to_string()),
+            _ if e.loaded == Some(false) => status.push("not loaded".to_string()),
+            _ if e.downloaded => status.push("downloaded".to_string()),
+            _ => {}
+        }
+        let style = if pos == mb.selected {
+            Style::default()
+                .fg(app.theme.selected)
+                .add_modifier(Modifier::BOLD)
+        } else {
+            Style::default().fg(app.theme.fg)
+        };
+        let hl = style.fg(app.theme.accent).add_modifier(Modifier::UNDERLINED);
+        let marks = fuzzy::highlights(&terms, &e.name);
+        let mut spans = vec![Span::styled(
+            format!("{} ", if pos == mb.selected { '›' } else { ' ' }),
+            style,
+        )];
+        let name = text::truncate(&e.name, name_w);
+        for (ci, c) in name.chars().enumerate() {
+            let s = if marks.binary_search(&ci).is_ok() { hl } else { style };
+            spans.push(Span::styled(c.to_string(), s));
+        }
+        let dash = || "-".to_string();
+        rows.push(
+            Row::new(vec![
+                Cell::from(Line::from(spans)),
+                Cell::from(e.file_size_mb.map_or_else(dash, |size| locale::bytes(size * 1024 * 1024))),
+                Cell::from(e.context_window.map_or_else(dash, locale::count)),
+                Cell::from(recommend::need_gb(e).map_or_else(dash, |g| format!("{} GB", locale::decimal(g, 1)))),
+                Cell::from(e.tags.join(",")),
+                Cell::from(status.join(" • ")),
+            ])
+            .style(style),
+        );
+    }
+    if mb.filtered.is_empty() && !mb.search.is_empty() {
+        rows.push(Row::new(vec![Cell::from(Span::styled(
+            format!("No models match \"{}\"", mb.search),
+            Style::default().fg(app.theme.secondary),
+        ))]));
+    }
+    let heading = |col: SortColumn| match mb.sort {
+        Some(c) if c == col => format!("{} {}", col.label(), if mb.sort_desc { "▼" } else { "▲" }),
+        _ => col.label().to_string(),
+    };
+    let header = Row::new(SortColumn::ALL.map(|c| Cell::from(heading(c))))
+        .style(Style::default().fg(app.theme.primary).add_modifier(Modifier::BOLD));
+    let widths = [
+        Constraint::Min(24),
+        Constraint::Length(10),
+        Constraint::Length(9),
+        Constraint::Length(8),
+        Constraint::Percentage(18),
+        Constraint::Length(18),
+    ];
+    let table = Table::new(rows, widths).header(header).block(block);
+    // Keeps the selection in view with long lists (e.g. 80+ Ollama models)
+    let mut state = TableState::default().with_selected(Some(mb.selected).filter(|_| !mb.filtered.is_empty()));
+    f.render_stateful_widget(table, upper, &mut state);
+
+    if show_info {
+        let mut lines: Vec<Line> = Vec::new();
+        if let Some(mb) = &app.model {
+            if let Some(e) = mb.current_entry() {
+                lines.push(Line::from(Span::styled(
+                    format!("{} ({})", e.name, e.id),
+                    Style::default()
+                        .fg(app.theme.primary)
+                        .add_modifier(Modifier::BOLD),
+                )));
+                if let Some(s) = &e.size {
+                    lines.push(Line::from(format!("size: {}", s)));
+                }
+                if let Some(fs) = e.file_size_mb {
+                    lines.push(Line::from(format!("file size: {}", locale::bytes(fs * 1024 * 1024))));
+                }
+                if let Some(ctx) = e.context_window {
+                    lines.push(Line::from(format!("context_window: {}", locale::count(ctx))));
+                }
+                if !e.tags.is_empty() {
+                    lines.push(Line::from(format!("tags: {}", e.tags.join(", "))));
+                }
+                match mb.repo_info_of(e) {
+                    Some(Ok(info)) => {
+                        let license = info.license.as_deref().map(hf::license_label).unwrap_or_else(|| "not stated".to_string());
+                        lines.push(Line::from(format!("license: {}", license)));
+                        if info.gating.is_gated() {
+                            let (text, color) = match app.hf_token {
+                                Some(_) => (format!("access: {} • token configured", info.gating.label()), app.theme.warn),
+                                None => (format!("access: {} • needs a Hugging Face token (Settings → h)", info.gating.label()), app.theme.err),
+                            };
+                            lines.push(Line::from(Span::styled(text, Style::default().fg(color))));
+                        } else {
+                            lines.push(Line::from("access: open"));
+                        }
+                    }
+                    Some(Err(err)) => lines.push(Line::from(Span::styled(format!("license: unknown ({})", err), Style::default().fg(app.theme.secondary)))),
+                    None if e.repo.is_some() => lines.push(Line::from("license: press i to look it up on Hugging Face")),
+                    None => {}
+                }
+                match e.loaded {
+                    _ if e.loaded.is_some() && app.lms_loader.is_loading(&e.id) => lines.push(Line::from("LM Studio: loading…")),
+                    Some(true) => lines.push(Line::from(Span::styled("LM Studio: loaded", Style::default().fg(app.theme.ok)))),
+                    Some(false) => lines.push(Line::from("LM Studio: downloaded, not loaded • l loads it now")),
+                    None => {}
+                }
+                match mb.current_details() {
+                    Some(Ok(d)) => {
+                        let field = |k: &str, v: &Option<String>| Line::from(format!("{}: {}", k, v.as_deref().unwrap_or("—")));
+                        lines.push(field("parameters", &d.parameter_size));
+                        lines.push(field("quantization", &d.quantization));
+                        lines.push(field("family", &d.family));
+                        lines.push(field("context length", &d.context_length.map(locale::count)));
+                        if let Some(t) = &d.template {
+                            lines.push(Line::from(Span::styled("template:", Style::default().fg(app.theme.secondary))));
+                            lines.extend(t.lines().map(|l| Line::from(format!("  {}", l))));
+                        }
+                    }
+                    Some(Err(err)) => lines.push(Line::from(Span::styled(format!("/api/show failed: {}", err), Style::default().fg(app.theme.err)))),
+                    None if e.server.as_ref().map_or(false, |p| p.ptype == "ollama") => lines.push(Line::from("press i to load details from Ollama")),
+                    None => {}
+                }
+                let on = app.model_index.available_on(&e.id);
+                if !on.is_empty() {
+                    let where_ = on.iter().map(|(p, m)| format!("{} ({})", p, m)).collect::<Vec<_>>().join(", ");
+                    lines.push(Line::from(Span::styled(format!("also available on: {}", where_), Style::default().fg(app.theme.ok))));
+                }
+                if let Some(st) = app.downloads.status(&e.id) {
+                    let progress = match st.total {
+                        Some(t) => format!("{} / {}", locale::bytes(st.done), locale::bytes(t)),
+                        None => locale::bytes(st.done),
+                    };
+                    let color = match st.state {
+                        DownloadState::Failed(_) => app.theme.err,
+                        DownloadState::Done => app.theme.ok,
+                        _ => app.theme.secondary,
+                    };
+                    lines.push(Line::from(Span::styled(
+                        format!("download: {} ({})", st.label(), progress),
+                        Style::default().fg(color),
+                    )));
+                } else if !e.downloaded && e.repo.is_some() {
+                    lines.push(Line::from("download: press d to fetch"));
+                }
+            }
+        }
+        let p = Paragraph::new(lines)
+            .style(Style::default().bg(app.theme.bg).fg(app.theme.fg))
+            .block(
+                Block::default()
+                    .borders(Borders::ALL)
+                    .border_style(Style::default().fg(app.theme.frame))
+                    .title("Info"),
+            )
+            .alignment(ratatui::layout::Alignment::Left)
+            .wrap(Wrap { trim: true });
+        f.render_widget(p, lower);
+    }
+}
+
+
+pub fn draw_disk_warning(f: &mut Frame, area: Rect, app: &App) {
+    let Some(w) = app.model.as_ref().and_then(|m| m.disk_warning.as_ref()) else {
+        return;
+    };
+    let pop = overlay_rect(app.compact, 60, 40, area);
+    let lines = vec![
+        Line::from(Span::styled(
+            format!("Not enough disk space for {}", w.id),
+            Style::default()
+                .fg(app.theme.warn)
+                .add_modifier(Modifier::BOLD),
+        )),
+        Line::from(""),
+        Line::from(format!("required:  {}", locale::bytes(w.required))),
+        Line::from(format!("available: {}", locale::bytes(w.available))),
+        Line::from(""),
+        Line::from("c open cache cleanup • y download anyway • Esc cancel"),
+    ];
+    let p = Paragraph::new(lines)
+        .style(Style::default().bg(app.theme.bg).fg(app.theme.fg))
+        .block(
+            Block::default()
+                .borders(Borders::ALL)
+                .border_style(Style::default().fg(app.theme.warn))
+                .title("Disk space"),
+        )
+        .wrap(Wrap { trim: true });
+    f.render_widget(Clear, pop);
+    f.render_widget(p, pop);
+}
+
+#[cfg(all(test, unix))]
+mod tests {
+    use std::time::Duration;
+
+    use super::*;
+    use crate::app::Page;
+    use crate::models::fetch_models;
+    use crate::testing::{screen, FakeCli};
+
+    #[test]
+    fn the_model_browser_shows_cached_models_while_the_prefetch_runs() {
+        let mut fake = FakeCli::new();
+        fake.set_env("CHI_TUI_CACHE", "on".to_string());
+        fetch_models(Duration::from_secs(5)).expect("catalog from an earlier run");
+        let mut app = App::new(false);
+        app.prefetch.start();
+        assert!(app.prefetch.spinner().is_some());
+
+        crate::open_page_loaded(&mut app, Page::ModelBrowser);
+        assert!(screen(&app, 120, 20).contains("Models • refreshing…"));
+        app.model.as_mut().expect("cached catalog").set_search("phi".to_string());
+
+        let started = std::time::Instant::now();
+        while !crate::poll_prefetch(&mut app) {
+            assert!(started.elapsed() < Duration::from_secs(10), "prefetch did not finish");
+            std::thread::sleep(Duration::from_millis(20));
+        }
+        let m = app.model.as_ref().expect("fresh lists");
+        assert_eq!(m.search, "phi");
+        assert_eq!(m.current_entry().map(|e| e.id.as_str()), Some("phi3-mini"));
+        assert!(!screen(&app, 120, 20).contains("refreshing"));
+        assert_eq!(fake.calls().iter().filter(|c| *c == "models list --json").count(), 1);
+    }
+}
@@ -0,0 +1,205 @@
+//! Mouse: clicks and the wheel. Drawing records where the clickable things
+//! are (page bar entries, Welcome menu items, provider rows, form rows and
+//! buttons, README TOC entries) in a `HitMap`, cleared at the start of every
+//! frame; a click looks its cell up there. The wheel moves like ↑/↓.
+//! While a dialog or overlay is open clicks are ignored, so nothing under
+//! it is pressed by accident.
+
+use std::cell::RefCell;
+
+use crossterm::event::{KeyCode, KeyEvent, KeyModifiers, MouseButton, MouseEvent, MouseEventKind};
+use ratatui::layout::Rect;
+
+use crate::app::{App, Page};
+
+/// What a click on a region does.
+#[derive(Copy, Clone, Debug, PartialEq, Eq)]
+pub enum Target {
+    /// An entry of the narrow header's page bar
+    Page(Page),
+    /// A Welcome menu item
+    MenuItem(usize),
+    /// A row of the Configure list; `entries.len()` is the add row
+    Provider(usize),
+    /// A row of the Configure form, by its `selected` index: Type, a
+    /// field, Advanced, or the Test/Save/Cancel buttons
+    FormRow(usize),
+    /// A README TOC entry
+    TocEntry(usize),
+}
+
+/// Clickable regions of the last frame. Drawing only borrows the app, so
+/// regions are added through a `RefCell`.
+#[derive(Default)]
+pub struct HitMap {
+    regions: RefCell<Vec<(Rect, Target)>>,
+}
+
+impl HitMap {
+    pub fn clear(&self) {
+        self.regions.borrow_mut().clear();
+    }
+
+    pub fn add(&self, area: Rect, target: Target) {
+        if area.width > 0 && area.height > 0 { self.regions.borrow_mut().push((area, target)); }
+    }
+
+    /// Rows of a list drawn inside `inner`, from item `offset` on, each
+    /// `height(i)` lines tall; rows past the bottom are not clickable.
+    pub fn rows(&self, inner: Rect, offset: usize, count: usize, height: impl Fn(usize) -> u16, target: impl Fn(usize) -> Target) {
+        let mut y = inner.y;
+        for i in offset..count {
+            let bottom = inner.y + inner.height;
+            if y >= bottom { break; }
+            let h = height(i).min(bottom - y);
+            self.add(Rect::new(inner.x, y, inner.width, h), target(i));
+            y += h;
+        }
+    }
+
+    /// The region under a cell; the one added last wins, as it was drawn on top.
+    pub fn at(&self, x: u16, y: u16) -> Option<Target> {
+        let regions = self.regions.borrow();
+        regions.iter().rev().find(|(r, _)| x >= r.x && x < r.x + r.width && y >= r.y && y < r.y + r.height).map(|(_, t)| *t)
+    }
+}
+
+/// An overlay or dialog has the keys; the regions under it are covered.
+fn overlay_open(app: &App) -> bool {
+    app.show_help
+        || app.confirm.is_some()
+        || app.shutdown.is_some()
+        || app.theme_picker.is_some()
+        || app.default_models.is_some()
+        || app.hf_token_input.is_some()
+        || app.profiles.is_some()
+        || (app.page == Page::Configure && app.inspector.visible)
+        || (app.page == Page::Readme && app.readme.as_ref().map_or(false, |rm| rm.link_picker.is_some()))
+        || app.providers.as_ref().map_or(false, |st| st.dropdown.is_some() || st.import.is_some() || st.qr.is_some() || st.export.is_some() || st.paste_input.is_some())
+}
+
+fn press(app: &mut App, code: KeyCode) {
+    crate::keys::handle_key(app, KeyEvent::new(code, KeyModifiers::NONE));
+}
+
+pub fn handle_mouse(app: &mut App, ev: MouseEvent) {
+    match ev.kind {
+        MouseEventKind::ScrollUp => press(app, KeyCode::Up),
+        MouseEventKind::ScrollDown => press(app, KeyCode::Down),
+        MouseEventKind::Down(MouseButton::Left) if !overlay_open(app) => {
+            if let Some(target) = app.hits.at(ev.column, ev.row) { click(app, target); }
+        }
+        _ => {}
+    }
+}
+
+/// Items and buttons act on the first click; list rows and form fields are
+/// selected first and act (Enter) when clicked again.
+pub fn click(app: &mut App, target: Target) {
+    match target {
+        Target::Page(page) => crate::open_page(app, page),
+        Target::MenuItem(i) => {
+            app.menu_idx = i;
+            press(app, KeyCode::Enter);
+        }
+        Target::Provider(i) => {
+            let Some(st) = app.providers.as_mut() else { return };
+            let again = !st.focus_right && st.selected == i;
+            st.focus_right = false;
+            if let Some(form) = st.form.as_mut() { form.editing = false; }
+            if st.selected != i {
+                st.selected = i;
+                st.form = None;
+            }
+            if again { press(app, KeyCode::Enter); }
+        }
+        Target::FormRow(i) => {
+            let Some(st) = app.providers.as_mut() else { return };
+            if st.form.is_none() && st.selected < st.entries.len() { crate::keys::ensure_form_for_selected(st); }
+            let focused = st.focus_right;
+            st.focus_right = true;
+            let Some(form) = st.form.as_mut() else { return };
+            let button = i >= form.test_idx() || Some(i) == form.expander_idx();
+            let again = focused && form.selected == i;
+            if form.selected != i { form.editing = false; }
+            form.selected = i;
+            // A second click on a field being edited leaves it as it is
+            if button || (again && !form.editing) { press(app, KeyCode::Enter); }
+        }
+        Target::TocEntry(i) => {
+            let Some(rm) = app.readme.as_mut() else { return };
+            rm.focus_toc = true;
+            rm.toc_selected = i;
+            press(app, KeyCode::Enter);
+        }
+    }
+}
+
+#[cfg(all(test, unix))]
+mod tests {
+    use super::*;
+    use crate::config_cli::run_config;
+    use crate::testing::{add_provider, screen, FakeCli};
+
+    #[test]
+    fn clicks_open_menu_items_select_providers_press_buttons_and_follow_the_toc() {
+        let _fake = FakeCli::new();
+        run_config(add_provider("ollama", "home", &[], true)).expect("add home");
+        run_config(add_provider("ollama", "work", &[], false)).expect("add work");
+        let mouse = |app: &mut App, kind: MouseEventKind, (column, row): (u16, u16)| {
+            handle_mouse(app, MouseEvent { kind, column, row, modifiers: KeyModifiers::NONE });
+        };
+        // The first cell of `needle` in a `w`x30 frame
+        let find = |app: &App, w: u16, needle: &str| -> (u16, u16) {
+            let text = screen(app, w, 30);
+            let (row, line) = text.lines().enumerate().find(|(_, l)| l.contains(needle)).unwrap_or_else(|| panic!("{:?} not on screen:\n{}", needle, text));
+            (line[..line.find(needle).unwrap_or(0)].chars().count() as u16, row as u16)
+        };
+        let click = |app: &mut App, w: u16, needle: &str| {
+            let at = find(app, w, needle);
+            mouse(app, MouseEventKind::Down(MouseButton::Left), at);
+        };
+        let mut app = App::new(false);
+        click(&mut app, 100, "Configure Providers");
+        assert_eq!(app.page, Page::Configure);
+        if let Some(st) = app.providers.as_mut() { st.focus_right = false; }
+
+        // A provider row is selected by the first click and opened by the second
+        let home = find(&app, 100, "home [ollama]");
+        click(&mut app, 100, "work [ollama]");
+        let st = app.providers.as_ref().expect("providers");
+        assert_eq!((st.entries[st.selected].name.as_str(), st.focus_right), ("work", false));
+        click(&mut app, 100, "work [ollama]");
+        assert!(app.providers.as_ref().map_or(false, |st| st.focus_right && st.form.is_some()));
+
+        // Buttons act on the first click
+        click(&mut app, 100, "[ Cancel ]");
+        assert!(!app.providers.as_ref().expect("providers").focus_right);
+        click(&mut app, 100, "[ Save ]");
+        let form = app.providers.as_ref().and_then(|st| st.form.as_ref()).expect("form");
+        assert!(form.selected == form.save_idx() && form.message.is_some());
+
+        // The Type row opens its dropdown on the second click; the dropdown covers the list
+        click(&mut app, 100, "Type: ollama");
+        assert!(app.providers.as_ref().map_or(false, |st| st.dropdown.is_none()));
+        click(&mut app, 100, "Type: ollama");
+        assert!(app.providers.as_ref().map_or(false, |st| st.dropdown.is_some()));
+        mouse(&mut app, MouseEventKind::Down(MouseButton::Left), home);
+        assert_eq!(app.providers.as_ref().map(|st| st.entries[st.selected].name.as_str()), Some("work"));
+
+        // README: a TOC entry jumps to its section; the wheel scrolls the text
+        crate::open_page_loaded(&mut app, Page::Readme);
+        crate::keys::handle_key(&mut app, KeyEvent::new(KeyCode::Char('h'), KeyModifiers::NONE));
+        let entry = app.readme.as_ref().and_then(|rm| rm.toc.get(1).cloned()).expect("README with two headings");
+        click(&mut app, 100, &format!("- {}", entry.title));
+        let rm = app.readme.as_ref().expect("readme");
+        assert_eq!((rm.scroll, rm.focus_toc), (entry.line, false));
+        mouse(&mut app, MouseEventKind::ScrollDown, (60, 15));
+        assert_eq!(app.readme.as_ref().map(|rm| rm.scroll), Some(entry.line + 1));
+
+        // Narrow terminals: the page bar switches pages
+        app.fit(70, 30);
+        click(&mut app, 70, "2 Configure");
+        assert_eq!(app.page, Page::Configure);
+    }
+}
@@ -0,0 +1,102 @@
+//! Ollama's own API for the Model Browser: `/api/tags` lists what a
+//! configured Ollama server has installed, `/api/show` describes one model
+//! (parameter size, quantization, family, context length, template).
+
+use std::time::Duration;
+
+use anyhow::{anyhow, Result};
+use serde_json::Value;
+
+use crate::http::local_api;
+use crate::providers::ProviderScratchEntry;
+
+/// What `/api/show` says about a model; fields Ollama leaves out are None.
+#[derive(Clone, Debug, Default, PartialEq, Eq)]
+pub struct ModelDetails {
+    pub parameter_size: Option<String>,
+    pub quantization: Option<String>,
+    pub family: Option<String>,
+    pub context_length: Option<u64>,
+    pub template: Option<String>,
+}
+
+/// Installed models as (name, size in bytes), from `/api/tags`.
+pub fn installed(entry: &ProviderScratchEntry, timeout: Duration) -> Result<Vec<(String, u64)>> {
+    let v = local_api(entry, "GET", "/api/tags", None, timeout)?;
+    let models = v.get("models").and_then(|m| m.as_array()).ok_or_else(|| anyhow!("/api/tags: no model list"))?;
+    Ok(models
+        .iter()
+        .filter_map(|m| {
+            let name = m.get("name").or_else(|| m.get("model")).and_then(|x| x.as_str())?;
+            Some((name.to_string(), m.get("size").and_then(|x| x.as_u64()).unwrap_or(0)))
+        })
+        .collect())
+}
+
+/// `/api/show` for `model`.
+pub fn show(entry: &ProviderScratchEntry, model: &str, timeout: Duration) -> Result<ModelDetails> {
+    let v = local_api(entry, "POST", "/api/show", Some(serde_json::json!({"model": model})), timeout)?;
+    Ok(parse_show(&v))
+}
+
+pub fn parse_show(v: &Value) -> ModelDetails {
+    let details = v.get("details");
+    let text = |x: Option<&Value>| x.and_then(|s| s.as_str()).map(|s| s.trim().to_string()).filter(|s| !s.is_empty());
+    // model_info keys are prefixed by architecture: "llama.context_length"
+    let context_length = v
+        .get("model_info")
+        .and_then(|m| m.as_object())
+        .and_then(|m| m.iter().find(|(k, _)| k.ends_with(".context_length")).and_then(|(_, n)| n.as_u64()));
+    ModelDetails {
+        parameter_size: text(details.and_then(|d| d.get("parameter_size"))),
+        quantization: text(details.and_then(|d| d.get("quantization_level"))),
+        family: text(details.and_then(|d| d.get("family"))),
+        context_length,
+        template: text(v.get("template")),
+    }
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+    use serde_json::json;
+
+    #[test]
+    fn show_reads_size_quantization_family_and_context() {
+        let v = json!({
+            "modelfile": "FROM qwen2.5:7b",
+            "template": "{{ .System }}\n{{ .Prompt }}",
+            "details": {"format": "gguf", "family": "qwen2", "families": ["qwen2"], "parameter_size": "7.6B", "quantization_level": "Q4_K_M"},
+            "model_info": {"general.architecture": "qwen2", "qwen2.block_count": 28, "qwen2.context_length": 32768},
+        });
+        assert_eq!(
+            parse_show(&v),
+            ModelDetails {
+                parameter_size: Some("7.6B".to_string()),
+                quantization: Some("Q4_K_M".to_string()),
+                family: Some("qwen2".to_string()),
+                context_length: Some(32768),
+                template: Some("{{ .System }}\n{{ .Prompt }}".to_string()),
+            }
+        );
+    }
+
+    #[test]
+    fn missing_and_blank_fields_are_none() {
+        assert_eq!(parse_show(&json!({})), ModelDetails::default());
+        let v = json!({"template": "  ", "details": {"family": "", "parameter_size": 7}, "model_info": {"llama.context_length": "long"}});
+        assert_eq!(parse_show(&v), ModelDetails::default());
+    }
+
+    #[cfg(unix)]
+    #[test]
+    fn installed_lists_names_and_sizes() {
+        let _fake = crate::testing::FakeCli::new();
+        let tags = json!({"models": [{"name": "qwen2.5:7b", "size": 4683087332u64}, {"model": "phi3:mini"}, {"size": 1}]});
+        let url = crate::testing::http_server("200 OK", "application/json", &tags.to_string());
+        let port = url.rsplit(':').next().expect("port");
+        let entry = crate::testing::entry("ollama", "ollama", json!({"host": "127.0.0.1", "port": port}));
+        let models = installed(&entry, Duration::from_secs(5)).expect("tags");
+        assert_eq!(models, vec![("qwen2.5:7b".to_string(), 4683087332), ("phi3:mini".to_string(), 0)]);
+    }
+}
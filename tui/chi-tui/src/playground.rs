@@ -0,0 +1,237 @@
+use std::fs;
+use std::path::PathBuf;
+use std::time::Duration;
+
+use anyhow::{anyhow, Result};
+use ratatui::layout::{Constraint, Direction, Layout, Rect};
+use ratatui::prelude::Frame;
+use ratatui::style::{Modifier, Style};
+use ratatui::text::{Line, Span};
+use ratatui::widgets::{Block, Borders, Paragraph, Wrap};
+use serde_json::{Map, Value};
+
+use crate::app::App;
+use crate::http;
+use crate::inspector;
+use crate::locale;
+use crate::providers::{read_scratch_entries, ProviderScratchEntry};
+use crate::util::fnv1a;
+
+const CHAT_TIMEOUT: Duration = Duration::from_secs(60);
+
+#[derive(Clone, Debug)]
+pub struct PlaygroundResponse {
+    pub text: String,
+    /// Set when served from the cache: when the response was first stored.
+    pub cached_at: Option<String>,
+    pub elapsed_ms: Option<u128>,
+}
+
+#[derive(Clone, Debug, Default)]
+pub struct PlaygroundState {
+    pub entries: Vec<ProviderScratchEntry>, // providers with an HTTP chat API only
+    pub provider_idx: usize,
+    pub prompt: String,
+    pub response: Option<PlaygroundResponse>,
+    pub status: Option<String>,
+}
+
+pub fn load_playground() -> PlaygroundState {
+    match read_scratch_entries() {
+        Ok(entries) => {
+            let entries: Vec<_> = entries.into_iter().filter(inspector::supports_chat).collect();
+            let status = if entries.is_empty() { Some("No openai/openai-compatible/anthropic/lmstudio/ollama providers configured".to_string()) } else { None };
+            PlaygroundState { entries, status, ..Default::default() }
+        }
+        Err(e) => PlaygroundState { status: Some(format!("Error: {}", e)), ..Default::default() },
+    }
+}
+
+fn model_of(entry: &ProviderScratchEntry) -> String {
+    entry.config.get("model").and_then(|v| v.as_str()).unwrap_or("").trim().to_string()
+}
+
+fn cache_path() -> Result<PathBuf> {
+    let home = dirs::home_dir().ok_or_else(|| anyhow!("home dir not found"))?;
+    Ok(home.join(".cache").join("chi_llm").join("playground_cache.json"))
+}
+
+/// Cache key over provider, model and prompt; fields are separated by a
+/// control character so "a"+"bc" and "ab"+"c" differ.
+pub fn cache_key(provider_id: &str, model: &str, prompt: &str) -> String {
+    format!("{:016x}", fnv1a(&format!("{}\u{1f}{}\u{1f}{}", provider_id, model, prompt)))
+}
+
+fn load_cache() -> Map<String, Value> {
+    cache_path()
+        .ok()
+        .and_then(|p| fs::read_to_string(p).ok())
+        .and_then(|t| serde_json::from_str::<Value>(&t).ok())
+        .and_then(|v| v.as_object().cloned())
+        .unwrap_or_default()
+}
+
+fn store_cache(key: &str, entry: Value) -> Result<()> {
+    let path = cache_path()?;
+    if let Some(dir) = path.parent() { fs::create_dir_all(dir)?; }
+    let mut map = load_cache();
+    map.insert(key.to_string(), entry);
+    fs::write(path, serde_json::to_vec_pretty(&Value::Object(map))?)?;
+    Ok(())
+}
+
+impl PlaygroundState {
+    pub fn current(&self) -> Option<&ProviderScratchEntry> {
+        self.entries.get(self.provider_idx)
+    }
+    pub fn next_provider(&mut self) {
+        if !self.entries.is_empty() {
+            self.provider_idx = (self.provider_idx + 1) % self.entries.len();
+            self.response = None;
+        }
+    }
+}
+
+/// Send the prompt to the selected provider. Unless `force` is set, a cached
+/// response for the same provider+model+prompt is shown instead of spending
+/// tokens again.
+pub fn run(app: &mut App, force: bool) {
+    let Some(st) = app.playground.as_mut() else { return };
+    let Some(entry) = st.current().cloned() else { return };
+    let prompt = st.prompt.trim().to_string();
+    if prompt.is_empty() { st.status = Some("Type a prompt first".to_string()); return; }
+    let model = model_of(&entry);
+    if model.is_empty() { st.status = Some(format!("Error: set a model on provider {}", entry.id)); return; }
+    let key = cache_key(&entry.id, &model, &prompt);
+    if !force {
+        if let Some(hit) = load_cache().get(&key) {
+            st.response = Some(PlaygroundResponse {
+                text: hit.get("response").and_then(|v| v.as_str()).unwrap_or("").to_string(),
+                cached_at: Some(hit.get("timestamp").and_then(|v| v.as_str()).unwrap_or("?").to_string()),
+                elapsed_ms: None,
+            });
+            st.status = Some("Served from cache (F5 to re-run)".to_string());
+            return;
+        }
+    }
+    let Some(ex) = inspector::chat(&http::Client::for_provider(&entry), &entry, &prompt, None, CHAT_TIMEOUT) else { return };
+    match (ex.ok(), inspector::completion_text(&ex)) {
+        (true, Some(text)) => {
+            let stored = serde_json::json!({
+                "provider": entry.id,
+                "model": model,
+                "prompt": prompt,
+                "response": text,
+                "timestamp": chrono::Utc::now().format("%Y-%m-%d %H:%M:%S UTC").to_string(),
+            });
+            st.status = match store_cache(&key, stored) {
+                Ok(()) => None,
+                Err(e) => Some(format!("Warning: cache not written: {}", e)),
+            };
+            st.response = Some(PlaygroundResponse { text, cached_at: None, elapsed_ms: Some(ex.elapsed.as_millis()) });
+        }
+        _ => {
+            let why = ex.error.clone().unwrap_or_else(|| format!("HTTP {}", ex.status.unwrap_or(0)));
+            st.status = Some(format!("Error: {} (F2 to inspect)", why));
+            st.response = None;
+        }
+    }
+    app.inspector.last = Some(ex);
+}
+
+pub fn draw_playground(f: &mut Frame, area: Rect, app: &App) {
+    let chunks = Layout::default()
+        .direction(Direction::Vertical)
+        .constraints([Constraint::Length(3), Constraint::Length(6), Constraint::Min(3)])
+        .split(area);
+    let frame_style = Style::default().fg(app.theme.frame);
+    let Some(st) = &app.playground else {
+        f.render_widget(Paragraph::new("Loading providers...").block(Block::default().borders(Borders::ALL)), area);
+        return;
+    };
+
+    let provider_line = match st.current() {
+        Some(e) => {
+            let model = model_of(e);
+            Line::from(vec![
+                Span::styled(format!("{} ", e.id), Style::default().fg(app.theme.selected).add_modifier(Modifier::BOLD)),
+                Span::styled(format!("({}, model: {})", e.ptype, if model.is_empty() { "—" } else { model.as_str() }), Style::default().fg(app.theme.secondary)),
+                Span::styled(format!("   {}/{}  Tab next", st.provider_idx + 1, st.entries.len()), Style::default().fg(app.theme.frame)),
+            ])
+        }
+        None => Line::from("No provider"),
+    };
+    f.render_widget(Paragraph::new(provider_line).block(Block::default().borders(Borders::ALL).border_style(frame_style).title("Provider")), chunks[0]);
+
+    let prompt = Paragraph::new(format!("{}▏", st.prompt))
+        .style(Style::default().bg(app.theme.bg).fg(app.theme.fg))
+        .block(Block::default().borders(Borders::ALL).border_style(Style::default().fg(app.theme.selected)).title("Prompt"))
+        .wrap(Wrap { trim: false });
+    f.render_widget(prompt, chunks[1]);
+
+    let mut title: Vec<Span> = vec![Span::raw("Response ")];
+    let mut lines: Vec<Line> = Vec::new();
+    if let Some(r) = &st.response {
+        match (&r.cached_at, r.elapsed_ms) {
+            (Some(ts), _) => title.push(Span::styled(format!("[cached {}]", locale::ago_utc(ts, "%Y-%m-%d %H:%M:%S UTC").unwrap_or_else(|| ts.clone())), Style::default().fg(app.theme.warn).add_modifier(Modifier::BOLD))),
+            (None, Some(ms)) => title.push(Span::styled(format!("[live {} ms]", ms), Style::default().fg(app.theme.ok))),
+            _ => {}
+        }
+        for l in r.text.lines() { lines.push(Line::from(l.to_string())); }
+    }
+    if let Some(msg) = &st.status {
+        if !lines.is_empty() { lines.push(Line::from("")); }
+        let (txt, style) = app.theme.status_text(msg);
+        lines.push(Line::from(Span::styled(txt, style)));
+    }
+    let p = Paragraph::new(lines)
+        .style(Style::default().bg(app.theme.bg).fg(app.theme.fg))
+        .block(Block::default().borders(Borders::ALL).border_style(frame_style).title(Line::from(title)))
+        .wrap(Wrap { trim: false });
+    f.render_widget(p, chunks[2]);
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+
+    #[test]
+    fn cache_keys_separate_their_fields() {
+        assert_ne!(cache_key("a", "bc", "p"), cache_key("ab", "c", "p"));
+        assert_eq!(cache_key("a", "m", "p"), cache_key("a", "m", "p"));
+        assert_eq!(cache_key("a", "m", "p").len(), 16);
+    }
+
+    #[cfg(unix)]
+    #[test]
+    fn a_cached_prompt_is_served_without_a_request() {
+        let mut fake = crate::testing::FakeCli::new();
+        fake.set_env("CHI_TUI_MOCK", "on".to_string());
+        crate::store::write(&crate::mock::providers()).expect("store");
+        let mut app = App::new(false);
+        app.playground = Some(load_playground());
+        let st = app.playground.as_mut().expect("playground");
+        let ids: Vec<&str> = st.entries.iter().map(|e| e.id.as_str()).collect();
+        assert!(!ids.contains(&"local"), "{:?}", ids);
+        st.provider_idx = ids.iter().position(|id| *id == "home-ollama").expect("ollama");
+
+        run(&mut app, false);
+        assert_eq!(app.playground.as_ref().and_then(|st| st.status.as_deref()), Some("Type a prompt first"));
+
+        app.playground.as_mut().expect("playground").prompt = "Say hi".to_string();
+        let key = cache_key("home-ollama", "qwen2.5-coder:7b", "Say hi");
+        store_cache(&key, serde_json::json!({"response": "hi!", "timestamp": "2026-10-16 10:00:00 UTC"})).expect("cache");
+        run(&mut app, false);
+        let st = app.playground.as_ref().expect("playground");
+        let resp = st.response.as_ref().expect("cached response");
+        assert_eq!((resp.text.as_str(), resp.cached_at.as_deref()), ("hi!", Some("2026-10-16 10:00:00 UTC")));
+        assert!(app.inspector.last.is_none());
+
+        // F5: asked again; the mock server has no chat endpoint
+        run(&mut app, true);
+        let st = app.playground.as_ref().expect("playground");
+        assert!(st.response.is_none());
+        assert_eq!(st.status.as_deref(), Some("Error: HTTP 404 (F2 to inspect)"));
+        assert!(app.inspector.last.is_some());
+    }
+}
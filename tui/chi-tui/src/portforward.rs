@@ -0,0 +1,196 @@
+use std::collections::HashMap;
+use std::net::{SocketAddr, TcpStream};
+use std::process::{Child, Command, Stdio};
+use std::time::{Duration, Instant};
+
+use anyhow::{anyhow, Result};
+
+use crate::log;
+use crate::providers::{FieldSchema, ProviderScratchEntry};
+
+/// TUI-only provider fields; stripped when writing the active config.
+pub const K8S_FIELD_PREFIX: &str = "k8s_";
+
+/// Extra form fields for server providers reachable via `kubectl port-forward`.
+pub fn k8s_fields() -> Vec<FieldSchema> {
+    let f = |name: &str, help: &str| FieldSchema {
+        name: name.to_string(),
+        ftype: "string".to_string(),
+        required: false,
+        default: None,
+        help: Some(help.to_string()),
+        options: None,
+        advanced: true,
+        min: None,
+        max: None,
+        step: None,
+    };
+    vec![
+        f("k8s_service", "kubectl port-forward: service name (empty = disabled)"),
+        f("k8s_namespace", "kubectl port-forward: namespace"),
+        f("k8s_context", "kubectl port-forward: kube context"),
+        f("k8s_remote_port", "kubectl port-forward: service port (defaults to port)"),
+    ]
+}
+
+struct Forward {
+    child: Child,
+    local_port: u16,
+}
+
+/// Port-forward processes owned by this TUI session, keyed by provider id.
+/// All children are killed when the TUI exits.
+#[derive(Default)]
+pub struct PortForwards {
+    procs: HashMap<String, Forward>,
+    labels: HashMap<String, String>,
+}
+
+fn cfg_str(entry: &ProviderScratchEntry, key: &str) -> String {
+    match entry.config.get(key) {
+        Some(serde_json::Value::String(s)) => s.trim().to_string(),
+        Some(serde_json::Value::Number(n)) => n.to_string(),
+        _ => String::new(),
+    }
+}
+
+fn wait_until_listening(port: u16, timeout: Duration) -> bool {
+    let addr = SocketAddr::from(([127, 0, 0, 1], port));
+    let start = Instant::now();
+    while start.elapsed() < timeout {
+        if TcpStream::connect_timeout(&addr, Duration::from_millis(200)).is_ok() { return true; }
+        std::thread::sleep(Duration::from_millis(100));
+    }
+    false
+}
+
+impl PortForwards {
+    /// Start (or reuse) a port-forward for `entry` when `k8s_service` is set.
+    /// Returns the local port, or None when the provider is not k8s-backed.
+    pub fn ensure(&mut self, entry: &ProviderScratchEntry) -> Result<Option<u16>> {
+        let service = cfg_str(entry, "k8s_service");
+        if service.is_empty() { return Ok(None); }
+        if let Some(fw) = self.procs.get_mut(&entry.id) {
+            if fw.child.try_wait()?.is_none() { return Ok(Some(fw.local_port)); }
+        }
+        self.procs.remove(&entry.id);
+        let local_port: u16 = cfg_str(entry, "port").parse().map_err(|_| anyhow!("port-forward needs a numeric port"))?;
+        let remote = cfg_str(entry, "k8s_remote_port");
+        let remote_port = if remote.is_empty() { local_port.to_string() } else { remote };
+        let svc = if service.contains('/') { service.clone() } else { format!("svc/{}", service) };
+        let mut cmd = Command::new("kubectl");
+        let ctx = cfg_str(entry, "k8s_context");
+        if !ctx.is_empty() { cmd.arg("--context").arg(&ctx); }
+        let ns = cfg_str(entry, "k8s_namespace");
+        if !ns.is_empty() { cmd.arg("--namespace").arg(&ns); }
+        cmd.arg("port-forward").arg(&svc).arg(format!("{}:{}", local_port, remote_port));
+        cmd.stdin(Stdio::null()).stdout(Stdio::null()).stderr(Stdio::null());
+        let child = cmd.spawn().map_err(|e| anyhow!("kubectl not available: {}", e))?;
+        log::info(&format!("port-forward {} started for {} on :{}", svc, entry.id, local_port));
+        self.procs.insert(entry.id.clone(), Forward { child, local_port });
+        self.refresh();
+        if !wait_until_listening(local_port, Duration::from_secs(3)) {
+            return Err(anyhow!("port-forward to {} not ready on :{}", svc, local_port));
+        }
+        Ok(Some(local_port))
+    }
+
+    /// Poll child processes and refresh list labels. Returns true when any
+    /// label changed (so the caller can redraw).
+    pub fn refresh(&mut self) -> bool {
+        let mut changed = false;
+        for (id, fw) in self.procs.iter_mut() {
+            let label = match fw.child.try_wait() {
+                Ok(None) => format!("pf:running:{}", fw.local_port),
+                Ok(Some(st)) => format!("pf:exited({})", st.code().map(|c| c.to_string()).unwrap_or_else(|| "signal".to_string())),
+                Err(_) => "pf:unknown".to_string(),
+            };
+            if self.labels.get(id) != Some(&label) {
+                self.labels.insert(id.clone(), label);
+                changed = true;
+            }
+        }
+        changed
+    }
+
+    /// Short label for lists, e.g. "pf:running:11434" or "pf:exited(1)".
+    pub fn label(&self, id: &str) -> Option<&str> {
+        self.labels.get(id).map(|s| s.as_str())
+    }
+
+    /// Port-forwards still running (as of the last refresh).
+    pub fn running_count(&self) -> usize {
+        self.labels.values().filter(|l| l.starts_with("pf:running")).count()
+    }
+
+    pub fn stop_all(&mut self) {
+        for (_, mut fw) in self.procs.drain() {
+            let _ = fw.child.kill();
+            let _ = fw.child.wait();
+        }
+    }
+}
+
+impl Drop for PortForwards {
+    fn drop(&mut self) {
+        self.stop_all();
+    }
+}
+
+#[cfg(all(test, unix))]
+mod tests {
+    use std::net::TcpListener;
+
+    use super::*;
+
+    fn entry(config: serde_json::Value) -> ProviderScratchEntry {
+        ProviderScratchEntry {
+            id: "cluster".into(),
+            name: "cluster".into(),
+            ptype: "ollama".into(),
+            tags: Vec::new(),
+            config,
+            extra: Default::default(),
+            scope: Default::default(),
+        }
+    }
+
+    #[test]
+    fn providers_without_a_service_need_no_forward() {
+        let mut pf = PortForwards::default();
+        assert_eq!(pf.ensure(&entry(serde_json::json!({"port": 11434}))).expect("no service"), None);
+        assert!(pf.ensure(&entry(serde_json::json!({"k8s_service": "ollama", "port": "x"}))).is_err());
+        assert_eq!(pf.running_count(), 0);
+    }
+
+    #[test]
+    fn a_forward_is_started_once_and_stopped() {
+        let fake = crate::testing::FakeCli::new();
+        let args = fake.root.join("kubectl-args");
+        fake.tool("kubectl", &format!("echo \"$*\" >> '{}'\nexec sleep 30", args.display()));
+        // Stands in for the forwarded port kubectl would open
+        let listener = TcpListener::bind(("127.0.0.1", 0)).expect("bind");
+        let port = listener.local_addr().expect("addr").port();
+        let e = entry(serde_json::json!({"k8s_service": "ollama", "k8s_namespace": "ml", "k8s_remote_port": 11434, "port": port}));
+
+        let mut pf = PortForwards::default();
+        assert_eq!(pf.ensure(&e).expect("start"), Some(port));
+        assert_eq!(pf.ensure(&e).expect("reuse"), Some(port));
+        // The port is already open, so kubectl may not have run yet
+        let started = Instant::now();
+        while fs_lines(&args).is_empty() && started.elapsed() < Duration::from_secs(2) {
+            std::thread::sleep(Duration::from_millis(10));
+        }
+        let calls = fs_lines(&args);
+        assert_eq!(calls, [format!("--namespace ml port-forward svc/ollama {}:11434", port)]);
+        assert_eq!(pf.label("cluster"), Some(format!("pf:running:{}", port).as_str()));
+        assert_eq!(pf.running_count(), 1);
+
+        pf.stop_all();
+        assert!(pf.procs.is_empty());
+    }
+
+    fn fs_lines(path: &std::path::Path) -> Vec<String> {
+        std::fs::read_to_string(path).unwrap_or_default().lines().map(|l| l.to_string()).collect()
+    }
+}
@@ -0,0 +1,130 @@
+//! User settings shared by every project, in `chi_llm/tui.json` in the user
+//! config dir: animation, theme, color-blind palette, the config format new
+//! projects start with, where Build writes by default, the discovery
+//! timeout and how long discovery results stay cached. Read at startup and whenever a setting
+//! is needed, so an edit to the file applies on the next use. Settings
+//! that belong to one project (density, prefer private, .env sync) stay in
+//! its store.
+
+use std::path::PathBuf;
+use std::time::Duration;
+
+use anyhow::{anyhow, Result};
+use serde_json::{json, Value};
+
+use crate::build::BuildTarget;
+use crate::store::{self, Format};
+
+/// Discovery timeouts `o` cycles through, in seconds.
+pub const DISCOVERY_TIMEOUTS: [u64; 4] = [2, 5, 10, 30];
+/// Cache lifetimes `l` cycles through, in seconds; `None` keeps each
+/// kind's own (catalog 10m, listings 5m, Ollama 2m).
+pub const CACHE_TTLS: [Option<u64>; 4] = [None, Some(60), Some(600), Some(3600)];
+
+#[derive(Clone, Debug, PartialEq, Eq)]
+pub struct Prefs {
+    pub animation: bool,
+    /// `dark`, `light` or a custom theme's name
+    pub theme: String,
+    pub colorblind: bool,
+    pub config_format: Format,
+    pub build_target: BuildTarget,
+    pub discovery_timeout: Duration,
+    /// Overrides the lifetime of cached catalogs and model listings
+    pub cache_ttl: Option<Duration>,
+}
+
+impl Default for Prefs {
+    fn default() -> Self {
+        Prefs {
+            animation: true,
+            theme: "dark".to_string(),
+            colorblind: false,
+            config_format: Format::Json,
+            build_target: BuildTarget::Project,
+            discovery_timeout: Duration::from_secs(5),
+            cache_ttl: None,
+        }
+    }
+}
+
+impl Prefs {
+    /// Missing or mistyped keys keep their defaults.
+    pub fn from_json(v: &Value) -> Prefs {
+        let d = Prefs::default();
+        let secs = |key: &str| v.get(key).and_then(|x| x.as_u64()).filter(|s| *s > 0).map(Duration::from_secs);
+        Prefs {
+            animation: v.get("animation").and_then(|x| x.as_bool()).unwrap_or(d.animation),
+            theme: v.get("theme").and_then(|x| x.as_str()).map(str::trim).filter(|t| !t.is_empty()).map_or(d.theme, str::to_string),
+            colorblind: v.get("colorblind").and_then(|x| x.as_bool()).unwrap_or(d.colorblind),
+            config_format: v.get("config_format").and_then(|x| x.as_str()).map_or(d.config_format, Format::from_key),
+            build_target: match v.get("build_target").and_then(|x| x.as_str()) {
+                Some("global") => BuildTarget::Global,
+                _ => d.build_target,
+            },
+            discovery_timeout: secs("discovery_timeout_secs").unwrap_or(d.discovery_timeout),
+            cache_ttl: secs("cache_ttl_secs"),
+        }
+    }
+
+    pub fn to_json(&self) -> Value {
+        json!({
+            "animation": self.animation,
+            "theme": self.theme,
+            "colorblind": self.colorblind,
+            "config_format": self.config_format.key(),
+            "build_target": match self.build_target {
+                BuildTarget::Project => "project",
+                BuildTarget::Global => "global",
+            },
+            "discovery_timeout_secs": self.discovery_timeout.as_secs(),
+            "cache_ttl_secs": self.cache_ttl.map(|d| d.as_secs()),
+        })
+    }
+
+    pub fn next_discovery_timeout(&self) -> Duration {
+        let now = self.discovery_timeout.as_secs();
+        let next = DISCOVERY_TIMEOUTS.iter().find(|s| **s > now).unwrap_or(&DISCOVERY_TIMEOUTS[0]);
+        Duration::from_secs(*next)
+    }
+
+    pub fn next_cache_ttl(&self) -> Option<Duration> {
+        let now = self.cache_ttl.map(|d| d.as_secs());
+        let i = CACHE_TTLS.iter().position(|t| *t == now).map_or(0, |i| (i + 1) % CACHE_TTLS.len());
+        CACHE_TTLS[i].map(Duration::from_secs)
+    }
+}
+
+pub fn path() -> Result<PathBuf> {
+    let base = dirs::config_dir().ok_or_else(|| anyhow!("config dir not found"))?;
+    Ok(base.join("chi_llm").join("tui.json"))
+}
+
+/// The saved settings; defaults when the file is missing or unreadable.
+pub fn load() -> Prefs {
+    let Ok(path) = path() else { return Prefs::default() };
+    std::fs::read_to_string(path).ok().and_then(|t| store::parse(&t).ok()).map(|v| Prefs::from_json(&v)).unwrap_or_default()
+}
+
+/// Write `prefs`, keeping keys this version does not know. Returns the path.
+pub fn save(prefs: &Prefs) -> Result<String> {
+    let path = path()?;
+    let before: Value = std::fs::read_to_string(&path).ok().and_then(|t| store::parse(&t).ok()).filter(|v| v.is_object()).unwrap_or_else(|| json!({}));
+    let mut root = before.clone();
+    if let (Some(obj), Value::Object(new)) = (root.as_object_mut(), prefs.to_json()) {
+        for (k, v) in new {
+            if v.is_null() { obj.remove(&k); } else { obj.insert(k, v); }
+        }
+    }
+    if let Some(dir) = path.parent() { std::fs::create_dir_all(dir)?; }
+    std::fs::write(&path, store::to_text(&root, Format::Json)?)?;
+    let path = path.display().to_string();
+    let _ = crate::audit::record("settings.tui", &path, &before, &root);
+    Ok(path)
+}
+
+/// Compact duration for the Settings page: `90s`, `10m`, `1h`.
+pub fn short_duration(d: Duration) -> String {
+    let s = d.as_secs();
+    if s >= 3600 && s % 3600 == 0 { format!("{}h", s / 3600) } else if s >= 60 && s % 60 == 0 { format!("{}m", s / 60) } else { format!("{}s", s) }
+}
@@ -0,0 +1,120 @@
+use std::net::IpAddr;
+
+use anyhow::Result;
+use ratatui::style::Color;
+use serde_json::Value;
+
+use crate::store;
+use crate::theme::Theme;
+
+/// Where prompts sent to a provider end up. Declared per type by the CLI
+/// schema (`privacy`); network types are refined from their configured host.
+#[derive(Copy, Clone, Debug, PartialEq, Eq)]
+pub enum Privacy {
+    Local,
+    Lan,
+    CloudFree,
+    CloudPaid,
+    Unknown,
+}
+
+impl Privacy {
+    pub fn from_key(key: &str) -> Privacy {
+        match key {
+            "local" => Privacy::Local,
+            "lan" => Privacy::Lan,
+            "cloud-free" => Privacy::CloudFree,
+            "cloud-paid" => Privacy::CloudPaid,
+            _ => Privacy::Unknown,
+        }
+    }
+
+    pub fn label(self) -> &'static str {
+        match self {
+            Privacy::Local => "local/private",
+            Privacy::Lan => "LAN",
+            Privacy::CloudFree => "cloud/free-tier",
+            Privacy::CloudPaid => "cloud/paid",
+            Privacy::Unknown => "unknown",
+        }
+    }
+
+    /// Sort key for "prefer private": lower is more private.
+    pub fn rank(self) -> u8 {
+        match self {
+            Privacy::Local => 0,
+            Privacy::Lan => 1,
+            Privacy::CloudFree => 2,
+            Privacy::CloudPaid => 3,
+            Privacy::Unknown => 4,
+        }
+    }
+
+    pub fn color(self, theme: &Theme) -> Color {
+        match self {
+            Privacy::Local => theme.ok,
+            Privacy::Lan => theme.accent,
+            Privacy::CloudFree | Privacy::CloudPaid => theme.warn,
+            Privacy::Unknown => theme.frame,
+        }
+    }
+}
+
+/// Fallback when the schema has not been loaded (mirrors `SUPPORTED` in the CLI).
+pub fn builtin(ptype: &str) -> Privacy {
+    match ptype {
+        "local" | "local-zeroconfig" | "local-custom" | "lmstudio" | "ollama" => Privacy::Local,
+        "openai" | "openai-compatible" | "anthropic" | "claude-cli" | "openai-cli" => Privacy::CloudPaid,
+        "groq" | "gemini" => Privacy::CloudFree,
+        _ => Privacy::Unknown,
+    }
+}
+
+fn is_loopback(host: &str) -> bool {
+    matches!(host, "" | "localhost" | "127.0.0.1" | "::1" | "0.0.0.0")
+}
+
+/// RFC 1918 / link-local / ULA addresses and single-label or `.local` names.
+fn is_private(host: &str) -> bool {
+    match host.trim_matches(['[', ']']).parse::<IpAddr>() {
+        Ok(IpAddr::V4(ip)) => ip.is_private() || ip.is_link_local(),
+        Ok(IpAddr::V6(ip)) => (ip.segments()[0] & 0xfe00) == 0xfc00 || (ip.segments()[0] & 0xffc0) == 0xfe80,
+        Err(_) => !host.contains('.') || host.ends_with(".local") || host.ends_with(".lan"),
+    }
+}
+
+/// Classify a configured provider. A "local" server type pointed at another
+/// host is LAN; a Kubernetes port-forward still terminates in the cluster.
+/// Generic OpenAI-compatible endpoints are judged by their base URL.
+pub fn classify(ptype: &str, config: &Value, declared: Option<Privacy>) -> Privacy {
+    if ptype == "openai-compatible" {
+        let url = config.get("base_url").and_then(|v| v.as_str()).unwrap_or("").trim();
+        if !url.is_empty() && !url.contains("{{") {
+            let (host, _) = crate::health::parse_base_url(url);
+            if is_loopback(&host) || host == "[::1]" { return Privacy::Local; }
+            if is_private(&host) { return Privacy::Lan; }
+        }
+    }
+    let base = declared.unwrap_or_else(|| builtin(ptype));
+    if base != Privacy::Local { return base; }
+    let host = config.get("host").and_then(|v| v.as_str()).unwrap_or("").trim();
+    let k8s = config.get("k8s_service").and_then(|v| v.as_str()).map_or(false, |s| !s.trim().is_empty());
+    if k8s || !is_loopback(host) { Privacy::Lan } else { Privacy::Local }
+}
+
+/// The "prefer private" setting, stored as `prefer_private` in chi.tmp.json.
+pub fn load_prefer_private() -> bool {
+    store::read()
+        .ok()
+        .and_then(|v| v.get("prefer_private").and_then(|x| x.as_bool()))
+        .unwrap_or(false)
+}
+
+pub fn save_prefer_private(on: bool) -> Result<()> {
+    let mut root = store::read_or_empty();
+    let before = root.clone();
+    if let Some(obj) = root.as_object_mut() { obj.insert("prefer_private".to_string(), Value::Bool(on)); }
+    let path = store::write(&root)?;
+    let _ = crate::audit::record("settings.prefer_private", &path, &before, &root);
+    Ok(())
+}
@@ -0,0 +1,202 @@
+use std::net::{TcpStream, ToSocketAddrs};
+use std::time::{Duration, Instant};
+
+use anyhow::{anyhow, Result};
+
+use crate::health::{endpoint_of, ConnectionStatus, DnsError, ErrorCode};
+use crate::http::{provider_request, Client};
+use crate::inspector::api_base;
+use crate::providers::{read_scratch_entries, ProviderScratchEntry};
+
+/// Parse "5s", "500ms", "2m" or a bare number of seconds.
+pub fn parse_timeout(s: &str) -> Result<Duration> {
+    let s = s.trim();
+    let (num, unit) = match s.find(|c: char| !c.is_ascii_digit() && c != '.') {
+        Some(i) => (&s[..i], &s[i..]),
+        None => (s, "s"),
+    };
+    let n: f64 = num.parse().map_err(|_| anyhow!("invalid timeout \"{}\"", s))?;
+    let secs = match unit {
+        "ms" => n / 1000.0,
+        "s" => n,
+        "m" => n * 60.0,
+        _ => return Err(anyhow!("invalid timeout unit \"{}\" (use ms, s or m)", unit)),
+    };
+    if secs <= 0.0 { return Err(anyhow!("timeout must be positive")); }
+    Ok(Duration::from_secs_f64(secs))
+}
+
+/// Connect to the endpoint within `timeout`, trying every resolved address.
+fn connect(host: &str, port: u16, timeout: Duration) -> Result<Duration> {
+    let addrs: Vec<_> = (host, port).to_socket_addrs().map_err(|e| DnsError(format!("{}: {}", host, e)))?.collect();
+    if addrs.is_empty() { return Err(DnsError(host.to_string()).into()); }
+    let mut last = None;
+    for addr in addrs {
+        let start = Instant::now();
+        match TcpStream::connect_timeout(&addr, timeout) {
+            Ok(_) => return Ok(start.elapsed()),
+            Err(e) => last = Some(e),
+        }
+    }
+    Err(last.map(Into::into).unwrap_or_else(|| anyhow!("no address to connect to")))
+}
+
+/// `GET {base}/v1/models` must answer 2xx; for OpenAI-compatible servers and Anthropic.
+fn http_ready(entry: &ProviderScratchEntry, timeout: Duration, st: &mut ConnectionStatus) {
+    let Some(base) = api_base(entry) else {
+        st.code = Some(ErrorCode::Other);
+        st.message = Some(format!("--http is not supported for type {}", entry.ptype));
+        return;
+    };
+    st.endpoint = format!("{}/models", base);
+    match Client::for_provider(entry).send(&provider_request(entry, "GET", st.endpoint.clone(), None, timeout)) {
+        Ok(resp) => {
+            st.http_status = Some(resp.status);
+            if let Some(code) = ErrorCode::from_http(resp.status) {
+                st.code = Some(code);
+                st.message = Some(format!("HTTP {}", resp.status));
+            }
+        }
+        Err(e) => st.fail(&e),
+    }
+}
+
+/// Err only for setup problems (unknown provider, no default); connection
+/// failures are reported in the status.
+fn check(provider: Option<&str>, timeout: Duration, http: bool) -> Result<ConnectionStatus> {
+    let id = match provider {
+        Some(id) => id.to_string(),
+        None => crate::rules::resolve_from_store()?.provider_id,
+    };
+    let entries = read_scratch_entries()?;
+    let entry = entries.iter().find(|e| e.id == id).ok_or_else(|| anyhow!("provider \"{}\" not found in {}", id, crate::store::path()))?;
+    let mut st = ConnectionStatus { provider_id: id, ..Default::default() };
+    let Some((host, port)) = endpoint_of(entry) else {
+        // In-process providers have nothing to connect to
+        st.message = Some(format!("{} provider has no network endpoint", entry.ptype));
+        return Ok(st);
+    };
+    st.endpoint = format!("tcp://{}:{}", host, port);
+    match connect(&host, port, timeout) {
+        Ok(rtt) => st.latency = Some(rtt),
+        Err(e) => {
+            st.fail(&e);
+            return Ok(st);
+        }
+    }
+    if http { http_ready(entry, timeout, &mut st); }
+    Ok(st)
+}
+
+fn describe(st: &ConnectionStatus) -> String {
+    match (st.error_label(), st.latency) {
+        (Some(err), _) => format!("{}: {} failed: {}", st.provider_id, st.endpoint, err),
+        (None, Some(rtt)) => format!("{}: {} ok ({} ms)", st.provider_id, st.endpoint, rtt.as_millis()),
+        (None, None) => format!("{}: {}", st.provider_id, st.message.clone().unwrap_or_default()),
+    }
+}
+
+/// `chi-tui probe`: exit 0 when the provider answers, 1 otherwise. Meant for
+/// systemd and Kubernetes readiness/liveness probes. `--json` prints the
+/// connection status with its `error_code`.
+pub fn run_probe(provider: Option<String>, timeout: &str, http: bool, quiet: bool, json: bool) -> ! {
+    let result = parse_timeout(timeout).and_then(|t| check(provider.as_deref(), t, http));
+    let ok = match &result {
+        Ok(st) => st.ok(),
+        Err(_) => false,
+    };
+    if !quiet {
+        match (&result, json) {
+            (Ok(st), true) => println!("{}", serde_json::to_string_pretty(&st.to_json()).unwrap_or_default()),
+            (Ok(st), false) if st.ok() => println!("{}", describe(st)),
+            (Ok(st), false) => eprintln!("{}", describe(st)),
+            (Err(e), true) => println!("{}", serde_json::json!({"ok": false, "error_code": "config", "error": e.to_string()})),
+            (Err(e), false) => eprintln!("{}", e),
+        }
+    }
+    std::process::exit(if ok { 0 } else { 1 })
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+
+    #[test]
+    fn timeouts_take_a_unit_or_default_to_seconds() {
+        assert_eq!(parse_timeout("5").expect("bare"), Duration::from_secs(5));
+        assert_eq!(parse_timeout(" 500ms ").expect("ms"), Duration::from_millis(500));
+        assert_eq!(parse_timeout("1.5s").expect("s"), Duration::from_millis(1500));
+        assert_eq!(parse_timeout("2m").expect("m"), Duration::from_secs(120));
+        assert!(parse_timeout("5h").expect_err("unit").to_string().contains("use ms, s or m"));
+        assert!(parse_timeout("fast").is_err());
+        assert!(parse_timeout("0").expect_err("zero").to_string().contains("positive"));
+    }
+
+    #[test]
+    fn connect_reports_refused_and_unresolvable_hosts() {
+        let listener = std::net::TcpListener::bind("127.0.0.1:0").expect("listen");
+        let port = listener.local_addr().expect("addr").port();
+        assert!(connect("127.0.0.1", port, Duration::from_secs(2)).is_ok());
+        drop(listener);
+        let refused = connect("127.0.0.1", port, Duration::from_secs(2)).expect_err("closed");
+        assert_eq!(ErrorCode::classify(&refused), ErrorCode::Refused);
+        let dns = connect("no-such-host.invalid", 80, Duration::from_secs(2)).expect_err("dns");
+        assert_eq!(ErrorCode::classify(&dns), ErrorCode::Dns);
+    }
+
+    /// Answer the first HTTP request on `listener` with `status`; bare
+    /// connections (the TCP check) are accepted and dropped.
+    #[cfg(unix)]
+    fn serve_once(listener: std::net::TcpListener, status: &'static str) {
+        use std::io::{Read, Write};
+        std::thread::spawn(move || {
+            for stream in listener.incoming() {
+                let Ok(mut stream) = stream else { continue };
+                let mut buf = [0u8; 4096];
+                if matches!(stream.read(&mut buf), Ok(n) if n > 0) {
+                    let _ = write!(stream, "HTTP/1.1 {}\r\nContent-Length: 0\r\nConnection: close\r\n\r\n", status);
+                    return;
+                }
+            }
+        });
+    }
+
+    #[cfg(unix)]
+    #[test]
+    fn check_reports_reachability_and_http_readiness() {
+        let _fake = crate::testing::FakeCli::new();
+        let up = std::net::TcpListener::bind("127.0.0.1:0").expect("listen");
+        let up_port = up.local_addr().expect("addr").port();
+        let down_port = std::net::TcpListener::bind("127.0.0.1:0").expect("listen").local_addr().expect("addr").port();
+        crate::store::write(&serde_json::json!({
+            "providers": [
+                {"id": "up", "name": "Up", "type": "ollama", "config": {"host": "127.0.0.1", "port": up_port}},
+                {"id": "down", "name": "Down", "type": "ollama", "config": {"host": "127.0.0.1", "port": down_port}},
+                {"id": "built-in", "name": "Built-in", "type": "local", "config": {}},
+            ],
+            "default_provider_id": "up",
+        }))
+        .expect("store");
+        let t = Duration::from_secs(2);
+
+        let st = check(None, t, false).expect("default");
+        assert!(st.ok() && st.latency.is_some());
+        assert_eq!(describe(&st), format!("up: tcp://127.0.0.1:{} ok ({} ms)", up_port, st.latency.expect("rtt").as_millis()));
+
+        let st = check(Some("down"), t, false).expect("down");
+        assert_eq!(st.code, Some(ErrorCode::Refused));
+        assert!(describe(&st).starts_with(&format!("down: tcp://127.0.0.1:{} failed: refused: ", down_port)));
+
+        let st = check(Some("built-in"), t, true).expect("local");
+        assert!(st.ok());
+        assert_eq!(describe(&st), "built-in: local provider has no network endpoint");
+
+        assert!(check(Some("nope"), t, false).expect_err("unknown").to_string().contains("provider \"nope\" not found"));
+
+        serve_once(up, "401 Unauthorized");
+        let st = check(Some("up"), t, true).expect("http");
+        assert_eq!(st.endpoint, format!("http://127.0.0.1:{}/v1/models", up_port));
+        assert_eq!((st.http_status, st.code), (Some(401), Some(ErrorCode::Auth)));
+        assert_eq!(st.to_json()["error_code"], "auth");
+    }
+}
@@ -0,0 +1,118 @@
+//! `--profile-startup`: wall time of each startup phase up to the first
+//! frame, printed on exit and optionally written as a Chrome trace
+//! (`--profile-out`, open in Perfetto or chrome://tracing).
+
+use std::time::{Duration, Instant};
+
+use anyhow::Result;
+
+use crate::locale;
+
+#[derive(Clone, Debug)]
+pub struct Phase {
+    pub name: &'static str,
+    /// Since the profile started
+    pub start: Duration,
+    pub took: Duration,
+}
+
+#[derive(Clone, Debug)]
+pub struct StartupProfile {
+    started: Instant,
+    last: Instant,
+    pub phases: Vec<Phase>,
+    /// Set once the first frame is recorded; later frames are not phases
+    pub rendered: bool,
+}
+
+impl StartupProfile {
+    pub fn new() -> Self {
+        let now = Instant::now();
+        StartupProfile { started: now, last: now, phases: Vec::new(), rendered: false }
+    }
+
+    /// Close the phase that ran since the previous mark.
+    pub fn mark(&mut self, name: &'static str) {
+        let now = Instant::now();
+        self.phases.push(Phase { name, start: self.last - self.started, took: now - self.last });
+        self.last = now;
+    }
+
+    /// Time `f` as its own phase, not counting whatever ran before it.
+    pub fn time<T>(&mut self, name: &'static str, f: impl FnOnce() -> T) -> T {
+        self.last = Instant::now();
+        let out = f();
+        self.mark(name);
+        out
+    }
+
+    pub fn total(&self) -> Duration {
+        self.last - self.started
+    }
+
+    /// Breakdown for the terminal, slowest phases flagged.
+    pub fn report(&self) -> String {
+        let total = self.total().as_secs_f64().max(f64::EPSILON);
+        let width = self.phases.iter().map(|p| p.name.len()).max().unwrap_or(0);
+        let mut out = format!("Startup profile: {} ms to the first frame\n", locale::decimal(self.total().as_secs_f64() * 1000.0, 1));
+        for p in &self.phases {
+            let share = p.took.as_secs_f64() / total * 100.0;
+            out.push_str(&format!(
+                "  {:<w$}  {:>9} ms  {:>5}%{}\n",
+                p.name,
+                locale::decimal(p.took.as_secs_f64() * 1000.0, 1),
+                locale::decimal(share, 1),
+                if share >= 25.0 { "  ◀" } else { "" },
+                w = width
+            ));
+        }
+        out
+    }
+
+    /// Chrome trace-event JSON: one complete ("X") event per phase.
+    pub fn to_trace(&self) -> serde_json::Value {
+        let events: Vec<serde_json::Value> = self
+            .phases
+            .iter()
+            .map(|p| {
+                serde_json::json!({
+                    "name": p.name,
+                    "cat": "startup",
+                    "ph": "X",
+                    "ts": p.start.as_micros() as u64,
+                    "dur": p.took.as_micros() as u64,
+                    "pid": std::process::id(),
+                    "tid": 1,
+                })
+            })
+            .collect();
+        serde_json::json!({"traceEvents": events, "displayTimeUnit": "ms"})
+    }
+
+    pub fn write_trace(&self, path: &std::path::Path) -> Result<()> {
+        std::fs::write(path, serde_json::to_vec_pretty(&self.to_trace())?)?;
+        Ok(())
+    }
+}
+
+#[cfg(all(test, unix))]
+mod tests {
+    use super::*;
+    use crate::models::fetch_models;
+    use crate::testing::FakeCli;
+
+    #[test]
+    fn startup_profile_reports_each_phase() {
+        let _fake = FakeCli::new();
+        let mut p = StartupProfile::new();
+        p.mark("config load");
+        let models = p.time("model catalog", || fetch_models(Duration::from_secs(5))).expect("models");
+        assert_eq!(models.entries.len(), 2);
+        let names: Vec<&str> = p.phases.iter().map(|ph| ph.name).collect();
+        assert_eq!(names, vec!["config load", "model catalog"]);
+        assert!(p.report().contains("model catalog"), "{}", p.report());
+        let trace = p.to_trace();
+        assert_eq!(trace["traceEvents"][1]["name"], "model catalog");
+        assert_eq!(trace["traceEvents"][1]["ph"], "X");
+    }
+}
@@ -0,0 +1,242 @@
+//! Profiles: named provider sets with their default ("work", "home",
+//! "offline"), one JSON file each under `~/.config/chi_llm/profiles/`.
+//! Switching writes the profile's providers and default into the project
+//! store and records its name there as `active_profile`.
+
+use std::fs;
+use std::path::PathBuf;
+
+use anyhow::{anyhow, Result};
+use ratatui::layout::Rect;
+use ratatui::prelude::Frame;
+use ratatui::style::{Modifier, Style};
+use ratatui::text::{Line, Span};
+use ratatui::widgets::{Block, Borders, Clear, Paragraph, Wrap};
+use serde_json::{json, Value};
+
+use crate::app::App;
+use crate::audit;
+use crate::backup;
+use crate::store;
+use crate::text;
+use crate::util::overlay_rect;
+
+/// Store key naming the profile last switched to (or saved from).
+pub const ACTIVE_KEY: &str = "active_profile";
+
+#[derive(Clone, Debug)]
+pub struct Profile {
+    pub name: String,
+    pub providers: Vec<Value>,
+    pub default_provider_id: Option<String>,
+}
+
+impl Profile {
+    fn from_value(name: &str, v: &Value) -> Self {
+        Profile {
+            name: name.to_string(),
+            providers: v.get("providers").and_then(|x| x.as_array()).cloned().unwrap_or_default(),
+            default_provider_id: v.get("default_provider_id").and_then(|x| x.as_str()).map(str::to_string),
+        }
+    }
+
+    /// "3 providers, default ollama"
+    pub fn summary(&self) -> String {
+        let n = self.providers.len();
+        let default = self.default_provider_id.as_deref().map(|d| format!(", default {}", d)).unwrap_or_default();
+        format!("{} provider{}{}", n, if n == 1 { "" } else { "s" }, default)
+    }
+}
+
+pub fn dir() -> Result<PathBuf> {
+    let base = dirs::config_dir().ok_or_else(|| anyhow!("config dir not found"))?;
+    Ok(base.join("chi_llm").join("profiles"))
+}
+
+/// Profile names become file names: letters, digits, `-`, `_` and `.`.
+pub fn valid_name(name: &str) -> bool {
+    !name.is_empty() && !name.starts_with('.') && name.chars().all(|c| c.is_ascii_alphanumeric() || "-_.".contains(c))
+}
+
+fn path_of(name: &str) -> Result<PathBuf> {
+    if !valid_name(name) { return Err(anyhow!("invalid profile name \"{}\" (use letters, digits, - _ .)", name)); }
+    Ok(dir()?.join(format!("{}.json", name)))
+}
+
+/// Saved profiles by name; unreadable files are skipped.
+pub fn list() -> Vec<Profile> {
+    let Ok(rd) = dir().and_then(|d| Ok(fs::read_dir(d)?)) else { return Vec::new() };
+    let mut out: Vec<Profile> = rd
+        .filter_map(|e| e.ok().map(|e| e.path()))
+        .filter(|p| p.extension().map_or(false, |x| x == "json"))
+        .filter_map(|p| {
+            let name = p.file_stem()?.to_str()?.to_string();
+            let v: Value = serde_json::from_str(&fs::read_to_string(&p).ok()?).ok()?;
+            Some(Profile::from_value(&name, &v))
+        })
+        .collect();
+    out.sort_by(|a, b| a.name.cmp(&b.name));
+    out
+}
+
+pub fn load(name: &str) -> Result<Profile> {
+    let path = path_of(name)?;
+    let text = fs::read_to_string(&path).map_err(|e| anyhow!("{}: {}", path.display(), e))?;
+    let v = store::parse(&text).map_err(|e| anyhow!("{}: {}", path.display(), e))?;
+    Ok(Profile::from_value(name, &v))
+}
+
+/// Profile the project store was last switched to or saved from.
+pub fn active() -> Option<String> {
+    store::read_or_empty().get(ACTIVE_KEY).and_then(|v| v.as_str()).map(str::to_string)
+}
+
+fn mark_active(root: &mut Value, name: &str) {
+    if let Some(obj) = root.as_object_mut() { obj.insert(ACTIVE_KEY.to_string(), Value::String(name.to_string())); }
+}
+
+/// Save the store's providers and default as profile `name` (overwriting
+/// it) and mark it active. Secrets stay in the keychain; the profile keeps
+/// only their `secret:` references.
+pub fn save_current(name: &str) -> Result<Profile> {
+    let path = path_of(name)?;
+    let before = store::read_or_empty();
+    let profile = Profile::from_value(name, &before);
+    if let Some(d) = path.parent() { fs::create_dir_all(d)?; }
+    let body = json!({"providers": profile.providers, "default_provider_id": profile.default_provider_id});
+    fs::write(&path, serde_json::to_string_pretty(&body)?)?;
+    if store::exists() && active().as_deref() != Some(name) {
+        let mut root = before.clone();
+        mark_active(&mut root, name);
+        let written = store::write(&root)?;
+        let _ = audit::record("profile.save", &written, &before, &root);
+    }
+    Ok(profile)
+}
+
+/// Replace the store's providers and default with profile `name`. The
+/// current store is snapshotted first (Backups page), so a provider set
+/// that was never saved as a profile is not lost. Returns the store path.
+pub fn switch(name: &str) -> Result<String> {
+    let profile = load(name)?;
+    if store::exists() { backup::snapshot_now()?; }
+    let before = store::read_or_empty();
+    let mut root = before.clone();
+    if let Some(obj) = root.as_object_mut() {
+        obj.insert("providers".to_string(), Value::Array(profile.providers));
+        match profile.default_provider_id {
+            Some(id) => { obj.insert("default_provider_id".to_string(), Value::String(id)); }
+            None => { obj.remove("default_provider_id"); }
+        }
+    }
+    mark_active(&mut root, name);
+    let written = store::write(&root)?;
+    let _ = audit::record("profile.switch", &written, &before, &root);
+    Ok(written)
+}
+
+pub fn delete(name: &str) -> Result<()> {
+    fs::remove_file(path_of(name)?)?;
+    Ok(())
+}
+
+/// Welcome `p`: pick a profile to switch to, or save the current providers
+/// as one.
+#[derive(Clone, Debug, Default)]
+pub struct ProfileSwitcher {
+    pub profiles: Vec<Profile>,
+    pub selected: usize,
+    pub active: Option<String>,
+    /// Name being typed for "save current as" (`n`)
+    pub name_input: Option<String>,
+    pub status: Option<String>,
+}
+
+impl ProfileSwitcher {
+    pub fn load() -> Self {
+        let mut s = ProfileSwitcher { profiles: list(), active: active(), ..Default::default() };
+        s.selected = s.active.as_ref().and_then(|a| s.profiles.iter().position(|p| &p.name == a)).unwrap_or(0);
+        s
+    }
+
+    /// Re-read the profiles, keeping the selection and status.
+    pub fn reload(&mut self) {
+        let status = self.status.take();
+        let keep = self.current().map(|p| p.name.clone());
+        *self = ProfileSwitcher::load();
+        if let Some(i) = keep.and_then(|k| self.profiles.iter().position(|p| p.name == k)) { self.selected = i; }
+        self.status = status;
+    }
+
+    pub fn current(&self) -> Option<&Profile> {
+        self.profiles.get(self.selected)
+    }
+}
+
+pub fn draw_profiles(f: &mut Frame, area: Rect, app: &App) {
+    let Some(sw) = &app.profiles else { return };
+    let pop = overlay_rect(app.compact, 60, 60, area);
+    let name_w = sw.profiles.iter().map(|p| text::width(&p.name)).max().unwrap_or(0).clamp(8, 24);
+    let mut lines: Vec<Line> = Vec::new();
+    if sw.profiles.is_empty() {
+        lines.push(Line::from("No profiles yet — press n to save the current providers as one."));
+    }
+    for (i, p) in sw.profiles.iter().enumerate() {
+        let sel = i == sw.selected;
+        let style = if sel { Style::default().fg(app.theme.selected).add_modifier(Modifier::BOLD) } else { Style::default().fg(app.theme.fg) };
+        let mark = if sw.active.as_deref() == Some(p.name.as_str()) { "●" } else { " " };
+        lines.push(Line::from(vec![
+            Span::styled(format!("{} {} {}  ", if sel { '›' } else { ' ' }, mark, text::fit(&p.name, name_w)), style),
+            Span::styled(p.summary(), Style::default().fg(app.theme.secondary)),
+        ]));
+    }
+    lines.push(Line::from(""));
+    if let Some(buf) = &sw.name_input {
+        lines.push(Line::from(vec![
+            Span::styled("Save current providers as: ", Style::default().fg(app.theme.secondary)),
+            Span::styled(format!("{}▏", buf), Style::default().fg(app.theme.accent)),
+        ]));
+    } else if let Some(s) = &sw.status {
+        let (text, style) = app.theme.status_text(s);
+        lines.push(Line::from(Span::styled(text, style)));
+    }
+    let title = format!("Profiles — active: {}", sw.active.as_deref().unwrap_or("none"));
+    let p = Paragraph::new(lines)
+        .style(Style::default().bg(app.theme.bg).fg(app.theme.fg))
+        .block(Block::default().borders(Borders::ALL).border_style(Style::default().fg(app.theme.accent)).title(title))
+        .wrap(Wrap { trim: false });
+    f.render_widget(Clear, pop);
+    f.render_widget(p, pop);
+}
+
+#[cfg(all(test, unix))]
+mod tests {
+    use super::*;
+    use crate::config_cli::run_config;
+    use crate::testing::{add_provider, FakeCli};
+
+    #[test]
+    fn profiles_switch_provider_sets() {
+        let fake = FakeCli::new();
+        run_config(add_provider("ollama", "home", &[], true)).expect("add provider");
+        assert_eq!(save_current("home").expect("save").summary(), "1 provider, default home");
+        run_config(add_provider("ollama", "work", &[("host", "gpu.internal")], true)).expect("add provider");
+        save_current("work").expect("save");
+        assert_eq!(list().iter().map(|p| p.name.as_str()).collect::<Vec<_>>(), vec!["home", "work"]);
+        assert_eq!(active().as_deref(), Some("work"));
+
+        switch("home").expect("switch");
+        let root = fake.store();
+        assert_eq!(root["providers"].as_array().expect("providers").len(), 1);
+        assert_eq!(root["default_provider_id"], "home");
+        assert_eq!(root[ACTIVE_KEY], "home");
+        // The replaced provider set can be restored from Backups
+        assert!(!crate::backup::load_backups().snapshots.is_empty());
+
+        switch("work").expect("switch");
+        assert_eq!(crate::build::get_default_provider_summary().expect("default").0, "work");
+        assert!(save_current("../escape").is_err());
+        delete("home").expect("delete");
+        assert_eq!(list().len(), 1);
+    }
+}
@@ -0,0 +1,158 @@
+use std::sync::Arc;
+use std::thread;
+use std::time::Duration;
+
+use anyhow::{anyhow, Result};
+use serde_json::Value;
+
+use super::import::{check_candidate, ImportPreview};
+use super::state::{ProviderScratchEntry, ProvidersState};
+use crate::health::{check_tcp, endpoint_of};
+use crate::http::{Client, HttpSettings, Request, ReqwestTransport};
+
+/// A well-known local server port and how to list its models.
+struct Target {
+    label: &'static str,
+    port: u16,
+    /// Provider type added for it
+    ptype: &'static str,
+    /// Model list path: Ollama's `/api/tags` or OpenAI-style `/v1/models`
+    path: &'static str,
+}
+
+const TARGETS: &[Target] = &[
+    Target { label: "Ollama", port: 11434, ptype: "ollama", path: "/api/tags" },
+    Target { label: "LM Studio", port: 1234, ptype: "lmstudio", path: "/v1/models" },
+    Target { label: "vLLM", port: 8000, ptype: "openai-compatible", path: "/v1/models" },
+    Target { label: "llama.cpp / LocalAI", port: 8080, ptype: "openai-compatible", path: "/v1/models" },
+    Target { label: "text-generation-webui", port: 5000, ptype: "openai-compatible", path: "/v1/models" },
+    Target { label: "Jan", port: 1337, ptype: "openai-compatible", path: "/v1/models" },
+];
+
+const HOST: &str = "127.0.0.1";
+
+fn is_loopback(host: &str) -> bool {
+    matches!(host, "127.0.0.1" | "localhost" | "::1" | "0.0.0.0")
+}
+
+/// Model ids from `{"models": [{"name"}]}` (Ollama) or `{"data": [{"id"}]}`.
+fn model_ids(v: &Value) -> Vec<String> {
+    let from = |key: &str, field: &str| -> Vec<String> {
+        v.get(key).and_then(|a| a.as_array()).map(|a| a.iter().filter_map(|m| m.get(field).and_then(|x| x.as_str()).map(|s| s.to_string())).collect()).unwrap_or_default()
+    };
+    let ollama = from("models", "name");
+    if ollama.is_empty() { from("data", "id") } else { ollama }
+}
+
+/// A server answering on `t.port` with a parseable model list.
+fn probe(client: &Client, t: &Target) -> Result<Vec<String>> {
+    let tcp = check_tcp("", HOST, t.port, Duration::from_millis(300));
+    if !tcp.ok() { return Err(anyhow!(tcp.error_label().unwrap_or_default())); }
+    let req = Request {
+        method: "GET".to_string(),
+        url: format!("http://{}:{}{}", HOST, t.port, t.path),
+        headers: vec![("Accept".to_string(), "application/json".to_string())],
+        body: None,
+        timeout: Some(Duration::from_secs(2)),
+    };
+    let resp = client.send(&req)?;
+    if !resp.ok() { return Err(anyhow!("HTTP {}", resp.status)); }
+    let v: Value = serde_json::from_str(&resp.body)?;
+    if v.get("models").is_none() && v.get("data").is_none() { return Err(anyhow!("not a model list")); }
+    Ok(model_ids(&v))
+}
+
+fn entry_for(t: &Target, models: &[String]) -> ProviderScratchEntry {
+    let mut config = serde_json::json!({"type": t.ptype});
+    if let Some(obj) = config.as_object_mut() {
+        if t.ptype == "openai-compatible" {
+            obj.insert("base_url".to_string(), Value::String(format!("http://{}:{}/v1", HOST, t.port)));
+        } else {
+            obj.insert("host".to_string(), Value::String(HOST.to_string()));
+            obj.insert("port".to_string(), Value::Number(t.port.into()));
+        }
+        if let Some(m) = models.first() { obj.insert("model".to_string(), Value::String(m.clone())); }
+    }
+    ProviderScratchEntry { id: String::new(), name: format!("{} (localhost:{})", t.label, t.port), ptype: t.ptype.to_string(), tags: vec!["auto-detected".to_string()], config, extra: Default::default(), scope: Default::default() }
+}
+
+/// Probe the well-known local server ports concurrently and offer every
+/// server found as an import candidate. Servers already configured at the
+/// same port are listed but not importable.
+pub fn detect_preview(st: &ProvidersState) -> Result<ImportPreview> {
+    // Loopback only: the configured proxy must not see these requests
+    let client = Client::with_transport(Arc::new(ReqwestTransport::new(HttpSettings::default())), 0);
+    let handles: Vec<_> = TARGETS
+        .iter()
+        .map(|t| {
+            let client = client.clone();
+            thread::spawn(move || probe(&client, t).ok().map(|models| (t, models)))
+        })
+        .collect();
+    let found: Vec<(&Target, Vec<String>)> = handles.into_iter().filter_map(|h| h.join().ok().flatten()).collect();
+    if found.is_empty() {
+        let ports: Vec<String> = TARGETS.iter().map(|t| t.port.to_string()).collect();
+        return Err(anyhow!("no local servers found (ports {})", ports.join(", ")));
+    }
+    let configured: Vec<(String, u16, String)> = st
+        .entries
+        .iter()
+        .filter_map(|e| endpoint_of(&crate::variables::resolve_entry(e)).map(|(h, p)| (h, p, e.id.clone())))
+        .filter(|(h, _, _)| is_loopback(h))
+        .collect();
+    let mut taken: Vec<String> = st.entries.iter().map(|e| e.id.clone()).collect();
+    let mut candidates = Vec::new();
+    for (t, models) in found {
+        let index = st.entries.len() + candidates.len() + 1;
+        let mut c = check_candidate(entry_for(t, &models), st, &mut taken, index);
+        if let Some((_, _, id)) = configured.iter().find(|(_, p, _)| *p == t.port) {
+            c.errors.push(format!("already configured as \"{}\"", id));
+        }
+        c.notes.push(match models.len() {
+            0 => "no models loaded".to_string(),
+            n if n <= 5 => format!("models: {}", models.join(", ")),
+            n => format!("models: {} (+{} more)", models[..5].join(", "), n - 5),
+        });
+        candidates.push(c);
+    }
+    Ok(ImportPreview { candidates, scroll: 0, source: Some("localhost".to_string()) })
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+
+    #[test]
+    fn model_lists_of_both_shapes_are_read() {
+        assert_eq!(model_ids(&serde_json::json!({"models": [{"name": "qwen2.5:7b"}, {"model": "x"}]})), vec!["qwen2.5:7b".to_string()]);
+        assert_eq!(model_ids(&serde_json::json!({"data": [{"id": "a"}, {"id": "b"}]})), vec!["a".to_string(), "b".to_string()]);
+        assert!(model_ids(&serde_json::json!({"models": []})).is_empty());
+    }
+
+    #[test]
+    fn entries_point_at_the_detected_port() {
+        let ollama = entry_for(&TARGETS[0], &["llama3".to_string()]);
+        assert_eq!(ollama.config, serde_json::json!({"type": "ollama", "host": "127.0.0.1", "port": 11434, "model": "llama3"}));
+        assert_eq!((ollama.name.as_str(), ollama.tags.as_slice()), ("Ollama (localhost:11434)", &["auto-detected".to_string()][..]));
+        let vllm = entry_for(&TARGETS[2], &[]);
+        assert_eq!(vllm.config, serde_json::json!({"type": "openai-compatible", "base_url": "http://127.0.0.1:8000/v1"}));
+        assert!(is_loopback("localhost") && is_loopback("::1") && !is_loopback("10.0.0.5"));
+    }
+
+    #[cfg(unix)]
+    #[test]
+    fn probes_need_an_open_port_and_a_model_list() {
+        let _fake = crate::testing::FakeCli::new();
+        let listener = std::net::TcpListener::bind((HOST, 0)).expect("listen");
+        let t = Target { label: "Test", port: listener.local_addr().expect("addr").port(), ptype: "openai-compatible", path: "/v1/models" };
+        let canned = crate::testing::Canned::new(&[(200, r#"{"data": [{"id": "m"}]}"#), (200, r#"{"ok": true}"#), (404, "")]);
+        let client = Client::with_transport(canned.clone(), 0);
+        assert_eq!(probe(&client, &t).expect("models"), vec!["m".to_string()]);
+        assert_eq!(canned.sent()[0].url, format!("http://127.0.0.1:{}/v1/models", t.port));
+        assert_eq!(probe(&client, &t).expect_err("shape").to_string(), "not a model list");
+        assert_eq!(probe(&client, &t).expect_err("status").to_string(), "HTTP 404");
+        drop(listener);
+        assert!(probe(&client, &t).expect_err("closed").to_string().starts_with("refused"));
+        assert_eq!(canned.sent().len(), 3, "a closed port is not asked over HTTP");
+    }
+}
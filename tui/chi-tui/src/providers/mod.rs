@@ -1,14 +1,20 @@
 mod state;
+mod autodetect;
+mod import;
 mod select_default;
 mod view;
+#[cfg(all(test, unix))]
+mod tests;
 
 pub use state::{
-    ProvidersState, ProviderScratchEntry, FieldSchema, FormField, FormState, DropdownState,
-    load_providers_state, compute_form_hash,
+    ProvidersState, ProviderScratchEntry, Scope, FieldSchema, FormField, FormState, DropdownState,
+    load_providers_state, publish_session, read_scratch_entries, read_scratch_entries_raw, compute_form_hash, type_choices,
 };
 pub use select_default::{
-    DefaultProviderState, load_providers_scratch, save_default_provider, draw_select_default,
+    DefaultProviderState, Preflight, load_providers_scratch, save_default_provider, draw_select_default,
 };
+pub use autodetect::detect_preview;
+pub use import::{apply_import, export_entry, parse_import, share_payload};
 pub use view::{
-    draw_providers_catalog, probe_provider,
+    draw_providers_catalog, probe_provider, test_connection, ConnectionTest,
 };
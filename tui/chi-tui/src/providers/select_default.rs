@@ -1,6 +1,10 @@
-use std::fs;
+use std::collections::{HashMap, HashSet};
+use std::sync::mpsc::{channel, Receiver, Sender};
+use std::thread;
+use std::time::Duration;
 
 use anyhow::Result;
+use chrono::Utc;
 use ratatui::layout::Rect;
 use ratatui::prelude::Frame;
 use ratatui::style::{Modifier, Style};
@@ -9,12 +13,26 @@ use ratatui::widgets::{Block, Borders, List, ListItem, Paragraph};
 use serde_json::Value;
 
 use crate::app::App;
+use crate::audit;
+use crate::locale;
+use crate::portforward::PortForwards;
+use crate::privacy::{self, Privacy};
+use crate::status::{self, StatusRow};
+use crate::store;
+use crate::text;
+use crate::theme::StatusKind;
+use super::state::{ProviderScratchEntry, Scope};
+
+/// How long a pre-flight result stays good before the cursor re-checks it.
+const PREFLIGHT_TTL: Duration = Duration::from_secs(60);
 
 #[derive(Clone, Debug)]
 pub struct DefaultProviderState {
     pub providers: Vec<ProviderEntry>,
     pub selected: usize,
     pub current_default_id: Option<String>,
+    /// Provider chosen by `default_rules` right now, with the reason.
+    pub resolved: Option<(String, String)>,
 }
 
 #[derive(Clone, Debug)]
@@ -23,13 +41,131 @@ pub struct ProviderEntry {
     pub name: String,
     pub ptype: String,
     pub tags: Vec<String>,
+    pub config: Value,
+    pub privacy: Privacy,
+    pub scope: Scope,
+}
+
+impl DefaultProviderState {
+    /// Stable sort so local/private providers come first.
+    pub fn sort_private_first(&mut self) {
+        self.providers.sort_by_key(|p| p.privacy.rank());
+        self.selected = 0;
+    }
+}
+
+impl ProviderEntry {
+    fn scratch(&self) -> ProviderScratchEntry {
+        ProviderScratchEntry { id: self.id.clone(), name: self.name.clone(), ptype: self.ptype.clone(), tags: self.tags.clone(), config: self.config.clone(), extra: Default::default(), scope: self.scope }
+    }
+}
+
+/// Reachability of Select Default rows, tested off the UI thread as the
+/// cursor reaches them (the Provider Status check, one provider at a time).
+pub struct Preflight {
+    results: HashMap<String, StatusRow>,
+    checking: HashSet<String>,
+    tx: Sender<StatusRow>,
+    rx: Receiver<StatusRow>,
+}
+
+impl Default for Preflight {
+    fn default() -> Self {
+        let (tx, rx) = channel();
+        Preflight { results: HashMap::new(), checking: HashSet::new(), tx, rx }
+    }
+}
+
+impl Preflight {
+    /// Test `p` unless a check is running or a recent result exists.
+    pub fn check(&mut self, p: &ProviderEntry, portfw: &mut PortForwards) {
+        let fresh = self.results.get(&p.id).and_then(|r| r.checked_at).map_or(false, |t| (Utc::now() - t).to_std().map_or(true, |age| age < PREFLIGHT_TTL));
+        if fresh || self.checking.contains(&p.id) { return; }
+        let entry = p.scratch();
+        if let Err(e) = portfw.ensure(&entry) {
+            let detail = format!("port-forward: {}", e);
+            self.results.insert(p.id.clone(), StatusRow { id: p.id.clone(), name: p.name.clone(), ptype: p.ptype.clone(), reachable: Some(false), latency: None, models: None, model_ids: Vec::new(), detail, checked_at: Some(Utc::now()), checking: false });
+            return;
+        }
+        self.checking.insert(p.id.clone());
+        let tx = self.tx.clone();
+        thread::spawn(move || {
+            let _ = tx.send(status::check(&entry));
+        });
+    }
+
+    /// Store finished checks. Returns the rows that arrived.
+    pub fn poll(&mut self) -> Vec<StatusRow> {
+        let mut done = Vec::new();
+        while let Ok(row) = self.rx.try_recv() {
+            self.checking.remove(&row.id);
+            self.results.insert(row.id.clone(), row.clone());
+            done.push(row);
+        }
+        done
+    }
+
+    pub fn is_checking(&self, id: &str) -> bool {
+        self.checking.contains(id)
+    }
+
+    /// Latest result for `id`, when one finished.
+    pub fn result(&self, id: &str) -> Option<&StatusRow> {
+        self.results.get(id)
+    }
+
+    /// The failed check for `id`, when its last result says unreachable.
+    pub fn unreachable(&self, id: &str) -> Option<&StatusRow> {
+        self.results.get(id).filter(|r| r.reachable == Some(false))
+    }
+
+    /// Why `p` should not become the default without asking: it did not
+    /// answer, or it answered without the model it is configured for.
+    pub fn problem(&self, p: &ProviderEntry) -> Option<String> {
+        if let Some(r) = self.unreachable(&p.id) { return Some(r.detail.clone()); }
+        let r = self.results.get(&p.id)?;
+        missing_model(r, &p.config).map(|m| format!("model \"{}\" is not among the {} models it lists", m, r.model_ids.len()))
+    }
+}
+
+/// The configured `model` when the provider listed its models and that one
+/// is not among them. Ollama's implicit `:latest` tag matches either way.
+fn missing_model(r: &StatusRow, config: &Value) -> Option<String> {
+    let model = config.get("model").and_then(|m| m.as_str()).map(str::trim).filter(|m| !m.is_empty())?;
+    if r.model_ids.is_empty() { return None; }
+    let bare = |s: &str| s.strip_suffix(":latest").unwrap_or(s).to_string();
+    let listed = r.model_ids.iter().any(|id| bare(id) == bare(model));
+    (!listed).then(|| model.to_string())
+}
+
+/// Inline pre-flight label for a row.
+fn preflight_span(app: &App, p: &ProviderEntry) -> Span<'static> {
+    let id = p.id.as_str();
+    if app.preflight.is_checking(id) && app.preflight.result(id).is_none() {
+        return Span::styled("  … checking", app.theme.status_style(StatusKind::Warn));
+    }
+    let Some(r) = app.preflight.result(id) else { return Span::raw("") };
+    let again = if app.preflight.is_checking(id) { " ↻" } else { "" };
+    if r.reachable == Some(false) {
+        return Span::styled(format!("  {} unreachable{}", StatusKind::Err.symbol(), again), app.theme.status_style(StatusKind::Err));
+    }
+    let kind = if missing_model(r, &p.config).is_some() { StatusKind::Warn } else { StatusKind::Ok };
+    let mut text = format!("  {} reachable", kind.symbol());
+    if let Some(n) = r.models { text.push_str(&format!(" • {} models", locale::count(n as u64))); }
+    if kind == StatusKind::Warn { text.push_str(" • model missing"); }
+    if let Some(d) = r.latency { text.push_str(&format!(" • {} ms", locale::decimal(d.as_secs_f64() * 1000.0, 1))); }
+    text.push_str(again);
+    Span::styled(text, app.theme.status_style(kind))
 }
 
 pub fn load_providers_scratch() -> Result<DefaultProviderState> {
-    let path = "chi.tmp.json";
-    let text = fs::read_to_string(path).unwrap_or_else(|_| "{}".to_string());
-    let v: Value = serde_json::from_str(&text)?;
+    let v = store::read_layered()?;
+    let project = if store::exists() { store::read()? } else { serde_json::json!({}) };
+    let in_project = |id: &str| {
+        project.get("providers").and_then(|x| x.as_array()).map_or(false, |a| a.iter().any(|p| p.get("id").and_then(|x| x.as_str()) == Some(id)))
+    };
     let mut providers: Vec<ProviderEntry> = Vec::new();
+    let vars = crate::variables::load_variables();
     if let Some(arr) = v.get("providers").and_then(|x| x.as_array()) {
         for p in arr {
             let id = p.get("id").and_then(|x| x.as_str()).unwrap_or("").to_string();
@@ -38,45 +174,133 @@ pub fn load_providers_scratch() -> Result<DefaultProviderState> {
             let tags: Vec<String> = p.get("tags").and_then(|x| x.as_array()).map(|a| {
                 a.iter().filter_map(|t| t.as_str().map(|s| s.to_string())).collect()
             }).unwrap_or_default();
-            if !id.is_empty() { providers.push(ProviderEntry { id, name, ptype, tags }); }
+            let config = p.get("config").map(|c| crate::variables::resolve_value(c, &vars)).unwrap_or(Value::Null);
+            let privacy = privacy::classify(&ptype, &config, None);
+            if !id.is_empty() {
+                let scope = if in_project(&id) { Scope::Project } else { Scope::Global };
+                providers.push(ProviderEntry { id, name, ptype, tags, config, privacy, scope });
+            }
         }
     }
     let current_default_id = v.get("default_provider_id").and_then(|x| x.as_str()).map(|s| s.to_string());
-    Ok(DefaultProviderState { providers, selected: 0, current_default_id })
+    let resolved = if v.get("default_rules").is_some() {
+        match crate::rules::resolve(&v, &crate::rules::current_context()) {
+            Ok(r) => Some((r.provider_id, r.reason)),
+            Err(e) => Some(("?".to_string(), format!("rules error: {}", e))),
+        }
+    } else {
+        None
+    };
+    Ok(DefaultProviderState { providers, selected: 0, current_default_id, resolved })
 }
 
 pub fn save_default_provider(id: &str) -> Result<()> {
-    let path = "chi.tmp.json";
-    let mut root: Value = if let Ok(text) = fs::read_to_string(path) {
-        serde_json::from_str(&text).unwrap_or_else(|_| Value::Object(Default::default()))
-    } else {
-        Value::Object(Default::default())
-    };
-    if !root.is_object() { root = Value::Object(Default::default()); }
+    let mut root = store::read_or_empty();
+    let before = root.clone();
     if let Some(obj) = root.as_object_mut() {
         obj.insert("default_provider_id".to_string(), Value::String(id.to_string()));
     }
-    fs::write(path, serde_json::to_vec_pretty(&root)?)?;
+    let path = store::write(&root)?;
+    let _ = audit::record("default.set", &path, &before, &root);
     Ok(())
 }
 
 pub fn draw_select_default(f: &mut Frame, area: Rect, app: &App) {
     let mut items: Vec<ListItem> = Vec::new();
+    let name_w = (area.width.saturating_sub(4) as usize / 3).max(12);
     if let Some(st) = &app.defaultp {
         for (i, p) in st.providers.iter().enumerate() {
-            let mut label = format!("{} {} [{}]", if i == st.selected { '›' } else { ' ' }, p.name, p.ptype);
+            let mut label = format!("{} {} [{}]", if i == st.selected { '›' } else { ' ' }, text::truncate(&p.name, name_w), p.ptype);
+            let privacy = Span::styled(format!("  [{}]", p.privacy.label()), Style::default().fg(p.privacy.color(&app.theme)));
             if let Some(cur) = &st.current_default_id { if cur == &p.id { label.push_str("  [default]"); } }
             if !p.tags.is_empty() { label.push_str(&format!("  [{}]", p.tags.join(","))); }
             let style = if i == st.selected { Style::default().fg(app.theme.selected).add_modifier(Modifier::BOLD) } else { Style::default().fg(app.theme.fg) };
-            items.push(ListItem::new(Line::from(Span::styled(label, style))))
+            let origin = Span::styled(format!("  [{}]", p.scope.label()), Style::default().fg(app.theme.secondary));
+            items.push(ListItem::new(Line::from(vec![Span::styled(label, style), privacy, origin, preflight_span(app, p)])))
+        }
+        if st.providers.is_empty() { items.push(ListItem::new("No providers configured → Configure first.")); }
+        // The selected name in full when the list had to cut it
+        if let Some(p) = st.providers.get(st.selected).filter(|p| text::width(&p.name) > name_w) {
+            items.push(ListItem::new(Line::from(Span::styled(format!("› {} ({})", p.name, p.id), Style::default().fg(app.theme.secondary)))));
+        }
+        if let Some((p, problem)) = st.providers.get(st.selected).and_then(|p| Some((p, app.preflight.problem(p)?))) {
+            items.push(ListItem::new(Line::from(Span::styled(format!("{} {}: {}", StatusKind::Warn.symbol(), p.name, problem), app.theme.status_style(StatusKind::Warn)))));
+        }
+        if let Some((id, reason)) = &st.resolved {
+            items.push(ListItem::new(Line::from(Span::styled(format!("Rules resolve to: {} ({})", id, reason), Style::default().fg(app.theme.secondary)))));
         }
-        if st.providers.is_empty() { items.push(ListItem::new("No providers found in chi.tmp.json → Configure first.")); }
     } else {
         items.push(ListItem::new("Loading providers..."));
     }
     let list = List::new(items)
-        .block(Block::default().borders(Borders::ALL).border_style(Style::default().fg(app.theme.frame)).title("Select Default Provider"))
+        .block(Block::default().borders(Borders::ALL).border_style(Style::default().fg(app.theme.frame)).title(if app.prefer_private { "Select Default Provider • private first" } else { "Select Default Provider" }))
         .highlight_style(Style::default().fg(app.theme.selected));
     f.render_widget(list, area);
 }
 
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+    use serde_json::json;
+
+    fn entry(model: &str) -> ProviderEntry {
+        let config = json!({"host": "box", "model": model});
+        ProviderEntry { id: "box".to_string(), name: "Box".to_string(), ptype: "ollama".to_string(), tags: Vec::new(), privacy: privacy::classify("ollama", &config, None), config, scope: Scope::Project }
+    }
+
+    fn checked(reachable: bool, model_ids: &[&str], detail: &str) -> StatusRow {
+        StatusRow {
+            id: "box".to_string(),
+            name: "Box".to_string(),
+            ptype: "ollama".to_string(),
+            reachable: Some(reachable),
+            latency: None,
+            models: Some(model_ids.len()),
+            model_ids: model_ids.iter().map(|m| m.to_string()).collect(),
+            detail: detail.to_string(),
+            checked_at: Some(Utc::now()),
+            checking: false,
+        }
+    }
+
+    fn preflight_with(row: StatusRow) -> Preflight {
+        let mut pf = Preflight::default();
+        pf.tx.send(row).expect("send");
+        assert_eq!(pf.poll().len(), 1);
+        pf
+    }
+
+    #[test]
+    fn a_reachable_provider_with_its_model_passes() {
+        let pf = preflight_with(checked(true, &["qwen2.5:7b", "llama3:latest"], "ollama: 2 models"));
+        assert!(pf.unreachable("box").is_none());
+        assert_eq!(pf.problem(&entry("qwen2.5:7b")), None);
+        // Ollama's implicit tag
+        assert_eq!(pf.problem(&entry("llama3")), None);
+        // No model configured, or none listed: nothing to compare
+        assert_eq!(pf.problem(&entry("")), None);
+        assert_eq!(preflight_with(checked(true, &[], "")).problem(&entry("qwen2.5:7b")), None);
+        assert_eq!(Preflight::default().problem(&entry("qwen2.5:7b")), None, "not checked yet");
+    }
+
+    #[test]
+    fn an_unreachable_provider_reports_the_failed_check() {
+        let pf = preflight_with(checked(false, &[], "connection refused"));
+        assert_eq!(pf.unreachable("box").map(|r| r.detail.as_str()), Some("connection refused"));
+        assert_eq!(pf.problem(&entry("qwen2.5:7b")), Some("connection refused".to_string()));
+    }
+
+    #[test]
+    fn a_provider_without_its_model_is_a_problem() {
+        let pf = preflight_with(checked(true, &["llama3:latest", "phi3"], "ollama: 2 models"));
+        assert_eq!(pf.problem(&entry("qwen2.5:7b")), Some("model \"qwen2.5:7b\" is not among the 2 models it lists".to_string()));
+    }
+
+    #[test]
+    fn a_fresh_result_is_not_checked_again() {
+        let mut pf = preflight_with(checked(true, &[], ""));
+        pf.check(&entry("qwen2.5:7b"), &mut PortForwards::default());
+        assert!(!pf.is_checking("box"));
+    }
+}
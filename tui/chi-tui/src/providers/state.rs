@@ -1,11 +1,63 @@
 use std::collections::HashMap;
-use std::fs;
+use std::sync::Mutex;
 use std::time::Duration;
 
-use anyhow::Result;
-use serde_json::Value;
+use anyhow::{anyhow, Result};
+use serde_json::{Map, Value};
 
-use crate::util::run_cli_json;
+use crate::audit;
+use crate::deprecations::{self, Deprecation};
+use crate::export::ExportPicker;
+use crate::privacy::Privacy;
+use crate::qr::ShareQr;
+use crate::secrets;
+use crate::store;
+use super::import::ImportPreview;
+use crate::discovery_cache;
+
+/// Which store a provider lives in: the project's `chi.tmp.*`, the
+/// global list every project sees, or none (`Session`: kept in memory until
+/// chi-tui exits, for trying out a server without touching the config).
+#[derive(Copy, Clone, Debug, Default, PartialEq, Eq)]
+pub enum Scope {
+    #[default]
+    Project,
+    Global,
+    Session,
+}
+
+impl Scope {
+    pub fn label(self) -> &'static str {
+        match self {
+            Scope::Project => "project",
+            Scope::Global => "global",
+            Scope::Session => "session",
+        }
+    }
+
+    /// Project ⇄ global; a session provider is promoted to the project.
+    pub fn toggled(self) -> Self {
+        match self {
+            Scope::Project => Scope::Global,
+            Scope::Global | Scope::Session => Scope::Project,
+        }
+    }
+}
+
+/// Session providers as last published by Configure; every reader of
+/// `read_scratch_entries` sees them, nothing writes them to disk.
+static SESSION: Mutex<Vec<ProviderScratchEntry>> = Mutex::new(Vec::new());
+
+fn session_entries() -> Vec<ProviderScratchEntry> {
+    SESSION.lock().map(|s| s.clone()).unwrap_or_default()
+}
+
+/// Replace the published session providers with those among `entries`.
+pub fn publish_session(entries: &[ProviderScratchEntry]) {
+    if let Ok(mut s) = SESSION.lock() {
+        *s = entries.iter().filter(|e| e.scope == Scope::Session).cloned().collect();
+    }
+}
 
 #[derive(Clone, Debug)]
 pub struct ProviderScratchEntry {
@@ -14,18 +66,41 @@ pub struct ProviderScratchEntry {
     pub ptype: String,
     pub tags: Vec<String>,
     pub config: Value,
+    /// Provider keys chi-tui does not edit (written by other tools), saved back as read
+    pub extra: Map<String, Value>,
+    pub scope: Scope,
 }
 
+/// Provider keys read into `ProviderScratchEntry` fields; anything else is `extra`.
+const ENTRY_KEYS: [&str; 5] = ["id", "name", "type", "tags", "config"];
+
 #[derive(Clone, Debug)]
 pub struct ProvidersState {
     pub entries: Vec<ProviderScratchEntry>,
     pub selected: usize,
     pub schema_types: Vec<String>,
     pub schema_map: HashMap<String, Vec<FieldSchema>>, // type -> fields
+    pub privacy_map: HashMap<String, Privacy>, // type -> declared privacy
+    /// Deprecated/retired hosted models the schema lists
+    pub deprecated: Vec<Deprecation>,
     pub test_status: Option<String>,
     pub form: Option<FormState>,
     pub focus_right: bool,
     pub dropdown: Option<DropdownState>,
+    /// Clipboard import awaiting confirmation
+    pub import: Option<ImportPreview>,
+    /// QR code of the selected provider
+    pub qr: Option<ShareQr>,
+    /// Snippet export for other tools (`x`)
+    pub export: Option<ExportPicker>,
+    /// Typed/pasted JSON when no clipboard tool is available
+    pub paste_input: Option<String>,
+    /// Provider made the default by the first save, until undone (`u`)
+    pub auto_default: Option<String>,
+    /// Set once `claim_first_default` has picked one, so an undo sticks
+    auto_default_done: bool,
+    /// `entries_snapshot` as last loaded or saved
+    saved: String,
 }
 
 impl ProvidersState {
@@ -35,10 +110,19 @@ impl ProvidersState {
             selected: 0,
             schema_types: Vec::new(),
             schema_map: HashMap::new(),
+            privacy_map: HashMap::new(),
+            deprecated: Vec::new(),
             test_status: None,
             form: None,
             focus_right: false,
             dropdown: None,
+            import: None,
+            qr: None,
+            export: None,
+            paste_input: None,
+            auto_default: None,
+            auto_default_done: false,
+            saved: entries_snapshot(&[]),
         }
     }
     pub fn len_with_add(&self) -> usize { self.entries.len() + 1 }
@@ -61,9 +145,56 @@ impl ProvidersState {
             ptype: cfg.get("type").and_then(|x| x.as_str()).unwrap_or("").to_string(),
             tags: Vec::new(),
             config: cfg,
+            extra: Map::new(),
+            scope: Scope::Project,
         });
         self.selected = self.entries.len().saturating_sub(1);
     }
+    /// Add a provider that lives only in this session (Configure, `e`).
+    pub fn add_session(&mut self) {
+        let id = (1..).map(|n| format!("session{}", n)).find(|id| !self.entries.iter().any(|e| &e.id == id)).unwrap_or_default();
+        self.add_default();
+        if let Some(e) = self.entries.last_mut() {
+            e.id = id;
+            e.scope = Scope::Session;
+        }
+        self.publish_session();
+    }
+    /// Make the session providers (as currently edited) visible to the
+    /// Playground, Model Browser, benchmarks and the other pages.
+    pub fn publish_session(&self) {
+        publish_session(&self.entries);
+    }
+    /// Duplicate the selected provider right below it with a fresh id
+    /// (`<id>-copy`, `<id>-copy2`, …) and " (copy)" on the name, so variants
+    /// that differ only in model or tags are quick to make. `secret:`
+    /// references are shared; save keeps a secret while any provider uses it.
+    /// Returns the new id.
+    pub fn clone_selected(&mut self) -> Option<String> {
+        let mut copy = self.entries.get(self.selected)?.clone();
+        let taken = |id: &str| self.entries.iter().any(|e| e.id == id);
+        let id = (1..)
+            .map(|n| if n == 1 { format!("{}-copy", copy.id) } else { format!("{}-copy{}", copy.id, n) })
+            .find(|id| !taken(id))?;
+        copy.id = id.clone();
+        copy.name = format!("{} (copy)", copy.name);
+        self.entries.insert(self.selected + 1, copy);
+        self.selected += 1;
+        self.form = None;
+        Some(id)
+    }
+    pub fn privacy_of(&self, e: &ProviderScratchEntry) -> Privacy {
+        crate::privacy::classify(&e.ptype, &e.config, self.privacy_map.get(&e.ptype).copied())
+    }
+    /// Set when the provider's model is deprecated or retired.
+    pub fn deprecation_of(&self, e: &ProviderScratchEntry) -> Option<&Deprecation> {
+        deprecations::find(&self.deprecated, &e.ptype, e.config.get("model").and_then(|v| v.as_str())?)
+    }
+    /// "Warning: …" for saved providers whose model is deprecated or retired.
+    pub fn deprecation_warning(&self) -> Option<String> {
+        let found: Vec<String> = self.entries.iter().filter_map(|e| Some(format!("{}: {}", e.name, self.deprecation_of(e)?.describe()))).collect();
+        if found.is_empty() { None } else { Some(format!("Warning: saved, but {}", found.join(" • "))) }
+    }
     pub fn delete_selected(&mut self) {
         if self.selected < self.entries.len() {
             self.entries.remove(self.selected);
@@ -77,41 +208,155 @@ impl ProvidersState {
             }
         }
     }
-    pub fn save(&self) -> Result<()> {
-        let path = "chi.tmp.json";
-        let mut root: Value = if let Ok(text) = fs::read_to_string(path) {
-            serde_json::from_str(&text).unwrap_or_else(|_| serde_json::json!({}))
-        } else {
-            serde_json::json!({})
-        };
+    /// Config with plain secret values moved to the keychain and replaced
+    /// by `secret:` references. Templates and references are kept as is.
+    fn protected_config(&self, e: &ProviderScratchEntry, namespace: &str) -> Result<Value> {
+        let mut config = e.config.clone();
+        let schema = self.schema_map.get(&e.ptype);
+        let is_secret = |k: &str| k == "api_key" || schema.map_or(false, |fs| fs.iter().any(|f| f.name == k && f.ftype == "secret"));
+        if let Some(obj) = config.as_object_mut() {
+            for (k, v) in obj.iter_mut() {
+                let Some(text) = v.as_str().filter(|t| is_secret(k) && !t.trim().is_empty() && !secrets::is_ref(t) && !t.contains("{{")) else { continue };
+                let name = secrets::ref_name(namespace, &e.id, k);
+                secrets::put(&name, text.trim()).map_err(|err| anyhow!("storing {} of {}: {}", k, e.id, err))?;
+                *v = Value::String(format!("{}{}", secrets::REF_PREFIX, name));
+            }
+        }
+        Ok(config)
+    }
+
+    /// Promote the selected provider to the global list, or demote it to
+    /// this project. Takes effect on save.
+    pub fn toggle_scope_selected(&mut self) -> Option<Scope> {
+        let e = self.entries.get_mut(self.selected)?;
+        e.scope = e.scope.toggled();
+        Some(e.scope)
+    }
+
+    /// Entries changed since load or the last save, or a form edit not yet
+    /// applied to its entry.
+    pub fn has_unsaved_changes(&self) -> bool {
+        let form_dirty = self.form.as_ref().map_or(false, |f| compute_form_hash(&f.fields) != f.initial_hash);
+        form_dirty || entries_snapshot(&self.entries) != self.saved
+    }
+
+    /// Provider objects of one scope, as stored.
+    fn stored_providers(&self, scope: Scope) -> Result<Vec<Value>> {
+        let namespace = secret_namespace(scope)?;
         let mut providers: Vec<Value> = Vec::new();
-        for e in &self.entries {
-            providers.push(serde_json::json!({
-                "id": e.id,
-                "name": e.name,
-                "type": e.ptype,
-                "tags": e.tags,
-                "config": e.config,
-            }));
-        }
-        if !root.is_object() { root = serde_json::json!({}); }
+        for e in self.entries.iter().filter(|e| e.scope == scope) {
+            let mut p = e.extra.clone();
+            p.insert("id".to_string(), Value::String(e.id.clone()));
+            p.insert("name".to_string(), Value::String(e.name.clone()));
+            p.insert("type".to_string(), Value::String(e.ptype.clone()));
+            p.insert("tags".to_string(), serde_json::json!(e.tags));
+            p.insert("config".to_string(), self.protected_config(e, &namespace)?);
+            providers.push(Value::Object(p));
+        }
+        Ok(providers)
+    }
+
+    /// Write project providers to the project store and global ones to the
+    /// global store. The global file is only created once something is in it.
+    pub fn save(&mut self) -> Result<()> {
+        self.publish_session();
+        let mut root = store::read_or_empty();
+        let providers = self.stored_providers(Scope::Project)?;
+        let before = root.clone();
         if let Some(obj) = root.as_object_mut() {
             obj.insert("providers".to_string(), Value::Array(providers));
         }
-        fs::write(path, serde_json::to_vec_pretty(&root)?)?;
+        // Session-only edits leave the store (and its mtime) alone
+        if !store::exists() || root != before {
+            let path = store::write(&root)?;
+            let _ = audit::record("providers.save", &path, &before, &root);
+        }
+        let (mut old, mut new) = (secret_refs(&before), secret_refs(&root));
+
+        let global = self.stored_providers(Scope::Global)?;
+        let mut groot = store::read_global()?;
+        if !global.is_empty() || groot.get("providers").is_some() {
+            let gbefore = groot.clone();
+            if let Some(obj) = groot.as_object_mut() {
+                obj.insert("providers".to_string(), Value::Array(global));
+            }
+            let gpath = store::write_global(&groot)?;
+            let _ = audit::record("providers.save", &gpath, &gbefore, &groot);
+            old.extend(secret_refs(&gbefore));
+            new.extend(secret_refs(&groot));
+        }
+        self.saved = entries_snapshot(&self.entries);
+        // Secrets of removed providers (or replaced references) are dropped,
+        // but only this project's and the global store's: a reference copied
+        // from another project (or from before namespaces) may still be used there
+        let owned = [secret_namespace(Scope::Project)?, secret_namespace(Scope::Global)?].map(|ns| format!("{}/", ns));
+        for name in old.iter().filter(|n| !new.contains(n) && owned.iter().any(|ns| n.starts_with(ns.as_str()))) { secrets::delete(name); }
         Ok(())
     }
+
+    /// After a save: with no default and no rules in either store, the only
+    /// stored provider becomes the default, so Select Default and Build are
+    /// not left with "None". Returns its name; `u` undoes it. Happens at most
+    /// once per session.
+    pub fn claim_first_default(&mut self) -> Option<String> {
+        if self.auto_default_done { return None; }
+        let layered = store::read_layered().ok()?;
+        let has_default = layered.get("default_provider_id").and_then(|v| v.as_str()).map_or(false, |s| !s.is_empty());
+        if has_default || layered.get("default_rules").is_some() { return None; }
+        let mut stored = self.entries.iter().filter(|e| e.scope != Scope::Session);
+        let (Some(only), None) = (stored.next(), stored.next()) else { return None };
+        let (id, name) = (only.id.clone(), only.name.clone());
+        super::save_default_provider(&id).ok()?;
+        self.auto_default = Some(id);
+        self.auto_default_done = true;
+        Some(name)
+    }
+
+    /// Drop the default `claim_first_default` set, unless it was changed since.
+    /// Returns the provider id when something was undone.
+    pub fn undo_auto_default(&mut self) -> Result<Option<String>> {
+        let Some(id) = self.auto_default.take() else { return Ok(None) };
+        let mut root = store::read_or_empty();
+        if root.get("default_provider_id").and_then(|v| v.as_str()) != Some(id.as_str()) { return Ok(None); }
+        let before = root.clone();
+        if let Some(obj) = root.as_object_mut() { obj.remove("default_provider_id"); }
+        let path = store::write(&root)?;
+        let _ = audit::record("default.unset", &path, &before, &root);
+        Ok(Some(id))
+    }
+}
+
+/// Secret namespace of the store `scope` saves to: the project directory
+/// (the store sits in the working directory) or the global providers file.
+fn secret_namespace(scope: Scope) -> Result<String> {
+    Ok(match scope {
+        Scope::Global => secrets::namespace(&store::global_path()?),
+        _ => secrets::namespace(&std::env::current_dir()?),
+    })
+}
+
+/// Names of the `secret:` references in the providers of a store.
+fn secret_refs(root: &Value) -> Vec<String> {
+    let mut out = Vec::new();
+    for p in root.get("providers").and_then(|x| x.as_array()).into_iter().flatten() {
+        for v in p.get("config").and_then(|c| c.as_object()).into_iter().flat_map(|o| o.values()) {
+            if let Some(name) = v.as_str().and_then(|s| s.strip_prefix(secrets::REF_PREFIX)) { out.push(name.to_string()); }
+        }
+    }
+    out
 }
 
 pub fn load_providers_state() -> Result<ProvidersState> {
     // Load schema types and fields
-    let schema = run_cli_json(&["providers", "schema", "--json"], Duration::from_secs(5))?;
+    let schema = discovery_cache::cli(&["providers", "schema", "--json"], Duration::from_secs(5))?;
     let mut types: Vec<String> = Vec::new();
     let mut schema_map: HashMap<String, Vec<FieldSchema>> = HashMap::new();
+    let mut privacy_map: HashMap<String, Privacy> = HashMap::new();
     if let Some(arr) = schema.get("providers").and_then(|v| v.as_array()) {
         for prov in arr {
             if let Some(ptype) = prov.get("type").and_then(|v| v.as_str()) {
                 types.push(ptype.to_string());
+                if let Some(key) = prov.get("privacy").and_then(|v| v.as_str()) { privacy_map.insert(ptype.to_string(), Privacy::from_key(key)); }
                 let mut fields: Vec<FieldSchema> = Vec::new();
                 if let Some(farr) = prov.get("fields").and_then(|v| v.as_array()) {
                     for f in farr {
@@ -133,43 +378,97 @@ pub fn load_providers_state() -> Result<ProvidersState> {
                             }
                         }
                         let options = if opts.is_empty() { None } else { Some(opts) };
-                        fields.push(FieldSchema { name, ftype, required, default, help, options });
+                        let advanced = f.get("advanced").and_then(|v| v.as_bool()).unwrap_or(false);
+                        let (min, max, step) = (f.get("min").and_then(|v| v.as_f64()), f.get("max").and_then(|v| v.as_f64()), f.get("step").and_then(|v| v.as_f64()));
+                        fields.push(FieldSchema { name, ftype, required, default, help, options, advanced, min, max, step });
                     }
                 }
+                if ptype == "lmstudio" || ptype == "ollama" {
+                    fields.extend(crate::portforward::k8s_fields());
+                }
                 schema_map.insert(ptype.to_string(), fields);
             }
         }
     }
     types.sort();
-    // Load scratch file
-    let path = "chi.tmp.json";
-    let text = fs::read_to_string(path).unwrap_or_else(|_| "{}".to_string());
-    let v: Value = serde_json::from_str(&text)?;
-    let mut entries: Vec<ProviderScratchEntry> = Vec::new();
-    if let Some(arr) = v.get("providers").and_then(|x| x.as_array()) {
-        for p in arr {
-            let id = p.get("id").and_then(|x| x.as_str()).unwrap_or("").to_string();
-            let name = p.get("name").and_then(|x| x.as_str()).unwrap_or(&id).to_string();
-            let ptype = p.get("type").and_then(|x| x.as_str()).unwrap_or("").to_string();
-            let tags: Vec<String> = p.get("tags").and_then(|x| x.as_array()).map(|a| {
-                a.iter().filter_map(|t| t.as_str().map(|s| s.to_string())).collect()
-            }).unwrap_or_default();
-            let config = p.get("config").cloned().unwrap_or_else(|| serde_json::json!({"type": ptype}));
-            entries.push(ProviderScratchEntry { id, name, ptype, tags, config });
-        }
-    }
+    // Raw: the form edits and saves templates, not their values
+    let entries = read_scratch_entries_raw()?;
+    let saved = entries_snapshot(&entries);
     Ok(ProvidersState {
         entries,
         selected: 0,
         schema_types: types,
         schema_map,
+        privacy_map,
+        deprecated: deprecations::parse(&schema),
         test_status: None,
         form: None,
         focus_right: false,
         dropdown: None,
+        import: None,
+        qr: None,
+        export: None,
+        paste_input: None,
+        auto_default: None,
+        auto_default_done: false,
+        saved,
     })
 }
 
+/// Provider types for the type dropdown; most private first when preferred.
+/// Takes the fields rather than `&ProvidersState` so callers can hold the form.
+pub fn type_choices(types: &[String], privacy_map: &HashMap<String, Privacy>, prefer_private: bool) -> Vec<String> {
+    let mut types = types.to_vec();
+    if prefer_private {
+        types.sort_by_key(|t| privacy_map.get(t).copied().unwrap_or_else(|| crate::privacy::builtin(t)).rank());
+    }
+    types
+}
+
+/// Configured providers with `{{ variables }}` substituted, for anything
+/// that talks to them.
+pub fn read_scratch_entries() -> Result<Vec<ProviderScratchEntry>> {
+    let vars = crate::variables::load_variables();
+    Ok(read_scratch_entries_raw()?.iter().map(|e| crate::variables::resolve_entry_with(e, &vars)).collect())
+}
+
+/// Read configured providers as written: the project store (chi.tmp.json)
+/// first, then global providers whose id the project does not reuse, then
+/// this session's providers.
+pub fn read_scratch_entries_raw() -> Result<Vec<ProviderScratchEntry>> {
+    let project = if store::exists() { store::read()? } else { serde_json::json!({}) };
+    let mut entries = entries_of(&project, Scope::Project);
+    for g in entries_of(&store::read_global()?, Scope::Global) {
+        if !entries.iter().any(|e| e.id == g.id) { entries.push(g); }
+    }
+    entries.extend(session_entries());
+    Ok(entries)
+}
+
+fn entries_of(v: &Value, scope: Scope) -> Vec<ProviderScratchEntry> {
+    let mut entries: Vec<ProviderScratchEntry> = Vec::new();
+    if let Some(arr) = v.get("providers").and_then(|x| x.as_array()) {
+        // Hand-edited or foreign stores: skip what is not a provider object and
+        // fall back to defaults for fields of the wrong type
+        for p in arr.iter().filter_map(|p| p.as_object()) {
+            let id = match p.get("id") {
+                Some(Value::String(s)) => s.clone(),
+                Some(Value::Number(n)) => n.to_string(),
+                _ => String::new(),
+            };
+            let name = p.get("name").and_then(|x| x.as_str()).unwrap_or(&id).to_string();
+            let ptype = p.get("type").and_then(|x| x.as_str()).unwrap_or("").to_string();
+            let tags: Vec<String> = p.get("tags").and_then(|x| x.as_array()).map(|a| {
+                a.iter().filter_map(|t| t.as_str().map(|s| s.to_string())).collect()
+            }).unwrap_or_default();
+            let config = p.get("config").filter(|c| c.is_object()).cloned().unwrap_or_else(|| serde_json::json!({"type": ptype}));
+            let extra = p.iter().filter(|(k, _)| !ENTRY_KEYS.contains(&k.as_str())).map(|(k, v)| (k.clone(), v.clone())).collect();
+            entries.push(ProviderScratchEntry { id, name, ptype, tags, config, extra, scope });
+        }
+    }
+    entries
+}
+
 #[derive(Clone, Debug)]
 pub struct FieldSchema {
     pub name: String,
@@ -178,6 +477,70 @@ pub struct FieldSchema {
     pub default: Option<String>,
     pub help: Option<String>,
     pub options: Option<Vec<String>>, // optional enum-like options for dropdowns
+    pub advanced: bool, // hidden behind the "Advanced ▸" expander
+    pub min: Option<f64>,
+    pub max: Option<f64>,
+    pub step: Option<f64>,
+}
+
+impl FieldSchema {
+    pub fn is_numeric(&self) -> bool {
+        self.ftype == "int" || self.ftype == "float"
+    }
+
+    fn format_number(&self, v: f64) -> String {
+        if self.ftype == "int" { format!("{}", v.round() as i64) } else { format!("{}", (v * 1000.0).round() / 1000.0) }
+    }
+
+    /// Move `buffer` by `steps` increments, clamped to the schema bounds.
+    /// An empty or unparsable buffer starts from the default (or min, or 0).
+    pub fn step_value(&self, buffer: &str, steps: f64) -> String {
+        let start = buffer
+            .trim()
+            .parse::<f64>()
+            .ok()
+            .or_else(|| self.default.as_deref().and_then(|d| d.parse().ok()))
+            .or(self.min)
+            .unwrap_or(0.0);
+        let step = self.step.unwrap_or(if self.ftype == "int" { 1.0 } else { 0.1 });
+        let mut v = start + steps * step;
+        if let Some(lo) = self.min { v = v.max(lo); }
+        if let Some(hi) = self.max { v = v.min(hi); }
+        self.format_number(v)
+    }
+
+    /// "1–600" style label for the field title.
+    pub fn range_label(&self) -> Option<String> {
+        match (self.min, self.max) {
+            (Some(lo), Some(hi)) => Some(format!("{}–{}", self.format_number(lo), self.format_number(hi))),
+            (Some(lo), None) => Some(format!("≥ {}", self.format_number(lo))),
+            (None, Some(hi)) => Some(format!("≤ {}", self.format_number(hi))),
+            (None, None) => None,
+        }
+    }
+
+    /// Fraction of the allowed range `buffer` sits at, for the slider bar.
+    pub fn slider_fraction(&self, buffer: &str) -> Option<f64> {
+        let (lo, hi) = (self.min?, self.max?);
+        let v: f64 = buffer.trim().parse().ok()?;
+        if hi <= lo { return None; }
+        Some(((v - lo) / (hi - lo)).clamp(0.0, 1.0))
+    }
+
+    /// Check a numeric value; empty optional fields are fine.
+    pub fn validate(&self, buffer: &str) -> std::result::Result<(), String> {
+        let b = buffer.trim();
+        if !self.is_numeric() || b.is_empty() { return Ok(()); }
+        let v: f64 = if self.ftype == "int" {
+            b.parse::<i64>().map(|n| n as f64).map_err(|_| format!("{}: not an integer", self.name))?
+        } else {
+            b.parse::<f64>().map_err(|_| format!("{}: not a number", self.name))?
+        };
+        if self.min.map_or(false, |lo| v < lo) || self.max.map_or(false, |hi| v > hi) {
+            return Err(format!("{}: must be {}", self.name, self.range_label().unwrap_or_default()));
+        }
+        Ok(())
+    }
 }
 
 #[derive(Clone, Debug)]
@@ -185,13 +548,81 @@ pub struct FormField { pub schema: FieldSchema, pub buffer: String, pub cursor:
 
 #[derive(Clone, Debug)]
 pub struct FormState {
-    pub fields: Vec<FormField>,
-    pub selected: usize, // 0: Type, 1..=fields: fields, fields+1: Save, fields+2: Cancel
+    pub fields: Vec<FormField>, // basic fields first, then advanced
+    pub selected: usize, // 0: Type, 1..=visible fields, [Advanced ▸], Test, Save, Cancel
     pub editing: bool,
     pub message: Option<String>,
     pub scroll: usize,
     pub initial_hash: String,
+    /// Field buffers as opened or last saved, for the changed-fields summary
+    pub initial: Vec<String>,
     pub last_test_ok_hash: Option<String>,
+    pub basic_len: usize,
+    pub show_advanced: bool,
+}
+
+impl FormState {
+    /// Number of fields currently shown (advanced ones only when expanded).
+    pub fn visible_len(&self) -> usize {
+        if self.show_advanced { self.fields.len() } else { self.basic_len }
+    }
+    /// Row of the "Advanced ▸" expander; None when the type has no advanced fields.
+    pub fn expander_idx(&self) -> Option<usize> {
+        if self.basic_len < self.fields.len() { Some(self.visible_len() + 1) } else { None }
+    }
+    pub fn test_idx(&self) -> usize {
+        self.visible_len() + 1 + usize::from(self.expander_idx().is_some())
+    }
+    pub fn save_idx(&self) -> usize { self.test_idx() + 1 }
+    pub fn cancel_idx(&self) -> usize { self.test_idx() + 2 }
+    /// Total number of selectable rows.
+    pub fn total(&self) -> usize { self.test_idx() + 3 }
+    /// Fields edited since the form was opened or saved: (name, old, new),
+    /// secrets masked.
+    pub fn changes(&self) -> Vec<(String, String, String)> {
+        let shown = |f: &FormField, v: &str| match v {
+            "" => "(empty)".to_string(),
+            _ if f.schema.ftype == "secret" => "••••••".to_string(),
+            v => v.to_string(),
+        };
+        self.fields
+            .iter()
+            .zip(&self.initial)
+            .filter(|(f, old)| f.buffer != **old)
+            .map(|(f, old)| (f.schema.name.clone(), shown(f, old), shown(f, &f.buffer)))
+            .collect()
+    }
+    /// Why Save refuses the values (missing required fields, values outside
+    /// the schema); None when they can be saved.
+    pub fn problem(&self) -> Option<String> {
+        let missing: Vec<&str> = self.fields.iter().filter(|f| f.schema.required && f.buffer.trim().is_empty()).map(|f| f.schema.name.as_str()).collect();
+        if !missing.is_empty() { return Some(format!("Missing required: {}", missing.join(", "))); }
+        let invalid: Vec<String> = self.fields.iter().filter_map(|f| f.schema.validate(&f.buffer).err()).collect();
+        if !invalid.is_empty() { return Some(format!("Error: {}", invalid.join("; "))); }
+        None
+    }
+    /// Mark the current values as saved.
+    pub fn mark_saved(&mut self) {
+        self.initial_hash = compute_form_hash(&self.fields);
+        self.initial = self.fields.iter().map(|f| f.buffer.clone()).collect();
+        self.last_test_ok_hash = Some(self.initial_hash.clone());
+    }
+    pub fn toggle_advanced(&mut self) {
+        self.show_advanced = !self.show_advanced;
+        self.editing = false;
+        if let Some(idx) = self.expander_idx() { self.selected = idx; }
+    }
+}
+
+/// Comparable form of the entries for unsaved-change checks.
+/// Stored entries only; session providers are never unsaved changes.
+fn entries_snapshot(entries: &[ProviderScratchEntry]) -> String {
+    let mut s = String::new();
+    for e in entries.iter().filter(|e| e.scope != Scope::Session) {
+        s.push_str(&serde_json::json!([e.id, e.name, e.ptype, e.tags, e.config, e.scope.label()]).to_string());
+        s.push('\u{1F}');
+    }
+    s
 }
 
 pub fn compute_form_hash(fields: &Vec<FormField>) -> String {
@@ -0,0 +1,163 @@
+//! Provider list tests against a fake `chi-llm`: scopes, clones, session
+//! providers, retired models and the Configure form.
+
+use serde_json::json;
+
+use super::*;
+use crate::app::{App, Page};
+use crate::config_cli::{run_config, ConfigCmd};
+use crate::store;
+use crate::testing::{add_provider, screen, FakeCli};
+
+#[test]
+fn providers_move_between_project_and_global() {
+    let fake = FakeCli::new();
+    run_config(add_provider("ollama", "home", &[], false)).expect("add provider");
+    run_config(add_provider("ollama", "shared", &[], true)).expect("add provider");
+
+    let mut st = load_providers_state().expect("state");
+    st.selected = st.entries.iter().position(|e| e.id == "shared").expect("shared");
+    assert_eq!(st.toggle_scope_selected(), Some(Scope::Global));
+    st.save().expect("save");
+    assert_eq!(fake.store()["providers"].as_array().expect("providers").len(), 1);
+    assert_eq!(store::read_global().expect("global")["providers"][0]["id"], "shared");
+    // Readers see both layers; the default may live in the global one
+    let scopes: Vec<(String, Scope)> = read_scratch_entries_raw().expect("entries").into_iter().map(|e| (e.id, e.scope)).collect();
+    assert_eq!(scopes, vec![("home".to_string(), Scope::Project), ("shared".to_string(), Scope::Global)]);
+    assert_eq!(crate::build::get_default_provider_summary().expect("default").0, "shared");
+
+    // A project provider with the same id shadows the global one
+    let mut root = fake.store();
+    root["providers"].as_array_mut().expect("providers").push(json!({"id": "shared", "type": "ollama", "config": {"type": "ollama"}}));
+    store::write(&root).expect("write store");
+    let entries = read_scratch_entries_raw().expect("entries");
+    assert_eq!(entries.len(), 2);
+    assert!(entries.iter().all(|e| e.scope == Scope::Project));
+}
+
+#[test]
+fn providers_clone_into_variants() {
+    let fake = FakeCli::new();
+    run_config(add_provider("ollama", "ollama", &[("model", "llama3.2")], true)).expect("add provider");
+
+    let mut st = load_providers_state().expect("state");
+    assert_eq!(st.clone_selected().as_deref(), Some("ollama-copy"));
+    assert_eq!(st.clone_selected().as_deref(), Some("ollama-copy-copy"));
+    st.selected = 0;
+    assert_eq!(st.clone_selected().as_deref(), Some("ollama-copy2"));
+    assert_eq!(st.selected, 1);
+    st.save().expect("save");
+
+    let providers = fake.store()["providers"].as_array().expect("providers").clone();
+    let ids: Vec<&str> = providers.iter().filter_map(|p| p["id"].as_str()).collect();
+    assert_eq!(ids, vec!["ollama", "ollama-copy2", "ollama-copy", "ollama-copy-copy"]);
+    assert!(providers.iter().all(|p| p["config"]["model"] == "llama3.2"));
+    assert_eq!(providers[1]["name"].as_str().map(|n| n.ends_with(" (copy)")), Some(true));
+    // The clone is a new provider, not a second default
+    assert_eq!(crate::build::get_default_provider_summary().expect("default").0, "ollama");
+}
+
+#[test]
+fn session_providers_stay_off_disk_until_promoted() {
+    let fake = FakeCli::new();
+    run_config(add_provider("ollama", "home", &[], true)).expect("add provider");
+    let mut st = load_providers_state().expect("state");
+    st.add_session();
+    let id = st.entries[st.selected].id.clone();
+    assert_eq!(id, "session1");
+    st.apply_model_to_selected("qwen3-1.7b");
+    st.publish_session();
+
+    // Every reader sees it; the store never does, and quitting loses nothing
+    let entries = read_scratch_entries().expect("entries");
+    let session = entries.iter().find(|e| e.id == id).expect("session provider listed");
+    assert_eq!((session.scope, session.config["model"].as_str()), (Scope::Session, Some("qwen3-1.7b")));
+    assert!(!st.has_unsaved_changes());
+    st.save().expect("save");
+    assert_eq!(fake.store()["providers"].as_array().map(|a| a.len()), Some(1));
+    assert_eq!(load_providers_state().expect("reload").entries.len(), 2);
+
+    // `g` promotes it to the project
+    assert_eq!(st.toggle_scope_selected(), Some(Scope::Project));
+    st.save().expect("save");
+    assert_eq!(fake.store()["providers"][1]["id"], "session1");
+    assert!(read_scratch_entries_raw().expect("entries").iter().all(|e| e.scope == Scope::Project));
+}
+
+#[test]
+fn retired_models_are_flagged_with_their_replacement() {
+    let mut fake = FakeCli::new();
+    let mut schema = crate::testing::default_schema();
+    schema["deprecated_models"] = json!([
+        {"type": "openai", "id": "gpt-4-32k", "retires": "2025-06-06", "replacement": "gpt-4o", "status": "retired"},
+        {"type": "openai", "id": "no-date", "status": "deprecated"},
+        {"id": "missing-type"},
+    ]);
+    fake.respond("providers schema", schema);
+    run_config(add_provider("openai", "oa", &[("api_key", "sk-test"), ("model", "GPT-4-32K")], true)).expect("add provider");
+    run_config(add_provider("ollama", "home", &[("model", "gpt-4-32k")], false)).expect("add provider");
+
+    let mut st = load_providers_state().expect("state");
+    assert_eq!(st.deprecated.len(), 2);
+    // Only the vendor's own type, whatever the case
+    let d = st.deprecation_of(&st.entries[0]).expect("flagged");
+    assert_eq!(d.describe(), "gpt-4-32k was retired on 2025-06-06; use gpt-4o instead");
+    assert!(st.deprecation_of(&st.entries[1]).is_none());
+    assert_eq!(st.deprecated[1].describe(), "no-date is deprecated");
+
+    st.save().expect("save");
+    assert_eq!(st.deprecation_warning().as_deref(), Some("Warning: saved, but openai: gpt-4-32k was retired on 2025-06-06; use gpt-4o instead"));
+
+    let mut app = App::new(false);
+    crate::open_page_loaded(&mut app, Page::Configure);
+    assert!(screen(&app, 160, 30).contains("[⚠ model retired]"));
+    st.apply_model_to_selected("gpt-4o");
+    assert!(st.deprecation_warning().is_none());
+}
+
+#[test]
+fn the_form_summarises_changed_fields_until_saved() {
+    let _fake = FakeCli::new();
+    run_config(add_provider("openai", "oa", &[("api_key", "sk-old"), ("model", "gpt-4o-mini")], true)).expect("add provider");
+    let mut app = App::new(false);
+    crate::open_page_loaded(&mut app, Page::Configure);
+    let st = app.providers.as_mut().expect("providers");
+    crate::keys::ensure_form_for_selected(st);
+    let form = st.form.as_mut().expect("form");
+    assert!(form.changes().is_empty());
+    for f in form.fields.iter_mut() {
+        match f.schema.name.as_str() {
+            "model" => f.buffer = "gpt-4.1".to_string(),
+            "api_key" => f.buffer = "sk-new".to_string(),
+            _ => {}
+        }
+    }
+    let changes = form.changes();
+    assert!(changes.contains(&("model".to_string(), "gpt-4o-mini".to_string(), "gpt-4.1".to_string())), "{:?}", changes);
+    // Secrets never show, only that they changed
+    assert!(changes.contains(&("api_key".to_string(), "••••••".to_string(), "••••••".to_string())), "{:?}", changes);
+    assert!(screen(&app, 160, 40).contains("~ model: gpt-4o-mini → gpt-4.1"));
+
+    let form = app.providers.as_mut().and_then(|st| st.form.as_mut()).expect("form");
+    form.mark_saved();
+    assert!(form.changes().is_empty());
+}
+
+#[test]
+fn long_names_are_cut_with_an_ellipsis() {
+    let _fake = FakeCli::new();
+    let long_name = "GPU box with a very long descriptive provider name";
+    let mut cmd = add_provider("ollama", "gpu", &[("model", "deepseek-coder-v2:16b-lite-instruct-q4_K_M")], true);
+    if let ConfigCmd::Add { name, .. } = &mut cmd { *name = Some(long_name.to_string()); }
+    run_config(cmd).expect("add provider");
+
+    let mut app = App::new(false);
+    crate::open_page_loaded(&mut app, Page::Configure);
+    assert!(screen(&app, 160, 30).contains("[model:deepseek-coder-v2:16b…]"));
+
+    crate::open_page_loaded(&mut app, Page::SelectDefault);
+    let shown = screen(&app, 80, 24);
+    assert!(shown.contains("GPU box with a very long…"), "{}", shown);
+    // The selected row's full name is spelled out below the list
+    assert!(shown.contains(&format!("› {} (gpu)", long_name)), "{}", shown);
+}
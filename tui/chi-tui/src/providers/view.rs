@@ -1,51 +1,65 @@
-use std::time::Duration;
 
-use anyhow::Result;
-use ratatui::layout::{Rect, Layout, Direction, Constraint};
+use anyhow::{anyhow, Result};
+use ratatui::layout::{Alignment, Rect, Layout, Direction, Constraint};
 use ratatui::prelude::Frame;
-use ratatui::style::{Modifier, Style};
+use ratatui::style::{Color, Modifier, Style};
 use ratatui::text::{Line, Span};
 use ratatui::widgets::{Block, Borders, Clear, List, ListItem, Paragraph, Wrap};
-use crate::util::run_cli_json;
+use crate::discovery_cache;
 use super::state::compute_form_hash;
 use serde_json::Value;
 
 use crate::app::App;
-use crate::util::centered_rect;
+use crate::export::{self, Format};
+use crate::text;
+use crate::focus::pane_block;
+use crate::mouse::Target;
+use crate::util::{overlay_rect, split_panes};
 
 use super::{ProvidersState, FormField};
 
+/// Rows of the changed-fields summary in the form; more changes are listed by name.
+const MAX_CHANGE_ROWS: usize = 3;
+
 pub fn draw_providers_catalog(f: &mut Frame, area: Rect, app: &App) {
-    let cols = Layout::default()
-        .direction(Direction::Horizontal)
-        .constraints([Constraint::Percentage(45), Constraint::Percentage(55)]).split(area);
+    let cols = split_panes(app.stacked(), 45, area);
 
-    // Left list
+    // Left list; long names and models end in "…", the form shows them in full
     let mut items: Vec<ListItem> = Vec::new();
+    let clip_w = (cols[0].width.saturating_sub(4) as usize / 3).max(12);
     if let Some(st) = &app.providers {
         for (i, e) in st.entries.iter().enumerate() {
-            let mut label = format!("{} {} [{}]", if i == st.selected { '›' } else { ' ' }, e.name, e.ptype);
-            if let Some(model) = e.config.get("model").and_then(|v| v.as_str()) { label.push_str(&format!("  [model:{}]", model)); }
+            let mut label = format!("{} {} [{}]", if i == st.selected { '›' } else { ' ' }, text::truncate(&e.name, clip_w), e.ptype);
+            if let Some(model) = e.config.get("model").and_then(|v| v.as_str()) { label.push_str(&format!("  [model:{}]", text::truncate(model, clip_w))); }
             if !e.tags.is_empty() { label.push_str(&format!("  [{}]", e.tags.join(","))); }
+            if let Some(pf) = app.portfw.label(&e.id) { label.push_str(&format!("  [{}]", pf)); }
+            let privacy = st.privacy_of(e);
             let mut style = if i == st.selected { Style::default().fg(app.theme.selected).add_modifier(Modifier::BOLD) } else { Style::default().fg(app.theme.fg) };
             if !st.focus_right && i == st.selected { style = style.add_modifier(Modifier::UNDERLINED); }
-            items.push(ListItem::new(Line::from(Span::styled(label, style))));
+            let origin = Span::styled(format!("  [{}]", e.scope.label()), Style::default().fg(app.theme.secondary));
+            let mut spans = vec![Span::styled(label, style), Span::styled(format!("  [{}]", privacy.label()), Style::default().fg(privacy.color(&app.theme))), origin];
+            if let Some(d) = st.deprecation_of(e) { spans.push(Span::styled(format!("  [⚠ model {}]", d.label()), Style::default().fg(app.theme.warn))); }
+            items.push(ListItem::new(Line::from(spans)));
         }
         let mut add_style = if st.is_add_row() { Style::default().fg(app.theme.selected).add_modifier(Modifier::BOLD) } else { Style::default().fg(app.theme.accent) };
         if !st.focus_right && st.is_add_row() { add_style = add_style.add_modifier(Modifier::UNDERLINED); }
         items.push(ListItem::new(Line::from(Span::styled("+ Add provider", add_style))));
         if let Some(status) = &st.test_status {
-            items.push(ListItem::new(Line::from(Span::styled(format!("Status: {}", status), Style::default().fg(app.theme.secondary)))));
+            let (txt, st_style) = app.theme.status_text(status);
+            items.push(ListItem::new(Line::from(vec![Span::styled("Status: ", Style::default().fg(app.theme.secondary)), Span::styled(txt, st_style)])));
         }
     } else {
         items.push(ListItem::new("Loading providers..."));
     }
-    // Highlight left pane when it has focus (focus_right == false)
-    let left_border = if let Some(st) = &app.providers { if !st.focus_right { app.theme.selected } else { app.theme.frame } } else { app.theme.frame };
+    let list_focused = app.providers.as_ref().map_or(false, |st| !st.focus_right);
     let list = List::new(items)
-        .block(Block::default().borders(Borders::ALL).border_style(Style::default().fg(left_border)).title("Configure Providers"))
+        .block(pane_block(&app.theme, "Configure Providers", list_focused))
         .highlight_style(Style::default().fg(app.theme.selected));
     f.render_widget(list, cols[0]);
+    if let Some(st) = &app.providers {
+        let inner = Block::default().borders(Borders::ALL).inner(cols[0]);
+        app.hits.rows(inner, 0, st.len_with_add(), |_| 1, Target::Provider);
+    }
 
     // Right form panel
     let right = cols[1];
@@ -54,14 +68,18 @@ pub fn draw_providers_catalog(f: &mut Frame, area: Rect, app: &App) {
         if st.selected < st.entries.len() {
             let entry = &st.entries[st.selected];
             title = format!("Provider Details — {}", entry.ptype);
-            let fields: Vec<FormField> = if let Some(form) = &st.form { form.fields.clone() } else { Vec::new() };
-            if fields.is_empty() {
+            let fields: Vec<FormField> = if let Some(form) = &st.form { form.fields[..form.visible_len()].to_vec() } else { Vec::new() };
+            let expander = st.form.as_ref().and_then(|form| form.expander_idx().map(|idx| (idx, form)));
+            if fields.is_empty() && expander.is_none() {
                 let p = Paragraph::new("Tab to open form").style(Style::default().bg(app.theme.bg).fg(app.theme.secondary)).block(Block::default().borders(Borders::ALL).border_style(Style::default().fg(app.theme.frame)).title(title));
                 f.render_widget(p, right);
             } else {
                 // layout with type row, fields (scroll), message, buttons
                 let total_height = right.height as usize;
-                let reserve = 3 + 1 + 3;
+                // Changed fields (old → new) above the buttons, at most MAX_CHANGE_ROWS
+                let changes = st.form.as_ref().map(|f| f.changes()).unwrap_or_default();
+                let change_rows = changes.len().min(MAX_CHANGE_ROWS);
+                let reserve = 3 + 1 + 3 + usize::from(expander.is_some()) + change_rows;
                 let per_field = 3usize;
                 let max_fields_visible = if total_height > reserve { (total_height - reserve) / per_field } else { 0 };
                 let mut start = 0usize; let mut end = fields.len();
@@ -77,6 +95,9 @@ pub fn draw_providers_catalog(f: &mut Frame, area: Rect, app: &App) {
                 let mut cons: Vec<Constraint> = Vec::new();
                 cons.push(Constraint::Length(3));
                 cons.extend(std::iter::repeat(Constraint::Length(3)).take(visible.len()));
+                let exp_rows = usize::from(expander.is_some());
+                if exp_rows == 1 { cons.push(Constraint::Length(1)); }
+                cons.push(Constraint::Length(change_rows as u16));
                 cons.push(Constraint::Length(1));
                 cons.push(Constraint::Length(3));
                 let chunks = Layout::default().direction(Direction::Vertical).constraints(cons).split(right);
@@ -85,6 +106,7 @@ pub fn draw_providers_catalog(f: &mut Frame, area: Rect, app: &App) {
                     let style = if st.focus_right && sel == 0 { Style::default().fg(app.theme.selected).add_modifier(Modifier::BOLD) } else { Style::default().fg(app.theme.fg) };
                     let p = Paragraph::new(format!("Type: {}  (Enter to change)", entry.ptype)).style(Style::default().bg(app.theme.bg).fg(app.theme.fg)).block(Block::default().borders(Borders::ALL).border_style(style));
                     f.render_widget(p, chunks[0]);
+                    app.hits.add(chunks[0], Target::FormRow(0));
                 }
                 for (i_vis, ff) in visible.iter().enumerate() {
                     let i = start + i_vis;
@@ -98,24 +120,69 @@ pub fn draw_providers_catalog(f: &mut Frame, area: Rect, app: &App) {
                         display.insert(byte_idx, '▌');
                     }
                     let mut bstyle = Style::default().fg(app.theme.frame);
-                    if ff.schema.required && ff.buffer.trim().is_empty() { bstyle = Style::default().fg(ratatui::style::Color::Red); }
+                    if ff.schema.required && ff.buffer.trim().is_empty() { bstyle = Style::default().fg(app.theme.err); }
                     if is_selected { bstyle = Style::default().fg(app.theme.selected).add_modifier(Modifier::BOLD); }
-                    let title_txt = if ff.schema.required { format!("* {}", ff.schema.name) } else { ff.schema.name.clone() };
+                    let mut title_txt = if ff.schema.required { format!("* {}", ff.schema.name) } else { ff.schema.name.clone() };
+                    if ff.schema.is_numeric() {
+                        if let Some(range) = ff.schema.range_label() { title_txt = format!("{} [{}] −/+", title_txt, range); }
+                        if let Err(e) = ff.schema.validate(&ff.buffer) { bstyle = Style::default().fg(app.theme.err); title_txt = format!("{} ✗ {}", title_txt, e.split(": ").last().unwrap_or("")); }
+                        if !(is_selected && is_editing) {
+                            if let Some(frac) = ff.schema.slider_fraction(&ff.buffer) {
+                                let width = 12usize;
+                                let filled = (frac * width as f64).round() as usize;
+                                display = format!("{:<8} ▕{}{}▏", display, "█".repeat(filled), "░".repeat(width - filled));
+                            }
+                        }
+                    }
+                    // Checked as typed, so the warning clears once a current model is entered
+                    if ff.schema.name == "model" {
+                        if let Some(d) = crate::deprecations::find(&st.deprecated, &entry.ptype, &ff.buffer) {
+                            if !is_selected { bstyle = Style::default().fg(app.theme.warn); }
+                            title_txt = format!("{} ⚠ {}", title_txt, d.describe());
+                        }
+                    }
                     let block = Block::default().borders(Borders::ALL).border_style(bstyle).title(title_txt);
                     let p = Paragraph::new(display).style(Style::default().bg(app.theme.bg).fg(app.theme.fg)).block(block).wrap(Wrap { trim: false });
                     f.render_widget(p, chunks[1 + i_vis]);
+                    app.hits.add(chunks[1 + i_vis], Target::FormRow(i + 1));
+                }
+                if let Some((idx, form)) = expander {
+                    let hidden = form.fields.len() - form.basic_len;
+                    let label = if form.show_advanced { format!("Advanced ▾ ({} fields, Enter to hide)", hidden) } else { format!("Advanced ▸ ({} more fields)", hidden) };
+                    let style = if st.focus_right && form.selected == idx { Style::default().fg(app.theme.selected).add_modifier(Modifier::BOLD) } else { Style::default().fg(app.theme.accent) };
+                    f.render_widget(Paragraph::new(Span::styled(label, style)), chunks[1 + visible.len()]);
+                    app.hits.add(chunks[1 + visible.len()], Target::FormRow(idx));
+                }
+                if change_rows > 0 {
+                    let val_w = (right.width as usize / 3).max(8);
+                    let mut lines: Vec<Line> = changes
+                        .iter()
+                        .take(if changes.len() > MAX_CHANGE_ROWS { MAX_CHANGE_ROWS - 1 } else { MAX_CHANGE_ROWS })
+                        .map(|(name, old, new)| Line::from(vec![
+                            Span::styled(format!("~ {}: ", name), Style::default().fg(app.theme.secondary)),
+                            Span::styled(text::truncate(old, val_w), Style::default().fg(app.theme.err)),
+                            Span::styled(" → ", Style::default().fg(app.theme.secondary)),
+                            Span::styled(text::truncate(new, val_w), Style::default().fg(app.theme.ok)),
+                        ]))
+                        .collect();
+                    if changes.len() > MAX_CHANGE_ROWS {
+                        let rest: Vec<&str> = changes[MAX_CHANGE_ROWS - 1..].iter().map(|(n, _, _)| n.as_str()).collect();
+                        lines.push(Line::from(Span::styled(format!("~ and {} more: {}", rest.len(), rest.join(", ")), Style::default().fg(app.theme.secondary))));
+                    }
+                    f.render_widget(Paragraph::new(lines).style(Style::default().bg(app.theme.bg)), chunks[1 + visible.len() + exp_rows]);
                 }
                 if let Some(form) = &st.form {
-                    let mut msg = form.message.clone().unwrap_or_default();
+                    let raw = form.message.clone().unwrap_or_default();
+                    let (mut msg, msg_style) = if raw.is_empty() { (raw, Style::default().fg(app.theme.secondary)) } else { app.theme.status_text(&raw) };
                     if fields.len() > end { msg = format!("{}  ↓ more…", msg); }
                     if start > 0 { msg = format!("↑ more…  {}", msg); }
-                    let p = Paragraph::new(msg).style(Style::default().bg(app.theme.bg).fg(app.theme.secondary)).block(Block::default());
-                    f.render_widget(p, chunks[1 + visible.len()]);
-                    let buttons_area = chunks[1 + visible.len() + 1];
+                    let p = Paragraph::new(msg).style(msg_style.bg(app.theme.bg)).block(Block::default());
+                    f.render_widget(p, chunks[1 + visible.len() + exp_rows + 1]);
+                    let buttons_area = chunks[1 + visible.len() + exp_rows + 2];
                     let sel = form.selected;
-                    let test_idx = fields.len() + 1;
-                    let save_idx = fields.len() + 2;
-                    let cancel_idx = fields.len() + 3;
+                    let test_idx = form.test_idx();
+                    let save_idx = form.save_idx();
+                    let cancel_idx = form.cancel_idx();
                     // Compute save enabled: disabled if dirty and not tested ok for current values
                     let cur_hash = crate::providers::compute_form_hash(&form.fields);
                     let dirty = cur_hash != form.initial_hash;
@@ -128,6 +195,11 @@ pub fn draw_providers_catalog(f: &mut Frame, area: Rect, app: &App) {
                     let btns = vec![Line::from(vec![Span::styled("[ Test ]  ", test_style), Span::styled("[ Save ]  ", save_style), Span::styled("[ Cancel ]", cancel_style)])];
                     let p = Paragraph::new(btns).style(Style::default().bg(app.theme.bg).fg(app.theme.fg)).block(Block::default().borders(Borders::ALL).border_style(Style::default().fg(app.theme.frame)).title(title)).alignment(ratatui::layout::Alignment::Left);
                     f.render_widget(p, buttons_area);
+                    // "[ Test ]  [ Save ]  [ Cancel ]" inside the border
+                    let row = Rect::new(buttons_area.x + 1, buttons_area.y + 1, 0, 1);
+                    for (x, width, idx) in [(0, 8, test_idx), (10, 8, save_idx), (20, 10, cancel_idx)] {
+                        app.hits.add(Rect { x: row.x + x, width, ..row }.intersection(buttons_area), Target::FormRow(idx));
+                    }
                 }
             }
         } else {
@@ -139,21 +211,26 @@ pub fn draw_providers_catalog(f: &mut Frame, area: Rect, app: &App) {
         f.render_widget(p, right);
     }
 
-    // Draw an outer border around the right pane to indicate active focus
+    // The pane's own border and title over the inner blocks' edges
     if let Some(st) = &app.providers {
-        let right_border = if st.focus_right { app.theme.selected } else { app.theme.frame };
-        let outer = Block::default().borders(Borders::ALL).border_style(Style::default().fg(right_border));
-        f.render_widget(outer, right);
+        let title = st.entries.get(st.selected).map_or("Provider Details".to_string(), |e| format!("Provider Details — {}", e.ptype));
+        f.render_widget(pane_block(&app.theme, &title, st.focus_right), right);
     }
 
     // Overlay dropdown
     if let Some(st) = &app.providers {
         if let Some(dd) = &st.dropdown {
-            let area_pop = centered_rect(50, 60, area);
+            let area_pop = overlay_rect(app.compact, 50, 60, area);
             let mut items: Vec<ListItem> = Vec::new();
             for (i, it) in dd.items.iter().enumerate() {
                 let style = if i == dd.selected { Style::default().fg(app.theme.selected).add_modifier(Modifier::BOLD) } else { Style::default().fg(app.theme.fg) };
-                items.push(ListItem::new(Line::from(Span::styled(it.clone(), style))));
+                let mut spans = vec![Span::styled(it.clone(), style)];
+                // Type picker: show where each type sends prompts
+                if dd.target_field.is_none() {
+                    let privacy = st.privacy_map.get(it).copied().unwrap_or_else(|| crate::privacy::builtin(it));
+                    spans.push(Span::styled(format!("  [{}]", privacy.label()), Style::default().fg(privacy.color(&app.theme))));
+                }
+                items.push(ListItem::new(Line::from(spans)));
             }
             let list = List::new(items)
                 .block(Block::default().borders(Borders::ALL).border_style(Style::default().fg(app.theme.frame)).title(dd.title.clone()))
@@ -162,39 +239,204 @@ pub fn draw_providers_catalog(f: &mut Frame, area: Rect, app: &App) {
             f.render_widget(list, area_pop);
         }
     }
+
+    // Overlay clipboard import preview
+    if let Some(preview) = app.providers.as_ref().and_then(|st| st.import.as_ref()) {
+        let area_pop = overlay_rect(app.compact, 70, 70, area);
+        let mut lines: Vec<Line> = Vec::new();
+        for c in &preview.candidates {
+            let ok = c.errors.is_empty();
+            let (mark, color) = if ok { ('✓', app.theme.ok) } else { ('✗', app.theme.err) };
+            let head = if c.entry.ptype.is_empty() { c.entry.name.clone() } else { format!("{} [{}] id={}", c.entry.name, c.entry.ptype, c.entry.id) };
+            lines.push(Line::from(Span::styled(format!("{} {}", mark, head), Style::default().fg(color).add_modifier(Modifier::BOLD))));
+            for e in &c.errors { lines.push(Line::from(Span::styled(format!("    error: {}", e), Style::default().fg(app.theme.err)))); }
+            for w in &c.warnings { lines.push(Line::from(Span::styled(format!("    warning: {}", w), Style::default().fg(app.theme.warn)))); }
+            for n in &c.notes { lines.push(Line::from(Span::styled(format!("    {}", n), Style::default().fg(app.theme.secondary)))); }
+        }
+        lines.push(Line::from(""));
+        let n = preview.importable();
+        lines.push(Line::from(if n > 0 { format!("Enter import {} valid • ↑/↓ scroll • Esc cancel", n) } else { "Nothing valid to import • Esc cancel".to_string() }));
+        let p = Paragraph::new(lines)
+            .style(Style::default().bg(app.theme.bg).fg(app.theme.fg))
+            .block(Block::default().borders(Borders::ALL).border_style(Style::default().fg(app.theme.accent)).title(format!("Import from {} ({} found)", preview.source.as_deref().unwrap_or("clipboard"), preview.candidates.len())))
+            .wrap(Wrap { trim: false })
+            .scroll((preview.scroll, 0));
+        f.render_widget(Clear, area_pop);
+        f.render_widget(p, area_pop);
+    }
+
+    // Overlay provider QR code, sized to the code itself
+    if let Some(q) = app.providers.as_ref().and_then(|st| st.qr.as_ref()) {
+        let qr_w = q.lines.iter().map(|l| text::width(l)).max().unwrap_or(0) as u16;
+        let w = (qr_w + 2).max(44).min(area.width);
+        let h = (q.lines.len() as u16 + 5).min(area.height);
+        let pop = Rect { x: area.x + (area.width - w) / 2, y: area.y + (area.height - h) / 2, width: w, height: h };
+        // Dark-on-light regardless of theme so phone cameras can read it
+        let code_style = Style::default().fg(Color::Black).bg(Color::White);
+        let mut lines: Vec<Line> = q.lines.iter().map(|l| Line::from(Span::styled(l.clone(), code_style))).collect();
+        if qr_w + 2 > area.width || q.lines.len() as u16 + 5 > area.height {
+            lines.insert(0, Line::from(Span::styled("Window too small for the full code — enlarge it to scan", Style::default().fg(app.theme.warn))));
+        }
+        if !q.omitted.is_empty() {
+            lines.push(Line::from(Span::styled(format!("omitted: {}", q.omitted.join(", ")), Style::default().fg(app.theme.warn))));
+        }
+        lines.push(Line::from("y copy JSON • any key close"));
+        let p = Paragraph::new(lines)
+            .alignment(Alignment::Center)
+            .style(Style::default().bg(app.theme.bg).fg(app.theme.fg))
+            .block(Block::default().borders(Borders::ALL).border_style(Style::default().fg(app.theme.accent)).title(format!("QR — {}", q.title)));
+        f.render_widget(Clear, pop);
+        f.render_widget(p, pop);
+    }
+
+    // Overlay snippet export: formats on top, the selected one previewed below
+    if let Some(st) = &app.providers {
+        if let Some(picker) = &st.export {
+            let pop = overlay_rect(app.compact, 80, 70, area);
+            let mut lines: Vec<Line> = Format::ALL
+                .iter()
+                .enumerate()
+                .map(|(i, fmt)| {
+                    let sel = i == picker.selected;
+                    let style = if sel { Style::default().fg(app.theme.selected).add_modifier(Modifier::BOLD) } else { Style::default().fg(app.theme.fg) };
+                    Line::from(Span::styled(format!("{} {}", if sel { '›' } else { ' ' }, fmt.label()), style))
+                })
+                .collect();
+            lines.push(Line::from(Span::styled("↑/↓ format • Enter/y copy • PgUp/PgDn scroll • Esc close", Style::default().fg(app.theme.secondary))));
+            lines.push(Line::from(""));
+            let name = match st.entries.iter().find(|e| e.id == picker.provider_id) {
+                Some(entry) => {
+                    match export::render(entry, picker.format()) {
+                        Ok(snippet) => lines.extend(snippet.lines().map(|l| Line::from(Span::styled(l.to_string(), Style::default().fg(app.theme.accent))))),
+                        Err(e) => lines.push(Line::from(Span::styled(format!("Error: {}", e), Style::default().fg(app.theme.err)))),
+                    }
+                    entry.name.clone()
+                }
+                None => picker.provider_id.clone(),
+            };
+            let p = Paragraph::new(lines)
+                .style(Style::default().bg(app.theme.bg).fg(app.theme.fg))
+                .block(Block::default().borders(Borders::ALL).border_style(Style::default().fg(app.theme.accent)).title(format!("Export — {}", name)))
+                .wrap(Wrap { trim: false })
+                .scroll((picker.scroll, 0));
+            f.render_widget(Clear, pop);
+            f.render_widget(p, pop);
+        }
+    }
+
+    // Overlay one-line JSON input
+    if let Some(buf) = app.providers.as_ref().and_then(|st| st.paste_input.as_ref()) {
+        let pop = overlay_rect(app.compact, 70, 20, area);
+        let lines = vec![
+            Line::from("Paste or type provider JSON (e.g. the text decoded from a QR code):"),
+            Line::from(Span::styled(format!("{}▏", buf), Style::default().fg(app.theme.selected))),
+            Line::from(""),
+            Line::from("Enter preview import • Backspace delete • Esc cancel"),
+        ];
+        let p = Paragraph::new(lines)
+            .style(Style::default().bg(app.theme.bg).fg(app.theme.fg))
+            .block(Block::default().borders(Borders::ALL).border_style(Style::default().fg(app.theme.accent)).title("Import JSON"))
+            .wrap(Wrap { trim: false });
+        f.render_widget(Clear, pop);
+        f.render_widget(p, pop);
+    }
+}
+
+/// Test connection result: the status line and, when the provider listed
+/// its models, how many there are and their ids.
+#[derive(Clone, Debug)]
+pub struct ConnectionTest {
+    pub message: String,
+    pub models: Option<usize>,
+    /// Ids of the listed models
+    pub model_ids: Vec<String>,
+}
+
+impl ConnectionTest {
+    fn note(message: String) -> Self {
+        ConnectionTest { message, models: None, model_ids: Vec::new() }
+    }
+
+    /// `discover-models` output; `describe` gets the model count.
+    fn listed(v: &Value, describe: impl FnOnce(usize) -> String) -> Self {
+        let model_ids: Vec<String> = v.get("models").and_then(|d| d.as_array()).map(|a| {
+            a.iter().filter_map(|m| m.get("id").or_else(|| m.get("name")).unwrap_or(m).as_str().map(|s| s.to_string())).collect()
+        }).unwrap_or_default();
+        let count = v.get("models").and_then(|d| d.as_array()).map(|a| a.len()).unwrap_or(0);
+        ConnectionTest { message: describe(count), models: Some(count), model_ids }
+    }
 }
 
 pub fn probe_provider(entry: &super::state::ProviderScratchEntry) -> Result<String> {
+    test_connection(entry).map(|t| t.message)
+}
+
+/// Test connection: list the provider's models through `chi-llm providers
+/// discover-models`. Also used by the Status page.
+pub fn test_connection(entry: &super::state::ProviderScratchEntry) -> Result<ConnectionTest> {
     let ptype = entry.ptype.as_str();
-    if ptype == "local" { return Ok("local: no network test".to_string()); }
+    if ptype == "local" { return Ok(ConnectionTest::note("local: no network test".to_string())); }
     match ptype {
         "lmstudio" => {
             let host = entry.config.get("host").and_then(|v| v.as_str()).unwrap_or("127.0.0.1");
             let port = entry.config.get("port").and_then(|v| v.as_u64()).unwrap_or(1234);
             let args = ["providers", "discover-models", "--type", "lmstudio", "--host", host, "--port", &port.to_string(), "--json"];
-            let v = run_cli_json(&args, Duration::from_secs(5))?;
-            let count = v.get("models").and_then(|d| d.as_array()).map(|a| a.len()).unwrap_or(0);
-            Ok(format!("lmstudio: {} models", count))
+            let v = discover(entry, &args)?;
+            Ok(ConnectionTest::listed(&v, |count| format!("lmstudio: {} models", count)))
         }
         "ollama" => {
             let host = entry.config.get("host").and_then(|v| v.as_str()).unwrap_or("127.0.0.1");
             let port = entry.config.get("port").and_then(|v| v.as_u64()).unwrap_or(11434);
             let args = ["providers", "discover-models", "--type", "ollama", "--host", host, "--port", &port.to_string(), "--json"];
-            let v = run_cli_json(&args, Duration::from_secs(5))?;
-            let count = v.get("models").and_then(|d| d.as_array()).map(|a| a.len()).unwrap_or(0);
-            Ok(format!("ollama: {} models", count))
+            let v = discover(entry, &args)?;
+            Ok(ConnectionTest::listed(&v, |count| format!("ollama: {} models", count)))
         }
         "openai" => {
             let base = entry.config.get("base_url").and_then(|v| v.as_str()).unwrap_or("https://api.openai.com");
             let api_key = entry.config.get("api_key").and_then(|v| v.as_str()).unwrap_or("");
             let org = entry.config.get("org_id").and_then(|v| v.as_str()).unwrap_or("");
-            if api_key.is_empty() { return Ok("openai: missing api_key".to_string()); }
+            if api_key.is_empty() { return Ok(ConnectionTest::note("openai: missing api_key".to_string())); }
             let mut args: Vec<&str> = vec!["providers", "discover-models", "--type", "openai", "--base-url", base, "--api-key", api_key, "--json"];
             if !org.is_empty() { args.push("--org-id"); args.push(org); }
-            let v = run_cli_json(&args, Duration::from_secs(5))?;
-            let count = v.get("models").and_then(|d| d.as_array()).map(|a| a.len()).unwrap_or(0);
-            Ok(format!("openai: {} models", count))
+            let v = discover(entry, &args)?;
+            Ok(ConnectionTest::listed(&v, |count| format!("openai: {} models", count)))
+        }
+        "openai-compatible" => {
+            let base = entry.config.get("base_url").and_then(|v| v.as_str()).unwrap_or("");
+            if base.is_empty() { return Ok(ConnectionTest::note("openai-compatible: missing base_url".to_string())); }
+            let api_key = entry.config.get("api_key").and_then(|v| v.as_str()).unwrap_or("");
+            let mut args: Vec<&str> = vec!["providers", "discover-models", "--type", "openai-compatible", "--base-url", base, "--json"];
+            if !api_key.is_empty() { args.push("--api-key"); args.push(api_key); }
+            let v = discover(entry, &args)?;
+            if let Some(err) = v.get("error").and_then(|e| e.as_str()) { return Err(anyhow!("openai-compatible: {}", err)); }
+            Ok(ConnectionTest::listed(&v, |count| format!("openai-compatible: {} models at {}", count, base)))
         }
-        _ => Ok(format!("{}: no test implemented", ptype)),
+        "anthropic" => {
+            let base = entry.config.get("base_url").and_then(|v| v.as_str()).unwrap_or("https://api.anthropic.com");
+            let api_key = entry.config.get("api_key").and_then(|v| v.as_str()).unwrap_or("");
+            if api_key.is_empty() { return Ok(ConnectionTest::note("anthropic: missing api_key".to_string())); }
+            let args = ["providers", "discover-models", "--type", "anthropic", "--base-url", base, "--api-key", api_key, "--json"];
+            let v = discover(entry, &args)?;
+            if let Some(err) = v.get("error").and_then(|e| e.as_str()) { return Err(anyhow!("anthropic: {}", err)); }
+            Ok(ConnectionTest::listed(&v, |count| format!("anthropic: {} models", count)))
+        }
+        _ => Ok(ConnectionTest::note(format!("{}: no test implemented", ptype))),
+    }
+}
+
+/// `providers discover-models` with the provider's `user_agent`, `proxy`,
+/// TLS options (`scheme`, `ca_cert`, `insecure_tls`), request logging and,
+/// for servers behind a proxy, its token (`api_key`, `auth_header`), if set.
+fn discover(entry: &super::state::ProviderScratchEntry, args: &[&str]) -> Result<Value> {
+    let mut args = args.to_vec();
+    let text = |key: &str| entry.config.get(key).and_then(|v| v.as_str()).map(str::trim).unwrap_or("");
+    for (key, flag) in [("user_agent", "--user-agent"), ("scheme", "--scheme"), ("ca_cert", "--ca-cert"), ("auth_header", "--auth-header"), ("proxy", "--proxy")] {
+        if !text(key).is_empty() { args.push(flag); args.push(text(key)); }
     }
+    // Hosted types pass their key themselves
+    if matches!(entry.ptype.as_str(), "lmstudio" | "ollama") && !text("api_key").is_empty() { args.push("--api-key"); args.push(text("api_key")); }
+    let flag = |key: &str| entry.config.get(key).map_or(false, |v| v.as_bool().unwrap_or_else(|| text(key).eq_ignore_ascii_case("true")));
+    if flag("insecure_tls") { args.push("--insecure"); }
+    if flag("debug_requests") { args.push("--debug-requests"); }
+    discovery_cache::refresh_cli(&args, crate::prefs::load().discovery_timeout)
 }
@@ -0,0 +1,52 @@
+use anyhow::{anyhow, Result};
+use qrcode::render::unicode::Dense1x2;
+use qrcode::{EcLevel, QrCode};
+
+/// Render `text` as a QR code in half-block characters (two modules per
+/// cell row), quiet zone included. Dark modules are the drawn glyphs, so
+/// display it dark-on-light for scanners.
+pub fn render(text: &str) -> Result<Vec<String>> {
+    let code = QrCode::with_error_correction_level(text.as_bytes(), EcLevel::L).map_err(|e| anyhow!("QR encode failed: {}", e))?;
+    let art = code.render::<Dense1x2>().dark_color(Dense1x2::Dark).light_color(Dense1x2::Light).quiet_zone(true).build();
+    Ok(art.lines().map(|l| l.to_string()).collect())
+}
+
+/// A provider QR code shown over the Configure page.
+#[derive(Clone, Debug)]
+pub struct ShareQr {
+    pub title: String,
+    pub lines: Vec<String>,
+    /// Payload as encoded, for copying or typing elsewhere
+    pub payload: String,
+    /// Secret fields left out of the payload
+    pub omitted: Vec<String>,
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+
+    #[test]
+    fn renders_a_square_code_two_modules_per_row() {
+        let lines = render(r#"{"id":"box","type":"ollama"}"#).expect("qr");
+        let width = lines[0].chars().count();
+        assert!(lines.iter().all(|l| l.chars().count() == width));
+        assert_eq!(lines.len(), (width + 1) / 2);
+        // The quiet zone is light all around
+        assert!(lines[0].chars().all(|c| c == ' '));
+        assert!(lines.iter().all(|l| l.starts_with(' ') && l.ends_with(' ')));
+    }
+
+    #[test]
+    fn longer_text_gives_a_larger_code() {
+        let small = render("x").expect("small")[0].chars().count();
+        let large = render(&"x".repeat(500)).expect("large")[0].chars().count();
+        assert!(large > small);
+    }
+
+    #[test]
+    fn text_over_the_capacity_is_an_error() {
+        let err = render(&"x".repeat(3000)).expect_err("too long");
+        assert!(err.to_string().starts_with("QR encode failed"));
+    }
+}
@@ -1,11 +1,16 @@
-use anyhow::Result;
+use std::path::{Path, PathBuf};
+
+use anyhow::{anyhow, Result};
 use ratatui::layout::{Alignment, Constraint, Direction, Layout, Rect};
 use ratatui::prelude::Frame;
 use ratatui::style::{Modifier, Style};
 use ratatui::text::{Line, Span};
-use ratatui::widgets::{Block, Borders, List, ListItem, Paragraph, Wrap};
+use ratatui::widgets::{Block, Borders, Clear, List, ListItem, ListState, Paragraph, Wrap};
 
 use crate::app::App;
+use crate::focus::pane_block;
+use crate::mouse::Target;
+use crate::util::{overlay_rect, split_panes};
 
 #[derive(Clone, Debug)]
 pub struct TocEntry {
@@ -14,30 +19,149 @@ pub struct TocEntry {
     pub line: usize,
 }
 
+/// A relative link to another markdown file, `[text](docs/CLI.md#anchor)`.
+#[derive(Clone, Debug)]
+pub struct DocLink {
+    pub text: String,
+    pub href: String,
+    pub line: usize,
+}
+
 #[derive(Clone, Debug)]
 pub struct ReadmeState {
+    /// The document shown; links resolve against its directory
+    pub path: PathBuf,
     pub lines: Vec<String>,
     pub toc: Vec<TocEntry>,
+    pub links: Vec<DocLink>,
     pub show_toc: bool,
     pub scroll: usize,
     pub focus_toc: bool,
     pub toc_selected: usize,
+    /// Link list (`l`, or Enter in the text): the selected link
+    pub link_picker: Option<usize>,
+    /// Documents left by following links, most recent last; Esc returns
+    pub back: Vec<ReadmeState>,
 }
 
 impl ReadmeState {
     pub fn scroll_up(&mut self, n: usize) {
         self.scroll = self.scroll.saturating_sub(n);
     }
+    /// Stops at the last line, so the text never scrolls away entirely.
+    /// The scroll position is a source line: wrapping happens when drawing,
+    /// at the pane's current width, so a resize keeps the same top line.
     pub fn scroll_down(&mut self, n: usize) {
-        self.scroll = self.scroll.saturating_add(n);
+        self.scroll = self.scroll.saturating_add(n).min(self.lines.len().saturating_sub(1));
+    }
+
+    /// The TOC entry of the section at the top of the view.
+    pub fn section(&self) -> Option<usize> {
+        self.toc.iter().rposition(|e| e.line <= self.scroll)
+    }
+
+    /// Scroll so TOC entry `i` is the top line, and select it.
+    pub fn jump(&mut self, i: usize) {
+        if let Some(e) = self.toc.get(i) {
+            self.scroll = e.line;
+            self.toc_selected = i;
+        }
+    }
+
+    /// `n`: the first heading below the top line. False at the last one.
+    pub fn next_heading(&mut self) -> bool {
+        let Some(i) = self.toc.iter().position(|e| e.line > self.scroll) else { return false };
+        self.jump(i);
+        true
+    }
+
+    /// `p`: the last heading above the top line. False at the first one.
+    pub fn prev_heading(&mut self) -> bool {
+        let Some(i) = self.toc.iter().rposition(|e| e.line < self.scroll) else { return false };
+        self.jump(i);
+        true
+    }
+
+    /// Open the link list at the first link on or below the top line.
+    pub fn open_links(&mut self) -> bool {
+        if self.links.is_empty() { return false; }
+        let at = self.links.iter().position(|l| l.line >= self.scroll).unwrap_or(self.links.len() - 1);
+        self.link_picker = Some(at);
+        true
+    }
+
+    /// Load link `i`'s document in place of this one, which goes on the
+    /// back stack. `#anchor` scrolls to the heading it names.
+    pub fn follow(&mut self, i: usize) -> Result<()> {
+        let link = self.links.get(i).ok_or_else(|| anyhow!("no link {}", i))?;
+        let (file, anchor) = link.href.split_once('#').unwrap_or((link.href.as_str(), ""));
+        let base = self.path.parent().unwrap_or_else(|| Path::new(""));
+        let mut next = load(&base.join(file))?;
+        if let Some(i) = next.toc.iter().position(|e| slug(&e.title) == anchor.to_lowercase()) { next.jump(i); }
+        next.show_toc = self.show_toc;
+        self.link_picker = None;
+        let mut prev = std::mem::replace(self, next);
+        self.back = std::mem::take(&mut prev.back);
+        self.back.push(prev);
+        Ok(())
+    }
+
+    /// Esc: the previous document, where it was left. False on the first one.
+    pub fn go_back(&mut self) -> bool {
+        let Some(mut prev) = self.back.pop() else { return false };
+        prev.back = std::mem::take(&mut self.back);
+        *self = prev;
+        true
+    }
+
+    /// Pane title: `README`, or the linked document's path.
+    pub fn title(&self) -> String {
+        if self.back.is_empty() { "README".to_string() } else { format!("{} (Esc back)", self.path.display()) }
+    }
+}
+
+/// GitHub-style heading anchor: `## Provider Setup` is `#provider-setup`.
+fn slug(title: &str) -> String {
+    title.trim().to_lowercase().chars().filter_map(|c| match c {
+        ' ' => Some('-'),
+        c if c.is_alphanumeric() || c == '-' || c == '_' => Some(c),
+        _ => None,
+    }).collect()
+}
+
+/// Relative `.md` links on a line; web, mail and same-page links are skipped.
+fn links_in(line: &str, idx: usize) -> Vec<DocLink> {
+    let mut out = Vec::new();
+    for (at, _) in line.match_indices("](") {
+        let Some(open) = line[..at].rfind('[') else { continue };
+        let Some(len) = line[at + 2..].find(')') else { continue };
+        let href = line[at + 2..at + 2 + len].trim();
+        let file = href.split('#').next().unwrap_or("");
+        if href.contains("://") || href.starts_with("mailto:") || !file.to_lowercase().ends_with(".md") { continue; }
+        out.push(DocLink { text: line[open + 1..at].to_string(), href: href.to_string(), line: idx });
     }
+    out
+}
+
+/// A markdown document from disk.
+pub fn load(path: &Path) -> Result<ReadmeState> {
+    let content = std::fs::read_to_string(path).map_err(|e| anyhow!("{}: {}", path.display(), e))?;
+    let mut rm = parse(&content);
+    rm.path = path.to_path_buf();
+    Ok(rm)
 }
 
 pub fn load_readme() -> ReadmeState {
     let content = std::fs::read_to_string("README.md")
         .unwrap_or_else(|_| "# README not found\n\nPlace a README.md in the current directory.".to_string());
+    parse(&content)
+}
+
+/// Lines and the TOC of `#`, `##` and `###` headings.
+pub fn parse(content: &str) -> ReadmeState {
     let mut lines = Vec::new();
     let mut toc = Vec::new();
+    let mut links = Vec::new();
     for (idx, raw) in content.lines().enumerate() {
         let mut level = 0u8;
         let mut title = raw.to_string();
@@ -51,6 +175,7 @@ pub fn load_readme() -> ReadmeState {
             level = 1;
             title = stripped.to_string();
         }
+        links.extend(links_in(raw, idx));
         if level > 0 {
             toc.push(TocEntry {
                 level,
@@ -61,12 +186,16 @@ pub fn load_readme() -> ReadmeState {
         lines.push(raw.to_string());
     }
     ReadmeState {
+        path: PathBuf::from("README.md"),
         lines,
         toc,
+        links,
         show_toc: false,
         scroll: 0,
         focus_toc: false,
         toc_selected: 0,
+        link_picker: None,
+        back: Vec::new(),
     }
 }
 
@@ -75,10 +204,8 @@ pub fn draw_readme(f: &mut Frame, area: Rect, app: &App) {
     let mut rm = app.readme.clone().unwrap_or_else(load_readme);
     let show_toc = rm.show_toc;
     let chunks = if show_toc {
-        Layout::default()
-            .direction(Direction::Horizontal)
-            .constraints([Constraint::Percentage(25), Constraint::Percentage(75)])
-            .split(area)
+        // Narrow terminals: TOC above the text
+        split_panes(app.stacked(), if app.stacked() { 35 } else { 25 }, area)
     } else {
         Layout::default()
             .direction(Direction::Horizontal)
@@ -87,6 +214,8 @@ pub fn draw_readme(f: &mut Frame, area: Rect, app: &App) {
     };
 
     if show_toc {
+        // Unfocused, the TOC marks the section being read
+        let section = rm.section();
         let mut toc_items: Vec<ListItem> = Vec::new();
         for (i, e) in rm.toc.iter().enumerate() {
             let indent = match e.level {
@@ -96,17 +225,17 @@ pub fn draw_readme(f: &mut Frame, area: Rect, app: &App) {
             };
             let style = if rm.focus_toc && i == rm.toc_selected {
                 Style::default().fg(app.theme.selected).add_modifier(Modifier::BOLD)
+            } else if !rm.focus_toc && Some(i) == section {
+                Style::default().fg(app.theme.accent)
             } else { Style::default().fg(app.theme.fg) };
             toc_items.push(ListItem::new(Line::from(Span::styled(format!("{}- {}", indent, e.title), style))));
         }
-        let left_border = if rm.focus_toc { app.theme.selected } else { app.theme.frame };
-        let list = List::new(toc_items).block(
-            Block::default()
-                .borders(Borders::ALL)
-                .border_style(Style::default().fg(left_border))
-                .title("TOC"),
-        );
-        f.render_widget(list, chunks[0]);
+        let list = List::new(toc_items).block(pane_block(&app.theme, "TOC", rm.focus_toc));
+        // Stateful so a long TOC scrolls to the selected entry
+        let shown = if rm.focus_toc { Some(rm.toc_selected) } else { section };
+        let mut state = ListState::default().with_selected(shown);
+        f.render_stateful_widget(list, chunks[0], &mut state);
+        app.hits.rows(Block::default().borders(Borders::ALL).inner(chunks[0]), state.offset(), rm.toc.len(), |_| 1, Target::TocEntry);
     }
 
     // Render content with simple styling for headings
@@ -137,18 +266,157 @@ pub fn draw_readme(f: &mut Frame, area: Rect, app: &App) {
             vlines.push(Line::from(raw.as_str()));
         }
     }
-    let right_border = if show_toc && !rm.focus_toc { app.theme.selected } else { app.theme.frame };
+    // With one pane there is nothing to tell apart
     let p = Paragraph::new(vlines)
         .style(Style::default().bg(app.theme.bg).fg(app.theme.fg))
-        .block(
-            Block::default()
-                .borders(Borders::ALL)
-                .border_style(Style::default().fg(right_border))
-                .title("README"),
-        )
+        .block(pane_block(&app.theme, &rm.title(), show_toc && !rm.focus_toc))
         .alignment(Alignment::Left)
         .wrap(Wrap { trim: true });
     f.render_widget(p, chunks[if show_toc { 1 } else { 0 }]);
 
+    if let Some(sel) = rm.link_picker {
+        let pop = overlay_rect(app.compact, 60, 50, area);
+        let items: Vec<ListItem> = rm.links.iter().enumerate().map(|(i, l)| {
+            let style = if i == sel { Style::default().fg(app.theme.selected).add_modifier(Modifier::BOLD) } else { Style::default().fg(app.theme.fg) };
+            ListItem::new(Line::from(vec![
+                Span::styled(format!("{} {}", if i == sel { '›' } else { ' ' }, l.text), style),
+                Span::styled(format!("  → {}", l.href), Style::default().fg(app.theme.secondary)),
+            ]))
+        }).collect();
+        let list = List::new(items).block(Block::default().borders(Borders::ALL).border_style(Style::default().fg(app.theme.frame)).title("Links (Enter open • Esc close)"));
+        let mut state = ListState::default().with_selected(Some(sel));
+        f.render_widget(Clear, pop);
+        f.render_stateful_widget(list, pop, &mut state);
+    }
+
     // Note: caller updates app.readme via key handler (holds &mut App there)
 }
+
+#[cfg(all(test, unix))]
+mod tests {
+    use super::*;
+    use crate::app::Page;
+    use crate::testing::{screen, FakeCli};
+
+    #[test]
+    fn readme_toc_jumps_to_sections_and_n_p_step_through_headings() {
+        use crossterm::event::{KeyCode, KeyEvent, KeyModifiers};
+        let press = |app: &mut App, code: KeyCode| crate::keys::handle_key(app, KeyEvent::new(code, KeyModifiers::NONE));
+        let mut app = App::new(false);
+        crate::open_page_loaded(&mut app, Page::Readme);
+        app.readme = Some(parse("# Title\nintro\n## Install\nsteps\nmore\n## Usage\nrun it\n### Flags\n--json\n"));
+        let state = |app: &App| app.readme.as_ref().map(|rm| (rm.scroll, rm.toc_selected, rm.focus_toc)).expect("readme");
+
+        // n/p: next and previous heading, stopping at the ends
+        press(&mut app, KeyCode::Char('n'));
+        assert_eq!(state(&app), (2, 1, false));
+        press(&mut app, KeyCode::Char('n'));
+        press(&mut app, KeyCode::Char('n'));
+        assert_eq!(state(&app), (7, 3, false));
+        press(&mut app, KeyCode::Char('n'));
+        assert_eq!(state(&app).0, 7);
+        // From inside a section, p goes to its own heading first
+        press(&mut app, KeyCode::Up);
+        press(&mut app, KeyCode::Char('p'));
+        assert_eq!(state(&app), (5, 2, false));
+        press(&mut app, KeyCode::Char('p'));
+        press(&mut app, KeyCode::Char('p'));
+        press(&mut app, KeyCode::Char('p'));
+        assert_eq!(state(&app), (0, 0, false));
+
+        // The TOC opens at the section being read; Enter jumps and hands focus to the text
+        press(&mut app, KeyCode::Down);
+        press(&mut app, KeyCode::Down);
+        press(&mut app, KeyCode::Down);
+        press(&mut app, KeyCode::Char('h'));
+        assert_eq!(state(&app), (3, 1, false));
+        press(&mut app, KeyCode::Tab);
+        press(&mut app, KeyCode::Down);
+        press(&mut app, KeyCode::Down);
+        assert_eq!(state(&app), (3, 3, true));
+        let text = screen(&app, 100, 30);
+        assert!(text.contains("▶ TOC") && text.contains("    - Flags"), "{}", text);
+        press(&mut app, KeyCode::Enter);
+        assert_eq!(state(&app), (7, 3, false));
+        assert!(screen(&app, 100, 30).contains("--json"));
+    }
+
+    #[test]
+    fn readme_links_open_other_documents_and_esc_walks_back() {
+        use crossterm::event::{KeyCode, KeyEvent, KeyModifiers};
+        let fake = FakeCli::new();
+        let docs = fake.root.join("docs");
+        std::fs::create_dir_all(&docs).expect("docs dir");
+        std::fs::write(fake.root.join("README.md"), "# Project\nSee [the CLI](docs/CLI.md) and [its flags](docs/CLI.md#output-flags).\nWeb: [site](https://example.com/x.md) • [gone](docs/missing.md)\n").expect("README");
+        std::fs::write(docs.join("CLI.md"), "# CLI\nBack to [home](../README.md)\n\nrun\n## Output Flags\n--json\n").expect("CLI.md");
+        let press = |app: &mut App, code: KeyCode| crate::keys::handle_key(app, KeyEvent::new(code, KeyModifiers::NONE));
+        let doc = |app: &App| app.readme.as_ref().map(|rm| (rm.path.file_name().map(|n| n.to_string_lossy().to_string()).unwrap_or_default(), rm.scroll, rm.back.len())).expect("readme");
+        let mut app = App::new(false);
+        crate::open_page_loaded(&mut app, Page::Readme);
+        app.readme = Some(load(&fake.root.join("README.md")).expect("load"));
+        let hrefs: Vec<String> = app.readme.as_ref().map(|rm| rm.links.iter().map(|l| l.href.clone()).collect()).unwrap_or_default();
+        assert_eq!(hrefs, ["docs/CLI.md", "docs/CLI.md#output-flags", "docs/missing.md"]);
+
+        // Enter in the text lists the links; keys go to the list, not the app
+        press(&mut app, KeyCode::Enter);
+        press(&mut app, KeyCode::Char('q'));
+        assert!(!app.should_quit && app.shutdown.is_none());
+        assert!(screen(&app, 100, 30).contains("the CLI  → docs/CLI.md"));
+        press(&mut app, KeyCode::Enter);
+        assert_eq!(doc(&app), ("CLI.md".to_string(), 0, 1));
+        assert!(screen(&app, 100, 30).contains("CLI.md (Esc back)"));
+
+        // Links chain, and Esc walks back one document at a time
+        press(&mut app, KeyCode::Char('l'));
+        press(&mut app, KeyCode::Enter);
+        assert_eq!(doc(&app), ("README.md".to_string(), 0, 2));
+        press(&mut app, KeyCode::Esc);
+        press(&mut app, KeyCode::Esc);
+        assert_eq!((app.page, doc(&app)), (Page::Readme, ("README.md".to_string(), 0, 0)));
+
+        // #anchor scrolls to its heading; a missing file is an error, not a page
+        press(&mut app, KeyCode::Char('l'));
+        press(&mut app, KeyCode::Down);
+        press(&mut app, KeyCode::Enter);
+        assert_eq!(doc(&app), ("CLI.md".to_string(), 4, 1));
+        press(&mut app, KeyCode::Esc);
+        press(&mut app, KeyCode::Char('l'));
+        press(&mut app, KeyCode::Down);
+        press(&mut app, KeyCode::Down);
+        press(&mut app, KeyCode::Enter);
+        assert_eq!(doc(&app), ("README.md".to_string(), 0, 0));
+        assert!(app.toast.as_ref().map_or(false, |t| t.text.contains("missing.md")));
+        assert!(app.readme.as_ref().map_or(false, |rm| rm.link_picker.is_none()));
+
+        // With nothing to go back to, Esc leaves the page as before
+        press(&mut app, KeyCode::Esc);
+        assert_eq!(app.page, Page::Welcome);
+    }
+
+    #[test]
+    fn readme_rewraps_on_resize_and_keeps_its_top_line() {
+        use crossterm::event::{KeyCode, KeyEvent, KeyModifiers};
+        let press = |app: &mut App, code: KeyCode| crate::keys::handle_key(app, KeyEvent::new(code, KeyModifiers::NONE));
+        let mut app = App::new(false);
+        crate::open_page_loaded(&mut app, Page::Readme);
+        let long = "word ".repeat(40);
+        app.readme = Some(parse(&format!("# Top\n{long}\n## Middle\n{long}\nend\n")));
+        press(&mut app, KeyCode::Char('n'));
+
+        // Each size wraps at its own width and starts at the same source line
+        let rows = |text: &str| text.lines().filter(|l| l.contains("word")).count();
+        let wide = crate::script::render(&mut app, 120, 30).expect("render");
+        let narrow = crate::script::render(&mut app, 50, 30).expect("render");
+        for text in [&wide, &narrow] {
+            assert!(text.contains("Middle") && !text.contains("Top"), "{}", text);
+            assert!(text.lines().all(|l| !l.contains("wordword")), "{}", text);
+        }
+        assert!(rows(&narrow) > rows(&wide), "wide:\n{}\nnarrow:\n{}", wide, narrow);
+        assert_eq!(app.readme.as_ref().map(|rm| rm.scroll), Some(2));
+
+        // Scrolling stops at the last line instead of leaving an empty pane
+        for _ in 0..3 { press(&mut app, KeyCode::PageDown); }
+        assert_eq!(app.readme.as_ref().map(|rm| rm.scroll), Some(4));
+        assert!(crate::script::render(&mut app, 50, 30).expect("render").contains("end"));
+    }
+}
@@ -0,0 +1,311 @@
+//! "Recommend for this machine": system RAM and GPU memory read directly
+//! (`/proc/meminfo`, `sysctl`, `nvidia-smi`) rather than through
+//! `chi-llm models current --explain`, and every catalog model scored by how
+//! it fits, with the reason spelled out ("fits with 3.2 GB headroom").
+
+use std::cmp::Ordering;
+use std::process::Command;
+
+use ratatui::layout::{Constraint, Direction, Layout, Rect};
+use ratatui::prelude::Frame;
+use ratatui::style::{Modifier, Style};
+use ratatui::text::{Line, Span};
+use ratatui::widgets::{Block, Borders, Cell, Paragraph, Row, Table, TableState};
+
+use crate::app::App;
+use crate::locale;
+use crate::models::ModelEntry;
+use crate::theme::StatusKind;
+
+const GB: f64 = 1024.0 * 1024.0 * 1024.0;
+/// chi_llm recommends models whose `recommended_ram_gb` is within 70% of RAM.
+const RAM_BUDGET: f64 = 0.7;
+/// VRAM over the file size for the KV cache and buffers.
+const VRAM_OVERHEAD: f64 = 1.2;
+
+#[derive(Clone, Debug, PartialEq)]
+pub struct Gpu {
+    pub name: String,
+    pub total_gb: f64,
+    pub free_gb: Option<f64>,
+}
+
+/// What this machine has; None where it could not be read.
+#[derive(Clone, Debug, Default, PartialEq)]
+pub struct Machine {
+    pub total_ram_gb: Option<f64>,
+    pub available_ram_gb: Option<f64>,
+    pub gpus: Vec<Gpu>,
+    /// Apple Silicon: the GPU shares system RAM
+    pub unified: bool,
+}
+
+impl Machine {
+    pub fn probe() -> Machine {
+        let (total_ram_gb, available_ram_gb) = match std::fs::read_to_string("/proc/meminfo") {
+            Ok(text) => parse_meminfo(&text),
+            // macOS: total only; "available" needs vm_stat arithmetic
+            Err(_) => (command_output("sysctl", &["-n", "hw.memsize"]).and_then(|s| s.trim().parse::<f64>().ok()).map(|b| b / GB), None),
+        };
+        let gpus = command_output("nvidia-smi", &["--query-gpu=name,memory.total,memory.free", "--format=csv,noheader,nounits"])
+            .map(|s| parse_nvidia_smi(&s))
+            .unwrap_or_default();
+        let unified = cfg!(all(target_os = "macos", target_arch = "aarch64"));
+        Machine { total_ram_gb, available_ram_gb, gpus, unified }
+    }
+
+    /// "RAM 31.2 GB (18.4 GB available) • NVIDIA RTX 3060: 12.0 GB VRAM (10.8 GB free)"
+    pub fn summary(&self) -> String {
+        let gb = |x: f64| format!("{} GB", locale::decimal(x, 1));
+        let mut parts = vec![match (self.total_ram_gb, self.available_ram_gb) {
+            (Some(t), Some(a)) => format!("RAM {} ({} available)", gb(t), gb(a)),
+            (Some(t), None) => format!("RAM {}", gb(t)),
+            _ => "RAM unknown".to_string(),
+        }];
+        for g in &self.gpus {
+            parts.push(match g.free_gb {
+                Some(f) => format!("{}: {} VRAM ({} free)", g.name, gb(g.total_gb), gb(f)),
+                None => format!("{}: {} VRAM", g.name, gb(g.total_gb)),
+            });
+        }
+        if self.unified { parts.push("unified memory (GPU uses system RAM)".to_string()); }
+        if self.gpus.is_empty() && !self.unified { parts.push("no GPU detected".to_string()); }
+        parts.join(" • ")
+    }
+}
+
+fn command_output(cmd: &str, args: &[&str]) -> Option<String> {
+    let out = Command::new(cmd).args(args).output().ok()?;
+    out.status.success().then(|| String::from_utf8_lossy(&out.stdout).to_string())
+}
+
+/// (MemTotal, MemAvailable) in GB from `/proc/meminfo` (values in kB).
+pub fn parse_meminfo(text: &str) -> (Option<f64>, Option<f64>) {
+    let field = |name: &str| {
+        text.lines()
+            .find_map(|l| l.strip_prefix(name))
+            .and_then(|rest| rest.trim_start_matches(':').split_whitespace().next())
+            .and_then(|kb| kb.parse::<f64>().ok())
+            .map(|kb| kb * 1024.0 / GB)
+    };
+    (field("MemTotal"), field("MemAvailable"))
+}
+
+/// `nvidia-smi --query-gpu=name,memory.total,memory.free --format=csv,noheader,nounits`
+/// (MiB per GPU, one per line).
+pub fn parse_nvidia_smi(text: &str) -> Vec<Gpu> {
+    text.lines()
+        .filter_map(|l| {
+            let cols: Vec<&str> = l.split(',').map(str::trim).collect();
+            let total = cols.get(1)?.parse::<f64>().ok()? / 1024.0;
+            Some(Gpu { name: cols[0].to_string(), total_gb: total, free_gb: cols.get(2).and_then(|f| f.parse::<f64>().ok()).map(|f| f / 1024.0) })
+        })
+        .collect()
+}
+
+/// How a model fits, best first.
+#[derive(Clone, Debug, PartialEq)]
+pub enum Fit {
+    /// Fully offloaded to the GPU with this much VRAM to spare
+    Gpu { headroom_gb: f64 },
+    Ram { headroom_gb: f64 },
+    /// Within total RAM, but not what is free right now
+    Tight { need_gb: f64, free_gb: f64 },
+    TooBig { need_gb: f64, have_gb: f64 },
+    Unknown,
+}
+
+impl Fit {
+    fn rank(&self) -> u8 {
+        match self {
+            Fit::Gpu { .. } => 0,
+            Fit::Ram { .. } => 1,
+            Fit::Tight { .. } => 2,
+            Fit::Unknown => 3,
+            Fit::TooBig { .. } => 4,
+        }
+    }
+
+    pub fn kind(&self) -> StatusKind {
+        match self {
+            Fit::Gpu { .. } | Fit::Ram { .. } => StatusKind::Ok,
+            Fit::Tight { .. } | Fit::Unknown => StatusKind::Warn,
+            Fit::TooBig { .. } => StatusKind::Err,
+        }
+    }
+
+    pub fn explain(&self) -> String {
+        let gb = |x: f64| format!("{} GB", locale::decimal(x, 1));
+        match self {
+            Fit::Gpu { headroom_gb } => format!("fits in VRAM with {} headroom (full GPU offload)", gb(*headroom_gb)),
+            Fit::Ram { headroom_gb } => format!("fits with {} headroom", gb(*headroom_gb)),
+            Fit::Tight { need_gb, free_gb } => format!("tight: needs {}, {} free now; close other apps", gb(*need_gb), gb(*free_gb)),
+            Fit::TooBig { need_gb, have_gb } => format!("too big: needs {}, budget is {}", gb(*need_gb), gb(*have_gb)),
+            Fit::Unknown => "unknown: RAM could not be read".to_string(),
+        }
+    }
+}
+
+/// RAM a model needs: the catalog's `recommended_ram_gb`, else the file size
+/// plus a fifth for context, plus half a GB of runtime.
+pub fn need_gb(e: &ModelEntry) -> Option<f64> {
+    e.raw
+        .get("recommended_ram_gb")
+        .and_then(|x| x.as_f64())
+        .or_else(|| e.file_size_mb.map(|mb| mb as f64 / 1024.0 * 1.2 + 0.5))
+}
+
+pub fn assess(m: &Machine, e: &ModelEntry) -> Fit {
+    let Some(need) = need_gb(e) else { return Fit::Unknown };
+    if let Some(file) = e.file_size_mb.map(|mb| mb as f64 / 1024.0) {
+        let vram = m.gpus.iter().filter_map(|g| g.free_gb.or(Some(g.total_gb))).fold(0.0, f64::max);
+        let headroom = vram - file * VRAM_OVERHEAD;
+        if vram > 0.0 && headroom >= 0.0 { return Fit::Gpu { headroom_gb: headroom }; }
+    }
+    let Some(total) = m.total_ram_gb else { return Fit::Unknown };
+    let budget = total * RAM_BUDGET;
+    let free = m.available_ram_gb.map_or(budget, |a| a.min(budget));
+    if need <= free {
+        Fit::Ram { headroom_gb: free - need }
+    } else if need <= budget {
+        Fit::Tight { need_gb: need, free_gb: free }
+    } else {
+        Fit::TooBig { need_gb: need, have_gb: budget }
+    }
+}
+
+#[derive(Clone, Debug)]
+pub struct Recommendation {
+    pub id: String,
+    pub name: String,
+    pub size: Option<String>,
+    pub need_gb: Option<f64>,
+    pub downloaded: bool,
+    pub fit: Fit,
+}
+
+/// Catalog models by fit: the largest model that fits comes first (the
+/// most capable one this machine runs well), models that do not fit last,
+/// smallest first.
+pub fn rank(m: &Machine, entries: &[ModelEntry]) -> Vec<Recommendation> {
+    let mut out: Vec<Recommendation> = entries
+        .iter()
+        // Catalog models only: installed server models and Hub results have no RAM figures
+        .filter(|e| e.server.is_none() && !e.tags.iter().any(|t| t == "hf"))
+        .map(|e| Recommendation { id: e.id.clone(), name: e.name.clone(), size: e.size.clone(), need_gb: need_gb(e), downloaded: e.downloaded, fit: assess(m, e) })
+        .collect();
+    out.sort_by(|a, b| {
+        let need = |r: &Recommendation| r.need_gb.unwrap_or(0.0);
+        a.fit.rank().cmp(&b.fit.rank()).then_with(|| match a.fit {
+            Fit::TooBig { .. } => need(a).partial_cmp(&need(b)).unwrap_or(Ordering::Equal),
+            _ => need(b).partial_cmp(&need(a)).unwrap_or(Ordering::Equal),
+        })
+    });
+    out
+}
+
+#[derive(Clone, Debug, Default)]
+pub struct RecommendState {
+    pub machine: Machine,
+    pub rows: Vec<Recommendation>,
+    pub selected: usize,
+    pub status: Option<String>,
+}
+
+impl RecommendState {
+    pub fn new(entries: &[ModelEntry]) -> Self {
+        let machine = Machine::probe();
+        let rows = rank(&machine, entries);
+        RecommendState { machine, rows, ..Default::default() }
+    }
+
+    pub fn move_up(&mut self) {
+        self.selected = self.selected.saturating_sub(1);
+    }
+
+    pub fn move_down(&mut self) {
+        if self.selected + 1 < self.rows.len() { self.selected += 1; }
+    }
+
+    pub fn current(&self) -> Option<&Recommendation> {
+        self.rows.get(self.selected)
+    }
+}
+
+pub fn draw_recommend(f: &mut Frame, area: Rect, app: &App) {
+    let Some(st) = &app.recommend else {
+        f.render_widget(Paragraph::new("Reading system memory...").block(Block::default().borders(Borders::ALL)), area);
+        return;
+    };
+    let chunks = Layout::default().direction(Direction::Vertical).constraints([Constraint::Length(4), Constraint::Min(3)]).split(area);
+    let mut lines = vec![Line::from(st.machine.summary())];
+    let status = match (&st.status, st.rows.first()) {
+        (Some(msg), _) => Some(app.theme.status_text(msg)),
+        (None, Some(best)) if best.fit.kind() == StatusKind::Ok => {
+            Some((format!("Best pick: {} — {}", best.name, best.fit.explain()), app.theme.status_style(StatusKind::Ok)))
+        }
+        (None, Some(_)) => Some(("No catalog model fits comfortably; see the reasons below".to_string(), app.theme.status_style(StatusKind::Warn))),
+        (None, None) => None,
+    };
+    if let Some((txt, style)) = status { lines.push(Line::from(Span::styled(txt, style))); }
+    let top = Paragraph::new(lines)
+        .style(Style::default().bg(app.theme.bg).fg(app.theme.fg))
+        .block(Block::default().borders(Borders::ALL).border_style(Style::default().fg(app.theme.frame)).title("This machine"));
+    f.render_widget(top, chunks[0]);
+
+    let header = Row::new(["Model", "Size", "Needs", "Fit"].map(Cell::from)).style(Style::default().fg(app.theme.primary).add_modifier(Modifier::BOLD));
+    let rows: Vec<Row> = st
+        .rows
+        .iter()
+        .enumerate()
+        .map(|(i, r)| {
+            let kind = r.fit.kind();
+            let name = if r.downloaded { format!("{} ✓", r.name) } else { r.name.clone() };
+            let style = if i == st.selected { Style::default().fg(app.theme.selected).add_modifier(Modifier::BOLD) } else { Style::default().fg(app.theme.fg) };
+            Row::new(vec![
+                Cell::from(name),
+                Cell::from(r.size.clone().unwrap_or_default()),
+                Cell::from(r.need_gb.map_or("-".to_string(), |g| format!("{} GB", locale::decimal(g, 1)))),
+                Cell::from(Span::styled(format!("{} {}", kind.symbol(), r.fit.explain()), app.theme.status_style(kind))),
+            ])
+            .style(style)
+        })
+        .collect();
+    let widths = [Constraint::Percentage(28), Constraint::Length(8), Constraint::Length(9), Constraint::Min(20)];
+    let table = Table::new(rows, widths)
+        .header(header)
+        .style(Style::default().bg(app.theme.bg).fg(app.theme.fg))
+        .block(Block::default().borders(Borders::ALL).border_style(Style::default().fg(app.theme.frame)).title("Recommended for this machine (✓ downloaded)"));
+    let mut state = TableState::default().with_selected(Some(st.selected).filter(|_| !st.rows.is_empty()));
+    f.render_stateful_widget(table, chunks[1], &mut state);
+}
+
+#[cfg(all(test, unix))]
+mod tests {
+    use std::time::Duration;
+
+    use super::*;
+    use crate::models::fetch_models;
+    use crate::testing::FakeCli;
+
+    #[test]
+    fn recommendations_rank_catalog_models_by_fit() {
+        let _fake = FakeCli::new();
+        let meminfo = "MemTotal:        4194304 kB\nMemFree:          524288 kB\nMemAvailable:    3145728 kB\n";
+        assert_eq!(parse_meminfo(meminfo), (Some(4.0), Some(3.0)));
+        let gpus = parse_nvidia_smi("NVIDIA GeForce RTX 3060, 12288, 8192\n");
+        assert_eq!(gpus, vec![Gpu { name: "NVIDIA GeForce RTX 3060".to_string(), total_gb: 12.0, free_gb: Some(8.0) }]);
+
+        let models = fetch_models(Duration::from_secs(5)).expect("models");
+        // 4 GB of RAM: a 2.8 GB budget fits Qwen3 1.7B but not Phi-3 Mini
+        let laptop = Machine { total_ram_gb: Some(4.0), available_ram_gb: Some(3.0), ..Default::default() };
+        let ranked = rank(&laptop, &models.entries);
+        assert_eq!(ranked.iter().map(|r| r.id.as_str()).collect::<Vec<_>>(), vec!["qwen3-1.7b", "phi3-mini"]);
+        assert!(ranked[0].fit.explain().starts_with("fits with 1"), "{}", ranked[0].fit.explain());
+        assert!(matches!(ranked[1].fit, Fit::TooBig { .. }));
+
+        // With the GPU both fit in VRAM; the larger model is the better pick
+        let desktop = Machine { gpus, ..laptop };
+        assert_eq!(rank(&desktop, &models.entries)[0].id, "phi3-mini");
+    }
+}
@@ -0,0 +1,149 @@
+use std::collections::hash_map::DefaultHasher;
+use std::hash::{Hash, Hasher};
+use std::io::{self, Write};
+
+use crossterm::execute;
+use crossterm::terminal::{BeginSynchronizedUpdate, EndSynchronizedUpdate};
+use ratatui::backend::CrosstermBackend;
+use ratatui::buffer::Buffer;
+use ratatui::prelude::Frame;
+use ratatui::Terminal;
+
+/// Tracks whether the screen needs repainting: after input, resize, or an
+/// explicit invalidation. A dirty frame is rendered off-screen first and
+/// only sent to the terminal when its content differs from the last one,
+/// so a tick that changes nothing visible writes nothing.
+#[derive(Debug)]
+pub struct FrameGate {
+    dirty: bool,
+    /// Content hash of the last frame sent to the terminal
+    last: Option<u64>,
+}
+
+impl FrameGate {
+    pub fn new() -> Self {
+        Self { dirty: true, last: None }
+    }
+    pub fn invalidate(&mut self) {
+        self.dirty = true;
+    }
+    /// Render a frame when dirty and send it when it changed. Output is
+    /// wrapped in a synchronized update (DEC mode 2026) so supporting
+    /// terminals present it atomically; terminals without support ignore
+    /// the escape sequences.
+    pub fn draw<W: Write, F>(&mut self, terminal: &mut Terminal<CrosstermBackend<W>>, render: F) -> io::Result<()>
+    where
+        F: FnOnce(&mut Frame),
+    {
+        if !self.dirty {
+            return Ok(());
+        }
+        self.dirty = false;
+        let before = terminal.get_frame().size();
+        // Clears the screen when the size changed: the next frame must be sent
+        terminal.autoresize()?;
+        if terminal.get_frame().size() != before { self.last = None; }
+        render(&mut terminal.get_frame());
+        let hash = content_hash(terminal.current_buffer_mut());
+        if self.last == Some(hash) {
+            // Start the next frame from a blank buffer, as a sent one would
+            terminal.current_buffer_mut().reset();
+            return Ok(());
+        }
+        execute!(terminal.backend_mut(), BeginSynchronizedUpdate)?;
+        let res = terminal.flush().and_then(|_| terminal.hide_cursor());
+        terminal.swap_buffers();
+        execute!(terminal.backend_mut(), EndSynchronizedUpdate)?;
+        res?;
+        terminal.backend_mut().flush()?;
+        self.last = Some(hash);
+        Ok(())
+    }
+}
+
+fn content_hash(buf: &Buffer) -> u64 {
+    let mut h = DefaultHasher::new();
+    buf.area.hash(&mut h);
+    for cell in &buf.content {
+        cell.symbol().hash(&mut h);
+        (cell.fg, cell.bg, cell.modifier).hash(&mut h);
+    }
+    h.finish()
+}
+
+#[cfg(test)]
+mod tests {
+    use std::cell::RefCell;
+    use std::rc::Rc;
+
+    use ratatui::layout::Rect;
+    use ratatui::widgets::Paragraph;
+    use ratatui::{TerminalOptions, Viewport};
+
+    use super::*;
+
+    /// A terminal writer whose output the test can read back.
+    #[derive(Clone, Default)]
+    struct Sink(Rc<RefCell<Vec<u8>>>);
+
+    impl Write for Sink {
+        fn write(&mut self, data: &[u8]) -> io::Result<usize> {
+            self.0.borrow_mut().extend_from_slice(data);
+            Ok(data.len())
+        }
+        fn flush(&mut self) -> io::Result<()> {
+            Ok(())
+        }
+    }
+
+    fn terminal(sink: &Sink) -> Terminal<CrosstermBackend<Sink>> {
+        let viewport = Viewport::Fixed(Rect::new(0, 0, 20, 3));
+        Terminal::with_options(CrosstermBackend::new(sink.clone()), TerminalOptions { viewport }).expect("terminal")
+    }
+
+    fn written(sink: &Sink) -> usize {
+        std::mem::take(&mut *sink.0.borrow_mut()).len()
+    }
+
+    #[test]
+    fn an_identical_frame_writes_nothing() {
+        let sink = Sink::default();
+        let mut term = terminal(&sink);
+        let mut gate = FrameGate::new();
+        let hello = |f: &mut Frame| f.render_widget(Paragraph::new("hello"), f.size());
+
+        gate.draw(&mut term, hello).expect("first");
+        assert!(written(&sink) > 0);
+        gate.invalidate();
+        gate.draw(&mut term, hello).expect("same");
+        assert_eq!(written(&sink), 0);
+        gate.invalidate();
+        gate.draw(&mut term, |f| f.render_widget(Paragraph::new("world"), f.size())).expect("changed");
+        assert!(written(&sink) > 0);
+    }
+
+    #[test]
+    fn a_clean_gate_does_not_render() {
+        let sink = Sink::default();
+        let mut term = terminal(&sink);
+        let mut gate = FrameGate::new();
+        gate.draw(&mut term, |_| {}).expect("first");
+        let mut rendered = false;
+        gate.draw(&mut term, |_| rendered = true).expect("clean");
+        assert!(!rendered);
+    }
+
+    #[test]
+    fn a_skipped_frame_leaves_no_stale_cells() {
+        let sink = Sink::default();
+        let mut term = terminal(&sink);
+        let mut gate = FrameGate::new();
+        gate.draw(&mut term, |f| f.render_widget(Paragraph::new("hello"), f.size())).expect("first");
+        gate.invalidate();
+        gate.draw(&mut term, |f| f.render_widget(Paragraph::new("hello"), f.size())).expect("skipped");
+        gate.invalidate();
+        gate.draw(&mut term, |_| {}).expect("blank");
+        // "hello" from the skipped render would make this frame a repeat
+        assert!(written(&sink) > 0);
+    }
+}
@@ -0,0 +1,250 @@
+use anyhow::{anyhow, Result};
+use chrono::{Datelike, Local, Timelike, Weekday};
+use serde_json::Value;
+
+/// One entry of `default_rules` in chi.tmp.json. All present conditions must
+/// hold; the first matching rule wins, otherwise `default_provider_id`.
+///
+/// ```json
+/// {"provider": "local1", "hours": "9-17", "days": "mon-fri", "power": "battery"}
+/// ```
+#[derive(Clone, Debug, Default)]
+pub struct DefaultRule {
+    pub provider: String,
+    pub hours: Option<(u32, u32)>,
+    pub days: Option<Vec<Weekday>>,
+    pub power: Option<PowerSource>,
+}
+
+#[derive(Copy, Clone, Debug, PartialEq, Eq)]
+pub enum PowerSource {
+    Battery,
+    Ac,
+}
+
+/// Inputs a rule is evaluated against; separated from the clock for clarity.
+#[derive(Copy, Clone, Debug)]
+pub struct RuleContext {
+    pub hour: u32,
+    pub weekday: Weekday,
+    pub power: Option<PowerSource>,
+}
+
+#[derive(Clone, Debug)]
+pub struct Resolution {
+    pub provider_id: String,
+    pub rule_index: Option<usize>,
+    pub reason: String,
+}
+
+fn parse_weekday(s: &str) -> Option<Weekday> {
+    match s.trim().to_lowercase().get(0..3)? {
+        "mon" => Some(Weekday::Mon),
+        "tue" => Some(Weekday::Tue),
+        "wed" => Some(Weekday::Wed),
+        "thu" => Some(Weekday::Thu),
+        "fri" => Some(Weekday::Fri),
+        "sat" => Some(Weekday::Sat),
+        "sun" => Some(Weekday::Sun),
+        _ => None,
+    }
+}
+
+/// "mon-fri", "sat,sun" or "mon,wed-fri".
+fn parse_days(s: &str) -> Result<Vec<Weekday>> {
+    let mut out = Vec::new();
+    for part in s.split(',') {
+        if let Some((a, b)) = part.split_once('-') {
+            let start = parse_weekday(a).ok_or_else(|| anyhow!("bad day: {}", a))?;
+            let end = parse_weekday(b).ok_or_else(|| anyhow!("bad day: {}", b))?;
+            let mut d = start;
+            loop {
+                out.push(d);
+                if d == end { break; }
+                d = d.succ();
+            }
+        } else {
+            out.push(parse_weekday(part).ok_or_else(|| anyhow!("bad day: {}", part))?);
+        }
+    }
+    Ok(out)
+}
+
+/// "9-17" means 09:00 up to (not including) 17:00; "22-6" wraps midnight.
+fn parse_hours(s: &str) -> Result<(u32, u32)> {
+    let (a, b) = s.split_once('-').ok_or_else(|| anyhow!("hours must look like 9-17"))?;
+    let a: u32 = a.trim().parse().map_err(|_| anyhow!("bad hour: {}", a))?;
+    let b: u32 = b.trim().parse().map_err(|_| anyhow!("bad hour: {}", b))?;
+    if a > 24 || b > 24 { return Err(anyhow!("hours must be within 0-24")); }
+    Ok((a, b))
+}
+
+pub fn parse_rules(root: &Value) -> Result<Vec<DefaultRule>> {
+    let mut rules = Vec::new();
+    let Some(arr) = root.get("default_rules").and_then(|x| x.as_array()) else { return Ok(rules) };
+    for (i, r) in arr.iter().enumerate() {
+        let provider = r.get("provider").and_then(|x| x.as_str()).ok_or_else(|| anyhow!("rule {}: missing provider", i + 1))?.to_string();
+        let hours = r.get("hours").and_then(|x| x.as_str()).map(parse_hours).transpose()?;
+        let days = r.get("days").and_then(|x| x.as_str()).map(parse_days).transpose()?;
+        let power = match r.get("power").and_then(|x| x.as_str()) {
+            Some("battery") => Some(PowerSource::Battery),
+            Some("ac") => Some(PowerSource::Ac),
+            Some(other) => return Err(anyhow!("rule {}: power must be battery or ac, got {}", i + 1, other)),
+            None => None,
+        };
+        rules.push(DefaultRule { provider, hours, days, power });
+    }
+    Ok(rules)
+}
+
+impl DefaultRule {
+    pub fn matches(&self, ctx: &RuleContext) -> bool {
+        if let Some((a, b)) = self.hours {
+            let inside = if a <= b { ctx.hour >= a && ctx.hour < b } else { ctx.hour >= a || ctx.hour < b };
+            if !inside { return false; }
+        }
+        if let Some(days) = &self.days {
+            if !days.contains(&ctx.weekday) { return false; }
+        }
+        if let Some(p) = self.power {
+            // Unknown power state never satisfies a power condition
+            if ctx.power != Some(p) { return false; }
+        }
+        true
+    }
+
+    pub fn describe(&self) -> String {
+        let mut parts = Vec::new();
+        if let Some((a, b)) = self.hours { parts.push(format!("{}-{}h", a, b)); }
+        if let Some(d) = &self.days { parts.push(d.iter().map(|w| w.to_string()).collect::<Vec<_>>().join(",")); }
+        match self.power {
+            Some(PowerSource::Battery) => parts.push("on battery".to_string()),
+            Some(PowerSource::Ac) => parts.push("on AC".to_string()),
+            None => {}
+        }
+        if parts.is_empty() { "always".to_string() } else { parts.join(" ") }
+    }
+}
+
+/// Best-effort power source detection; None when unknown (desktops, Windows).
+pub fn detect_power() -> Option<PowerSource> {
+    if cfg!(target_os = "linux") {
+        let rd = std::fs::read_dir("/sys/class/power_supply").ok()?;
+        let mut saw_battery = false;
+        for e in rd.flatten() {
+            let p = e.path();
+            let kind = std::fs::read_to_string(p.join("type")).unwrap_or_default();
+            if kind.trim() == "Mains" {
+                if std::fs::read_to_string(p.join("online")).unwrap_or_default().trim() == "1" { return Some(PowerSource::Ac); }
+            } else if kind.trim() == "Battery" {
+                saw_battery = true;
+                if std::fs::read_to_string(p.join("status")).unwrap_or_default().trim() == "Discharging" { return Some(PowerSource::Battery); }
+            }
+        }
+        if saw_battery { Some(PowerSource::Ac) } else { None }
+    } else if cfg!(target_os = "macos") {
+        let out = std::process::Command::new("pmset").args(["-g", "batt"]).output().ok()?;
+        let text = String::from_utf8_lossy(&out.stdout);
+        if text.contains("Battery Power") { Some(PowerSource::Battery) } else if text.contains("AC Power") { Some(PowerSource::Ac) } else { None }
+    } else {
+        None
+    }
+}
+
+pub fn current_context() -> RuleContext {
+    let now = Local::now();
+    RuleContext { hour: now.hour(), weekday: now.weekday(), power: detect_power() }
+}
+
+pub fn resolve(root: &Value, ctx: &RuleContext) -> Result<Resolution> {
+    for (i, rule) in parse_rules(root)?.iter().enumerate() {
+        if rule.matches(ctx) {
+            return Ok(Resolution { provider_id: rule.provider.clone(), rule_index: Some(i), reason: format!("rule #{}: {}", i + 1, rule.describe()) });
+        }
+    }
+    let id = root
+        .get("default_provider_id")
+        .and_then(|x| x.as_str())
+        .ok_or_else(|| anyhow!("no rule matched and no default_provider_id set"))?;
+    Ok(Resolution { provider_id: id.to_string(), rule_index: None, reason: "default_provider_id".to_string() })
+}
+
+pub fn resolve_from_store() -> Result<Resolution> {
+    let root = crate::store::read_layered()?;
+    resolve(&root, &current_context())
+}
+
+/// `chi-tui resolve-default [--json]`: print the provider id in effect now.
+pub fn run_resolve_default(json: bool) -> Result<()> {
+    let r = resolve_from_store()?;
+    if json {
+        println!("{}", serde_json::to_string_pretty(&serde_json::json!({
+            "provider_id": r.provider_id,
+            "rule_index": r.rule_index,
+            "reason": r.reason,
+        }))?);
+    } else {
+        println!("{}", r.provider_id);
+    }
+    Ok(())
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+
+    fn ctx(hour: u32, weekday: Weekday, power: Option<PowerSource>) -> RuleContext {
+        RuleContext { hour, weekday, power }
+    }
+
+    #[test]
+    fn days_and_hours_parse() {
+        assert_eq!(parse_days("mon-fri").expect("range"), [Weekday::Mon, Weekday::Tue, Weekday::Wed, Weekday::Thu, Weekday::Fri]);
+        assert_eq!(parse_days("sat,Sunday").expect("list"), [Weekday::Sat, Weekday::Sun]);
+        assert_eq!(parse_days("fri-mon").expect("wraps"), [Weekday::Fri, Weekday::Sat, Weekday::Sun, Weekday::Mon]);
+        assert!(parse_days("mon-funday").is_err());
+        assert_eq!(parse_hours("9-17").expect("hours"), (9, 17));
+        assert!(parse_hours("9").is_err());
+        assert!(parse_hours("9-25").is_err());
+    }
+
+    #[test]
+    fn hour_ranges_exclude_the_end_and_may_wrap_midnight() {
+        let office = DefaultRule { provider: "p".into(), hours: Some((9, 17)), ..Default::default() };
+        assert!(office.matches(&ctx(9, Weekday::Mon, None)));
+        assert!(!office.matches(&ctx(17, Weekday::Mon, None)));
+        let night = DefaultRule { provider: "p".into(), hours: Some((22, 6)), ..Default::default() };
+        assert!(night.matches(&ctx(23, Weekday::Mon, None)) && night.matches(&ctx(5, Weekday::Mon, None)));
+        assert!(!night.matches(&ctx(12, Weekday::Mon, None)));
+    }
+
+    #[test]
+    fn unknown_power_never_matches_a_power_rule() {
+        let battery = DefaultRule { provider: "p".into(), power: Some(PowerSource::Battery), ..Default::default() };
+        assert!(battery.matches(&ctx(0, Weekday::Mon, Some(PowerSource::Battery))));
+        assert!(!battery.matches(&ctx(0, Weekday::Mon, Some(PowerSource::Ac))));
+        assert!(!battery.matches(&ctx(0, Weekday::Mon, None)));
+    }
+
+    #[test]
+    fn the_first_matching_rule_wins_else_the_default() {
+        let root = serde_json::json!({
+            "default_provider_id": "cloud",
+            "default_rules": [
+                {"provider": "laptop", "power": "battery"},
+                {"provider": "office", "hours": "9-17", "days": "mon-fri"},
+            ],
+        });
+        let r = resolve(&root, &ctx(10, Weekday::Tue, Some(PowerSource::Battery))).expect("battery");
+        assert_eq!((r.provider_id.as_str(), r.rule_index), ("laptop", Some(0)));
+        let r = resolve(&root, &ctx(10, Weekday::Tue, Some(PowerSource::Ac))).expect("office");
+        assert_eq!((r.provider_id.as_str(), r.rule_index), ("office", Some(1)));
+        assert!(r.reason.starts_with("rule #2: 9-17h"), "{}", r.reason);
+        let r = resolve(&root, &ctx(10, Weekday::Sat, None)).expect("weekend");
+        assert_eq!((r.provider_id.as_str(), r.rule_index), ("cloud", None));
+
+        assert!(resolve(&serde_json::json!({"default_rules": [{"hours": "9-17"}]}), &ctx(10, Weekday::Mon, None)).is_err());
+        assert!(resolve(&serde_json::json!({"default_rules": [{"provider": "x", "power": "solar"}]}), &ctx(10, Weekday::Mon, None)).is_err());
+        assert!(resolve(&serde_json::json!({}), &ctx(10, Weekday::Mon, None)).is_err());
+    }
+}
@@ -0,0 +1,206 @@
+//! `chi-tui --script "2,A,tab,s"`: feed keys to the app without a terminal
+//! and print the final screen, for end-to-end tests of whole flows and for
+//! reproducing bug reports. Steps are separated by commas or newlines; in a
+//! script file, lines starting with `#` are comments.
+//!
+//! - a single character types it (`A` arrives with Shift, as from a
+//!   terminal); `comma` and `space` type those
+//! - named keys: `enter`, `esc`, `tab`, `backtab`, `up`, `down`, `left`,
+//!   `right`, `home`, `end`, `pgup`, `pgdn`, `backspace`, `delete`,
+//!   `insert`, `f1`…`f12`
+//! - modifiers prefix any key: `ctrl+c`, `alt+enter`, `shift+enter`
+//! - `text:hello world` types each character
+//! - `wait:500ms` lets background work (checks, downloads) run first
+
+use std::path::Path;
+use std::time::{Duration, Instant};
+
+use anyhow::{anyhow, Result};
+use crossterm::event::{KeyCode, KeyEvent, KeyModifiers};
+use ratatui::backend::TestBackend;
+use ratatui::buffer::Buffer;
+use ratatui::Terminal;
+
+use crate::app::App;
+use crate::keys::{handle_diagnostics_key, handle_key};
+use crate::probe::parse_timeout;
+use crate::{menu, tick, ui};
+
+#[derive(Clone, Copy, Debug, PartialEq, Eq)]
+pub enum Step {
+    Key(KeyEvent),
+    Wait(Duration),
+}
+
+/// `--script` argument: a script file if one exists at that path, else the
+/// script itself.
+pub fn load(arg: &str) -> Result<Vec<Step>> {
+    let path = Path::new(arg);
+    if path.is_file() {
+        let text = std::fs::read_to_string(path).map_err(|e| anyhow!("{}: {}", path.display(), e))?;
+        return parse(&text).map_err(|e| anyhow!("{}: {}", path.display(), e));
+    }
+    parse(arg)
+}
+
+pub fn parse(script: &str) -> Result<Vec<Step>> {
+    let mut steps = Vec::new();
+    for line in script.lines().map(str::trim).filter(|l| !l.starts_with('#')) {
+        for token in line.split(',').map(str::trim).filter(|t| !t.is_empty()) {
+            if let Some(text) = token.strip_prefix("text:") {
+                steps.extend(text.chars().map(|c| Step::Key(char_key(c, KeyModifiers::NONE))));
+            } else if let Some(d) = token.strip_prefix("wait:") {
+                steps.push(Step::Wait(parse_timeout(d)?));
+            } else {
+                steps.push(Step::Key(key(token)?));
+            }
+        }
+    }
+    Ok(steps)
+}
+
+fn char_key(c: char, mut mods: KeyModifiers) -> KeyEvent {
+    if c.is_uppercase() { mods |= KeyModifiers::SHIFT; }
+    KeyEvent::new(KeyCode::Char(c), mods)
+}
+
+/// One key, e.g. `enter`, `q`, `ctrl+c`, `shift+tab`.
+fn key(token: &str) -> Result<KeyEvent> {
+    let mut mods = KeyModifiers::NONE;
+    let mut rest = token;
+    while let Some((m, r)) = rest.split_once('+').filter(|(_, r)| !r.is_empty()) {
+        mods |= match m.to_lowercase().as_str() {
+            "ctrl" | "control" => KeyModifiers::CONTROL,
+            "alt" => KeyModifiers::ALT,
+            "shift" => KeyModifiers::SHIFT,
+            _ => return Err(anyhow!("unknown modifier \"{}\" in \"{}\" (ctrl, alt, shift)", m, token)),
+        };
+        rest = r;
+    }
+    let code = match rest.to_lowercase().as_str() {
+        "enter" | "return" => KeyCode::Enter,
+        "esc" | "escape" => KeyCode::Esc,
+        "tab" if mods.contains(KeyModifiers::SHIFT) => KeyCode::BackTab,
+        "tab" => KeyCode::Tab,
+        "backtab" => { mods |= KeyModifiers::SHIFT; KeyCode::BackTab }
+        "up" => KeyCode::Up,
+        "down" => KeyCode::Down,
+        "left" => KeyCode::Left,
+        "right" => KeyCode::Right,
+        "home" => KeyCode::Home,
+        "end" => KeyCode::End,
+        "pgup" | "pageup" => KeyCode::PageUp,
+        "pgdn" | "pagedown" => KeyCode::PageDown,
+        "backspace" | "bs" => KeyCode::Backspace,
+        "delete" | "del" => KeyCode::Delete,
+        "insert" | "ins" => KeyCode::Insert,
+        "space" => KeyCode::Char(' '),
+        "comma" => KeyCode::Char(','),
+        f if f.len() > 1 && f.starts_with('f') && f[1..].parse::<u8>().map_or(false, |n| (1..=12).contains(&n)) => {
+            KeyCode::F(f[1..].parse().unwrap_or(1))
+        }
+        _ => {
+            let mut chars = rest.chars();
+            return match (chars.next(), chars.next()) {
+                (Some(c), None) => Ok(char_key(c, mods)),
+                _ => Err(anyhow!("unknown key \"{}\"", token)),
+            };
+        }
+    };
+    Ok(KeyEvent::new(code, mods))
+}
+
+/// Play the steps on an off-screen terminal of `size` and return the final
+/// screen as text. Stops early when the app quits.
+pub fn run(mut app: App, steps: &[Step], (width, height): (u16, u16)) -> Result<String> {
+    app.fit(width, height);
+    for step in steps {
+        let inputs = menu::inputs(&app);
+        app.menu.refresh(inputs);
+        match *step {
+            Step::Key(key) => {
+                if !handle_diagnostics_key(&mut app, key) { handle_key(&mut app, key); }
+            }
+            Step::Wait(d) => {
+                let until = Instant::now() + d;
+                while Instant::now() < until {
+                    tick(&mut app);
+                    std::thread::sleep(Duration::from_millis(20));
+                }
+            }
+        }
+        tick(&mut app);
+        if app.should_quit { break; }
+    }
+    render(&mut app, width, height)
+}
+
+/// One fully rendered frame of `app` at `width`x`height`, as text: the same
+/// layout decisions (compact mode, menu state) the interactive loop makes
+/// before drawing. Used by `--script`, `--dump-frame` and golden tests.
+pub fn render(app: &mut App, width: u16, height: u16) -> Result<String> {
+    let mut terminal = Terminal::new(TestBackend::new(width, height))?;
+    app.fit(width, height);
+    let inputs = menu::inputs(app);
+    app.menu.refresh(inputs);
+    terminal.draw(|f| ui::draw(f, app))?;
+    Ok(screen_text(terminal.backend().buffer()))
+}
+
+/// `--size`: `WIDTHxHEIGHT`, e.g. `100x30`.
+pub fn parse_size(s: &str) -> std::result::Result<(u16, u16), String> {
+    let parsed = s.split_once(['x', 'X']).and_then(|(w, h)| Some((w.trim().parse().ok()?, h.trim().parse().ok()?)));
+    match parsed {
+        Some((w, h)) if w > 0 && h > 0 => Ok((w, h)),
+        _ => Err(format!("expected WIDTHxHEIGHT, e.g. 100x30, got \"{}\"", s)),
+    }
+}
+
+/// The rendered screen as text, one line per row, trailing spaces trimmed.
+pub fn screen_text(buf: &Buffer) -> String {
+    let area = buf.area;
+    (area.top()..area.bottom())
+        .map(|y| (area.left()..area.right()).map(|x| buf.get(x, y).symbol()).collect::<String>().trim_end().to_string())
+        .collect::<Vec<_>>()
+        .join("\n")
+        + "\n"
+}
+
+#[cfg(all(test, unix))]
+mod tests {
+    use super::*;
+    use crate::testing::FakeCli;
+
+    #[test]
+    fn a_key_script_adds_and_saves_a_provider() {
+        let fake = FakeCli::new();
+        let out = run(App::new(false), &parse("2, A, tab, s").expect("script"), (100, 30)).expect("run script");
+        let store = fake.store();
+        assert_eq!(store["providers"][0]["type"], "local");
+        assert_eq!(store["default_provider_id"], "p1");
+        assert_eq!(out.lines().count(), 30);
+        assert!(out.contains("Configure") && out.contains("is now the default provider"), "{}", out);
+
+        let steps = parse("# a comment\nctrl+c, shift+tab ,F5\ncomma,text:a B,wait:50ms").expect("parse");
+        assert_eq!(steps, [
+            Step::Key(KeyEvent::new(KeyCode::Char('c'), KeyModifiers::CONTROL)),
+            Step::Key(KeyEvent::new(KeyCode::BackTab, KeyModifiers::SHIFT)),
+            Step::Key(KeyEvent::new(KeyCode::F(5), KeyModifiers::NONE)),
+            Step::Key(KeyEvent::new(KeyCode::Char(','), KeyModifiers::NONE)),
+            Step::Key(KeyEvent::new(KeyCode::Char('a'), KeyModifiers::NONE)),
+            Step::Key(KeyEvent::new(KeyCode::Char(' '), KeyModifiers::NONE)),
+            Step::Key(KeyEvent::new(KeyCode::Char('B'), KeyModifiers::SHIFT)),
+            Step::Wait(Duration::from_millis(50)),
+        ]);
+        assert!(parse("enter,nope").expect_err("unknown key").to_string().contains("\"nope\""));
+        assert_eq!(parse_size("80x24"), Ok((80, 24)));
+        assert!(parse_size("80").is_err());
+
+        // A file works the same as the inline form; q ends the run
+        let path = fake.root.join("flow.keys");
+        std::fs::write(&path, "# open Select Default\n3\nq\n4\n").expect("write script");
+        let steps = load(&path.display().to_string()).expect("load script file");
+        let out = run(App::new(false), &steps, (100, 30)).expect("run script");
+        assert!(out.contains("Select Default") && !out.contains("Loading diagnostics"), "{}", out);
+    }
+}
@@ -0,0 +1,329 @@
+use std::collections::BTreeMap;
+use std::fs;
+use std::io::Write;
+use std::path::{Path, PathBuf};
+use std::process::{Command, Stdio};
+
+use anyhow::{anyhow, Context, Result};
+use chacha20poly1305::aead::{Aead, KeyInit};
+use chacha20poly1305::{ChaCha20Poly1305, Key, Nonce};
+use serde_json::Value;
+use sha2::{Digest, Sha256};
+
+use crate::log;
+
+/// Secret provider fields (API keys) are kept out of chi.tmp.json: saving
+/// moves the value to the OS keychain, or an encrypted file when no keychain
+/// is usable, and writes `secret:<store>/<provider id>.<field>` in its place.
+/// Reading a provider for use resolves the reference back to the value.
+pub const REF_PREFIX: &str = "secret:";
+
+const SERVICE: &str = "chi_llm";
+
+/// Where a secret was stored.
+#[derive(Copy, Clone, Debug, PartialEq, Eq)]
+pub enum Backend {
+    /// OS keychain via the named tool
+    Keychain(&'static str),
+    /// `secrets.json`, encrypted with a local key file
+    File,
+}
+
+impl Backend {
+    pub fn label(self) -> &'static str {
+        match self {
+            Backend::Keychain(tool) => tool,
+            Backend::File => "encrypted file",
+        }
+    }
+}
+
+pub fn is_ref(s: &str) -> bool {
+    s.starts_with(REF_PREFIX)
+}
+
+/// Namespace for the secrets of one store: 12 hex digits of the SHA-256 of
+/// its canonical location (a project directory, or the global providers
+/// file). Two projects with a provider `openai` keep two separate keys.
+pub fn namespace(location: &Path) -> String {
+    let canonical = location.canonicalize().unwrap_or_else(|_| location.to_path_buf());
+    hex(&Sha256::digest(canonical.to_string_lossy().as_bytes())[..6])
+}
+
+pub fn ref_name(namespace: &str, provider_id: &str, field: &str) -> String {
+    format!("{}/{}.{}", namespace, provider_id, field)
+}
+
+/// `CHI_TUI_SECRETS=file` skips the keychain (headless boxes, CI).
+fn keychain_enabled() -> bool {
+    std::env::var("CHI_TUI_SECRETS").map_or(true, |v| v.trim().to_lowercase() != "file")
+}
+
+/// Store `value` under `name`, preferring the keychain.
+pub fn put(name: &str, value: &str) -> Result<Backend> {
+    if keychain_enabled() {
+        match keychain_put(name, value) {
+            Ok(tool) => {
+                // A stale copy in the file would shadow nothing, but would linger
+                let _ = file_delete(name);
+                return Ok(Backend::Keychain(tool));
+            }
+            Err(e) => log::warn(&format!("keychain unavailable for {}: {}; using encrypted file", name, e)),
+        }
+    }
+    file_put(name, value)?;
+    Ok(Backend::File)
+}
+
+pub fn get(name: &str) -> Result<String> {
+    if keychain_enabled() {
+        if let Ok(v) = keychain_get(name) { return Ok(v); }
+    }
+    file_get(name)?.ok_or_else(|| anyhow!("secret \"{}\" not found in keychain or secrets file", name))
+}
+
+/// Replace `secret:` references anywhere in a config with their values.
+/// Missing secrets stay as the reference, so a test fails with a visible cause.
+pub fn resolve_value(v: &Value) -> Value {
+    match v {
+        Value::String(s) if is_ref(s) => match get(&s[REF_PREFIX.len()..]) {
+            Ok(secret) => Value::String(secret),
+            Err(e) => {
+                log::warn(&e.to_string());
+                v.clone()
+            }
+        },
+        Value::Object(o) => Value::Object(o.iter().map(|(k, x)| (k.clone(), resolve_value(x))).collect()),
+        Value::Array(a) => Value::Array(a.iter().map(resolve_value).collect()),
+        other => other.clone(),
+    }
+}
+
+// --- OS keychain, through the platform's command-line tool ---
+
+fn run_with_stdin(cmd: &mut Command, input: &str) -> Result<String> {
+    let mut child = cmd.stdin(Stdio::piped()).stdout(Stdio::piped()).stderr(Stdio::piped()).spawn()?;
+    if let Some(mut stdin) = child.stdin.take() { stdin.write_all(input.as_bytes())?; }
+    let out = child.wait_with_output()?;
+    if !out.status.success() {
+        return Err(anyhow!("{}", String::from_utf8_lossy(&out.stderr).trim().to_string()));
+    }
+    Ok(String::from_utf8_lossy(&out.stdout).trim_end_matches(['\r', '\n']).to_string())
+}
+
+/// Values travel over stdin, never argv, so they do not show in `ps`.
+#[cfg(target_os = "macos")]
+fn keychain_put(name: &str, value: &str) -> Result<&'static str> {
+    // `security -i` reads commands from stdin; it has no escaping for quotes
+    if value.contains(['"', '\\', '\n']) { return Err(anyhow!("value not storable via security -i")); }
+    run_with_stdin(Command::new("security").arg("-i"), &format!("add-generic-password -U -s {} -a \"{}\" -w \"{}\"\n", SERVICE, name, value))?;
+    Ok("macOS Keychain")
+}
+
+#[cfg(target_os = "macos")]
+fn keychain_get(name: &str) -> Result<String> {
+    run_with_stdin(Command::new("security").args(["find-generic-password", "-s", SERVICE, "-a", name, "-w"]), "")
+}
+
+#[cfg(target_os = "macos")]
+fn keychain_delete(name: &str) -> Result<()> {
+    run_with_stdin(Command::new("security").args(["delete-generic-password", "-s", SERVICE, "-a", name]), "").map(|_| ())
+}
+
+#[cfg(windows)]
+const VAULT: &str = "[void][Windows.Security.Credentials.PasswordVault,Windows.Security.Credentials,ContentType=WindowsRuntime]; $v = New-Object Windows.Security.Credentials.PasswordVault;";
+
+#[cfg(windows)]
+fn keychain_put(name: &str, value: &str) -> Result<&'static str> {
+    let script = format!("{} $s = [Console]::In.ReadToEnd(); $v.Add((New-Object Windows.Security.Credentials.PasswordCredential('{}', '{}', $s)))", VAULT, SERVICE, name.replace('\'', "''"));
+    run_with_stdin(Command::new("powershell.exe").args(["-NoProfile", "-Command", &script]), value)?;
+    Ok("Windows Credential Manager")
+}
+
+#[cfg(windows)]
+fn keychain_get(name: &str) -> Result<String> {
+    let script = format!("{} $c = $v.Retrieve('{}', '{}'); $c.RetrievePassword(); [Console]::Out.Write($c.Password)", VAULT, SERVICE, name.replace('\'', "''"));
+    run_with_stdin(Command::new("powershell.exe").args(["-NoProfile", "-Command", &script]), "")
+}
+
+#[cfg(windows)]
+fn keychain_delete(name: &str) -> Result<()> {
+    let script = format!("{} $v.Remove($v.Retrieve('{}', '{}'))", VAULT, SERVICE, name.replace('\'', "''"));
+    run_with_stdin(Command::new("powershell.exe").args(["-NoProfile", "-Command", &script]), "").map(|_| ())
+}
+
+/// libsecret (GNOME Keyring, KWallet via the Secret Service API).
+#[cfg(all(unix, not(target_os = "macos")))]
+fn keychain_put(name: &str, value: &str) -> Result<&'static str> {
+    let label = format!("{} {}", SERVICE, name);
+    run_with_stdin(Command::new("secret-tool").args(["store", "--label", &label, "service", SERVICE, "account", name]), value)?;
+    Ok("libsecret")
+}
+
+#[cfg(all(unix, not(target_os = "macos")))]
+fn keychain_get(name: &str) -> Result<String> {
+    let v = run_with_stdin(Command::new("secret-tool").args(["lookup", "service", SERVICE, "account", name]), "")?;
+    if v.is_empty() { return Err(anyhow!("not in keyring")); }
+    Ok(v)
+}
+
+#[cfg(all(unix, not(target_os = "macos")))]
+fn keychain_delete(name: &str) -> Result<()> {
+    run_with_stdin(Command::new("secret-tool").args(["clear", "service", SERVICE, "account", name]), "").map(|_| ())
+}
+
+// --- Encrypted file fallback ---
+
+/// `secrets.json` maps names to hex(nonce ‖ ChaCha20-Poly1305 ciphertext);
+/// the 32-byte key sits next to it in `secrets.key`. Both are user-only on
+/// Unix. This keeps keys out of project files and backups, not away from
+/// someone who can read the user's home directory.
+fn secrets_dir() -> Result<PathBuf> {
+    let base = dirs::config_dir().ok_or_else(|| anyhow!("config dir not found"))?;
+    Ok(base.join("chi_llm"))
+}
+
+/// Write a file only the user can read. New files are created 0600 rather
+/// than chmod-ed after the write, so the content is never readable by others.
+pub fn write_private(path: &Path, data: &[u8]) -> Result<()> {
+    let mut opts = fs::OpenOptions::new();
+    opts.write(true).create(true).truncate(true);
+    #[cfg(unix)]
+    {
+        use std::os::unix::fs::OpenOptionsExt;
+        opts.mode(0o600);
+    }
+    let mut file = opts.open(path)?;
+    // An existing file keeps its mode on open: tighten it before writing
+    #[cfg(unix)]
+    {
+        use std::os::unix::fs::PermissionsExt;
+        file.set_permissions(fs::Permissions::from_mode(0o600))?;
+    }
+    file.write_all(data)?;
+    Ok(())
+}
+
+fn file_key() -> Result<[u8; 32]> {
+    let dir = secrets_dir()?;
+    let path = dir.join("secrets.key");
+    if let Ok(bytes) = fs::read(&path) {
+        return bytes.try_into().map_err(|_| anyhow!("{} is corrupt (expected 32 bytes)", path.display()));
+    }
+    fs::create_dir_all(&dir)?;
+    let mut key = [0u8; 32];
+    getrandom::getrandom(&mut key).map_err(|e| anyhow!("no system randomness: {}", e))?;
+    write_private(&path, &key)?;
+    Ok(key)
+}
+
+fn read_file_map() -> Result<BTreeMap<String, String>> {
+    let path = secrets_dir()?.join("secrets.json");
+    match fs::read_to_string(&path) {
+        Ok(text) => serde_json::from_str(&text).with_context(|| format!("reading {}", path.display())),
+        Err(_) => Ok(BTreeMap::new()),
+    }
+}
+
+fn write_file_map(map: &BTreeMap<String, String>) -> Result<()> {
+    let dir = secrets_dir()?;
+    fs::create_dir_all(&dir)?;
+    write_private(&dir.join("secrets.json"), serde_json::to_string_pretty(map)?.as_bytes())
+}
+
+fn hex(bytes: &[u8]) -> String {
+    bytes.iter().map(|b| format!("{:02x}", b)).collect()
+}
+
+fn unhex(s: &str) -> Option<Vec<u8>> {
+    if s.len() % 2 != 0 { return None; }
+    (0..s.len()).step_by(2).map(|i| u8::from_str_radix(s.get(i..i + 2)?, 16).ok()).collect()
+}
+
+fn file_put(name: &str, value: &str) -> Result<()> {
+    let cipher = ChaCha20Poly1305::new(Key::from_slice(&file_key()?));
+    let mut nonce = [0u8; 12];
+    getrandom::getrandom(&mut nonce).map_err(|e| anyhow!("no system randomness: {}", e))?;
+    let ct = cipher.encrypt(Nonce::from_slice(&nonce), value.as_bytes()).map_err(|_| anyhow!("encryption failed"))?;
+    let mut map = read_file_map()?;
+    map.insert(name.to_string(), hex(&[nonce.as_slice(), ct.as_slice()].concat()));
+    write_file_map(&map)
+}
+
+fn file_get(name: &str) -> Result<Option<String>> {
+    let map = read_file_map()?;
+    let Some(text) = map.get(name) else { return Ok(None) };
+    let raw = unhex(text).filter(|r| r.len() > 12).ok_or_else(|| anyhow!("secret \"{}\" is corrupt", name))?;
+    let cipher = ChaCha20Poly1305::new(Key::from_slice(&file_key()?));
+    let plain = cipher
+        .decrypt(Nonce::from_slice(&raw[..12]), &raw[12..])
+        .map_err(|_| anyhow!("secret \"{}\" does not decrypt (was secrets.key replaced?)", name))?;
+    Ok(Some(String::from_utf8(plain)?))
+}
+
+fn file_delete(name: &str) -> Result<()> {
+    let mut map = read_file_map()?;
+    if map.remove(name).is_some() { write_file_map(&map)?; }
+    Ok(())
+}
+
+/// Remove a secret from wherever it is stored.
+pub fn delete(name: &str) {
+    if keychain_enabled() { let _ = keychain_delete(name); }
+    let _ = file_delete(name);
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+
+    #[test]
+    fn hex_round_trips_and_rejects_bad_input() {
+        let bytes = [0u8, 1, 0x7f, 0xff];
+        assert_eq!(hex(&bytes), "00017fff");
+        assert_eq!(unhex("00017fff"), Some(bytes.to_vec()));
+        assert_eq!(unhex("abc"), None);
+        assert_eq!(unhex("zz"), None);
+    }
+
+    #[test]
+    fn refs_are_namespaced_per_store_location() {
+        let (a, b) = (namespace(Path::new("/no/such/project-a")), namespace(Path::new("/no/such/project-b")));
+        assert_eq!(a.len(), 12);
+        assert_ne!(a, b);
+        assert_eq!(a, namespace(Path::new("/no/such/project-a")));
+        assert_eq!(ref_name(&a, "openai", "api_key"), format!("{}/openai.api_key", a));
+    }
+
+    #[cfg(unix)]
+    #[test]
+    fn file_backend_encrypts_and_keeps_files_private() {
+        use std::os::unix::fs::PermissionsExt;
+        let _fake = crate::testing::FakeCli::new();
+        assert_eq!(put("ns/p.api_key", "sk-secret").expect("put"), Backend::File);
+        assert_eq!(get("ns/p.api_key").expect("get"), "sk-secret");
+        let dir = secrets_dir().expect("dir");
+        assert!(!fs::read_to_string(dir.join("secrets.json")).expect("secrets.json").contains("sk-secret"));
+        for name in ["secrets.json", "secrets.key"] {
+            assert_eq!(fs::metadata(dir.join(name)).expect("meta").permissions().mode() & 0o777, 0o600, "{}", name);
+        }
+        // A replaced key file cannot decrypt, and says why
+        write_private(&dir.join("secrets.key"), &[7u8; 32]).expect("new key");
+        assert!(get("ns/p.api_key").expect_err("wrong key").to_string().contains("secrets.key"));
+        delete("ns/p.api_key");
+        assert!(file_get("ns/p.api_key").expect("read").is_none());
+    }
+
+    #[cfg(unix)]
+    #[test]
+    fn write_private_tightens_an_existing_file() {
+        use std::os::unix::fs::PermissionsExt;
+        let fake = crate::testing::FakeCli::new();
+        let path = fake.root.join("open.txt");
+        fs::write(&path, "x").expect("write");
+        fs::set_permissions(&path, fs::Permissions::from_mode(0o644)).expect("chmod");
+        write_private(&path, b"token").expect("write_private");
+        assert_eq!(fs::metadata(&path).expect("meta").permissions().mode() & 0o777, 0o600);
+        assert_eq!(fs::read_to_string(&path).expect("read"), "token");
+    }
+}
@@ -0,0 +1,235 @@
+use std::fs::{self, File};
+use std::io::Read;
+use std::net::{SocketAddr, TcpStream};
+use std::path::PathBuf;
+use std::process::{Child, Command, Stdio};
+use std::time::Duration;
+
+use anyhow::{anyhow, Result};
+use ratatui::layout::{Constraint, Direction, Layout, Rect};
+use ratatui::prelude::Frame;
+use ratatui::style::{Modifier, Style};
+use ratatui::text::{Line, Span};
+use ratatui::widgets::{Block, Borders, Paragraph, Wrap};
+use serde_json::Value;
+
+use crate::app::App;
+use crate::log;
+use crate::portforward::K8S_FIELD_PREFIX;
+use crate::providers::{read_scratch_entries, ProviderScratchEntry};
+use crate::util::fnv1a;
+
+pub const DEFAULT_PORT: u16 = 8765;
+const LOG_TAIL: usize = 200;
+
+pub fn log_file() -> Result<PathBuf> {
+    let home = dirs::home_dir().ok_or_else(|| anyhow!("home dir not found"))?;
+    Ok(home.join(".cache").join("chi_llm").join("api_server.log"))
+}
+
+/// 32 hex chars from /dev/urandom, falling back to a time/pid hash.
+pub fn random_token() -> String {
+    let mut buf = [0u8; 16];
+    if File::open("/dev/urandom").and_then(|mut f| f.read_exact(&mut buf)).is_ok() {
+        return buf.iter().map(|b| format!("{:02x}", b)).collect();
+    }
+    let seed = format!("{:?}{}", std::time::SystemTime::now(), std::process::id());
+    format!("{:016x}{:016x}", fnv1a(&seed), fnv1a(&seed.chars().rev().collect::<String>()))
+}
+
+/// The `chi-llm serve` child process. Lives on App so the server keeps
+/// running while other pages are open; killed when the TUI exits.
+#[derive(Default)]
+pub struct ApiServer {
+    child: Option<Child>,
+    pub port: u16,
+    pub provider: String,
+    pub auth: bool,
+    /// Local start time, e.g. "14:03:12"
+    pub started: Option<String>,
+    /// Accepting connections (checked until the first success)
+    pub ready: bool,
+    pub exit: Option<String>,
+    log_len: u64,
+}
+
+impl ApiServer {
+    pub fn running(&self) -> bool {
+        self.child.is_some()
+    }
+
+    /// Launch `chi-llm serve`. `entry` = None serves the active chi_llm config.
+    pub fn start(&mut self, port: u16, token: &str, entry: Option<&ProviderScratchEntry>) -> Result<()> {
+        if self.running() { return Err(anyhow!("server already running")); }
+        let path = log_file()?;
+        if let Some(dir) = path.parent() { fs::create_dir_all(dir)?; }
+        let out = File::create(&path)?;
+        let mut cmd = Command::new("chi-llm");
+        cmd.args(["serve", "--port", &port.to_string()]);
+        // Token via env so it is not visible in process listings
+        if !token.is_empty() { cmd.env("CHI_LLM_SERVER_TOKEN", token); }
+        if let Some(e) = entry { cmd.env("CHI_LLM_CONFIG", provider_override(e).to_string()); }
+        cmd.env("PYTHONUNBUFFERED", "1").stdin(Stdio::null()).stdout(Stdio::from(out.try_clone()?)).stderr(Stdio::from(out));
+        let child = cmd.spawn().map_err(|e| anyhow!("failed to run chi-llm serve: {}", e))?;
+        let provider = entry.map(|e| e.id.clone()).unwrap_or_else(|| "active config".to_string());
+        log::info(&format!("api server started on :{} (provider {}, pid {})", port, provider, child.id()));
+        self.child = Some(child);
+        self.port = port;
+        self.provider = provider;
+        self.auth = !token.is_empty();
+        self.started = Some(chrono::Local::now().format("%H:%M:%S").to_string());
+        self.ready = false;
+        self.exit = None;
+        self.log_len = 0;
+        Ok(())
+    }
+
+    pub fn stop(&mut self) {
+        if let Some(mut child) = self.child.take() {
+            let _ = child.kill();
+            let _ = child.wait();
+            log::info(&format!("api server on :{} stopped", self.port));
+            self.exit = Some("stopped".to_string());
+        }
+        self.ready = false;
+    }
+
+    /// Poll the child, readiness and log growth. Returns true when the page
+    /// should redraw.
+    pub fn refresh(&mut self) -> bool {
+        let Some(child) = self.child.as_mut() else { return false };
+        let mut changed = false;
+        if let Ok(Some(st)) = child.try_wait() {
+            self.exit = Some(st.code().map(|c| format!("exited ({})", c)).unwrap_or_else(|| "killed".to_string()));
+            log::warn(&format!("api server on :{} {}", self.port, self.exit.as_deref().unwrap_or("")));
+            self.child = None;
+            self.ready = false;
+            changed = true;
+        } else if !self.ready {
+            let addr = SocketAddr::from(([127, 0, 0, 1], self.port));
+            if TcpStream::connect_timeout(&addr, Duration::from_millis(20)).is_ok() { self.ready = true; changed = true; }
+        }
+        let len = log_file().ok().and_then(|p| fs::metadata(p).ok()).map_or(0, |m| m.len());
+        if len != self.log_len { self.log_len = len; changed = true; }
+        changed
+    }
+
+    pub fn status_line(&self) -> String {
+        match (&self.child, &self.started) {
+            (Some(child), Some(t)) => {
+                let state = if self.ready { "running" } else { "starting" };
+                let auth = if self.auth { "token" } else { "no auth" };
+                format!("{} on http://127.0.0.1:{}/v1 • pid {} • provider {} • {} • since {}", state, self.port, child.id(), self.provider, auth, t)
+            }
+            _ => format!("stopped{}", self.exit.as_ref().map(|e| format!(" — last run {}", e)).unwrap_or_default()),
+        }
+    }
+}
+
+impl Drop for ApiServer {
+    fn drop(&mut self) {
+        self.stop();
+    }
+}
+
+/// CHI_LLM_CONFIG payload selecting `entry` as the provider (TUI-only k8s
+/// fields stripped).
+fn provider_override(entry: &ProviderScratchEntry) -> Value {
+    let mut provider = entry.config.as_object().cloned().unwrap_or_default();
+    provider.retain(|k, _| !k.starts_with(K8S_FIELD_PREFIX));
+    provider.insert("type".to_string(), Value::String(entry.ptype.clone()));
+    serde_json::json!({ "provider": provider })
+}
+
+pub const FIELD_PORT: usize = 0;
+pub const FIELD_TOKEN: usize = 1;
+pub const FIELD_PROVIDER: usize = 2;
+
+/// Launch form. `provider_idx` 0 is the active chi_llm config; i > 0 is
+/// `entries[i - 1]` from the scratch store.
+#[derive(Clone, Debug, Default)]
+pub struct ServerForm {
+    pub entries: Vec<ProviderScratchEntry>,
+    pub provider_idx: usize,
+    pub port: String,
+    pub token: String,
+    pub field: usize,
+    pub show_token: bool,
+    pub status: Option<String>,
+}
+
+pub fn load_server_form() -> ServerForm {
+    let (entries, status) = match read_scratch_entries() {
+        Ok(e) => (e, None),
+        Err(e) => (Vec::new(), Some(format!("Warning: providers not loaded: {}", e))),
+    };
+    ServerForm { entries, port: DEFAULT_PORT.to_string(), status, ..Default::default() }
+}
+
+impl ServerForm {
+    pub fn selected_entry(&self) -> Option<&ProviderScratchEntry> {
+        self.provider_idx.checked_sub(1).and_then(|i| self.entries.get(i))
+    }
+    pub fn provider_label(&self) -> String {
+        match self.selected_entry() {
+            Some(e) => format!("{} [{}]", e.name, e.ptype),
+            None => "(active chi_llm config)".to_string(),
+        }
+    }
+    pub fn cycle_provider(&mut self, forward: bool) {
+        let n = self.entries.len() + 1;
+        self.provider_idx = if forward { (self.provider_idx + 1) % n } else { (self.provider_idx + n - 1) % n };
+    }
+    pub fn port(&self) -> Result<u16> {
+        match self.port.trim().parse::<u16>() {
+            Ok(p) if p > 0 => Ok(p),
+            _ => Err(anyhow!("port must be 1–65535")),
+        }
+    }
+}
+
+fn tail(n: usize) -> Vec<String> {
+    let text = log_file().ok().and_then(|p| fs::read_to_string(p).ok()).unwrap_or_default();
+    let lines: Vec<&str> = text.lines().collect();
+    lines[lines.len().saturating_sub(n)..].iter().map(|l| l.to_string()).collect()
+}
+
+pub fn draw_server(f: &mut Frame, area: Rect, app: &App) {
+    let chunks = Layout::default()
+        .direction(Direction::Vertical)
+        .constraints([Constraint::Length(7), Constraint::Min(3)])
+        .split(area);
+    let Some(form) = &app.server_form else {
+        f.render_widget(Paragraph::new("Loading...").block(Block::default().borders(Borders::ALL)), area);
+        return;
+    };
+    let srv = &app.api_server;
+    let row = |idx: usize, label: &str, value: String| {
+        let sel = form.field == idx;
+        let style = if sel { Style::default().fg(app.theme.selected).add_modifier(Modifier::BOLD) } else { Style::default().fg(app.theme.fg) };
+        Line::from(vec![Span::styled(format!("{} {:<9}", if sel { '›' } else { ' ' }, label), style), Span::raw(value)])
+    };
+    let token = if form.token.is_empty() { "(none — no auth)".to_string() } else if form.show_token { form.token.clone() } else { "•".repeat(form.token.chars().count().min(16)) };
+    let (state_color, dot) = if srv.running() && srv.ready { (app.theme.ok, '●') } else if srv.running() { (app.theme.warn, '◐') } else { (app.theme.frame, '○') };
+    let mut lines = vec![
+        row(FIELD_PORT, "Port", form.port.clone()),
+        row(FIELD_TOKEN, "Token", token),
+        row(FIELD_PROVIDER, "Provider", format!("◂ {} ▸", form.provider_label())),
+        Line::from(""),
+        Line::from(Span::styled(format!("{} {}", dot, srv.status_line()), Style::default().fg(state_color))),
+    ];
+    if let Some(msg) = &form.status {
+        let (txt, style) = app.theme.status_text(msg);
+        lines.push(Line::from(Span::styled(txt, style)));
+    }
+    let title = if srv.running() { "API Server — Enter stop" } else { "API Server — Enter start" };
+    f.render_widget(Paragraph::new(lines).block(Block::default().borders(Borders::ALL).border_style(Style::default().fg(app.theme.frame)).title(title)), chunks[0]);
+
+    let height = chunks[1].height.saturating_sub(2) as usize;
+    let logs: Vec<Line> = tail(LOG_TAIL.min(height.max(1))).into_iter().map(Line::from).collect();
+    let p = Paragraph::new(logs)
+        .style(Style::default().bg(app.theme.bg).fg(app.theme.fg))
+        .block(Block::default().borders(Borders::ALL).border_style(Style::default().fg(app.theme.frame)).title("Log (~/.cache/chi_llm/api_server.log)"))
+        .wrap(Wrap { trim: false });
+    f.render_widget(p, chunks[1]);
+}
@@ -0,0 +1,97 @@
+use ratatui::layout::Rect;
+use ratatui::prelude::Frame;
+use ratatui::style::{Modifier, Style};
+use ratatui::text::{Line, Span};
+use ratatui::widgets::{Block, Borders, Clear, Paragraph, Wrap};
+
+use crate::app::App;
+use crate::build::BuildTarget;
+use crate::hf::TokenSource;
+use crate::prefs;
+use crate::theme::StatusKind;
+use crate::util::overlay_rect;
+
+fn on_off(v: bool) -> &'static str {
+    if v { "on" } else { "off" }
+}
+
+pub fn draw_settings(f: &mut Frame, area: Rect, app: &App) {
+    let mut lines: Vec<Line> = Vec::new();
+    lines.push(Line::from(Span::styled(
+        "Settings",
+        Style::default().fg(app.theme.primary).add_modifier(Modifier::BOLD),
+    )));
+    let heading = |text: String| Line::from(Span::styled(text, Style::default().fg(app.theme.secondary)));
+    let where_ = prefs::path().map_or_else(|_| "tui.json".to_string(), |p| p.display().to_string());
+    lines.push(heading(format!("All projects ({})", where_)));
+    lines.push(Line::from(format!("t  Theme: {} ({} base)", app.theme.name, app.theme.mode.key())));
+    lines.push(Line::from(format!("a  Animation: {}", on_off(app.anim))));
+    lines.push(Line::from(format!("c  Color-blind palette: {}", on_off(app.theme.colorblind))));
+    let target = match app.prefs.build_target {
+        BuildTarget::Project => "project (.chi_llm.*)",
+        BuildTarget::Global => "global (model_config.json)",
+    };
+    lines.push(Line::from(format!("w  Build writes to: {}", target)));
+    lines.push(Line::from(format!("o  Discovery timeout: {}", prefs::short_duration(app.prefs.discovery_timeout))));
+    let ttl = match app.prefs.cache_ttl {
+        Some(d) => prefs::short_duration(d),
+        None => "default (catalog 10m, listings 5m, Ollama 2m)".to_string(),
+    };
+    lines.push(Line::from(format!("l  Cache lifetime: {}", ttl)));
+    lines.push(Line::from("m  Default model per provider type (pre-fills new providers)"));
+    let token = match &app.hf_token {
+        Some(TokenSource::Env(var)) => format!("from {}", var),
+        Some(TokenSource::Stored) => "stored".to_string(),
+        None => "not set (needed for gated models)".to_string(),
+    };
+    lines.push(Line::from(format!("h  Hugging Face token: {}", token)));
+    for _ in 0..app.density.spacer() {
+        lines.push(Line::from(""));
+    }
+    lines.push(heading("This project (chi.tmp.*)".to_string()));
+    lines.push(Line::from(format!("f  Config file format: {} (chi.tmp.{} / .chi_llm.{}; also the default for new projects)", app.config_format.key(), app.config_format.ext(), app.config_format.ext())));
+    lines.push(Line::from(format!("d  Density: {}", app.density.key())));
+    lines.push(Line::from(format!("p  Prefer private providers: {}", on_off(app.prefer_private))));
+    lines.push(Line::from(format!("e  Sync .env/.envrc on save: {}", on_off(app.env_sync))));
+    for _ in 0..app.density.spacer() {
+        lines.push(Line::from(""));
+    }
+    // Live preview of status indicators with the active palette
+    let mut preview: Vec<Span> = vec![Span::raw("Preview: ")];
+    for (kind, label) in [(StatusKind::Ok, "ok"), (StatusKind::Warn, "warning"), (StatusKind::Err, "error")] {
+        preview.push(Span::styled(format!("{} {}  ", kind.symbol(), label), app.theme.status_style(kind)));
+    }
+    lines.push(Line::from(preview));
+    let p = Paragraph::new(lines)
+        .style(Style::default().bg(app.theme.bg).fg(app.theme.fg))
+        .block(
+            Block::default()
+                .borders(Borders::ALL)
+                .border_style(Style::default().fg(app.theme.frame))
+                .title("Settings")
+                .padding(app.density.padding()),
+        )
+        .alignment(ratatui::layout::Alignment::Left)
+        .wrap(Wrap { trim: true });
+    f.render_widget(p, area);
+    draw_hf_token_input(f, area, app);
+}
+
+/// Token input over the page; the value is masked.
+fn draw_hf_token_input(f: &mut Frame, area: Rect, app: &App) {
+    let Some(buf) = &app.hf_token_input else { return };
+    let pop = overlay_rect(app.compact, 60, 25, area);
+    let lines = vec![
+        Line::from("Paste a token from huggingface.co/settings/tokens (read access is enough)."),
+        Line::from(""),
+        Line::from(Span::styled(format!("{}▏", "•".repeat(buf.chars().count())), Style::default().fg(app.theme.accent))),
+        Line::from(""),
+        Line::from(Span::styled("Enter save • empty + Enter removes the stored token • Esc cancel", Style::default().fg(app.theme.secondary))),
+    ];
+    let p = Paragraph::new(lines)
+        .style(Style::default().bg(app.theme.bg).fg(app.theme.fg))
+        .block(Block::default().borders(Borders::ALL).border_style(Style::default().fg(app.theme.accent)).title("Hugging Face token"))
+        .wrap(Wrap { trim: false });
+    f.render_widget(Clear, pop);
+    f.render_widget(p, pop);
+}
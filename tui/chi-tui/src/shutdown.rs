@@ -0,0 +1,235 @@
+use std::path::PathBuf;
+
+use crossterm::event::{KeyCode, KeyEvent, KeyModifiers};
+use ratatui::layout::Rect;
+use ratatui::prelude::Frame;
+use ratatui::style::{Modifier, Style};
+use ratatui::text::{Line, Span};
+use ratatui::widgets::{Block, Borders, Clear, Paragraph, Wrap};
+
+use crate::app::App;
+use crate::confirm::{ConfirmAction, ConfirmDialog};
+use crate::downloads;
+use crate::log;
+use crate::theme::StatusKind;
+use crate::util::overlay_rect;
+
+/// What to do with running downloads before exiting.
+#[derive(Copy, Clone, Debug, PartialEq, Eq)]
+pub enum Finish {
+    /// Let them complete, then quit
+    Wait,
+    /// Stop them (partial files removed), then quit
+    Cancel,
+    /// Stop them here and continue each in a background `chi-tui fetch`
+    /// from where it stopped
+    Detach,
+}
+
+/// Quit dialog shown when `q` is pressed while work is in flight. Tests and
+/// saves run on the UI thread and are never pending here; downloads are
+/// threads, the API server and port-forwards are child processes.
+#[derive(Clone, Debug, Default)]
+pub struct ShutdownDialog {
+    pub finish: Option<Finish>,
+    /// (url, target) to hand off once the in-process downloads have stopped
+    detach: Vec<(String, PathBuf)>,
+}
+
+fn has_pending(app: &App) -> bool {
+    app.downloads.active_count() > 0 || app.api_server.running() || app.portfw.running_count() > 0
+}
+
+fn has_unsaved(app: &App) -> bool {
+    app.providers.as_ref().map_or(false, |p| p.has_unsaved_changes())
+}
+
+/// Quit, or open a dialog when something would be cut off or lost.
+pub fn request_quit(app: &mut App) {
+    if has_pending(app) {
+        app.shutdown = Some(ShutdownDialog::default());
+    } else if has_unsaved(app) {
+        let msg = vec!["Provider changes on the Configure page are not saved.".to_string(), "Quit and discard them?".to_string()];
+        app.confirm = Some(ConfirmDialog::new("Quit chi-tui?", msg, "discard and quit", ConfirmAction::Quit));
+    } else {
+        app.should_quit = true;
+    }
+}
+
+/// Ctrl+C: the same questions as `q`; pressed again on the quit question it
+/// answers it. Another open dialog is closed first.
+pub fn interrupt(app: &mut App) {
+    if app.shutdown.is_some() {
+        handle_shutdown_key(app, KeyEvent::new(KeyCode::Char('q'), KeyModifiers::NONE));
+    } else if app.confirm.as_ref().map_or(false, |d| d.action == ConfirmAction::Quit) {
+        app.should_quit = true;
+    } else {
+        app.confirm = None;
+        request_quit(app);
+    }
+}
+
+/// Dialog keys; consumes every key while open.
+pub fn handle_shutdown_key(app: &mut App, key: KeyEvent) {
+    let downloading = app.downloads.active_count() > 0;
+    let Some(dlg) = app.shutdown.as_mut() else { return };
+    match key.code {
+        KeyCode::Char('w') | KeyCode::Char('W') if downloading && dlg.finish.is_none() => dlg.finish = Some(Finish::Wait),
+        KeyCode::Char('c') | KeyCode::Char('C') if downloading && dlg.finish != Some(Finish::Detach) => {
+            app.downloads.cancel_all();
+            dlg.finish = Some(Finish::Cancel);
+        }
+        KeyCode::Char('d') | KeyCode::Char('D') if downloading && dlg.finish.is_none() => {
+            dlg.detach = app.downloads.running().iter().filter_map(|j| app.downloads.source(&j.id)).collect();
+            app.downloads.pause_all();
+            dlg.finish = Some(Finish::Detach);
+        }
+        KeyCode::Char('q') | KeyCode::Char('Q') | KeyCode::Enter if dlg.finish.is_none() || !downloading => {
+            app.downloads.pause_all();
+            app.should_quit = true;
+        }
+        // Stop and detach are already under way; only waiting can be undone
+        KeyCode::Esc if dlg.finish.is_none() || dlg.finish == Some(Finish::Wait) => app.shutdown = None,
+        _ => {}
+    }
+}
+
+/// Run loop hook: quit once the chosen downloads have stopped. Returns true
+/// when the dialog should redraw.
+pub fn tick(app: &mut App) -> bool {
+    let Some(dlg) = app.shutdown.as_mut() else { return false };
+    if dlg.finish.is_none() { return app.downloads.active_count() > 0; }
+    if app.downloads.active_count() > 0 { return true; }
+    for (url, target) in std::mem::take(&mut dlg.detach) {
+        if let Err(e) = downloads::spawn_detached(&url, &target) {
+            log::warn(&format!("could not detach download of {}: {}", target.display(), e));
+        }
+    }
+    app.should_quit = true;
+    true
+}
+
+pub fn draw_shutdown(f: &mut Frame, area: Rect, app: &App) {
+    let Some(dlg) = &app.shutdown else { return };
+    let pop = overlay_rect(app.compact, 70, 60, area);
+    let head = Style::default().fg(app.theme.primary).add_modifier(Modifier::BOLD);
+    let dim = Style::default().fg(app.theme.secondary);
+    let mut lines: Vec<Line> = vec![Line::from(Span::styled("Still running:", head))];
+    for j in app.downloads.running() {
+        lines.push(Line::from(format!("  download {}  {}", j.id, j.label())));
+    }
+    if app.api_server.running() {
+        lines.push(Line::from(format!("  API server on :{} (stopped on exit)", app.api_server.port)));
+    }
+    let pf = app.portfw.running_count();
+    if pf > 0 { lines.push(Line::from(format!("  {} kubectl port-forward(s) (stopped on exit)", pf))); }
+    if has_unsaved(app) { lines.push(Line::from("  unsaved provider changes (lost on exit)")); }
+    lines.push(Line::from(""));
+    let downloading = app.downloads.active_count() > 0;
+    match dlg.finish {
+        None if downloading => {
+            lines.push(Line::from("w  wait for downloads, then quit"));
+            lines.push(Line::from("c  cancel downloads and quit"));
+            lines.push(Line::from("d  detach downloads (continue in the background) and quit"));
+            lines.push(Line::from("q  quit now (downloads resume on the next start)"));
+            lines.push(Line::from(Span::styled("Esc  keep working", dim)));
+        }
+        None => {
+            lines.push(Line::from("q/Enter  quit"));
+            lines.push(Line::from(Span::styled("Esc  keep working", dim)));
+        }
+        Some(Finish::Wait) => {
+            lines.push(Line::from(Span::styled("Waiting for downloads to finish…", app.theme.status_style(StatusKind::Warn))));
+            lines.push(Line::from(Span::styled("c  cancel them instead • Esc  keep working", dim)));
+        }
+        Some(Finish::Cancel) => lines.push(Line::from(Span::styled("Cancelling downloads…", app.theme.status_style(StatusKind::Warn)))),
+        Some(Finish::Detach) => lines.push(Line::from(Span::styled("Handing downloads off to background processes…", app.theme.status_style(StatusKind::Warn)))),
+    }
+    let p = Paragraph::new(lines)
+        .style(Style::default().bg(app.theme.bg).fg(app.theme.fg))
+        .block(Block::default().borders(Borders::ALL).border_style(Style::default().fg(app.theme.accent)).title("Quit chi-tui?"))
+        .wrap(Wrap { trim: false });
+    f.render_widget(Clear, pop);
+    f.render_widget(p, pop);
+}
+
+#[cfg(all(test, unix))]
+mod tests {
+    use std::time::{Duration, Instant};
+
+    use super::*;
+
+    fn press(app: &mut App, code: KeyCode) {
+        handle_shutdown_key(app, KeyEvent::new(code, KeyModifiers::NONE));
+    }
+
+    /// An app with one download in progress from a slow local server.
+    fn downloading() -> App {
+        let url = crate::testing::slow_server(1 << 20, Duration::from_millis(20));
+        let mut app = App::new(false);
+        app.downloads.start("big", vec![format!("{}/big.gguf", url)], "big.gguf", None).expect("start");
+        app
+    }
+
+    #[test]
+    fn quitting_with_nothing_running_is_immediate() {
+        let _fake = crate::testing::FakeCli::new();
+        let mut app = App::new(false);
+        request_quit(&mut app);
+        assert!(app.should_quit && app.shutdown.is_none() && app.confirm.is_none());
+    }
+
+    #[test]
+    fn unsaved_provider_changes_ask_for_confirmation() {
+        let _fake = crate::testing::FakeCli::new();
+        let mut app = App::new(false);
+        let mut st = crate::providers::ProvidersState::empty();
+        st.entries.push(crate::testing::entry("box", "ollama", serde_json::json!({"host": "box"})));
+        app.providers = Some(st);
+        request_quit(&mut app);
+        assert!(!app.should_quit && app.shutdown.is_none());
+        assert!(app.confirm.is_some());
+    }
+
+    #[test]
+    fn ctrl_c_asks_about_unsaved_changes_and_a_second_one_quits() {
+        let _fake = crate::testing::FakeCli::new();
+        let mut app = App::new(false);
+        let mut st = crate::providers::ProvidersState::empty();
+        st.entries.push(crate::testing::entry("box", "ollama", serde_json::json!({"host": "box"})));
+        app.providers = Some(st);
+        app.confirm = Some(ConfirmDialog::new("Delete?", Vec::new(), "delete", ConfirmAction::DeleteProvider("box".to_string())));
+        interrupt(&mut app);
+        assert!(!app.should_quit);
+        assert_eq!(app.confirm.as_ref().map(|d| d.action.clone()), Some(ConfirmAction::Quit));
+        interrupt(&mut app);
+        assert!(app.should_quit);
+    }
+
+    #[test]
+    fn waiting_can_be_undone_and_cancelling_quits_once_stopped() {
+        let _fake = crate::testing::FakeCli::new();
+        let mut app = downloading();
+        request_quit(&mut app);
+        assert!(app.shutdown.is_some() && !app.should_quit);
+        press(&mut app, KeyCode::Char('w'));
+        assert_eq!(app.shutdown.as_ref().and_then(|d| d.finish), Some(Finish::Wait));
+        assert!(tick(&mut app) && !app.should_quit, "still downloading");
+        press(&mut app, KeyCode::Esc);
+        assert!(app.shutdown.is_none());
+
+        request_quit(&mut app);
+        press(&mut app, KeyCode::Char('c'));
+        assert_eq!(app.shutdown.as_ref().and_then(|d| d.finish), Some(Finish::Cancel));
+        press(&mut app, KeyCode::Esc);
+        assert!(app.shutdown.is_some(), "a cancel cannot be undone");
+        let started = Instant::now();
+        while !app.should_quit {
+            assert!(started.elapsed() < Duration::from_secs(10), "never quit");
+            app.downloads.poll();
+            tick(&mut app);
+            std::thread::sleep(Duration::from_millis(20));
+        }
+        assert_eq!(app.downloads.status("big").map(|j| j.state.clone()), Some(crate::downloads::DownloadState::Cancelled));
+    }
+}
@@ -0,0 +1,301 @@
+use std::sync::mpsc::{channel, Receiver};
+use std::thread;
+use std::time::{Duration, Instant};
+
+use anyhow::Result;
+use chrono::{DateTime, Utc};
+use ratatui::layout::{Constraint, Rect};
+use ratatui::prelude::Frame;
+use ratatui::style::{Modifier, Style};
+use ratatui::text::{Line, Span};
+use ratatui::widgets::{Block, Borders, Cell, Paragraph, Row, Table};
+use serde_json::Value;
+
+use crate::app::App;
+use crate::audit;
+use crate::health::{check_tcp, endpoint_of};
+use crate::locale;
+use crate::portforward::PortForwards;
+use crate::providers::{read_scratch_entries, test_connection};
+use crate::store;
+use crate::text;
+use crate::theme::StatusKind;
+use crate::variables;
+
+/// Auto-refresh choices cycled with `i`; 0 turns it off.
+const INTERVALS: [u64; 5] = [0, 10, 30, 60, 300];
+const DEFAULT_INTERVAL: u64 = 30;
+
+/// One provider's latest check.
+#[derive(Clone, Debug)]
+pub struct StatusRow {
+    pub id: String,
+    pub name: String,
+    pub ptype: String,
+    /// None until the first check finishes
+    pub reachable: Option<bool>,
+    /// TCP round trip to the endpoint, when it has one
+    pub latency: Option<Duration>,
+    pub models: Option<usize>,
+    /// Ids of the listed models
+    pub model_ids: Vec<String>,
+    /// Test connection message or error
+    pub detail: String,
+    pub checked_at: Option<DateTime<Utc>>,
+    pub checking: bool,
+}
+
+/// Status page: every configured provider tested in parallel.
+pub struct StatusBoard {
+    pub rows: Vec<StatusRow>,
+    pub selected: usize,
+    /// Seconds between automatic refreshes; 0 is off
+    pub interval: u64,
+    rx: Option<Receiver<StatusRow>>,
+    last_run: Option<Instant>,
+}
+
+pub fn load_interval() -> u64 {
+    store::read().ok().and_then(|v| v.get("status_refresh_secs").and_then(|x| x.as_u64())).unwrap_or(DEFAULT_INTERVAL)
+}
+
+pub fn save_interval(secs: u64) -> Result<()> {
+    let mut root = store::read_or_empty();
+    let before = root.clone();
+    if let Some(obj) = root.as_object_mut() { obj.insert("status_refresh_secs".to_string(), Value::from(secs)); }
+    let path = store::write(&root)?;
+    let _ = audit::record("settings.status_refresh_secs", &path, &before, &root);
+    Ok(())
+}
+
+/// Same checks as Test connection, plus the TCP round trip Latency Map uses.
+pub fn check(entry: &crate::providers::ProviderScratchEntry) -> StatusRow {
+    let entry = variables::resolve_entry(entry);
+    let latency = endpoint_of(&entry).and_then(|(host, port)| check_tcp(&entry.id, &host, port, Duration::from_secs(2)).latency);
+    // Reachable: the model list came back, or at least the port answered
+    // (e.g. a cloud provider without an api_key yet)
+    let (reachable, models, model_ids, detail) = match test_connection(&entry) {
+        Ok(t) => (t.models.is_some() || latency.is_some() || entry.ptype == "local", t.models, t.model_ids, t.message),
+        Err(e) => (false, None, Vec::new(), e.to_string()),
+    };
+    StatusRow {
+        id: entry.id.clone(),
+        name: entry.name.clone(),
+        ptype: entry.ptype.clone(),
+        reachable: Some(reachable),
+        latency,
+        models,
+        model_ids,
+        detail,
+        checked_at: Some(Utc::now()),
+        checking: false,
+    }
+}
+
+impl StatusBoard {
+    pub fn new() -> Self {
+        StatusBoard { rows: Vec::new(), selected: 0, interval: load_interval(), rx: None, last_run: None }
+    }
+
+    /// Test every provider, one thread each. Rows keep their last result
+    /// until the new one arrives.
+    pub fn refresh(&mut self, portfw: &mut PortForwards) {
+        let entries = read_scratch_entries().unwrap_or_default();
+        let old = std::mem::take(&mut self.rows);
+        let (tx, rx) = channel();
+        for e in &entries {
+            let mut row = old.iter().find(|r| r.id == e.id).cloned().unwrap_or(StatusRow {
+                id: e.id.clone(),
+                name: e.name.clone(),
+                ptype: e.ptype.clone(),
+                reachable: None,
+                latency: None,
+                models: None,
+                model_ids: Vec::new(),
+                detail: String::new(),
+                checked_at: None,
+                checking: false,
+            });
+            if let Err(err) = portfw.ensure(e) {
+                row.reachable = Some(false);
+                row.detail = format!("port-forward: {}", err);
+                row.checked_at = Some(Utc::now());
+                self.rows.push(row);
+                continue;
+            }
+            row.checking = true;
+            self.rows.push(row);
+            let (tx, e) = (tx.clone(), e.clone());
+            thread::spawn(move || {
+                let _ = tx.send(check(&e));
+            });
+        }
+        self.selected = self.selected.min(self.rows.len().saturating_sub(1));
+        self.rx = Some(rx);
+        self.last_run = Some(Instant::now());
+    }
+
+    /// Apply finished checks and start a refresh when due. Returns true when
+    /// the table changed.
+    pub fn poll(&mut self, portfw: &mut PortForwards) -> bool {
+        let mut changed = false;
+        if let Some(rx) = &self.rx {
+            loop {
+                match rx.try_recv() {
+                    Ok(row) => {
+                        if let Some(r) = self.rows.iter_mut().find(|r| r.id == row.id) { *r = row; }
+                        changed = true;
+                    }
+                    Err(std::sync::mpsc::TryRecvError::Empty) => break,
+                    Err(std::sync::mpsc::TryRecvError::Disconnected) => {
+                        // A check thread died; do not wait on it forever
+                        for r in self.rows.iter_mut().filter(|r| r.checking) { r.checking = false; }
+                        break;
+                    }
+                }
+            }
+        }
+        if self.rows.iter().any(|r| r.checking) { return changed; }
+        self.rx = None;
+        let due = self.last_run.map_or(true, |t| self.interval > 0 && t.elapsed() >= Duration::from_secs(self.interval));
+        if due {
+            self.refresh(portfw);
+            changed = true;
+        }
+        changed
+    }
+
+    pub fn cycle_interval(&mut self) -> Result<()> {
+        let i = INTERVALS.iter().position(|s| *s == self.interval).map_or(0, |i| (i + 1) % INTERVALS.len());
+        self.interval = INTERVALS[i];
+        save_interval(self.interval)
+    }
+
+    pub fn move_up(&mut self) { if self.selected > 0 { self.selected -= 1; } }
+    pub fn move_down(&mut self) { if self.selected + 1 < self.rows.len() { self.selected += 1; } }
+}
+
+pub fn draw_status(f: &mut Frame, area: Rect, app: &App) {
+    let Some(st) = &app.status_board else {
+        f.render_widget(Paragraph::new("Checking providers...").block(Block::default().borders(Borders::ALL)), area);
+        return;
+    };
+    let header = Row::new(["Name", "Type", "Reachable", "Latency", "Models", "Last checked"].map(Cell::from))
+        .style(Style::default().fg(app.theme.primary).add_modifier(Modifier::BOLD));
+    let mut rows: Vec<Row> = Vec::new();
+    // Matches the 24% name column; the detail box below shows the full name
+    let name_w = (area.width.saturating_sub(2) as usize * 24 / 100).max(8);
+    for (i, r) in st.rows.iter().enumerate() {
+        let (mark, kind) = match r.reachable {
+            Some(true) => ("yes", StatusKind::Ok),
+            Some(false) => ("no", StatusKind::Err),
+            None => ("…", StatusKind::Warn),
+        };
+        let reach = if r.checking { format!("{} {} ↻", kind.symbol(), mark) } else { format!("{} {}", kind.symbol(), mark) };
+        let latency = r.latency.map_or("-".to_string(), |d| format!("{} ms", locale::decimal(d.as_secs_f64() * 1000.0, 1)));
+        let models = r.models.map_or("-".to_string(), |n| locale::count(n as u64));
+        let checked = r.checked_at.map_or("never".to_string(), locale::ago);
+        let style = if i == st.selected { Style::default().fg(app.theme.selected).add_modifier(Modifier::BOLD) } else { Style::default().fg(app.theme.fg) };
+        rows.push(
+            Row::new(vec![
+                Cell::from(text::truncate(&r.name, name_w)),
+                Cell::from(r.ptype.clone()),
+                Cell::from(Span::styled(reach, app.theme.status_style(kind))),
+                Cell::from(latency),
+                Cell::from(models),
+                Cell::from(checked),
+            ])
+            .style(style),
+        );
+    }
+    let refresh = if st.interval == 0 { "auto-refresh off".to_string() } else { format!("auto-refresh every {}", locale::duration(Duration::from_secs(st.interval))) };
+    let title = format!("Provider Status ({})", refresh);
+    let widths = [Constraint::Percentage(24), Constraint::Percentage(16), Constraint::Length(12), Constraint::Length(12), Constraint::Length(8), Constraint::Min(14)];
+    let chunks = ratatui::layout::Layout::default()
+        .direction(ratatui::layout::Direction::Vertical)
+        .constraints([Constraint::Min(3), Constraint::Length(3)])
+        .split(area);
+    let table = Table::new(rows, widths)
+        .header(header)
+        .block(Block::default().borders(Borders::ALL).border_style(Style::default().fg(app.theme.frame)).title(title));
+    f.render_widget(table, chunks[0]);
+    let detail = match st.rows.get(st.selected) {
+        Some(r) if !r.detail.is_empty() => {
+            let kind = if r.reachable == Some(false) { StatusKind::Err } else { StatusKind::Ok };
+            Line::from(Span::styled(r.detail.clone(), app.theme.status_style(kind)))
+        }
+        Some(_) => Line::from("checking…"),
+        None => Line::from("No providers configured."),
+    };
+    let title = st.rows.get(st.selected).map_or("Test connection".to_string(), |r| format!("Test connection — {}", r.name));
+    let p = Paragraph::new(detail)
+        .style(Style::default().bg(app.theme.bg).fg(app.theme.fg))
+        .block(Block::default().borders(Borders::ALL).border_style(Style::default().fg(app.theme.frame)).title(title));
+    f.render_widget(p, chunks[1]);
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+
+    fn row(id: &str, checking: bool) -> StatusRow {
+        StatusRow {
+            id: id.to_string(),
+            name: id.to_string(),
+            ptype: "ollama".to_string(),
+            reachable: None,
+            latency: None,
+            models: None,
+            model_ids: Vec::new(),
+            detail: String::new(),
+            checked_at: None,
+            checking,
+        }
+    }
+
+    fn board(rows: Vec<StatusRow>, rx: Option<Receiver<StatusRow>>) -> StatusBoard {
+        StatusBoard { rows, selected: 0, interval: 0, rx, last_run: Some(Instant::now()) }
+    }
+
+    #[test]
+    fn finished_checks_replace_their_rows() {
+        let (tx, rx) = channel();
+        let mut st = board(vec![row("a", true), row("b", true)], Some(rx));
+        let mut portfw = PortForwards::default();
+        assert!(!st.poll(&mut portfw));
+        tx.send(StatusRow { reachable: Some(true), models: Some(3), ..row("b", false) }).expect("send");
+        assert!(st.poll(&mut portfw));
+        assert!(st.rows[0].checking && st.rows[1].reachable == Some(true) && st.rows[1].models == Some(3));
+        // A check that never reports does not leave its row spinning
+        drop(tx);
+        st.poll(&mut portfw);
+        assert!(st.rows.iter().all(|r| !r.checking));
+    }
+
+    #[test]
+    fn selection_stays_on_the_table() {
+        let mut st = board(vec![row("a", false), row("b", false)], None);
+        st.move_up();
+        assert_eq!(st.selected, 0);
+        st.move_down();
+        st.move_down();
+        assert_eq!(st.selected, 1);
+    }
+
+    #[cfg(unix)]
+    #[test]
+    fn the_interval_cycles_and_is_saved() {
+        let _fake = crate::testing::FakeCli::new();
+        let mut st = StatusBoard::new();
+        assert_eq!(st.interval, DEFAULT_INTERVAL);
+        st.cycle_interval().expect("save");
+        assert_eq!((st.interval, load_interval()), (60, 60));
+        st.cycle_interval().expect("save");
+        st.cycle_interval().expect("save");
+        assert_eq!((st.interval, load_interval()), (0, 0));
+        // A hand-edited value off the list starts the cycle over
+        st.interval = 45;
+        st.cycle_interval().expect("save");
+        assert_eq!(st.interval, 0);
+    }
+}
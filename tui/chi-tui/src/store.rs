@@ -0,0 +1,226 @@
+use std::fs;
+use std::path::{Path, PathBuf};
+
+use anyhow::{anyhow, Result};
+use serde_json::Value;
+
+/// Scratch store candidates, in lookup order. Only one is expected to exist:
+/// saving in the preferred format removes the others.
+const STORE_PATHS: [&str; 3] = ["chi.tmp.json", "chi.tmp.yaml", "chi.tmp.yml"];
+
+#[derive(Copy, Clone, Debug, Default, PartialEq, Eq)]
+pub enum Format {
+    #[default]
+    Json,
+    Yaml,
+}
+
+impl Format {
+    pub fn from_key(s: &str) -> Self {
+        match s.trim().to_lowercase().as_str() {
+            "yaml" | "yml" => Format::Yaml,
+            _ => Format::Json,
+        }
+    }
+
+    pub fn of_path(path: &str) -> Self {
+        if path.ends_with(".yaml") || path.ends_with(".yml") { Format::Yaml } else { Format::Json }
+    }
+
+    pub fn key(self) -> &'static str {
+        match self {
+            Format::Json => "json",
+            Format::Yaml => "yaml",
+        }
+    }
+
+    pub fn toggled(self) -> Self {
+        match self {
+            Format::Json => Format::Yaml,
+            Format::Yaml => Format::Json,
+        }
+    }
+
+    pub fn ext(self) -> &'static str {
+        self.key()
+    }
+}
+
+/// Parse JSON or YAML regardless of the file name: JSON first, since YAML
+/// would also accept most JSON but with looser typing.
+pub fn parse(text: &str) -> Result<Value> {
+    match serde_json::from_str(text) {
+        Ok(v) => Ok(v),
+        Err(json_err) => serde_yaml::from_str(text).map_err(|e| anyhow!("not JSON ({}) or YAML ({})", json_err, e)),
+    }
+}
+
+pub fn to_text(v: &Value, fmt: Format) -> Result<String> {
+    Ok(match fmt {
+        Format::Json => serde_json::to_string_pretty(v)?,
+        Format::Yaml => serde_yaml::to_string(v)?,
+    })
+}
+
+/// Store file in use; for a fresh project, the name for the preferred format.
+pub fn path() -> String {
+    STORE_PATHS
+        .iter()
+        .find(|p| Path::new(p).exists())
+        .map(|p| p.to_string())
+        .unwrap_or_else(|| format!("chi.tmp.{}", Format::default().ext()))
+}
+
+pub fn exists() -> bool {
+    STORE_PATHS.iter().any(|p| Path::new(p).exists())
+}
+
+/// The store as JSON values, whatever its on-disk format.
+pub fn read() -> Result<Value> {
+    let path = path();
+    let text = fs::read_to_string(&path).map_err(|e| anyhow!("{}: {}", path, e))?;
+    parse(&text).map_err(|e| anyhow!("{}: {}", path, e))
+}
+
+/// The store object, or `{}` when it is missing or unreadable.
+pub fn read_or_empty() -> Value {
+    read().ok().filter(|v| v.is_object()).unwrap_or_else(|| Value::Object(Default::default()))
+}
+
+/// Preferred write format: `config_format` in the store, else the current
+/// file's, else the user's default for new projects (`prefs`).
+pub fn write_format(root: &Value) -> Format {
+    root.get("config_format").and_then(|v| v.as_str()).map(Format::from_key).unwrap_or_else(|| {
+        if exists() { Format::of_path(&path()) } else { crate::prefs::load().config_format }
+    })
+}
+
+/// Save the store in its preferred format. Returns the path written; a
+/// store in the other format is removed so readers never see stale data.
+pub fn write(root: &Value) -> Result<String> {
+    let fmt = write_format(root);
+    let old = path();
+    let target = if Format::of_path(&old) == fmt { old.clone() } else { format!("chi.tmp.{}", fmt.ext()) };
+    fs::write(&target, to_text(root, fmt)?)?;
+    for p in STORE_PATHS.iter().filter(|p| **p != target) {
+        if Path::new(p).exists() { let _ = fs::remove_file(p); }
+    }
+    Ok(target)
+}
+
+/// Global provider list shared by every project (`chi_llm/providers.json`
+/// in the user config dir, next to the secrets file).
+pub fn global_path() -> Result<PathBuf> {
+    let base = dirs::config_dir().ok_or_else(|| anyhow!("config dir not found"))?;
+    Ok(base.join("chi_llm").join("providers.json"))
+}
+
+/// The global store, or `{}` when there is none yet.
+pub fn read_global() -> Result<Value> {
+    let path = global_path()?;
+    match fs::read_to_string(&path) {
+        Ok(text) => parse(&text).map_err(|e| anyhow!("{}: {}", path.display(), e)),
+        Err(e) if e.kind() == std::io::ErrorKind::NotFound => Ok(Value::Object(Default::default())),
+        Err(e) => Err(anyhow!("{}: {}", path.display(), e)),
+    }
+}
+
+pub fn write_global(root: &Value) -> Result<String> {
+    let path = global_path()?;
+    if let Some(dir) = path.parent() { fs::create_dir_all(dir)?; }
+    fs::write(&path, to_text(root, Format::Json)?)?;
+    Ok(path.display().to_string())
+}
+
+/// The project store over the global one: project keys win, and
+/// `providers` holds the project's providers followed by global ones whose
+/// id the project does not reuse. For readers; writes go to one layer.
+pub fn read_layered() -> Result<Value> {
+    let project = if exists() { read()? } else { Value::Object(Default::default()) };
+    let global = read_global()?;
+    let mut root = global.as_object().cloned().unwrap_or_default();
+    if let Some(p) = project.as_object() {
+        for (k, v) in p { root.insert(k.clone(), v.clone()); }
+    }
+    let list = |v: &Value| v.get("providers").and_then(|x| x.as_array()).cloned().unwrap_or_default();
+    let mut providers = list(&project);
+    let id = |p: &Value| p.get("id").cloned();
+    for g in list(&global) {
+        if !providers.iter().any(|p| id(p) == id(&g)) { providers.push(g); }
+    }
+    root.insert("providers".to_string(), Value::Array(providers));
+    Ok(Value::Object(root))
+}
+
+/// Settings toggle: persist the preference and rewrite the store in it.
+pub fn save_write_format(fmt: Format) -> Result<String> {
+    let mut root = read_or_empty();
+    let before = root.clone();
+    if let Some(obj) = root.as_object_mut() { obj.insert("config_format".to_string(), Value::String(fmt.key().to_string())); }
+    let written = write(&root)?;
+    let _ = crate::audit::record("settings.config_format", &written, &before, &root);
+    Ok(written)
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+    use serde_json::json;
+
+    #[test]
+    fn formats_follow_keys_and_file_names() {
+        assert_eq!(Format::from_key(" YML "), Format::Yaml);
+        assert_eq!(Format::from_key("toml"), Format::Json);
+        assert_eq!(Format::of_path("chi.tmp.yaml"), Format::Yaml);
+        assert_eq!(Format::of_path("chi.tmp.json"), Format::Json);
+        assert_eq!(Format::Json.toggled().ext(), "yaml");
+    }
+
+    #[test]
+    fn json_and_yaml_round_trip_to_the_same_values() {
+        let v = json!({"providers": [{"id": "box", "config": {"port": 11434, "host": "10.0.0.5"}}], "default_provider_id": "box", "ui_density": null});
+        for fmt in [Format::Json, Format::Yaml] {
+            assert_eq!(parse(&to_text(&v, fmt).expect("text")).expect("parse"), v, "{:?}", fmt);
+        }
+        assert_eq!(parse("port: 8080\nhost: box\n").expect("yaml"), json!({"port": 8080, "host": "box"}));
+        let err = parse("{ not: [valid").expect_err("neither").to_string();
+        assert!(err.starts_with("not JSON (") && err.contains(") or YAML ("), "{}", err);
+    }
+
+    #[cfg(unix)]
+    #[test]
+    fn writing_in_the_other_format_replaces_the_file() {
+        let _fake = crate::testing::FakeCli::new();
+        assert!(!exists() && read_or_empty() == json!({}));
+        assert_eq!(write(&json!({"a": 1})).expect("json"), "chi.tmp.json");
+        assert_eq!(write_format(&read().expect("read")), Format::Json);
+        let written = save_write_format(Format::Yaml).expect("yaml");
+        assert_eq!(written, "chi.tmp.yaml");
+        assert!(!Path::new("chi.tmp.json").exists());
+        assert_eq!(path(), "chi.tmp.yaml");
+        assert_eq!(read().expect("read"), json!({"a": 1, "config_format": "yaml"}));
+        assert!(fs::read_to_string("chi.tmp.yaml").expect("text").contains("config_format: yaml"));
+        // A store in YAML keeps its format when written without a preference
+        fs::write("chi.tmp.yaml", "a: 2\n").expect("reset");
+        assert_eq!(write(&json!({"a": 3})).expect("write"), "chi.tmp.yaml");
+    }
+
+    #[cfg(unix)]
+    #[test]
+    fn the_project_layer_wins_over_the_global_one() {
+        let _fake = crate::testing::FakeCli::new();
+        write_global(&json!({
+            "providers": [{"id": "shared", "type": "openai"}, {"id": "box", "type": "ollama", "config": {"host": "global"}}],
+            "default_provider_id": "shared",
+            "http": {"retries": 1},
+        }))
+        .expect("global");
+        write(&json!({"providers": [{"id": "box", "type": "ollama", "config": {"host": "project"}}], "default_provider_id": "box"})).expect("project");
+        let v = read_layered().expect("layered");
+        assert_eq!(v["default_provider_id"], "box");
+        assert_eq!(v["http"]["retries"], 1);
+        let ids: Vec<&str> = v["providers"].as_array().expect("list").iter().filter_map(|p| p["id"].as_str()).collect();
+        assert_eq!(ids, ["box", "shared"]);
+        assert_eq!(v["providers"][0]["config"]["host"], "project");
+    }
+}
@@ -0,0 +1,135 @@
+use std::io::{self, Stdout, Write};
+
+use anyhow::Result;
+use crossterm::event::{DisableMouseCapture, EnableMouseCapture};
+use crossterm::cursor::{MoveTo, Show};
+use crossterm::execute;
+use crossterm::terminal::{disable_raw_mode, enable_raw_mode, Clear, ClearType, EnterAlternateScreen, LeaveAlternateScreen, SetTitle};
+use ratatui::backend::CrosstermBackend;
+use ratatui::Terminal;
+
+/// How the terminal is driven. IDE and web terminals often mishandle the
+/// alternate screen or mouse reporting, so both can be turned off.
+#[derive(Copy, Clone, Debug)]
+pub struct TermOptions {
+    pub alt_screen: bool,
+    pub mouse: bool,
+    /// Window title and taskbar progress (OSC 9;4)
+    pub title: bool,
+}
+
+/// XTWINOPS: push the window title on setup and pop it on exit, so the
+/// shell's title comes back.
+const PUSH_TITLE: &str = "\x1b[22;0t";
+const POP_TITLE: &str = "\x1b[23;0t";
+
+pub fn setup(opts: TermOptions) -> Result<Terminal<CrosstermBackend<Stdout>>> {
+    enable_raw_mode()?;
+    let mut stdout = io::stdout();
+    if opts.alt_screen { execute!(stdout, EnterAlternateScreen)?; }
+    if opts.mouse { execute!(stdout, EnableMouseCapture)?; }
+    if opts.title { write!(stdout, "{}", PUSH_TITLE)?; }
+    let mut terminal = Terminal::new(CrosstermBackend::new(stdout))?;
+    // Inline mode draws over the shell's screen; blank it so cells ratatui
+    // considers empty do not show leftover text.
+    if !opts.alt_screen { terminal.clear()?; }
+    Ok(terminal)
+}
+
+/// Undo `setup`. Safe to call more than once (panic hook + normal exit).
+pub fn restore(opts: TermOptions) -> io::Result<()> {
+    let mut stdout = io::stdout();
+    if opts.mouse { execute!(stdout, DisableMouseCapture)?; }
+    if opts.title { write!(stdout, "{}{}", Progress::Off.osc(), POP_TITLE)?; }
+    if opts.alt_screen {
+        execute!(stdout, LeaveAlternateScreen)?;
+    } else {
+        // Hand the shell a clean screen instead of a half-overwritten frame
+        execute!(stdout, Clear(ClearType::All), MoveTo(0, 0))?;
+    }
+    disable_raw_mode()?;
+    execute!(stdout, Show)
+}
+
+/// Restore the terminal before the default panic message is printed, so a
+/// crash does not leave the shell in raw mode.
+pub fn install_panic_hook(opts: TermOptions) {
+    let default_hook = std::panic::take_hook();
+    std::panic::set_hook(Box::new(move |info| {
+        let _ = restore(opts);
+        default_hook(info);
+    }));
+}
+
+/// Taskbar progress, sent as OSC 9;4. Windows Terminal and ConEmu show it
+/// on the taskbar button; terminals without support ignore the sequence.
+#[derive(Copy, Clone, Debug, PartialEq, Eq)]
+pub enum Progress {
+    Off,
+    Percent(u8),
+    /// Running, size unknown
+    Indeterminate,
+}
+
+impl Progress {
+    pub fn osc(self) -> String {
+        match self {
+            Progress::Off => "\x1b]9;4;0;0\x07".to_string(),
+            Progress::Percent(p) => format!("\x1b]9;4;1;{}\x07", p.min(100)),
+            Progress::Indeterminate => "\x1b]9;4;3;0\x07".to_string(),
+        }
+    }
+}
+
+/// Title and progress last sent; each is only written when it changes.
+#[derive(Debug, Default)]
+pub struct WindowStatus {
+    title: Option<String>,
+    progress: Option<Progress>,
+}
+
+impl WindowStatus {
+    pub fn update(&mut self, out: &mut impl Write, title: &str, progress: Progress) -> io::Result<()> {
+        if self.title.as_deref() != Some(title) {
+            execute!(out, SetTitle(title))?;
+            self.title = Some(title.to_string());
+        }
+        if self.progress != Some(progress) {
+            write!(out, "{}", progress.osc())?;
+            out.flush()?;
+            self.progress = Some(progress);
+        }
+        Ok(())
+    }
+}
+
+#[cfg(all(test, unix))]
+mod tests {
+    use super::*;
+    use crate::app::{App, Page};
+    use crate::config_cli::run_config;
+    use crate::testing::{add_provider, FakeCli};
+
+    #[test]
+    fn window_title_and_taskbar_progress_follow_the_app() {
+        let _fake = FakeCli::new();
+        run_config(add_provider("ollama", "home", &[], true)).expect("add provider");
+        let mut app = App::new(false);
+        assert_eq!(crate::window_status(&app), ("chi-tui — Welcome".to_string(), Progress::Off));
+        crate::open_page_loaded(&mut app, Page::Configure);
+        let name = app.providers.as_ref().expect("providers").entries[0].name.clone();
+        assert_eq!(crate::window_status(&app).0, format!("chi-tui — Configure Providers • {}", name));
+
+        // Title and OSC 9;4 are only written when they change
+        let mut out = Vec::new();
+        let mut w = WindowStatus::default();
+        w.update(&mut out, "chi-tui — Model Browser", Progress::Percent(42)).expect("write");
+        let sent = String::from_utf8(out.clone()).expect("utf-8");
+        assert!(sent.contains("chi-tui — Model Browser"));
+        assert!(sent.ends_with("\x1b]9;4;1;42\x07"), "{:?}", sent);
+        w.update(&mut out, "chi-tui — Model Browser", Progress::Percent(42)).expect("write");
+        assert_eq!(out.len(), sent.len());
+        w.update(&mut out, "chi-tui — Model Browser", Progress::Off).expect("write");
+        assert!(String::from_utf8(out).expect("utf-8").ends_with("\x1b]9;4;0;0\x07"));
+    }
+}
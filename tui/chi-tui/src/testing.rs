@@ -0,0 +1,407 @@
+//! Test harness: a fake `chi-llm` on PATH serving canned JSON, inside a
+//! throwaway project directory. chi-tui reads the store from the working
+//! directory and runs `chi-llm` from PATH, both process-wide, so a
+//! `FakeCli` holds a global lock for its lifetime and tests using it run
+//! one at a time.
+
+use std::fs;
+use std::io::{BufRead, BufReader, Read, Write};
+use std::net::TcpListener;
+use std::os::unix::fs::PermissionsExt;
+use std::path::{Path, PathBuf};
+use std::sync::atomic::{AtomicUsize, Ordering};
+use std::sync::{Arc, Mutex, MutexGuard};
+use std::time::Duration;
+
+use anyhow::Result;
+use ratatui::backend::TestBackend;
+use ratatui::Terminal;
+use serde_json::Value;
+
+use crate::app::App;
+use crate::config_cli::ConfigCmd;
+use crate::http::{Request, Response, Transport};
+use crate::providers::ProviderScratchEntry;
+
+static LOCK: Mutex<()> = Mutex::new(());
+static SEQ: AtomicUsize = AtomicUsize::new(0);
+
+/// Compare a rendered frame with `golden/<name>.txt`. A missing file is
+/// recorded and reported so a clean checkout passes; under `CI` it fails, so
+/// a golden that was never committed cannot pass there. `CHI_TUI_UPDATE_GOLDEN=1`
+/// re-records them after an intended layout change. On a mismatch the
+/// differing rows are printed.
+pub fn assert_golden(name: &str, actual: &str) {
+    let path = Path::new(env!("CARGO_MANIFEST_DIR")).join("golden").join(format!("{}.txt", name));
+    let update = std::env::var("CHI_TUI_UPDATE_GOLDEN").map_or(false, |v| v == "1");
+    if !update && !path.exists() && std::env::var_os("CI").is_some() {
+        panic!("{} is missing (record it with CHI_TUI_UPDATE_GOLDEN=1 and commit it)", path.display());
+    }
+    if update || !path.exists() {
+        fs::create_dir_all(path.parent().expect("golden dir")).expect("create golden dir");
+        fs::write(&path, actual).expect("write golden file");
+        if !update { eprintln!("recorded {}: review and commit it", path.display()); }
+        return;
+    }
+    let expected = fs::read_to_string(&path).unwrap_or_else(|e| panic!("{}: {}", path.display(), e));
+    if expected == actual { return; }
+    let (want, got): (Vec<&str>, Vec<&str>) = (expected.lines().collect(), actual.lines().collect());
+    let rows: Vec<String> = (0..want.len().max(got.len()))
+        .filter(|&i| want.get(i) != got.get(i))
+        .map(|i| format!("row {:>2}\n  want |{}|\n  got  |{}|", i, want.get(i).unwrap_or(&""), got.get(i).unwrap_or(&"")))
+        .collect();
+    panic!("{} differs from {} (CHI_TUI_UPDATE_GOLDEN=1 to accept):\n{}", name, path.display(), rows.join("\n"));
+}
+
+/// Entries of a stored (uncompressed) zip as text, in order; panics on a
+/// bad header or checksum.
+pub fn read_stored_zip(path: &Path) -> Vec<(String, String)> {
+    let data = fs::read(path).expect("read zip");
+    let u16at = |i: usize| u16::from_le_bytes([data[i], data[i + 1]]) as usize;
+    let u32at = |i: usize| u32::from_le_bytes([data[i], data[i + 1], data[i + 2], data[i + 3]]);
+    let mut entries = Vec::new();
+    let mut i = 0;
+    while u32at(i) == 0x0403_4b50 {
+        assert_eq!(u16at(i + 8), 0, "stored entries only");
+        let (crc, size) = (u32at(i + 14), u32at(i + 18) as usize);
+        let (name_len, extra_len) = (u16at(i + 26), u16at(i + 28));
+        let name = String::from_utf8(data[i + 30..i + 30 + name_len].to_vec()).expect("utf-8 name");
+        let start = i + 30 + name_len + extra_len;
+        let body = &data[start..start + size];
+        assert_eq!(crate::bundle::crc32(body), crc, "{}", name);
+        entries.push((name, String::from_utf8_lossy(body).to_string()));
+        i = start + size;
+    }
+    assert_eq!(u32at(i), 0x0201_4b50, "central directory after the entries");
+    entries
+}
+
+/// `providers schema --json`: the types the tests configure.
+pub fn default_schema() -> Value {
+    serde_json::json!({"providers": [
+        {"type": "local", "privacy": "local", "fields": [
+            {"name": "model", "type": "string"},
+        ]},
+        {"type": "ollama", "privacy": "local", "fields": [
+            {"name": "host", "type": "string", "default": "127.0.0.1"},
+            {"name": "port", "type": "int", "default": 11434, "min": 1, "max": 65535},
+            {"name": "model", "type": "string"},
+        ]},
+        {"type": "openai", "privacy": "cloud-paid", "fields": [
+            {"name": "api_key", "type": "secret", "required": true},
+            {"name": "model", "type": "string", "required": true},
+            {"name": "base_url", "type": "string", "advanced": true},
+        ]},
+    ]})
+}
+
+/// `models list --json`: two models, one downloaded, distinct tags.
+pub fn default_models() -> Value {
+    serde_json::json!([
+        {"id": "qwen3-1.7b", "name": "Qwen3 1.7B", "size": "1.7B", "file_size_mb": 1100,
+         "context_window": 32768, "tags": ["small", "chat"], "downloaded": true, "current": true,
+         "repo": "Qwen/Qwen3-1.7B-GGUF", "filename": "qwen3-1.7b-q4_k_m.gguf"},
+        {"id": "phi3-mini", "name": "Phi-3 Mini", "size": "3.8B", "file_size_mb": 2300,
+         "context_window": 4096, "tags": ["coding"], "downloaded": false, "current": false,
+         "repo": "microsoft/Phi-3-mini-4k-instruct-gguf", "filename": "Phi-3-mini-4k-instruct-q4.gguf"},
+    ])
+}
+
+/// What the fake answers for one argument prefix.
+enum Reply {
+    Json(Value),
+    Fail(String),
+}
+
+pub struct FakeCli {
+    pub root: PathBuf,
+    bin: PathBuf,
+    replies: Vec<(String, Reply)>,
+    /// Variables changed by `new`, with their previous values
+    saved_env: Vec<(&'static str, Option<String>)>,
+    old_cwd: PathBuf,
+    _guard: MutexGuard<'static, ()>,
+}
+
+fn sh_quote(s: &str) -> String {
+    format!("'{}'", s.replace('\'', "'\\''"))
+}
+
+impl FakeCli {
+    /// Fresh project dir (also HOME) with a fake `chi-llm` first on PATH,
+    /// answering `providers schema`, `models list` and `diagnostics` with
+    /// the defaults above.
+    pub fn new() -> Self {
+        let guard = LOCK.lock().unwrap_or_else(|e| e.into_inner());
+        // Session providers are process-wide too
+        crate::providers::ProvidersState::empty().publish_session();
+        let root = std::env::temp_dir().join(format!("chi-tui-test-{}-{}", std::process::id(), SEQ.fetch_add(1, Ordering::Relaxed)));
+        let _ = fs::remove_dir_all(&root);
+        let bin = root.join("bin");
+        fs::create_dir_all(&bin).expect("create fake bin dir");
+        let mut fake = FakeCli {
+            root: root.clone(),
+            bin,
+            replies: Vec::new(),
+            saved_env: Vec::new(),
+            old_cwd: std::env::current_dir().expect("cwd"),
+            _guard: guard,
+        };
+        fake.respond("providers schema", default_schema());
+        fake.respond("models list", default_models());
+        fake.respond("diagnostics", serde_json::json!({"python": {"version": "3.11.9"}}));
+        fake.respond("models current", serde_json::json!({"config_source": "default", "current_model": "qwen3-1.7b", "available_ram_gb": 7.8}));
+        let path = format!("{}:{}", fake.bin.display(), std::env::var("PATH").unwrap_or_default());
+        fake.set_env("PATH", path);
+        fake.set_env("HOME", root.display().to_string());
+        fake.set_env("XDG_CONFIG_HOME", root.join(".config").display().to_string());
+        fake.set_env("XDG_CACHE_HOME", root.join(".cache").display().to_string());
+        // Keychain tools must not be touched by tests
+        fake.set_env("CHI_TUI_SECRETS", "file".to_string());
+        fake.set_env("CHI_TUI_POST_SAVE_HOOK", String::new());
+        // Each call shows up in `calls()`; the bridge and cache tests turn them back on
+        fake.set_env("CHI_TUI_BRIDGE", "off".to_string());
+        fake.set_env("CHI_TUI_CACHE", "off".to_string());
+        fake.set_env("HF_TOKEN", String::new());
+        fake.set_env("HUGGING_FACE_HUB_TOKEN", String::new());
+        std::env::set_current_dir(&root).expect("enter test dir");
+        fake
+    }
+
+    /// Set (or with an empty value, remove) `key` until the fake is dropped.
+    pub fn set_env(&mut self, key: &'static str, value: String) {
+        self.saved_env.push((key, std::env::var(key).ok()));
+        if value.is_empty() { std::env::remove_var(key) } else { std::env::set_var(key, value) }
+    }
+
+    /// Answer calls whose arguments start with `prefix` (later calls win).
+    pub fn respond(&mut self, prefix: &str, json: Value) -> &mut Self {
+        self.replies.insert(0, (prefix.to_string(), Reply::Json(json)));
+        self.write_script();
+        self
+    }
+
+    /// Exit 1 with `stderr` for calls starting with `prefix`.
+    pub fn fail(&mut self, prefix: &str, stderr: &str) -> &mut Self {
+        self.replies.insert(0, (prefix.to_string(), Reply::Fail(stderr.to_string())));
+        self.write_script();
+        self
+    }
+
+    fn write_script(&self) {
+        let mut sh = format!("#!/bin/sh\nprintf '%s\\n' \"$*\" >> {}\ncase \"$*\" in\n", sh_quote(&self.calls_path().to_string_lossy()));
+        sh.push_str("  --version) echo 'chi-llm 0.0.0 (fake)' ;;\n");
+        for (i, (prefix, reply)) in self.replies.iter().enumerate() {
+            let pattern = format!("{}*", sh_quote(prefix));
+            match reply {
+                Reply::Json(v) => {
+                    let file = self.root.join(format!("reply-{}.json", i));
+                    fs::write(&file, serde_json::to_string(v).expect("json")).expect("write reply");
+                    sh.push_str(&format!("  {}) cat {} ;;\n", pattern, sh_quote(&file.to_string_lossy())));
+                }
+                Reply::Fail(msg) => sh.push_str(&format!("  {}) echo {} >&2; exit 1 ;;\n", pattern, sh_quote(msg))),
+            }
+        }
+        sh.push_str("  *) echo \"fake chi-llm: no reply for: $*\" >&2; exit 2 ;;\nesac\n");
+        let exe = self.bin.join("chi-llm");
+        fs::write(&exe, sh).expect("write fake chi-llm");
+        fs::set_permissions(&exe, fs::Permissions::from_mode(0o755)).expect("chmod fake chi-llm");
+    }
+
+    fn calls_path(&self) -> PathBuf {
+        self.root.join("calls.log")
+    }
+
+    /// Argument lists the fake was called with, in order.
+    pub fn calls(&self) -> Vec<String> {
+        fs::read_to_string(self.calls_path()).unwrap_or_default().lines().map(|l| l.to_string()).collect()
+    }
+
+    /// Put an executable shell script `name` on PATH next to the fake
+    /// chi-llm, e.g. a stand-in for kubectl.
+    pub fn tool(&self, name: &str, script: &str) {
+        let exe = self.bin.join(name);
+        fs::write(&exe, format!("#!/bin/sh\n{}\n", script)).expect("write fake tool");
+        fs::set_permissions(&exe, fs::Permissions::from_mode(0o755)).expect("chmod fake tool");
+    }
+
+    /// PATH with nothing but the (emptied) fake dir, as if chi-llm were
+    /// not installed.
+    pub fn uninstall(&self) {
+        let _ = fs::remove_file(self.bin.join("chi-llm"));
+        std::env::set_var("PATH", &self.bin);
+    }
+
+    /// The provider store as written.
+    pub fn store(&self) -> Value {
+        crate::store::read().expect("read provider store")
+    }
+
+    /// Whether `rel` exists in the project dir.
+    pub fn exists(&self, rel: &str) -> bool {
+        self.root.join(rel).exists()
+    }
+}
+
+impl Drop for FakeCli {
+    fn drop(&mut self) {
+        let _ = std::env::set_current_dir(&self.old_cwd);
+        for (key, old) in self.saved_env.drain(..).rev() {
+            match old {
+                Some(v) => std::env::set_var(key, v),
+                None => std::env::remove_var(key),
+            }
+        }
+        let _ = fs::remove_dir_all(&self.root);
+    }
+}
+
+/// A provider entry of type `ptype` with `config`, as read from a store.
+pub fn entry(id: &str, ptype: &str, config: Value) -> ProviderScratchEntry {
+    ProviderScratchEntry {
+        id: id.to_string(),
+        name: id.to_string(),
+        ptype: ptype.to_string(),
+        tags: Vec::new(),
+        config,
+        extra: Default::default(),
+        scope: Default::default(),
+    }
+}
+
+/// `chi-tui config add` of a `ptype` provider `id` with `KEY=VALUE` fields.
+pub fn add_provider(ptype: &str, id: &str, fields: &[(&str, &str)], default: bool) -> ConfigCmd {
+    ConfigCmd::Add {
+        ptype: ptype.to_string(),
+        id: Some(id.to_string()),
+        name: None,
+        host: None,
+        port: None,
+        model: None,
+        base_url: None,
+        api_key: None,
+        tags: Vec::new(),
+        set: fields.iter().map(|(k, v)| format!("{}={}", k, v)).collect(),
+        default,
+        no_validate: false,
+        json: false,
+    }
+}
+
+/// The screen as text, one line per row.
+pub fn screen(app: &App, w: u16, h: u16) -> String {
+    let mut term = Terminal::new(TestBackend::new(w, h)).expect("test terminal");
+    term.draw(|f| crate::ui::draw(f, app)).expect("draw");
+    let buf = term.backend().buffer();
+    (0..h).map(|y| (0..w).map(|x| buf.get(x, y).symbol()).collect::<String>()).collect::<Vec<_>>().join("\n")
+}
+
+/// HTTP transport answering with canned responses, in order (the last one
+/// repeats), and keeping the requests it was sent.
+pub struct Canned {
+    replies: Mutex<Vec<(u16, String)>>,
+    sent: Mutex<Vec<Request>>,
+}
+
+impl Canned {
+    pub fn new(replies: &[(u16, &str)]) -> Arc<Self> {
+        let replies = replies.iter().map(|(s, b)| (*s, b.to_string())).collect();
+        Arc::new(Canned { replies: Mutex::new(replies), sent: Mutex::new(Vec::new()) })
+    }
+
+    pub fn sent(&self) -> Vec<Request> {
+        self.sent.lock().expect("sent").clone()
+    }
+}
+
+impl Transport for Canned {
+    fn round_trip(&self, req: &Request) -> Result<Response> {
+        self.sent.lock().expect("sent").push(req.clone());
+        let mut replies = self.replies.lock().expect("replies");
+        let (status, body) = if replies.len() > 1 { replies.remove(0) } else { replies[0].clone() };
+        Ok(Response { status, headers: vec![("content-type".to_string(), "application/json".to_string())], body })
+    }
+}
+
+/// Local HTTP server answering every request with `len` zero bytes, sent
+/// in 1 KiB pieces `pause` apart, so a download can be watched (and
+/// stopped) while it runs. Returns the server's base URL.
+pub fn slow_server(len: usize, pause: Duration) -> String {
+    let listener = TcpListener::bind("127.0.0.1:0").expect("bind slow server");
+    let url = format!("http://{}", listener.local_addr().expect("slow server addr"));
+    std::thread::spawn(move || {
+        for stream in listener.incoming() {
+            let Ok(mut stream) = stream else { continue };
+            std::thread::spawn(move || {
+                let mut buf = [0u8; 4096];
+                if !matches!(stream.read(&mut buf), Ok(n) if n > 0) { return; }
+                if write!(stream, "HTTP/1.1 200 OK\r\nContent-Length: {}\r\nConnection: close\r\n\r\n", len).is_err() { return; }
+                let mut sent = 0;
+                while sent < len {
+                    let n = (len - sent).min(1024);
+                    if stream.write_all(&vec![0u8; n]).is_err() { return; }
+                    sent += n;
+                    std::thread::sleep(pause);
+                }
+            });
+        }
+    });
+    url
+}
+
+/// Local HTTP server answering every request with `status` and `body`
+/// (`content_type`), after reading the request in full. Returns its base URL.
+pub fn http_server(status: &'static str, content_type: &'static str, body: &str) -> String {
+    let listener = TcpListener::bind("127.0.0.1:0").expect("bind test server");
+    let url = format!("http://{}", listener.local_addr().expect("test server addr"));
+    let body = body.to_string();
+    std::thread::spawn(move || {
+        for stream in listener.incoming() {
+            let Ok(mut stream) = stream else { continue };
+            let mut request = Vec::new();
+            let mut buf = [0u8; 4096];
+            // Headers, then as much body as Content-Length announces
+            while let Ok(n) = stream.read(&mut buf) {
+                if n == 0 { break; }
+                request.extend_from_slice(&buf[..n]);
+                let text = String::from_utf8_lossy(&request).to_string();
+                let Some(end) = text.find("\r\n\r\n") else { continue };
+                let len = text[..end]
+                    .lines()
+                    .find_map(|l| l.split_once(':').filter(|(k, _)| k.eq_ignore_ascii_case("content-length")).and_then(|(_, v)| v.trim().parse::<usize>().ok()))
+                    .unwrap_or(0);
+                if request.len() >= end + 4 + len { break; }
+            }
+            let _ = write!(stream, "HTTP/1.1 {}\r\nContent-Type: {}\r\nContent-Length: {}\r\nConnection: close\r\n\r\n{}", status, content_type, body.len(), body);
+        }
+    });
+    url
+}
+
+/// Local HTTP server serving `body` with ETag `etag`, honouring `Range`
+/// when `If-Range` matches. Returns the file's URL and the headers of the
+/// requests it got.
+pub fn serve_file(body: &'static [u8], etag: &'static str) -> (String, Arc<Mutex<Vec<String>>>) {
+    let listener = TcpListener::bind("127.0.0.1:0").expect("bind file server");
+    let url = format!("http://{}/model.gguf", listener.local_addr().expect("file server addr"));
+    let seen = Arc::new(Mutex::new(Vec::new()));
+    let log = seen.clone();
+    std::thread::spawn(move || {
+        for stream in listener.incoming().flatten() {
+            let mut reader = BufReader::new(stream.try_clone().expect("clone"));
+            let mut head = String::new();
+            while reader.read_line(&mut head).map_or(false, |n| n > 2) && !head.ends_with("\r\n\r\n") {}
+            let header = |name: &str| head.lines().find_map(|l| l.split_once(':').filter(|(k, _)| k.eq_ignore_ascii_case(name)).map(|(_, v)| v.trim().to_string()));
+            let from = header("range").and_then(|r| r.trim_start_matches("bytes=").trim_end_matches('-').parse::<usize>().ok()).filter(|_| header("if-range").as_deref() == Some(etag));
+            log.lock().expect("log").push(head.clone());
+            let (status, part) = match from {
+                Some(n) => ("206 Partial Content", &body[n..]),
+                None => ("200 OK", body),
+            };
+            let mut out = stream;
+            let _ = write!(out, "HTTP/1.1 {}\r\nContent-Length: {}\r\nETag: {}\r\nConnection: close\r\n\r\n", status, part.len(), etag);
+            let _ = out.write_all(part);
+        }
+    });
+    (url, seen)
+}
@@ -0,0 +1,70 @@
+//! Text measurement in terminal cells. CJK and most emoji take two cells and
+//! combining marks none, so `chars().count()` and `{:<w$}` misalign columns
+//! as soon as a provider or variable name contains them. Every column that
+//! is padded or cut by hand goes through here; nothing splits a char.
+
+use unicode_width::{UnicodeWidthChar, UnicodeWidthStr};
+
+pub const ELLIPSIS: &str = "…";
+
+/// Cells `s` occupies on screen.
+pub fn width(s: &str) -> usize {
+    UnicodeWidthStr::width(s)
+}
+
+/// `s` cut to at most `max` cells, ending in "…" when anything was dropped.
+pub fn truncate(s: &str, max: usize) -> String {
+    if width(s) <= max {
+        return s.to_string();
+    }
+    let room = max.saturating_sub(width(ELLIPSIS));
+    let mut out = String::new();
+    let mut used = 0;
+    for c in s.chars() {
+        let w = c.width().unwrap_or(0);
+        if used + w > room {
+            break;
+        }
+        used += w;
+        out.push(c);
+    }
+    if max > 0 {
+        out.push_str(ELLIPSIS);
+    }
+    out
+}
+
+/// `s` followed by spaces up to `w` cells; longer text is left as is.
+pub fn pad(s: &str, w: usize) -> String {
+    format!("{}{}", s, " ".repeat(w.saturating_sub(width(s))))
+}
+
+/// Exactly `w` cells: truncated with "…" or padded.
+pub fn fit(s: &str, w: usize) -> String {
+    pad(&truncate(s, w), w)
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+
+    #[test]
+    fn columns_measure_wide_glyphs_in_cells() {
+        assert_eq!(width("ollama"), 6);
+        assert_eq!(width("本地模型"), 8);
+        assert_eq!(width("🚀 fast"), 7);
+        assert_eq!(width("cafe\u{301}"), 4);
+
+        assert_eq!(truncate("ollama", 6), "ollama");
+        assert_eq!(truncate("deepseek-coder-v2:16b", 10), "deepseek-…");
+        // A wide glyph that would straddle the edge is dropped, not split
+        assert_eq!(truncate("本地模型", 6), "本地…");
+        assert_eq!(truncate("本地模型", 1), "…");
+        assert_eq!(truncate("本地模型", 0), "");
+
+        for (s, w) in [("本地", 6), ("🚀 fast", 5), ("cafe\u{301}", 4), ("", 3)] {
+            assert_eq!(width(&fit(s, w)), w, "{:?}", s);
+        }
+        assert_eq!(pad("本地", 3), "本地");
+    }
+}
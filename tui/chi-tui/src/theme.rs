@@ -1,4 +1,26 @@
-use ratatui::style::Color;
+//! Palettes: the built-in synthwave dark and light ones, plus custom themes
+//! from `chi_llm/themes/*.{json,yaml,toml}` in the user config dir. A
+//! custom theme names a base palette and overrides any of its colors:
+//!
+//! ```toml
+//! name = "Nord"
+//! base = "dark"
+//! [colors]
+//! bg = "#2e3440"
+//! primary = "lightcyan"
+//! ```
+//!
+//! Colors are names (`red`, `lightblue`, `darkgray`…), `#rrggbb` or a
+//! 256-color index.
+
+use std::path::{Path, PathBuf};
+use std::str::FromStr;
+
+use anyhow::{anyhow, Result};
+use ratatui::style::{Color, Modifier, Style};
+use serde_json::Value;
+
+use crate::store;
 
 #[derive(Copy, Clone, Debug, PartialEq, Eq)]
 pub enum ThemeMode {
@@ -6,8 +28,22 @@ pub enum ThemeMode {
     Dark,
 }
 
+impl ThemeMode {
+    pub fn key(self) -> &'static str {
+        match self {
+            ThemeMode::Dark => "dark",
+            ThemeMode::Light => "light",
+        }
+    }
+}
+
+/// Color slots a custom theme can set, in palette order.
+pub const COLOR_NAMES: [&str; 10] = ["bg", "fg", "primary", "secondary", "accent", "frame", "selected", "ok", "warn", "err"];
+
 #[derive(Clone, Debug)]
 pub struct Theme {
+    /// `dark`, `light` or a custom theme's name; what gets saved.
+    pub name: String,
     pub mode: ThemeMode,
     pub bg: Color,
     pub fg: Color,
@@ -16,11 +52,59 @@ pub struct Theme {
     pub accent: Color,
     pub frame: Color,
     pub selected: Color,
+    pub ok: Color,
+    pub warn: Color,
+    pub err: Color,
+    pub colorblind: bool,
+    /// The palette's own ok/warn/err, restored when color-blind mode is off.
+    status: [Color; 3],
+}
+
+/// Outcome class for status text (connection tests, health, fitness).
+#[derive(Copy, Clone, Debug, PartialEq, Eq)]
+pub enum StatusKind {
+    Ok,
+    Warn,
+    Err,
+}
+
+impl StatusKind {
+    /// Shape redundancy so status never relies on color alone.
+    pub fn symbol(self) -> &'static str {
+        match self {
+            StatusKind::Ok => "✓",
+            StatusKind::Warn => "!",
+            StatusKind::Err => "✗",
+        }
+    }
+}
+
+/// Best-effort classification of free-form status messages.
+pub fn classify_status(msg: &str) -> StatusKind {
+    let low = msg.to_lowercase();
+    // "Warning: … failed" is still a warning
+    if low.starts_with("warning") {
+        StatusKind::Warn
+    } else if low.starts_with("error") || low.contains("failed") || low.contains("http ") {
+        StatusKind::Err
+    } else if low.contains("missing") || low.contains("no test") || low.contains("no models") || low.starts_with("run test") {
+        StatusKind::Warn
+    } else {
+        StatusKind::Ok
+    }
 }
 
 impl Theme {
+    pub fn new(mode: ThemeMode) -> Self {
+        match mode {
+            ThemeMode::Dark => Self::synthwave_dark(),
+            ThemeMode::Light => Self::synthwave_light(),
+        }
+    }
+
     pub fn synthwave_dark() -> Self {
         Self {
+            name: "dark".to_string(),
             mode: ThemeMode::Dark,
             bg: Color::Rgb(10, 8, 20),
             fg: Color::Rgb(220, 220, 235),
@@ -29,14 +113,230 @@ impl Theme {
             accent: Color::Rgb(64, 160, 255),
             frame: Color::Rgb(120, 80, 200),
             selected: Color::Rgb(255, 120, 0),
+            ok: Color::Rgb(80, 220, 120),
+            warn: Color::Rgb(255, 170, 0),
+            err: Color::Rgb(255, 70, 70),
+            colorblind: false,
+            status: [Color::Rgb(80, 220, 120), Color::Rgb(255, 170, 0), Color::Rgb(255, 70, 70)],
+        }
+    }
+
+    /// Same hues on a pale background, darkened to keep their contrast.
+    pub fn synthwave_light() -> Self {
+        Self {
+            name: "light".to_string(),
+            mode: ThemeMode::Light,
+            bg: Color::Rgb(246, 242, 250),
+            fg: Color::Rgb(34, 28, 52),
+            primary: Color::Rgb(196, 0, 118),
+            secondary: Color::Rgb(0, 118, 138),
+            accent: Color::Rgb(30, 92, 200),
+            frame: Color::Rgb(124, 84, 196),
+            selected: Color::Rgb(200, 88, 0),
+            ok: Color::Rgb(22, 138, 62),
+            warn: Color::Rgb(176, 108, 0),
+            err: Color::Rgb(200, 30, 30),
+            colorblind: false,
+            status: [Color::Rgb(22, 138, 62), Color::Rgb(176, 108, 0), Color::Rgb(200, 30, 30)],
+        }
+    }
+
+    /// A custom theme file's contents (JSON, YAML or TOML); `fallback_name`
+    /// (the file stem) when it has no `name`.
+    pub fn parse_custom(text: &str, fallback_name: &str) -> Result<Theme> {
+        let v = match store::parse(text) {
+            Ok(v) if v.is_object() => v,
+            _ => parse_toml(text)?,
+        };
+        let mode = match v.get("base").and_then(|b| b.as_str()).unwrap_or("dark") {
+            "dark" => ThemeMode::Dark,
+            "light" => ThemeMode::Light,
+            other => return Err(anyhow!("base \"{}\": expected dark or light", other)),
+        };
+        let mut theme = Theme::new(mode);
+        theme.name = v.get("name").and_then(|n| n.as_str()).map(str::trim).filter(|n| !n.is_empty()).unwrap_or(fallback_name).to_string();
+        if matches!(theme.name.as_str(), "dark" | "light") {
+            return Err(anyhow!("name \"{}\" is taken by a built-in theme", theme.name));
+        }
+        let colors = v.get("colors").and_then(|c| c.as_object()).cloned().unwrap_or_default();
+        for (slot, value) in &colors {
+            let value = value.as_str().ok_or_else(|| anyhow!("colors.{}: expected a string", slot))?;
+            let color = parse_color(value).ok_or_else(|| anyhow!("colors.{}: unknown color \"{}\"", slot, value))?;
+            *theme.slot_mut(slot).ok_or_else(|| anyhow!("colors.{}: unknown slot (one of {})", slot, COLOR_NAMES.join(", ")))? = color;
+        }
+        theme.status = [theme.ok, theme.warn, theme.err];
+        Ok(theme)
+    }
+
+    fn slot_mut(&mut self, name: &str) -> Option<&mut Color> {
+        Some(match name {
+            "bg" => &mut self.bg,
+            "fg" => &mut self.fg,
+            "primary" => &mut self.primary,
+            "secondary" => &mut self.secondary,
+            "accent" => &mut self.accent,
+            "frame" => &mut self.frame,
+            "selected" => &mut self.selected,
+            "ok" => &mut self.ok,
+            "warn" => &mut self.warn,
+            "err" => &mut self.err,
+            _ => return None,
+        })
+    }
+
+    /// Switch status colors to an Okabe–Ito based palette that stays
+    /// distinguishable for red/green color vision deficiencies.
+    pub fn set_colorblind(&mut self, on: bool) {
+        self.colorblind = on;
+        if on {
+            self.ok = Color::Rgb(0, 114, 178);
+            self.warn = Color::Rgb(230, 159, 0);
+            self.err = Color::Rgb(213, 94, 0);
+        } else {
+            [self.ok, self.warn, self.err] = self.status;
+        }
+    }
+
+    pub fn status_color(&self, kind: StatusKind) -> Color {
+        match kind {
+            StatusKind::Ok => self.ok,
+            StatusKind::Warn => self.warn,
+            StatusKind::Err => self.err,
         }
     }
 
+    pub fn status_style(&self, kind: StatusKind) -> Style {
+        let st = Style::default().fg(self.status_color(kind));
+        if kind == StatusKind::Err { st.add_modifier(Modifier::BOLD) } else { st }
+    }
+
+    /// Prefix a message with its status symbol, e.g. "✓ lmstudio: 3 models".
+    pub fn status_text(&self, msg: &str) -> (String, Style) {
+        let kind = classify_status(msg);
+        (format!("{} {}", kind.symbol(), msg), self.status_style(kind))
+    }
+
+    /// Switch between the built-in dark and light palettes; a custom theme
+    /// switches to the built-in opposite of its base.
     pub fn toggle(&mut self) {
-        self.mode = match self.mode {
+        let colorblind = self.colorblind;
+        *self = Theme::new(match self.mode {
             ThemeMode::Dark => ThemeMode::Light,
             ThemeMode::Light => ThemeMode::Dark,
+        });
+        self.set_colorblind(colorblind);
+    }
+}
+
+/// `red`, `lightblue`, `#ff0099`, `208`; what ratatui accepts.
+fn parse_color(s: &str) -> Option<Color> {
+    Color::from_str(s.trim()).ok()
+}
+
+/// The TOML a theme file needs: top-level `key = "value"` pairs and one
+/// `[colors]` table of them, with `#` comments.
+fn parse_toml(text: &str) -> Result<Value> {
+    let mut root = serde_json::Map::new();
+    let mut table: Option<String> = None;
+    for (i, raw) in text.lines().enumerate() {
+        let line = raw.trim();
+        if line.is_empty() || line.starts_with('#') { continue; }
+        if let Some(name) = line.strip_prefix('[').and_then(|l| l.strip_suffix(']')) {
+            let name = name.trim().to_string();
+            root.entry(name.clone()).or_insert_with(|| Value::Object(Default::default()));
+            table = Some(name);
+            continue;
+        }
+        let (key, value) = line.split_once('=').ok_or_else(|| anyhow!("line {}: expected key = \"value\"", i + 1))?;
+        let value = value.trim();
+        let value = match value.strip_prefix('"').and_then(|v| v.split_once('"')) {
+            Some((v, rest)) if rest.trim().is_empty() || rest.trim().starts_with('#') => v,
+            _ => return Err(anyhow!("line {}: expected a quoted string", i + 1)),
         };
+        let target = match &table {
+            Some(t) => root.get_mut(t).and_then(|v| v.as_object_mut()).ok_or_else(|| anyhow!("line {}: bad table", i + 1))?,
+            None => &mut root,
+        };
+        target.insert(key.trim().trim_matches('"').to_string(), Value::String(value.to_string()));
+    }
+    Ok(Value::Object(root))
+}
+
+/// Where custom themes live.
+pub fn themes_dir() -> Option<PathBuf> {
+    Some(dirs::config_dir()?.join("chi_llm").join("themes"))
+}
+
+/// Custom themes in `dir`, sorted by name, and one message per file that
+/// could not be loaded.
+pub fn load_custom(dir: &Path) -> (Vec<Theme>, Vec<String>) {
+    let (mut themes, mut errors) = (Vec::new(), Vec::new());
+    let Ok(read) = std::fs::read_dir(dir) else { return (themes, errors) };
+    let mut paths: Vec<PathBuf> = read.filter_map(|e| e.ok().map(|e| e.path())).collect();
+    paths.retain(|p| p.extension().and_then(|e| e.to_str()).map_or(false, |e| matches!(e, "json" | "yaml" | "yml" | "toml")));
+    paths.sort();
+    for path in paths {
+        let stem = path.file_stem().and_then(|s| s.to_str()).unwrap_or("custom");
+        match std::fs::read_to_string(&path).map_err(anyhow::Error::from).and_then(|t| Theme::parse_custom(&t, stem)) {
+            Ok(t) if themes.iter().any(|o: &Theme| o.name == t.name) => errors.push(format!("{}: theme \"{}\" defined twice", path.display(), t.name)),
+            Ok(t) => themes.push(t),
+            Err(e) => errors.push(format!("{}: {}", path.display(), e)),
+        }
     }
+    themes.sort_by(|a, b| a.name.to_lowercase().cmp(&b.name.to_lowercase()));
+    (themes, errors)
 }
 
+/// Built-in themes first, then the custom ones.
+pub fn available() -> (Vec<Theme>, Vec<String>) {
+    let (custom, errors) = themes_dir().map(|d| load_custom(&d)).unwrap_or_default();
+    let mut all = vec![Theme::synthwave_dark(), Theme::synthwave_light()];
+    all.extend(custom);
+    (all, errors)
+}
+
+/// The theme saved in the settings (`prefs`) by name; dark when it no
+/// longer exists.
+pub fn by_name(name: &str) -> Theme {
+    match name {
+        "dark" => Theme::synthwave_dark(),
+        "light" => Theme::synthwave_light(),
+        _ => available().0.into_iter().find(|t| t.name == name).unwrap_or_else(Theme::synthwave_dark),
+    }
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+
+    #[test]
+    fn status_messages_are_classified() {
+        assert_eq!(classify_status("Error: connection refused"), StatusKind::Err);
+        assert_eq!(classify_status("lmstudio: HTTP 500"), StatusKind::Err);
+        assert_eq!(classify_status("Warning: chi-llm is not on PATH"), StatusKind::Warn);
+        assert_eq!(classify_status("warning: .env sync failed"), StatusKind::Warn);
+        assert_eq!(classify_status("api_key missing"), StatusKind::Warn);
+        assert_eq!(classify_status("lmstudio: 3 models"), StatusKind::Ok);
+    }
+
+    #[test]
+    fn status_text_carries_the_symbol() {
+        let theme = Theme::synthwave_dark();
+        let (text, style) = theme.status_text("Warning: benchmark still running");
+        assert_eq!(text, "! Warning: benchmark still running");
+        assert_eq!(style.fg, Some(theme.warn));
+    }
+
+    #[test]
+    fn colorblind_mode_survives_a_toggle_and_restores_the_palette() {
+        let mut theme = Theme::synthwave_dark();
+        let ok = theme.ok;
+        theme.set_colorblind(true);
+        assert_ne!(theme.ok, ok);
+        theme.toggle();
+        assert!(theme.colorblind && theme.mode == ThemeMode::Light);
+        theme.toggle();
+        theme.set_colorblind(false);
+        assert_eq!(theme.ok, ok);
+    }
+}
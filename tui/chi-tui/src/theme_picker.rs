@@ -0,0 +1,135 @@
+//! Settings → `t`: pick the theme from the built-in palettes and the custom
+//! ones in the themes dir. Moving the selection applies it live; Enter
+//! keeps and saves it, Esc goes back to the theme from before.
+
+use ratatui::layout::Rect;
+use ratatui::prelude::Frame;
+use ratatui::style::{Modifier, Style};
+use ratatui::text::{Line, Span};
+use ratatui::widgets::{Block, Borders, Clear, Paragraph, Wrap};
+
+use crate::app::App;
+use crate::theme::{self, StatusKind, Theme};
+use crate::util::overlay_rect;
+
+pub struct ThemePicker {
+    pub themes: Vec<Theme>,
+    pub selected: usize,
+    /// Restored on Esc.
+    pub original: Theme,
+    /// Custom theme files that failed to load.
+    pub errors: Vec<String>,
+}
+
+impl ThemePicker {
+    pub fn open(current: &Theme) -> Self {
+        let (themes, errors) = theme::available();
+        let selected = themes.iter().position(|t| t.name == current.name).unwrap_or(0);
+        ThemePicker { themes, selected, original: current.clone(), errors }
+    }
+
+    /// The selected palette, keeping the color-blind status colors if on.
+    pub fn preview(&self) -> Theme {
+        let mut t = self.themes[self.selected].clone();
+        t.set_colorblind(self.original.colorblind);
+        t
+    }
+}
+
+pub fn draw_theme_picker(f: &mut Frame, area: Rect, app: &App) {
+    let Some(tp) = &app.theme_picker else { return };
+    let pop = overlay_rect(app.compact, 50, 60, area);
+    let mut lines: Vec<Line> = Vec::new();
+    for (i, t) in tp.themes.iter().enumerate() {
+        let sel = i == tp.selected;
+        let style = if sel { Style::default().fg(app.theme.selected).add_modifier(Modifier::BOLD) } else { Style::default().fg(app.theme.fg) };
+        let kind = if i < 2 { "built-in".to_string() } else { format!("custom, {} base", t.mode.key()) };
+        lines.push(Line::from(vec![
+            Span::styled(format!("{} {}  ", if sel { '›' } else { ' ' }, t.name), style),
+            Span::styled(kind, Style::default().fg(app.theme.secondary)),
+        ]));
+    }
+    lines.push(Line::from(""));
+    // Swatches of the previewed palette
+    lines.push(Line::from(
+        [app.theme.primary, app.theme.secondary, app.theme.accent, app.theme.frame, app.theme.selected]
+            .into_iter()
+            .map(|c| Span::styled("██ ", Style::default().fg(c)))
+            .collect::<Vec<_>>(),
+    ));
+    for e in &tp.errors {
+        lines.push(Line::from(Span::styled(format!("{} {}", StatusKind::Warn.symbol(), e), app.theme.status_style(StatusKind::Warn))));
+    }
+    if let Some(dir) = theme::themes_dir() {
+        lines.push(Line::from(Span::styled(format!("Custom themes: {}", dir.display()), Style::default().fg(app.theme.secondary))));
+    }
+    let p = Paragraph::new(lines)
+        .style(Style::default().bg(app.theme.bg).fg(app.theme.fg))
+        .block(Block::default().borders(Borders::ALL).border_style(Style::default().fg(app.theme.accent)).title("Theme (all projects)"))
+        .wrap(Wrap { trim: false });
+    f.render_widget(Clear, pop);
+    f.render_widget(p, pop);
+}
+
+#[cfg(all(test, unix))]
+mod tests {
+    use ratatui::style::Color;
+
+    use super::*;
+    use crate::app::Page;
+    use crate::prefs;
+    use crate::testing::{screen, FakeCli};
+    use crate::theme::ThemeMode;
+
+    #[test]
+    fn themes_are_picked_live_and_remembered() {
+        use crossterm::event::{KeyCode, KeyEvent, KeyModifiers};
+        let _fake = FakeCli::new();
+        let press = |app: &mut App, code: KeyCode| crate::keys::handle_key(app, KeyEvent::new(code, KeyModifiers::NONE));
+        let dir = theme::themes_dir().expect("themes dir");
+        std::fs::create_dir_all(&dir).expect("create themes dir");
+        std::fs::write(dir.join("nord.toml"), "# a comment\nname = \"Nord\"\nbase = \"dark\"\n[colors]\nbg = \"#2e3440\"\nprimary = \"lightcyan\" # trailing\n").expect("write toml");
+        std::fs::write(dir.join("paper.json"), r##"{"base": "light", "colors": {"accent": "blue", "ok": "34"}}"##).expect("write json");
+        std::fs::write(dir.join("broken.yaml"), "base: neon\n").expect("write yaml");
+
+        let (themes, errors) = theme::available();
+        let names: Vec<&str> = themes.iter().map(|t| t.name.as_str()).collect();
+        assert_eq!(names, ["dark", "light", "Nord", "paper"]);
+        assert_eq!((themes[2].bg, themes[2].primary, themes[2].fg), (Color::Rgb(0x2e, 0x34, 0x40), Color::LightCyan, Theme::synthwave_dark().fg));
+        assert_eq!((themes[3].mode, themes[3].accent, themes[3].ok), (ThemeMode::Light, Color::Blue, Color::Indexed(34)));
+        assert_ne!(Theme::synthwave_light().bg, Theme::synthwave_dark().bg);
+        assert!(errors.len() == 1 && errors[0].contains("broken.yaml") && errors[0].contains("\"neon\""), "{:?}", errors);
+        let unknown = Theme::parse_custom(r#"{"colors": {"glow": "red"}}"#, "x").expect_err("unknown slot").to_string();
+        assert!(unknown.contains("colors.glow"), "{}", unknown);
+
+        // t toggles dark/light everywhere but Settings, where it opens the picker
+        let mut app = App::new(false);
+        press(&mut app, KeyCode::Char('t'));
+        assert_eq!(app.theme.name, "light");
+        press(&mut app, KeyCode::Char('t'));
+        crate::open_page_loaded(&mut app, Page::Settings);
+        app.theme.set_colorblind(true);
+        press(&mut app, KeyCode::Char('t'));
+        assert_eq!(app.theme_picker.as_ref().map(|p| p.selected), Some(0));
+        press(&mut app, KeyCode::Down);
+        press(&mut app, KeyCode::Down);
+        assert_eq!((app.theme.name.as_str(), app.theme.bg), ("Nord", Color::Rgb(0x2e, 0x34, 0x40)));
+        assert!(app.theme.colorblind);
+        press(&mut app, KeyCode::Esc);
+        assert_eq!((app.theme.name.as_str(), app.theme_picker.is_none()), ("dark", true));
+        assert_eq!(prefs::load().theme, "dark");
+
+        press(&mut app, KeyCode::Char('t'));
+        press(&mut app, KeyCode::Down);
+        press(&mut app, KeyCode::Down);
+        press(&mut app, KeyCode::Enter);
+        assert_eq!(prefs::load().theme, "Nord");
+        assert_eq!(theme::by_name("Nord").bg, Color::Rgb(0x2e, 0x34, 0x40));
+        let text = screen(&app, 100, 30);
+        assert!(text.contains("Theme: Nord (dark base)"), "{}", text);
+
+        // A theme file that went away falls back to dark
+        std::fs::remove_file(dir.join("nord.toml")).expect("remove theme");
+        assert_eq!(theme::by_name("Nord").name, "dark");
+    }
+}
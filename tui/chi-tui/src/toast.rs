@@ -0,0 +1,45 @@
+use std::time::{Duration, Instant};
+
+use ratatui::layout::Rect;
+use ratatui::prelude::Frame;
+use ratatui::style::Style;
+use ratatui::text::{Line, Span};
+use ratatui::widgets::{Block, Borders, Clear, Paragraph, Wrap};
+
+use crate::text;
+use crate::theme::{StatusKind, Theme};
+
+/// Short-lived notification drawn in the bottom-right corner.
+#[derive(Clone, Debug)]
+pub struct Toast {
+    pub kind: StatusKind,
+    pub text: String,
+    pub expires: Instant,
+}
+
+impl Toast {
+    pub fn new(kind: StatusKind, text: impl Into<String>) -> Self {
+        Self { kind, text: text.into(), expires: Instant::now() + Duration::from_secs(5) }
+    }
+    pub fn expired(&self) -> bool {
+        Instant::now() >= self.expires
+    }
+}
+
+pub fn draw_toast(f: &mut Frame, area: Rect, toast: &Toast, theme: &Theme) {
+    let width = (text::width(&toast.text) as u16 + 6).max(10).min(area.width.saturating_sub(2));
+    let height = 3u16.min(area.height);
+    let rect = Rect {
+        x: area.x + area.width.saturating_sub(width + 1),
+        y: area.y + area.height.saturating_sub(height + 1),
+        width,
+        height,
+    };
+    let style = theme.status_style(toast.kind);
+    let p = Paragraph::new(Line::from(Span::styled(format!("{} {}", toast.kind.symbol(), toast.text), style)))
+        .style(Style::default().bg(theme.bg))
+        .block(Block::default().borders(Borders::ALL).border_style(style))
+        .wrap(Wrap { trim: true });
+    f.render_widget(Clear, rect);
+    f.render_widget(p, rect);
+}
@@ -0,0 +1,178 @@
+//! The frame: header, the current page, footer, then whatever overlays,
+//! toasts and dialogs are open on top.
+
+use ratatui::layout::{Alignment, Constraint, Direction, Layout, Rect};
+use ratatui::prelude::Frame;
+use ratatui::style::{Modifier, Style};
+use ratatui::text::{Line, Span};
+use ratatui::widgets::{Block, Borders, List, ListItem, ListState, Paragraph, Wrap};
+
+use crate::app::{App, Page};
+use crate::audit::draw_audit;
+use crate::backup::draw_backups;
+use crate::build::draw_build_config;
+use crate::cache::draw_cache;
+use crate::diagnostics::draw_diagnostics;
+use crate::help::{draw_help_overlay, footer_hint};
+use crate::inspector::draw_inspector;
+use crate::latency::draw_latency;
+use crate::models::{draw_disk_warning, draw_model_browser};
+use crate::playground::draw_playground;
+use crate::profiles::draw_profiles;
+use crate::providers::{draw_providers_catalog, draw_select_default};
+use crate::readme::draw_readme;
+use crate::server::draw_server;
+use crate::settings::draw_settings;
+use crate::theme::StatusKind;
+use crate::toast::{draw_toast, Toast};
+use crate::util::neon_gradient_line;
+use crate::{benchmark, build, confirm, logs, menu, model_defaults, mouse, recommend, shutdown, status, theme_picker, variables};
+
+pub fn draw(f: &mut Frame, app: &App) {
+    // Drawing records this frame's clickable regions
+    app.hits.clear();
+    let chunks = Layout::default()
+        .direction(Direction::Vertical)
+        .constraints([
+            Constraint::Length(if app.compact { 1 } else if app.narrow { 2 } else if app.density.is_compact() { 3 } else { 6 }), // header with animation space
+            Constraint::Min(3),
+            Constraint::Length(1), // footer
+        ]).split(f.size());
+
+    draw_header(f, chunks[0], app);
+    match app.page {
+        Page::Welcome => draw_welcome(f, chunks[1], app),
+        Page::Readme => draw_readme(f, chunks[1], app),
+        Page::Configure => draw_providers_catalog(f, chunks[1], app),
+        Page::SelectDefault => draw_select_default(f, chunks[1], app),
+        Page::ModelBrowser => draw_model_browser(f, chunks[1], app),
+        Page::Diagnostics => draw_diagnostics(f, chunks[1], app),
+        Page::Build => draw_build_config(f, chunks[1], app),
+        Page::Settings => draw_settings(f, chunks[1], app),
+        Page::Audit => draw_audit(f, chunks[1], app),
+        Page::Backups => draw_backups(f, chunks[1], app),
+        Page::Latency => draw_latency(f, chunks[1], app),
+        Page::Playground => draw_playground(f, chunks[1], app),
+        Page::Cache => draw_cache(f, chunks[1], app),
+        Page::Server => draw_server(f, chunks[1], app),
+        Page::Variables => variables::draw_variables(f, chunks[1], app),
+        Page::Status => status::draw_status(f, chunks[1], app),
+        Page::Benchmark => benchmark::draw_benchmark(f, chunks[1], app),
+        Page::Recommend => recommend::draw_recommend(f, chunks[1], app),
+        Page::Logs => logs::draw_logs(f, chunks[1], app),
+    }
+    draw_footer(f, chunks[2], app);
+
+    if matches!(app.page, Page::Configure | Page::Playground) && app.inspector.visible { draw_inspector(f, chunks[1], app); }
+    if app.page == Page::ModelBrowser { draw_disk_warning(f, chunks[1], app); }
+    if app.page == Page::Welcome { draw_profiles(f, chunks[1], app); }
+    if app.page == Page::Settings { model_defaults::draw_default_models(f, chunks[1], app); }
+    if app.page == Page::Settings { theme_picker::draw_theme_picker(f, chunks[1], app); }
+    if app.show_help { draw_help_overlay(f, app); }
+    if let Some(t) = &app.toast { draw_toast(f, chunks[1], t, &app.theme); }
+    else if let Some(s) = &app.default_watch.suggestion {
+        // Keys first: long error text may be cut off at the window edge
+        let text = format!("Ctrl+Y switch default to {} ({}) • Ctrl+N keep {} — {}", s.to_name, s.reason, s.from, app.default_watch.failing_label());
+        draw_toast(f, chunks[1], &Toast::new(StatusKind::Warn, text), &app.theme);
+    }
+    shutdown::draw_shutdown(f, chunks[1], app);
+    confirm::draw_confirm(f, chunks[1], app);
+}
+
+fn draw_header(f: &mut Frame, area: Rect, app: &App) {
+    if app.compact {
+        let p = Paragraph::new(Line::from(vec![
+            Span::styled(" chi_llm TUI • ? help ", Style::default().fg(app.theme.primary).add_modifier(Modifier::BOLD)),
+            config_source_span(app),
+        ]))
+            .style(Style::default().bg(app.theme.bg));
+        f.render_widget(p, area);
+        return;
+    }
+    if app.narrow {
+        // No room for the hero: title, then the shortcut pages as a bar
+        let title = Line::from(vec![
+            Span::styled(" chi_llm TUI • ? help ", Style::default().fg(app.theme.primary).add_modifier(Modifier::BOLD)),
+            config_source_span(app),
+        ]);
+        let mut bar: Vec<Span> = vec![Span::raw(" ")];
+        let mut shown = false;
+        let mut x = area.x + 1;
+        for (key, label, page) in menu::top_bar() {
+            let current = page == app.page;
+            shown |= current;
+            let style = if current { Style::default().fg(app.theme.selected).add_modifier(Modifier::BOLD | Modifier::REVERSED) } else { Style::default().fg(app.theme.secondary) };
+            let text = format!("{} {}", key, label);
+            let width = text.chars().count() as u16;
+            app.hits.add(Rect::new(x, area.y + 1, width, 1).intersection(area), mouse::Target::Page(page));
+            x = x.saturating_add(width + 2);
+            bar.push(Span::styled(text, style));
+            bar.push(Span::raw("  "));
+        }
+        if !shown && app.page != Page::Welcome {
+            bar.push(Span::styled(format!("› {}", menu::label(app.page)), Style::default().fg(app.theme.selected).add_modifier(Modifier::BOLD)));
+        }
+        let p = Paragraph::new(vec![title, Line::from(bar)]).style(Style::default().bg(app.theme.bg).fg(app.theme.fg));
+        f.render_widget(p, area);
+        return;
+    }
+    let title = neon_gradient_line(" chi_llm — micro‑LLM • TUI vNext ", &app.theme);
+    let sub = Line::from(vec![
+        Span::styled("  retro/synthwave • arrows + enter • ? help ", Style::default().fg(app.theme.secondary)),
+        config_source_span(app),
+    ]);
+    let block = Block::default()
+        .borders(Borders::BOTTOM)
+        .border_style(Style::default().fg(app.theme.frame))
+        .title(Span::styled("CHI_TUI", Style::default().fg(app.theme.primary).add_modifier(Modifier::BOLD)))
+        .title_alignment(Alignment::Center);
+    let v = if app.density.is_compact() { vec![title] } else { vec![title, sub] };
+    let p = Paragraph::new(v)
+        .style(Style::default().bg(app.theme.bg).fg(app.theme.fg))
+        .block(block)
+        .alignment(Alignment::Center)
+        .wrap(Wrap { trim: true });
+    f.render_widget(p, area);
+}
+
+/// Where the active chi_llm settings come from; a project override stands out.
+fn config_source_span(app: &App) -> Span<'static> {
+    let source = build::active_config_source();
+    let style = match source {
+        build::ConfigSource::Project(_) => app.theme.status_style(StatusKind::Warn),
+        _ => Style::default().fg(app.theme.secondary),
+    };
+    Span::styled(format!("• settings: {} ", source.label()), style)
+}
+
+fn draw_footer(f: &mut Frame, area: Rect, app: &App) {
+    let msg = Line::from(Span::styled(footer_hint(app), Style::default().fg(app.theme.secondary)));
+    let p = Paragraph::new(msg)
+        .style(Style::default().bg(app.theme.bg).fg(app.theme.fg))
+        .block(Block::default())
+        .alignment(Alignment::Center);
+    f.render_widget(p, area);
+}
+
+fn draw_welcome(f: &mut Frame, area: Rect, app: &App) {
+    let help = app.density.menu_help() && !app.narrow;
+    let items: Vec<ListItem> = app.menu.items.iter().enumerate().map(|(i, item)| {
+        let style = if i == app.menu_idx { Style::default().fg(app.theme.selected).add_modifier(Modifier::BOLD) } else { Style::default().fg(app.theme.fg) };
+        let key = item.spec.key.map(|k| format!("[{}] ", k)).unwrap_or_else(|| "    ".to_string());
+        let mut first = vec![Span::styled(format!("{} {}{}", if i == app.menu_idx {"›"} else {" "}, key, item.spec.label), style)];
+        if let Some((kind, text)) = &item.badge { first.push(Span::styled(format!("  {} {}", kind.symbol(), text), app.theme.status_style(*kind))); }
+        if let Some(text) = &item.busy { first.push(Span::styled(format!("  {}", text), Style::default().fg(app.theme.secondary))); }
+        let mut lines = vec![Line::from(first)];
+        if help { lines.push(Line::from(Span::styled(format!("        {}", item.spec.help), Style::default().fg(app.theme.secondary)))); }
+        ListItem::new(lines)
+    }).collect();
+    let block = Block::default().borders(Borders::ALL).border_style(Style::default().fg(app.theme.frame)).title("Welcome").padding(app.density.padding());
+    let inner = block.inner(area);
+    let list = List::new(items)
+        .block(block)
+        .highlight_style(Style::default().fg(app.theme.selected));
+    // Stateful so the selection stays visible when help lines overflow
+    let mut state = ListState::default().with_selected(Some(app.menu_idx));
+    f.render_stateful_widget(list, area, &mut state);
+    app.hits.rows(inner, state.offset(), app.menu.len(), |_| if help { 2 } else { 1 }, mouse::Target::MenuItem);
+}
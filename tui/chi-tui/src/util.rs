@@ -14,6 +14,7 @@ use serde_json::Value;
 use crate::theme::Theme;
 
 pub fn ensure_chi_llm() -> Result<()> {
+    if crate::mock::enabled() { return Ok(()); }
     match Command::new("chi-llm").arg("--version").output() {
         Ok(_) => Ok(()),
         Err(e) if e.kind() == io::ErrorKind::NotFound => Err(anyhow!(
@@ -23,6 +24,27 @@ pub fn ensure_chi_llm() -> Result<()> {
     }
 }
 
+/// Below this size the UI switches to the compact, single-column layout.
+pub const COMPACT_MIN_WIDTH: u16 = 60;
+pub const COMPACT_MIN_HEIGHT: u16 = 20;
+/// Below this width the header becomes a one-line page bar and panes
+/// stack; overlays stay centered until the compact size.
+pub const NARROW_MIN_WIDTH: u16 = 80;
+
+/// Popup area: centered normally, the whole area in compact mode so
+/// overlays stay readable in small or inline terminals.
+pub fn overlay_rect(compact: bool, pct_x: u16, pct_y: u16, r: Rect) -> Rect {
+    if compact { r } else { centered_rect(pct_x, pct_y, r) }
+}
+
+/// Two panes side by side, or stacked top/bottom in compact mode.
+pub fn split_panes(compact: bool, first_pct: u16, area: Rect) -> std::rc::Rc<[Rect]> {
+    Layout::default()
+        .direction(if compact { Direction::Vertical } else { Direction::Horizontal })
+        .constraints([Constraint::Percentage(first_pct), Constraint::Percentage(100 - first_pct)])
+        .split(area)
+}
+
 pub fn centered_rect(pct_x: u16, pct_y: u16, r: Rect) -> Rect {
     let popup_layout = Layout::default()
         .direction(Direction::Vertical)
@@ -61,6 +83,8 @@ pub fn neon_gradient_line(text: &str, theme: &Theme) -> Line<'static> {
 
 pub fn run_cli_json(args: &[&str], timeout: Duration) -> Result<Value> {
     use wait_timeout::ChildExt;
+    if crate::mock::enabled() { return crate::mock::cli(args); }
+    if let Some(result) = crate::bridge::run(args, timeout) { return result; }
     let mut cmd = Command::new("chi-llm");
     cmd.args(args).stdout(Stdio::piped()).stderr(Stdio::piped());
     let mut child = cmd.spawn()?;
@@ -90,3 +114,50 @@ pub fn run_cli_json(args: &[&str], timeout: Duration) -> Result<Value> {
     Ok(val)
 }
 
+
+/// FNV-1a, stable across toolchains (unlike DefaultHasher).
+pub fn fnv1a(s: &str) -> u64 {
+    let mut h: u64 = 0xcbf29ce484222325;
+    for b in s.bytes() {
+        h ^= b as u64;
+        h = h.wrapping_mul(0x100000001b3);
+    }
+    h
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+
+    #[test]
+    fn overlays_fill_the_screen_when_compact() {
+        let area = Rect::new(0, 0, 100, 30);
+        assert_eq!(overlay_rect(true, 60, 50, area), area);
+        let popup = overlay_rect(false, 60, 50, area);
+        assert!(popup.width < area.width && popup.height < area.height);
+        // Centered: the same margin on both sides, give or take a cell
+        let (left, right) = (popup.x - area.x, area.right() - popup.right());
+        assert!(left.abs_diff(right) <= 1, "{:?}", popup);
+    }
+
+    #[test]
+    fn panes_stack_when_compact() {
+        let area = Rect::new(0, 0, 100, 30);
+        let side = split_panes(false, 40, area);
+        assert_eq!((side[0].width, side[1].width, side[0].height), (40, 60, 30));
+        let stacked = split_panes(true, 40, area);
+        assert_eq!((stacked[0].width, stacked[0].height + stacked[1].height), (100, 30));
+        assert!(stacked[1].y > stacked[0].y);
+    }
+
+    #[cfg(unix)]
+    #[test]
+    fn cli_failures_carry_stderr() {
+        let mut fake = crate::testing::FakeCli::new();
+        fake.fail("models list", "boom: cache unreadable");
+        let err = crate::models::fetch_models(Duration::from_secs(5)).err().expect("fake failed").to_string();
+        assert!(err.contains("boom: cache unreadable"), "{}", err);
+        let err = run_cli_json(&["unknown"], Duration::from_secs(5)).expect_err("no canned reply").to_string();
+        assert!(err.contains("no reply for: unknown"), "{}", err);
+    }
+}
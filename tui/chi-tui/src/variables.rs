@@ -0,0 +1,339 @@
+use std::collections::BTreeMap;
+
+use anyhow::Result;
+use ratatui::layout::Rect;
+use ratatui::prelude::Frame;
+use ratatui::style::{Modifier, Style};
+use ratatui::text::{Line, Span};
+use ratatui::widgets::{Block, Borders, Paragraph, Wrap};
+use serde_json::Value;
+
+use crate::app::App;
+use crate::audit;
+use crate::providers::{read_scratch_entries_raw, ProviderScratchEntry};
+use crate::store;
+use crate::text;
+
+/// `{{ name }}` placeholders in provider config strings, defined once under
+/// `variables` in chi.tmp.json (or as an environment variable of the same
+/// name) and substituted whenever a provider is read for use. Edits keep the
+/// raw template, so changing a host is one edit instead of five.
+pub fn load_variables() -> BTreeMap<String, String> {
+    store::read()
+        .ok()
+        .and_then(|v| v.get("variables").and_then(|x| x.as_object()).cloned())
+        .map(|o| o.into_iter().map(|(k, v)| (k, v.as_str().map(|s| s.to_string()).unwrap_or_else(|| v.to_string()))).collect())
+        .unwrap_or_default()
+}
+
+pub fn save_variables(vars: &BTreeMap<String, String>) -> Result<()> {
+    let mut root = store::read_or_empty();
+    let before = root.clone();
+    let obj: serde_json::Map<String, Value> = vars.iter().map(|(k, v)| (k.clone(), Value::String(v.clone()))).collect();
+    if let Some(r) = root.as_object_mut() { r.insert("variables".to_string(), Value::Object(obj)); }
+    let path = store::write(&root)?;
+    let _ = audit::record("variables.save", &path, &before, &root);
+    Ok(())
+}
+
+fn valid_name(name: &str) -> bool {
+    !name.is_empty() && name.chars().all(|c| c.is_ascii_alphanumeric() || c == '_' || c == '-' || c == '.')
+}
+
+/// Each `{{ name }}` in `text` as (byte range, name).
+fn placeholders(text: &str) -> Vec<(std::ops::Range<usize>, String)> {
+    let mut out = Vec::new();
+    let mut from = 0;
+    while let Some(open) = text[from..].find("{{").map(|i| from + i) {
+        let Some(close) = text[open + 2..].find("}}").map(|i| open + 2 + i) else { break };
+        let name = text[open + 2..close].trim();
+        if valid_name(name) { out.push((open..close + 2, name.to_string())); }
+        from = close + 2;
+    }
+    out
+}
+
+pub fn lookup(name: &str, vars: &BTreeMap<String, String>) -> Option<String> {
+    vars.get(name).cloned().or_else(|| std::env::var(name).ok())
+}
+
+/// Substitute placeholders; unknown names are left as written and returned.
+pub fn render(text: &str, vars: &BTreeMap<String, String>) -> (String, Vec<String>) {
+    let mut out = String::with_capacity(text.len());
+    let mut missing = Vec::new();
+    let mut last = 0;
+    for (range, name) in placeholders(text) {
+        out.push_str(&text[last..range.start]);
+        match lookup(&name, vars) {
+            Some(v) => out.push_str(&v),
+            None => {
+                out.push_str(&text[range.clone()]);
+                missing.push(name);
+            }
+        }
+        last = range.end;
+    }
+    out.push_str(&text[last..]);
+    (out, missing)
+}
+
+/// Resolve every string in a config. A value that is exactly one placeholder
+/// and resolves to an integer becomes a number (e.g. `port`).
+pub fn resolve_value(v: &Value, vars: &BTreeMap<String, String>) -> Value {
+    match v {
+        Value::String(s) if s.contains("{{") => {
+            let (text, _) = render(s, vars);
+            let whole = placeholders(s).first().map_or(false, |(r, _)| r.start == 0 && r.end == s.len());
+            match text.parse::<i64>() {
+                Ok(n) if whole => Value::Number(n.into()),
+                _ => Value::String(text),
+            }
+        }
+        Value::Object(o) => Value::Object(o.iter().map(|(k, x)| (k.clone(), resolve_value(x, vars))).collect()),
+        Value::Array(a) => Value::Array(a.iter().map(|x| resolve_value(x, vars)).collect()),
+        other => other.clone(),
+    }
+}
+
+/// Variables, then `secret:` references (a variable may hold a reference).
+pub fn resolve_entry_with(entry: &ProviderScratchEntry, vars: &BTreeMap<String, String>) -> ProviderScratchEntry {
+    ProviderScratchEntry { config: crate::secrets::resolve_value(&resolve_value(&entry.config, vars)), ..entry.clone() }
+}
+
+/// Resolve one entry against the current variables (reads chi.tmp.json).
+pub fn resolve_entry(entry: &ProviderScratchEntry) -> ProviderScratchEntry {
+    resolve_entry_with(entry, &load_variables())
+}
+
+fn references(v: &Value, out: &mut Vec<String>) {
+    match v {
+        Value::String(s) => out.extend(placeholders(s).into_iter().map(|(_, n)| n)),
+        Value::Object(o) => o.values().for_each(|x| references(x, out)),
+        Value::Array(a) => a.iter().for_each(|x| references(x, out)),
+        _ => {}
+    }
+}
+
+#[derive(Clone, Debug)]
+pub struct VarRow {
+    pub name: String,
+    /// Defined in chi.tmp.json
+    pub value: Option<String>,
+    /// Environment fallback
+    pub env: Option<String>,
+    /// Provider ids referencing it
+    pub used_by: Vec<String>,
+}
+
+#[derive(Clone, Debug, Default)]
+pub struct VarEdit {
+    pub name: String,
+    pub value: String,
+    /// Editing the name (new variable) rather than the value
+    pub on_name: bool,
+    pub is_new: bool,
+}
+
+#[derive(Clone, Debug, Default)]
+pub struct VariablesState {
+    pub vars: BTreeMap<String, String>,
+    pub rows: Vec<VarRow>,
+    pub selected: usize,
+    pub edit: Option<VarEdit>,
+    pub status: Option<String>,
+}
+
+pub fn load_variables_state() -> VariablesState {
+    let mut st = VariablesState { vars: load_variables(), ..Default::default() };
+    st.rebuild();
+    st
+}
+
+impl VariablesState {
+    /// Rows for defined and referenced names, with usage.
+    pub fn rebuild(&mut self) {
+        let mut used: BTreeMap<String, Vec<String>> = self.vars.keys().map(|k| (k.clone(), Vec::new())).collect();
+        for e in read_scratch_entries_raw().unwrap_or_default() {
+            let mut names = Vec::new();
+            references(&e.config, &mut names);
+            for n in names {
+                let ids = used.entry(n).or_default();
+                if !ids.contains(&e.id) { ids.push(e.id.clone()); }
+            }
+        }
+        self.rows = used
+            .into_iter()
+            .map(|(name, used_by)| VarRow { value: self.vars.get(&name).cloned(), env: std::env::var(&name).ok(), name, used_by })
+            .collect();
+        if self.selected >= self.rows.len() { self.selected = self.rows.len().saturating_sub(1); }
+    }
+
+    pub fn current(&self) -> Option<&VarRow> {
+        self.rows.get(self.selected)
+    }
+
+    pub fn start_edit(&mut self) {
+        if let Some(r) = self.current() {
+            let value = r.value.clone().or_else(|| r.env.clone()).unwrap_or_default();
+            self.edit = Some(VarEdit { name: r.name.clone(), value, on_name: false, is_new: false });
+        }
+    }
+
+    pub fn start_new(&mut self) {
+        self.edit = Some(VarEdit { on_name: true, is_new: true, ..Default::default() });
+    }
+
+    /// Store the edit and write chi.tmp.json.
+    pub fn commit_edit(&mut self) -> Result<(), String> {
+        let Some(ed) = self.edit.clone() else { return Ok(()) };
+        let name = ed.name.trim().to_string();
+        if !valid_name(&name) { return Err("name: letters, digits, _ - . only".to_string()); }
+        if ed.is_new && self.vars.contains_key(&name) { return Err(format!("{} already exists", name)); }
+        self.vars.insert(name.clone(), ed.value);
+        save_variables(&self.vars).map_err(|e| e.to_string())?;
+        self.edit = None;
+        self.rebuild();
+        self.selected = self.rows.iter().position(|r| r.name == name).unwrap_or(0);
+        Ok(())
+    }
+
+    pub fn delete_selected(&mut self) -> Result<Option<String>> {
+        let Some(name) = self.current().map(|r| r.name.clone()) else { return Ok(None) };
+        if self.vars.remove(&name).is_none() { return Ok(None); }
+        save_variables(&self.vars)?;
+        self.rebuild();
+        Ok(Some(name))
+    }
+}
+
+pub fn draw_variables(f: &mut Frame, area: Rect, app: &App) {
+    let mut lines: Vec<Line> = Vec::new();
+    let Some(st) = &app.variables else {
+        f.render_widget(Paragraph::new("Loading...").block(Block::default().borders(Borders::ALL)), area);
+        return;
+    };
+    lines.push(Line::from(Span::styled(
+        "Use {{ name }} in any provider field; values below (or env vars of the same name) are filled in when providers are used.",
+        Style::default().fg(app.theme.secondary),
+    )));
+    lines.push(Line::from(""));
+    if st.rows.is_empty() { lines.push(Line::from("No variables yet — press n to add one.")); }
+    let name_w = st.rows.iter().map(|r| text::width(&r.name)).max().unwrap_or(4).clamp(4, 24);
+    for (i, r) in st.rows.iter().enumerate() {
+        let sel = i == st.selected;
+        let style = if sel { Style::default().fg(app.theme.selected).add_modifier(Modifier::BOLD) } else { Style::default().fg(app.theme.fg) };
+        let (value, vstyle) = match (&r.value, &r.env) {
+            (Some(v), _) => (v.clone(), Style::default().fg(app.theme.fg)),
+            (None, Some(v)) => (format!("{}  (env)", v), Style::default().fg(app.theme.accent)),
+            (None, None) => ("undefined".to_string(), Style::default().fg(app.theme.err)),
+        };
+        let used = if r.used_by.is_empty() { "unused".to_string() } else { format!("used by {}", r.used_by.join(", ")) };
+        lines.push(Line::from(vec![
+            Span::styled(format!("{} {}  ", if sel { '›' } else { ' ' }, text::fit(&r.name, name_w)), style),
+            Span::styled(value, vstyle),
+            Span::styled(format!("  — {}", used), Style::default().fg(app.theme.secondary)),
+        ]));
+    }
+    if let Some(ed) = &st.edit {
+        lines.push(Line::from(""));
+        let cursor = |on: bool| if on { "▏" } else { "" };
+        if ed.is_new {
+            lines.push(Line::from(Span::styled(format!("name:  {}{}", ed.name, cursor(ed.on_name)), Style::default().fg(app.theme.selected))));
+        }
+        lines.push(Line::from(Span::styled(format!("{} = {}{}", if ed.is_new { "value" } else { ed.name.as_str() }, ed.value, cursor(!ed.on_name)), Style::default().fg(app.theme.selected))));
+    }
+    if let Some(msg) = &st.status {
+        let (txt, style) = app.theme.status_text(msg);
+        lines.push(Line::from(Span::styled(txt, style)));
+    }
+    let p = Paragraph::new(lines)
+        .style(Style::default().bg(app.theme.bg).fg(app.theme.fg))
+        .block(Block::default().borders(Borders::ALL).border_style(Style::default().fg(app.theme.frame)).title("Variables").padding(app.density.padding()))
+        .wrap(Wrap { trim: false });
+    f.render_widget(p, area);
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+
+    fn vars(pairs: &[(&str, &str)]) -> BTreeMap<String, String> {
+        pairs.iter().map(|(k, v)| (k.to_string(), v.to_string())).collect()
+    }
+
+    #[test]
+    fn placeholders_need_a_valid_name_and_both_braces() {
+        let found: Vec<String> = placeholders("{{ a }}-{{b.c}}/{{ not valid }}/{{open").into_iter().map(|(_, n)| n).collect();
+        assert_eq!(found, vec!["a".to_string(), "b.c".to_string()]);
+        assert_eq!(placeholders("x{{ a }}")[0].0, 1..8);
+    }
+
+    #[test]
+    fn render_substitutes_known_names_and_reports_the_rest() {
+        let v = vars(&[("chi_test_host", "gpu-box")]);
+        let (text, missing) = render("http://{{ chi_test_host }}:{{chi_test_port}}/v1", &v);
+        assert_eq!(text, "http://gpu-box:{{chi_test_port}}/v1");
+        assert_eq!(missing, vec!["chi_test_port".to_string()]);
+        assert_eq!(render("no placeholders", &v), ("no placeholders".to_string(), Vec::new()));
+    }
+
+    #[cfg(unix)]
+    #[test]
+    fn environment_variables_are_the_fallback() {
+        let mut fake = crate::testing::FakeCli::new();
+        fake.set_env("CHI_TEST_REGION", "eu".to_string());
+        assert_eq!(render("{{ CHI_TEST_REGION }}", &BTreeMap::new()).0, "eu");
+        assert_eq!(render("{{ CHI_TEST_REGION }}", &vars(&[("CHI_TEST_REGION", "us")])).0, "us");
+    }
+
+    #[test]
+    fn a_whole_placeholder_resolving_to_an_integer_becomes_a_number() {
+        let v = vars(&[("chi_test_port", "8000"), ("chi_test_host", "box")]);
+        let config = serde_json::json!({
+            "port": "{{ chi_test_port }}",
+            "label": "port {{ chi_test_port }}",
+            "urls": ["http://{{chi_test_host}}"],
+            "retries": 2,
+        });
+        assert_eq!(
+            resolve_value(&config, &v),
+            serde_json::json!({"port": 8000, "label": "port 8000", "urls": ["http://box"], "retries": 2})
+        );
+    }
+
+    #[cfg(unix)]
+    #[test]
+    fn rows_list_defined_and_referenced_names_with_their_users() {
+        let _fake = crate::testing::FakeCli::new();
+        store::write(&serde_json::json!({
+            "providers": [
+                {"id": "a", "name": "A", "type": "ollama", "config": {"host": "{{ chi_test_host }}"}},
+                {"id": "b", "name": "B", "type": "ollama", "config": {"host": "{{ chi_test_host }}", "model": "{{ chi_test_model }}"}},
+            ],
+        }))
+        .expect("store");
+        let mut st = load_variables_state();
+        let names: Vec<&str> = st.rows.iter().map(|r| r.name.as_str()).collect();
+        assert_eq!(names, ["chi_test_host", "chi_test_model"]);
+        assert_eq!(st.rows[0].used_by, vec!["a".to_string(), "b".to_string()]);
+        assert!(st.rows[0].value.is_none());
+
+        st.start_new();
+        st.edit.as_mut().expect("edit").name = "bad name".to_string();
+        assert!(st.commit_edit().is_err());
+        st.edit = Some(VarEdit { name: "chi_test_model".to_string(), value: "qwen".to_string(), on_name: false, is_new: false });
+        st.commit_edit().expect("save");
+        assert_eq!(load_variables().get("chi_test_model").map(String::as_str), Some("qwen"));
+        assert_eq!(st.current().map(|r| r.name.as_str()), Some("chi_test_model"));
+        st.edit = Some(VarEdit { name: "chi_test_model".to_string(), value: "x".to_string(), on_name: true, is_new: true });
+        assert_eq!(st.commit_edit(), Err("chi_test_model already exists".to_string()));
+
+        st.edit = None;
+        assert_eq!(st.delete_selected().expect("delete"), Some("chi_test_model".to_string()));
+        assert!(load_variables().is_empty());
+        // Still referenced, so the row stays, now undefined
+        assert_eq!(st.rows.len(), 2);
+        st.selected = 0;
+        assert_eq!(st.delete_selected().expect("not defined"), None);
+    }
+}